@@ -0,0 +1,221 @@
+// Package audit records every insert, update and delete on selected tables in
+// an _audit_log table, using generated AFTER triggers.
+//
+// Each entry holds the table, rowid, operation, the old and new row as JSON,
+// a UTC timestamp and the actor set by the application. The actor is read by
+// the triggers through the audit_actor() SQL function, so every connection
+// that writes to an audited table must register it with PrepareConn:
+//
+//	pool.InitPool(uri, 4, pool.WithPrepareConn(audit.PrepareConn))
+//
+// Connections without the function, such as the sqlite3 shell, fail to write
+// to audited tables unless Enable was called with Options.NoActor.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/internal/triggers"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// LogTable is the name of the table audit entries are written to.
+const LogTable = "_audit_log"
+
+// Options configures the triggers created by Enable.
+type Options struct {
+	// Exclude lists columns, in any audited table, that are left out of the
+	// recorded old and new values, such as password hashes.
+	Exclude []string
+	// NoActor records a NULL actor instead of calling audit_actor(), so
+	// connections without PrepareConn can still write to audited tables.
+	NoActor bool
+}
+
+// Entry is one row of the audit log.
+type Entry struct {
+	ID    int64
+	Table string
+	RowID int64
+	Op    string
+	// Old and New are the row before and after the change; Old is nil for
+	// inserts and New is nil for deletes. Blobs are hex encoded.
+	Old   map[string]interface{}
+	New   map[string]interface{}
+	Actor string
+	Time  time.Time
+}
+
+const createLogTable = `CREATE TABLE IF NOT EXISTS _audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tbl TEXT NOT NULL,
+	row_id INTEGER NOT NULL,
+	op TEXT NOT NULL,
+	old_values TEXT,
+	new_values TEXT,
+	actor TEXT,
+	ts TEXT NOT NULL DEFAULT (strftime('%Y-%m-%dT%H:%M:%fZ', 'now'))
+);
+CREATE INDEX IF NOT EXISTS _audit_log_row ON _audit_log (tbl, row_id);`
+
+var ops = []string{"insert", "update", "delete"}
+
+// Enable creates the audit log table and installs triggers on tables,
+// replacing any triggers a previous Enable installed on them.
+func Enable(ctx context.Context, tables []string, opts Options) error {
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) (err error) {
+		endFn, err := sqlitex.ImmediateTransaction(conn)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer endFn(&err)
+
+		if err := sqlitex.ExecuteScript(conn, createLogTable, nil); err != nil {
+			return fmt.Errorf("failed to create %s table: %w", LogTable, err)
+		}
+		for _, table := range tables {
+			columns, err := triggers.TableColumns(conn, table, opts.Exclude)
+			if err != nil {
+				return err
+			}
+			for _, op := range ops {
+				if err := dropTrigger(conn, table, op); err != nil {
+					return err
+				}
+				if err := sqlitex.ExecuteTransient(conn, triggerSQL(table, op, columns, opts), nil); err != nil {
+					return fmt.Errorf("failed to create audit trigger on %s: %w", table, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Disable removes the audit triggers from tables. The log is kept.
+func Disable(ctx context.Context, tables []string) error {
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		for _, table := range tables {
+			for _, op := range ops {
+				if err := dropTrigger(conn, table, op); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// History returns the audit entries for one row of table, oldest first.
+func History(ctx context.Context, table string, rowID int64) ([]Entry, error) {
+	var entries []Entry
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		return sqlitex.Execute(conn, `SELECT id, tbl, row_id, op, old_values, new_values, actor, ts
+			FROM _audit_log WHERE tbl = ? AND row_id = ? ORDER BY id;`, &sqlitex.ExecOptions{
+			Args: []interface{}{table, rowID},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				e := Entry{
+					ID:    stmt.ColumnInt64(0),
+					Table: stmt.ColumnText(1),
+					RowID: stmt.ColumnInt64(2),
+					Op:    stmt.ColumnText(3),
+					Actor: stmt.ColumnText(6),
+				}
+				var err error
+				if e.Old, err = triggers.DecodeValues(stmt, 4); err != nil {
+					return err
+				}
+				if e.New, err = triggers.DecodeValues(stmt, 5); err != nil {
+					return err
+				}
+				if e.Time, err = time.Parse("2006-01-02T15:04:05.999Z", stmt.ColumnText(7)); err != nil {
+					return fmt.Errorf("invalid audit timestamp: %w", err)
+				}
+				entries = append(entries, e)
+				return nil
+			},
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", LogTable, err)
+	}
+	return entries, nil
+}
+
+// actors holds the actor set on each connection by SetActor.
+var actors sync.Map // map[*sqlite.Conn]string
+
+// PrepareConn registers the audit_actor() function on conn. Pass it to
+// pool.WithPrepareConn.
+func PrepareConn(conn *sqlite.Conn) error {
+	return conn.CreateFunction("audit_actor", &sqlite.FunctionImpl{
+		NArgs:         0,
+		AllowIndirect: true,
+		Scalar: func(ctx sqlite.Context, args []sqlite.Value) (sqlite.Value, error) {
+			if actor, ok := actors.Load(conn); ok {
+				return sqlite.TextValue(actor.(string)), nil
+			}
+			return sqlite.Value{}, nil
+		},
+	})
+}
+
+// SetActor sets the actor recorded for changes made on conn. An empty actor
+// records NULL.
+func SetActor(conn *sqlite.Conn, actor string) {
+	if actor == "" {
+		actors.Delete(conn)
+		return
+	}
+	actors.Store(conn, actor)
+}
+
+// WithActor runs fn on a pooled connection whose changes are recorded as made
+// by actor.
+func WithActor(ctx context.Context, actor string, fn func(conn *sqlite.Conn) error) error {
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		SetActor(conn, actor)
+		defer SetActor(conn, "")
+		return fn(conn)
+	})
+}
+
+func triggerName(table, op string) string {
+	return "_audit_" + table + "_" + op
+}
+
+func dropTrigger(conn *sqlite.Conn, table, op string) error {
+	query := "DROP TRIGGER IF EXISTS " + sqliteutils.QuoteIdentifier(triggerName(table, op)) + ";"
+	if err := sqlitex.ExecuteTransient(conn, query, nil); err != nil {
+		return fmt.Errorf("failed to drop audit trigger on %s: %w", table, err)
+	}
+	return nil
+}
+
+// triggerSQL builds the AFTER trigger auditing op on table.
+func triggerSQL(table, op string, columns []string, opts Options) string {
+	oldValues, newValues, ref := "NULL", "NULL", "NEW"
+	if op != "insert" {
+		oldValues = triggers.JSONObject("OLD", columns)
+	}
+	if op != "delete" {
+		newValues = triggers.JSONObject("NEW", columns)
+	} else {
+		ref = "OLD"
+	}
+	actor := "audit_actor()"
+	if opts.NoActor {
+		actor = "NULL"
+	}
+	return fmt.Sprintf(`CREATE TRIGGER %s AFTER %s ON %s BEGIN
+	INSERT INTO _audit_log (tbl, row_id, op, old_values, new_values, actor)
+	VALUES (%s, %s.rowid, '%s', %s, %s, %s);
+END;`, sqliteutils.QuoteIdentifier(triggerName(table, op)), strings.ToUpper(op), sqliteutils.QuoteIdentifier(table),
+		triggers.QuoteLiteral(table), ref, op, oldValues, newValues, actor)
+}
@@ -0,0 +1,85 @@
+package audit_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/audit"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestAudit(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "audit.db"), 2, pool.WithPrepareConn(audit.PrepareConn)))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.Exec(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, password TEXT);", nil, nil))
+
+	require.NoError(t, audit.Enable(ctx, []string{"users"}, audit.Options{Exclude: []string{"password"}}))
+	// Enabling again replaces the triggers instead of failing.
+	require.NoError(t, audit.Enable(ctx, []string{"users"}, audit.Options{Exclude: []string{"password"}}))
+
+	start := time.Now().Add(-time.Second)
+	require.NoError(t, audit.WithActor(ctx, "alice", func(conn *sqlite.Conn) error {
+		return sqlitex.ExecuteScript(conn, `
+			INSERT INTO users (id, name, password) VALUES (1, 'Bob', 'secret');
+			UPDATE users SET name = 'Robert' WHERE id = 1;
+		`, nil)
+	}))
+	require.NoError(t, exec.Exec(ctx, "DELETE FROM users WHERE id = 1;", nil, nil))
+
+	entries, err := audit.History(ctx, "users", 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+
+	assert.Equal(t, "insert", entries[0].Op)
+	assert.Equal(t, "alice", entries[0].Actor)
+	assert.Nil(t, entries[0].Old)
+	assert.Equal(t, map[string]interface{}{"id": int64(1), "name": "Bob"}, entries[0].New)
+	assert.True(t, entries[0].Time.After(start), entries[0].Time)
+
+	assert.Equal(t, "update", entries[1].Op)
+	assert.Equal(t, "Bob", entries[1].Old["name"])
+	assert.Equal(t, "Robert", entries[1].New["name"])
+
+	assert.Equal(t, "delete", entries[2].Op)
+	assert.Empty(t, entries[2].Actor)
+	assert.Equal(t, "Robert", entries[2].Old["name"])
+	assert.Nil(t, entries[2].New)
+
+	require.NoError(t, audit.Disable(ctx, []string{"users"}))
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO users (id, name) VALUES (1, 'Carol');", nil, nil))
+	entries, err = audit.History(ctx, "users", 1)
+	require.NoError(t, err)
+	assert.Len(t, entries, 3)
+
+	assert.Error(t, audit.Enable(ctx, []string{"missing"}, audit.Options{}))
+}
+
+func TestNoActor(t *testing.T) {
+	ctx := context.Background()
+	// Without PrepareConn, audit_actor() is unavailable.
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "audit.db"), 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.Exec(ctx, "CREATE TABLE items (name TEXT);", nil, nil))
+
+	require.NoError(t, audit.Enable(ctx, []string{"items"}, audit.Options{}))
+	assert.Error(t, exec.Exec(ctx, "INSERT INTO items (name) VALUES ('a');", nil, nil))
+
+	require.NoError(t, audit.Enable(ctx, []string{"items"}, audit.Options{NoActor: true}))
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO items (name) VALUES ('a');", nil, nil))
+	entries, err := audit.History(ctx, "items", 1)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Empty(t, entries[0].Actor)
+}
@@ -1,8 +1,6 @@
 package backup
 
 import (
-	"fmt"
-	"os"
 	"strings"
 	"time"
 
@@ -10,7 +8,12 @@ import (
 	"zombiezen.com/go/sqlite"
 )
 
-func BackupDatabase(sourceDBPath, destDBPath string) error {
+// BackupDatabase copies sourceDBPath to destDBPath with the online backup API
+// and writes a JSON manifest describing the copy next to it.
+func BackupDatabase(sourceDBPath, destDBPath string) (err error) {
+	start := time.Now()
+	defer func() { recordBackup(sourceDBPath, destDBPath, start, err) }()
+
 	// Open the source database
 	srcConn, err := sqlite.OpenConn(sourceDBPath, sqlite.OpenReadOnly)
 	if err != nil {
@@ -18,14 +21,23 @@ func BackupDatabase(sourceDBPath, destDBPath string) error {
 	}
 	defer srcConn.Close()
 
+	if err := copyDatabase(srcConn, destDBPath); err != nil {
+		return err
+	}
+
+	return WriteManifest(destDBPath, sourceDBPath, time.Since(start))
+}
+
+// copyDatabase copies the main database of srcConn into the file at destDBPath.
+func copyDatabase(srcConn *sqlite.Conn, destDBPath string) error {
 	// Open the destination database
 	dstConn, err := sqlite.OpenConn(destDBPath, sqlite.OpenReadWrite|sqlite.OpenCreate)
 	if err != nil {
 		return sqliteutils.FailedToOpenDatabaseError(err, destDBPath)
 	}
 	defer func() {
-		if err = dstConn.Close(); err != nil {
-			fmt.Fprintln(os.Stderr, err)
+		if err := dstConn.Close(); err != nil {
+			sqliteutils.Logger().Error("failed to close backup destination", "path", destDBPath, "error", err)
 		}
 	}()
 
@@ -36,7 +48,7 @@ func BackupDatabase(sourceDBPath, destDBPath string) error {
 	}
 	defer func() {
 		if err := backup.Close(); err != nil {
-			fmt.Fprintln(os.Stderr, err)
+			sqliteutils.Logger().Error("failed to finish backup", "path", destDBPath, "error", err)
 		}
 	}()
 
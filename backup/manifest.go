@@ -0,0 +1,245 @@
+package backup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// ManifestSuffix is appended to a backup's path to name its manifest file.
+const ManifestSuffix = ".manifest.json"
+
+// Manifest describes a backup file.
+type Manifest struct {
+	Path          string            `json:"path"`
+	SourceURI     string            `json:"source_uri"`
+	CreatedAt     time.Time         `json:"created_at"`
+	SchemaVersion int64             `json:"schema_version"`
+	UserVersion   int64             `json:"user_version"`
+	PageSize      int64             `json:"page_size"`
+	PageCount     int64             `json:"page_count"`
+	Size          int64             `json:"size"`
+	SHA256        string            `json:"sha256"`
+	Duration      time.Duration     `json:"duration"`
+	SQLiteVersion string            `json:"sqlite_version"`
+	GoVersion     string            `json:"go_version"`
+	Libraries     map[string]string `json:"libraries,omitempty"`
+}
+
+// RestoreOptions controls the safety checks performed by Restore.
+type RestoreOptions struct {
+	// Force restores even when the checksum, page size or schema version checks fail.
+	Force bool
+}
+
+// WriteManifest inspects the backup at backupPath and writes its manifest to
+// backupPath + ManifestSuffix. BackupDatabase calls it automatically.
+func WriteManifest(backupPath, sourceURI string, duration time.Duration) error {
+	m, err := readManifestInfo(backupPath)
+	if err != nil {
+		return err
+	}
+	m.SourceURI = sourceURI
+	m.CreatedAt = time.Now().UTC()
+	m.Duration = duration
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return sqliteutils.FailedToWriteManifestError(err, backupPath)
+	}
+	if err := writeFileAtomic(backupPath+ManifestSuffix, data); err != nil {
+		return sqliteutils.FailedToWriteManifestError(err, backupPath)
+	}
+	return nil
+}
+
+// ReadManifest reads the manifest written for the backup at backupPath.
+func ReadManifest(backupPath string) (*Manifest, error) {
+	data, err := os.ReadFile(backupPath + ManifestSuffix)
+	if err != nil {
+		return nil, sqliteutils.FailedToReadManifestError(err, backupPath)
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, sqliteutils.FailedToReadManifestError(err, backupPath)
+	}
+	return &m, nil
+}
+
+// ListBackups returns the manifests of every backup under target, including
+// WAL shipping snapshots, ordered from oldest to newest.
+func ListBackups(target string) ([]Manifest, error) {
+	var manifests []Manifest
+	err := filepath.WalkDir(target, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ManifestSuffix) {
+			return nil
+		}
+		m, err := ReadManifest(strings.TrimSuffix(path, ManifestSuffix))
+		if err != nil {
+			return err
+		}
+		manifests = append(manifests, *m)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(manifests, func(i, j int) bool { return manifests[i].CreatedAt.Before(manifests[j].CreatedAt) })
+	return manifests, nil
+}
+
+// Restore copies the backup at backupPath over the database at destPath using
+// the online backup API. When the backup has a manifest its checksum is
+// verified, and when destPath already exists Restore refuses to overwrite a
+// database with a different page size or an older user_version than the
+// backup, unless opts.Force is set.
+func Restore(ctx context.Context, backupPath, destPath string, opts *RestoreOptions) error {
+	if opts == nil {
+		opts = &RestoreOptions{}
+	}
+
+	m, err := ReadManifest(backupPath)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if m == nil {
+		if m, err = readManifestInfo(backupPath); err != nil {
+			return err
+		}
+	} else if !opts.Force {
+		sum, _, err := fileChecksum(backupPath)
+		if err != nil {
+			return sqliteutils.FailedToOpenDatabaseError(err, backupPath)
+		}
+		if sum != m.SHA256 {
+			return sqliteutils.ErrChecksumMismatch
+		}
+	}
+
+	if _, err := os.Stat(destPath); err == nil && !opts.Force {
+		current, err := readManifestInfo(destPath)
+		if err != nil {
+			return err
+		}
+		if current.PageSize != m.PageSize {
+			return sqliteutils.ErrPageSizeMismatch
+		}
+		if m.UserVersion > current.UserVersion {
+			return sqliteutils.ErrNewerSchemaVersion
+		}
+	}
+
+	srcConn, err := sqlite.OpenConn(backupPath, sqlite.OpenReadOnly)
+	if err != nil {
+		return sqliteutils.FailedToOpenDatabaseError(err, backupPath)
+	}
+	defer srcConn.Close()
+	srcConn.SetInterrupt(ctx.Done())
+
+	return copyDatabase(srcConn, destPath)
+}
+
+// readManifestInfo collects everything in a Manifest that can be read from the
+// database file itself.
+func readManifestInfo(path string) (*Manifest, error) {
+	conn, err := sqlite.OpenConn(path, sqlite.OpenReadOnly)
+	if err != nil {
+		return nil, sqliteutils.FailedToOpenDatabaseError(err, path)
+	}
+	defer conn.Close()
+
+	m := &Manifest{
+		Path:      path,
+		GoVersion: runtime.Version(),
+		Libraries: libraryVersions(),
+	}
+	pragmas := []struct {
+		query string
+		dest  *int64
+	}{
+		{"PRAGMA schema_version;", &m.SchemaVersion},
+		{"PRAGMA user_version;", &m.UserVersion},
+		{"PRAGMA page_size;", &m.PageSize},
+		{"PRAGMA page_count;", &m.PageCount},
+	}
+	for _, p := range pragmas {
+		dest := p.dest
+		err := sqlitex.Execute(conn, p.query, &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				*dest = stmt.ColumnInt64(0)
+				return nil
+			},
+		})
+		if err != nil {
+			return nil, sqliteutils.FailedToReadManifestError(err, path)
+		}
+	}
+	err = sqlitex.Execute(conn, "SELECT sqlite_version();", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			m.SQLiteVersion = stmt.ColumnText(0)
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, sqliteutils.FailedToReadManifestError(err, path)
+	}
+
+	if m.SHA256, m.Size, err = fileChecksum(path); err != nil {
+		return nil, sqliteutils.FailedToReadManifestError(err, path)
+	}
+	return m, nil
+}
+
+// fileChecksum returns the hex-encoded SHA-256 and size of a file.
+func fileChecksum(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// libraryVersions reports the versions of the SQLite-related modules linked
+// into the running binary.
+func libraryVersions() map[string]string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return nil
+	}
+	versions := make(map[string]string)
+	for _, dep := range info.Deps {
+		switch dep.Path {
+		case "github.com/dropsite-ai/sqliteutils", "zombiezen.com/go/sqlite", "modernc.org/sqlite":
+			versions[dep.Path] = dep.Version
+		}
+	}
+	if info.Main.Path == "github.com/dropsite-ai/sqliteutils" {
+		versions[info.Main.Path] = info.Main.Version
+	}
+	return versions
+}
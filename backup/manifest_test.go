@@ -0,0 +1,77 @@
+package backup_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/backup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func createDatabase(t *testing.T, path, script string) {
+	t.Helper()
+	conn, err := sqlite.OpenConn(path, sqlite.OpenReadWrite|sqlite.OpenCreate)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, sqlitex.ExecScript(conn, script))
+}
+
+func TestManifest(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "source.db")
+	backupDir := filepath.Join(dir, "backups")
+	backupPath := filepath.Join(backupDir, "backup.db")
+
+	createDatabase(t, srcPath, `
+		PRAGMA user_version = 3;
+		CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);
+		INSERT INTO items (name) VALUES ('a'), ('b');
+	`)
+	require.NoError(t, backup.BackupDatabase(srcPath, filepath.Join(dir, "ignored.db")))
+	require.NoError(t, os.MkdirAll(backupDir, 0o755))
+	require.NoError(t, backup.BackupDatabase(srcPath, backupPath))
+
+	manifests, err := backup.ListBackups(backupDir)
+	require.NoError(t, err)
+	require.Len(t, manifests, 1)
+	m := manifests[0]
+	assert.Equal(t, backupPath, m.Path)
+	assert.Equal(t, srcPath, m.SourceURI)
+	assert.Equal(t, int64(3), m.UserVersion)
+	assert.Equal(t, int64(4096), m.PageSize)
+	assert.NotEmpty(t, m.SHA256)
+	assert.NotEmpty(t, m.SQLiteVersion)
+	assert.Positive(t, m.Size)
+
+	t.Run("RestoreOverOlderSchema", func(t *testing.T) {
+		destPath := filepath.Join(dir, "older.db")
+		createDatabase(t, destPath, "PRAGMA user_version = 2;")
+
+		err := backup.Restore(ctx, backupPath, destPath, nil)
+		assert.ErrorIs(t, err, sqliteutils.ErrNewerSchemaVersion)
+
+		require.NoError(t, backup.Restore(ctx, backupPath, destPath, &backup.RestoreOptions{Force: true}))
+		assert.Equal(t, int64(2), countRows(t, destPath))
+	})
+
+	t.Run("RestoreOverDifferentPageSize", func(t *testing.T) {
+		destPath := filepath.Join(dir, "pagesize.db")
+		createDatabase(t, destPath, "PRAGMA page_size = 8192; PRAGMA user_version = 3; CREATE TABLE items (id INTEGER PRIMARY KEY);")
+
+		err := backup.Restore(ctx, backupPath, destPath, nil)
+		assert.ErrorIs(t, err, sqliteutils.ErrPageSizeMismatch)
+	})
+
+	t.Run("RestoreNewFile", func(t *testing.T) {
+		destPath := filepath.Join(dir, "new.db")
+		require.NoError(t, backup.Restore(ctx, backupPath, destPath, nil))
+		assert.Equal(t, int64(2), countRows(t, destPath))
+	})
+}
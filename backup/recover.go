@@ -0,0 +1,406 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// RowIDRange is a run of rows Recover could not read: those with rowids
+// strictly between After and Before. Before is math.MaxInt64 when the rest
+// of the table was lost.
+type RowIDRange struct {
+	After  int64
+	Before int64
+}
+
+// TableRecovery describes what Recover salvaged from one table.
+type TableRecovery struct {
+	Table string
+	// Rows is the number of rows copied.
+	Rows int64
+	// Lost lists the rowid ranges that could not be read.
+	Lost []RowIDRange
+	// Err describes the first read error, or why the table was skipped.
+	Err string
+}
+
+// RecoverReport is the result of Recover.
+type RecoverReport struct {
+	Path string
+	// OK is true when every row and schema object was recovered.
+	OK bool
+	// Tables holds what was salvaged from each table, in schema order.
+	Tables []TableRecovery
+	// Failed lists the indexes, views and triggers that could not be
+	// recreated, with the error.
+	Failed []string
+}
+
+// schemaObject is a row of sqlite_schema.
+type schemaObject struct {
+	typ, name, sql string
+}
+
+// Recover salvages what can still be read from the damaged database at
+// corruptPath into a new database at outPath, which must not exist. It
+// recreates the schema, copies every table row by row, skipping the rowid
+// ranges stored in unreadable pages, and then recreates indexes, views and
+// triggers. The report lists the rows and objects that were lost. Virtual
+// tables are not copied; rebuild them from their content afterwards.
+func Recover(ctx context.Context, corruptPath, outPath string) (*RecoverReport, error) {
+	if _, err := os.Stat(outPath); err == nil {
+		return nil, fmt.Errorf("failed to recover database: %s already exists", outPath)
+	}
+
+	src, err := sqlite.OpenConn(corruptPath, sqlite.OpenReadOnly)
+	if err != nil {
+		return nil, sqliteutils.FailedToOpenDatabaseError(err, corruptPath)
+	}
+	defer src.Close()
+	src.SetInterrupt(ctx.Done())
+
+	var objects []schemaObject
+	err = sqlitex.Execute(src, "SELECT type, name, sql FROM sqlite_schema WHERE sql IS NOT NULL ORDER BY rowid;", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			objects = append(objects, schemaObject{stmt.ColumnText(0), stmt.ColumnText(1), stmt.ColumnText(2)})
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema of %s: %w", corruptPath, err)
+	}
+
+	dst, err := sqlite.OpenConn(outPath, sqlite.OpenReadWrite|sqlite.OpenCreate)
+	if err != nil {
+		return nil, sqliteutils.FailedToOpenDatabaseError(err, outPath)
+	}
+	report, err := recoverInto(ctx, src, dst, objects)
+	if closeErr := dst.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close %s: %w", outPath, closeErr)
+	}
+	if err != nil {
+		os.Remove(outPath)
+		return nil, err
+	}
+	report.Path = outPath
+	return report, nil
+}
+
+func recoverInto(ctx context.Context, src, dst *sqlite.Conn, objects []schemaObject) (*RecoverReport, error) {
+	dst.SetInterrupt(ctx.Done())
+	report := &RecoverReport{OK: true}
+
+	// Shadow tables belong to their virtual table and are skipped with it.
+	virtual := map[string]bool{}
+	shadow := map[string]bool{}
+	err := sqlitex.Execute(src, "SELECT name, type FROM pragma_table_list WHERE schema = 'main' AND type IN ('virtual', 'shadow');", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			if stmt.ColumnText(1) == "virtual" {
+				virtual[stmt.ColumnText(0)] = true
+			} else {
+				shadow[stmt.ColumnText(0)] = true
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list virtual tables: %w", err)
+	}
+	// shadowOf returns the virtual table that name is or belongs to, or "".
+	// Shadow tables are named after their virtual table, so the longest
+	// virtual table name they start with is theirs.
+	shadowOf := func(name string) string {
+		if virtual[name] {
+			return name
+		}
+		if !shadow[name] {
+			return ""
+		}
+		owner := name
+		for v := range virtual {
+			if strings.HasPrefix(name, v+"_") && (owner == name || len(v) > len(owner)) {
+				owner = v
+			}
+		}
+		return owner
+	}
+
+	var tables []string
+	for _, obj := range objects {
+		if obj.typ != "table" {
+			continue
+		}
+		if v := shadowOf(obj.name); v != "" {
+			report.OK = false
+			report.Tables = append(report.Tables, TableRecovery{Table: obj.name, Err: fmt.Sprintf("part of virtual table %s, which is not recovered", v)})
+			continue
+		}
+		// sqlite_sequence is created along with the first AUTOINCREMENT table.
+		if obj.name != "sqlite_sequence" {
+			if err := sqlitex.ExecuteTransient(dst, obj.sql, nil); err != nil {
+				return nil, fmt.Errorf("failed to create table %s: %w", obj.name, err)
+			}
+		}
+		tables = append(tables, obj.name)
+	}
+
+	for _, table := range tables {
+		recovery, err := recoverTable(ctx, src, dst, table)
+		if err != nil {
+			return nil, err
+		}
+		if recovery.Err != "" {
+			report.OK = false
+		}
+		report.Tables = append(report.Tables, recovery)
+	}
+
+	for _, obj := range objects {
+		if obj.typ == "table" || shadowOf(obj.name) != "" {
+			continue
+		}
+		if err := sqlitex.ExecuteTransient(dst, obj.sql, nil); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			report.OK = false
+			report.Failed = append(report.Failed, fmt.Sprintf("%s %s: %v", obj.typ, obj.name, err))
+		}
+	}
+	return report, nil
+}
+
+// recoverTable copies the readable rows of table from src to dst. Scans
+// stop at the first unreadable page; the next readable rowid is then found
+// by seeking ever further past the last row read, which skips the pages the
+// seeks fail on.
+func recoverTable(ctx context.Context, src, dst *sqlite.Conn, table string) (recovery TableRecovery, err error) {
+	recovery.Table = table
+	columns, withoutRowID, colErr := tableColumns(src, table)
+	if colErr != nil {
+		recovery.Err = colErr.Error()
+		recovery.Lost = []RowIDRange{{After: math.MinInt64, Before: math.MaxInt64}}
+		return recovery, nil
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = sqliteutils.QuoteIdentifier(column)
+	}
+	list := strings.Join(quoted, ", ")
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ")
+	name := sqliteutils.QuoteIdentifier(table)
+
+	endFn, err := sqlitex.ImmediateTransaction(dst)
+	if err != nil {
+		return recovery, fmt.Errorf("failed to begin copying %s: %w", table, err)
+	}
+	defer endFn(&err)
+
+	if withoutRowID {
+		// Rows are ordered by primary key, so a scan cannot resume past a
+		// broken page.
+		insert, err := dst.Prepare("INSERT INTO " + name + " (" + list + ") VALUES (" + placeholders + ");")
+		if err != nil {
+			return recovery, fmt.Errorf("failed to copy %s: %w", table, err)
+		}
+		scanErr := copyRows(src, "SELECT "+list+" FROM "+name+";", insert, nil, &recovery)
+		var insertErr *recoverInsertError
+		switch {
+		case scanErr == nil:
+		case ctx.Err() != nil:
+			return recovery, ctx.Err()
+		case errors.As(scanErr, &insertErr):
+			return recovery, scanErr
+		default:
+			recovery.Err = scanErr.Error()
+		}
+		return recovery, nil
+	}
+
+	insert, err := dst.Prepare("INSERT INTO " + name + " (_rowid_, " + list + ") VALUES (?, " + placeholders + ");")
+	if err != nil {
+		return recovery, fmt.Errorf("failed to copy %s: %w", table, err)
+	}
+	query := "SELECT _rowid_, " + list + " FROM " + name + " WHERE _rowid_ > ? ORDER BY _rowid_;"
+	after := int64(math.MinInt64)
+	for {
+		scanErr := copyRows(src, query, insert, &after, &recovery)
+		if scanErr == nil {
+			return recovery, nil
+		}
+		if ctx.Err() != nil {
+			return recovery, ctx.Err()
+		}
+		var insertErr *recoverInsertError
+		if errors.As(scanErr, &insertErr) {
+			return recovery, scanErr
+		}
+		if recovery.Err == "" {
+			recovery.Err = scanErr.Error()
+		}
+		next, ok := nextReadable(src, name, after)
+		before := next + 1
+		if !ok {
+			before = math.MaxInt64
+		}
+		// A scan that failed again before its first row extends the
+		// previous range.
+		if n := len(recovery.Lost); n > 0 && recovery.Lost[n-1].Before == after+1 {
+			recovery.Lost[n-1].Before = before
+		} else {
+			recovery.Lost = append(recovery.Lost, RowIDRange{After: after, Before: before})
+		}
+		if !ok {
+			return recovery, nil
+		}
+		after = next
+	}
+}
+
+// recoverInsertError is a failure to write a row to the recovered database,
+// which unlike a read error stops Recover.
+type recoverInsertError struct {
+	err error
+}
+
+func (e *recoverInsertError) Error() string {
+	return "failed to insert recovered row: " + e.err.Error()
+}
+
+func (e *recoverInsertError) Unwrap() error {
+	return e.err
+}
+
+// copyRows runs query on src and inserts each row with insert. When after
+// is not nil, it is bound to the query's parameter, the first column is the
+// rowid and *after is advanced as rows are copied.
+func copyRows(src *sqlite.Conn, query string, insert *sqlite.Stmt, after *int64, recovery *TableRecovery) error {
+	stmt, _, err := src.PrepareTransient(query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Finalize()
+	if after != nil {
+		stmt.BindInt64(1, *after)
+	}
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return err
+		}
+		if !hasRow {
+			return nil
+		}
+		for i := 0; i < stmt.ColumnCount(); i++ {
+			bindColumn(insert, i+1, stmt, i)
+		}
+		if _, err := insert.Step(); err != nil {
+			insert.Reset()
+			return &recoverInsertError{err}
+		}
+		if err := insert.Reset(); err != nil {
+			return &recoverInsertError{err}
+		}
+		if after != nil {
+			*after = stmt.ColumnInt64(0)
+		}
+		recovery.Rows++
+	}
+}
+
+// nextReadable returns the largest rowid r past after such that a seek to
+// the rows after r succeeds, with ok false when none does. Seeks first
+// double their distance from after until one succeeds, then the distance is
+// narrowed down by bisection, so few readable rows are skipped.
+func nextReadable(src *sqlite.Conn, name string, after int64) (int64, bool) {
+	seeks := func(from int64) bool {
+		stmt, _, err := src.PrepareTransient("SELECT _rowid_ FROM " + name + " WHERE _rowid_ > ? ORDER BY _rowid_ LIMIT 1;")
+		if err != nil {
+			return false
+		}
+		defer stmt.Finalize()
+		stmt.BindInt64(1, from)
+		_, err = stmt.Step()
+		return err == nil
+	}
+
+	// The seek to after itself failed.
+	failed := after
+	var ok int64
+	found := false
+	for step := int64(1); step > 0; step *= 2 {
+		if after > math.MaxInt64-step {
+			break
+		}
+		if seeks(after + step) {
+			ok, found = after+step, true
+			break
+		}
+		failed = after + step
+	}
+	if !found {
+		return 0, false
+	}
+	for ok-failed > 1 {
+		mid := failed + (ok-failed)/2
+		if seeks(mid) {
+			ok = mid
+		} else {
+			failed = mid
+		}
+	}
+	return ok, true
+}
+
+// tableColumns returns the stored columns of table, leaving out generated
+// ones, and whether it is a WITHOUT ROWID table.
+func tableColumns(conn *sqlite.Conn, table string) (columns []string, withoutRowID bool, err error) {
+	err = sqlitex.Execute(conn, "SELECT name FROM pragma_table_xinfo(?) WHERE hidden = 0 ORDER BY cid;", &sqlitex.ExecOptions{
+		Args: []interface{}{table},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			columns = append(columns, stmt.ColumnText(0))
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(columns) == 0 {
+		return nil, false, fmt.Errorf("table %s has no readable columns", table)
+	}
+	// Only rowid tables have a rowid to select.
+	stmt, _, err := conn.PrepareTransient("SELECT _rowid_ FROM " + sqliteutils.QuoteIdentifier(table) + " LIMIT 0;")
+	if err != nil {
+		return columns, true, nil
+	}
+	stmt.Finalize()
+	return columns, false, nil
+}
+
+// bindColumn binds column col of src to parameter param of dst, keeping its
+// storage class.
+func bindColumn(dst *sqlite.Stmt, param int, src *sqlite.Stmt, col int) {
+	switch src.ColumnType(col) {
+	case sqlite.TypeInteger:
+		dst.BindInt64(param, src.ColumnInt64(col))
+	case sqlite.TypeFloat:
+		dst.BindFloat(param, src.ColumnFloat(col))
+	case sqlite.TypeText:
+		dst.BindText(param, src.ColumnText(col))
+	case sqlite.TypeBlob:
+		blob := make([]byte, src.ColumnLen(col))
+		src.ColumnBytes(col, blob)
+		dst.BindBytes(param, blob)
+	default:
+		dst.BindNull(param)
+	}
+}
@@ -0,0 +1,118 @@
+package backup_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/backup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestRecover(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	corruptPath := filepath.Join(dir, "corrupt.db")
+	outPath := filepath.Join(dir, "recovered.db")
+
+	conn, err := sqlite.OpenConn(corruptPath, sqlite.OpenReadWrite|sqlite.OpenCreate)
+	require.NoError(t, err)
+	require.NoError(t, sqlitex.ExecScript(conn, `
+		PRAGMA page_size = 4096;
+		CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, data BLOB);
+		CREATE INDEX items_name ON items (name);
+		CREATE TABLE tags (name TEXT PRIMARY KEY, n INTEGER) WITHOUT ROWID;
+		INSERT INTO tags VALUES ('a', 1), ('b', 2);
+	`))
+	require.NoError(t, sqlitex.ExecScript(conn, `
+		INSERT INTO items (id, name, data)
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 2000)
+		SELECT i, 'item ' || i, randomblob(100) FROM n;
+	`))
+	var rootPage int64
+	require.NoError(t, sqlitex.Execute(conn, "SELECT rootpage FROM sqlite_schema WHERE name = 'items';", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			rootPage = stmt.ColumnInt64(0)
+			return nil
+		},
+	}))
+	// Find a leaf page of items in the middle of the table.
+	var leaf int64
+	require.NoError(t, sqlitex.Execute(conn, "SELECT pageno FROM dbstat WHERE name = 'items' AND pagetype = 'leaf' ORDER BY path LIMIT 1 OFFSET 20;", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			leaf = stmt.ColumnInt64(0)
+			return nil
+		},
+	}))
+	require.NoError(t, conn.Close())
+	require.NotZero(t, leaf)
+	require.NotEqual(t, rootPage, leaf)
+
+	f, err := os.OpenFile(corruptPath, os.O_RDWR, 0)
+	require.NoError(t, err)
+	_, err = f.WriteAt([]byte(strings.Repeat("\xff", 4096)), (leaf-1)*4096)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	report, err := backup.Recover(ctx, corruptPath, outPath)
+	require.NoError(t, err)
+	assert.False(t, report.OK)
+	require.Len(t, report.Tables, 2)
+
+	items := report.Tables[0]
+	assert.Equal(t, "items", items.Table)
+	assert.NotEmpty(t, items.Err)
+	require.Len(t, items.Lost, 1)
+	lost := items.Lost[0].Before - items.Lost[0].After - 1
+	assert.Equal(t, int64(2000), items.Rows+lost)
+	assert.Less(t, lost, int64(100))
+	assert.Equal(t, backup.TableRecovery{Table: "tags", Rows: 2}, report.Tables[1])
+	assert.Empty(t, report.Failed)
+
+	verify, err := backup.Verify(ctx, outPath, nil)
+	require.NoError(t, err)
+	assert.True(t, verify.OK)
+	assert.Equal(t, items.Rows, verify.Tables[0].BackupRows)
+
+	_, err = backup.Recover(ctx, corruptPath, outPath)
+	assert.Error(t, err, "an existing output file is not overwritten")
+}
+
+func TestRecover_VirtualTables(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "docs.db")
+	conn, err := sqlite.OpenConn(path, sqlite.OpenReadWrite|sqlite.OpenCreate)
+	require.NoError(t, err)
+	require.NoError(t, sqlitex.ExecScript(conn, `
+		CREATE VIRTUAL TABLE docs USING fts5(body);
+		CREATE TABLE docs_archive (id INTEGER PRIMARY KEY, body TEXT);
+		INSERT INTO docs VALUES ('current');
+		INSERT INTO docs_archive (body) VALUES ('old'), ('older');
+	`))
+	require.NoError(t, conn.Close())
+
+	report, err := backup.Recover(ctx, path, filepath.Join(dir, "recovered.db"))
+	require.NoError(t, err)
+	assert.False(t, report.OK)
+
+	// docs_archive is named like a shadow table of docs but is an ordinary
+	// table, so it is copied.
+	skipped := map[string]string{}
+	for _, table := range report.Tables {
+		if table.Table == "docs_archive" {
+			assert.Equal(t, backup.TableRecovery{Table: "docs_archive", Rows: 2}, table)
+			continue
+		}
+		skipped[table.Table] = table.Err
+	}
+	assert.Len(t, report.Tables, len(skipped)+1)
+	for _, name := range []string{"docs", "docs_data", "docs_idx", "docs_content", "docs_docsize", "docs_config"} {
+		assert.Equal(t, "part of virtual table docs, which is not recovered", skipped[name], name)
+	}
+}
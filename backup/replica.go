@@ -0,0 +1,210 @@
+package backup
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+)
+
+// Replica keeps a database file up to date by replaying the WAL segments a
+// WALShipper archives into target. The replica file must not be written to by
+// anyone else, and readers should reopen it after each Sync.
+type Replica struct {
+	target string
+	path   string
+
+	generation string
+	nextSeq    uint64
+	applied    time.Time
+}
+
+// NewReplica returns a Replica that materializes the archive in target at path.
+func NewReplica(target, path string) *Replica {
+	return &Replica{target: target, path: path}
+}
+
+// Sync applies every segment archived since the previous call. When the
+// shipper has started a new generation, the replica is rebuilt from its snapshot.
+func (r *Replica) Sync(ctx context.Context) error {
+	generations, err := listGenerations(r.target)
+	if err != nil {
+		return err
+	}
+	if len(generations) == 0 {
+		return sqliteutils.ErrNoWALGeneration
+	}
+
+	latest := generations[len(generations)-1]
+	if latest != r.generation {
+		snapshotPath := filepath.Join(r.target, latest, snapshotFileName)
+		if err := copyFile(snapshotPath, r.path); err != nil {
+			return err
+		}
+		r.generation = latest
+		r.nextSeq = 0
+		r.applied, _ = generationTime(latest)
+	}
+
+	segments, err := listSegments(r.target, r.generation)
+	if err != nil {
+		return err
+	}
+	for _, segment := range segments {
+		if segment.seq < r.nextSeq {
+			continue
+		}
+		if segment.seq != r.nextSeq {
+			return sqliteutils.FailedToApplyWALSegmentError(
+				fmt.Errorf("missing segment %d", r.nextSeq), segment.path)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := applySegment(r.path, segment.path); err != nil {
+			return err
+		}
+		r.nextSeq++
+		r.applied = segment.time
+	}
+	return nil
+}
+
+// Pending reports whether Sync would change the replica file: the shipper
+// has started a new generation or archived segments not yet applied.
+func (r *Replica) Pending() (bool, error) {
+	generations, err := listGenerations(r.target)
+	if err != nil {
+		return false, err
+	}
+	if len(generations) == 0 {
+		return false, sqliteutils.ErrNoWALGeneration
+	}
+	if generations[len(generations)-1] != r.generation {
+		return true, nil
+	}
+	segments, err := listSegments(r.target, r.generation)
+	if err != nil {
+		return false, err
+	}
+	return len(segments) > 0 && segments[len(segments)-1].seq >= r.nextSeq, nil
+}
+
+// LastApplied returns when the newest change in the replica file was
+// shipped: the time of the last applied segment, or of the generation
+// snapshot. It is zero before the first Sync.
+func (r *Replica) LastApplied() time.Time {
+	return r.applied
+}
+
+// Run calls Sync every interval until ctx is canceled.
+func (r *Replica) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := r.Sync(ctx); err != nil && !errors.Is(err, sqliteutils.ErrNoWALGeneration) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// applySegment writes every page in an archived segment into the database
+// file at dbPath, truncating the file at each commit frame like a checkpoint would.
+func applySegment(dbPath, segmentPath string) error {
+	data, err := os.ReadFile(segmentPath)
+	if err != nil {
+		return sqliteutils.FailedToApplyWALSegmentError(err, segmentPath)
+	}
+
+	db, err := os.OpenFile(dbPath, os.O_RDWR, 0)
+	if err != nil {
+		return sqliteutils.FailedToApplyWALSegmentError(err, dbPath)
+	}
+	defer db.Close()
+
+	pageSize, err := readPageSize(db)
+	if err != nil {
+		return sqliteutils.FailedToApplyWALSegmentError(err, dbPath)
+	}
+
+	frameSize := walFrameHeaderSize + pageSize
+	if len(data)%frameSize != 0 {
+		return sqliteutils.FailedToApplyWALSegmentError(
+			fmt.Errorf("segment size %d is not a multiple of frame size %d", len(data), frameSize), segmentPath)
+	}
+	for off := 0; off < len(data); off += frameSize {
+		frame := data[off : off+frameSize]
+		pgno := int64(binary.BigEndian.Uint32(frame[0:]))
+		commitSize := int64(binary.BigEndian.Uint32(frame[4:]))
+		if _, err := db.WriteAt(frame[walFrameHeaderSize:], (pgno-1)*int64(pageSize)); err != nil {
+			return sqliteutils.FailedToApplyWALSegmentError(err, dbPath)
+		}
+		if commitSize != 0 {
+			if err := db.Truncate(commitSize * int64(pageSize)); err != nil {
+				return sqliteutils.FailedToApplyWALSegmentError(err, dbPath)
+			}
+		}
+	}
+	return db.Sync()
+}
+
+// readPageSize returns the page size recorded in a database file header.
+func readPageSize(r io.ReaderAt) (int, error) {
+	buf := make([]byte, 2)
+	if _, err := r.ReadAt(buf, 16); err != nil {
+		return 0, err
+	}
+	pageSize := int(binary.BigEndian.Uint16(buf))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 {
+		return 0, fmt.Errorf("invalid page size %d", pageSize)
+	}
+	return pageSize, nil
+}
+
+// copyFile replaces dst with a copy of src, removing any stale WAL or shared
+// memory files left next to dst.
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return sqliteutils.FailedToOpenDatabaseError(err, src)
+	}
+	defer in.Close()
+
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dst + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	tmpPath := dst + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, dst)
+}
@@ -0,0 +1,82 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+)
+
+// RestoreToTime rebuilds the database archived in target as it was at timestamp
+// and writes it to destPath. It starts from the newest generation snapshot taken
+// at or before timestamp and replays that generation's WAL segments that were
+// shipped at or before timestamp, so the result is accurate to within one
+// shipping interval.
+func RestoreToTime(ctx context.Context, target string, timestamp time.Time, destPath string) error {
+	generations, err := listGenerations(target)
+	if err != nil {
+		return err
+	}
+
+	var generation string
+	for _, g := range generations {
+		started, err := generationTime(g)
+		if err != nil {
+			continue
+		}
+		if started.After(timestamp) {
+			break
+		}
+		generation = g
+	}
+	if generation == "" {
+		return fmt.Errorf("%w at or before %s", sqliteutils.ErrNoWALGeneration, timestamp.Format(time.RFC3339))
+	}
+
+	segments, err := listSegments(target, generation)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := destPath + ".restore"
+	if err := copyFile(filepath.Join(target, generation, snapshotFileName), tmpPath); err != nil {
+		return err
+	}
+	defer os.Remove(tmpPath)
+
+	for i, segment := range segments {
+		if segment.time.After(timestamp) {
+			break
+		}
+		if segment.seq != uint64(i) {
+			return sqliteutils.FailedToApplyWALSegmentError(fmt.Errorf("missing segment %d", i), segment.path)
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := applySegment(tmpPath, segment.path); err != nil {
+			return err
+		}
+	}
+
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(destPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return os.Rename(tmpPath, destPath)
+}
+
+// generationTime returns the time a generation's snapshot was taken.
+func generationTime(generation string) (time.Time, error) {
+	ns, err := strconv.ParseInt(generation, 16, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(0, ns), nil
+}
@@ -0,0 +1,52 @@
+package backup
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+)
+
+// Status describes the most recent BackupDatabase call.
+type Status struct {
+	Source      string    `json:"source,omitempty"`
+	Destination string    `json:"destination,omitempty"`
+	Time        time.Time `json:"time"`
+	DurationMs  float64   `json:"duration_ms"`
+	Error       string    `json:"error,omitempty"`
+	Count       int64     `json:"count"`
+	Failures    int64     `json:"failures"`
+}
+
+var (
+	statusLock sync.Mutex
+	lastStatus Status
+)
+
+func init() {
+	sqliteutils.RegisterStats("backup", func() interface{} { return LastStatus() })
+}
+
+// LastStatus returns the status of the most recent BackupDatabase call and
+// the number of calls and failures so far. Time is zero if none has run.
+func LastStatus() Status {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+	return lastStatus
+}
+
+// recordBackup records a BackupDatabase call that started at start.
+func recordBackup(src, dst string, start time.Time, err error) {
+	statusLock.Lock()
+	defer statusLock.Unlock()
+	lastStatus.Source = src
+	lastStatus.Destination = dst
+	lastStatus.Time = start
+	lastStatus.DurationMs = float64(time.Since(start)) / float64(time.Millisecond)
+	lastStatus.Error = ""
+	lastStatus.Count++
+	if err != nil {
+		lastStatus.Error = err.Error()
+		lastStatus.Failures++
+	}
+}
@@ -0,0 +1,156 @@
+package backup
+
+import (
+	"context"
+	"sort"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// VerifyOptions controls how thoroughly Verify checks a backup.
+type VerifyOptions struct {
+	// Quick runs PRAGMA quick_check instead of the slower integrity_check.
+	Quick bool
+	// SourcePath, when set, is opened read-only and its per-table row counts
+	// are compared against the backup.
+	SourcePath string
+}
+
+// TableCount holds the row count of one table in the backup and, when a source
+// was given, in the source database.
+type TableCount struct {
+	Table      string
+	BackupRows int64
+	SourceRows int64
+	Match      bool
+}
+
+// VerifyReport is the result of Verify.
+type VerifyReport struct {
+	Path string
+	// OK is true when the integrity check passed and every table count matched.
+	OK bool
+	// Problems lists the messages reported by the integrity check.
+	Problems []string
+	// Tables holds per-table row counts, sorted by table name.
+	Tables []TableCount
+}
+
+// Verify opens the backup at backupPath read-only, runs an integrity check and
+// optionally compares row counts against the source database.
+// A nil opts runs a full integrity check without a source comparison.
+func Verify(ctx context.Context, backupPath string, opts *VerifyOptions) (*VerifyReport, error) {
+	if opts == nil {
+		opts = &VerifyOptions{}
+	}
+
+	conn, err := sqlite.OpenConn(backupPath, sqlite.OpenReadOnly)
+	if err != nil {
+		return nil, sqliteutils.FailedToOpenDatabaseError(err, backupPath)
+	}
+	defer conn.Close()
+	conn.SetInterrupt(ctx.Done())
+
+	report := &VerifyReport{Path: backupPath}
+
+	pragma := "PRAGMA integrity_check;"
+	if opts.Quick {
+		pragma = "PRAGMA quick_check;"
+	}
+	err = sqlitex.Execute(conn, pragma, &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			if msg := stmt.ColumnText(0); msg != "ok" {
+				report.Problems = append(report.Problems, msg)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, sqliteutils.FailedToVerifyBackupError(err, backupPath)
+	}
+
+	counts, err := tableCounts(conn)
+	if err != nil {
+		return nil, sqliteutils.FailedToVerifyBackupError(err, backupPath)
+	}
+
+	var sourceCounts map[string]int64
+	if opts.SourcePath != "" {
+		srcConn, err := sqlite.OpenConn(opts.SourcePath, sqlite.OpenReadOnly)
+		if err != nil {
+			return nil, sqliteutils.FailedToOpenDatabaseError(err, opts.SourcePath)
+		}
+		defer srcConn.Close()
+		srcConn.SetInterrupt(ctx.Done())
+
+		srcCounts, err := tableCounts(srcConn)
+		if err != nil {
+			return nil, sqliteutils.FailedToVerifyBackupError(err, opts.SourcePath)
+		}
+		sourceCounts = make(map[string]int64, len(srcCounts))
+		for _, c := range srcCounts {
+			sourceCounts[c.Table] = c.BackupRows
+		}
+	}
+
+	report.OK = len(report.Problems) == 0
+	for _, c := range counts {
+		c.Match = true
+		if sourceCounts != nil {
+			src, ok := sourceCounts[c.Table]
+			c.SourceRows = src
+			c.Match = ok && src == c.BackupRows
+			delete(sourceCounts, c.Table)
+		}
+		report.OK = report.OK && c.Match
+		report.Tables = append(report.Tables, c)
+	}
+	// Tables that exist only in the source are missing from the backup.
+	for _, c := range sortedCounts(sourceCounts) {
+		report.OK = false
+		report.Tables = append(report.Tables, TableCount{Table: c.Table, SourceRows: c.BackupRows})
+	}
+
+	return report, nil
+}
+
+// tableCounts returns the row count of every user table, sorted by name.
+func tableCounts(conn *sqlite.Conn) ([]TableCount, error) {
+	var tables []string
+	err := sqlitex.Execute(conn, "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name;", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			tables = append(tables, stmt.ColumnText(0))
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]TableCount, 0, len(tables))
+	for _, table := range tables {
+		c := TableCount{Table: table}
+		err := sqlitex.Execute(conn, "SELECT COUNT(1) FROM "+sqliteutils.QuoteIdentifier(table)+";", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				c.BackupRows = stmt.ColumnInt64(0)
+				return nil
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		counts = append(counts, c)
+	}
+	return counts, nil
+}
+
+func sortedCounts(m map[string]int64) []TableCount {
+	counts := make([]TableCount, 0, len(m))
+	for table, rows := range m {
+		counts = append(counts, TableCount{Table: table, BackupRows: rows})
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Table < counts[j].Table })
+	return counts
+}
@@ -0,0 +1,54 @@
+package backup_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/backup"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	srcPath := filepath.Join(dir, "source.db")
+	dstPath := filepath.Join(dir, "backup.db")
+
+	conn, err := sqlite.OpenConn(srcPath, sqlite.OpenReadWrite|sqlite.OpenCreate)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, sqlitex.ExecScript(conn, `
+		CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);
+		INSERT INTO items (name) VALUES ('a'), ('b'), ('c');
+	`))
+
+	require.NoError(t, backup.BackupDatabase(srcPath, dstPath))
+
+	report, err := backup.Verify(ctx, dstPath, &backup.VerifyOptions{SourcePath: srcPath})
+	require.NoError(t, err)
+	assert.True(t, report.OK)
+	assert.Empty(t, report.Problems)
+	require.Len(t, report.Tables, 1)
+	assert.Equal(t, backup.TableCount{Table: "items", BackupRows: 3, SourceRows: 3, Match: true}, report.Tables[0])
+
+	// Diverge the source from the backup.
+	require.NoError(t, sqlitex.ExecScript(conn, `
+		INSERT INTO items (name) VALUES ('d');
+		CREATE TABLE extra (id INTEGER PRIMARY KEY);
+	`))
+
+	report, err = backup.Verify(ctx, dstPath, &backup.VerifyOptions{Quick: true, SourcePath: srcPath})
+	require.NoError(t, err)
+	assert.False(t, report.OK)
+	require.Len(t, report.Tables, 2)
+	assert.Equal(t, backup.TableCount{Table: "items", BackupRows: 3, SourceRows: 4}, report.Tables[0])
+	assert.Equal(t, backup.TableCount{Table: "extra"}, report.Tables[1])
+
+	report, err = backup.Verify(ctx, dstPath, nil)
+	require.NoError(t, err)
+	assert.True(t, report.OK)
+}
@@ -0,0 +1,518 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// WAL file layout, see https://www.sqlite.org/fileformat2.html#walformat
+const (
+	walHeaderSize      = 32
+	walFrameHeaderSize = 24
+	walMagicLE         = 0x377f0682
+	walMagicBE         = 0x377f0683
+
+	snapshotFileName = "snapshot.db"
+	walDirName       = "wal"
+	segmentExt       = ".wal"
+)
+
+// WALShipperOptions configures a WALShipper.
+type WALShipperOptions struct {
+	// Interval between automatic syncs. Defaults to one second.
+	Interval time.Duration
+	// CheckpointFrames is the WAL length, in frames, after which the shipper
+	// checkpoints the database once everything has been archived. Defaults to 1000.
+	CheckpointFrames int
+	// OnError is called with errors from background syncs.
+	// Defaults to logging them to sqliteutils.Logger().
+	OnError func(error)
+}
+
+// WALShipper archives committed WAL frames of a database into a target
+// directory as they are produced. Each archive generation starts with a full
+// snapshot followed by numbered WAL segments that can be replayed on top of it.
+//
+// The shipper is the only component that should checkpoint the database while
+// it runs; initialize the pool with pool.WithPrepareConn(backup.DisableAutoCheckpoint)
+// so pooled connections never restart the WAL behind its back.
+type WALShipper struct {
+	dbPath string
+	target string
+	opts   WALShipperOptions
+
+	mu         sync.Mutex
+	lockConn   *sqlite.Conn // holds the write lock while frames are copied
+	ckptConn   *sqlite.Conn // takes snapshots and runs checkpoints
+	generation string
+	seq        uint64
+	salt       [2]uint32
+	offset     int64
+	checksum   [2]uint32
+	clean      bool // every shipped frame has also been checkpointed
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// DisableAutoCheckpoint turns off automatic checkpoints on a connection.
+// It is meant to be passed to pool.WithPrepareConn when WAL shipping is enabled.
+func DisableAutoCheckpoint(conn *sqlite.Conn) error {
+	return sqlitex.Execute(conn, "PRAGMA wal_autocheckpoint = 0;", nil)
+}
+
+// StartWALShipping takes an initial snapshot of dbPath into a new generation
+// under target and then ships new WAL frames every opts.Interval until ctx is
+// canceled or Close is called.
+func StartWALShipping(ctx context.Context, dbPath, target string, opts WALShipperOptions) (*WALShipper, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	if opts.CheckpointFrames <= 0 {
+		opts.CheckpointFrames = 1000
+	}
+	if opts.OnError == nil {
+		opts.OnError = func(err error) {
+			sqliteutils.Logger().Error("WAL shipping failed", "db", dbPath, "target", target, "error", err)
+		}
+	}
+
+	s := &WALShipper{dbPath: dbPath, target: target, opts: opts}
+
+	var err error
+	if s.lockConn, err = sqlite.OpenConn(dbPath, sqlite.OpenReadWrite|sqlite.OpenWAL); err != nil {
+		return nil, sqliteutils.FailedToOpenDatabaseError(err, dbPath)
+	}
+	if s.ckptConn, err = sqlite.OpenConn(dbPath, sqlite.OpenReadWrite|sqlite.OpenWAL); err != nil {
+		s.lockConn.Close()
+		return nil, sqliteutils.FailedToOpenDatabaseError(err, dbPath)
+	}
+	for _, conn := range []*sqlite.Conn{s.lockConn, s.ckptConn} {
+		if err := DisableAutoCheckpoint(conn); err != nil {
+			s.closeConns()
+			return nil, err
+		}
+	}
+
+	if err := s.withWriteLock(ctx, s.newGeneration); err != nil {
+		s.closeConns()
+		return nil, err
+	}
+
+	loopCtx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(loopCtx)
+
+	return s, nil
+}
+
+// Generation returns the name of the archive generation currently being written.
+func (s *WALShipper) Generation() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generation
+}
+
+// Sync ships any WAL frames committed since the last sync and checkpoints the
+// database when the WAL has grown past CheckpointFrames.
+func (s *WALShipper) Sync(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.lockConn == nil {
+		return errors.New("wal shipper closed")
+	}
+	return s.withWriteLock(ctx, s.syncLocked)
+}
+
+// Close stops background shipping, performs a final sync and releases the
+// shipper's connections.
+func (s *WALShipper) Close() error {
+	s.cancel()
+	<-s.done
+
+	err := s.Sync(context.Background())
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeConns()
+	return err
+}
+
+func (s *WALShipper) run(ctx context.Context) {
+	defer close(s.done)
+	ticker := time.NewTicker(s.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Sync(ctx); err != nil && ctx.Err() == nil {
+				s.opts.OnError(err)
+			}
+		}
+	}
+}
+
+func (s *WALShipper) closeConns() {
+	for _, conn := range []*sqlite.Conn{s.ckptConn, s.lockConn} {
+		if conn == nil {
+			continue
+		}
+		if err := conn.Close(); err != nil {
+			sqliteutils.Logger().Warn("failed to close WAL shipping connection", "db", s.dbPath, "error", err)
+		}
+	}
+	s.lockConn, s.ckptConn = nil, nil
+}
+
+// withWriteLock runs fn while lockConn holds the database write lock, so no
+// other connection can append to or restart the WAL.
+func (s *WALShipper) withWriteLock(ctx context.Context, fn func() error) (err error) {
+	s.lockConn.SetInterrupt(ctx.Done())
+	s.ckptConn.SetInterrupt(ctx.Done())
+	defer s.lockConn.SetInterrupt(nil)
+	defer s.ckptConn.SetInterrupt(nil)
+
+	if err := sqlitex.Execute(s.lockConn, "BEGIN IMMEDIATE;", nil); err != nil {
+		return fmt.Errorf("failed to acquire write lock: %w", err)
+	}
+	defer func() {
+		if rollbackErr := sqlitex.Execute(s.lockConn, "ROLLBACK;", nil); rollbackErr != nil && err == nil {
+			err = rollbackErr
+		}
+	}()
+	return fn()
+}
+
+// newGeneration snapshots the database into a fresh generation directory and
+// positions the shipper at the end of the current WAL.
+func (s *WALShipper) newGeneration() error {
+	generation := fmt.Sprintf("%016x", time.Now().UnixNano())
+	dir := filepath.Join(s.target, generation)
+	if err := os.MkdirAll(filepath.Join(dir, walDirName), 0o755); err != nil {
+		return sqliteutils.FailedToWriteWALSegmentError(err, dir)
+	}
+
+	start := time.Now()
+	snapshotPath := filepath.Join(dir, snapshotFileName)
+	if err := snapshotConn(s.ckptConn, snapshotPath); err != nil {
+		return err
+	}
+	if err := WriteManifest(snapshotPath, s.dbPath, time.Since(start)); err != nil {
+		return err
+	}
+
+	s.generation = generation
+	s.seq = 0
+	s.salt = [2]uint32{}
+	s.offset = 0
+	s.checksum = [2]uint32{}
+	s.clean = false
+
+	walPath := s.dbPath + "-wal"
+	f, err := os.Open(walPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return sqliteutils.FailedToReadWALError(err, walPath)
+	}
+	defer f.Close()
+
+	hdr, ok, err := readWALHeader(f)
+	if err != nil {
+		return sqliteutils.FailedToReadWALError(err, walPath)
+	}
+	if !ok {
+		return nil
+	}
+	// Everything already committed to the WAL is part of the snapshot.
+	_, end, checksum, err := scanWAL(f, hdr, walHeaderSize, hdr.checksum)
+	if err != nil {
+		return sqliteutils.FailedToReadWALError(err, walPath)
+	}
+	s.salt = hdr.salt
+	s.offset = end
+	s.checksum = checksum
+	return nil
+}
+
+func (s *WALShipper) syncLocked() error {
+	walPath := s.dbPath + "-wal"
+	f, err := os.Open(walPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return sqliteutils.FailedToReadWALError(err, walPath)
+	}
+	defer f.Close()
+
+	hdr, ok, err := readWALHeader(f)
+	if err != nil {
+		return sqliteutils.FailedToReadWALError(err, walPath)
+	}
+	if !ok {
+		return nil
+	}
+
+	if hdr.salt != s.salt {
+		if s.salt != ([2]uint32{}) && !s.clean {
+			// The WAL was restarted by someone else before every frame was
+			// archived, so the chain is broken and a new snapshot is needed.
+			return s.newGeneration()
+		}
+		s.salt = hdr.salt
+		s.offset = walHeaderSize
+		s.checksum = hdr.checksum
+		s.clean = false
+	}
+
+	frames, end, checksum, err := scanWAL(f, hdr, s.offset, s.checksum)
+	if err != nil {
+		return sqliteutils.FailedToReadWALError(err, walPath)
+	}
+	if len(frames) > 0 {
+		name := fmt.Sprintf("%016x-%016x%s", s.seq, time.Now().UnixNano(), segmentExt)
+		path := filepath.Join(s.target, s.generation, walDirName, name)
+		if err := writeFileAtomic(path, frames); err != nil {
+			return sqliteutils.FailedToWriteWALSegmentError(err, path)
+		}
+		s.seq++
+		s.offset = end
+		s.checksum = checksum
+		s.clean = false
+	}
+
+	frameCount := int((s.offset - walHeaderSize) / int64(walFrameHeaderSize+hdr.pageSize))
+	if s.clean || frameCount < s.opts.CheckpointFrames {
+		return nil
+	}
+
+	// A passive checkpoint does not need the write lock, so it can run on the
+	// second connection while lockConn keeps writers out.
+	var logFrames, checkpointed int64 = -1, -2
+	err = sqlitex.Execute(s.ckptConn, "PRAGMA wal_checkpoint(PASSIVE);", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			logFrames = stmt.ColumnInt64(1)
+			checkpointed = stmt.ColumnInt64(2)
+			return nil
+		},
+	})
+	if err != nil {
+		return sqliteutils.FailedToCheckpointError(err)
+	}
+	s.clean = logFrames == checkpointed
+	return nil
+}
+
+// snapshotConn copies the database behind conn page-for-page into path, so
+// archived WAL frames can later be replayed on top of it.
+func snapshotConn(conn *sqlite.Conn, path string) error {
+	tmpPath := path + ".tmp"
+	os.Remove(tmpPath)
+
+	dstConn, err := sqlite.OpenConn(tmpPath, sqlite.OpenReadWrite|sqlite.OpenCreate)
+	if err != nil {
+		return sqliteutils.FailedToOpenDatabaseError(err, tmpPath)
+	}
+
+	b, err := sqlite.NewBackup(dstConn, "main", conn, "main")
+	if err != nil {
+		dstConn.Close()
+		return sqliteutils.FailedToInitBackupError(err)
+	}
+	_, stepErr := b.Step(-1)
+	closeErr := b.Close()
+	if err := dstConn.Close(); err != nil && closeErr == nil {
+		closeErr = err
+	}
+	if stepErr != nil {
+		return sqliteutils.BackupStepFailedError(stepErr)
+	}
+	if closeErr != nil {
+		return closeErr
+	}
+	return os.Rename(tmpPath, path)
+}
+
+type walHeader struct {
+	bigEndian bool
+	pageSize  int
+	salt      [2]uint32
+	checksum  [2]uint32
+}
+
+// readWALHeader reads and validates the WAL header. ok is false when the file
+// is too short or does not yet hold a valid header.
+func readWALHeader(r io.ReaderAt) (hdr walHeader, ok bool, err error) {
+	buf := make([]byte, walHeaderSize)
+	if _, err := r.ReadAt(buf, 0); err != nil {
+		if errors.Is(err, io.EOF) {
+			return hdr, false, nil
+		}
+		return hdr, false, err
+	}
+
+	switch binary.BigEndian.Uint32(buf[0:]) {
+	case walMagicLE:
+	case walMagicBE:
+		hdr.bigEndian = true
+	default:
+		return hdr, false, nil
+	}
+	hdr.pageSize = int(binary.BigEndian.Uint32(buf[8:]))
+	hdr.salt = [2]uint32{binary.BigEndian.Uint32(buf[16:]), binary.BigEndian.Uint32(buf[20:])}
+	hdr.checksum = [2]uint32{binary.BigEndian.Uint32(buf[24:]), binary.BigEndian.Uint32(buf[28:])}
+
+	if walChecksum(hdr.bigEndian, [2]uint32{}, buf[:24]) != hdr.checksum {
+		return hdr, false, nil
+	}
+	return hdr, true, nil
+}
+
+// scanWAL reads frames starting at offset, whose running checksum is ck, and
+// returns the raw bytes of every valid frame up to the last commit frame.
+func scanWAL(r io.ReaderAt, hdr walHeader, offset int64, ck [2]uint32) (frames []byte, end int64, endCk [2]uint32, err error) {
+	frameSize := int64(walFrameHeaderSize + hdr.pageSize)
+	buf := make([]byte, frameSize)
+	var pending bytes.Buffer
+
+	end, endCk = offset, ck
+	for pos := offset; ; pos += frameSize {
+		if _, err := r.ReadAt(buf, pos); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, 0, ck, err
+		}
+		salt := [2]uint32{binary.BigEndian.Uint32(buf[8:]), binary.BigEndian.Uint32(buf[12:])}
+		if salt != hdr.salt {
+			break
+		}
+		ck = walChecksum(hdr.bigEndian, ck, buf[:8])
+		ck = walChecksum(hdr.bigEndian, ck, buf[walFrameHeaderSize:])
+		if ck != [2]uint32{binary.BigEndian.Uint32(buf[16:]), binary.BigEndian.Uint32(buf[20:])} {
+			break
+		}
+
+		pending.Write(buf)
+		if binary.BigEndian.Uint32(buf[4:]) != 0 {
+			// Commit frame: everything so far is a complete transaction.
+			frames = append(frames, pending.Bytes()...)
+			pending.Reset()
+			end, endCk = pos+frameSize, ck
+		}
+	}
+	return frames, end, endCk, nil
+}
+
+// walChecksum extends the cumulative WAL checksum s over data.
+func walChecksum(bigEndian bool, s [2]uint32, data []byte) [2]uint32 {
+	var order binary.ByteOrder = binary.LittleEndian
+	if bigEndian {
+		order = binary.BigEndian
+	}
+	for i := 0; i+8 <= len(data); i += 8 {
+		s[0] += order.Uint32(data[i:]) + s[1]
+		s[1] += order.Uint32(data[i+4:]) + s[0]
+	}
+	return s
+}
+
+// walSegment describes one archived segment file.
+type walSegment struct {
+	seq  uint64
+	time time.Time
+	path string
+}
+
+// listGenerations returns the generation names under target, oldest first.
+func listGenerations(target string) ([]string, error) {
+	entries, err := os.ReadDir(target)
+	if err != nil {
+		return nil, err
+	}
+	var generations []string
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(target, entry.Name(), snapshotFileName)); err != nil {
+			continue
+		}
+		generations = append(generations, entry.Name())
+	}
+	sort.Strings(generations)
+	return generations, nil
+}
+
+// listSegments returns the segments of a generation ordered by sequence number.
+func listSegments(target, generation string) ([]walSegment, error) {
+	dir := filepath.Join(target, generation, walDirName)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var segments []walSegment
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		seqHex, tsHex, found := strings.Cut(strings.TrimSuffix(name, segmentExt), "-")
+		if !found {
+			continue
+		}
+		seq, err := strconv.ParseUint(seqHex, 16, 64)
+		if err != nil {
+			continue
+		}
+		ts, err := strconv.ParseInt(tsHex, 16, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, walSegment{seq: seq, time: time.Unix(0, ts), path: filepath.Join(dir, name)})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].seq < segments[j].seq })
+	return segments, nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it into place so
+// readers never observe a partially written file.
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,119 @@
+package backup_test
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/backup"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func countRows(t *testing.T, path string) int64 {
+	t.Helper()
+	conn, err := sqlite.OpenConn(path, sqlite.OpenReadOnly)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	var count int64
+	err = sqlitex.Execute(conn, "SELECT COUNT(1) FROM items;", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			count = stmt.ColumnInt64(0)
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	return count
+}
+
+func insertItems(t *testing.T, ctx context.Context, from, to int) {
+	t.Helper()
+	for i := from; i < to; i++ {
+		err := exec.Exec(ctx, "INSERT INTO items (name) VALUES ($name);", map[string]interface{}{
+			"$name": fmt.Sprintf("item %d", i),
+		}, nil)
+		require.NoError(t, err)
+	}
+}
+
+func TestWALShipping(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "source.db")
+	target := filepath.Join(dir, "archive")
+	replicaPath := filepath.Join(dir, "replica.db")
+
+	require.NoError(t, pool.InitPool(dbPath, 2, pool.WithPrepareConn(backup.DisableAutoCheckpoint)))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.Exec(ctx, "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);", nil, nil))
+	insertItems(t, ctx, 0, 10)
+
+	shipper, err := backup.StartWALShipping(ctx, dbPath, target, backup.WALShipperOptions{CheckpointFrames: 4})
+	require.NoError(t, err)
+	generation := shipper.Generation()
+
+	replica := backup.NewReplica(target, replicaPath)
+	require.NoError(t, replica.Sync(ctx))
+	assert.Equal(t, int64(10), countRows(t, replicaPath))
+
+	// Each round grows the WAL past CheckpointFrames, so the shipper checkpoints
+	// and the next writer restarts the WAL without breaking the chain.
+	for round := 1; round <= 3; round++ {
+		insertItems(t, ctx, round*10, round*10+10)
+		require.NoError(t, shipper.Sync(ctx))
+		require.NoError(t, replica.Sync(ctx))
+		assert.Equal(t, int64(10+round*10), countRows(t, replicaPath))
+	}
+	assert.Equal(t, generation, shipper.Generation(), "WAL restarts after checkpoints should not start a new generation")
+
+	insertItems(t, ctx, 100, 105)
+	require.NoError(t, shipper.Close())
+	require.NoError(t, replica.Sync(ctx))
+	assert.Equal(t, int64(45), countRows(t, replicaPath))
+}
+
+func TestRestoreToTime(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "source.db")
+	target := filepath.Join(dir, "archive")
+	restorePath := filepath.Join(dir, "restored.db")
+
+	require.NoError(t, pool.InitPool(dbPath, 2, pool.WithPrepareConn(backup.DisableAutoCheckpoint)))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.Exec(ctx, "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);", nil, nil))
+
+	beforeArchive := time.Now()
+	shipper, err := backup.StartWALShipping(ctx, dbPath, target, backup.WALShipperOptions{CheckpointFrames: 4})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, shipper.Close())
+	}()
+
+	var marks []time.Time
+	for round := 0; round < 3; round++ {
+		insertItems(t, ctx, round*10, round*10+10)
+		require.NoError(t, shipper.Sync(ctx))
+		marks = append(marks, time.Now())
+	}
+
+	for i, mark := range marks {
+		require.NoError(t, backup.RestoreToTime(ctx, target, mark, restorePath))
+		assert.Equal(t, int64((i+1)*10), countRows(t, restorePath))
+	}
+
+	err = backup.RestoreToTime(ctx, target, beforeArchive, restorePath)
+	assert.ErrorIs(t, err, sqliteutils.ErrNoWALGeneration)
+}
@@ -0,0 +1,147 @@
+package bench_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+)
+
+// seededRows is the number of users setup inserts.
+const seededRows = 1000
+
+// setup opens the global pool with size connections on a fresh database
+// file holding seededRows users, and closes it when the benchmark ends.
+func setup(b *testing.B, size int) context.Context {
+	b.Helper()
+	ctx := context.Background()
+	uri := "file:" + filepath.Join(b.TempDir(), "bench.db")
+	if err := pool.InitPool(uri, size); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		if err := pool.ClosePool(); err != nil {
+			b.Error(err)
+		}
+	})
+	queries := []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, email TEXT NOT NULL, score REAL);`,
+		`CREATE TABLE files (id INTEGER PRIMARY KEY, data BLOB);`,
+		fmt.Sprintf(`WITH RECURSIVE seq(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM seq WHERE i < %d)
+			INSERT INTO users (id, name, email, score) SELECT i, 'user ' || i, 'user' || i || '@example.com', i * 0.5 FROM seq;`, seededRows),
+	}
+	if err := exec.ExecMulti(ctx, queries, make([]map[string]interface{}, len(queries)), nil); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	return ctx
+}
+
+func BenchmarkExec_SingleRow(b *testing.B) {
+	ctx := setup(b, 1)
+	query := "SELECT id, name, email, score FROM users WHERE id = :id;"
+	for i := 0; i < b.N; i++ {
+		params := map[string]interface{}{":id": i%seededRows + 1}
+		if err := exec.Exec(ctx, query, params, func(int, map[string]interface{}) {}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkExecMultiTx_BatchInsert(b *testing.B) {
+	for _, batch := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("batch=%d", batch), func(b *testing.B) {
+			ctx := setup(b, 1)
+			queries := make([]string, batch)
+			params := make([]map[string]interface{}, batch)
+			for j := range queries {
+				queries[j] = "INSERT INTO users (name, email, score) VALUES (:name, :email, :score);"
+			}
+			for i := 0; i < b.N; i++ {
+				for j := range params {
+					params[j] = map[string]interface{}{":name": "bench", ":email": "bench@example.com", ":score": float64(j)}
+				}
+				if err := exec.ExecMultiTx(ctx, queries, params, nil); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*batch)/b.Elapsed().Seconds(), "rows/s")
+		})
+	}
+}
+
+type user struct {
+	ID    int64
+	Name  string
+	Email string
+	Score float64
+}
+
+func BenchmarkQueryRows_StructScan(b *testing.B) {
+	ctx := setup(b, 1)
+	users := make([]user, 0, seededRows)
+	for i := 0; i < b.N; i++ {
+		users = users[:0]
+		err := exec.QueryRows(ctx, "SELECT id, name, email, score FROM users;", nil, func(row *exec.Row) {
+			users = append(users, user{
+				ID:    row.Values[0].(int64),
+				Name:  row.Values[1].(string),
+				Email: row.Values[2].(string),
+				Score: row.Values[3].(float64),
+			})
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBlob_Stream(b *testing.B) {
+	for _, size := range []int{64 << 10, 1 << 20, 8 << 20} {
+		b.Run(fmt.Sprintf("size=%dKiB", size>>10), func(b *testing.B) {
+			ctx := setup(b, 1)
+			content := bytes.Repeat([]byte{0xa5}, size)
+			rowID, err := exec.CreateBlob(ctx, "files", "data", int64(size), nil)
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.SetBytes(int64(2 * size))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := exec.WriteBlobFromReader(ctx, "files", "data", rowID, 0, bytes.NewReader(content)); err != nil {
+					b.Fatal(err)
+				}
+				if _, err := exec.StreamReadBlob(ctx, "files", "data", rowID, 0, -1, io.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkQuery_ConcurrentReaders(b *testing.B) {
+	for _, size := range []int{1, 4, 8, 16} {
+		b.Run(fmt.Sprintf("pool=%d", size), func(b *testing.B) {
+			ctx := setup(b, size)
+			b.SetParallelism(size)
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					i++
+					params := map[string]interface{}{":min": i % seededRows}
+					err := exec.Query(ctx, "SELECT id, name FROM users WHERE id > :min LIMIT 20;", params, func([]string, []interface{}) {})
+					if err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,7 @@
+// Package bench holds benchmarks of the exec and pool layers, run with
+//
+//	go test ./bench -bench . -benchmem
+//
+// Compare results across releases, for example with benchstat, to catch
+// performance regressions. The package has no API.
+package bench
@@ -0,0 +1,307 @@
+// Package cache is an opt-in, in-process cache of query results keyed by SQL
+// text and parameters.
+//
+// Entries expire after a TTL and are invalidated per table: Cache records the
+// tables each query reads, including those behind views, and Invalidate drops
+// every entry that read a table. InvalidateOn wires this to a cdc.Capture so
+// changes committed by any connection or process invalidate the cache.
+//
+// Only read-only statements are cached. Queries whose results depend on more
+// than table contents, such as those calling random() or datetime('now'),
+// are served from the cache until they expire.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/cdc"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+)
+
+// Options configures a Cache.
+type Options struct {
+	// TTL bounds how long a result is served. Defaults to one minute.
+	TTL time.Duration
+	// MaxEntries bounds the number of cached results; the least recently
+	// used is evicted first. Defaults to 1000.
+	MaxEntries int
+}
+
+// Result holds the rows of a query. Results are shared between callers and
+// must not be modified.
+type Result struct {
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// Stats counts cache lookups.
+type Stats struct {
+	Hits    int64
+	Misses  int64
+	Entries int
+}
+
+type entry struct {
+	key     string
+	result  *Result
+	tables  []string
+	expires time.Time
+	elem    *list.Element
+}
+
+// Cache caches query results. It is safe for concurrent use.
+type Cache struct {
+	opts Options
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	byTable map[string]map[string]*entry
+	lru     *list.List
+	// generation is incremented by every invalidation, so a query that
+	// raced with one does not store its possibly stale result.
+	generation uint64
+	hits       int64
+	misses     int64
+}
+
+// New returns an empty Cache.
+func New(opts Options) *Cache {
+	if opts.TTL <= 0 {
+		opts.TTL = time.Minute
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = 1000
+	}
+	return &Cache{
+		opts:    opts,
+		entries: make(map[string]*entry),
+		byTable: make(map[string]map[string]*entry),
+		lru:     list.New(),
+	}
+}
+
+// Query returns the result of query with params, from the cache when a fresh
+// entry exists and otherwise by running it on the global pool. Statements
+// that write are rejected.
+func (c *Cache) Query(ctx context.Context, query string, params map[string]interface{}) (*Result, error) {
+	key, err := cacheKey(query, params)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && time.Now().Before(e.expires) {
+		c.lru.MoveToFront(e.elem)
+		c.hits++
+		c.mu.Unlock()
+		return e.result, nil
+	}
+	c.misses++
+	generation := c.generation
+	c.mu.Unlock()
+
+	p, err := pool.GetPool()
+	if err != nil {
+		return nil, sqliteutils.FailedToGetPoolError(err)
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		return nil, sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+
+	tables, err := readTables(conn, query)
+	if err != nil {
+		return nil, err
+	}
+	result := &Result{}
+	err = exec.QueryConn(conn, query, params, func(columns []string, values []interface{}) {
+		result.Columns = columns
+		result.Rows = append(result.Rows, values)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation == generation {
+		c.store(key, result, tables)
+	}
+	return result, nil
+}
+
+// store adds or replaces the entry for key. The caller holds c.mu.
+func (c *Cache) store(key string, result *Result, tables []string) {
+	if old, ok := c.entries[key]; ok {
+		c.remove(old)
+	}
+	e := &entry{key: key, result: result, tables: tables, expires: time.Now().Add(c.opts.TTL)}
+	e.elem = c.lru.PushFront(e)
+	c.entries[key] = e
+	for _, table := range tables {
+		if c.byTable[table] == nil {
+			c.byTable[table] = make(map[string]*entry)
+		}
+		c.byTable[table][key] = e
+	}
+	for c.lru.Len() > c.opts.MaxEntries {
+		c.remove(c.lru.Back().Value.(*entry))
+	}
+}
+
+// remove deletes e from every index. The caller holds c.mu.
+func (c *Cache) remove(e *entry) {
+	c.lru.Remove(e.elem)
+	delete(c.entries, e.key)
+	for _, table := range e.tables {
+		delete(c.byTable[table], e.key)
+		if len(c.byTable[table]) == 0 {
+			delete(c.byTable, table)
+		}
+	}
+}
+
+// Invalidate drops every cached result that read one of tables. Table names
+// are matched case-insensitively.
+func (c *Cache) Invalidate(tables ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for _, table := range tables {
+		for _, e := range c.byTable[strings.ToLower(table)] {
+			c.remove(e)
+		}
+	}
+}
+
+// InvalidateOn invalidates the cache for every change delivered by capture.
+// Start the capture on the tables the cached queries read.
+func (c *Cache) InvalidateOn(capture *cdc.Capture) {
+	capture.OnChange(func(e cdc.Event) {
+		c.Invalidate(e.Table)
+	})
+}
+
+// Clear drops every cached result.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.entries = make(map[string]*entry)
+	c.byTable = make(map[string]map[string]*entry)
+	c.lru.Init()
+}
+
+// Stats returns the cache's hit and miss counts and current size.
+func (c *Cache) Stats() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return Stats{Hits: c.hits, Misses: c.misses, Entries: len(c.entries)}
+}
+
+// readTables prepares query with an authorizer that records the tables it
+// reads, rejecting statements that write.
+func readTables(conn *sqlite.Conn, query string) ([]string, error) {
+	seen := make(map[string]bool)
+	var tables []string
+	writes := false
+	err := conn.SetAuthorizer(sqlite.AuthorizeFunc(func(action sqlite.Action) sqlite.AuthResult {
+		switch action.Type() {
+		case sqlite.OpRead:
+			if table := strings.ToLower(action.Table()); !seen[table] {
+				seen[table] = true
+				tables = append(tables, table)
+			}
+		case sqlite.OpSelect, sqlite.OpFunction, sqlite.OpRecursive:
+		default:
+			writes = true
+		}
+		return sqlite.AuthResultOK
+	}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to set authorizer: %w", err)
+	}
+	defer conn.SetAuthorizer(nil)
+
+	// A transient statement always runs the authorizer; Prepare may return a
+	// cached statement that skips it.
+	stmt, _, err := conn.PrepareTransient(strings.TrimSpace(query))
+	if err != nil {
+		return nil, fmt.Errorf("SQL preparation error for query '%s': %w", query, err)
+	}
+	stmt.Finalize()
+	if writes {
+		return nil, fmt.Errorf("query '%s' is not read-only and cannot be cached", query)
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
+
+// cacheKey identifies query with params independent of map order. Params
+// are keyed by the value that is bound, as exec binds it, so that a reused
+// pointer or sql.Null* value is keyed by what it holds now.
+func cacheKey(query string, params map[string]interface{}) (string, error) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(query))
+	for _, name := range names {
+		v, err := keyValue(params[name])
+		if err != nil {
+			return "", fmt.Errorf("parameter %s: %w", name, err)
+		}
+		fmt.Fprintf(&b, "\x00%s=%T:%v", name, v, v)
+	}
+	return b.String(), nil
+}
+
+// keyValue returns the value exec binds for v: nil pointers are nil, other
+// pointers and driver.Valuer values are replaced by what they hold, and
+// times and exec.JSON values by the text they bind as.
+func keyValue(v interface{}) (interface{}, error) {
+	for v != nil {
+		val := reflect.ValueOf(v)
+		if val.Kind() == reflect.Ptr && val.IsNil() {
+			return nil, nil
+		}
+		if valuer, ok := v.(driver.Valuer); ok {
+			var err error
+			if v, err = valuer.Value(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if val.Kind() == reflect.Ptr {
+			v = val.Elem().Interface()
+			continue
+		}
+		switch v := v.(type) {
+		case time.Time:
+			return v.UTC().Format(time.RFC3339Nano), nil
+		case exec.JSONParam:
+			data, err := json.Marshal(v.Value)
+			if err != nil {
+				return nil, err
+			}
+			return string(data), nil
+		}
+		break
+	}
+	return v, nil
+}
@@ -0,0 +1,137 @@
+package cache_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/cache"
+	"github.com/dropsite-ai/sqliteutils/cdc"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const migration = `
+	CREATE TABLE orders (id INTEGER PRIMARY KEY, total INTEGER NOT NULL);
+	CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+	CREATE VIEW order_totals AS SELECT SUM(total) AS total FROM orders;
+	INSERT INTO orders (total) VALUES (10), (20);
+	INSERT INTO users (name) VALUES ('Alice');
+`
+
+func total(t *testing.T, c *cache.Cache, query string) int64 {
+	t.Helper()
+	result, err := c.Query(context.Background(), query, nil)
+	require.NoError(t, err)
+	require.Len(t, result.Rows, 1)
+	return result.Rows[0][0].(int64)
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	c := cache.New(cache.Options{MaxEntries: 2})
+	const sum = "SELECT total FROM order_totals"
+	assert.Equal(t, int64(30), total(t, c, sum))
+
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO orders (total) VALUES (5);", nil, nil))
+	assert.Equal(t, int64(30), total(t, c, sum), "served from cache")
+
+	// Invalidating an unrelated table keeps the entry; the view's base table drops it.
+	c.Invalidate("users")
+	assert.Equal(t, int64(30), total(t, c, sum))
+	c.Invalidate("ORDERS")
+	assert.Equal(t, int64(35), total(t, c, sum))
+	assert.Equal(t, cache.Stats{Hits: 2, Misses: 2, Entries: 1}, c.Stats())
+
+	// Params are part of the key, independent of map order.
+	q := "SELECT name FROM users WHERE id = $id AND name <> $skip"
+	r1, err := c.Query(ctx, q, map[string]interface{}{"$id": 1, "$skip": ""})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"name"}, r1.Columns)
+	assert.Equal(t, [][]interface{}{{"Alice"}}, r1.Rows)
+	r2, err := c.Query(ctx, q, map[string]interface{}{"$skip": "", "$id": 1})
+	require.NoError(t, err)
+	assert.Same(t, r1, r2)
+	r3, err := c.Query(ctx, q, map[string]interface{}{"$id": 2, "$skip": ""})
+	require.NoError(t, err)
+	assert.Empty(t, r3.Rows)
+
+	// MaxEntries evicts the least recently used entry.
+	assert.Equal(t, 2, c.Stats().Entries)
+
+	// Pointers and sql.Null* values are keyed by what they hold when bound.
+	id := int64(1)
+	byID := "SELECT name FROM users WHERE id = $id"
+	r4, err := c.Query(ctx, byID, map[string]interface{}{"$id": &id})
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"Alice"}}, r4.Rows)
+	id = 2
+	r5, err := c.Query(ctx, byID, map[string]interface{}{"$id": &id})
+	require.NoError(t, err)
+	assert.Empty(t, r5.Rows)
+	r6, err := c.Query(ctx, byID, map[string]interface{}{"$id": sql.NullInt64{Int64: 1, Valid: true}})
+	require.NoError(t, err)
+	assert.Same(t, r4, r6)
+
+	_, err = c.Query(ctx, "DELETE FROM users", nil)
+	assert.Error(t, err)
+	assert.Equal(t, 1, countUsers(t))
+
+	c.Clear()
+	assert.Zero(t, c.Stats().Entries)
+}
+
+func countUsers(t *testing.T) int {
+	t.Helper()
+	n := 0
+	require.NoError(t, exec.Exec(context.Background(), "SELECT id FROM users;", nil, func(int, map[string]interface{}) { n++ }))
+	return n
+}
+
+func TestTTL(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	c := cache.New(cache.Options{TTL: 20 * time.Millisecond})
+	const sum = "SELECT SUM(total) FROM orders"
+	assert.Equal(t, int64(30), total(t, c, sum))
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO orders (total) VALUES (5);", nil, nil))
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, int64(35), total(t, c, sum))
+}
+
+func TestInvalidateOn(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "cache.db"), 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.Exec(ctx, "CREATE TABLE orders (id INTEGER PRIMARY KEY, total INTEGER NOT NULL);", nil, nil))
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO orders (total) VALUES (10);", nil, nil))
+
+	capture, err := cdc.Start(ctx, cdc.Options{Tables: []string{"orders"}, Interval: 5 * time.Millisecond})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, capture.Close())
+	}()
+	c := cache.New(cache.Options{TTL: time.Hour})
+	c.InvalidateOn(capture)
+
+	const sum = "SELECT SUM(total) FROM orders"
+	assert.Equal(t, int64(10), total(t, c, sum))
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO orders (total) VALUES (5);", nil, nil))
+	assert.Eventually(t, func() bool { return total(t, c, sum) == 15 }, 5*time.Second, 10*time.Millisecond)
+}
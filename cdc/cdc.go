@@ -0,0 +1,424 @@
+// Package cdc captures row changes to selected tables and delivers them to
+// Go callbacks and channels.
+//
+// SQLite's update and commit hooks are not exposed by the driver this module
+// uses, so changes are recorded by AFTER INSERT/UPDATE/DELETE triggers into
+// the _cdc_changes table, in the same transaction as the change itself. A
+// Capture polls PRAGMA data_version and reads new rows from that table, so
+// only committed changes are delivered, in commit order, including changes
+// made by other processes.
+package cdc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/internal/triggers"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Op is the kind of change an Event records.
+type Op string
+
+const (
+	Insert Op = "insert"
+	Update Op = "update"
+	Delete Op = "delete"
+)
+
+// Event is a committed change to one row.
+type Event struct {
+	// ID increases with every recorded change.
+	ID    int64
+	Table string
+	RowID int64
+	Op    Op
+	// Old and New hold the row before and after the change when the table
+	// was enabled with values. Blobs are hex encoded.
+	Old  map[string]interface{}
+	New  map[string]interface{}
+	Time time.Time
+}
+
+// Options configures a Capture.
+type Options struct {
+	// Tables are enabled for capture when the Capture starts.
+	Tables []string
+	// Values records the old and new column values of each change.
+	Values bool
+	// Interval is how often PRAGMA data_version is polled. Defaults to 100ms.
+	Interval time.Duration
+	// FromStart delivers changes recorded before Start; otherwise only
+	// changes committed after Start are delivered.
+	FromStart bool
+	// Prune deletes changes from _cdc_changes once they have been delivered.
+	// Leave it unset when several captures read the same database.
+	Prune bool
+	// BatchSize bounds how many changes are read per query. Defaults to 1000.
+	BatchSize int
+}
+
+const createChangesTable = `CREATE TABLE IF NOT EXISTS _cdc_changes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tbl TEXT NOT NULL,
+	row_id INTEGER NOT NULL,
+	op TEXT NOT NULL,
+	old_values TEXT,
+	new_values TEXT,
+	ts INTEGER NOT NULL
+);`
+
+// nowMillis is a SQL expression for the current Unix time in milliseconds.
+const nowMillis = "CAST((julianday('now') - 2440587.5) * 86400000 AS INTEGER)"
+
+// Enable creates _cdc_changes and installs capture triggers on tables. It is
+// idempotent; to change whether values are recorded, Disable and Enable again.
+func Enable(ctx context.Context, tables []string, values bool) error {
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		return enable(conn, tables, values)
+	})
+}
+
+// Disable removes the capture triggers from tables. Recorded changes are kept.
+func Disable(ctx context.Context, tables []string) error {
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		for _, table := range tables {
+			for _, op := range []Op{Insert, Update, Delete} {
+				query := "DROP TRIGGER IF EXISTS " + sqliteutils.QuoteIdentifier(triggerName(table, op)) + ";"
+				if err := sqlitex.ExecuteTransient(conn, query, nil); err != nil {
+					return fmt.Errorf("failed to drop cdc trigger on %s: %w", table, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func enable(conn *sqlite.Conn, tables []string, values bool) (err error) {
+	endFn, err := sqlitex.ImmediateTransaction(conn)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer endFn(&err)
+
+	if err := sqlitex.ExecuteTransient(conn, createChangesTable, nil); err != nil {
+		return fmt.Errorf("failed to create _cdc_changes table: %w", err)
+	}
+	for _, table := range tables {
+		columns, err := triggers.TableColumns(conn, table, nil)
+		if err != nil {
+			return err
+		}
+		for _, op := range []Op{Insert, Update, Delete} {
+			if err := sqlitex.ExecuteTransient(conn, triggerSQL(table, op, columns, values), nil); err != nil {
+				return fmt.Errorf("failed to create cdc trigger on %s: %w", table, err)
+			}
+		}
+	}
+	return nil
+}
+
+func triggerName(table string, op Op) string {
+	return "_cdc_" + table + "_" + string(op)
+}
+
+// triggerSQL builds the AFTER trigger recording op on table.
+func triggerSQL(table string, op Op, columns []string, values bool) string {
+	oldValues, newValues := "NULL", "NULL"
+	if values {
+		if op != Insert {
+			oldValues = triggers.JSONObject("OLD", columns)
+		}
+		if op != Delete {
+			newValues = triggers.JSONObject("NEW", columns)
+		}
+	}
+	ref := "NEW"
+	if op == Delete {
+		ref = "OLD"
+	}
+	quotedTable := sqliteutils.QuoteIdentifier(table)
+	return fmt.Sprintf(`CREATE TRIGGER IF NOT EXISTS %s AFTER %s ON %s BEGIN
+	INSERT INTO _cdc_changes (tbl, row_id, op, old_values, new_values, ts)
+	VALUES (%s, %s.rowid, '%s', %s, %s, %s);
+END;`, sqliteutils.QuoteIdentifier(triggerName(table, op)), strings.ToUpper(string(op)), quotedTable,
+		triggers.QuoteLiteral(table), ref, op, oldValues, newValues, nowMillis)
+}
+
+// Capture delivers recorded changes to its subscribers.
+type Capture struct {
+	opts Options
+
+	mu       sync.Mutex
+	handlers []func(Event)
+	channels []chan Event
+
+	// subscribed is closed by the first OnChange or Subscribe, so that no
+	// change is delivered before anyone receives it.
+	subscribed     chan struct{}
+	subscribedOnce sync.Once
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// Start enables capture on opts.Tables and starts delivering changes once
+// the first callback or channel is registered with OnChange or Subscribe.
+// The Capture holds one pooled connection until Close.
+func Start(ctx context.Context, opts Options) (*Capture, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = 100 * time.Millisecond
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 1000
+	}
+
+	p, err := pool.GetPool()
+	if err != nil {
+		return nil, sqliteutils.FailedToGetPoolError(err)
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		return nil, sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	if err := enable(conn, opts.Tables, opts.Values); err != nil {
+		p.Put(conn)
+		return nil, err
+	}
+
+	var lastID int64
+	if !opts.FromStart {
+		err := sqlitex.ExecuteTransient(conn, "SELECT COALESCE(MAX(id), 0) FROM _cdc_changes;", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				lastID = stmt.ColumnInt64(0)
+				return nil
+			},
+		})
+		if err != nil {
+			p.Put(conn)
+			return nil, fmt.Errorf("failed to read _cdc_changes: %w", err)
+		}
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	c := &Capture{opts: opts, cancel: cancel, done: make(chan struct{}), subscribed: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		defer p.Put(conn)
+		conn.SetInterrupt(runCtx.Done())
+		defer conn.SetInterrupt(nil)
+		c.err = c.run(runCtx, conn, lastID)
+	}()
+	return c, nil
+}
+
+// OnChange registers fn to be called with every change, in order. Callbacks
+// run on the Capture's goroutine and delay delivery while they run.
+func (c *Capture) OnChange(fn func(Event)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.handlers = append(c.handlers, fn)
+	c.subscribedOnce.Do(func() { close(c.subscribed) })
+}
+
+// Subscribe returns a channel receiving every change, in order. Delivery
+// blocks while the channel is full. The channel is closed by Close.
+func (c *Capture) Subscribe(buffer int) <-chan Event {
+	ch := make(chan Event, buffer)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.channels = append(c.channels, ch)
+	c.subscribedOnce.Do(func() { close(c.subscribed) })
+	return ch
+}
+
+// Close stops delivery, closes subscribed channels and returns the error that
+// stopped the Capture, if any.
+func (c *Capture) Close() error {
+	c.cancel()
+	<-c.done
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, ch := range c.channels {
+		close(ch)
+	}
+	c.channels = nil
+	return c.err
+}
+
+func (c *Capture) run(ctx context.Context, conn *sqlite.Conn, lastID int64) error {
+	select {
+	case <-ctx.Done():
+		return nil
+	case <-c.subscribed:
+	}
+
+	ticker := time.NewTicker(c.opts.Interval)
+	defer ticker.Stop()
+	version := int64(-1)
+	for {
+		v, err := dataVersion(conn)
+		if err != nil {
+			return ignoreCanceled(ctx, err)
+		}
+		if v != version {
+			version = v
+			for {
+				events, err := c.read(conn, lastID)
+				if err != nil {
+					return ignoreCanceled(ctx, err)
+				}
+				for _, e := range events {
+					if !c.deliver(ctx, e) {
+						return nil
+					}
+					lastID = e.ID
+				}
+				if c.opts.Prune && len(events) > 0 {
+					err := sqlitex.ExecuteTransient(conn, "DELETE FROM _cdc_changes WHERE id <= ?;", &sqlitex.ExecOptions{
+						Args: []interface{}{lastID},
+					})
+					if err != nil {
+						return ignoreCanceled(ctx, fmt.Errorf("failed to prune _cdc_changes: %w", err))
+					}
+				}
+				if len(events) < c.opts.BatchSize {
+					break
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// read returns up to BatchSize changes recorded after lastID.
+func (c *Capture) read(conn *sqlite.Conn, lastID int64) ([]Event, error) {
+	return readChanges(conn, lastID, nil, c.opts.BatchSize)
+}
+
+// Changes returns up to limit changes recorded after afterID, in order. When
+// tables is not empty, only changes to those tables are returned. Together
+// with an Event's ID it lets a consumer that was offline catch up on what it
+// missed, as long as the changes have not been pruned.
+func Changes(ctx context.Context, afterID int64, tables []string, limit int) ([]Event, error) {
+	var events []Event
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) (err error) {
+		events, err = readChanges(conn, afterID, tables, limit)
+		return err
+	})
+	return events, err
+}
+
+// Range returns the ID of the oldest change still in _cdc_changes and of the
+// latest change ever recorded. When every change has been pruned, oldest is
+// latest+1. Both are zero if capture was never enabled.
+func Range(ctx context.Context) (oldest, latest int64, err error) {
+	err = pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		return sqlitex.ExecuteTransient(conn, `SELECT
+			(SELECT MIN(id) FROM _cdc_changes),
+			(SELECT seq FROM sqlite_sequence WHERE name = '_cdc_changes');`, &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				latest = stmt.ColumnInt64(1)
+				oldest = latest + 1
+				if stmt.ColumnType(0) != sqlite.TypeNull {
+					oldest = stmt.ColumnInt64(0)
+				}
+				return nil
+			},
+		})
+	})
+	if err != nil {
+		if strings.Contains(err.Error(), "no such table") {
+			return 0, 0, nil
+		}
+		return 0, 0, fmt.Errorf("failed to read _cdc_changes: %w", err)
+	}
+	return oldest, latest, nil
+}
+
+// readChanges returns up to limit changes to tables, or to all tables when
+// it is empty, recorded after lastID.
+func readChanges(conn *sqlite.Conn, lastID int64, tables []string, limit int) ([]Event, error) {
+	tableFilter, err := json.Marshal(tables)
+	if err != nil {
+		return nil, err
+	}
+	var events []Event
+	err = sqlitex.Execute(conn, `SELECT id, tbl, row_id, op, old_values, new_values, ts
+		FROM _cdc_changes WHERE id > ?1 AND (json_array_length(?2) = 0 OR tbl IN (SELECT value FROM json_each(?2))) ORDER BY id LIMIT ?3;`, &sqlitex.ExecOptions{
+		Args: []interface{}{lastID, string(tableFilter), limit},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			e := Event{
+				ID:    stmt.ColumnInt64(0),
+				Table: stmt.ColumnText(1),
+				RowID: stmt.ColumnInt64(2),
+				Op:    Op(stmt.ColumnText(3)),
+				Time:  time.UnixMilli(stmt.ColumnInt64(6)),
+			}
+			var err error
+			if e.Old, err = triggers.DecodeValues(stmt, 4); err != nil {
+				return err
+			}
+			if e.New, err = triggers.DecodeValues(stmt, 5); err != nil {
+				return err
+			}
+			events = append(events, e)
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read _cdc_changes: %w", err)
+	}
+	return events, nil
+}
+
+// deliver hands e to every subscriber and reports whether the Capture is still running.
+func (c *Capture) deliver(ctx context.Context, e Event) bool {
+	c.mu.Lock()
+	handlers := append([]func(Event){}, c.handlers...)
+	channels := append([]chan Event{}, c.channels...)
+	c.mu.Unlock()
+
+	for _, fn := range handlers {
+		fn(e)
+	}
+	for _, ch := range channels {
+		select {
+		case ch <- e:
+		case <-ctx.Done():
+			return false
+		}
+	}
+	return true
+}
+
+func dataVersion(conn *sqlite.Conn) (int64, error) {
+	var version int64
+	err := sqlitex.ExecuteTransient(conn, "PRAGMA data_version;", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			version = stmt.ColumnInt64(0)
+			return nil
+		},
+	})
+	return version, err
+}
+
+// ignoreCanceled drops errors caused by Close interrupting the connection.
+func ignoreCanceled(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
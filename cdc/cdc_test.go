@@ -0,0 +1,118 @@
+package cdc_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/cdc"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, ch <-chan cdc.Event) cdc.Event {
+	t.Helper()
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for change event")
+		return cdc.Event{}
+	}
+}
+
+func TestCapture(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "cdc.db"), 3))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.Exec(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, avatar BLOB);", nil, nil))
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO users (name) VALUES ('Before');", nil, nil))
+
+	c, err := cdc.Start(ctx, cdc.Options{Tables: []string{"users"}, Values: true, Interval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	events := c.Subscribe(10)
+	var called []cdc.Op
+	c.OnChange(func(e cdc.Event) { called = append(called, e.Op) })
+
+	require.NoError(t, exec.ExecMultiTx(ctx, []string{
+		"INSERT INTO users (id, name, avatar) VALUES (2, 'Alice', x'0102');",
+		"UPDATE users SET name = 'Alicia' WHERE id = 2;",
+		"DELETE FROM users WHERE id = 2;",
+	}, []map[string]interface{}{nil, nil, nil}, nil))
+
+	e := receive(t, events)
+	assert.Equal(t, cdc.Insert, e.Op)
+	assert.Equal(t, "users", e.Table)
+	assert.Equal(t, int64(2), e.RowID)
+	assert.Nil(t, e.Old)
+	assert.Equal(t, map[string]interface{}{"id": int64(2), "name": "Alice", "avatar": "0102"}, e.New)
+
+	e = receive(t, events)
+	assert.Equal(t, cdc.Update, e.Op)
+	assert.Equal(t, "Alice", e.Old["name"])
+	assert.Equal(t, "Alicia", e.New["name"])
+
+	e = receive(t, events)
+	assert.Equal(t, cdc.Delete, e.Op)
+	assert.Equal(t, "Alicia", e.Old["name"])
+	assert.Nil(t, e.New)
+
+	require.NoError(t, c.Close())
+	assert.Equal(t, []cdc.Op{cdc.Insert, cdc.Update, cdc.Delete}, called)
+	_, open := <-events
+	assert.False(t, open)
+
+	// Rolled back changes are never delivered, and FromStart replays history.
+	assert.Error(t, exec.ExecMultiTx(ctx, []string{
+		"INSERT INTO users (name) VALUES ('Ghost');",
+		"SELECT * FROM missing;",
+	}, []map[string]interface{}{nil, nil}, nil))
+	c, err = cdc.Start(ctx, cdc.Options{Tables: []string{"users"}, FromStart: true, Prune: true, Interval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	events = c.Subscribe(10)
+	for _, op := range []cdc.Op{cdc.Insert, cdc.Update, cdc.Delete} {
+		assert.Equal(t, op, receive(t, events).Op)
+	}
+	require.NoError(t, c.Close())
+
+	var remaining int64
+	require.NoError(t, exec.Exec(ctx, "SELECT COUNT(*) AS n FROM _cdc_changes;", nil, func(_ int, row map[string]interface{}) {
+		remaining = row["n"].(int64)
+	}))
+	assert.Zero(t, remaining)
+
+	require.NoError(t, cdc.Disable(ctx, []string{"users"}))
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO users (name) VALUES ('After');", nil, nil))
+	require.NoError(t, exec.Exec(ctx, "SELECT COUNT(*) AS n FROM _cdc_changes;", nil, func(_ int, row map[string]interface{}) {
+		remaining = row["n"].(int64)
+	}))
+	assert.Zero(t, remaining)
+
+	assert.Error(t, cdc.Enable(ctx, []string{"missing"}, false))
+}
+
+func TestCapture_SubscribeAfterStart(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "cdc.db"), 3))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.Exec(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);", nil, nil))
+
+	c, err := cdc.Start(ctx, cdc.Options{Tables: []string{"users"}, Interval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO users (name) VALUES ('Alice');", nil, nil))
+
+	// Changes committed before the first subscriber are kept for it.
+	time.Sleep(50 * time.Millisecond)
+	events := c.Subscribe(10)
+	e := receive(t, events)
+	assert.Equal(t, cdc.Insert, e.Op)
+	assert.Equal(t, int64(1), e.RowID)
+	require.NoError(t, c.Close())
+}
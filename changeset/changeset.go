@@ -0,0 +1,146 @@
+// Package changeset records and applies SQLite session extension changesets.
+//
+// A changeset is a compact binary description of the rows inserted, updated
+// and deleted by a transaction. Record captures one from work done on the
+// global pool, and Apply replays it on another database, resolving rows that
+// no longer match with a conflict handler. Together they are the building
+// block for syncing offline copies of a database with a server copy.
+//
+// Only tables with a PRIMARY KEY are recorded.
+package changeset
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// ConflictHandler decides how to resolve a change that does not apply cleanly.
+// See sqlite.ConflictHandler.
+type ConflictHandler = sqlite.ConflictHandler
+
+// Abort rolls back the whole changeset on the first conflict.
+func Abort(sqlite.ConflictType, *sqlite.ChangesetIterator) sqlite.ConflictAction {
+	return sqlite.ChangesetAbort
+}
+
+// Omit skips conflicting changes and keeps the destination's rows.
+func Omit(sqlite.ConflictType, *sqlite.ChangesetIterator) sqlite.ConflictAction {
+	return sqlite.ChangesetOmit
+}
+
+// Replace overwrites conflicting destination rows with the changeset's rows.
+// Changes to rows that no longer exist, and changes violating constraints,
+// are skipped.
+func Replace(conflict sqlite.ConflictType, _ *sqlite.ChangesetIterator) sqlite.ConflictAction {
+	switch conflict {
+	case sqlite.ChangesetData, sqlite.ChangesetConflict:
+		return sqlite.ChangesetReplace
+	default:
+		return sqlite.ChangesetOmit
+	}
+}
+
+// Record runs fn in an immediate transaction on a pooled connection and
+// returns the changeset of its changes to tables, or to every table when
+// tables is empty. If fn returns an error the transaction is rolled back and
+// no changeset is returned.
+func Record(ctx context.Context, tables []string, fn func(conn *sqlite.Conn) error) ([]byte, error) {
+	p, err := pool.GetPool()
+	if err != nil {
+		return nil, sqliteutils.FailedToGetPoolError(err)
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		return nil, sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+	return RecordConn(conn, tables, fn)
+}
+
+// RecordConn is Record on a connection the caller already holds.
+func RecordConn(conn *sqlite.Conn, tables []string, fn func(conn *sqlite.Conn) error) (changeset []byte, err error) {
+	session, err := conn.CreateSession("")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	defer session.Delete()
+
+	if len(tables) == 0 {
+		tables = []string{""}
+	}
+	for _, table := range tables {
+		if err := session.Attach(table); err != nil {
+			return nil, err
+		}
+	}
+
+	endFn, err := sqlitex.ImmediateTransaction(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	err = fn(conn)
+	endFn(&err)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := session.WriteChangeset(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Apply applies changeset to the database behind the global pool in a single
+// transaction. Conflicts are resolved by onConflict, which defaults to Abort.
+func Apply(ctx context.Context, changeset []byte, onConflict ConflictHandler) error {
+	p, err := pool.GetPool()
+	if err != nil {
+		return sqliteutils.FailedToGetPoolError(err)
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		return sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+	return ApplyConn(conn, changeset, onConflict)
+}
+
+// ApplyConn is Apply on a connection the caller already holds.
+func ApplyConn(conn *sqlite.Conn, changeset []byte, onConflict ConflictHandler) error {
+	if onConflict == nil {
+		onConflict = Abort
+	}
+	return conn.ApplyChangeset(bytes.NewReader(changeset), nil, onConflict)
+}
+
+// Invert returns a changeset that undoes changeset.
+func Invert(changeset []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := sqlite.InvertChangeset(&buf, bytes.NewReader(changeset)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Concat combines changesets, in order, into one changeset.
+func Concat(changesets ...[]byte) ([]byte, error) {
+	group := new(sqlite.Changegroup)
+	defer group.Clear()
+	for _, changeset := range changesets {
+		if err := group.Add(bytes.NewReader(changeset)); err != nil {
+			return nil, err
+		}
+	}
+	var buf bytes.Buffer
+	if _, err := group.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,124 @@
+package changeset_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/changeset"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+const migration = `
+	CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT NOT NULL);
+	CREATE TABLE scratch (id INTEGER PRIMARY KEY, body TEXT);
+	INSERT INTO notes (id, body) VALUES (1, 'first'), (2, 'second');
+`
+
+// openDB initializes the global pool on a fresh database file with migration applied.
+func openDB(t *testing.T, ctx context.Context, path string) {
+	t.Helper()
+	require.NoError(t, pool.InitPool(path, 2))
+	p, err := pool.GetPool()
+	require.NoError(t, err)
+	conn, err := p.Take(ctx)
+	require.NoError(t, err)
+	defer p.Put(conn)
+	require.NoError(t, sqlitex.ExecuteScript(conn, migration, nil))
+}
+
+func notes(t *testing.T, ctx context.Context) map[int64]string {
+	t.Helper()
+	rows := map[int64]string{}
+	require.NoError(t, exec.Exec(ctx, "SELECT id, body FROM notes;", nil, func(_ int, row map[string]interface{}) {
+		rows[row["id"].(int64)] = row["body"].(string)
+	}))
+	return rows
+}
+
+func TestRecordApply(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Record changes on the device copy.
+	openDB(t, ctx, filepath.Join(dir, "device.db"))
+	cs, err := changeset.Record(ctx, []string{"notes"}, func(conn *sqlite.Conn) error {
+		return sqlitex.ExecuteScript(conn, `
+			INSERT INTO notes (id, body) VALUES (3, 'third');
+			UPDATE notes SET body = 'FIRST' WHERE id = 1;
+			DELETE FROM notes WHERE id = 2;
+			INSERT INTO scratch (body) VALUES ('not recorded');
+		`, nil)
+	})
+	require.NoError(t, err)
+	assert.NotEmpty(t, cs)
+
+	// A failing fn rolls back and records nothing.
+	_, err = changeset.Record(ctx, nil, func(conn *sqlite.Conn) error {
+		return sqlitex.ExecuteTransient(conn, "INSERT INTO notes (id) VALUES (9);", nil)
+	})
+	assert.Error(t, err)
+	assert.NotContains(t, notes(t, ctx), int64(9))
+	require.NoError(t, pool.ClosePool())
+
+	// Apply them to the server copy.
+	openDB(t, ctx, filepath.Join(dir, "server.db"))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, changeset.Apply(ctx, cs, nil))
+	assert.Equal(t, map[int64]string{1: "FIRST", 3: "third"}, notes(t, ctx))
+
+	var scratch int64
+	require.NoError(t, exec.Exec(ctx, "SELECT COUNT(*) AS n FROM scratch;", nil, func(_ int, row map[string]interface{}) {
+		scratch = row["n"].(int64)
+	}))
+	assert.Zero(t, scratch)
+
+	// Undo the changeset.
+	inverse, err := changeset.Invert(cs)
+	require.NoError(t, err)
+	require.NoError(t, changeset.Apply(ctx, inverse, nil))
+	assert.Equal(t, map[int64]string{1: "first", 2: "second"}, notes(t, ctx))
+
+	// The server edits a row the changeset also updates.
+	require.NoError(t, exec.Exec(ctx, "UPDATE notes SET body = 'server' WHERE id = 1;", nil, nil))
+	assert.Error(t, changeset.Apply(ctx, cs, changeset.Abort))
+	assert.Equal(t, map[int64]string{1: "server", 2: "second"}, notes(t, ctx))
+
+	require.NoError(t, changeset.Apply(ctx, cs, changeset.Omit))
+	assert.Equal(t, map[int64]string{1: "server", 3: "third"}, notes(t, ctx))
+
+	require.NoError(t, changeset.Apply(ctx, inverse, changeset.Replace))
+	assert.Equal(t, map[int64]string{1: "first", 2: "second"}, notes(t, ctx))
+}
+
+func TestConcat(t *testing.T) {
+	ctx := context.Background()
+	openDB(t, ctx, filepath.Join(t.TempDir(), "concat.db"))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	record := func(script string) []byte {
+		cs, err := changeset.Record(ctx, nil, func(conn *sqlite.Conn) error {
+			return sqlitex.ExecuteScript(conn, script, nil)
+		})
+		require.NoError(t, err)
+		return cs
+	}
+	first := record("INSERT INTO notes (id, body) VALUES (3, 'third');")
+	second := record("UPDATE notes SET body = 'THIRD' WHERE id = 3;")
+
+	combined, err := changeset.Concat(first, second)
+	require.NoError(t, err)
+	inverse, err := changeset.Invert(combined)
+	require.NoError(t, err)
+	require.NoError(t, changeset.Apply(ctx, inverse, nil))
+	assert.Equal(t, map[int64]string{1: "first", 2: "second"}, notes(t, ctx))
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+)
+
+const blobUsage = `usage: sqliteutils blob put -table TABLE -column COLUMN -file PATH [-rowid ID] [-set col=value ...]
+       sqliteutils blob get -table TABLE -column COLUMN -rowid ID [-out PATH]
+`
+
+// setFlag collects repeated -set column=value flags.
+type setFlag map[string]interface{}
+
+func (s setFlag) String() string {
+	return fmt.Sprint(map[string]interface{}(s))
+}
+
+func (s setFlag) Set(value string) error {
+	column, val, ok := strings.Cut(value, "=")
+	if !ok || column == "" {
+		return fmt.Errorf("expected column=value, got %q", value)
+	}
+	s[column] = val
+	return nil
+}
+
+// runBlob streams files into and out of blob columns.
+func runBlob(args []string) int {
+	if len(args) == 0 || (args[0] != "put" && args[0] != "get") {
+		fmt.Print(blobUsage)
+		return 2
+	}
+	fs := flag.NewFlagSet("blob "+args[0], flag.ExitOnError)
+	dbPath := fs.String("dbpath", "sqlite.db", "Path to the SQLite database file")
+	table := fs.String("table", "", "Table holding the blob")
+	column := fs.String("column", "", "Blob column")
+	rowID := fs.Int64("rowid", 0, "Row ID of the blob (put inserts a new row when omitted)")
+	file := fs.String("file", "", "File to store (put)")
+	out := fs.String("out", "-", "File to write the blob to, or - for stdout (get)")
+	chunkSize := fs.Int("chunk", 1<<20, "Bytes copied per blob write (put)")
+	set := setFlag{}
+	fs.Var(set, "set", "Extra column value for inserted rows as column=value (repeatable, put)")
+	parseFlags(fs, args[1:])
+
+	if *table == "" || *column == "" {
+		fmt.Print(blobUsage)
+		return 2
+	}
+
+	if !initPool(*dbPath, 1) {
+		return 1
+	}
+	defer closePool()
+
+	ctx := context.Background()
+	if args[0] == "put" {
+		if *file == "" {
+			fmt.Print(blobUsage)
+			return 2
+		}
+		id, n, err := putBlob(ctx, *table, *column, *rowID, *file, *chunkSize, set)
+		if err != nil {
+			fmt.Printf("Failed to store blob: %v\n", err)
+			return 1
+		}
+		fmt.Printf("Stored %d bytes in %s.%s rowid %d\n", n, *table, *column, id)
+		return 0
+	}
+
+	if *rowID == 0 {
+		fmt.Print(blobUsage)
+		return 2
+	}
+	var w io.Writer = os.Stdout
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			fmt.Printf("Failed to create output file: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		w = f
+	}
+	n, err := exec.StreamReadBlob(ctx, *table, *column, *rowID, 0, -1, w)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read blob: %v\n", err)
+		return 1
+	}
+	if *out != "-" {
+		fmt.Printf("Wrote %d bytes to %s\n", n, *out)
+	}
+	return 0
+}
+
+// putBlob sizes the blob to match the file, either in a new row or in an
+// existing one, then copies the file in chunks.
+func putBlob(ctx context.Context, table, column string, rowID int64, path string, chunkSize int, extra map[string]interface{}) (int64, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	size := info.Size()
+
+	if rowID == 0 {
+		if rowID, err = exec.CreateBlob(ctx, table, column, size, extra); err != nil {
+			return 0, 0, err
+		}
+	} else {
+		query := fmt.Sprintf("UPDATE %s SET %s = zeroblob($size) WHERE rowid = $rowid;",
+			sqliteutils.QuoteIdentifier(table), sqliteutils.QuoteIdentifier(column))
+		if err := exec.Exec(ctx, query, map[string]interface{}{"$size": size, "$rowid": rowID}, nil); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	if chunkSize <= 0 {
+		chunkSize = 1 << 20
+	}
+	buf := make([]byte, chunkSize)
+	var offset int64
+	for offset < size {
+		n, err := io.ReadFull(f, buf)
+		if n > 0 {
+			if err := exec.WriteBlobChunk(ctx, table, column, rowID, offset, buf[:n]); err != nil {
+				return rowID, offset, err
+			}
+			offset += int64(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return rowID, offset, err
+		}
+	}
+	return rowID, offset, nil
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"gopkg.in/yaml.v3"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// defaultConfigFile is read from the home directory when --config is not given.
+const defaultConfigFile = ".sqliteutils.yaml"
+
+// config holds defaults for command-line flags. Values given on the command
+// line always take precedence.
+type config struct {
+	DBPath   string            `yaml:"dbpath"`
+	PoolSize int               `yaml:"poolsize"`
+	Format   string            `yaml:"format"`
+	Pragmas  map[string]string `yaml:"pragmas"`
+	Profiles map[string]config `yaml:"profiles"`
+}
+
+// cliConfig is the configuration loaded at startup, with the selected profile applied.
+var cliConfig config
+
+// loadConfig removes the global --config and --profile flags from args,
+// loads the configuration file and applies the selected profile. It returns
+// the remaining arguments.
+func loadConfig(args []string) ([]string, error) {
+	var path, profile string
+	explicit := false
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		if !strings.HasPrefix(args[i], "-") || (name != "config" && name != "profile") {
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("flag needs an argument: -%s", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "config" {
+			path, explicit = value, true
+		} else {
+			profile = value
+		}
+	}
+
+	if path == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			path = filepath.Join(home, defaultConfigFile)
+		}
+	}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		switch {
+		case err == nil:
+			if err := yaml.Unmarshal(data, &cliConfig); err != nil {
+				return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+			}
+		case explicit || !errors.Is(err, os.ErrNotExist):
+			return nil, fmt.Errorf("failed to read config: %w", err)
+		}
+	}
+
+	if profile != "" {
+		p, ok := cliConfig.Profiles[profile]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q", profile)
+		}
+		cliConfig.merge(p)
+	}
+	return rest, nil
+}
+
+// merge overrides c with the values set in p.
+func (c *config) merge(p config) {
+	if p.DBPath != "" {
+		c.DBPath = p.DBPath
+	}
+	if p.PoolSize != 0 {
+		c.PoolSize = p.PoolSize
+	}
+	if p.Format != "" {
+		c.Format = p.Format
+	}
+	if len(p.Pragmas) > 0 {
+		pragmas := make(map[string]string, len(c.Pragmas)+len(p.Pragmas))
+		for k, v := range c.Pragmas {
+			pragmas[k] = v
+		}
+		for k, v := range p.Pragmas {
+			pragmas[k] = v
+		}
+		c.Pragmas = pragmas
+	}
+}
+
+// pragmaList returns the configured pragmas as key=value pairs in key order,
+// followed by the --pragma flags, which replace configured pragmas of the same name.
+func (c *config) pragmaList() []string {
+	overridden := make(map[string]bool, len(pragmaFlags))
+	for _, pragma := range pragmaFlags {
+		key, _, _ := strings.Cut(pragma, "=")
+		overridden[strings.ToLower(strings.TrimSpace(key))] = true
+	}
+	keys := make([]string, 0, len(c.Pragmas))
+	for k := range c.Pragmas {
+		if !overridden[strings.ToLower(k)] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	pragmas := make([]string, 0, len(keys)+len(pragmaFlags))
+	for _, k := range keys {
+		pragmas = append(pragmas, k+"="+c.Pragmas[k])
+	}
+	return append(pragmas, pragmaFlags...)
+}
+
+// pragmaFlags holds the --pragma flags of the running command.
+var pragmaFlags pragmaFlag
+
+// pragmaFlag collects repeated --pragma key=value flags.
+type pragmaFlag []string
+
+func (p *pragmaFlag) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pragmaFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || strings.TrimSpace(val) == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	for _, r := range key {
+		if r != '_' && r != '.' && (r < '0' || r > '9') && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return fmt.Errorf("invalid pragma name %q", key)
+		}
+	}
+	*p = append(*p, value)
+	return nil
+}
+
+// pragmaOption returns a pool option that runs PRAGMA key = value for each
+// key=value pair on every new connection.
+func pragmaOption(pragmas []string) pool.Option {
+	return pool.WithPrepareConn(func(conn *sqlite.Conn) error {
+		for _, pragma := range pragmas {
+			key, value, _ := strings.Cut(pragma, "=")
+			query := fmt.Sprintf("PRAGMA %s = %s;", strings.TrimSpace(key), strings.TrimSpace(value))
+			if err := sqlitex.ExecuteTransient(conn, query, nil); err != nil {
+				return fmt.Errorf("failed to set pragma %s: %w", key, err)
+			}
+		}
+		return nil
+	})
+}
+
+// parseFlags adds the --pragma flag to fs, applies configured defaults to the
+// flags fs defines and then parses args, so explicit flags override the configuration.
+func parseFlags(fs *flag.FlagSet, args []string) {
+	fs.Var(&pragmaFlags, "pragma", "Pragma applied to every pooled connection as key=value (repeatable)")
+	defaults := map[string]string{
+		"dbpath": cliConfig.DBPath,
+		"format": cliConfig.Format,
+	}
+	if cliConfig.PoolSize > 0 {
+		defaults["poolsize"] = strconv.Itoa(cliConfig.PoolSize)
+	}
+	for name, value := range defaults {
+		if value == "" || fs.Lookup(name) == nil {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			fmt.Fprintf(fs.Output(), "invalid config value for %s: %v\n", name, err)
+			os.Exit(2)
+		}
+	}
+	fs.Parse(args)
+}
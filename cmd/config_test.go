@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	require.NoError(t, os.WriteFile(filepath.Join(home, defaultConfigFile), []byte(`
+dbpath: home.db
+poolsize: 2
+pragmas:
+  cache_size: "-2000"
+profiles:
+  prod:
+    dbpath: prod.db
+    pragmas:
+      synchronous: NORMAL
+`), 0o644))
+	other := filepath.Join(t.TempDir(), "other.yaml")
+	require.NoError(t, os.WriteFile(other, []byte("dbpath: other.db\n"), 0o644))
+	invalid := filepath.Join(t.TempDir(), "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("poolsize: [\n"), 0o644))
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantRest []string
+		want     config
+		wantErr  bool
+	}{
+		{
+			name:     "default file",
+			args:     []string{"-query", "SELECT 1"},
+			wantRest: []string{"-query", "SELECT 1"},
+			want: config{DBPath: "home.db", PoolSize: 2, Pragmas: map[string]string{"cache_size": "-2000"},
+				Profiles: map[string]config{"prod": {DBPath: "prod.db", Pragmas: map[string]string{"synchronous": "NORMAL"}}}},
+		},
+		{
+			name:     "profile",
+			args:     []string{"--profile", "prod", "schema"},
+			wantRest: []string{"schema"},
+			want: config{DBPath: "prod.db", PoolSize: 2, Pragmas: map[string]string{"cache_size": "-2000", "synchronous": "NORMAL"},
+				Profiles: map[string]config{"prod": {DBPath: "prod.db", Pragmas: map[string]string{"synchronous": "NORMAL"}}}},
+		},
+		{
+			name:     "explicit file",
+			args:     []string{"-config=" + other, "-dbpath", "x.db"},
+			wantRest: []string{"-dbpath", "x.db"},
+			want:     config{DBPath: "other.db"},
+		},
+		{name: "unknown profile", args: []string{"-profile=staging"}, wantErr: true},
+		{name: "missing explicit file", args: []string{"-config", filepath.Join(home, "missing.yaml")}, wantErr: true},
+		{name: "invalid file", args: []string{"-config", invalid}, wantErr: true},
+		{name: "missing value", args: []string{"-profile"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cliConfig = config{}
+			defer func() { cliConfig = config{} }()
+			rest, err := loadConfig(tt.args)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantRest, rest)
+			assert.Equal(t, tt.want, cliConfig)
+		})
+	}
+
+	// A missing default file is not an error.
+	t.Setenv("HOME", t.TempDir())
+	cliConfig = config{}
+	rest, err := loadConfig([]string{"schema"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"schema"}, rest)
+	assert.Equal(t, config{}, cliConfig)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/dropsite-ai/sqliteutils/export"
+	"github.com/dropsite-ai/sqliteutils/export/parquet"
+)
+
+// runExport streams the results of a query to a file as CSV, JSON Lines or
+// Parquet.
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	dbPath := fs.String("dbpath", "sqlite.db", "Path to the SQLite database file")
+	query := fs.String("query", "", "SELECT statement whose results are exported")
+	format := fs.String("format", "csv", "Output format: csv, jsonl or parquet")
+	out := fs.String("out", "-", "Output file (use - for stdout)")
+	delimiter := fs.String("delimiter", ",", `CSV field delimiter (use \t for tab)`)
+	null := fs.String("null", "", "CSV text for NULL values")
+	blob := fs.String("blob", "hexliteral", "CSV blob encoding: hexliteral, hex or base64")
+	noHeader := fs.Bool("no-header", false, "Leave out the CSV header row")
+	params := paramFlag{}
+	fs.Var(params, "param", "Query parameter as name=value (repeatable)")
+	parseFlags(fs, args)
+
+	if *query == "" {
+		fmt.Println("export requires -query")
+		fs.Usage()
+		return 2
+	}
+	csvOpts := &export.CSVOptions{Null: *null, NoHeader: *noHeader}
+	switch *format {
+	case "csv":
+		csvOpts.Delimiter = delimiterRune(*delimiter)
+		encoding, err := export.ParseBlobEncoding(*blob)
+		if err != nil {
+			fmt.Println(err)
+			return 2
+		}
+		csvOpts.Blob = encoding
+	case "jsonl", "parquet":
+	default:
+		fmt.Printf("Unsupported export format %q\n", *format)
+		return 2
+	}
+
+	if !initPool(*dbPath, 1) {
+		return 1
+	}
+	defer closePool()
+
+	write := func(w io.Writer) (int64, error) {
+		bw := bufio.NewWriter(w)
+		var rows int64
+		var err error
+		switch *format {
+		case "csv":
+			rows, err = export.CSV(context.Background(), *query, params, bw, csvOpts)
+		case "jsonl":
+			rows, err = export.WriteJSONL(context.Background(), *query, params, bw)
+		case "parquet":
+			rows, err = parquet.Export(context.Background(), *query, params, bw, nil)
+		}
+		if flushErr := bw.Flush(); err == nil {
+			err = flushErr
+		}
+		return rows, err
+	}
+	var rows int64
+	var err error
+	if *out == "-" {
+		rows, err = write(os.Stdout)
+	} else {
+		rows, err = writeFile(*out, write)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to export after %d rows: %v\n", rows, err)
+		return 1
+	}
+	if *out != "-" {
+		fmt.Printf("Exported %d rows to %s\n", rows, *out)
+	}
+	return 0
+}
+
+// writeFile runs write on a temporary file next to path and renames it to
+// path once write and closing the file succeed. A failed export removes the
+// temporary file and leaves what was at path in place.
+func writeFile(path string, write func(w io.Writer) (int64, error)) (rows int64, err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return 0, fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(f.Name())
+		}
+	}()
+	// CreateTemp makes the file private; exports are as readable as files
+	// created with os.Create.
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return 0, err
+	}
+	rows, err = write(f)
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close output file: %w", closeErr)
+	}
+	if err != nil {
+		return rows, err
+	}
+	return rows, os.Rename(f.Name(), path)
+}
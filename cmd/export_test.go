@@ -0,0 +1,43 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out.csv")
+
+	rows, err := writeFile(path, func(w io.Writer) (int64, error) {
+		_, err := io.WriteString(w, "a\n1\n")
+		return 1, err
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), rows)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "a\n1\n", string(data))
+
+	// A failed export leaves the earlier file and no temporary file behind.
+	_, err = writeFile(path, func(w io.Writer) (int64, error) {
+		io.WriteString(w, "partial")
+		return 0, errors.New("no such column")
+	})
+	assert.ErrorContains(t, err, "no such column")
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "a\n1\n", string(data))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	_, err = writeFile(filepath.Join(dir, "missing", "out.csv"), func(io.Writer) (int64, error) { return 0, nil })
+	assert.Error(t, err)
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/importer"
+)
+
+// runImport loads a CSV or JSON Lines file into a table in batched
+// transactions.
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	dbPath := fs.String("dbpath", "sqlite.db", "Path to the SQLite database file")
+	csvPath := fs.String("csv", "", "Path to the CSV file to import (use - for stdin)")
+	jsonlPath := fs.String("jsonl", "", "Path to a JSON Lines file to import instead of -csv (use - for stdin)")
+	table := fs.String("table", "", "Name of the table to import into")
+	mapping := fs.String("map", "", "Header to column mapping as header=column,... (map a header to - to skip it)")
+	types := fs.String("types", "", "Column types as column=TYPE,... (inferred from the data when omitted)")
+	createTable := fs.Bool("create-table", false, "Create the table if it does not exist (for -jsonl, also add missing columns)")
+	batchSize := fs.Int("batch", 1000, "Number of rows per transaction")
+	inferRows := fs.Int("infer-rows", 1000, "Number of rows sampled to infer column types")
+	delimiter := fs.String("delimiter", ",", `Field delimiter (use \t for tab)`)
+	emptyNull := fs.Bool("empty-null", false, "Import empty fields of text columns as NULL instead of empty strings")
+	parseFlags(fs, args)
+
+	if (*csvPath == "") == (*jsonlPath == "") || *table == "" {
+		fmt.Println("import requires -table and one of -csv or -jsonl")
+		fs.Usage()
+		return 2
+	}
+	if *batchSize <= 0 {
+		*batchSize = 1
+	}
+
+	path := *csvPath
+	if *jsonlPath != "" {
+		path = *jsonlPath
+	}
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Printf("Failed to open input file: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	columnMap, err := parsePairs(*mapping)
+	if err != nil {
+		fmt.Printf("Invalid -map: %v\n", err)
+		return 2
+	}
+
+	if *jsonlPath != "" {
+		if !initPool(*dbPath, 1) {
+			return 1
+		}
+		defer closePool()
+		n, err := importer.ReadJSONL(context.Background(), *table, in, &importer.JSONLOptions{
+			BatchSize:     *batchSize,
+			Columns:       columnMap,
+			CreateColumns: *createTable,
+		})
+		if err != nil {
+			fmt.Printf("Failed to import JSON Lines after %d rows: %v\n", n, err)
+			return 1
+		}
+		fmt.Printf("Imported %d rows into %s\n", n, *table)
+		return 0
+	}
+
+	r := csv.NewReader(in)
+	r.Comma = delimiterRune(*delimiter)
+	columnTypes, err := parsePairs(*types)
+	if err != nil {
+		fmt.Printf("Invalid -types: %v\n", err)
+		return 2
+	}
+
+	if !initPool(*dbPath, 1) {
+		return 1
+	}
+	defer closePool()
+
+	imp := &csvImporter{
+		table:     *table,
+		emptyNull: *emptyNull,
+		batchSize: *batchSize,
+	}
+	n, err := imp.run(context.Background(), r, columnMap, columnTypes, *createTable, *inferRows)
+	if err != nil {
+		fmt.Printf("Failed to import CSV after %d rows: %v\n", n, err)
+		return 1
+	}
+	fmt.Printf("Imported %d rows into %s\n", n, *table)
+	return 0
+}
+
+// csvImporter inserts CSV records into one table.
+type csvImporter struct {
+	table     string
+	emptyNull bool
+	batchSize int
+
+	// fields holds the index of each imported CSV field; columns and types
+	// hold the matching column names and declared types.
+	fields  []int
+	columns []string
+	types   []string
+}
+
+func (imp *csvImporter) run(ctx context.Context, r *csv.Reader, columnMap, columnTypes map[string]string, create bool, inferRows int) (int, error) {
+	header, err := r.Read()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read header: %w", err)
+	}
+	for i, name := range header {
+		if mapped, ok := columnMap[name]; ok {
+			name = mapped
+		}
+		if name == "-" {
+			continue
+		}
+		imp.fields = append(imp.fields, i)
+		imp.columns = append(imp.columns, name)
+	}
+	if len(imp.columns) == 0 {
+		return 0, errors.New("no columns to import")
+	}
+
+	// Sample the first rows to infer types; they are inserted like any other rows.
+	var sample [][]string
+	for len(sample) < inferRows {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+		sample = append(sample, record)
+	}
+	imp.types = make([]string, len(imp.columns))
+	for i, column := range imp.columns {
+		if typ, ok := columnTypes[column]; ok {
+			imp.types[i] = strings.ToUpper(typ)
+		} else {
+			imp.types[i] = inferType(sample, imp.fields[i])
+		}
+	}
+
+	if create {
+		if err := imp.createTable(ctx); err != nil {
+			return 0, err
+		}
+	}
+
+	insert := imp.insertStatement()
+	var queries []string
+	var params []map[string]interface{}
+	imported := 0
+	flush := func() error {
+		if len(queries) == 0 {
+			return nil
+		}
+		if err := exec.ExecMultiTx(ctx, queries, params, nil); err != nil {
+			return err
+		}
+		imported += len(queries)
+		queries, params = queries[:0], params[:0]
+		return nil
+	}
+	add := func(record []string) error {
+		queries = append(queries, insert)
+		params = append(params, imp.rowParams(record))
+		if len(queries) >= imp.batchSize {
+			return flush()
+		}
+		return nil
+	}
+
+	for _, record := range sample {
+		if err := add(record); err != nil {
+			return imported, err
+		}
+	}
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return imported, err
+		}
+		if err := add(record); err != nil {
+			return imported, err
+		}
+	}
+	return imported, flush()
+}
+
+func (imp *csvImporter) createTable(ctx context.Context) error {
+	defs := make([]string, len(imp.columns))
+	for i, column := range imp.columns {
+		defs[i] = sqliteutils.QuoteIdentifier(column) + " " + imp.types[i]
+	}
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s);", sqliteutils.QuoteIdentifier(imp.table), strings.Join(defs, ", "))
+	if err := exec.Exec(ctx, query, nil, nil); err != nil {
+		return fmt.Errorf("failed to create table: %w", err)
+	}
+	return nil
+}
+
+func (imp *csvImporter) insertStatement() string {
+	quoted := make([]string, len(imp.columns))
+	placeholders := make([]string, len(imp.columns))
+	for i, column := range imp.columns {
+		quoted[i] = sqliteutils.QuoteIdentifier(column)
+		placeholders[i] = fmt.Sprintf("$c%d", i)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", sqliteutils.QuoteIdentifier(imp.table),
+		strings.Join(quoted, ", "), strings.Join(placeholders, ", "))
+}
+
+// rowParams converts a record to insert parameters, converting fields to
+// the column's type where they parse cleanly.
+func (imp *csvImporter) rowParams(record []string) map[string]interface{} {
+	params := make(map[string]interface{}, len(imp.fields))
+	for i, field := range imp.fields {
+		name := fmt.Sprintf("$c%d", i)
+		if field >= len(record) {
+			params[name] = nil
+			continue
+		}
+		value := record[field]
+		if value == "" && (imp.emptyNull || !textType(imp.types[i])) {
+			// Empty fields only mean an empty string in text columns.
+			params[name] = nil
+			continue
+		}
+		params[name] = convertField(value, imp.types[i])
+	}
+	return params
+}
+
+// textType reports whether a column declared typ has TEXT affinity.
+func textType(typ string) bool {
+	return strings.Contains(typ, "CHAR") || strings.Contains(typ, "CLOB") || strings.Contains(typ, "TEXT")
+}
+
+// inferType picks INTEGER, REAL or TEXT for a column from sampled values,
+// ignoring empty fields.
+func inferType(sample [][]string, field int) string {
+	typ := "INTEGER"
+	seen := false
+	for _, record := range sample {
+		if field >= len(record) || record[field] == "" {
+			continue
+		}
+		seen = true
+		value := record[field]
+		if typ == "INTEGER" {
+			if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+				continue
+			}
+			typ = "REAL"
+		}
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "TEXT"
+		}
+	}
+	if !seen {
+		return "TEXT"
+	}
+	return typ
+}
+
+// convertField parses value according to a declared column type, falling
+// back to the raw text when it does not parse.
+func convertField(value, typ string) interface{} {
+	switch {
+	case strings.Contains(typ, "INT"):
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case strings.Contains(typ, "REAL"), strings.Contains(typ, "FLOA"), strings.Contains(typ, "DOUB"):
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+// parsePairs parses a comma-separated list of key=value pairs. Commas inside
+// parentheses do not separate pairs, so types such as DECIMAL(10,2) can be
+// given.
+func parsePairs(s string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	if s == "" {
+		return pairs, nil
+	}
+	for _, item := range splitOutsideParens(s) {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", item)
+		}
+		pairs[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return pairs, nil
+}
+
+// splitOutsideParens splits s at the commas that are not inside parentheses.
+func splitOutsideParens(s string) []string {
+	var items []string
+	depth, start := 0, 0
+	for i, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			if depth > 0 {
+				depth--
+			}
+		case ',':
+			if depth == 0 {
+				items = append(items, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(items, s[start:])
+}
+
+// delimiterRune returns the field delimiter named by s, accepting \t for tab.
+func delimiterRune(s string) rune {
+	if s == `\t` {
+		return '\t'
+	}
+	for _, r := range s {
+		return r
+	}
+	return ','
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferType(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample [][]string
+		want   string
+	}{
+		{"integers", [][]string{{"1"}, {"-20"}}, "INTEGER"},
+		{"reals", [][]string{{"1"}, {"2.5"}}, "REAL"},
+		{"text", [][]string{{"1"}, {"2.5"}, {"x"}}, "TEXT"},
+		{"empty values are skipped", [][]string{{""}, {"3"}}, "INTEGER"},
+		{"short records are skipped", [][]string{{}, {"3"}}, "INTEGER"},
+		{"no values", [][]string{{""}, {}}, "TEXT"},
+		{"no rows", nil, "TEXT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, inferType(tt.sample, 0))
+		})
+	}
+}
+
+func TestConvertField(t *testing.T) {
+	tests := []struct {
+		value, typ string
+		want       interface{}
+	}{
+		{"42", "INTEGER", int64(42)},
+		{"42", "BIGINT", int64(42)},
+		{"4.5", "INTEGER", "4.5"},
+		{"4.5", "REAL", 4.5},
+		{"4", "DOUBLE PRECISION", 4.0},
+		{"x", "FLOAT", "x"},
+		{"42", "TEXT", "42"},
+		{"42", "", "42"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.typ+"/"+tt.value, func(t *testing.T) {
+			assert.Equal(t, tt.want, convertField(tt.value, tt.typ))
+		})
+	}
+}
+
+func TestRowParams(t *testing.T) {
+	imp := &csvImporter{
+		fields:  []int{0, 1, 2, 3},
+		columns: []string{"n", "x", "name", "note"},
+		types:   []string{"INTEGER", "REAL", "TEXT", "VARCHAR(20)"},
+	}
+	tests := []struct {
+		name      string
+		record    []string
+		emptyNull bool
+		want      []interface{}
+	}{
+		{"values", []string{"1", "2.5", "a", "b"}, false, []interface{}{int64(1), 2.5, "a", "b"}},
+		{"empty fields of numeric columns are NULL", []string{"", "", "", ""}, false, []interface{}{nil, nil, "", ""}},
+		{"empty-null", []string{"", "", "", ""}, true, []interface{}{nil, nil, nil, nil}},
+		{"short record", []string{"1"}, false, []interface{}{int64(1), nil, nil, nil}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imp.emptyNull = tt.emptyNull
+			params := imp.rowParams(tt.record)
+			for i, want := range tt.want {
+				assert.Equal(t, want, params[fmt.Sprintf("$c%d", i)], imp.columns[i])
+			}
+		})
+	}
+}
+
+func TestParsePairs(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"", map[string]string{}, false},
+		{"a=x", map[string]string{"a": "x"}, false},
+		{" a = x , b=y", map[string]string{"a": "x", "b": "y"}, false},
+		{"a=", map[string]string{"a": ""}, false},
+		{"a=x=y", map[string]string{"a": "x=y"}, false},
+		{"a", nil, true},
+		{"=x", nil, true},
+		{"a=x,", nil, true},
+		{"price=DECIMAL(10,2),qty=INTEGER", map[string]string{"price": "DECIMAL(10,2)", "qty": "INTEGER"}, false},
+		{"a=x(1,(2,3)),b=y", map[string]string{"a": "x(1,(2,3))", "b": "y"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := parsePairs(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
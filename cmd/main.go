@@ -2,42 +2,232 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 
+	"github.com/dropsite-ai/sqliteutils"
 	"github.com/dropsite-ai/sqliteutils/exec"
 	"github.com/dropsite-ai/sqliteutils/pool"
 )
 
 func main() {
+	// Load the configuration file and strip the global flags it uses
+	args, err := loadConfig(os.Args[1:])
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(2)
+	}
+	os.Args = append(os.Args[:1], args...)
+
+	// Dispatch subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "repl":
+			os.Exit(runREPL(os.Args[2:]))
+		case "schema":
+			os.Exit(runSchema(os.Args[2:]))
+		case "import":
+			os.Exit(runImport(os.Args[2:]))
+		case "export":
+			os.Exit(runExport(os.Args[2:]))
+		case "maintain":
+			os.Exit(runMaintain(os.Args[2:]))
+		case "blob":
+			os.Exit(runBlob(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		case "watch":
+			os.Exit(runWatch(os.Args[2:]))
+		}
+	}
+
 	// Define and parse flags
 	dbPath := flag.String("dbpath", "sqlite.db", "Path to the SQLite database file")
 	poolSize := flag.Int("poolsize", 4, "Number of connections in the pool")
-	query := flag.String("query", "SELECT sqlite_version();", "SQL query to execute")
-	flag.Parse()
+	queries := queryFlag{}
+	flag.Var(&queries, "query", `SQL query to execute (repeatable, default "SELECT sqlite_version();")`)
+	file := flag.String("file", "", "Path to a SQL script to execute instead of -query (use - for stdin)")
+	params := paramFlag{}
+	flag.Var(params, "param", "Query parameter as name=value (repeatable)")
+	paramsJSON := flag.String("params-json", "", `Query parameters as a JSON object, e.g. '{"$name":"Alice"}'`)
+	tx := flag.Bool("tx", false, "Execute all statements atomically in one transaction")
+	txModeName := flag.String("tx-mode", "deferred", "Transaction mode for -tx: deferred, immediate or exclusive")
+	parseFlags(flag.CommandLine, os.Args[1:])
 
-	// Initialize the database pool
-	err := pool.InitPool(*dbPath, *poolSize)
+	txMode, err := exec.ParseTxMode(*txModeName)
 	if err != nil {
-		fmt.Printf("Failed to initialize database pool: %v\n", err)
-		os.Exit(1)
+		fmt.Println(err)
+		os.Exit(2)
 	}
-	defer func() {
-		if err = pool.ClosePool(); err != nil {
-			fmt.Printf("Failed to close database pool: %v\n", err)
+
+	if *paramsJSON != "" {
+		if err := parseParamsJSON(params, *paramsJSON); err != nil {
+			fmt.Println(err)
+			os.Exit(1)
 		}
-	}()
+	}
 
-	// Execute the query
-	ctx := context.Background()
-	err = exec.Exec(ctx, *query, nil, func(index int, row map[string]interface{}) {
-		fmt.Printf("Result %d: %+v\n", index+1, row)
-	})
+	// Read the script from -file, or from stdin when SQL is piped in and -query is not set
+	if isFlagSet("file") && *file == "" {
+		fmt.Println("-file requires a path, or - for stdin")
+		os.Exit(2)
+	}
+	script, read, err := readScript(*file, isFlagSet("query"))
 	if err != nil {
+		fmt.Printf("Failed to read SQL script: %v\n", err)
+		os.Exit(1)
+	}
+	if !read {
+		script = queries.script()
+	}
+
+	// Initialize the database pool
+	if !initPool(*dbPath, *poolSize) {
+		os.Exit(1)
+	}
+	defer closePool()
+
+	// Execute the script
+	ctx := context.Background()
+	if err = runScript(ctx, script, params, *tx, txMode); err != nil {
 		fmt.Printf("Failed to execute query: %v\n", err)
 		os.Exit(1)
 	}
 
 	fmt.Println("Query executed successfully")
 }
+
+// initPool initializes the global pool for a subcommand, reporting failures.
+func initPool(dbPath string, poolSize int, opts ...pool.Option) bool {
+	if pragmas := cliConfig.pragmaList(); len(pragmas) > 0 {
+		opts = append([]pool.Option{pragmaOption(pragmas)}, opts...)
+	}
+	uri, err := sqliteutils.ParseURI(dbPath)
+	if err != nil {
+		fmt.Printf("Invalid database path: %v\n", err)
+		return false
+	}
+	if err := pool.InitPool(uri.String(), poolSize, opts...); err != nil {
+		fmt.Printf("Failed to initialize database pool: %v\n", err)
+		return false
+	}
+	return true
+}
+
+// closePool closes the global pool, reporting failures.
+func closePool() {
+	if err := pool.ClosePool(); err != nil {
+		fmt.Printf("Failed to close database pool: %v\n", err)
+	}
+}
+
+// isFlagSet reports whether the named flag was given on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// readScript returns the contents of file, or of stdin when file is "-" or
+// when input is piped and no explicit query was given. read is false when
+// there is no script to read, so that an empty script is not mistaken for
+// a missing one.
+func readScript(file string, querySet bool) (script string, read bool, err error) {
+	if file == "" && !querySet {
+		if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+			file = "-"
+		}
+	}
+	var data []byte
+	switch file {
+	case "":
+		return "", false, nil
+	case "-":
+		data, err = io.ReadAll(os.Stdin)
+	default:
+		data, err = os.ReadFile(file)
+	}
+	return string(data), err == nil, err
+}
+
+// queryFlag collects repeated -query flags.
+type queryFlag []string
+
+func (q *queryFlag) String() string {
+	return strings.Join(*q, " ")
+}
+
+func (q *queryFlag) Set(value string) error {
+	*q = append(*q, value)
+	return nil
+}
+
+// script joins the queries into one script, terminating each with a
+// semicolon so they split into separate statements.
+func (q queryFlag) script() string {
+	if len(q) == 0 {
+		return "SELECT sqlite_version();"
+	}
+	var b strings.Builder
+	for _, query := range q {
+		query = strings.TrimSpace(query)
+		b.WriteString(query)
+		if !strings.HasSuffix(query, ";") {
+			b.WriteString(";")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// runScript splits script into statements and executes them in order on one
+// connection with the same params, printing result rows and reporting the
+// line of a failing statement. With tx set the statements run in a single
+// transaction begun in txMode and are rolled back together on failure.
+func runScript(ctx context.Context, script string, args map[string]interface{}, tx bool, txMode exec.TxMode) error {
+	statements, rest := sqliteutils.SplitStatements(script)
+	if rest != "" {
+		statements = append(statements, sqliteutils.Statement{SQL: rest, Line: lineOf(script, rest)})
+	}
+
+	queries := make([]string, len(statements))
+	params := make([]map[string]interface{}, len(statements))
+	for i, stmt := range statements {
+		queries[i] = stmt.SQL
+		params[i] = args
+	}
+
+	printRow := func(index int, row map[string]interface{}) {
+		fmt.Printf("Result %d: %+v\n", index+1, row)
+	}
+	var err error
+	if tx {
+		err = exec.ExecMultiTxMode(ctx, txMode, queries, params, printRow)
+	} else {
+		err = exec.ExecMulti(ctx, queries, params, printRow)
+	}
+	var stmtErr *exec.StatementError
+	if errors.As(err, &stmtErr) {
+		return fmt.Errorf("line %d: %w", statements[stmtErr.Index].Line, stmtErr)
+	}
+	return err
+}
+
+// lineOf returns the 1-based line on which the trailing text rest starts in script.
+func lineOf(script, rest string) int {
+	line := 1
+	for _, c := range script[:len(script)-len(rest)] {
+		if c == '\n' {
+			line++
+		}
+	}
+	return line
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryFlag(t *testing.T) {
+	tests := []struct {
+		name    string
+		queries []string
+		want    string
+	}{
+		{"default", nil, "SELECT sqlite_version();"},
+		{"one", []string{"SELECT 1"}, "SELECT 1;\n"},
+		{"several", []string{" SELECT 1; ", "SELECT 2"}, "SELECT 1;\nSELECT 2;\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := queryFlag{}
+			for _, query := range tt.queries {
+				require.NoError(t, q.Set(query))
+			}
+			assert.Equal(t, tt.want, q.script())
+		})
+	}
+}
+
+func TestParamFlag(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    paramFlag
+		wantErr bool
+	}{
+		{"name=Alice", paramFlag{"$name": "Alice", ":name": "Alice", "@name": "Alice"}, false},
+		{":name=Alice", paramFlag{":name": "Alice"}, false},
+		{"$expr=a=b", paramFlag{"$expr": "a=b"}, false},
+		{"name", nil, true},
+		{"=Alice", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			p := paramFlag{}
+			err := p.Set(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, p)
+		})
+	}
+}
+
+func TestParseParamsJSON(t *testing.T) {
+	params := map[string]interface{}{"$kept": "x"}
+	require.NoError(t, parseParamsJSON(params, `{"$n": 2, "f": 1.5, "o": {"k": "<b>"}}`))
+	assert.Equal(t, map[string]interface{}{
+		"$kept": "x",
+		"$n":    int64(2),
+		"$f":    1.5, ":f": 1.5, "@f": 1.5,
+		"$o": `{"k":"<b>"}`, ":o": `{"k":"<b>"}`, "@o": `{"k":"<b>"}`,
+	}, params)
+	assert.Error(t, parseParamsJSON(params, `[1]`))
+}
+
+func TestReadScript(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "script.sql")
+	require.NoError(t, os.WriteFile(script, []byte("SELECT 1;"), 0o644))
+	empty := filepath.Join(dir, "empty.sql")
+	require.NoError(t, os.WriteFile(empty, nil, 0o644))
+
+	tests := []struct {
+		name     string
+		file     string
+		want     string
+		wantRead bool
+		wantErr  bool
+	}{
+		{name: "file", file: script, want: "SELECT 1;", wantRead: true},
+		{name: "empty file", file: empty, wantRead: true},
+		{name: "no file", file: ""},
+		{name: "missing file", file: filepath.Join(dir, "missing.sql"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, read, err := readScript(tt.file, true)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantRead, read)
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+)
+
+// runMaintain runs the selected maintenance steps and prints file sizes
+// before and after.
+func runMaintain(args []string) int {
+	fs := flag.NewFlagSet("maintain", flag.ExitOnError)
+	dbPath := fs.String("dbpath", "sqlite.db", "Path to the SQLite database file")
+	optimize := fs.Bool("optimize", true, "Run PRAGMA optimize")
+	analyze := fs.Bool("analyze", false, "Run ANALYZE")
+	incremental := fs.Int("incremental-vacuum", -1, "Run PRAGMA incremental_vacuum freeing up to N pages (0 frees all; requires auto_vacuum=INCREMENTAL)")
+	vacuum := fs.Bool("vacuum", false, "Run a full VACUUM")
+	checkpoint := fs.Bool("checkpoint", true, "Run PRAGMA wal_checkpoint(TRUNCATE)")
+	parseFlags(fs, args)
+
+	before := fileSizes(*dbPath)
+	if !initPool(*dbPath, 1) {
+		return 1
+	}
+	defer closePool()
+
+	ctx := context.Background()
+	steps := []struct {
+		enabled bool
+		name    string
+		query   string
+	}{
+		{*analyze, "ANALYZE", "ANALYZE;"},
+		{*optimize, "PRAGMA optimize", "PRAGMA optimize;"},
+		{*incremental >= 0, "PRAGMA incremental_vacuum", fmt.Sprintf("PRAGMA incremental_vacuum(%d);", *incremental)},
+		{*vacuum, "VACUUM", "VACUUM;"},
+		{*checkpoint, "PRAGMA wal_checkpoint(TRUNCATE)", "PRAGMA wal_checkpoint(TRUNCATE);"},
+	}
+	if *incremental >= 0 {
+		if err := exec.Query(ctx, "PRAGMA auto_vacuum;", nil, func(_ []string, values []interface{}) {
+			if mode, _ := values[0].(int64); mode != 2 {
+				fmt.Println("Warning: auto_vacuum is not INCREMENTAL, so incremental_vacuum frees nothing")
+			}
+		}); err != nil {
+			fmt.Printf("Failed to read auto_vacuum: %v\n", err)
+			return 1
+		}
+	}
+	for _, step := range steps {
+		if !step.enabled {
+			continue
+		}
+		if err := exec.Query(ctx, step.query, nil, nil); err != nil {
+			fmt.Printf("%s failed: %v\n", step.name, err)
+			return 1
+		}
+		fmt.Printf("%s done\n", step.name)
+	}
+
+	after := fileSizes(*dbPath)
+	for i, suffix := range []string{"", "-wal"} {
+		fmt.Printf("%-8s %12d -> %12d bytes\n", "db"+suffix, before[i], after[i])
+	}
+	return 0
+}
+
+// fileSizes returns the sizes of the database file and its WAL, using 0 for
+// files that do not exist.
+func fileSizes(dbPath string) [2]int64 {
+	var sizes [2]int64
+	for i, suffix := range []string{"", "-wal"} {
+		if info, err := os.Stat(dbPath + suffix); err == nil {
+			sizes[i] = info.Size()
+		}
+	}
+	return sizes
+}
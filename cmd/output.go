@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// formatValue renders a column value for display.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return "x'" + hex.EncodeToString(v) + "'"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// printTable writes rows as an aligned table with a header row.
+func printTable(w io.Writer, columns []string, rows [][]interface{}) {
+	widths := make([]int, len(columns))
+	cells := make([][]string, len(rows))
+	for i, name := range columns {
+		widths[i] = utf8.RuneCountInString(name)
+	}
+	for r, row := range rows {
+		cells[r] = make([]string, len(row))
+		for i, v := range row {
+			cells[r][i] = formatValue(v)
+			if n := utf8.RuneCountInString(cells[r][i]); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	writeRow := func(values []string) {
+		for i, v := range values {
+			if i > 0 {
+				fmt.Fprint(w, " | ")
+			}
+			fmt.Fprint(w, v)
+			if i < len(values)-1 {
+				fmt.Fprint(w, strings.Repeat(" ", widths[i]-utf8.RuneCountInString(v)))
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	writeRow(columns)
+	for i, width := range widths {
+		if i > 0 {
+			fmt.Fprint(w, "-+-")
+		}
+		fmt.Fprint(w, strings.Repeat("-", width))
+	}
+	fmt.Fprintln(w)
+	for _, row := range cells {
+		writeRow(row)
+	}
+	if len(rows) == 1 {
+		fmt.Fprintln(w, "(1 row)")
+	} else {
+		fmt.Fprintf(w, "(%d rows)\n", len(rows))
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils/httpapi"
+)
+
+// paramPrefixes are the parameter prefixes SQLite accepts for named parameters.
+var paramPrefixes = []string{"$", ":", "@"}
+
+// paramFlag collects repeated -param name=value flags.
+type paramFlag map[string]interface{}
+
+func (p paramFlag) String() string {
+	return fmt.Sprint(map[string]interface{}(p))
+}
+
+func (p paramFlag) Set(value string) error {
+	name, val, ok := strings.Cut(value, "=")
+	if !ok || name == "" {
+		return fmt.Errorf("expected name=value, got %q", value)
+	}
+	addParam(p, name, val)
+	return nil
+}
+
+// addParam stores value under name. A name without a prefix is stored under
+// every prefix so it binds to $name, :name and @name alike.
+func addParam(params map[string]interface{}, name string, value interface{}) {
+	for _, prefix := range paramPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			params[name] = value
+			return
+		}
+	}
+	for _, prefix := range paramPrefixes {
+		params[prefix+name] = value
+	}
+}
+
+// parseParamsJSON decodes a JSON object of parameters into params, as the
+// /query and /exec endpoints of serve do.
+func parseParamsJSON(params map[string]interface{}, data string) error {
+	values, err := httpapi.DecodeParams(json.RawMessage(data))
+	if err != nil {
+		return fmt.Errorf("failed to parse params JSON: %w", err)
+	}
+	for name, value := range values {
+		params[name] = value
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/chzyer/readline"
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+)
+
+const (
+	replPrompt             = "sqlite> "
+	replContinuationPrompt = "   ...> "
+	replHistoryFile        = ".sqliteutils_history"
+)
+
+const replHelp = `.help            Show this message
+.tables          List tables and views
+.schema [TABLE]  Show CREATE statements, optionally for one table
+.queries         Show the most recently executed statements
+.quit            Exit the REPL
+`
+
+// runREPL starts an interactive prompt that executes SQL statements as they
+// are completed with a semicolon.
+func runREPL(args []string) int {
+	fs := flag.NewFlagSet("repl", flag.ExitOnError)
+	dbPath := fs.String("dbpath", "sqlite.db", "Path to the SQLite database file")
+	poolSize := fs.Int("poolsize", 4, "Number of connections in the pool")
+	queryLog := fs.Int("querylog", 100, "Number of recent statements kept for .queries")
+	parseFlags(fs, args)
+
+	if !initPool(*dbPath, *poolSize) {
+		return 1
+	}
+	defer closePool()
+	sqliteutils.EnableQueryLog(*queryLog)
+
+	historyFile := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		historyFile = filepath.Join(home, replHistoryFile)
+	}
+	rl, err := readline.NewEx(&readline.Config{
+		Prompt:                 replPrompt,
+		HistoryFile:            historyFile,
+		DisableAutoSaveHistory: true,
+		InterruptPrompt:        "^C",
+		EOFPrompt:              ".quit",
+	})
+	if err != nil {
+		fmt.Printf("Failed to start REPL: %v\n", err)
+		return 1
+	}
+	defer rl.Close()
+
+	fmt.Fprintf(rl.Stdout(), "Connected to %s. Enter \".help\" for usage hints.\n", *dbPath)
+	ctx := context.Background()
+	var buffer []string
+	for {
+		line, err := rl.Readline()
+		if errors.Is(err, readline.ErrInterrupt) {
+			buffer = nil
+			rl.SetPrompt(replPrompt)
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			return 0
+		}
+		if err != nil {
+			fmt.Printf("Failed to read input: %v\n", err)
+			return 1
+		}
+
+		if len(buffer) == 0 && strings.HasPrefix(strings.TrimSpace(line), ".") {
+			rl.SaveHistory(line)
+			if quit := runDotCommand(ctx, rl.Stdout(), strings.TrimSpace(line)); quit {
+				return 0
+			}
+			continue
+		}
+
+		buffer = append(buffer, line)
+		statements, rest := sqliteutils.SplitStatements(strings.Join(buffer, "\n"))
+		if len(statements) > 0 {
+			rl.SaveHistory(strings.Join(buffer, " "))
+		}
+		for _, stmt := range statements {
+			if err := runStatement(ctx, rl.Stdout(), stmt.SQL); err != nil {
+				fmt.Fprintf(rl.Stderr(), "Error: %v\n", err)
+			}
+		}
+		buffer = nil
+		if strings.TrimSpace(rest) != "" {
+			buffer = []string{rest}
+		}
+		if len(buffer) > 0 {
+			rl.SetPrompt(replContinuationPrompt)
+		} else {
+			rl.SetPrompt(replPrompt)
+		}
+	}
+}
+
+// runStatement executes one statement and prints its result set, if any.
+func runStatement(ctx context.Context, w io.Writer, query string) error {
+	var columns []string
+	var rows [][]interface{}
+	err := exec.Query(ctx, query, nil, func(cols []string, values []interface{}) {
+		columns = cols
+		rows = append(rows, values)
+	})
+	if err != nil {
+		return err
+	}
+	if len(columns) > 0 {
+		printTable(w, columns, rows)
+	}
+	return nil
+}
+
+// runDotCommand handles a REPL meta-command and reports whether to exit.
+func runDotCommand(ctx context.Context, w io.Writer, line string) bool {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case ".quit", ".exit":
+		return true
+	case ".help":
+		fmt.Fprint(w, replHelp)
+	case ".tables":
+		err := exec.Query(ctx, `SELECT name FROM sqlite_schema
+			WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY name;`, nil,
+			func(_ []string, values []interface{}) {
+				fmt.Fprintln(w, values[0])
+			})
+		if err != nil {
+			fmt.Fprintf(w, "Error: %v\n", err)
+		}
+	case ".schema":
+		query := "SELECT sql FROM sqlite_schema WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' ORDER BY tbl_name, type DESC, name;"
+		params := map[string]interface{}{}
+		if len(fields) > 1 {
+			query = "SELECT sql FROM sqlite_schema WHERE sql IS NOT NULL AND tbl_name = $table ORDER BY type DESC, name;"
+			params["$table"] = fields[1]
+		}
+		err := exec.Query(ctx, query, params, func(_ []string, values []interface{}) {
+			fmt.Fprintf(w, "%s;\n", values[0])
+		})
+		if err != nil {
+			fmt.Fprintf(w, "Error: %v\n", err)
+		}
+	case ".queries":
+		for _, q := range sqliteutils.RecentQueries() {
+			fmt.Fprintf(w, "%s %9s %s", q.Time.Format("15:04:05.000"), q.Duration.Round(time.Microsecond), q.SQL)
+			if len(q.Params) > 0 {
+				fmt.Fprintf(w, " %v", q.Params)
+			}
+			if q.Error != "" {
+				fmt.Fprintf(w, " -- error: %s", q.Error)
+			}
+			fmt.Fprintln(w)
+		}
+	default:
+		fmt.Fprintf(w, "Unknown command %s. Enter \".help\" for usage hints.\n", fields[0])
+	}
+	return false
+}
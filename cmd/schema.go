@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/dropsite-ai/sqliteutils/export"
+)
+
+// runSchema prints the CREATE statements of the database, or a full SQL dump
+// with -data.
+func runSchema(args []string) int {
+	fs := flag.NewFlagSet("schema", flag.ExitOnError)
+	dbPath := fs.String("dbpath", "sqlite.db", "Path to the SQLite database file")
+	table := fs.String("table", "", "Only include this table and its indexes, triggers and data")
+	data := fs.Bool("data", false, "Emit a full SQL dump including INSERT statements for every row")
+	parseFlags(fs, args)
+
+	if !initPool(*dbPath, 1) {
+		return 1
+	}
+	defer closePool()
+
+	err := export.Dump(context.Background(), os.Stdout, &export.DumpOptions{Table: *table, SchemaOnly: !*data})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to dump schema: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/httpapi"
+)
+
+// runServe serves the httpapi query endpoints until interrupted.
+func runServe(args []string) int {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	dbPath := fs.String("dbpath", "sqlite.db", "Path to the SQLite database file")
+	poolSize := fs.Int("poolsize", 4, "Number of connections in the pool")
+	addr := fs.String("addr", ":8080", "Address to listen on")
+	readOnly := fs.Bool("readonly", false, "Disable /exec so no statement can write to the database")
+	token := fs.String("token", os.Getenv("SQLITEUTILS_TOKEN"), "Bearer token required by every request (defaults to $SQLITEUTILS_TOKEN)")
+	timeout := fs.Duration("timeout", 30*time.Second, "Maximum time a statement may run")
+	queryLog := fs.Int("querylog", 0, "Keep the last N statements and serve them at GET /debug/queries")
+	parseFlags(fs, args)
+
+	if !initPool(*dbPath, *poolSize) {
+		return 1
+	}
+	defer closePool()
+
+	opts := httpapi.Options{Mode: httpapi.ReadWrite, Timeout: *timeout}
+	if *readOnly {
+		opts.Mode = httpapi.ReadOnly
+	}
+	if *token != "" {
+		opts.Auth = httpapi.BearerToken(*token)
+	}
+	if *queryLog > 0 {
+		sqliteutils.EnableQueryLog(*queryLog)
+		opts.QueryLog = true
+	}
+	server := &http.Server{Addr: *addr, Handler: httpapi.NewHandler(opts), ReadHeaderTimeout: 10 * time.Second}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	mode := "read-write"
+	if *readOnly {
+		mode = "read-only"
+	}
+	fmt.Printf("Serving %s (%s) on %s\n", *dbPath, mode, *addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Printf("Failed to serve: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"reflect"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+)
+
+// watchEvent is one line of watch output.
+type watchEvent struct {
+	Op    string                 `json:"op"`
+	Table string                 `json:"table"`
+	RowID int64                  `json:"rowid"`
+	Row   map[string]interface{} `json:"row,omitempty"`
+	Old   map[string]interface{} `json:"old,omitempty"`
+	Time  time.Time              `json:"time"`
+}
+
+// watchedRow is the last seen state of a row.
+type watchedRow struct {
+	columns []string
+	values  []interface{}
+}
+
+// runWatch polls PRAGMA data_version and prints the rows of a table that
+// were inserted, updated or deleted by other connections as NDJSON.
+func runWatch(args []string) int {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	dbPath := fs.String("dbpath", "sqlite.db", "Path to the SQLite database file")
+	table := fs.String("table", "", "Table to watch")
+	interval := fs.Duration("interval", 250*time.Millisecond, "How often to check for changes")
+	initial := fs.Bool("initial", false, "Print the existing rows as inserts before watching")
+	parseFlags(fs, args)
+
+	if *table == "" {
+		fmt.Println("watch requires -table")
+		fs.Usage()
+		return 2
+	}
+	// A single connection keeps data_version comparable between polls.
+	if !initPool(*dbPath, 1) {
+		return 1
+	}
+	defer closePool()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	w := &tableWatcher{table: *table, enc: json.NewEncoder(os.Stdout)}
+	w.enc.SetEscapeHTML(false)
+	if err := w.watch(ctx, *interval, *initial); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "Failed to watch %s: %v\n", *table, err)
+		return 1
+	}
+	return 0
+}
+
+// tableWatcher diffs successive snapshots of a table by rowid.
+type tableWatcher struct {
+	table string
+	enc   *json.Encoder
+	rows  map[int64]watchedRow
+}
+
+func (w *tableWatcher) watch(ctx context.Context, interval time.Duration, initial bool) error {
+	version, err := dataVersion(ctx)
+	if err != nil {
+		return err
+	}
+	current, err := w.snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	if initial {
+		w.rows = map[int64]watchedRow{}
+		if err := w.emitChanges(current); err != nil {
+			return err
+		}
+	}
+	w.rows = current
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+		v, err := dataVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if v == version {
+			continue
+		}
+		version = v
+		current, err := w.snapshot(ctx)
+		if err != nil {
+			return err
+		}
+		if err := w.emitChanges(current); err != nil {
+			return err
+		}
+		w.rows = current
+	}
+}
+
+// snapshot reads every row of the table keyed by rowid.
+func (w *tableWatcher) snapshot(ctx context.Context) (map[int64]watchedRow, error) {
+	rows := make(map[int64]watchedRow)
+	query := "SELECT _rowid_, * FROM " + sqliteutils.QuoteIdentifier(w.table) + ";"
+	err := exec.Query(ctx, query, nil, func(columns []string, values []interface{}) {
+		rowID, _ := values[0].(int64)
+		rows[rowID] = watchedRow{columns: columns[1:], values: values[1:]}
+	})
+	return rows, err
+}
+
+// emitChanges prints the differences between the previous snapshot and
+// current in rowid order, with deletes after inserts and updates.
+func (w *tableWatcher) emitChanges(current map[int64]watchedRow) error {
+	now := time.Now().UTC()
+	ids := make([]int64, 0, len(current))
+	for id := range current {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		row := current[id]
+		old, existed := w.rows[id]
+		switch {
+		case !existed:
+			if err := w.enc.Encode(watchEvent{Op: "insert", Table: w.table, RowID: id, Row: row.object(), Time: now}); err != nil {
+				return err
+			}
+		case !reflect.DeepEqual(old.values, row.values):
+			if err := w.enc.Encode(watchEvent{Op: "update", Table: w.table, RowID: id, Row: row.object(), Old: old.object(), Time: now}); err != nil {
+				return err
+			}
+		}
+	}
+
+	var deleted []int64
+	for id := range w.rows {
+		if _, ok := current[id]; !ok {
+			deleted = append(deleted, id)
+		}
+	}
+	sort.Slice(deleted, func(i, j int) bool { return deleted[i] < deleted[j] })
+	for _, id := range deleted {
+		if err := w.enc.Encode(watchEvent{Op: "delete", Table: w.table, RowID: id, Old: w.rows[id].object(), Time: now}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r watchedRow) object() map[string]interface{} {
+	obj := make(map[string]interface{}, len(r.columns))
+	for i, name := range r.columns {
+		obj[name] = r.values[i]
+	}
+	return obj
+}
+
+// dataVersion returns PRAGMA data_version, which changes whenever another
+// connection commits to the database.
+func dataVersion(ctx context.Context) (int64, error) {
+	var version int64
+	err := exec.Query(ctx, "PRAGMA data_version;", nil, func(_ []string, values []interface{}) {
+		version, _ = values[0].(int64)
+	})
+	return version, err
+}
@@ -0,0 +1,495 @@
+// Package dbsync copies tables of the global pool to an external database,
+// such as a central PostgreSQL or MySQL warehouse, through database/sql.
+//
+// A table is copied in one of three modes. FullRefresh replaces every row
+// of the target table in one transaction. Incremental upserts the rows whose
+// updated column is at or past the last synced value, and CDC replays the
+// changes recorded by the cdc package, deleting rows that were deleted. The
+// progress of the incremental modes is kept in a state table of the target
+// database, updated in the same transaction as each batch of rows, so an
+// interrupted sync resumes where it stopped.
+//
+// It is a module of its own so that the database/sql drivers it is used
+// with, including the one its tests use, stay out of the main module.
+package dbsync
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+)
+
+// Dialect is the SQL dialect of the target database.
+type Dialect int
+
+const (
+	// Postgres uses $n placeholders and INSERT ... ON CONFLICT upserts.
+	Postgres Dialect = iota + 1
+	// MySQL uses ? placeholders and INSERT ... ON DUPLICATE KEY UPDATE
+	// upserts.
+	MySQL
+	// SQLite targets another SQLite database.
+	SQLite
+)
+
+// Mode is how a table is copied.
+type Mode int
+
+const (
+	// FullRefresh deletes every row of the target table and copies the
+	// source table again, in one target transaction.
+	FullRefresh Mode = iota
+	// Incremental upserts the rows whose Table.UpdatedColumn is at or past
+	// the largest value synced before. Deleted rows are not noticed.
+	Incremental
+	// CDC replays the changes recorded for the table by cdc.Enable since the
+	// last sync, upserting changed rows and deleting deleted ones. The table
+	// must have an INTEGER PRIMARY KEY, as changes are recorded by rowid.
+	CDC
+)
+
+// StateTable is the target table holding the progress of incremental syncs.
+const StateTable = "sqliteutils_sync"
+
+// Table is a source table to copy.
+type Table struct {
+	// Name is the source table.
+	Name string
+	// Target is the target table. Defaults to Name.
+	Target string
+	Mode   Mode
+	// UpdatedColumn is the column Incremental mode compares, such as an
+	// updated_at timestamp or a version number that grows on every write.
+	UpdatedColumn string
+}
+
+// Options configures a Syncer.
+type Options struct {
+	// Dialect is the SQL dialect of the target database. Required.
+	Dialect Dialect
+	// BatchSize is how many rows are written per INSERT statement and, in
+	// the incremental modes, per target transaction. Defaults to 500.
+	BatchSize int
+	// CreateTables creates missing target tables, with column types mapped
+	// from the declared types of the source columns and the same primary
+	// key.
+	CreateTables bool
+}
+
+// Result counts the rows a sync wrote.
+type Result struct {
+	Table    string
+	Upserted int64
+	Deleted  int64
+}
+
+// Syncer copies tables to one target database. It is safe for concurrent
+// use, but syncs of the same table must not overlap.
+type Syncer struct {
+	db   *sql.DB
+	opts Options
+}
+
+// NewSyncer returns a Syncer writing to db.
+func NewSyncer(db *sql.DB, opts Options) (*Syncer, error) {
+	if opts.Dialect < Postgres || opts.Dialect > SQLite {
+		return nil, fmt.Errorf("invalid dialect %d", opts.Dialect)
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 500
+	}
+	return &Syncer{db: db, opts: opts}, nil
+}
+
+// Sync copies tables in order, stopping at the first that fails.
+func (s *Syncer) Sync(ctx context.Context, tables ...Table) ([]Result, error) {
+	var results []Result
+	for _, table := range tables {
+		result, err := s.SyncTable(ctx, table)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// SyncTable copies one table.
+func (s *Syncer) SyncTable(ctx context.Context, table Table) (Result, error) {
+	if table.Target == "" {
+		table.Target = table.Name
+	}
+	result := Result{Table: table.Name}
+	columns, err := sourceColumns(ctx, table.Name)
+	if err != nil {
+		return result, err
+	}
+	if s.opts.CreateTables {
+		if _, err := s.db.ExecContext(ctx, s.createTableSQL(table.Target, columns)); err != nil {
+			return result, fmt.Errorf("failed to create target table %s: %w", table.Target, err)
+		}
+	}
+
+	switch table.Mode {
+	case FullRefresh:
+		err = s.fullRefresh(ctx, table, columns, &result)
+	case Incremental:
+		if table.UpdatedColumn == "" {
+			return result, fmt.Errorf("incremental sync of %s requires UpdatedColumn", table.Name)
+		}
+		err = s.incremental(ctx, table, columns, &result)
+	case CDC:
+		err = s.cdc(ctx, table, columns, &result)
+	default:
+		err = fmt.Errorf("invalid mode %d", table.Mode)
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to sync %s: %w", table.Name, err)
+	}
+	return result, nil
+}
+
+// column is a source column.
+type column struct {
+	name, declType string
+	// pk is the column's 1-based position in the primary key, or 0.
+	pk int
+}
+
+func sourceColumns(ctx context.Context, table string) ([]column, error) {
+	var columns []column
+	err := exec.Query(ctx, "SELECT name, type, pk FROM pragma_table_info(:table) ORDER BY cid;",
+		map[string]interface{}{":table": table}, func(_ []string, values []interface{}) {
+			pk, _ := values[2].(int64)
+			columns = append(columns, column{name: values[0].(string), declType: values[1].(string), pk: int(pk)})
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no such table: %s", table)
+	}
+	return columns, nil
+}
+
+// keyColumns returns the primary key columns in key order.
+func keyColumns(columns []column) []column {
+	var keys []column
+	for pos := 1; ; pos++ {
+		found := false
+		for _, c := range columns {
+			if c.pk == pos {
+				keys = append(keys, c)
+				found = true
+			}
+		}
+		if !found {
+			return keys
+		}
+	}
+}
+
+// batchRows is how many rows fit in one statement, keeping the number of
+// placeholders within the limits of every dialect.
+func (s *Syncer) batchRows(columns int) int {
+	n := s.opts.BatchSize
+	if max := 30000 / columns; n > max {
+		n = max
+	}
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// fullRefresh replaces the target table's rows in one transaction.
+func (s *Syncer) fullRefresh(ctx context.Context, table Table, columns []column, result *Result) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "DELETE FROM "+s.quote(table.Target))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil {
+		result.Deleted = n
+	}
+
+	w := &batchWriter{s: s, tx: tx, table: table.Target, columns: columns}
+	var writeErr error
+	err = exec.Query(ctx, selectSQL(table.Name, columns, ""), nil, func(_ []string, values []interface{}) {
+		if writeErr == nil {
+			writeErr = w.add(ctx, values)
+		}
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if err == nil {
+		err = w.flush(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	result.Upserted = w.written
+	return tx.Commit()
+}
+
+// incremental upserts rows changed since the stored watermark, committing
+// each batch with the largest updated value it holds.
+func (s *Syncer) incremental(ctx context.Context, table Table, columns []column, result *Result) error {
+	if len(keyColumns(columns)) == 0 {
+		return errors.New("incremental sync requires a primary key")
+	}
+	updated := -1
+	for i, c := range columns {
+		if c.name == table.UpdatedColumn {
+			updated = i
+		}
+	}
+	if updated < 0 {
+		return fmt.Errorf("no such column: %s", table.UpdatedColumn)
+	}
+	watermark, ok, err := s.watermark(ctx, table.Target)
+	if err != nil {
+		return err
+	}
+	where, params := "", map[string]interface{}(nil)
+	if ok {
+		where = "WHERE " + quoteSQLite(table.UpdatedColumn) + " >= :watermark"
+		params = map[string]interface{}{":watermark": parseWatermark(watermark)}
+	}
+	query := selectSQL(table.Name, columns, where) + " ORDER BY " + quoteSQLite(table.UpdatedColumn)
+
+	var batch [][]interface{}
+	commit := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		last := batch[len(batch)-1][updated]
+		n, err := s.writeBatch(ctx, table.Target, columns, batch, func(tx *sql.Tx) error {
+			if last == nil {
+				// Only NULL values so far, which sort first.
+				return nil
+			}
+			return s.setWatermark(ctx, tx, table.Target, formatWatermark(last))
+		})
+		result.Upserted += n
+		batch = batch[:0]
+		return err
+	}
+	var writeErr error
+	err = exec.Query(ctx, query, params, func(_ []string, values []interface{}) {
+		if writeErr != nil {
+			return
+		}
+		// Rows sharing an updated value are kept in one batch, so the
+		// watermark never falls between them.
+		if len(batch) >= s.opts.BatchSize && formatWatermark(values[updated]) != formatWatermark(batch[len(batch)-1][updated]) {
+			writeErr = commit()
+		}
+		batch = append(batch, values)
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if err == nil {
+		err = commit()
+	}
+	return err
+}
+
+// cdc replays the changes recorded for the table after the stored change ID.
+func (s *Syncer) cdc(ctx context.Context, table Table, columns []column, result *Result) error {
+	keys := keyColumns(columns)
+	if len(keys) != 1 || !strings.EqualFold(keys[0].declType, "INTEGER") {
+		return errors.New("cdc sync requires an INTEGER PRIMARY KEY")
+	}
+	watermark, _, err := s.watermark(ctx, table.Target)
+	if err != nil {
+		return err
+	}
+	lastID, _ := strconv.ParseInt(watermark, 10, 64)
+
+	for {
+		var rowIDs []interface{}
+		seen := map[int64]bool{}
+		maxID := lastID
+		err := exec.Query(ctx, "SELECT id, row_id FROM _cdc_changes WHERE tbl = :table AND id > :id ORDER BY id LIMIT :limit;",
+			map[string]interface{}{":table": table.Name, ":id": lastID, ":limit": s.opts.BatchSize},
+			func(_ []string, values []interface{}) {
+				maxID = values[0].(int64)
+				if rowID := values[1].(int64); !seen[rowID] {
+					seen[rowID] = true
+					rowIDs = append(rowIDs, rowID)
+				}
+			})
+		if err != nil {
+			return err
+		}
+		if maxID == lastID {
+			return nil
+		}
+
+		// Changed rows are read as they are now; those no longer there
+		// were deleted.
+		var rows [][]interface{}
+		key := -1
+		for i, c := range columns {
+			if c.pk == 1 {
+				key = i
+			}
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(rowIDs)), ", ")
+		err = exec.Q(ctx, selectSQL(table.Name, columns, "WHERE rowid IN ("+placeholders+")"), func(_ []string, values []interface{}) {
+			rows = append(rows, values)
+			delete(seen, values[key].(int64))
+		}, rowIDs...)
+		if err != nil {
+			return err
+		}
+		var deleted []interface{}
+		for _, rowID := range rowIDs {
+			if seen[rowID.(int64)] {
+				deleted = append(deleted, rowID)
+			}
+		}
+
+		n, err := s.writeBatch(ctx, table.Target, columns, rows, func(tx *sql.Tx) error {
+			if len(deleted) > 0 {
+				query := fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)", s.quote(table.Target), s.quote(keys[0].name), s.placeholders(1, len(deleted)))
+				if _, err := tx.ExecContext(ctx, query, deleted...); err != nil {
+					return err
+				}
+			}
+			return s.setWatermark(ctx, tx, table.Target, strconv.FormatInt(maxID, 10))
+		})
+		if err != nil {
+			return err
+		}
+		result.Upserted += n
+		result.Deleted += int64(len(deleted))
+		lastID = maxID
+	}
+}
+
+// writeBatch upserts rows and runs finish in one target transaction.
+func (s *Syncer) writeBatch(ctx context.Context, target string, columns []column, rows [][]interface{}, finish func(tx *sql.Tx) error) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	w := &batchWriter{s: s, tx: tx, table: target, columns: columns, upsert: true}
+	for _, values := range rows {
+		if err := w.add(ctx, values); err != nil {
+			return 0, err
+		}
+	}
+	if err := w.flush(ctx); err != nil {
+		return 0, err
+	}
+	if err := finish(tx); err != nil {
+		return 0, err
+	}
+	return w.written, tx.Commit()
+}
+
+// batchWriter inserts rows into a target table with multi-row INSERT
+// statements.
+type batchWriter struct {
+	s       *Syncer
+	tx      *sql.Tx
+	table   string
+	columns []column
+	upsert  bool
+	args    []interface{}
+	rows    int
+	written int64
+}
+
+func (w *batchWriter) add(ctx context.Context, values []interface{}) error {
+	w.args = append(w.args, values...)
+	w.rows++
+	if w.rows >= w.s.batchRows(len(w.columns)) {
+		return w.flush(ctx)
+	}
+	return nil
+}
+
+func (w *batchWriter) flush(ctx context.Context) error {
+	if w.rows == 0 {
+		return nil
+	}
+	if _, err := w.tx.ExecContext(ctx, w.s.insertSQL(w.table, w.columns, w.rows, w.upsert), w.args...); err != nil {
+		return err
+	}
+	w.written += int64(w.rows)
+	w.args, w.rows = w.args[:0], 0
+	return nil
+}
+
+// watermark returns the stored progress of target, creating the state
+// table if needed.
+func (s *Syncer) watermark(ctx context.Context, target string) (string, bool, error) {
+	textType := "TEXT"
+	if s.opts.Dialect == MySQL {
+		textType = "VARCHAR(255)"
+	}
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (table_name %s PRIMARY KEY, watermark TEXT NOT NULL)", s.quote(StateTable), textType)
+	if _, err := s.db.ExecContext(ctx, create); err != nil {
+		return "", false, fmt.Errorf("failed to create %s: %w", StateTable, err)
+	}
+	var watermark string
+	query := fmt.Sprintf("SELECT watermark FROM %s WHERE table_name = %s", s.quote(StateTable), s.placeholders(1, 1))
+	err := s.db.QueryRowContext(ctx, query, target).Scan(&watermark)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read watermark of %s: %w", target, err)
+	}
+	return watermark, true, nil
+}
+
+func (s *Syncer) setWatermark(ctx context.Context, tx *sql.Tx, target, watermark string) error {
+	state := []column{{name: "table_name", pk: 1}, {name: "watermark"}}
+	if _, err := tx.ExecContext(ctx, s.insertSQL(StateTable, state, 1, true), target, watermark); err != nil {
+		return fmt.Errorf("failed to store watermark of %s: %w", target, err)
+	}
+	return nil
+}
+
+// formatWatermark renders an updated value for the state table.
+func formatWatermark(v interface{}) string {
+	switch v := v.(type) {
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// parseWatermark returns a stored watermark as the number it renders, if
+// any, so that it compares with numeric columns as a number.
+func parseWatermark(s string) interface{} {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
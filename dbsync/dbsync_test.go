@@ -0,0 +1,121 @@
+package dbsync_test
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/cdc"
+	"github.com/dropsite-ai/sqliteutils/dbsync"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+const migration = `
+	CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, score REAL, avatar BLOB, updated_at INTEGER);
+	INSERT INTO users VALUES (1, 'ada', 1.5, x'01', 100), (2, 'bob', NULL, NULL, 100), (3, 'cy', 3, NULL, 200);
+`
+
+// targetRows returns the id, name and score of the rows of table ordered by
+// id.
+func targetRows(t *testing.T, db *sql.DB, table string) [][]interface{} {
+	rows, err := db.Query("SELECT id, name, score FROM " + table + " ORDER BY id")
+	require.NoError(t, err)
+	defer rows.Close()
+	var out [][]interface{}
+	for rows.Next() {
+		var id int64
+		var name sql.NullString
+		var score sql.NullFloat64
+		require.NoError(t, rows.Scan(&id, &name, &score))
+		row := []interface{}{id, nil, nil}
+		if name.Valid {
+			row[1] = name.String
+		}
+		if score.Valid {
+			row[2] = score.Float64
+		}
+		out = append(out, row)
+	}
+	require.NoError(t, rows.Err())
+	return out
+}
+
+func openTarget(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", filepath.Join(t.TempDir(), "warehouse.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSync(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, cdc.Enable(ctx, []string{"users"}, false))
+
+	// The Postgres dialect's $n placeholders and ON CONFLICT upserts also
+	// run on SQLite.
+	for _, dialect := range []dbsync.Dialect{dbsync.SQLite, dbsync.Postgres} {
+		db := openTarget(t)
+		s, err := dbsync.NewSyncer(db, dbsync.Options{Dialect: dialect, BatchSize: 1, CreateTables: true})
+		require.NoError(t, err)
+
+		results, err := s.Sync(ctx,
+			dbsync.Table{Name: "users", Target: "users_full"},
+			dbsync.Table{Name: "users", Target: "users_incr", Mode: dbsync.Incremental, UpdatedColumn: "updated_at"},
+			dbsync.Table{Name: "users", Target: "users_cdc", Mode: dbsync.CDC},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, []dbsync.Result{
+			{Table: "users", Upserted: 3},
+			{Table: "users", Upserted: 3},
+			{Table: "users", Upserted: 0},
+		}, results)
+		all := [][]interface{}{{int64(1), "ada", 1.5}, {int64(2), "bob", nil}, {int64(3), "cy", 3.0}}
+		assert.Equal(t, all, targetRows(t, db, "users_full"))
+		assert.Equal(t, all, targetRows(t, db, "users_incr"))
+
+		var avatar []byte
+		require.NoError(t, db.QueryRow("SELECT avatar FROM users_full WHERE id = 1").Scan(&avatar))
+		assert.Equal(t, []byte{1}, avatar)
+	}
+
+	// Later changes are picked up by the incremental modes; the watermark
+	// row is synced again since rows may share it.
+	db := openTarget(t)
+	s, err := dbsync.NewSyncer(db, dbsync.Options{Dialect: dbsync.SQLite, CreateTables: true})
+	require.NoError(t, err)
+	incr := dbsync.Table{Name: "users", Mode: dbsync.Incremental, UpdatedColumn: "updated_at"}
+	cdcTable := dbsync.Table{Name: "users", Target: "users_cdc", Mode: dbsync.CDC}
+	_, err = s.Sync(ctx, incr, cdcTable)
+	require.NoError(t, err)
+
+	require.NoError(t, exec.E(ctx, "UPDATE users SET name = 'BOB', updated_at = 300 WHERE id = 2;"))
+	require.NoError(t, exec.E(ctx, "INSERT INTO users (id, name, updated_at) VALUES (4, 'dee', 300);"))
+	require.NoError(t, exec.E(ctx, "DELETE FROM users WHERE id = 1;"))
+
+	results, err := s.Sync(ctx, incr, cdcTable)
+	require.NoError(t, err)
+	assert.Equal(t, []dbsync.Result{
+		{Table: "users", Upserted: 3},
+		{Table: "users", Upserted: 2, Deleted: 1},
+	}, results)
+	assert.Equal(t, [][]interface{}{{int64(1), "ada", 1.5}, {int64(2), "BOB", nil}, {int64(3), "cy", 3.0}, {int64(4), "dee", nil}},
+		targetRows(t, db, "users"))
+	assert.Equal(t, [][]interface{}{{int64(2), "BOB", nil}, {int64(4), "dee", nil}}, targetRows(t, db, "users_cdc"))
+
+	_, err = s.SyncTable(ctx, dbsync.Table{Name: "users", Mode: dbsync.Incremental})
+	assert.Error(t, err)
+	_, err = s.SyncTable(ctx, dbsync.Table{Name: "missing"})
+	assert.Error(t, err)
+	_, err = dbsync.NewSyncer(db, dbsync.Options{})
+	assert.Error(t, err)
+}
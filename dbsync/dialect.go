@@ -0,0 +1,155 @@
+package dbsync
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+)
+
+// quote quotes an identifier for the target database.
+func (s *Syncer) quote(name string) string {
+	if s.opts.Dialect == MySQL {
+		return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+	}
+	return sqliteutils.QuoteIdentifier(name)
+}
+
+// quoteSQLite quotes an identifier for the source database.
+func quoteSQLite(name string) string {
+	return sqliteutils.QuoteIdentifier(name)
+}
+
+// placeholders returns n comma-separated placeholders, numbered from first
+// for Postgres.
+func (s *Syncer) placeholders(first, n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if s.opts.Dialect == Postgres {
+			fmt.Fprintf(&b, "$%d", first+i)
+		} else {
+			b.WriteString("?")
+		}
+	}
+	return b.String()
+}
+
+// selectSQL selects columns of the source table.
+func selectSQL(table string, columns []column, where string) string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = quoteSQLite(c.name)
+	}
+	query := "SELECT " + strings.Join(names, ", ") + " FROM " + quoteSQLite(table)
+	if where != "" {
+		query += " " + where
+	}
+	return query
+}
+
+// insertSQL inserts rows rows of columns into table, updating rows whose
+// primary key exists already when upsert is set.
+func (s *Syncer) insertSQL(table string, columns []column, rows int, upsert bool) string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = s.quote(c.name)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", s.quote(table), strings.Join(names, ", "))
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(" + s.placeholders(r*len(columns)+1, len(columns)) + ")")
+	}
+	if !upsert {
+		return b.String()
+	}
+
+	keys := keyColumns(columns)
+	var updates []string
+	for _, c := range columns {
+		if c.pk != 0 {
+			continue
+		}
+		if s.opts.Dialect == MySQL {
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", s.quote(c.name), s.quote(c.name)))
+		} else {
+			updates = append(updates, fmt.Sprintf("%s = excluded.%s", s.quote(c.name), s.quote(c.name)))
+		}
+	}
+	if s.opts.Dialect == MySQL {
+		if len(updates) == 0 {
+			updates = []string{fmt.Sprintf("%s = %s", s.quote(keys[0].name), s.quote(keys[0].name))}
+		}
+		return b.String() + " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+	}
+	keyNames := make([]string, len(keys))
+	for i, c := range keys {
+		keyNames[i] = s.quote(c.name)
+	}
+	if len(updates) == 0 {
+		return b.String() + " ON CONFLICT (" + strings.Join(keyNames, ", ") + ") DO NOTHING"
+	}
+	return b.String() + " ON CONFLICT (" + strings.Join(keyNames, ", ") + ") DO UPDATE SET " + strings.Join(updates, ", ")
+}
+
+// createTableSQL creates the target table for columns if it is missing.
+func (s *Syncer) createTableSQL(table string, columns []column) string {
+	defs := make([]string, len(columns))
+	for i, c := range columns {
+		defs[i] = s.quote(c.name) + " " + s.columnType(c)
+	}
+	if keys := keyColumns(columns); len(keys) > 0 {
+		names := make([]string, len(keys))
+		for i, c := range keys {
+			names[i] = s.quote(c.name)
+		}
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(names, ", ")+")")
+	}
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", s.quote(table), strings.Join(defs, ", "))
+}
+
+// columnType maps the declared type of a source column to a target type by
+// SQLite's column affinity rules.
+func (s *Syncer) columnType(c column) string {
+	t := strings.ToUpper(c.declType)
+	affinity := "NUMERIC"
+	switch {
+	case strings.Contains(t, "INT"):
+		affinity = "INTEGER"
+	case strings.Contains(t, "CHAR"), strings.Contains(t, "CLOB"), strings.Contains(t, "TEXT"):
+		affinity = "TEXT"
+	case strings.Contains(t, "BLOB"):
+		affinity = "BLOB"
+	case t == "":
+		// Columns without a type may hold anything; text holds most.
+		affinity = "TEXT"
+	case strings.Contains(t, "REAL"), strings.Contains(t, "FLOA"), strings.Contains(t, "DOUB"):
+		affinity = "REAL"
+	}
+
+	switch s.opts.Dialect {
+	case Postgres:
+		return map[string]string{
+			"INTEGER": "BIGINT", "REAL": "DOUBLE PRECISION", "TEXT": "TEXT", "BLOB": "BYTEA", "NUMERIC": "NUMERIC",
+		}[affinity]
+	case MySQL:
+		if c.pk != 0 {
+			// MySQL keys need a bounded length.
+			switch affinity {
+			case "TEXT":
+				return "VARCHAR(255)"
+			case "BLOB":
+				return "VARBINARY(255)"
+			}
+		}
+		return map[string]string{
+			"INTEGER": "BIGINT", "REAL": "DOUBLE", "TEXT": "LONGTEXT", "BLOB": "LONGBLOB", "NUMERIC": "DECIMAL(65,30)",
+		}[affinity]
+	}
+	return affinity
+}
@@ -0,0 +1,106 @@
+package dbsync_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/dbsync"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a database/sql driver that records the statements run on it
+// instead of running them, so the SQL of dialects without a database at
+// hand can be checked. Queries return no rows.
+type recorder struct {
+	statements []string
+}
+
+func (r *recorder) Connect(context.Context) (driver.Conn, error) { return recorderConn{r}, nil }
+func (r *recorder) Driver() driver.Driver                        { return nil }
+
+type recorderConn struct{ r *recorder }
+
+func (c recorderConn) Prepare(query string) (driver.Stmt, error) {
+	return recorderStmt{c.r, query}, nil
+}
+func (c recorderConn) Close() error              { return nil }
+func (c recorderConn) Begin() (driver.Tx, error) { return recorderTx{}, nil }
+
+type recorderTx struct{}
+
+func (recorderTx) Commit() error   { return nil }
+func (recorderTx) Rollback() error { return nil }
+
+type recorderStmt struct {
+	r     *recorder
+	query string
+}
+
+func (s recorderStmt) Close() error  { return nil }
+func (s recorderStmt) NumInput() int { return -1 }
+
+func (s recorderStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.r.statements = append(s.r.statements, s.query)
+	return driver.RowsAffected(0), nil
+}
+
+func (s recorderStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.r.statements = append(s.r.statements, s.query)
+	return noRows{}, nil
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string         { return []string{"value"} }
+func (noRows) Close() error              { return nil }
+func (noRows) Next([]driver.Value) error { return io.EOF }
+
+func TestSync_MySQL(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration+`
+		CREATE TABLE tags (name TEXT PRIMARY KEY, data BLOB, weight NUMERIC, "odd`+"`"+`name");
+		INSERT INTO tags VALUES ('a', x'01', 1, 'x'), ('b', NULL, 2, NULL);
+		CREATE TABLE seen (id INTEGER PRIMARY KEY);
+		INSERT INTO seen VALUES (7);
+	`, 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	r := &recorder{}
+	db := sql.OpenDB(r)
+	defer db.Close()
+	s, err := dbsync.NewSyncer(db, dbsync.Options{Dialect: dbsync.MySQL, BatchSize: 2, CreateTables: true})
+	require.NoError(t, err)
+	_, err = s.Sync(ctx,
+		dbsync.Table{Name: "users"},
+		dbsync.Table{Name: "tags", Target: "tags_incr", Mode: dbsync.Incremental, UpdatedColumn: "weight"},
+		dbsync.Table{Name: "seen", Mode: dbsync.Incremental, UpdatedColumn: "id"},
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"CREATE TABLE IF NOT EXISTS `users` (`id` BIGINT, `name` LONGTEXT, `score` DOUBLE, `avatar` LONGBLOB, `updated_at` BIGINT, PRIMARY KEY (`id`))",
+		"DELETE FROM `users`",
+		"INSERT INTO `users` (`id`, `name`, `score`, `avatar`, `updated_at`) VALUES (?, ?, ?, ?, ?), (?, ?, ?, ?, ?)",
+		"INSERT INTO `users` (`id`, `name`, `score`, `avatar`, `updated_at`) VALUES (?, ?, ?, ?, ?)",
+		// Text and blob keys get a bounded length.
+		"CREATE TABLE IF NOT EXISTS `tags_incr` (`name` VARCHAR(255), `data` LONGBLOB, `weight` DECIMAL(65,30), `odd``name` LONGTEXT, PRIMARY KEY (`name`))",
+		"CREATE TABLE IF NOT EXISTS `sqliteutils_sync` (table_name VARCHAR(255) PRIMARY KEY, watermark TEXT NOT NULL)",
+		"SELECT watermark FROM `sqliteutils_sync` WHERE table_name = ?",
+		"INSERT INTO `tags_incr` (`name`, `data`, `weight`, `odd``name`) VALUES (?, ?, ?, ?), (?, ?, ?, ?) ON DUPLICATE KEY UPDATE `data` = VALUES(`data`), `weight` = VALUES(`weight`), `odd``name` = VALUES(`odd``name`)",
+		"INSERT INTO `sqliteutils_sync` (`table_name`, `watermark`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `watermark` = VALUES(`watermark`)",
+		// Rows of key-only tables are left alone when they exist.
+		"CREATE TABLE IF NOT EXISTS `seen` (`id` BIGINT, PRIMARY KEY (`id`))",
+		"CREATE TABLE IF NOT EXISTS `sqliteutils_sync` (table_name VARCHAR(255) PRIMARY KEY, watermark TEXT NOT NULL)",
+		"SELECT watermark FROM `sqliteutils_sync` WHERE table_name = ?",
+		"INSERT INTO `seen` (`id`) VALUES (?) ON DUPLICATE KEY UPDATE `id` = `id`",
+		"INSERT INTO `sqliteutils_sync` (`table_name`, `watermark`) VALUES (?, ?) ON DUPLICATE KEY UPDATE `watermark` = VALUES(`watermark`)",
+	}, r.statements)
+}
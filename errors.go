@@ -8,6 +8,25 @@ import (
 // Common errors
 var (
 	ErrPoolNotInitialized = errors.New("pool not initialized")
+	ErrNoWALGeneration    = errors.New("no wal generation found")
+	ErrCheckpointBusy     = errors.New("checkpoint could not complete while the wal is in use")
+	ErrInsufficientSpace  = errors.New("not enough free disk space")
+	ErrChecksumMismatch   = errors.New("backup checksum does not match manifest")
+	ErrPageSizeMismatch   = errors.New("backup page size does not match destination")
+	ErrNewerSchemaVersion = errors.New("backup schema version is newer than destination")
+
+	ErrIrreversibleMigration = errors.New("migration is not reversible")
+	ErrMigrationLocked       = errors.New("migrations are locked by another process")
+	ErrMigrationLockLost     = errors.New("migration lock lease expired")
+
+	ErrSchemaMismatch = errors.New("database schema does not match expected schema")
+
+	ErrRowNotFound  = errors.New("row not found")
+	ErrStaleVersion = errors.New("row version is stale")
+	ErrReadOnly     = errors.New("statement is not allowed in a read-only transaction")
+
+	ErrQueueEmpty = errors.New("no job is due")
+	ErrLeaseLost  = errors.New("job lease expired or was taken over")
 )
 
 // Error functions
@@ -73,3 +92,52 @@ func BackupStepFailedError(err error) error {
 	}
 	return fmt.Errorf("backup step failed: %w", err)
 }
+
+func FailedToReadWALError(err error, path string) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("failed to read wal: [%s] %w", path, err)
+}
+
+func FailedToWriteWALSegmentError(err error, path string) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("failed to write wal segment: [%s] %w", path, err)
+}
+
+func FailedToApplyWALSegmentError(err error, path string) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("failed to apply wal segment: [%s] %w", path, err)
+}
+
+func FailedToCheckpointError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("failed to checkpoint: %w", err)
+}
+
+func FailedToVerifyBackupError(err error, path string) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("failed to verify backup: [%s] %w", path, err)
+}
+
+func FailedToWriteManifestError(err error, path string) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("failed to write manifest: [%s] %w", path, err)
+}
+
+func FailedToReadManifestError(err error, path string) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("failed to read manifest: [%s] %w", path, err)
+}
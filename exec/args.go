@@ -0,0 +1,98 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+)
+
+// E executes a single SQL statement with its parameters bound from args in
+// order, for scripts and tests where building a parameter map is ceremony:
+//
+//	err := exec.E(ctx, "INSERT INTO t (a, b) VALUES (?, ?)", a, b)
+//
+// Named parameters are bound by position too. args take the same types as
+// the values of a parameter map, and their number must match the statement's
+// parameters.
+func E(ctx context.Context, query string, args ...interface{}) error {
+	return Q(ctx, query, nil, args...)
+}
+
+// Q is Query with its parameters bound from args in order, as for E.
+func Q(ctx context.Context, query string, rowFunc func(columns []string, values []interface{}), args ...interface{}) error {
+	p, err := pool.GetPool()
+	if err != nil {
+		return fmt.Errorf("failed to create database pool: %w", err)
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return fmt.Errorf("failed to obtain database connection: %w", err)
+	}
+	defer p.Put(conn)
+	err = queryArgs(conn, query, args, rowFunc)
+	rollbackAfterPanic(conn, err)
+	return err
+}
+
+// queryArgs is QueryConn with positional arguments.
+func queryArgs(conn *sqlite.Conn, query string, args []interface{}, rowFunc func(columns []string, values []interface{})) (err error) {
+	defer countQuery(&err)
+	trimmedQuery := trimQuery(query)
+	prof := startProfile()
+	defer func() {
+		if prof != nil {
+			prof.finish(trimmedQuery, argParams(args), err)
+		}
+	}()
+	stmt, err := conn.Prepare(trimmedQuery)
+	if err != nil {
+		return fmt.Errorf("SQL preparation error for query '%s': %w", trimmedQuery, err)
+	}
+	defer stmt.Finalize()
+	if n := stmt.BindParamCount(); n != len(args) {
+		return fmt.Errorf("query '%s' has %d parameters, but %d arguments were given", trimmedQuery, n, len(args))
+	}
+	defer recoverCallback(stmt, &err)
+	for i, arg := range args {
+		if err := bindValue(conn, stmt, i+1, fmt.Sprintf("%d", i+1), arg); err != nil {
+			return fmt.Errorf("failed to bind arguments for query '%s': %w", trimmedQuery, err)
+		}
+	}
+
+	var columns []string
+	var reader columnReader
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return fmt.Errorf("error executing SQL query '%s': %w", trimmedQuery, err)
+		}
+		if !hasRow {
+			return nil
+		}
+		prof.row()
+		if rowFunc != nil {
+			if columns == nil {
+				columns = columnNames(stmt)
+				if reader, err = newColumnReader(conn, stmt, columns); err != nil {
+					return fmt.Errorf("error reading result of query '%s': %w", trimmedQuery, err)
+				}
+			}
+			values := make([]interface{}, len(columns))
+			if err := reader.values(stmt, values); err != nil {
+				return fmt.Errorf("error reading result of query '%s': %w", trimmedQuery, err)
+			}
+			rowFunc(columns, values)
+		}
+	}
+}
+
+// argParams names args by position, as ?1, ?2 and so on, for the query log.
+func argParams(args []interface{}) map[string]interface{} {
+	params := make(map[string]interface{}, len(args))
+	for i, arg := range args {
+		params[fmt.Sprintf("?%d", i+1)] = arg
+	}
+	return params
+}
@@ -0,0 +1,30 @@
+package exec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestE(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE t (a TEXT, b INTEGER, c BLOB);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	require.NoError(t, exec.E(ctx, "INSERT INTO t (a, b, c) VALUES (?, ?, ?);", "x", 1, []byte{1}))
+	require.NoError(t, exec.E(ctx, "INSERT INTO t (a, b) VALUES (:a, ?2);", "y", nil))
+	assert.Error(t, exec.E(ctx, "INSERT INTO t (a, b) VALUES (?, ?);", "z"))
+
+	var rows [][]interface{}
+	require.NoError(t, exec.Q(ctx, "SELECT a, b FROM t WHERE a >= ? ORDER BY a;", func(_ []string, values []interface{}) {
+		rows = append(rows, values)
+	}, "x"))
+	assert.Equal(t, [][]interface{}{{"x", int64(1)}, {"y", nil}}, rows)
+}
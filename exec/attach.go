@@ -0,0 +1,73 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+)
+
+// WithAttached attaches the databases in attachments, which maps schema
+// aliases to file paths or URIs, to one connection from the global pool and
+// runs fn in a deferred transaction on it, so its queries can join tables
+// across databases as alias.table. The transaction is committed if fn
+// returns nil. The databases are detached before the connection is returned
+// to the pool, whether or not fn succeeds.
+func WithAttached(ctx context.Context, attachments map[string]string, fn func(tx *Tx) error) (err error) {
+	aliases := make([]string, 0, len(attachments))
+	for alias := range attachments {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	p, err := pool.GetPool()
+	if err != nil {
+		return fmt.Errorf("failed to create database pool: %w", err)
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return fmt.Errorf("failed to obtain database connection: %w", err)
+	}
+	defer p.Put(conn)
+
+	var attached []string
+	defer func() {
+		if detachErr := detachAll(conn, attached); detachErr != nil {
+			err = errors.Join(err, detachErr)
+		}
+	}()
+	for _, alias := range aliases {
+		stmt, _, prepErr := conn.PrepareTransient("ATTACH DATABASE ? AS " + sqliteutils.QuoteIdentifier(alias) + ";")
+		if prepErr != nil {
+			return fmt.Errorf("failed to attach %s: %w", alias, prepErr)
+		}
+		stmt.BindText(1, attachments[alias])
+		_, stepErr := stmt.Step()
+		stmt.Finalize()
+		if stepErr != nil {
+			return fmt.Errorf("failed to attach %s: %w", alias, stepErr)
+		}
+		attached = append(attached, alias)
+	}
+
+	tx, err := beginConn(conn, TxDeferred, func() {})
+	if err != nil {
+		return err
+	}
+	return runTx(tx, fn)
+}
+
+// detachAll detaches aliases from conn in reverse order.
+func detachAll(conn *sqlite.Conn, aliases []string) error {
+	var errs []error
+	for i := len(aliases) - 1; i >= 0; i-- {
+		if err := executeRawStatement(conn, "DETACH DATABASE "+sqliteutils.QuoteIdentifier(aliases[i])+";"); err != nil {
+			errs = append(errs, fmt.Errorf("failed to detach %s: %w", aliases[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,57 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestWithAttached(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO orders VALUES (1, 10), (2, 20);", nil, nil))
+
+	crmPath := filepath.Join(t.TempDir(), "crm.db")
+	crm, err := sqlite.OpenConn(crmPath)
+	require.NoError(t, err)
+	require.NoError(t, sqlitex.ExecuteScript(crm, "CREATE TABLE customers (id INTEGER PRIMARY KEY, name TEXT); INSERT INTO customers VALUES (10, 'alice'), (20, 'bob');", nil))
+	require.NoError(t, crm.Close())
+
+	var names []string
+	err = exec.WithAttached(ctx, map[string]string{"crm": crmPath}, func(tx *exec.Tx) error {
+		if err := tx.Exec("UPDATE crm.customers SET name = upper(name) WHERE id = 10;", nil, nil); err != nil {
+			return err
+		}
+		return tx.Query("SELECT c.name FROM orders o JOIN crm.customers c ON c.id = o.customer_id ORDER BY o.id;", nil,
+			func(_ []string, values []interface{}) { names = append(names, values[0].(string)) })
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ALICE", "bob"}, names)
+
+	// The connection is detached even when fn fails, and its writes roll back.
+	errBoom := errors.New("boom")
+	err = exec.WithAttached(ctx, map[string]string{"crm": crmPath}, func(tx *exec.Tx) error {
+		require.NoError(t, tx.Exec("DELETE FROM crm.customers;", nil, nil))
+		return errBoom
+	})
+	assert.ErrorIs(t, err, errBoom)
+	assert.Error(t, exec.Exec(ctx, "SELECT * FROM crm.customers;", nil, nil))
+
+	var count int64
+	require.NoError(t, exec.WithAttached(ctx, map[string]string{"crm": crmPath}, func(tx *exec.Tx) error {
+		return tx.Query("SELECT count(*) FROM crm.customers;", nil, func(_ []string, values []interface{}) { count = values[0].(int64) })
+	}))
+	assert.Equal(t, int64(2), count)
+}
@@ -5,9 +5,10 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
+	"time"
 
 	"zombiezen.com/go/sqlite"
-	"zombiezen.com/go/sqlite/sqlitex"
 
 	"github.com/dropsite-ai/sqliteutils/pool"
 )
@@ -25,7 +26,7 @@ func CreateBlob(
 	if err != nil {
 		return 0, err
 	}
-	conn, err := p.Take(ctx)
+	conn, err := pool.Take(ctx, p)
 	if err != nil {
 		return 0, err
 	}
@@ -49,22 +50,10 @@ func CreateBlob(
 		strings.Join(colNames, ", "),
 		strings.Join(colParams, ", "),
 	)
-	if err = executeNoRows(conn, insertSQL, paramMap); err != nil {
-		return 0, fmt.Errorf("failed to insert zeroblob row: %w", err)
-	}
-
-	// Get the rowID of the newly inserted row.
-	var rowID int64
-	err = sqlitex.Execute(conn, "SELECT last_insert_rowid() as id;", &sqlitex.ExecOptions{
-		ResultFunc: func(stmt *sqlite.Stmt) error {
-			rowID = stmt.ColumnInt64(0)
-			return nil
-		},
-	})
+	rowID, err := InsertGetIDConn(conn, insertSQL, paramMap)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get rowID: %w", err)
+		return 0, fmt.Errorf("failed to insert zeroblob row: %w", err)
 	}
-
 	return rowID, nil
 }
 
@@ -82,7 +71,7 @@ func WriteBlobChunk(
 	if err != nil {
 		return err
 	}
-	conn, err := p.Take(ctx)
+	conn, err := pool.Take(ctx, p)
 	if err != nil {
 		return err
 	}
@@ -98,7 +87,9 @@ func WriteBlobChunk(
 	if _, err = blob.Seek(offset, io.SeekStart); err != nil {
 		return fmt.Errorf("blob seek error: %w", err)
 	}
+	start := time.Now()
 	n, err := blob.Write(data)
+	recordBlobWrite(int64(n), time.Since(start))
 	if err != nil {
 		return fmt.Errorf("blob write error: %w", err)
 	}
@@ -123,7 +114,7 @@ func StreamReadBlob(
 	if err != nil {
 		return 0, err
 	}
-	conn, err := p.Take(ctx)
+	conn, err := pool.Take(ctx, p)
 	if err != nil {
 		return 0, err
 	}
@@ -140,10 +131,93 @@ func StreamReadBlob(
 			return 0, fmt.Errorf("seek failed: %w", err)
 		}
 	}
-	if length < 0 {
-		return io.Copy(w, blob)
+	var src io.Reader = blob
+	if length >= 0 {
+		src = io.LimitReader(blob, length)
+	}
+	start := time.Now()
+	n, err := copyBlob(conn, w, src)
+	recordBlobRead(n, time.Since(start))
+	return n, err
+}
+
+// WriteBlobFromReader copies r into the blob identified by table, column and
+// rowID, starting at offset, and returns the number of bytes written. The
+// blob must already be large enough, as created by CreateBlob.
+func WriteBlobFromReader(
+	ctx context.Context,
+	table string,
+	column string,
+	rowID int64,
+	offset int64,
+	r io.Reader,
+) (int64, error) {
+	p, err := pool.GetPool()
+	if err != nil {
+		return 0, err
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return 0, err
+	}
+	defer p.Put(conn)
+
+	blob, err := conn.OpenBlob("", table, column, rowID, true)
+	if err != nil {
+		return 0, fmt.Errorf("open blob handle failed: %w", err)
+	}
+	defer blob.Close()
+
+	if _, err = blob.Seek(offset, io.SeekStart); err != nil {
+		return 0, fmt.Errorf("blob seek error: %w", err)
 	}
-	return io.Copy(w, io.LimitReader(blob, length))
+	start := time.Now()
+	n, err := copyBlob(conn, blob, r)
+	recordBlobWrite(n, time.Since(start))
+	if err != nil {
+		return n, fmt.Errorf("blob write error: %w", err)
+	}
+	return n, nil
+}
+
+// DefaultBlobBufferSize is the size of the copy buffers used for streaming
+// blobs on pools without WithBlobBufferSize.
+const DefaultBlobBufferSize = 64 * 1024
+
+// blobBufferSizeKey is the pool.ConnValue key of the blob buffer size of a
+// pool.
+type blobBufferSizeKey struct{}
+
+// blobBuffers holds a *sync.Pool of *[]byte buffers for each buffer size.
+var blobBuffers sync.Map
+
+// WithBlobBufferSize sets the size of the pooled buffers StreamReadBlob and
+// WriteBlobFromReader copy through on the connections of a pool. Sizes below
+// 1 keep the default.
+func WithBlobBufferSize(size int) pool.Option {
+	if size < 1 {
+		size = DefaultBlobBufferSize
+	}
+	return pool.WithConnValue(blobBufferSizeKey{}, size)
+}
+
+// copyBlob copies src to dst through a pooled buffer of the size set for
+// the pool conn belongs to.
+func copyBlob(conn *sqlite.Conn, dst io.Writer, src io.Reader) (int64, error) {
+	size, ok := pool.ConnValue(conn, blobBufferSizeKey{}).(int)
+	if !ok {
+		size = DefaultBlobBufferSize
+	}
+	buffers, _ := blobBuffers.LoadOrStore(size, &sync.Pool{
+		New: func() interface{} {
+			b := make([]byte, size)
+			return &b
+		},
+	})
+	buf := buffers.(*sync.Pool).Get().(*[]byte)
+	defer buffers.(*sync.Pool).Put(buf)
+	// Hide ReaderFrom and WriterTo so that the copy goes through buf.
+	return io.CopyBuffer(struct{ io.Writer }{dst}, struct{ io.Reader }{src}, *buf)
 }
 
 // executeNoRows is a helper that prepares and executes a statement that does not return any rows.
@@ -154,7 +228,9 @@ func executeNoRows(conn *sqlite.Conn, query string, params map[string]interface{
 	}
 	defer stmt.Finalize()
 
-	bindParams(stmt, params)
+	if err := bindParams(conn, stmt, params); err != nil {
+		return err
+	}
 
 	for {
 		hasRow, stepErr := stmt.Step()
@@ -184,3 +184,62 @@ func TestStreamReadBlob_InvalidRow(t *testing.T) {
 		t.Fatal("expected error when reading blob from non-existent row, but got none")
 	}
 }
+
+// TestWriteBlobFromReader streams a reader into a blob through buffers
+// smaller than the content and reads it back, checking the stream stats.
+func TestWriteBlobFromReader(t *testing.T) {
+	ctx := context.Background()
+	const migration = `
+		CREATE TABLE test_blob (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			data BLOB
+		);
+	`
+	if err := test.Pool(ctx, t, migration, 1, exec.WithBlobBufferSize(7)); err != nil {
+		t.Fatalf("failed to initialize pool: %v", err)
+	}
+	defer func() {
+		if err := pool.ClosePool(); err != nil {
+			t.Errorf("failed to close pool: %v", err)
+		}
+	}()
+
+	content := bytes.Repeat([]byte("0123456789"), 10)
+	rowID, err := exec.CreateBlob(ctx, "test_blob", "data", int64(len(content))+5, nil)
+	if err != nil {
+		t.Fatalf("CreateBlob failed: %v", err)
+	}
+	before := exec.GetStats()
+
+	n, err := exec.WriteBlobFromReader(ctx, "test_blob", "data", rowID, 5, bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("WriteBlobFromReader failed: %v", err)
+	}
+	if n != int64(len(content)) {
+		t.Fatalf("wrote %d bytes, expected %d", n, len(content))
+	}
+
+	var buf bytes.Buffer
+	if _, err := exec.StreamReadBlob(ctx, "test_blob", "data", rowID, 5, -1, &buf); err != nil {
+		t.Fatalf("StreamReadBlob failed: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), content) {
+		t.Fatalf("read %q, expected %q", buf.Bytes(), content)
+	}
+
+	after := exec.GetStats()
+	if got := after.BlobBytesWritten - before.BlobBytesWritten; got != int64(len(content)) {
+		t.Errorf("BlobBytesWritten grew by %d, expected %d", got, len(content))
+	}
+	if got := after.BlobBytesRead - before.BlobBytesRead; got != int64(len(content)) {
+		t.Errorf("BlobBytesRead grew by %d, expected %d", got, len(content))
+	}
+	if after.BlobReadBytesPerSec <= 0 || after.BlobWriteBytesPerSec <= 0 {
+		t.Errorf("expected positive throughput, got %+v", after)
+	}
+
+	// Content beyond the end of the blob is an error.
+	if _, err := exec.WriteBlobFromReader(ctx, "test_blob", "data", rowID, 50, bytes.NewReader(content)); err == nil {
+		t.Errorf("expected an error writing past the end of the blob")
+	}
+}
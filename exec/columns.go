@@ -0,0 +1,105 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// ColumnInfo describes a result column of a query.
+type ColumnInfo struct {
+	Name string `json:"name"`
+	// Database and Table name the table the result column reads, and are
+	// empty for expressions.
+	Database string `json:"database,omitempty"`
+	Table    string `json:"table,omitempty"`
+	// DeclType is the type the column is declared with in its table, and
+	// NotNull and PrimaryKey report its constraints. The driver does not
+	// expose the name of the table column a result column reads, so they
+	// are only known for columns selected under their own name: a column
+	// renamed with AS, or an expression, has no declared type. Columns of
+	// views and outer joins can be NULL regardless.
+	DeclType   string `json:"decl_type,omitempty"`
+	NotNull    bool   `json:"not_null,omitempty"`
+	PrimaryKey bool   `json:"primary_key,omitempty"`
+}
+
+// Columns prepares query on a connection from the global pool, without
+// running it, and describes its result columns, so tools can render values
+// by their declared types rather than guess from the first row.
+func Columns(ctx context.Context, query string) ([]ColumnInfo, error) {
+	var columns []ColumnInfo
+	err := withConn(ctx, func(conn *sqlite.Conn) error {
+		var err error
+		columns, err = ColumnsConn(conn, query)
+		return err
+	})
+	return columns, err
+}
+
+// ColumnsConn is Columns on a connection the caller already holds.
+func ColumnsConn(conn *sqlite.Conn, query string) ([]ColumnInfo, error) {
+	trimmedQuery := trimQuery(query)
+	stmt, _, err := conn.PrepareTransient(trimmedQuery)
+	if err != nil {
+		return nil, fmt.Errorf("SQL preparation error for query '%s': %w", trimmedQuery, err)
+	}
+	defer stmt.Finalize()
+	return describeColumns(conn, stmt)
+}
+
+// describeColumns describes the result columns of stmt, which was prepared
+// on conn, reading the declared types of table columns from
+// pragma_table_xinfo once per table.
+func describeColumns(conn *sqlite.Conn, stmt *sqlite.Stmt) ([]ColumnInfo, error) {
+	columns := make([]ColumnInfo, stmt.ColumnCount())
+	type table struct{ database, name string }
+	tables := map[table]map[string]ColumnInfo{}
+	for i := range columns {
+		c := ColumnInfo{
+			Name:     stmt.ColumnName(i),
+			Database: stmt.ColumnDatabaseName(i),
+			Table:    stmt.ColumnTableName(i),
+		}
+		if c.Table != "" {
+			t := table{c.Database, c.Table}
+			declared, ok := tables[t]
+			if !ok {
+				var err error
+				if declared, err = tableColumns(conn, c.Database, c.Table); err != nil {
+					return nil, err
+				}
+				tables[t] = declared
+			}
+			if d, ok := declared[strings.ToLower(c.Name)]; ok {
+				c.DeclType, c.NotNull, c.PrimaryKey = d.DeclType, d.NotNull, d.PrimaryKey
+			}
+		}
+		columns[i] = c
+	}
+	return columns, nil
+}
+
+// tableColumns returns the declared type and constraints of the columns of
+// a table, by lower-case name.
+func tableColumns(conn *sqlite.Conn, database, table string) (map[string]ColumnInfo, error) {
+	columns := map[string]ColumnInfo{}
+	err := sqlitex.Execute(conn, `SELECT name, type, "notnull", pk FROM pragma_table_xinfo(?, ?);`, &sqlitex.ExecOptions{
+		Args: []interface{}{table, database},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			columns[strings.ToLower(stmt.ColumnText(0))] = ColumnInfo{
+				DeclType:   stmt.ColumnText(1),
+				NotNull:    stmt.ColumnBool(2),
+				PrimaryKey: stmt.ColumnInt64(3) > 0,
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s.%s: %w", database, table, err)
+	}
+	return columns, nil
+}
@@ -0,0 +1,45 @@
+package exec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestColumns(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	columns, err := exec.Columns(ctx, "SELECT u.id, u.NAME, u.email AS user_email, o.quantity, count(*) AS n FROM users u JOIN orders o ON o.user_id = u.id GROUP BY u.id;")
+	require.NoError(t, err)
+	assert.Equal(t, []exec.ColumnInfo{
+		{Name: "id", DeclType: "INTEGER", Database: "main", Table: "users", PrimaryKey: true},
+		{Name: "name", DeclType: "TEXT", Database: "main", Table: "users", NotNull: true},
+		// Renamed columns have no declared type.
+		{Name: "user_email", Database: "main", Table: "users"},
+		{Name: "quantity", DeclType: "INTEGER", Database: "main", Table: "orders", NotNull: true},
+		{Name: "n"},
+	}, columns)
+
+	require.NoError(t, exec.E(ctx, "INSERT INTO users (name, email) VALUES ('a', 'a@example.com');"))
+	columns, err = exec.Columns(ctx, "DELETE FROM users")
+	require.NoError(t, err)
+	assert.Empty(t, columns)
+
+	var count int64
+	require.NoError(t, exec.Query(ctx, "SELECT count(*) FROM users", nil, func(_ []string, values []interface{}) {
+		count = values[0].(int64)
+	}))
+	assert.Equal(t, int64(1), count)
+
+	_, err = exec.Columns(ctx, "SELECT missing FROM users")
+	assert.Error(t, err)
+}
@@ -0,0 +1,190 @@
+package exec
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+)
+
+// ConvertFunc converts a non-NULL column value, an int64, float64, string
+// or []byte, to the value rows and struct scans receive.
+type ConvertFunc func(value interface{}) (interface{}, error)
+
+// Conversions decodes result columns to richer Go types than SQLite's
+// storage classes, chosen by the type a column is declared with in its
+// table or by the name it has in the result. Install them on the
+// connections of a pool with WithConversions:
+//
+//	pool.InitPool(uri, 4, exec.WithConversions(exec.DefaultConversions()))
+//
+// Conversions apply to the row maps and values of Exec, Query and
+// QueryRows, and to the structs of Select and Get. A value that fails to
+// convert fails the statement. Conversions must not be changed once
+// installed.
+type Conversions struct {
+	declTypes map[string]ConvertFunc
+	names     []nameConversion
+}
+
+type nameConversion struct {
+	pattern string
+	fn      ConvertFunc
+}
+
+// NewConversions returns an empty set of conversions.
+func NewConversions() *Conversions {
+	return &Conversions{declTypes: map[string]ConvertFunc{}}
+}
+
+// DefaultConversions converts columns declared DATETIME, TIMESTAMP or DATE,
+// and columns named *_at, with ConvertTime, and columns declared BOOLEAN or
+// BOOL, and columns named is_* or has_*, with ConvertBool.
+func DefaultConversions() *Conversions {
+	return NewConversions().
+		DeclType("DATETIME", ConvertTime).
+		DeclType("TIMESTAMP", ConvertTime).
+		DeclType("DATE", ConvertTime).
+		DeclType("BOOLEAN", ConvertBool).
+		DeclType("BOOL", ConvertBool).
+		Name("*_at", ConvertTime).
+		Name("is_*", ConvertBool).
+		Name("has_*", ConvertBool)
+}
+
+// DeclType converts the columns declared with declType, compared without
+// regard to case. A column's declared type takes precedence over its name.
+// Only columns selected under their own name have a declared type, as
+// described by ColumnInfo.
+func (c *Conversions) DeclType(declType string, fn ConvertFunc) *Conversions {
+	c.declTypes[strings.ToUpper(declType)] = fn
+	return c
+}
+
+// Name converts the result columns whose name, in lower case, matches
+// pattern, a path.Match pattern such as "*_at". Patterns are tried in the
+// order they were added.
+func (c *Conversions) Name(pattern string, fn ConvertFunc) *Conversions {
+	c.names = append(c.names, nameConversion{pattern: strings.ToLower(pattern), fn: fn})
+	return c
+}
+
+// conversionsKey is the pool.ConnValue key of the conversions of a pool.
+type conversionsKey struct{}
+
+// WithConversions installs c on the connections of a pool.
+func WithConversions(c *Conversions) pool.Option {
+	return pool.WithConnValue(conversionsKey{}, c)
+}
+
+// lookup returns the conversion of a column, or nil.
+func (c *Conversions) lookup(name, declType string) ConvertFunc {
+	if fn, ok := c.declTypes[strings.ToUpper(declType)]; ok && declType != "" {
+		return fn
+	}
+	name = strings.ToLower(name)
+	for _, n := range c.names {
+		if ok, _ := path.Match(n.pattern, name); ok {
+			return n.fn
+		}
+	}
+	return nil
+}
+
+// ConvertTime converts integer Unix seconds, and text in RFC 3339 or SQLite
+// datetime format, to a time.Time.
+func ConvertTime(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int64:
+		return time.Unix(v, 0).UTC(), nil
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+		return nil, fmt.Errorf("cannot parse %q as a time", v)
+	}
+	return nil, fmt.Errorf("cannot convert %T to a time", value)
+}
+
+// ConvertBool converts integers, true when not zero, and text accepted by
+// strconv.ParseBool to a bool.
+func ConvertBool(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case int64:
+		return v != 0, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse %q as a bool", v)
+		}
+		return b, nil
+	}
+	return nil, fmt.Errorf("cannot convert %T to a bool", value)
+}
+
+// columnReader reads the columns of a statement's rows, applying the
+// conversions installed on its connection. The zero value applies none.
+type columnReader struct {
+	columns []string
+	convert []ConvertFunc
+}
+
+// newColumnReader returns a reader for the columns of stmt, which was
+// prepared on conn.
+func newColumnReader(conn *sqlite.Conn, stmt *sqlite.Stmt, columns []string) (columnReader, error) {
+	r := columnReader{columns: columns}
+	c, _ := pool.ConnValue(conn, conversionsKey{}).(*Conversions)
+	if c == nil {
+		return r, nil
+	}
+	declTypes := make([]string, len(columns))
+	if len(c.declTypes) > 0 {
+		described, err := describeColumns(conn, stmt)
+		if err != nil {
+			return r, err
+		}
+		for i, d := range described {
+			declTypes[i] = d.DeclType
+		}
+	}
+	for i, name := range columns {
+		if fn := c.lookup(name, declTypes[i]); fn != nil {
+			if r.convert == nil {
+				r.convert = make([]ConvertFunc, len(columns))
+			}
+			r.convert[i] = fn
+		}
+	}
+	return r, nil
+}
+
+// value reads column i of the current row of stmt.
+func (r columnReader) value(stmt *sqlite.Stmt, i int) (interface{}, error) {
+	v := columnValue(stmt, i)
+	if r.convert == nil || r.convert[i] == nil || v == nil {
+		return v, nil
+	}
+	converted, err := r.convert[i](v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert column %s: %w", r.columns[i], err)
+	}
+	return converted, nil
+}
+
+// values reads the current row of stmt into values.
+func (r columnReader) values(stmt *sqlite.Stmt, values []interface{}) error {
+	for i := range values {
+		v, err := r.value(stmt, i)
+		if err != nil {
+			return err
+		}
+		values[i] = v
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package exec_test
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConversions(t *testing.T) {
+	ctx := context.Background()
+	conversions := exec.DefaultConversions().Name("score", func(value interface{}) (interface{}, error) {
+		return strings.Repeat("*", int(value.(int64))), nil
+	})
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "convert.db"), 2, exec.WithConversions(conversions)))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.E(ctx, `CREATE TABLE posts (
+		id INTEGER PRIMARY KEY,
+		published DATETIME,
+		archived BOOLEAN,
+		updated_at TEXT,
+		is_draft INTEGER,
+		score INTEGER
+	);`))
+	require.NoError(t, exec.E(ctx, "INSERT INTO posts VALUES (1, '2024-05-01 10:30:00', 1, '2024-05-02T08:00:00Z', 0, 3), (2, NULL, 0, NULL, 1, 1);"))
+
+	published := time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)
+	updated := time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC)
+
+	var rows []map[string]interface{}
+	require.NoError(t, exec.Exec(ctx, "SELECT * FROM posts ORDER BY id", nil, func(_ int, row map[string]interface{}) {
+		rows = append(rows, row)
+	}))
+	require.Len(t, rows, 2)
+	assert.Equal(t, published, rows[0]["published"])
+	assert.Equal(t, true, rows[0]["archived"])
+	assert.Equal(t, updated, rows[0]["updated_at"])
+	assert.Equal(t, false, rows[0]["is_draft"])
+	assert.Equal(t, "***", rows[0]["score"])
+	assert.Nil(t, rows[1]["published"])
+	assert.Equal(t, true, rows[1]["is_draft"])
+
+	// Renamed columns and expressions have no declared type, so only names
+	// apply.
+	require.NoError(t, exec.Query(ctx, "SELECT published AS p, archived + 0 AS a, 1 AS is_new FROM posts WHERE id = 1", nil, func(_ []string, values []interface{}) {
+		assert.Equal(t, []interface{}{"2024-05-01 10:30:00", int64(1), true}, values)
+	}))
+
+	type post struct {
+		ID        int64
+		Published *time.Time
+		Archived  bool
+		UpdatedAt string `db:"updated_at"`
+		IsDraft   bool   `db:"is_draft"`
+	}
+	var posts []post
+	require.NoError(t, exec.Select(ctx, &posts, "SELECT id, published, archived, updated_at, is_draft FROM posts ORDER BY id", nil))
+	require.Len(t, posts, 2)
+	assert.Equal(t, published, *posts[0].Published)
+	assert.True(t, posts[0].Archived)
+	assert.Equal(t, "2024-05-02T08:00:00Z", posts[0].UpdatedAt)
+	assert.Nil(t, posts[1].Published)
+	assert.True(t, posts[1].IsDraft)
+
+	require.NoError(t, exec.E(ctx, "UPDATE posts SET published = 'soon' WHERE id = 2;"))
+	err := exec.Query(ctx, "SELECT published FROM posts WHERE id = 2", nil, func([]string, []interface{}) {})
+	assert.ErrorContains(t, err, "failed to convert column published")
+}
+
+func TestConvertBool(t *testing.T) {
+	for value, want := range map[interface{}]bool{int64(2): true, int64(0): false, "true": true, "0": false} {
+		got, err := exec.ConvertBool(value)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := exec.ConvertBool(1.5)
+	assert.Error(t, err)
+}
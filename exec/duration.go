@@ -0,0 +1,66 @@
+package exec
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+)
+
+// DefaultDurationUnit is the unit time.Duration values are stored in on
+// pools without WithDurationUnit.
+const DefaultDurationUnit = time.Nanosecond
+
+// durationUnitKey is the pool.ConnValue key of the duration unit of a pool.
+type durationUnitKey struct{}
+
+// WithDurationUnit sets the unit time.Duration parameters are bound in and
+// integer columns scanned into time.Duration fields are read in on the
+// connections of a pool, such as time.Millisecond to store 1500 for 1.5s.
+// Bound durations are truncated to whole units. Units below a nanosecond
+// keep the default.
+func WithDurationUnit(unit time.Duration) pool.Option {
+	if unit < 1 {
+		unit = DefaultDurationUnit
+	}
+	return pool.WithConnValue(durationUnitKey{}, unit)
+}
+
+// durationUnit returns the duration unit of the pool conn belongs to.
+func durationUnit(conn *sqlite.Conn) time.Duration {
+	if unit, ok := pool.ConnValue(conn, durationUnitKey{}).(time.Duration); ok {
+		return unit
+	}
+	return DefaultDurationUnit
+}
+
+// durationUnit returns the duration unit of the connection r was read on.
+func (r *Row) durationUnit() time.Duration {
+	if r.unit < 1 {
+		return DefaultDurationUnit
+	}
+	return r.unit
+}
+
+// parseDuration converts a column value to a time.Duration: integers and
+// floats count unit, and text is parsed by time.ParseDuration.
+func parseDuration(value interface{}, unit time.Duration) (time.Duration, error) {
+	switch v := value.(type) {
+	case int64:
+		if v > math.MaxInt64/int64(unit) || v < math.MinInt64/int64(unit) {
+			return 0, fmt.Errorf("%d units of %s overflow time.Duration", v, unit)
+		}
+		return time.Duration(v) * unit, nil
+	case float64:
+		d := v * float64(unit)
+		if d >= math.MaxInt64 || d < math.MinInt64 {
+			return 0, fmt.Errorf("%g units of %s overflow time.Duration", v, unit)
+		}
+		return time.Duration(d), nil
+	case string:
+		return time.ParseDuration(v)
+	}
+	return 0, fmt.Errorf("cannot store %T in time.Duration", value)
+}
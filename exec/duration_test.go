@@ -0,0 +1,61 @@
+package exec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type retryPolicy struct {
+	Name    string         `db:"name"`
+	Timeout time.Duration  `db:"timeout"`
+	Backoff *time.Duration `db:"backoff"`
+}
+
+func TestDuration(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE policies (name TEXT, timeout INTEGER, backoff);", 1))
+
+	// Durations bind as nanoseconds by default.
+	require.NoError(t, exec.E(ctx, "INSERT INTO policies VALUES ('ns', ?, NULL);", 2*time.Second))
+	var stored []interface{}
+	require.NoError(t, exec.Q(ctx, "SELECT timeout FROM policies;", func(_ []string, values []interface{}) {
+		stored = append(stored, values[0])
+	}))
+	assert.Equal(t, []interface{}{int64(2e9)}, stored)
+	require.NoError(t, pool.ClosePool())
+
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE policies (name TEXT, timeout INTEGER, backoff);", 1, exec.WithDurationUnit(time.Millisecond)))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	backoff := 250 * time.Millisecond
+	require.NoError(t, exec.NamedExec(ctx, "INSERT INTO policies VALUES (:name, :timeout, :backoff);",
+		retryPolicy{Name: "ms", Timeout: 1500 * time.Millisecond, Backoff: &backoff}))
+	require.NoError(t, exec.E(ctx, "INSERT INTO policies VALUES ('text', 0, '1m30s');"))
+
+	stored = nil
+	require.NoError(t, exec.Q(ctx, "SELECT timeout FROM policies WHERE name = 'ms';", func(_ []string, values []interface{}) {
+		stored = append(stored, values[0])
+	}))
+	assert.Equal(t, []interface{}{int64(1500)}, stored)
+
+	var policies []retryPolicy
+	require.NoError(t, exec.Select(ctx, &policies, "SELECT * FROM policies ORDER BY rowid;", nil))
+	require.Len(t, policies, 2)
+	assert.Equal(t, 1500*time.Millisecond, policies[0].Timeout)
+	assert.Equal(t, backoff, *policies[0].Backoff)
+	assert.Equal(t, 90*time.Second, *policies[1].Backoff)
+
+	var timeout time.Duration
+	require.NoError(t, exec.QueryRows(ctx, "SELECT timeout FROM policies WHERE name = 'ms';", nil, func(row *exec.Row) {
+		require.NoError(t, row.Scan("timeout", &timeout))
+	}))
+	assert.Equal(t, 1500*time.Millisecond, timeout)
+}
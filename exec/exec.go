@@ -2,14 +2,35 @@ package exec
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
 	"strings"
+	"time"
 
+	"github.com/dropsite-ai/sqliteutils"
 	"github.com/dropsite-ai/sqliteutils/pool"
 	"zombiezen.com/go/sqlite"
 )
 
+// StatementError reports which statement of ExecMulti or ExecMultiTx failed.
+type StatementError struct {
+	// Index is the 0-based position of the statement in the queries slice.
+	Index int
+	Err   error
+}
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("error executing statement %d: %v", e.Index+1, e.Err)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
+
 // Exec executes a single SQL statement with parameters.
 func Exec(ctx context.Context, query string, params map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
 	return ExecMulti(ctx, []string{query}, []map[string]interface{}{params}, resultFunc)
@@ -24,84 +45,179 @@ func ExecMulti(ctx context.Context, queries []string, params []map[string]interf
 	}
 
 	// Obtain a connection pool
-	pool, err := pool.GetPool()
+	p, err := pool.GetPool()
 	if err != nil {
 		return fmt.Errorf("failed to create database pool: %w", err)
 	}
 
 	// Take a connection from the pool
-	conn, err := pool.Take(ctx)
+	conn, err := pool.Take(ctx, p)
 	if err != nil {
 		return fmt.Errorf("failed to obtain database connection: %w", err)
 	}
-	defer pool.Put(conn)
+	defer p.Put(conn)
 
-	// Execute each query with its corresponding parameters
-	for i, query := range queries {
-		trimmedQuery := trimQuery(query)
-		if trimmedQuery == "" {
-			continue
+	err = executeStatements(conn, queries, params, resultFunc)
+	rollbackAfterPanic(conn, err)
+	return err
+}
+
+// ExecConn is Exec on a connection the caller already holds.
+func ExecConn(conn *sqlite.Conn, query string, params map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	trimmedQuery := trimQuery(query)
+	if trimmedQuery == "" {
+		return nil
+	}
+	if err := executeSingleStatement(conn, trimmedQuery, params, 0, resultFunc); err != nil {
+		return &StatementError{Index: 0, Err: err}
+	}
+	return nil
+}
+
+// Query executes a single SQL statement with parameters and calls rowFunc with
+// the column names and values of each result row, in select-list order.
+func Query(ctx context.Context, query string, params map[string]interface{}, rowFunc func(columns []string, values []interface{})) error {
+	// Obtain a connection pool
+	p, err := pool.GetPool()
+	if err != nil {
+		return fmt.Errorf("failed to create database pool: %w", err)
+	}
+
+	// Take a connection from the pool
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return fmt.Errorf("failed to obtain database connection: %w", err)
+	}
+	defer p.Put(conn)
+
+	err = QueryConn(conn, query, params, rowFunc)
+	rollbackAfterPanic(conn, err)
+	return err
+}
+
+// QueryConn is Query on a connection the caller already holds.
+func QueryConn(conn *sqlite.Conn, query string, params map[string]interface{}, rowFunc func(columns []string, values []interface{})) (err error) {
+	defer countQuery(&err)
+	trimmedQuery := trimQuery(query)
+	prof := startProfile()
+	defer func() { prof.finish(trimmedQuery, params, err) }()
+	stmt, err := conn.Prepare(trimmedQuery)
+	if err != nil {
+		return fmt.Errorf("SQL preparation error for query '%s': %w", trimmedQuery, err)
+	}
+	defer stmt.Finalize()
+	defer recoverCallback(stmt, &err)
+	if err := bindParams(conn, stmt, params); err != nil {
+		return fmt.Errorf("failed to bind parameters for query '%s': %w", trimmedQuery, err)
+	}
+
+	columns := columnNames(stmt)
+	reader, err := newColumnReader(conn, stmt, columns)
+	if err != nil {
+		return fmt.Errorf("error reading result of query '%s': %w", trimmedQuery, err)
+	}
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return fmt.Errorf("error executing SQL query '%s': %w", trimmedQuery, err)
 		}
-		if err := executeSingleStatement(conn, trimmedQuery, params[i], i, resultFunc); err != nil {
-			return fmt.Errorf("error executing statement %d: %w", i+1, err)
+		if !hasRow {
+			break
+		}
+		prof.row()
+		if rowFunc != nil {
+			values := make([]interface{}, len(columns))
+			if err := reader.values(stmt, values); err != nil {
+				stmt.Reset()
+				return fmt.Errorf("error reading result of query '%s': %w", trimmedQuery, err)
+			}
+			rowFunc(columns, values)
 		}
 	}
+	return stmt.Reset()
+}
 
-	return nil
+// TxMode is the locking behavior of a transaction, as in BEGIN DEFERRED,
+// BEGIN IMMEDIATE or BEGIN EXCLUSIVE.
+type TxMode string
+
+const (
+	TxDeferred  TxMode = "DEFERRED"
+	TxImmediate TxMode = "IMMEDIATE"
+	TxExclusive TxMode = "EXCLUSIVE"
+)
+
+// ParseTxMode parses a transaction mode name, ignoring case.
+func ParseTxMode(s string) (TxMode, error) {
+	switch mode := TxMode(strings.ToUpper(strings.TrimSpace(s))); mode {
+	case TxDeferred, TxImmediate, TxExclusive:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid transaction mode %q: use deferred, immediate or exclusive", s)
+	}
 }
 
 // ExecTx executes multiple SQL statements within a single transaction.
 // Each query in the `queries` slice corresponds to the parameters in the `params` slice by index.
 func ExecMultiTx(ctx context.Context, queries []string, params []map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	return ExecMultiTxMode(ctx, TxDeferred, queries, params, resultFunc)
+}
+
+// ExecMultiTxMode is ExecMultiTx with the transaction begun in the given mode.
+// Use TxImmediate to take the write lock up front and avoid SQLITE_BUSY on upgrade.
+func ExecMultiTxMode(ctx context.Context, mode TxMode, queries []string, params []map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	if _, err := ParseTxMode(string(mode)); err != nil {
+		return err
+	}
+
 	// Validate that the number of queries matches the number of params
 	if len(queries) != len(params) {
 		return fmt.Errorf("the number of queries (%d) does not match the number of params (%d)", len(queries), len(params))
 	}
 
 	// Obtain a connection pool
-	pool, err := pool.GetPool()
+	p, err := pool.GetPool()
 	if err != nil {
 		return fmt.Errorf("failed to create database pool: %w", err)
 	}
 
 	// Take a connection from the pool
-	conn, err := pool.Take(ctx)
+	conn, err := pool.Take(ctx, p)
 	if err != nil {
 		return fmt.Errorf("failed to obtain database connection: %w", err)
 	}
-	defer pool.Put(conn)
+	defer p.Put(conn)
 
-	// Begin the transaction
-	if err := executeRawStatement(conn, "BEGIN TRANSACTION;"); err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+	return ExecMultiTxConn(conn, mode, queries, params, resultFunc)
+}
+
+// ExecMultiTxConn is ExecMultiTxMode on a connection the caller already
+// holds. If conn is already inside a transaction, the statements run in a
+// savepoint of it instead, so they are undone together on failure without
+// ending the outer transaction.
+func ExecMultiTxConn(conn *sqlite.Conn, mode TxMode, queries []string, params []map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	if _, err := ParseTxMode(string(mode)); err != nil {
+		return err
+	}
+	if len(queries) != len(params) {
+		return fmt.Errorf("the number of queries (%d) does not match the number of params (%d)", len(queries), len(params))
 	}
 
-	committed := false
+	tx, err := beginConn(conn, mode, func() {})
+	if err != nil {
+		return err
+	}
 	defer func() {
-		if !committed {
-			if rollbackErr := executeRawStatement(conn, "ROLLBACK;"); rollbackErr != nil {
-				fmt.Printf("failed to rollback transaction: %v\n", rollbackErr)
-			}
+		if rollbackErr := tx.Rollback(); rollbackErr != nil {
+			sqliteutils.Logger().Error("failed to rollback transaction", "error", rollbackErr)
 		}
 	}()
 
 	// Execute each query with its corresponding parameters
-	for i, query := range queries {
-		trimmedQuery := trimQuery(query)
-		if trimmedQuery == "" {
-			continue
-		}
-		if err := executeSingleStatement(conn, trimmedQuery, params[i], i, resultFunc); err != nil {
-			return fmt.Errorf("error executing statement %d: %w", i+1, err)
-		}
+	if err := executeStatements(conn, queries, params, resultFunc); err != nil {
+		return err
 	}
-
-	// Commit the transaction
-	if err := executeRawStatement(conn, "COMMIT;"); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
-	}
-	committed = true
-	return nil
+	return tx.Commit()
 }
 
 // executeRawStatement executes a single SQL statement without parameter binding or result processing.
@@ -126,16 +242,56 @@ func executeRawStatement(conn *sqlite.Conn, statement string) error {
 
 // executeSingleStatement prepares and executes a single SQL statement with parameter binding and result processing.
 func executeSingleStatement(conn *sqlite.Conn, query string, params map[string]interface{}, index int, resultFunc func(int, map[string]interface{})) error {
-	stmt, err := conn.Prepare(query)
-	if err != nil {
-		return fmt.Errorf("SQL preparation error for query '%s': %w", query, err)
+	stmts := make(statementCache)
+	defer stmts.finalize()
+	return stmts.execute(conn, query, params, index, resultFunc)
+}
+
+// executeStatements executes each query with its corresponding parameters,
+// preparing repeated queries, such as the statements of a bulk insert, only
+// once. A failing statement is reported as a *StatementError.
+func executeStatements(conn *sqlite.Conn, queries []string, params []map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	stmts := make(statementCache)
+	defer stmts.finalize()
+	for i, query := range queries {
+		trimmedQuery := trimQuery(query)
+		if trimmedQuery == "" {
+			continue
+		}
+		if err := stmts.execute(conn, trimmedQuery, params[i], i, resultFunc); err != nil {
+			return &StatementError{Index: i, Err: err}
+		}
 	}
-	defer stmt.Finalize()
+	return nil
+}
+
+// statementCache holds the statements prepared during one call by query text.
+type statementCache map[string]*sqlite.Stmt
+
+// execute executes query with parameter binding and result processing,
+// reusing the statement prepared for an earlier occurrence of query.
+func (c statementCache) execute(conn *sqlite.Conn, query string, params map[string]interface{}, index int, resultFunc func(int, map[string]interface{})) (err error) {
+	defer countQuery(&err)
+	prof := startProfile()
+	defer func() { prof.finish(query, params, err) }()
+	stmt, ok := c[query]
+	if !ok {
+		stmt, err = conn.Prepare(query)
+		if err != nil {
+			return fmt.Errorf("SQL preparation error for query '%s': %w", query, err)
+		}
+		c[query] = stmt
+	}
+	defer recoverCallback(stmt, &err)
 
 	// Bind parameters specific to this query
-	bindParams(stmt, params) // No error handling needed
+	if err := bindParams(conn, stmt, params); err != nil {
+		stmt.ClearBindings()
+		return fmt.Errorf("failed to bind parameters for query '%s': %w", query, err)
+	}
 
 	// Execute the statement and process results
+	var reader *columnReader
 	for {
 		hasRow, err := stmt.Step()
 		if err != nil {
@@ -144,47 +300,94 @@ func executeSingleStatement(conn *sqlite.Conn, query string, params map[string]i
 		if !hasRow {
 			break
 		}
+		prof.row()
 		if resultFunc != nil {
-			resultFunc(index, readRow(stmt))
+			if reader == nil {
+				r, err := newColumnReader(conn, stmt, columnNames(stmt))
+				if err != nil {
+					stmt.Reset()
+					stmt.ClearBindings()
+					return fmt.Errorf("error reading result of query '%s': %w", query, err)
+				}
+				reader = &r
+			}
+			row, err := readRow(stmt, *reader)
+			if err != nil {
+				stmt.Reset()
+				stmt.ClearBindings()
+				return fmt.Errorf("error reading result of query '%s': %w", query, err)
+			}
+			resultFunc(index, row)
 		}
 	}
 
-	// Reset the statement for potential reuse
+	// Reset the statement and its bindings for the next occurrence
 	if err := stmt.Reset(); err != nil {
 		return fmt.Errorf("failed to reset statement for query '%s': %w", query, err)
 	}
+	if err := stmt.ClearBindings(); err != nil {
+		return fmt.Errorf("failed to clear bindings for query '%s': %w", query, err)
+	}
 
 	return nil
 }
 
-// readRow reads the current row from the statement and returns it as a map.
-func readRow(stmt *sqlite.Stmt) map[string]interface{} {
-	columnData := make(map[string]interface{})
-	for i := 0; i < stmt.ColumnCount(); i++ {
-		columnName := stmt.ColumnName(i)
-		switch stmt.ColumnType(i) {
-		case sqlite.TypeInteger:
-			columnData[columnName] = stmt.ColumnInt64(i)
-		case sqlite.TypeFloat:
-			columnData[columnName] = stmt.ColumnFloat(i)
-		case sqlite.TypeText:
-			columnData[columnName] = stmt.ColumnText(i)
-		case sqlite.TypeBlob:
-			columnData[columnName] = stmt.ColumnBytes(i, nil)
-		case sqlite.TypeNull:
-			columnData[columnName] = nil
-		default:
-			columnData[columnName] = stmt.ColumnText(i)
-		}
-	}
-	return columnData
-}
-
-// bindParams binds parameters to the SQL statement.
-// NOTE: This function no longer returns an error because the Bind* methods do not.
-func bindParams(stmt *sqlite.Stmt, params map[string]interface{}) {
+// finalize finalizes the cached statements.
+func (c statementCache) finalize() {
+	for _, stmt := range c {
+		stmt.Finalize()
+	}
+}
+
+// readRow reads the current row from the statement and returns it as a map
+// keyed by the reader's column names.
+func readRow(stmt *sqlite.Stmt, reader columnReader) (map[string]interface{}, error) {
+	columnData := make(map[string]interface{}, len(reader.columns))
+	for i, name := range reader.columns {
+		v, err := reader.value(stmt, i)
+		if err != nil {
+			return nil, err
+		}
+		columnData[name] = v
+	}
+	return columnData, nil
+}
+
+// columnNames returns the column names of stmt in result order.
+func columnNames(stmt *sqlite.Stmt) []string {
+	columns := make([]string, stmt.ColumnCount())
+	for i := range columns {
+		columns[i] = stmt.ColumnName(i)
+	}
+	return columns
+}
+
+// columnValue reads column i of the current row as its Go equivalent.
+func columnValue(stmt *sqlite.Stmt, i int) interface{} {
+	switch stmt.ColumnType(i) {
+	case sqlite.TypeInteger:
+		return stmt.ColumnInt64(i)
+	case sqlite.TypeFloat:
+		return stmt.ColumnFloat(i)
+	case sqlite.TypeText:
+		return stmt.ColumnText(i)
+	case sqlite.TypeBlob:
+		buf := make([]byte, stmt.ColumnLen(i))
+		stmt.ColumnBytes(i, buf)
+		return buf
+	case sqlite.TypeNull:
+		return nil
+	default:
+		return stmt.ColumnText(i)
+	}
+}
+
+// bindParams binds parameters to the SQL statement, prepared on conn.
+// It returns an error for values that cannot be bound, such as unsigned
+// integers above the int64 range.
+func bindParams(conn *sqlite.Conn, stmt *sqlite.Stmt, params map[string]interface{}) error {
 	if params == nil {
-		return
+		return nil
 	}
 	for i := 1; i <= stmt.BindParamCount(); i++ {
 		paramName := stmt.BindParamName(i)
@@ -193,41 +396,105 @@ func bindParams(stmt *sqlite.Stmt, params map[string]interface{}) {
 		}
 
 		// Ensure that the parameter map keys include the prefix used in the SQL query (e.g., ":name")
-		value, exists := params[paramName]
-		if !exists || value == nil {
-			stmt.BindNull(i)
-			continue
+		if err := bindValue(conn, stmt, i, paramName, params[paramName]); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		val := reflect.ValueOf(value)
-		if val.Kind() == reflect.Ptr {
-			if val.IsNil() {
-				stmt.BindNull(i)
-				continue
-			}
-			value = val.Elem().Interface()
-			val = reflect.ValueOf(value)
-		}
-
-		switch v := value.(type) {
-		case string:
-			stmt.BindText(i, v)
-		case int, int32, int64:
-			intVal := val.Int()
-			stmt.BindInt64(i, intVal)
-		case float32, float64:
-			floatVal := val.Float()
-			stmt.BindFloat(i, floatVal)
-		case bool:
-			stmt.BindBool(i, v)
-		case []byte:
-			stmt.BindBytes(i, v)
-		default:
-			// Unsupported types are handled by handleBindErr internally
-			// Optionally, you can log or panic here if needed
-			fmt.Printf("unsupported type for parameter '%s': %T\n", paramName, value)
+// bindValue binds value to the parameter at index i, named paramName for
+// messages. Unsigned integers above the int64 range are an error, since
+// SQLite integers are signed 64-bit.
+func bindValue(conn *sqlite.Conn, stmt *sqlite.Stmt, i int, paramName string, value interface{}) error {
+	if value == nil {
+		stmt.BindNull(i)
+		return nil
+	}
+
+	val := reflect.ValueOf(value)
+	if val.Kind() == reflect.Ptr && val.IsNil() {
+		stmt.BindNull(i)
+		return nil
+	}
+	if valuer, ok := value.(driver.Valuer); ok {
+		// Types such as sql.NullString choose their own representation.
+		v, err := valuer.Value()
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", paramName, err)
 		}
+		return bindValue(conn, stmt, i, paramName, v)
+	}
+	if val.Kind() == reflect.Ptr {
+		value = val.Elem().Interface()
+		val = reflect.ValueOf(value)
+	}
+
+	switch v := value.(type) {
+	case string:
+		stmt.BindText(i, v)
+	case time.Duration:
+		stmt.BindInt64(i, int64(v/durationUnit(conn)))
+	case int, int8, int16, int32, int64:
+		intVal := val.Int()
+		stmt.BindInt64(i, intVal)
+	case uint, uint8, uint16, uint32, uint64, uintptr:
+		uintVal := val.Uint()
+		if uintVal > math.MaxInt64 {
+			return fmt.Errorf("parameter %s: %d overflows the int64 range of SQLite integers", paramName, uintVal)
+		}
+		stmt.BindInt64(i, int64(uintVal))
+	case float32, float64:
+		floatVal := val.Float()
+		stmt.BindFloat(i, floatVal)
+	case bool:
+		stmt.BindBool(i, v)
+	case []byte:
+		stmt.BindBytes(i, v)
+	case time.Time:
+		stmt.BindText(i, v.UTC().Format(time.RFC3339Nano))
+	case JSONParam:
+		data, err := json.Marshal(v.Value)
+		if err != nil {
+			return fmt.Errorf("parameter %s: %w", paramName, err)
+		}
+		stmt.BindText(i, string(data))
+	default:
+		return fmt.Errorf("parameter %s: unsupported type %T", paramName, value)
+	}
+	return nil
+}
+
+// nullTypes are the database/sql types for nullable values.
+var nullTypes = map[reflect.Type]bool{
+	reflect.TypeOf(sql.NullBool{}):    true,
+	reflect.TypeOf(sql.NullByte{}):    true,
+	reflect.TypeOf(sql.NullFloat64{}): true,
+	reflect.TypeOf(sql.NullInt16{}):   true,
+	reflect.TypeOf(sql.NullInt32{}):   true,
+	reflect.TypeOf(sql.NullInt64{}):   true,
+	reflect.TypeOf(sql.NullString{}):  true,
+	reflect.TypeOf(sql.NullTime{}):    true,
+}
+
+// nullType reports whether t is one of the sql.Null* types.
+func nullType(t reflect.Type) bool {
+	return nullTypes[t]
+}
+
+var (
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
+// valueStruct reports whether values of struct type t are bound and scanned
+// whole rather than field by field.
+func valueStruct(t reflect.Type) bool {
+	if t == reflect.TypeOf(time.Time{}) || t == reflect.TypeOf(JSONParam{}) || nullType(t) {
+		return true
 	}
+	ptr := reflect.PtrTo(t)
+	return ptr.Implements(valuerType) || ptr.Implements(scannerType)
 }
 
 // trimQuery trims whitespace and ensures the query does not end with a semicolon.
@@ -3,6 +3,7 @@ package exec_test
 import (
 	"context"
 	"fmt"
+	"math"
 	"sync"
 	"testing"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/dropsite-ai/sqliteutils/pool"
 	"github.com/dropsite-ai/sqliteutils/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const migration = `
@@ -252,6 +254,10 @@ func TestExec(t *testing.T) {
 
 		err := exec.ExecMultiTx(ctx, queries, params, nil)
 		assert.Error(t, err, "ExecTx should return an error due to UNIQUE constraint violation")
+		var stmtErr *exec.StatementError
+		if assert.ErrorAs(t, err, &stmtErr, "ExecTx should report the failing statement") {
+			assert.Equal(t, 1, stmtErr.Index, "The second statement should fail")
+		}
 
 		// Verify that no new users were inserted
 		verifyQuery := `SELECT COUNT(1) as count FROM users WHERE name = $name OR name = $name2;`
@@ -455,6 +461,120 @@ func TestExec(t *testing.T) {
 		assert.Equal(t, "Laura Palmer", name, "User name should match")
 		assert.Equal(t, "laura@example.com", email, "User email should match")
 	})
+
+	// Test Case 12: ExecMultiTxMode with IMMEDIATE and an invalid mode
+	t.Run("ExecTxMode", func(t *testing.T) {
+		queries := []string{`INSERT INTO users (name, email) VALUES ($name, $email);`}
+		params := []map[string]interface{}{{"$name": "Major Briggs", "$email": "briggs@example.com"}}
+		err := exec.ExecMultiTxMode(ctx, exec.TxImmediate, queries, params, nil)
+		assert.NoError(t, err, "ExecMultiTxMode should commit an IMMEDIATE transaction")
+
+		err = exec.ExecMultiTxMode(ctx, exec.TxMode("LAZY"), queries, params, nil)
+		assert.Error(t, err, "ExecMultiTxMode should reject unknown modes")
+
+		mode, err := exec.ParseTxMode("exclusive")
+		assert.NoError(t, err)
+		assert.Equal(t, exec.TxExclusive, mode)
+	})
+
+	// Test Case 13: Query preserves column order
+	t.Run("Query_ColumnOrder", func(t *testing.T) {
+		var columns []string
+		var rows [][]interface{}
+		err := exec.Query(ctx, `SELECT email, name, NULL AS missing, x'00ff' AS data FROM users WHERE name = $name;`, map[string]interface{}{
+			"$name": "Laura Palmer",
+		}, func(cols []string, values []interface{}) {
+			columns = cols
+			rows = append(rows, values)
+		})
+		assert.NoError(t, err, "Query should execute SELECT without error")
+		assert.Equal(t, []string{"email", "name", "missing", "data"}, columns)
+		assert.Equal(t, [][]interface{}{{"laura@example.com", "Laura Palmer", nil, []byte{0x00, 0xff}}}, rows)
+	})
+}
+
+func TestExecMulti_RepeatedStatements(t *testing.T) {
+	ctx := context.Background()
+	err := test.Pool(ctx, t, `CREATE TABLE kv (k TEXT PRIMARY KEY, v TEXT);`, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	// The same insert is prepared once and rebound for every row; a
+	// parameter missing from a later map binds NULL, not the earlier value.
+	insert := `INSERT INTO kv (k, v) VALUES ($k, $v) RETURNING k, v;`
+	queries := []string{insert, insert, insert}
+	params := []map[string]interface{}{
+		{"$k": "a", "$v": "1"},
+		{"$k": "b", "$v": "2"},
+		{"$k": "c"},
+	}
+	var rows []map[string]interface{}
+	err = exec.ExecMultiTx(ctx, queries, params, func(index int, row map[string]interface{}) {
+		row["index"] = index
+		rows = append(rows, row)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"index": 0, "k": "a", "v": "1"},
+		{"index": 1, "k": "b", "v": "2"},
+		{"index": 2, "k": "c", "v": nil},
+	}, rows)
+
+	// A failing repetition is reported with its own index.
+	err = exec.ExecMulti(ctx, []string{insert, insert}, []map[string]interface{}{{"$k": "d"}, {"$k": "a"}}, nil)
+	var stmtErr *exec.StatementError
+	if assert.ErrorAs(t, err, &stmtErr) {
+		assert.Equal(t, 1, stmtErr.Index)
+	}
+}
+
+func TestExec_IntegerKinds(t *testing.T) {
+	ctx := context.Background()
+	err := test.Pool(ctx, t, `CREATE TABLE ids (id INTEGER);`, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	big := uint64(math.MaxInt64)
+	for _, v := range []interface{}{int8(-8), int16(-16), uint(1), uint8(8), uint16(16), uint32(32), big, &big} {
+		assert.NoError(t, exec.Exec(ctx, "INSERT INTO ids VALUES (:id);", map[string]interface{}{":id": v}, nil), "%T", v)
+	}
+	var ids []interface{}
+	require.NoError(t, exec.Query(ctx, "SELECT id FROM ids;", nil, func(_ []string, values []interface{}) {
+		ids = append(ids, values[0])
+	}))
+	assert.Equal(t, []interface{}{int64(-8), int64(-16), int64(1), int64(8), int64(16), int64(32), int64(math.MaxInt64), int64(math.MaxInt64)}, ids)
+
+	// Values above the int64 range are an error rather than wrapping around.
+	err = exec.Exec(ctx, "INSERT INTO ids VALUES (:id);", map[string]interface{}{":id": big + 1}, nil)
+	assert.ErrorContains(t, err, "overflows")
+	assert.Error(t, exec.E(ctx, "INSERT INTO ids VALUES (?);", uint64(math.MaxUint64)))
+}
+
+func TestExec_UnsupportedParam(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, `CREATE TABLE t (v);`, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	// Values of unsupported types fail the statement rather than bind NULL.
+	err := exec.Exec(ctx, "INSERT INTO t (v) VALUES ($v);", map[string]interface{}{"$v": struct{}{}}, nil)
+	assert.ErrorContains(t, err, "parameter $v: unsupported type struct {}")
+	assert.Error(t, exec.E(ctx, "INSERT INTO t (v) VALUES (?);", []int{1}))
+
+	var count int64
+	require.NoError(t, exec.Q(ctx, "SELECT count(*) FROM t;", func(_ []string, values []interface{}) {
+		count = values[0].(int64)
+	}))
+	assert.Zero(t, count)
 }
 
 // TestExec_Concurrency tests concurrent executions of Exec and ExecTx.
@@ -0,0 +1,79 @@
+package exec
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// StatementInfo describes a statement run by this package.
+type StatementInfo struct {
+	// SQL is the statement as run and Digest its normalized form, see Digest.
+	SQL    string
+	Digest string
+	// Duration is the time spent preparing and stepping the statement.
+	Duration time.Duration
+	// Rows is the number of result rows read.
+	Rows int64
+	Err  error
+}
+
+type statementHook struct {
+	fn func(StatementInfo)
+}
+
+var (
+	statementHooksLock sync.Mutex
+	// statementHooks holds the registered hooks, or nil when there are none,
+	// and is replaced rather than modified so statements can read it without
+	// locking.
+	statementHooks atomic.Pointer[[]*statementHook]
+)
+
+// OnStatement calls fn after each statement this package runs, on the
+// goroutine that ran it, so fn should only hand the information off, for
+// example to a metrics library. It is a lighter alternative to
+// EnableProfiling that leaves aggregation to the caller. The returned
+// function unregisters fn.
+func OnStatement(fn func(StatementInfo)) (remove func()) {
+	hook := &statementHook{fn: fn}
+	statementHooksLock.Lock()
+	defer statementHooksLock.Unlock()
+	var hooks []*statementHook
+	if current := statementHooks.Load(); current != nil {
+		hooks = append(hooks, *current...)
+	}
+	hooks = append(hooks, hook)
+	statementHooks.Store(&hooks)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { removeStatementHook(hook) })
+	}
+}
+
+func removeStatementHook(hook *statementHook) {
+	statementHooksLock.Lock()
+	defer statementHooksLock.Unlock()
+	var hooks []*statementHook
+	for _, h := range *statementHooks.Load() {
+		if h != hook {
+			hooks = append(hooks, h)
+		}
+	}
+	if len(hooks) == 0 {
+		statementHooks.Store(nil)
+		return
+	}
+	statementHooks.Store(&hooks)
+}
+
+func callStatementHooks(info StatementInfo) {
+	hooks := statementHooks.Load()
+	if hooks == nil {
+		return
+	}
+	for _, h := range *hooks {
+		h.fn(info)
+	}
+}
@@ -0,0 +1,49 @@
+package exec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOnStatement(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	var infos []exec.StatementInfo
+	remove := exec.OnStatement(func(info exec.StatementInfo) {
+		infos = append(infos, info)
+	})
+	var others int
+	removeOther := exec.OnStatement(func(exec.StatementInfo) { others++ })
+	defer removeOther()
+
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO users (name, email) VALUES ('a', 'a@example.com');", nil, nil))
+	require.NoError(t, exec.Query(ctx, "SELECT name FROM users WHERE id = 1", nil, nil))
+	assert.Error(t, exec.Exec(ctx, "SELECT * FROM missing", nil, nil))
+
+	require.Len(t, infos, 3)
+	assert.Equal(t, "INSERT INTO users (name, email) VALUES ('a', 'a@example.com')", infos[0].SQL)
+	assert.Equal(t, "INSERT INTO users (name, email) VALUES (?)", infos[0].Digest)
+	assert.NoError(t, infos[0].Err)
+	assert.Zero(t, infos[0].Rows)
+	assert.Equal(t, "SELECT name FROM users WHERE id = ?", infos[1].Digest)
+	assert.Equal(t, int64(1), infos[1].Rows)
+	assert.Positive(t, infos[1].Duration)
+	assert.Error(t, infos[2].Err)
+	assert.Equal(t, 3, others)
+
+	remove()
+	remove()
+	require.NoError(t, exec.Query(ctx, "SELECT 1", nil, nil))
+	assert.Len(t, infos, 3)
+	assert.Equal(t, 4, others)
+}
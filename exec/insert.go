@@ -0,0 +1,56 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+)
+
+// InsertGetID executes an INSERT statement with parameters and returns the
+// rowid of the inserted row. See InsertGetIDConn.
+func InsertGetID(ctx context.Context, query string, params map[string]interface{}) (int64, error) {
+	p, err := pool.GetPool()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create database pool: %w", err)
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return 0, fmt.Errorf("failed to obtain database connection: %w", err)
+	}
+	defer p.Put(conn)
+	return InsertGetIDConn(conn, query, params)
+}
+
+// InsertGetIDConn is InsertGetID on a connection the caller already holds.
+// The ID is read on the same connection right after the statement, so other
+// connections cannot interleave. If query has a RETURNING clause, the first
+// column of its first row is returned instead of the rowid, and must be an
+// integer. For a statement inserting several rows, the ID of the last one is
+// returned. If no row was inserted, as with INSERT OR IGNORE on a conflict,
+// the error wraps sqliteutils.ErrRowNotFound.
+func InsertGetIDConn(conn *sqlite.Conn, query string, params map[string]interface{}) (int64, error) {
+	var returned interface{}
+	hasReturning := false
+	err := QueryConn(conn, query, params, func(_ []string, values []interface{}) {
+		if !hasReturning && len(values) > 0 {
+			hasReturning, returned = true, values[0]
+		}
+	})
+	if err != nil {
+		return 0, err
+	}
+	if conn.Changes() == 0 {
+		return 0, fmt.Errorf("%w: no row was inserted", sqliteutils.ErrRowNotFound)
+	}
+	if hasReturning {
+		id, ok := returned.(int64)
+		if !ok {
+			return 0, fmt.Errorf("RETURNING value %v is a %T, not an integer ID", returned, returned)
+		}
+		return id, nil
+	}
+	return conn.LastInsertRowID(), nil
+}
@@ -0,0 +1,41 @@
+package exec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsertGetID(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT UNIQUE, seq INTEGER);
+		CREATE TABLE log (id INTEGER PRIMARY KEY, user_id INTEGER);
+		CREATE TRIGGER users_log AFTER INSERT ON users BEGIN INSERT INTO log (user_id) VALUES (NEW.id); END;
+		INSERT INTO log (id) VALUES (100);
+	`, 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	// Rows inserted by triggers do not change the returned ID.
+	id, err := exec.InsertGetID(ctx, "INSERT INTO users (email) VALUES (:email);", map[string]interface{}{":email": "a@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), id)
+
+	id, err = exec.InsertGetID(ctx, "INSERT INTO users (email, seq) VALUES ('b@example.com', 42) RETURNING seq;", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+
+	_, err = exec.InsertGetID(ctx, "INSERT OR IGNORE INTO users (email) VALUES ('a@example.com');", nil)
+	assert.ErrorIs(t, err, sqliteutils.ErrRowNotFound)
+
+	_, err = exec.InsertGetID(ctx, "INSERT INTO users (email) VALUES ('c@example.com') RETURNING email;", nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,134 @@
+package exec
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+)
+
+// JSONParam is a parameter bound as the JSON encoding of its value.
+type JSONParam struct {
+	Value interface{}
+}
+
+// JSON wraps v so that it binds as JSON text, for use with json1 functions:
+//
+//	exec.Exec(ctx, "INSERT INTO users (prefs) VALUES (json($prefs))",
+//		map[string]interface{}{"$prefs": exec.JSON(prefs)}, nil)
+func JSON(v interface{}) JSONParam {
+	return JSONParam{Value: v}
+}
+
+// JSONExtract decodes the value at path, a JSON path such as "$.theme", in
+// the JSON column of the row of table with the given rowID into dest. A
+// missing path decodes as JSON null. It returns sqliteutils.ErrRowNotFound
+// when there is no such row.
+func JSONExtract(ctx context.Context, table, column string, rowID int64, path string, dest interface{}) error {
+	p, err := pool.GetPool()
+	if err != nil {
+		return err
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer p.Put(conn)
+
+	// -> returns the JSON representation of the value, unlike json_extract
+	// which returns strings unquoted.
+	query := fmt.Sprintf("SELECT COALESCE(%s -> :path, 'null') FROM %s WHERE rowid = :rowid;",
+		sqliteutils.QuoteIdentifier(column), sqliteutils.QuoteIdentifier(table))
+	var data string
+	found := false
+	err = sqlitex.Execute(conn, query, &sqlitex.ExecOptions{
+		Named: map[string]interface{}{":path": path, ":rowid": rowID},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			data = stmt.ColumnText(0)
+			found = true
+			return nil
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to extract %s from %s.%s: %w", path, table, column, err)
+	}
+	if !found {
+		return sqliteutils.ErrRowNotFound
+	}
+	if err := json.Unmarshal([]byte(data), dest); err != nil {
+		return fmt.Errorf("failed to decode %s from %s.%s: %w", path, table, column, err)
+	}
+	return nil
+}
+
+// JSONSet sets path in the JSON column of the row of table with the given
+// rowID to the JSON encoding of value, creating the path as needed. A NULL
+// column is treated as an empty object. It returns sqliteutils.ErrRowNotFound
+// when there is no such row.
+func JSONSet(ctx context.Context, table, column string, rowID int64, path string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode value for %s: %w", path, err)
+	}
+
+	p, err := pool.GetPool()
+	if err != nil {
+		return err
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer p.Put(conn)
+
+	col := sqliteutils.QuoteIdentifier(column)
+	query := fmt.Sprintf("UPDATE %s SET %s = json_set(COALESCE(%s, '{}'), :path, json(:value)) WHERE rowid = :rowid;",
+		sqliteutils.QuoteIdentifier(table), col, col)
+	err = executeNoRows(conn, query, map[string]interface{}{":path": path, ":value": string(data), ":rowid": rowID})
+	if err != nil {
+		return fmt.Errorf("failed to set %s in %s.%s: %w", path, table, column, err)
+	}
+	if conn.Changes() == 0 {
+		return sqliteutils.ErrRowNotFound
+	}
+	return nil
+}
+
+// JSONIndex makes path in the JSON column of table queryable by index. It adds
+// a virtual generated column named name holding the value at path and an
+// index on it named <table>_<name>. Query the generated column, or the same
+// expression column ->> path, to use the index.
+func JSONIndex(ctx context.Context, table, column, path, name string) error {
+	p, err := pool.GetPool()
+	if err != nil {
+		return err
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return err
+	}
+	defer p.Put(conn)
+
+	t := sqliteutils.QuoteIdentifier(table)
+	n := sqliteutils.QuoteIdentifier(name)
+	script := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s GENERATED ALWAYS AS (%s ->> '%s') VIRTUAL;\n",
+		t, n, sqliteutils.QuoteIdentifier(column), strings.ReplaceAll(path, "'", "''")) +
+		fmt.Sprintf("CREATE INDEX %s ON %s (%s);\n", sqliteutils.QuoteIdentifier(table+"_"+name), t, n)
+
+	endFn, err := sqlitex.ImmediateTransaction(conn)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	err = sqlitex.ExecuteScript(conn, script, nil)
+	endFn(&err)
+	if err != nil {
+		return fmt.Errorf("failed to index %s of %s.%s: %w", path, table, column, err)
+	}
+	return nil
+}
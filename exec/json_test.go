@@ -0,0 +1,87 @@
+package exec_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type prefs struct {
+	Theme string   `json:"theme"`
+	Tags  []string `json:"tags"`
+}
+
+func TestJSON(t *testing.T) {
+	ctx := context.Background()
+	const migration = `
+		CREATE TABLE profiles (
+			id INTEGER PRIMARY KEY,
+			prefs TEXT
+		);
+		INSERT INTO profiles (id) VALUES (2);
+	`
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	// Structs bind as JSON text and round-trip through json1.
+	in := prefs{Theme: "dark", Tags: []string{"a", "b"}}
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO profiles (id, prefs) VALUES (1, json($prefs));",
+		map[string]interface{}{"$prefs": exec.JSON(in)}, nil))
+
+	var out prefs
+	require.NoError(t, exec.JSONExtract(ctx, "profiles", "prefs", 1, "$", &out))
+	assert.Equal(t, in, out)
+
+	var theme string
+	require.NoError(t, exec.JSONExtract(ctx, "profiles", "prefs", 1, "$.theme", &theme))
+	assert.Equal(t, "dark", theme)
+
+	var missing *string
+	require.NoError(t, exec.JSONExtract(ctx, "profiles", "prefs", 1, "$.missing", &missing))
+	assert.Nil(t, missing)
+
+	assert.ErrorIs(t, exec.JSONExtract(ctx, "profiles", "prefs", 9, "$", &out), sqliteutils.ErrRowNotFound)
+
+	// JSONSet nests values as JSON, not as quoted strings.
+	require.NoError(t, exec.JSONSet(ctx, "profiles", "prefs", 1, "$.tags", []string{"c"}))
+	require.NoError(t, exec.JSONSet(ctx, "profiles", "prefs", 1, "$.layout.sidebar", true))
+	require.NoError(t, exec.JSONSet(ctx, "profiles", "prefs", 2, "$.theme", "light"))
+	assert.ErrorIs(t, exec.JSONSet(ctx, "profiles", "prefs", 9, "$.theme", "light"), sqliteutils.ErrRowNotFound)
+
+	var raw string
+	require.NoError(t, exec.Exec(ctx, "SELECT prefs FROM profiles WHERE id = 1;", nil, func(_ int, row map[string]interface{}) {
+		raw = row["prefs"].(string)
+	}))
+	assert.JSONEq(t, `{"theme":"dark","tags":["c"],"layout":{"sidebar":true}}`, raw)
+
+	// The generated column is indexed and used by queries on it.
+	require.NoError(t, exec.JSONIndex(ctx, "profiles", "prefs", "$.theme", "theme"))
+	var ids []int64
+	require.NoError(t, exec.Exec(ctx, "SELECT id FROM profiles WHERE theme = 'light';", nil, func(_ int, row map[string]interface{}) {
+		ids = append(ids, row["id"].(int64))
+	}))
+	assert.Equal(t, []int64{2}, ids)
+
+	var plan []string
+	require.NoError(t, exec.Exec(ctx, "EXPLAIN QUERY PLAN SELECT id FROM profiles WHERE theme = 'light';", nil, func(_ int, row map[string]interface{}) {
+		plan = append(plan, row["detail"].(string))
+	}))
+	assert.Contains(t, strings.Join(plan, "\n"), "profiles_theme")
+
+	assert.Error(t, exec.JSONIndex(ctx, "profiles", "prefs", "$.theme", "theme"))
+
+	// A value that fails to encode is an error, not a NULL.
+	err := exec.Exec(ctx, "UPDATE profiles SET prefs = $prefs WHERE id = 2;", map[string]interface{}{"$prefs": exec.JSON(make(chan int))}, nil)
+	assert.ErrorContains(t, err, "parameter $prefs")
+	require.NoError(t, exec.JSONExtract(ctx, "profiles", "prefs", 2, "$.theme", &theme))
+	assert.Equal(t, "light", theme)
+}
@@ -0,0 +1,156 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+)
+
+// RowSource yields the rows for Load. It returns the values of the next
+// row in the order of Load's columns, or io.EOF once there are no more.
+type RowSource func() ([]interface{}, error)
+
+// LoadOptions configures Load.
+type LoadOptions struct {
+	// ChunkSize is how many rows are inserted per transaction. Defaults to
+	// 10000.
+	ChunkSize int
+	// Unsafe sets synchronous=OFF on the connection for the load and
+	// restores it after. The journal mode is left alone, since changing it
+	// needs exclusive access to the database. A power loss during the load
+	// can corrupt the database, so only set it for scratch databases that
+	// can be rebuilt.
+	Unsafe bool
+	// Progress, if set, is called after each chunk commits with the number
+	// of rows loaded so far.
+	Progress func(loaded int64)
+}
+
+// Load inserts the rows of source into columns of table through a single
+// prepared statement, committing every opts.ChunkSize rows in an IMMEDIATE
+// transaction. Unlike ExecMulti, it needs no query and parameter map per
+// row, so memory stays flat however many rows source yields. opts may be
+// nil.
+//
+// Load returns the number of rows committed. When it fails, the rows of the
+// chunk being inserted are rolled back and earlier chunks are kept.
+func Load(ctx context.Context, table string, columns []string, source RowSource, opts *LoadOptions) (int64, error) {
+	var loaded int64
+	err := withConn(ctx, func(conn *sqlite.Conn) error {
+		var err error
+		loaded, err = LoadConn(conn, table, columns, source, opts)
+		return err
+	})
+	return loaded, err
+}
+
+// LoadConn is Load on a connection the caller already holds. If conn is
+// inside a transaction, each chunk is a savepoint of it.
+func LoadConn(conn *sqlite.Conn, table string, columns []string, source RowSource, opts *LoadOptions) (loaded int64, err error) {
+	if len(columns) == 0 {
+		return 0, errors.New("no columns to load")
+	}
+	chunkSize := 10000
+	var progress func(int64)
+	if opts != nil {
+		if opts.ChunkSize > 0 {
+			chunkSize = opts.ChunkSize
+		}
+		progress = opts.Progress
+		if opts.Unsafe {
+			restore, err := unsafePragmas(conn)
+			if err != nil {
+				return 0, err
+			}
+			defer func() {
+				err = errors.Join(err, restore())
+			}()
+		}
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = sqliteutils.QuoteIdentifier(column)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", sqliteutils.QuoteIdentifier(table),
+		strings.Join(quoted, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		return 0, fmt.Errorf("SQL preparation error for query '%s': %w", query, err)
+	}
+
+	for done := false; !done; {
+		tx, err := beginConn(conn, TxImmediate, func() {})
+		if err != nil {
+			return loaded, err
+		}
+		var n int64
+		n, done, err = loadChunk(conn, stmt, columns, source, chunkSize)
+		if err != nil {
+			return loaded, errors.Join(err, tx.Rollback())
+		}
+		if err := tx.Commit(); err != nil {
+			return loaded, err
+		}
+		loaded += n
+		if progress != nil && n > 0 {
+			progress(loaded)
+		}
+	}
+	return loaded, nil
+}
+
+// loadChunk inserts up to size rows of source with stmt, reporting whether
+// source is exhausted.
+func loadChunk(conn *sqlite.Conn, stmt *sqlite.Stmt, columns []string, source RowSource, size int) (n int64, done bool, err error) {
+	defer stmt.ClearBindings()
+	for n < int64(size) {
+		values, err := source()
+		if errors.Is(err, io.EOF) {
+			return n, true, nil
+		}
+		if err != nil {
+			return n, false, fmt.Errorf("failed to read row %d: %w", n+1, err)
+		}
+		if len(values) != len(columns) {
+			return n, false, fmt.Errorf("row has %d values, but %d columns were given", len(values), len(columns))
+		}
+		for i, value := range values {
+			if err := bindValue(conn, stmt, i+1, columns[i], value); err != nil {
+				return n, false, err
+			}
+		}
+		_, err = stmt.Step()
+		if resetErr := stmt.Reset(); err == nil {
+			err = resetErr
+		}
+		if err != nil {
+			return n, false, fmt.Errorf("failed to insert row: %w", err)
+		}
+		n++
+	}
+	return n, false, nil
+}
+
+// unsafePragmas turns off syncing on conn, returning a function that
+// restores the previous setting.
+func unsafePragmas(conn *sqlite.Conn) (func() error, error) {
+	var synchronous int64
+	err := QueryConn(conn, "PRAGMA synchronous;", nil, func(_ []string, values []interface{}) {
+		synchronous, _ = values[0].(int64)
+	})
+	if err == nil {
+		err = executeRawStatement(conn, "PRAGMA synchronous = OFF;")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to set load pragmas: %w", err)
+	}
+	return func() error {
+		return executeRawStatement(conn, fmt.Sprintf("PRAGMA synchronous = %d;", synchronous))
+	}, nil
+}
@@ -0,0 +1,119 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingSource yields rows (i, "name i") for i in [1, n], then fails with
+// err if it is set.
+func countingSource(n int, err error) exec.RowSource {
+	i := 0
+	return func() ([]interface{}, error) {
+		if i == n {
+			if err != nil {
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		i++
+		return []interface{}{i, fmt.Sprintf("name %d", i)}, nil
+	}
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	var progress []int64
+	loaded, err := exec.Load(ctx, "items", []string{"id", "name"}, countingSource(2500, nil), &exec.LoadOptions{
+		ChunkSize: 1000,
+		Unsafe:    true,
+		Progress:  func(n int64) { progress = append(progress, n) },
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2500), loaded)
+	assert.Equal(t, []int64{1000, 2000, 2500}, progress)
+
+	var count int64
+	var synchronous int64
+	require.NoError(t, exec.Q(ctx, "SELECT count(*) FROM items;", func(_ []string, values []interface{}) {
+		count = values[0].(int64)
+	}))
+	assert.Equal(t, int64(2500), count)
+	require.NoError(t, exec.Q(ctx, "PRAGMA synchronous;", func(_ []string, values []interface{}) {
+		synchronous = values[0].(int64)
+	}))
+	assert.NotEqual(t, int64(0), synchronous)
+
+	// A failing chunk is rolled back, keeping the chunks before it.
+	require.NoError(t, exec.E(ctx, "DELETE FROM items;"))
+	errSource := errors.New("source failed")
+	loaded, err = exec.Load(ctx, "items", []string{"id", "name"}, countingSource(150, errSource), &exec.LoadOptions{ChunkSize: 100})
+	assert.ErrorIs(t, err, errSource)
+	assert.Equal(t, int64(100), loaded)
+	require.NoError(t, exec.Q(ctx, "SELECT count(*) FROM items;", func(_ []string, values []interface{}) {
+		count = values[0].(int64)
+	}))
+	assert.Equal(t, int64(100), count)
+
+	_, err = exec.Load(ctx, "items", []string{"id"}, countingSource(1, nil), nil)
+	assert.Error(t, err)
+	_, err = exec.Load(ctx, "missing", []string{"id"}, countingSource(1, nil), nil)
+	assert.Error(t, err)
+}
+
+func TestLoad_SharedPool(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "load.db"), 4))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.E(ctx, "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);"))
+
+	// Hold a read transaction open on another connection of the pool.
+	pinned, err := pool.Pin(ctx)
+	require.NoError(t, err)
+	require.NoError(t, exec.ExecConn(pinned.Conn(), "BEGIN;", nil, nil))
+	require.NoError(t, exec.QueryConn(pinned.Conn(), "SELECT count(*) FROM items;", nil, func([]string, []interface{}) {}))
+
+	loaded, err := exec.Load(ctx, "items", []string{"id", "name"}, countingSource(500, nil), &exec.LoadOptions{ChunkSize: 100, Unsafe: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(500), loaded)
+	require.NoError(t, pinned.Release())
+
+	var journalMode string
+	require.NoError(t, exec.Q(ctx, "PRAGMA journal_mode;", func(_ []string, values []interface{}) {
+		journalMode = values[0].(string)
+	}))
+	assert.Equal(t, "wal", journalMode)
+}
+
+func BenchmarkLoad(b *testing.B) {
+	benchmarkRows(b, 1)
+	if err := exec.E(context.Background(), "DELETE FROM items;"); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := exec.Load(context.Background(), "items", []string{"id", "name"}, countingSource(1000, nil), nil); err != nil {
+			b.Fatal(err)
+		}
+		if err := exec.E(context.Background(), "DELETE FROM items;"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
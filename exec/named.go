@@ -0,0 +1,115 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// NamedExec executes query with named parameters taken from arg, a struct,
+// a pointer to one or a map with string keys. See NamedParams.
+func NamedExec(ctx context.Context, query string, arg interface{}) error {
+	params, err := NamedParams(arg)
+	if err != nil {
+		return err
+	}
+	return Exec(ctx, query, params, nil)
+}
+
+// NamedParams returns the named parameters of arg for binding with :name,
+// @name or $name.
+//
+// Struct fields are named by their db tag, or by their lowercased name if
+// they have none; fields tagged db:"-" and unexported fields are skipped. The
+// fields of embedded structs are promoted, and those of other struct fields
+// are named with the field's name and an underscore as a prefix, as in
+// address_city. Nil pointers bind NULL. Map keys are used as names as given.
+func NamedParams(arg interface{}) (map[string]interface{}, error) {
+	params := make(map[string]interface{})
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, fmt.Errorf("named parameters must not be a nil pointer")
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("named parameter map keys must be strings, not %s", v.Type().Key())
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			addNamedParam(params, iter.Key().String(), iter.Value().Interface())
+		}
+	case reflect.Struct:
+		addStructParams(params, "", v)
+	default:
+		return nil, fmt.Errorf("named parameters must be a struct or map, not %T", arg)
+	}
+	return params, nil
+}
+
+func addStructParams(params map[string]interface{}, prefix string, v reflect.Value) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("db")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name := tag
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+
+		fv := v.Field(i)
+		if nested, ok := nestedStruct(fv); ok {
+			if !nested.IsValid() {
+				continue
+			}
+			if field.Anonymous && tag == "" {
+				addStructParams(params, prefix, nested)
+			} else {
+				addStructParams(params, prefix+name+"_", nested)
+			}
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		addNamedParam(params, prefix+name, fv.Interface())
+	}
+}
+
+// nestedStruct returns the struct fv holds or points to, if its fields should
+// be bound individually. Nil pointers to structs have no fields to bind.
+func nestedStruct(fv reflect.Value) (reflect.Value, bool) {
+	t := fv.Type()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || valueStruct(t) {
+		return reflect.Value{}, false
+	}
+	if fv.Kind() == reflect.Ptr {
+		if fv.IsNil() {
+			return reflect.Value{}, true
+		}
+		fv = fv.Elem()
+	}
+	return fv, true
+}
+
+// addNamedParam adds value under name with every parameter prefix SQLite
+// accepts, unless name already has one.
+func addNamedParam(params map[string]interface{}, name string, value interface{}) {
+	if name != "" && strings.ContainsAny(name[:1], ":@$") {
+		params[name] = value
+		return
+	}
+	for _, prefix := range []string{":", "@", "$"} {
+		params[prefix+name] = value
+	}
+}
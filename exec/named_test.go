@@ -0,0 +1,97 @@
+package exec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type address struct {
+	City string `db:"city"`
+	Zip  *string
+}
+
+type timestamps struct {
+	CreatedAt int64 `db:"created_at"`
+}
+
+type customer struct {
+	timestamps
+	ID      int64    `db:"id"`
+	Name    string   `db:"name"`
+	Email   *string  `db:"email"`
+	Home    address  `db:"home"`
+	Work    *address `db:"work"`
+	Ignored string   `db:"-"`
+	secret  string
+}
+
+func TestNamedExec(t *testing.T) {
+	ctx := context.Background()
+	const migration = `CREATE TABLE customers (
+		id INTEGER PRIMARY KEY, name TEXT, email TEXT,
+		home_city TEXT, home_zip TEXT, work_city TEXT, created_at INTEGER
+	);`
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	zip := "10115"
+	c := customer{
+		timestamps: timestamps{CreatedAt: 1700000000000},
+		ID:         1,
+		Name:       "alice",
+		Home:       address{City: "Berlin", Zip: &zip},
+		Ignored:    "x",
+		secret:     "y",
+	}
+	const insert = `INSERT INTO customers (id, name, email, home_city, home_zip, work_city, created_at)
+		VALUES (:id, @name, $email, :home_city, :home_zip, :work_city, :created_at);`
+	require.NoError(t, exec.NamedExec(ctx, insert, &c))
+	require.NoError(t, exec.NamedExec(ctx, "UPDATE customers SET name = :name WHERE id = :id;",
+		map[string]interface{}{"id": 1, "name": "alice b."}))
+
+	var row map[string]interface{}
+	require.NoError(t, exec.Exec(ctx, "SELECT * FROM customers;", nil, func(_ int, r map[string]interface{}) { row = r }))
+	assert.Equal(t, map[string]interface{}{
+		"id": int64(1), "name": "alice b.", "email": nil,
+		"home_city": "Berlin", "home_zip": "10115", "work_city": nil, "created_at": int64(1700000000000),
+	}, row)
+
+	params, err := exec.NamedParams(c)
+	require.NoError(t, err)
+	assert.NotContains(t, params, ":ignored")
+	assert.NotContains(t, params, ":secret")
+	assert.Contains(t, params, ":home_zip")
+
+	_, err = exec.NamedParams(42)
+	assert.Error(t, err)
+}
+
+func TestNamedExec_Time(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE events (id INTEGER PRIMARY KEY, at TEXT);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	type event struct {
+		ID int64     `db:"id"`
+		At time.Time `db:"at"`
+	}
+	at := time.Date(2024, 5, 1, 10, 30, 0, 0, time.FixedZone("CEST", 2*60*60))
+	require.NoError(t, exec.NamedExec(ctx, "INSERT INTO events (id, at) VALUES (:id, :at);", event{ID: 1, At: at}))
+
+	var stored interface{}
+	require.NoError(t, exec.Query(ctx, "SELECT at FROM events WHERE id = 1;", nil, func(_ []string, values []interface{}) {
+		stored = values[0]
+	}))
+	assert.Equal(t, "2024-05-01T08:30:00Z", stored)
+}
@@ -0,0 +1,56 @@
+package exec
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+)
+
+// PanicError is returned when a result callback panics. The statement is
+// reset before it is returned, and a transaction left open on a connection
+// taken from the pool is rolled back before the connection is returned, so
+// the panic does not leak into later calls.
+type PanicError struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack of the panicking goroutine.
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("result callback panicked: %v", e.Value)
+}
+
+// Unwrap returns Value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// recoverCallback turns a panic of a result callback stepping stmt into a
+// *PanicError in *err, resetting stmt so its connection can be reused. It
+// must be deferred directly.
+func recoverCallback(stmt *sqlite.Stmt, err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	stmt.Reset()
+	stmt.ClearBindings()
+	*err = &PanicError{Value: r, Stack: debug.Stack()}
+}
+
+// rollbackAfterPanic rolls back the transaction that statements interrupted
+// by a panicking callback left open on conn, before it returns to the pool.
+func rollbackAfterPanic(conn *sqlite.Conn, err error) {
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) || conn.AutocommitEnabled() {
+		return
+	}
+	if rollbackErr := executeRawStatement(conn, "ROLLBACK;"); rollbackErr != nil {
+		sqliteutils.Logger().Error("failed to rollback transaction", "error", rollbackErr)
+	}
+}
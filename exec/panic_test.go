@@ -0,0 +1,66 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPanickingCallback(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO users (name, email) VALUES ('a', 'a@example.com'), ('b', 'b@example.com');", nil, nil))
+
+	boom := errors.New("boom")
+	var panicErr *exec.PanicError
+
+	err := exec.Query(ctx, "SELECT name FROM users", nil, func([]string, []interface{}) { panic(boom) })
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, boom, panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+	assert.ErrorIs(t, err, boom)
+
+	err = exec.QueryRows(ctx, "SELECT name FROM users", nil, func(*exec.Row) { panic("rows") })
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "rows", panicErr.Value)
+
+	err = exec.Q(ctx, "SELECT name FROM users WHERE name = ?", func([]string, []interface{}) { panic("args") }, "a")
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "args", panicErr.Value)
+
+	// A transaction opened by earlier statements is rolled back before the
+	// connection returns to the pool.
+	err = exec.ExecMulti(ctx, []string{
+		"BEGIN;",
+		"INSERT INTO users (name, email) VALUES ('c', 'c@example.com');",
+		"SELECT name FROM users;",
+	}, make([]map[string]interface{}, 3), func(int, map[string]interface{}) { panic("exec") })
+	var stmtErr *exec.StatementError
+	require.ErrorAs(t, err, &stmtErr)
+	assert.Equal(t, 2, stmtErr.Index)
+	require.ErrorAs(t, err, &panicErr)
+
+	err = exec.ExecMultiTx(ctx, []string{
+		"INSERT INTO users (name, email) VALUES ('d', 'd@example.com');",
+		"SELECT name FROM users;",
+	}, make([]map[string]interface{}, 2), func(int, map[string]interface{}) { panic("tx") })
+	require.ErrorAs(t, err, &panicErr)
+
+	// The single connection is usable and saw neither insert.
+	var count int64
+	require.NoError(t, exec.Query(ctx, "SELECT count(*) FROM users", nil, func(_ []string, values []interface{}) {
+		count = values[0].(int64)
+	}))
+	assert.Equal(t, int64(2), count)
+	require.NoError(t, exec.Exec(ctx, "BEGIN IMMEDIATE;", nil, nil))
+	require.NoError(t, exec.Exec(ctx, "ROLLBACK;", nil, nil))
+}
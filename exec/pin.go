@@ -0,0 +1,63 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+)
+
+// Pinned runs statements on one connection of the global pool until it is
+// released, so connection-scoped state such as temporary tables,
+// last_insert_rowid and PRAGMA settings carries over between calls. A Pinned
+// is not safe for concurrent use.
+type Pinned struct {
+	*pool.Pinned
+}
+
+// Pin pins a connection of the global pool with pool.Pin. Release must be
+// called to return it.
+func Pin(ctx context.Context) (*Pinned, error) {
+	pin, err := pool.Pin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &Pinned{pin}, nil
+}
+
+// Exec is Exec on the pinned connection.
+func (pin *Pinned) Exec(query string, params map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	return pin.ExecMulti([]string{query}, []map[string]interface{}{params}, resultFunc)
+}
+
+// ExecMulti is ExecMulti on the pinned connection.
+func (pin *Pinned) ExecMulti(queries []string, params []map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	if len(queries) != len(params) {
+		return fmt.Errorf("the number of queries (%d) does not match the number of params (%d)", len(queries), len(params))
+	}
+	return pin.Do(func(conn *sqlite.Conn) error {
+		return executeStatements(conn, queries, params, resultFunc)
+	})
+}
+
+// Query is Query on the pinned connection.
+func (pin *Pinned) Query(query string, params map[string]interface{}, rowFunc func(columns []string, values []interface{})) error {
+	return pin.Do(func(conn *sqlite.Conn) error {
+		return QueryConn(conn, query, params, rowFunc)
+	})
+}
+
+// Begin begins a transaction in mode on the pinned connection. Finishing
+// the transaction keeps the connection pinned.
+func (pin *Pinned) Begin(mode TxMode) (*Tx, error) {
+	if _, err := ParseTxMode(string(mode)); err != nil {
+		return nil, err
+	}
+	var tx *Tx
+	err := pin.Do(func(conn *sqlite.Conn) (err error) {
+		tx, err = beginConn(conn, mode, func() {})
+		return err
+	})
+	return tx, err
+}
@@ -0,0 +1,99 @@
+package exec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPin(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);", 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	pin, err := exec.Pin(ctx)
+	require.NoError(t, err)
+
+	// Temporary tables and last_insert_rowid carry over between calls.
+	require.NoError(t, pin.Exec("CREATE TEMP TABLE scratch (v INTEGER);", nil, nil))
+	require.NoError(t, pin.Exec("INSERT INTO items (name) VALUES ('a');", nil, nil))
+	require.NoError(t, pin.Exec("INSERT INTO scratch VALUES (last_insert_rowid());", nil, nil))
+	var v int64
+	require.NoError(t, pin.Query("SELECT v FROM scratch;", nil, func(_ []string, values []interface{}) {
+		v = values[0].(int64)
+	}))
+	assert.Equal(t, int64(1), v)
+
+	// Transactions on the pinned connection keep it pinned.
+	tx, err := pin.Begin(exec.TxImmediate)
+	require.NoError(t, err)
+	require.NoError(t, tx.Exec("INSERT INTO items (name) VALUES ('b');", nil, nil))
+	require.NoError(t, tx.Commit())
+	require.NoError(t, pin.Query("SELECT last_insert_rowid();", nil, func(_ []string, values []interface{}) {
+		v = values[0].(int64)
+	}))
+	assert.Equal(t, int64(2), v)
+
+	// A transaction left open is rolled back on release.
+	_, err = pin.Begin(exec.TxDeferred)
+	require.NoError(t, err)
+	require.NoError(t, pin.Exec("DELETE FROM items;", nil, nil))
+	require.NoError(t, pin.Release())
+	assert.NoError(t, pin.Release())
+	assert.ErrorIs(t, pin.Exec("SELECT 1;", nil, nil), pool.ErrReleased)
+	assert.Nil(t, pin.Conn())
+
+	var names []interface{}
+	require.NoError(t, exec.Query(ctx, "SELECT name FROM items ORDER BY id;", nil, func(_ []string, values []interface{}) {
+		names = append(names, values[0])
+	}))
+	assert.Equal(t, []interface{}{"a", "b"}, names)
+}
+
+func TestPin_ReleaseResetsConnection(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	pin, err := exec.Pin(ctx)
+	require.NoError(t, err)
+	require.NoError(t, pin.ExecMulti([]string{
+		"CREATE TEMP TABLE scratch (v INTEGER);",
+		"CREATE TEMP VIEW scratch_view AS SELECT v FROM scratch;",
+		"CREATE TEMP TRIGGER items_name AFTER INSERT ON items BEGIN UPDATE items SET name = 'x' WHERE id = new.id; END;",
+		"PRAGMA foreign_keys = OFF;",
+		"PRAGMA recursive_triggers = ON;",
+		"ATTACH DATABASE ':memory:' AS scratch_db;",
+	}, make([]map[string]interface{}, 6), nil))
+	require.NoError(t, pin.Release())
+
+	// The next borrower of the only connection sees none of it.
+	var temp int64
+	require.NoError(t, exec.Query(ctx, "SELECT count(*) FROM temp.sqlite_schema;", nil, func(_ []string, values []interface{}) {
+		temp = values[0].(int64)
+	}))
+	assert.Zero(t, temp)
+	assert.Error(t, exec.Query(ctx, "SELECT * FROM scratch;", nil, nil))
+
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO items (name) VALUES ('a');", nil, nil))
+	var name string
+	require.NoError(t, exec.Query(ctx, "SELECT name FROM items;", nil, func(_ []string, values []interface{}) {
+		name = values[0].(string)
+	}))
+	assert.Equal(t, "a", name)
+
+	var settings []interface{}
+	require.NoError(t, exec.Query(ctx, "SELECT * FROM pragma_foreign_keys, pragma_recursive_triggers, (SELECT count(*) FROM pragma_database_list WHERE name = 'scratch_db');", nil, func(_ []string, values []interface{}) {
+		settings = values
+	}))
+	assert.Equal(t, []interface{}{int64(1), int64(0), int64(0)}, settings)
+}
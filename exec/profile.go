@@ -0,0 +1,266 @@
+package exec
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"github.com/dropsite-ai/sqliteutils"
+)
+
+// MaxProfileDigests bounds the number of distinct statements profiled.
+// Statements beyond it are counted under OtherDigest.
+const MaxProfileDigests = 1000
+
+// OtherDigest collects statements profiled after MaxProfileDigests.
+const OtherDigest = "(other)"
+
+// profileSamples is the number of recent durations kept per digest for P95.
+const profileSamples = 256
+
+// StatementProfile is the accumulated cost of one statement digest.
+type StatementProfile struct {
+	// Digest is the statement with literals replaced by ? and whitespace
+	// collapsed, so calls differing only in values share a profile.
+	Digest string `json:"digest"`
+	Calls  int64  `json:"calls"`
+	Errors int64  `json:"errors"`
+	// Total is the time spent preparing and stepping the statement, and Mean
+	// its average per call. P95 is taken over the most recent 256 calls.
+	Total time.Duration `json:"total"`
+	Mean  time.Duration `json:"mean"`
+	P95   time.Duration `json:"p95"`
+	// RowsReturned counts result rows. The driver does not expose the
+	// statement status counters, so the rows a statement examined are not
+	// reported; EXPLAIN QUERY PLAN shows whether it scans a table.
+	RowsReturned int64 `json:"rows_returned"`
+}
+
+type statementProfile struct {
+	StatementProfile
+	samples []time.Duration
+	next    int
+}
+
+var (
+	profiling   atomic.Bool
+	profileLock sync.Mutex
+	profiles    = map[string]*statementProfile{}
+)
+
+// EnableProfiling starts recording the cost of every statement this package
+// runs, by digest. It adds a map lookup per statement.
+func EnableProfiling() {
+	profiling.Store(true)
+}
+
+// DisableProfiling stops recording statement costs. Recorded profiles are
+// kept until ResetProfile.
+func DisableProfiling() {
+	profiling.Store(false)
+}
+
+// ResetProfile discards the recorded profiles.
+func ResetProfile() {
+	profileLock.Lock()
+	defer profileLock.Unlock()
+	profiles = map[string]*statementProfile{}
+}
+
+// ProfileSnapshot returns the recorded profiles, most total time first.
+func ProfileSnapshot() []StatementProfile {
+	profileLock.Lock()
+	snapshot := make([]StatementProfile, 0, len(profiles))
+	var samples []time.Duration
+	for _, p := range profiles {
+		s := p.StatementProfile
+		s.Mean = s.Total / time.Duration(s.Calls)
+		samples = append(samples[:0], p.samples...)
+		sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+		s.P95 = samples[(len(samples)*95+99)/100-1]
+		snapshot = append(snapshot, s)
+	}
+	profileLock.Unlock()
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Total != snapshot[j].Total {
+			return snapshot[i].Total > snapshot[j].Total
+		}
+		return snapshot[i].Digest < snapshot[j].Digest
+	})
+	return snapshot
+}
+
+// profiler times one execution of a statement for the profile, the
+// OnStatement hooks and the query log. It is nil when none is in use.
+type profiler struct {
+	start time.Time
+	rows  int64
+}
+
+func startProfile() *profiler {
+	if !profiling.Load() && statementHooks.Load() == nil && !sqliteutils.QueryLogEnabled() {
+		return nil
+	}
+	return &profiler{start: time.Now()}
+}
+
+// row counts a result row.
+func (p *profiler) row() {
+	if p != nil {
+		p.rows++
+	}
+}
+
+// finish reports the execution of query to the OnStatement hooks, the
+// profile and the query log.
+func (p *profiler) finish(query string, params map[string]interface{}, err error) {
+	if p == nil {
+		return
+	}
+	sqliteutils.LogQuery(p.start, query, params, err)
+	elapsed := time.Since(p.start)
+	digest := Digest(query)
+	callStatementHooks(StatementInfo{SQL: query, Digest: digest, Duration: elapsed, Rows: p.rows, Err: err})
+	if profiling.Load() {
+		p.record(digest, elapsed, err)
+	}
+}
+
+// record adds an execution to the profile of digest.
+func (p *profiler) record(digest string, elapsed time.Duration, err error) {
+	profileLock.Lock()
+	defer profileLock.Unlock()
+	prof, ok := profiles[digest]
+	if !ok {
+		if len(profiles) >= MaxProfileDigests {
+			digest = OtherDigest
+			prof = profiles[digest]
+		}
+		if prof == nil {
+			prof = &statementProfile{StatementProfile: StatementProfile{Digest: digest}}
+			profiles[digest] = prof
+		}
+	}
+	prof.Calls++
+	if err != nil {
+		prof.Errors++
+	}
+	prof.Total += elapsed
+	prof.RowsReturned += p.rows
+	if len(prof.samples) < profileSamples {
+		prof.samples = append(prof.samples, elapsed)
+	} else {
+		prof.samples[prof.next] = elapsed
+		prof.next = (prof.next + 1) % profileSamples
+	}
+}
+
+// Digest normalizes query for profiling: comments are dropped, string, blob
+// and numeric literals become ?, tokens are separated by single spaces, and
+// lists of placeholders such as IN (1, 2, 3) become a single ?.
+func Digest(query string) string {
+	var b strings.Builder
+	var last string
+	emit := func(token string) {
+		switch {
+		case b.Len() == 0, last == "(", last == ".", token == ",", token == ")", token == ";", token == ".":
+		default:
+			b.WriteByte(' ')
+		}
+		b.WriteString(token)
+		last = token
+	}
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			i += end
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 4
+			}
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '\'' || ((c == 'x' || c == 'X') && i+1 < len(query) && query[i+1] == '\'' && !identByte(query, i-1)):
+			if c != '\'' {
+				i++
+			}
+			i = quotedEnd(query, i, '\'')
+			emit("?")
+		case c == '"' || c == '`' || c == '[':
+			closer := c
+			if c == '[' {
+				closer = ']'
+			}
+			end := quotedEnd(query, i, closer)
+			emit(query[i:end])
+			i = end
+		case c >= '0' && c <= '9' && !identByte(query, i-1):
+			for i < len(query) && (identByte(query, i) || query[i] == '.' ||
+				((query[i] == '+' || query[i] == '-') && (query[i-1] == 'e' || query[i-1] == 'E'))) {
+				i++
+			}
+			emit("?")
+		case c == '?':
+			for i++; i < len(query) && query[i] >= '0' && query[i] <= '9'; i++ {
+			}
+			emit("?")
+		case identByte(query, i) || c == '$' || c == ':' || c == '@':
+			start := i
+			for i++; i < len(query) && identByte(query, i); i++ {
+			}
+			emit(query[start:i])
+		case strings.IndexByte("<>=!|", c) >= 0:
+			start := i
+			for i++; i < len(query) && strings.IndexByte("<>=!|", query[i]) >= 0; i++ {
+			}
+			emit(query[start:i])
+		default:
+			emit(string(c))
+			i++
+		}
+	}
+	digest := strings.TrimSuffix(b.String(), ";")
+	for {
+		collapsed := strings.ReplaceAll(digest, "?, ?", "?")
+		if collapsed == digest {
+			return digest
+		}
+		digest = collapsed
+	}
+}
+
+// identByte reports whether query[i] can be part of an identifier.
+func identByte(query string, i int) bool {
+	if i < 0 || i >= len(query) {
+		return false
+	}
+	c := query[i]
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// quotedEnd returns the index after the quoted token starting at query[i],
+// where a doubled closer is an escaped one.
+func quotedEnd(query string, i int, closer byte) int {
+	for i++; i < len(query); i++ {
+		if query[i] == closer {
+			if i+1 < len(query) && query[i+1] == closer && closer != ']' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
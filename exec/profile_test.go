@@ -0,0 +1,88 @@
+package exec_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDigest(t *testing.T) {
+	for query, want := range map[string]string{
+		"SELECT * FROM users WHERE id = 42;":                           "SELECT * FROM users WHERE id = ?",
+		"select  name\n\tfrom users -- comment\n where name='O''Hara'": "select name from users where name = ?",
+		"SELECT * FROM t WHERE id IN (1, 2, 3) AND b = x'00ff'":        "SELECT * FROM t WHERE id IN (?) AND b = ?",
+		`INSERT INTO "t 1" (a, b) VALUES (:a, 1.5e-3) /* c */`:         `INSERT INTO "t 1" (a, b) VALUES (:a, ?)`,
+		"SELECT col1, ?2 FROM t2":                                      "SELECT col1, ? FROM t2",
+		"SELECT u.name FROM users u WHERE u.id>=7":                     "SELECT u.name FROM users u WHERE u.id >= ?",
+	} {
+		assert.Equal(t, want, exec.Digest(query), query)
+	}
+}
+
+func TestProfileSnapshot(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	exec.EnableProfiling()
+	defer exec.DisableProfiling()
+	exec.ResetProfile()
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, exec.Exec(ctx, "INSERT INTO users (name, email) VALUES (:name, :email);", map[string]interface{}{
+			":name":  fmt.Sprintf("user%d", i),
+			":email": fmt.Sprintf("user%d@example.com", i),
+		}, nil))
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, exec.Query(ctx, fmt.Sprintf("SELECT name FROM users WHERE name >= 'user%d'", i), nil, nil))
+	}
+	assert.Error(t, exec.Exec(ctx, "SELECT * FROM missing WHERE id = 1", nil, nil))
+	require.NoError(t, exec.E(ctx, "UPDATE users SET email = ? WHERE name = ?;", "a@example.com", "user1"))
+	require.NoError(t, exec.Q(ctx, "SELECT id FROM users WHERE id > ?;", nil, 5))
+	require.NoError(t, exec.QueryRows(ctx, "SELECT id FROM users WHERE id <= :id;", map[string]interface{}{":id": 2}, nil))
+
+	snapshot := exec.ProfileSnapshot()
+	profiles := map[string]exec.StatementProfile{}
+	for _, p := range snapshot {
+		profiles[p.Digest] = p
+	}
+	require.Len(t, profiles, 6)
+
+	insert := profiles["INSERT INTO users (name, email) VALUES (:name, :email)"]
+	assert.Equal(t, int64(10), insert.Calls)
+	assert.Zero(t, insert.Errors)
+	assert.Positive(t, insert.Total)
+	assert.Equal(t, insert.Total/10, insert.Mean)
+	assert.LessOrEqual(t, insert.P95, insert.Total)
+
+	query := profiles["SELECT name FROM users WHERE name >= ?"]
+	assert.Equal(t, int64(3), query.Calls)
+	assert.Equal(t, int64(10+9+8), query.RowsReturned)
+
+	missing := profiles["SELECT * FROM missing WHERE id = ?"]
+	assert.Equal(t, int64(1), missing.Errors)
+
+	// E, Q and QueryRows are profiled too.
+	assert.Equal(t, int64(1), profiles["UPDATE users SET email = ? WHERE name = ?"].Calls)
+	assert.Equal(t, int64(5), profiles["SELECT id FROM users WHERE id > ?"].RowsReturned)
+	assert.Equal(t, int64(2), profiles["SELECT id FROM users WHERE id <= :id"].RowsReturned)
+
+	for i := 1; i < len(snapshot); i++ {
+		assert.GreaterOrEqual(t, snapshot[i-1].Total, snapshot[i].Total)
+	}
+
+	exec.DisableProfiling()
+	require.NoError(t, exec.Query(ctx, "SELECT 1", nil, nil))
+	assert.Len(t, exec.ProfileSnapshot(), 6)
+	exec.ResetProfile()
+	assert.Empty(t, exec.ProfileSnapshot())
+}
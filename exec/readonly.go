@@ -0,0 +1,127 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+)
+
+// ReadOnlyError is returned by ReadOnly when fn tried to change the
+// database. It matches sqliteutils.ErrReadOnly with errors.Is.
+type ReadOnlyError struct {
+	// Action describes the first denied operation, e.g. "SQLITE_INSERT table=users".
+	Action string
+	Err    error
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("read-only violation (%s): %v", e.Action, e.Err)
+}
+
+func (e *ReadOnlyError) Unwrap() []error {
+	return []error{sqliteutils.ErrReadOnly, e.Err}
+}
+
+// ReadOnly runs fn in a deferred transaction on a connection from the global
+// pool that cannot change the database. Statements that would write, change
+// the schema, attach databases or set pragmas fail to prepare, and ReadOnly
+// returns a *ReadOnlyError for them, even if fn ignored the failure. The
+// transaction is always rolled back.
+func ReadOnly(ctx context.Context, fn func(tx *Tx) error) error {
+	p, err := pool.GetPool()
+	if err != nil {
+		return fmt.Errorf("failed to create database pool: %w", err)
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return fmt.Errorf("failed to obtain database connection: %w", err)
+	}
+	defer p.Put(conn)
+	return ReadOnlyConn(conn, fn)
+}
+
+// ReadOnlyConn is ReadOnly on a connection the caller already holds. The
+// connection is left as it was found: query_only is turned off and the
+// authorizer removed when fn returns.
+func ReadOnlyConn(conn *sqlite.Conn, fn func(tx *Tx) error) (err error) {
+	// query_only also stops writes the authorizer does not see, such as
+	// those of virtual table modules.
+	if err := executeRawStatement(conn, "PRAGMA query_only = ON;"); err != nil {
+		return fmt.Errorf("failed to enable query_only: %w", err)
+	}
+	defer func() {
+		if resetErr := executeRawStatement(conn, "PRAGMA query_only = OFF;"); resetErr != nil {
+			err = errors.Join(err, fmt.Errorf("failed to disable query_only: %w", resetErr))
+		}
+	}()
+
+	var denied string
+	err = conn.SetAuthorizer(sqlite.AuthorizeFunc(func(action sqlite.Action) sqlite.AuthResult {
+		if readOnlyAction(action) {
+			return sqlite.AuthResultOK
+		}
+		if denied == "" {
+			denied = action.String()
+		}
+		return sqlite.AuthResultDeny
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to set authorizer: %w", err)
+	}
+	defer conn.SetAuthorizer(nil)
+
+	tx, err := beginConn(conn, TxDeferred, func() {})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	err = fn(tx)
+	if denied != "" {
+		if err == nil {
+			err = errors.New("statement was denied")
+		}
+		return &ReadOnlyError{Action: denied, Err: err}
+	}
+	return err
+}
+
+// readOnlyAction reports whether action cannot change the database.
+func readOnlyAction(action sqlite.Action) bool {
+	switch action.Type() {
+	case sqlite.OpSelect, sqlite.OpRead, sqlite.OpFunction, sqlite.OpRecursive, sqlite.OpTransaction, sqlite.OpSavepoint:
+		return true
+	case sqlite.OpPragma:
+		pragma := strings.ToLower(action.Pragma())
+		if action.PragmaArg() == "" {
+			return !writingPragmas[pragma]
+		}
+		// Other pragmas given a value set it.
+		return readingPragmas[pragma]
+	}
+	return false
+}
+
+// writingPragmas change the database even without a value.
+var writingPragmas = map[string]bool{
+	"incremental_vacuum": true,
+	"optimize":           true,
+	"wal_checkpoint":     true,
+}
+
+// readingPragmas take an argument but only read.
+var readingPragmas = map[string]bool{
+	"foreign_key_check": true,
+	"foreign_key_list":  true,
+	"index_info":        true,
+	"index_list":        true,
+	"index_xinfo":       true,
+	"integrity_check":   true,
+	"quick_check":       true,
+	"table_info":        true,
+	"table_xinfo":       true,
+}
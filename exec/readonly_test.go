@@ -0,0 +1,57 @@
+package exec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnly(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE kv (k TEXT PRIMARY KEY, v INTEGER); INSERT INTO kv VALUES ('a', 1);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	var count int64
+	require.NoError(t, exec.ReadOnly(ctx, func(tx *exec.Tx) error {
+		if err := tx.Exec("PRAGMA table_info(kv);", nil, nil); err != nil {
+			return err
+		}
+		return tx.Query("WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 3) SELECT count(*) FROM kv, n;", nil,
+			func(_ []string, values []interface{}) { count = values[0].(int64) })
+	}))
+	assert.Equal(t, int64(3), count)
+
+	for _, query := range []string{
+		"UPDATE kv SET v = 2;",
+		"CREATE TEMP TABLE scratch (x);",
+		"PRAGMA query_only = OFF;",
+		"ATTACH DATABASE ':memory:' AS other;",
+	} {
+		err := exec.ReadOnly(ctx, func(tx *exec.Tx) error {
+			return tx.Exec(query, nil, nil)
+		})
+		assert.ErrorIs(t, err, sqliteutils.ErrReadOnly, query)
+		var roErr *exec.ReadOnlyError
+		if assert.ErrorAs(t, err, &roErr, query) {
+			assert.NotEmpty(t, roErr.Action)
+		}
+	}
+
+	// Denied statements are reported even if fn ignores the failure.
+	err := exec.ReadOnly(ctx, func(tx *exec.Tx) error {
+		_ = tx.Exec("DELETE FROM kv;", nil, nil)
+		return nil
+	})
+	assert.ErrorIs(t, err, sqliteutils.ErrReadOnly)
+
+	// The connection is writable again afterwards.
+	require.NoError(t, exec.Exec(ctx, "UPDATE kv SET v = 5;", nil, nil))
+}
@@ -0,0 +1,184 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+)
+
+// Row is a result row of QueryRows. Its column names are read once per
+// statement and its Values buffer is reused for every row, so a Row is only
+// valid during the callback it is passed to: copy anything to keep.
+type Row struct {
+	// Columns are the result column names in select-list order.
+	Columns []string
+	// Values are the current row's values, in the order of Columns.
+	Values []interface{}
+
+	index map[string]int
+	// unit is the duration unit of the connection the row was read on.
+	unit time.Duration
+}
+
+// Value returns the value of the named column and whether the row has it.
+func (r *Row) Value(name string) (interface{}, bool) {
+	if r.index == nil {
+		r.index = make(map[string]int, len(r.Columns))
+		for i, column := range r.Columns {
+			// The first of duplicate names wins, as in select-list order.
+			if _, ok := r.index[column]; !ok {
+				r.index[column] = i
+			}
+		}
+	}
+	i, ok := r.index[name]
+	if !ok {
+		return nil, false
+	}
+	return r.Values[i], true
+}
+
+// Int64 returns the named column as an integer. Like the other typed
+// getters, it returns the zero value for NULL, and an error if the row has
+// no such column or its value does not convert.
+func (r *Row) Int64(name string) (int64, error) {
+	var v int64
+	err := r.Scan(name, &v)
+	return v, err
+}
+
+// Float64 returns the named column as a float.
+func (r *Row) Float64(name string) (float64, error) {
+	var v float64
+	err := r.Scan(name, &v)
+	return v, err
+}
+
+// Text returns the named column as a string.
+func (r *Row) Text(name string) (string, error) {
+	var v string
+	err := r.Scan(name, &v)
+	return v, err
+}
+
+// Bool returns the named column as a boolean, from an integer 0 or 1.
+func (r *Row) Bool(name string) (bool, error) {
+	var v bool
+	err := r.Scan(name, &v)
+	return v, err
+}
+
+// Bytes returns the named column as a byte slice.
+func (r *Row) Bytes(name string) ([]byte, error) {
+	var v []byte
+	err := r.Scan(name, &v)
+	return v, err
+}
+
+// Time returns the named column as a time, from Unix seconds or text in
+// one of the layouts Select parses.
+func (r *Row) Time(name string) (time.Time, error) {
+	var v time.Time
+	err := r.Scan(name, &v)
+	return v, err
+}
+
+// IsNull reports whether the named column is NULL or missing.
+func (r *Row) IsNull(name string) bool {
+	v, _ := r.Value(name)
+	return v == nil
+}
+
+// Scan converts the named column into the value dest points to, as Select
+// does for struct fields, so it accepts pointers, sql.Null* types and
+// sql.Scanner implementations as well as plain types.
+func (r *Row) Scan(name string, dest interface{}) error {
+	v, ok := r.Value(name)
+	if !ok {
+		return fmt.Errorf("row has no column %q", name)
+	}
+	target := reflect.ValueOf(dest)
+	if target.Kind() != reflect.Ptr || target.IsNil() {
+		return fmt.Errorf("destination must be a non-nil pointer, got %T", dest)
+	}
+	if err := assignValue(target.Elem(), v, r.durationUnit()); err != nil {
+		return fmt.Errorf("column %q: %w", name, err)
+	}
+	return nil
+}
+
+// Map returns a copy of the row keyed by column name.
+func (r *Row) Map() map[string]interface{} {
+	m := make(map[string]interface{}, len(r.Columns))
+	for i, column := range r.Columns {
+		m[column] = r.Values[i]
+	}
+	return m
+}
+
+// QueryRows is Query for large result sets: it reuses one Row for every
+// result row instead of allocating the values of each.
+func QueryRows(ctx context.Context, query string, params map[string]interface{}, rowFunc func(row *Row)) error {
+	// Obtain a connection pool
+	p, err := pool.GetPool()
+	if err != nil {
+		return fmt.Errorf("failed to create database pool: %w", err)
+	}
+
+	// Take a connection from the pool
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return fmt.Errorf("failed to obtain database connection: %w", err)
+	}
+	defer p.Put(conn)
+
+	err = QueryRowsConn(conn, query, params, rowFunc)
+	rollbackAfterPanic(conn, err)
+	return err
+}
+
+// QueryRowsConn is QueryRows on a connection the caller already holds.
+func QueryRowsConn(conn *sqlite.Conn, query string, params map[string]interface{}, rowFunc func(row *Row)) (err error) {
+	defer countQuery(&err)
+	trimmedQuery := trimQuery(query)
+	prof := startProfile()
+	defer func() { prof.finish(trimmedQuery, params, err) }()
+	stmt, err := conn.Prepare(trimmedQuery)
+	if err != nil {
+		return fmt.Errorf("SQL preparation error for query '%s': %w", trimmedQuery, err)
+	}
+	defer stmt.Finalize()
+	defer recoverCallback(stmt, &err)
+	if err := bindParams(conn, stmt, params); err != nil {
+		return fmt.Errorf("failed to bind parameters for query '%s': %w", trimmedQuery, err)
+	}
+
+	columns := columnNames(stmt)
+	row := &Row{Columns: columns, Values: make([]interface{}, len(columns)), unit: durationUnit(conn)}
+	reader, err := newColumnReader(conn, stmt, columns)
+	if err != nil {
+		return fmt.Errorf("error reading result of query '%s': %w", trimmedQuery, err)
+	}
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return fmt.Errorf("error executing SQL query '%s': %w", trimmedQuery, err)
+		}
+		if !hasRow {
+			break
+		}
+		prof.row()
+		if rowFunc != nil {
+			if err := reader.values(stmt, row.Values); err != nil {
+				stmt.Reset()
+				return fmt.Errorf("error reading result of query '%s': %w", trimmedQuery, err)
+			}
+			rowFunc(row)
+		}
+	}
+	return stmt.Reset()
+}
@@ -0,0 +1,135 @@
+package exec_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryRows(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, `
+		CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, price REAL);
+		INSERT INTO items (name, price) VALUES ('pen', 1.5), ('ink', NULL);
+	`, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	var maps []map[string]interface{}
+	var names []interface{}
+	require.NoError(t, exec.QueryRows(ctx, "SELECT id, name, price FROM items WHERE id >= :min ORDER BY id;",
+		map[string]interface{}{":min": 1}, func(row *exec.Row) {
+			assert.Equal(t, []string{"id", "name", "price"}, row.Columns)
+			name, ok := row.Value("name")
+			assert.True(t, ok)
+			names = append(names, name)
+			_, ok = row.Value("missing")
+			assert.False(t, ok)
+			maps = append(maps, row.Map())
+		}))
+	assert.Equal(t, []interface{}{"pen", "ink"}, names)
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "name": "pen", "price": 1.5},
+		{"id": int64(2), "name": "ink", "price": nil},
+	}, maps)
+}
+
+func TestRow_Getters(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, `
+		CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT, score REAL, done INTEGER, data BLOB, created_at TEXT, note TEXT);
+		INSERT INTO events VALUES (1, 'launch', 2.5, 1, x'0102', '2024-05-01 12:30:00', NULL);
+	`, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	require.NoError(t, exec.QueryRows(ctx, "SELECT * FROM events;", nil, func(row *exec.Row) {
+		id, err := row.Int64("id")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), id)
+		name, err := row.Text("name")
+		assert.NoError(t, err)
+		assert.Equal(t, "launch", name)
+		score, err := row.Float64("score")
+		assert.NoError(t, err)
+		assert.Equal(t, 2.5, score)
+		done, err := row.Bool("done")
+		assert.NoError(t, err)
+		assert.True(t, done)
+		data, err := row.Bytes("data")
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{1, 2}, data)
+		created, err := row.Time("created_at")
+		assert.NoError(t, err)
+		assert.Equal(t, time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC), created)
+
+		// NULL reads as the zero value; missing columns and bad conversions fail.
+		assert.True(t, row.IsNull("note"))
+		note, err := row.Text("note")
+		assert.NoError(t, err)
+		assert.Equal(t, "", note)
+		var nullable *string
+		assert.NoError(t, row.Scan("note", &nullable))
+		assert.Nil(t, nullable)
+		_, err = row.Int64("missing")
+		assert.Error(t, err)
+		_, err = row.Int64("name")
+		assert.Error(t, err)
+		assert.Error(t, row.Scan("id", id))
+	}))
+}
+
+// benchmarkRows fills a pool with n rows for the row reading benchmarks.
+func benchmarkRows(b *testing.B, n int) {
+	ctx := context.Background()
+	if err := pool.InitPool("file::memory:?mode=memory", 1); err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { pool.ClosePool() })
+	err := exec.ExecMulti(ctx, []string{
+		"CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, price REAL, sku TEXT);",
+		fmt.Sprintf(`WITH RECURSIVE seq(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM seq WHERE i < %d)
+			INSERT INTO items (name, price, sku) SELECT 'item ' || i, i * 0.5, hex(i) FROM seq;`, n),
+	}, make([]map[string]interface{}, 2), nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+}
+
+func BenchmarkExec_Rows(b *testing.B) {
+	benchmarkRows(b, 1000)
+	for i := 0; i < b.N; i++ {
+		if err := exec.Exec(context.Background(), "SELECT * FROM items;", nil, func(int, map[string]interface{}) {}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkQuery_Rows(b *testing.B) {
+	benchmarkRows(b, 1000)
+	for i := 0; i < b.N; i++ {
+		if err := exec.Query(context.Background(), "SELECT * FROM items;", nil, func([]string, []interface{}) {}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkQueryRows(b *testing.B) {
+	benchmarkRows(b, 1000)
+	for i := 0; i < b.N; i++ {
+		if err := exec.QueryRows(context.Background(), "SELECT * FROM items;", nil, func(*exec.Row) {}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
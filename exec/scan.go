@@ -0,0 +1,509 @@
+package exec
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+)
+
+// Select runs query and stores its result rows in dest, a pointer to a
+// slice of structs or of pointers to structs.
+//
+// Columns are matched to fields by name, ignoring case. Fields are named by
+// their db tag, or by their lowercased name if they have none; fields tagged
+// db:"-" and unexported fields are skipped, and the fields of embedded
+// structs are promoted. Every column must have a field.
+//
+// Columns of joined tables map to nested structs through a prefix with a
+// dot, as in SELECT o.id, u.id AS "user.id", u.name AS "user.name" for
+//
+//	type Order struct {
+//		ID   int64 `db:"id"`
+//		User *User `db:"user"`
+//	}
+//
+// A nested struct pointer is left nil when all its columns are NULL, as for
+// a LEFT JOIN without a match. A nested slice of structs collects a
+// one-to-many join: rows with equal values in the columns outside the slice
+// fold into one element whose slice gets one element per distinct set of
+// the slice's columns, skipping those that are all NULL.
+//
+// Integer, float, text and blob values are converted to fields of the Go
+// types they fit; integers fill bool fields, and text in RFC 3339 or SQLite
+// datetime format or integer Unix seconds fill time.Time fields. Numbers in
+// the unit set by WithDurationUnit and text such as "1m30s" fill
+// time.Duration fields. NULL leaves pointer fields nil, sql.Null* fields
+// invalid and other fields zero. Fields whose pointer implements
+// sql.Scanner convert values themselves.
+func Select(ctx context.Context, dest interface{}, query string, params map[string]interface{}) error {
+	return withConn(ctx, func(conn *sqlite.Conn) error {
+		return SelectConn(conn, dest, query, params)
+	})
+}
+
+// SelectConn is Select on a connection the caller already holds.
+func SelectConn(conn *sqlite.Conn, dest interface{}, query string, params map[string]interface{}) error {
+	slice := reflect.ValueOf(dest)
+	if slice.Kind() != reflect.Ptr || slice.IsNil() || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("select destination must be a pointer to a slice, not %T", dest)
+	}
+	slice = slice.Elem()
+	elemType := slice.Type().Elem()
+	structType := elemType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return fmt.Errorf("select destination must be a slice of structs, not %s", slice.Type())
+	}
+
+	elems, err := scanStructs(conn, structType, query, params)
+	if err != nil {
+		return err
+	}
+	result := reflect.MakeSlice(slice.Type(), 0, len(elems))
+	for _, elem := range elems {
+		if elemType.Kind() != reflect.Ptr {
+			elem = elem.Elem()
+		}
+		result = reflect.Append(result, elem)
+	}
+	slice.Set(result)
+	return nil
+}
+
+// Get runs query and stores its first result row in dest, a pointer to a
+// struct, mapping columns as Select does. Rows folded into one-to-many
+// slices count as one. If there is no row, it returns an error wrapping
+// sqliteutils.ErrRowNotFound.
+func Get(ctx context.Context, dest interface{}, query string, params map[string]interface{}) error {
+	return withConn(ctx, func(conn *sqlite.Conn) error {
+		return GetConn(conn, dest, query, params)
+	})
+}
+
+// GetConn is Get on a connection the caller already holds.
+func GetConn(conn *sqlite.Conn, dest interface{}, query string, params map[string]interface{}) error {
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("get destination must be a pointer to a struct, not %T", dest)
+	}
+	elems, err := scanStructs(conn, v.Elem().Type(), query, params)
+	if err != nil {
+		return err
+	}
+	if len(elems) == 0 {
+		return fmt.Errorf("%w: %s", sqliteutils.ErrRowNotFound, trimQuery(query))
+	}
+	v.Elem().Set(elems[0].Elem())
+	return nil
+}
+
+// withConn runs fn with a connection taken from the global pool, rolling back
+// a transaction left open by a panicking callback.
+func withConn(ctx context.Context, fn func(conn *sqlite.Conn) error) error {
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		err := fn(conn)
+		rollbackAfterPanic(conn, err)
+		return err
+	})
+}
+
+// scanStructs runs query and returns pointers to the structs of type t its
+// rows map to.
+func scanStructs(conn *sqlite.Conn, t reflect.Type, query string, params map[string]interface{}) ([]reflect.Value, error) {
+	var root *scanNode
+	unit := durationUnit(conn)
+	var rows [][]interface{}
+	var elems []reflect.Value
+	var scanErr error
+	err := QueryRowsConn(conn, query, params, func(row *Row) {
+		if scanErr != nil {
+			return
+		}
+		if root == nil {
+			root = &scanNode{typ: t}
+			for i, column := range row.Columns {
+				if scanErr = root.add(strings.Split(column, "."), i); scanErr != nil {
+					return
+				}
+			}
+		}
+		values := append([]interface{}(nil), row.Values...)
+		if root.hasSlices() {
+			// Rows can only be folded once all have been read.
+			rows = append(rows, values)
+			return
+		}
+		var elem reflect.Value
+		if elem, scanErr = root.build([][]interface{}{values}, unit); scanErr == nil {
+			elems = append(elems, elem)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if scanErr != nil {
+		return nil, scanErr
+	}
+	if root != nil && root.hasSlices() {
+		for _, group := range root.group(rows) {
+			elem, err := root.build(group, unit)
+			if err != nil {
+				return nil, err
+			}
+			elems = append(elems, elem)
+		}
+	}
+	return elems, nil
+}
+
+// scanNode maps result columns to the fields of a struct type and, through
+// its children, to nested structs.
+type scanNode struct {
+	typ      reflect.Type
+	fields   []scanField
+	children []*scanChild
+}
+
+// scanField is a column stored in a field of a scanNode's struct.
+type scanField struct {
+	column int
+	index  []int
+}
+
+// scanChild is a nested struct field, a pointer to one or a slice of them.
+type scanChild struct {
+	name  string
+	index []int
+	// ptr is set for pointer fields and for slices of pointers.
+	ptr   bool
+	slice bool
+	node  *scanNode
+}
+
+// add maps the column at position column, whose name is split at dots into
+// path, to a field of n's struct or of a nested one.
+func (n *scanNode) add(path []string, column int) error {
+	field, ok := structFields(n.typ)[strings.ToLower(path[0])]
+	if !ok {
+		return fmt.Errorf("column %s has no destination field in %s", strings.Join(path, "."), n.typ)
+	}
+	if len(path) == 1 {
+		if _, ok := nestedType(field.Type); ok {
+			return fmt.Errorf("column %s maps to struct field %s.%s: select its columns as %s.<column>", path[0], n.typ, field.Name, path[0])
+		}
+		n.fields = append(n.fields, scanField{column: column, index: field.Index})
+		return nil
+	}
+
+	var child *scanChild
+	for _, c := range n.children {
+		if c.name == strings.ToLower(path[0]) {
+			child = c
+		}
+	}
+	if child == nil {
+		t, ok := nestedType(field.Type)
+		if !ok {
+			return fmt.Errorf("column %s has no destination: %s.%s is not a struct", strings.Join(path, "."), n.typ, field.Name)
+		}
+		child = &scanChild{name: strings.ToLower(path[0]), index: field.Index, node: &scanNode{typ: t}}
+		ft := field.Type
+		if ft.Kind() == reflect.Slice {
+			child.slice = true
+			ft = ft.Elem()
+		}
+		child.ptr = ft.Kind() == reflect.Ptr
+		n.children = append(n.children, child)
+	}
+	return child.node.add(path[1:], column)
+}
+
+// hasSlices reports whether n or a nested struct collects a one-to-many join.
+func (n *scanNode) hasSlices() bool {
+	for _, c := range n.children {
+		if c.slice || c.node.hasSlices() {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the values of the columns identifying an element of n: its
+// own and those of nested structs outside slices.
+func (n *scanNode) key(values []interface{}) (key string, null bool) {
+	var b strings.Builder
+	null = true
+	var walk func(n *scanNode)
+	walk = func(n *scanNode) {
+		for _, f := range n.fields {
+			if values[f.column] != nil {
+				null = false
+			}
+			fmt.Fprintf(&b, "%#v\x00", values[f.column])
+		}
+		for _, c := range n.children {
+			if !c.slice {
+				walk(c.node)
+			}
+		}
+	}
+	walk(n)
+	return b.String(), null
+}
+
+// group splits rows into runs of equal keys, in order of first appearance.
+func (n *scanNode) group(rows [][]interface{}) [][][]interface{} {
+	var groups [][][]interface{}
+	positions := make(map[string]int)
+	for _, row := range rows {
+		key, _ := n.key(row)
+		i, ok := positions[key]
+		if !ok {
+			i = len(groups)
+			positions[key] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], row)
+	}
+	return groups
+}
+
+// build returns a pointer to a new struct of n's type filled from rows,
+// which share n's key, reading integer durations in unit.
+func (n *scanNode) build(rows [][]interface{}, unit time.Duration) (reflect.Value, error) {
+	v := reflect.New(n.typ)
+	s := v.Elem()
+	for _, f := range n.fields {
+		value := rows[0][f.column]
+		if err := assignValue(s.FieldByIndex(f.index), value, unit); err != nil {
+			name := n.typ.FieldByIndex(f.index).Name
+			return reflect.Value{}, fmt.Errorf("failed to scan column into %s.%s: %w", n.typ, name, err)
+		}
+	}
+	for _, c := range n.children {
+		field := s.FieldByIndex(c.index)
+		if !c.slice {
+			if _, null := c.node.key(rows[0]); null && c.ptr {
+				continue
+			}
+			elem, err := c.node.build(rows, unit)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if c.ptr {
+				field.Set(elem)
+			} else {
+				field.Set(elem.Elem())
+			}
+			continue
+		}
+		for _, group := range c.node.group(rows) {
+			if _, null := c.node.key(group[0]); null {
+				continue
+			}
+			elem, err := c.node.build(group, unit)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			if !c.ptr {
+				elem = elem.Elem()
+			}
+			field.Set(reflect.Append(field, elem))
+		}
+	}
+	return v, nil
+}
+
+// structFields returns the fields of struct type t that columns can map to,
+// keyed by lowercased name, with the fields of embedded structs promoted.
+func structFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			tag := field.Tag.Get("db")
+			if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+				continue
+			}
+			field.Index = append(append([]int(nil), index...), i)
+			if field.Anonymous && tag == "" && field.Type.Kind() == reflect.Struct {
+				walk(field.Type, field.Index)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			name := tag
+			if name == "" {
+				name = field.Name
+			}
+			if _, ok := fields[strings.ToLower(name)]; !ok {
+				fields[strings.ToLower(name)] = field
+			}
+		}
+	}
+	walk(t, nil)
+	return fields
+}
+
+// nestedType returns the struct type a field of type t holds columns of a
+// joined table in: a struct, a pointer to one or a slice of either.
+func nestedType(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || valueStruct(t) {
+		return nil, false
+	}
+	return t, true
+}
+
+// timeLayouts are the text formats of time values, as written by SQLite's
+// date and time functions and by Go.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// assignValue stores value, as returned by columnValue, in field. Numbers
+// stored in time.Duration fields count unit.
+func assignValue(field reflect.Value, value interface{}, unit time.Duration) error {
+	if field.Kind() == reflect.Ptr {
+		if value == nil {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		elem := reflect.New(field.Type().Elem())
+		if err := assignValue(elem.Elem(), value, unit); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+	if scanner, ok := field.Addr().Interface().(sql.Scanner); ok && !nullType(field.Type()) {
+		// The sql.Null* types are filled below, so that their fields
+		// convert like others; sql.NullTime cannot scan text itself.
+		return scanner.Scan(value)
+	}
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if nullType(field.Type()) {
+		// sql.Null* types hold their value in the first field.
+		if err := assignValue(field.Field(0), value, unit); err != nil {
+			return err
+		}
+		field.FieldByName("Valid").SetBool(true)
+		return nil
+	}
+
+	if field.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := parseDuration(value, unit)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	if field.Type() == reflect.TypeOf(time.Time{}) {
+		switch value.(type) {
+		case time.Time:
+			field.Set(reflect.ValueOf(value))
+			return nil
+		case int64, string:
+			t, err := ConvertTime(value)
+			if err != nil {
+				return err
+			}
+			field.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		switch v := value.(type) {
+		case string:
+			field.SetString(v)
+			return nil
+		case []byte:
+			field.SetString(string(v))
+			return nil
+		case time.Time:
+			field.SetString(v.Format(time.RFC3339Nano))
+			return nil
+		}
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			switch v := value.(type) {
+			case []byte:
+				field.SetBytes(v)
+				return nil
+			case string:
+				field.SetBytes([]byte(v))
+				return nil
+			}
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v, ok := value.(int64); ok {
+			if field.OverflowInt(v) {
+				return fmt.Errorf("%d overflows %s", v, field.Type())
+			}
+			field.SetInt(v)
+			return nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v, ok := value.(int64); ok {
+			if v < 0 || field.OverflowUint(uint64(v)) {
+				return fmt.Errorf("%d overflows %s", v, field.Type())
+			}
+			field.SetUint(uint64(v))
+			return nil
+		}
+	case reflect.Float32, reflect.Float64:
+		switch v := value.(type) {
+		case float64:
+			if field.Kind() == reflect.Float32 && math.Abs(v) > math.MaxFloat32 && !math.IsInf(v, 0) {
+				return fmt.Errorf("%g overflows %s", v, field.Type())
+			}
+			field.SetFloat(v)
+			return nil
+		case int64:
+			field.SetFloat(float64(v))
+			return nil
+		}
+	case reflect.Bool:
+		switch v := value.(type) {
+		case int64:
+			field.SetBool(v != 0)
+			return nil
+		case bool:
+			field.SetBool(v)
+			return nil
+		}
+	case reflect.Interface:
+		if field.NumMethod() == 0 {
+			field.Set(reflect.ValueOf(value))
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot store %T in %s", value, field.Type())
+}
@@ -0,0 +1,196 @@
+package exec_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type scanUser struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+type scanItem struct {
+	SKU string `db:"sku"`
+	Qty uint16 `db:"qty"`
+}
+
+type scanOrder struct {
+	ID       int64 `db:"id"`
+	Total    float64
+	Paid     bool       `db:"paid"`
+	PlacedAt time.Time  `db:"placed_at"`
+	Note     *string    `db:"note"`
+	User     *scanUser  `db:"user"`
+	Items    []scanItem `db:"items"`
+}
+
+func TestSelect(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER, total REAL, paid INTEGER, placed_at TEXT, note TEXT);
+		CREATE TABLE items (order_id INTEGER, sku TEXT, qty INTEGER);
+		INSERT INTO users VALUES (1, 'ada');
+		INSERT INTO orders VALUES (10, 1, 9.5, 1, '2024-05-01 12:30:00', 'gift'), (11, NULL, 3, 0, '2024-05-02T08:00:00Z', NULL);
+		INSERT INTO items VALUES (10, 'a', 1), (10, 'b', 2);
+	`, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	// A plain query fills one struct per row.
+	var users []scanUser
+	require.NoError(t, exec.Select(ctx, &users, "SELECT id, NAME FROM users;", nil))
+	assert.Equal(t, []scanUser{{ID: 1, Name: "ada"}}, users)
+
+	// Joined columns fill nested structs, and one-to-many rows fold.
+	var orders []*scanOrder
+	require.NoError(t, exec.Select(ctx, &orders, `
+		SELECT o.id, o.total, o.paid, o.placed_at, o.note,
+			u.id AS "user.id", u.name AS "user.name",
+			i.sku AS "items.sku", i.qty AS "items.qty"
+		FROM orders o
+		LEFT JOIN users u ON u.id = o.user_id
+		LEFT JOIN items i ON i.order_id = o.id
+		ORDER BY o.id, i.sku;`, nil))
+	require.Len(t, orders, 2)
+
+	gift := "gift"
+	assert.Equal(t, &scanOrder{
+		ID:       10,
+		Total:    9.5,
+		Paid:     true,
+		PlacedAt: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+		Note:     &gift,
+		User:     &scanUser{ID: 1, Name: "ada"},
+		Items:    []scanItem{{SKU: "a", Qty: 1}, {SKU: "b", Qty: 2}},
+	}, orders[0])
+	assert.Equal(t, &scanOrder{
+		ID:       11,
+		Total:    3,
+		PlacedAt: time.Date(2024, 5, 2, 8, 0, 0, 0, time.UTC),
+	}, orders[1])
+
+	var order scanOrder
+	require.NoError(t, exec.Get(ctx, &order, `SELECT o.id, i.sku AS "items.sku" FROM orders o JOIN items i ON i.order_id = o.id;`, nil))
+	assert.Equal(t, scanOrder{ID: 10, Items: []scanItem{{SKU: "a"}, {SKU: "b"}}}, order)
+
+	err := exec.Get(ctx, &order, "SELECT id FROM orders WHERE id = 99;", nil)
+	assert.ErrorIs(t, err, sqliteutils.ErrRowNotFound)
+	assert.Error(t, exec.Select(ctx, &users, "SELECT id, 1 AS unknown FROM users;", nil))
+	assert.Error(t, exec.Select(ctx, &users, "SELECT name AS id FROM users;", nil))
+	assert.Error(t, exec.Select(ctx, users, "SELECT id FROM users;", nil))
+}
+
+type nullable struct {
+	Name    *string         `db:"name"`
+	Age     *int64          `db:"age"`
+	Email   sql.NullString  `db:"email"`
+	Score   sql.NullFloat64 `db:"score"`
+	Active  sql.NullBool    `db:"active"`
+	Flags   sql.NullByte    `db:"flags"`
+	Created sql.NullTime    `db:"created"`
+}
+
+func TestSelect_Nullable(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE people (name TEXT, age INTEGER, email TEXT, score REAL, active INTEGER, flags INTEGER, created TEXT);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	name, age := "ada", int64(36)
+	created := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	for _, p := range []nullable{
+		{
+			Name:    &name,
+			Age:     &age,
+			Email:   sql.NullString{String: "ada@example.com", Valid: true},
+			Score:   sql.NullFloat64{Float64: 1.5, Valid: true},
+			Active:  sql.NullBool{Bool: true, Valid: true},
+			Flags:   sql.NullByte{Byte: 7, Valid: true},
+			Created: sql.NullTime{Time: created, Valid: true},
+		},
+		{},
+	} {
+		require.NoError(t, exec.NamedExec(ctx,
+			"INSERT INTO people VALUES (:name, :age, :email, :score, :active, :flags, :created);", p))
+	}
+
+	var people []nullable
+	require.NoError(t, exec.Select(ctx, &people, "SELECT * FROM people ORDER BY rowid;", nil))
+	require.Len(t, people, 2)
+	assert.Equal(t, "ada", *people[0].Name)
+	assert.Equal(t, int64(36), *people[0].Age)
+	assert.Equal(t, sql.NullString{String: "ada@example.com", Valid: true}, people[0].Email)
+	assert.Equal(t, sql.NullFloat64{Float64: 1.5, Valid: true}, people[0].Score)
+	assert.Equal(t, sql.NullBool{Bool: true, Valid: true}, people[0].Active)
+	assert.Equal(t, sql.NullByte{Byte: 7, Valid: true}, people[0].Flags)
+	assert.True(t, people[0].Created.Valid)
+	assert.True(t, created.Equal(people[0].Created.Time))
+	assert.Equal(t, nullable{}, people[1])
+}
+
+// point is stored as "x,y" text through driver.Valuer and sql.Scanner.
+type point struct {
+	X, Y int
+}
+
+func (p point) Value() (driver.Value, error) {
+	return fmt.Sprintf("%d,%d", p.X, p.Y), nil
+}
+
+func (p *point) Scan(src interface{}) error {
+	s, ok := src.(string)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into point", src)
+	}
+	_, err := fmt.Sscanf(s, "%d,%d", &p.X, &p.Y)
+	return err
+}
+
+type place struct {
+	Name string `db:"name"`
+	At   point  `db:"at"`
+	Near *point `db:"near"`
+}
+
+func TestSelect_ValuerScanner(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE places (name TEXT, at TEXT, near TEXT);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	require.NoError(t, exec.NamedExec(ctx, "INSERT INTO places VALUES (:name, :at, :near);",
+		place{Name: "home", At: point{1, 2}, Near: &point{3, 4}}))
+	require.NoError(t, exec.E(ctx, "INSERT INTO places VALUES (?, ?, ?);", "work", point{5, 6}, (*point)(nil)))
+
+	var stored []interface{}
+	require.NoError(t, exec.Query(ctx, "SELECT at FROM places ORDER BY rowid;", nil, func(_ []string, values []interface{}) {
+		stored = append(stored, values[0])
+	}))
+	assert.Equal(t, []interface{}{"1,2", "5,6"}, stored)
+
+	var places []place
+	require.NoError(t, exec.Select(ctx, &places, "SELECT * FROM places ORDER BY rowid;", nil))
+	assert.Equal(t, []place{
+		{Name: "home", At: point{1, 2}, Near: &point{3, 4}},
+		{Name: "work", At: point{5, 6}},
+	}, places)
+
+	var p place
+	assert.Error(t, exec.Get(ctx, &p, "SELECT 'x' AS name, 7 AS at;", nil))
+}
@@ -0,0 +1,27 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+)
+
+// Querier runs read queries. Tx implements it.
+type Querier interface {
+	Query(query string, params map[string]interface{}, rowFunc func(columns []string, values []interface{})) error
+}
+
+// WithSnapshot runs fn against a snapshot of the database taken when it is
+// called: every query fn runs sees the same committed state, even while
+// other connections commit. In WAL mode writers are not blocked while fn
+// runs, but the WAL cannot be checkpointed past the snapshot, so keep fn
+// short. Like ReadOnly, fn cannot change the database.
+func WithSnapshot(ctx context.Context, fn func(q Querier) error) error {
+	return ReadOnly(ctx, func(tx *Tx) error {
+		// A deferred transaction only starts reading at its first
+		// statement, so read now to pin the snapshot.
+		if err := executeRawStatement(tx.conn, "SELECT count(*) FROM sqlite_schema;"); err != nil {
+			return fmt.Errorf("failed to start snapshot: %w", err)
+		}
+		return fn(tx)
+	})
+}
@@ -0,0 +1,42 @@
+package exec_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithSnapshot(t *testing.T) {
+	ctx := context.Background()
+	// Snapshots need WAL mode, which in-memory databases do not have.
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "snapshot.db"), 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.E(ctx, "CREATE TABLE kv (k TEXT PRIMARY KEY, v INTEGER);"))
+	require.NoError(t, exec.E(ctx, "INSERT INTO kv VALUES ('a', 1);"))
+
+	count := func(q exec.Querier) (n int64) {
+		require.NoError(t, q.Query("SELECT count(*) FROM kv;", nil, func(_ []string, values []interface{}) { n = values[0].(int64) }))
+		return n
+	}
+	require.NoError(t, exec.WithSnapshot(ctx, func(q exec.Querier) error {
+		// Commits after the snapshot was taken, before the first query,
+		// are not seen.
+		require.NoError(t, exec.Exec(ctx, "INSERT INTO kv VALUES ('b', 2);", nil, nil))
+		assert.Equal(t, int64(1), count(q))
+		require.NoError(t, exec.Exec(ctx, "INSERT INTO kv VALUES ('c', 3);", nil, nil))
+		assert.Equal(t, int64(1), count(q))
+		return nil
+	}))
+
+	require.NoError(t, exec.WithSnapshot(ctx, func(q exec.Querier) error {
+		assert.Equal(t, int64(3), count(q))
+		return nil
+	}))
+}
@@ -0,0 +1,128 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+)
+
+// DefaultSoftDeleteColumn is the column RegisterSoftDelete uses when none is given.
+const DefaultSoftDeleteColumn = "deleted_at"
+
+var (
+	softDeleteMu      sync.RWMutex
+	softDeleteColumns = make(map[string]string)
+)
+
+// RegisterSoftDelete makes table soft-deletable through column, an INTEGER
+// column that is NULL for live rows and holds the Unix time in milliseconds
+// at which the others were deleted. An empty column means
+// DefaultSoftDeleteColumn.
+func RegisterSoftDelete(table, column string) {
+	if column == "" {
+		column = DefaultSoftDeleteColumn
+	}
+	softDeleteMu.Lock()
+	defer softDeleteMu.Unlock()
+	softDeleteColumns[table] = column
+}
+
+// softDeleteColumn returns the soft delete column registered for table.
+func softDeleteColumn(table string) (string, error) {
+	softDeleteMu.RLock()
+	defer softDeleteMu.RUnlock()
+	column, ok := softDeleteColumns[table]
+	if !ok {
+		return "", fmt.Errorf("table %s is not registered for soft deletes", table)
+	}
+	return column, nil
+}
+
+// SoftDelete marks the live rows of table matching where as deleted and
+// returns how many it marked. where is a SQL expression using params, as in
+// a WHERE clause; empty matches every row.
+func SoftDelete(ctx context.Context, table, where string, params map[string]interface{}) (int64, error) {
+	column, err := softDeleteColumn(table)
+	if err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s = :_deleted_at WHERE %s;",
+		sqliteutils.QuoteIdentifier(table), sqliteutils.QuoteIdentifier(column), livePredicate(column, where))
+	merged := make(map[string]interface{}, len(params)+1)
+	for k, v := range params {
+		merged[k] = v
+	}
+	merged[":_deleted_at"] = time.Now().UnixMilli()
+	return changesOf(ctx, query, merged)
+}
+
+// FindOptions configures Find.
+type FindOptions struct {
+	// IncludeDeleted also returns soft-deleted rows.
+	IncludeDeleted bool
+}
+
+// Find calls resultFunc with every live row of table matching where, a SQL
+// expression using params; empty matches every row. Soft-deleted rows are
+// skipped unless opts.IncludeDeleted is set. opts may be nil.
+func Find(ctx context.Context, table, where string, params map[string]interface{}, opts *FindOptions, resultFunc func(row map[string]interface{})) error {
+	column, err := softDeleteColumn(table)
+	if err != nil {
+		return err
+	}
+	if opts == nil || !opts.IncludeDeleted {
+		where = livePredicate(column, where)
+	} else if strings.TrimSpace(where) == "" {
+		where = "1"
+	}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s;", sqliteutils.QuoteIdentifier(table), where)
+	return Exec(ctx, query, params, func(_ int, row map[string]interface{}) {
+		if resultFunc != nil {
+			resultFunc(row)
+		}
+	})
+}
+
+// Purge permanently deletes the rows of table that were soft-deleted more
+// than retention ago and returns how many it deleted.
+func Purge(ctx context.Context, table string, retention time.Duration) (int64, error) {
+	column, err := softDeleteColumn(table)
+	if err != nil {
+		return 0, err
+	}
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s < :cutoff;",
+		sqliteutils.QuoteIdentifier(table), sqliteutils.QuoteIdentifier(column))
+	return changesOf(ctx, query, map[string]interface{}{":cutoff": time.Now().Add(-retention).UnixMilli()})
+}
+
+// livePredicate restricts where to rows whose soft delete column is NULL.
+func livePredicate(column, where string) string {
+	live := sqliteutils.QuoteIdentifier(column) + " IS NULL"
+	if strings.TrimSpace(where) == "" {
+		return live
+	}
+	return "(" + where + ") AND " + live
+}
+
+// changesOf executes query and returns the number of rows it changed.
+func changesOf(ctx context.Context, query string, params map[string]interface{}) (int64, error) {
+	p, err := pool.GetPool()
+	if err != nil {
+		return 0, sqliteutils.FailedToGetPoolError(err)
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return 0, sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+
+	if err := executeSingleStatement(conn, query, params, 0, nil); err != nil {
+		return 0, err
+	}
+	return int64(conn.Changes()), nil
+}
@@ -0,0 +1,59 @@
+package exec_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	const migration = `
+		CREATE TABLE notes (
+			id INTEGER PRIMARY KEY,
+			body TEXT,
+			deleted_at INTEGER
+		);
+		INSERT INTO notes (id, body) VALUES (1, 'a'), (2, 'b'), (3, 'c');
+	`
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	_, err := exec.SoftDelete(ctx, "notes", "", nil)
+	assert.Error(t, err, "unregistered tables are rejected")
+	exec.RegisterSoftDelete("notes", "")
+
+	n, err := exec.SoftDelete(ctx, "notes", "id <= :id", map[string]interface{}{":id": 2})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	// Rows already deleted are not marked again.
+	n, err = exec.SoftDelete(ctx, "notes", "id = 1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), n)
+
+	ids := func(opts *exec.FindOptions) []int64 {
+		var out []int64
+		require.NoError(t, exec.Find(ctx, "notes", "", nil, opts, func(row map[string]interface{}) {
+			out = append(out, row["id"].(int64))
+		}))
+		return out
+	}
+	assert.Equal(t, []int64{3}, ids(nil))
+	assert.Equal(t, []int64{1, 2, 3}, ids(&exec.FindOptions{IncludeDeleted: true}))
+
+	// Only rows deleted before the retention window are purged.
+	require.NoError(t, exec.Exec(ctx, "UPDATE notes SET deleted_at = deleted_at - 3600000 WHERE id = 1;", nil, nil))
+	n, err = exec.Purge(ctx, "notes", 30*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	assert.Equal(t, []int64{2, 3}, ids(&exec.FindOptions{IncludeDeleted: true}))
+}
@@ -0,0 +1,76 @@
+package exec
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+)
+
+// Stats counts the statements run by this package and the blob bytes it
+// streamed.
+type Stats struct {
+	Queries int64 `json:"queries"`
+	Errors  int64 `json:"errors"`
+	// BlobBytesRead and BlobBytesWritten count the bytes streamed out of and
+	// into blobs, and the BytesPerSec fields the throughput of that streaming
+	// while it ran.
+	BlobBytesRead        int64   `json:"blob_bytes_read"`
+	BlobBytesWritten     int64   `json:"blob_bytes_written"`
+	BlobReadBytesPerSec  float64 `json:"blob_read_bytes_per_sec"`
+	BlobWriteBytesPerSec float64 `json:"blob_write_bytes_per_sec"`
+}
+
+var (
+	queryCount atomic.Int64
+	errorCount atomic.Int64
+
+	blobBytesRead    atomic.Int64
+	blobReadNanos    atomic.Int64
+	blobBytesWritten atomic.Int64
+	blobWriteNanos   atomic.Int64
+)
+
+func init() {
+	sqliteutils.RegisterStats("exec", func() interface{} { return GetStats() })
+}
+
+// GetStats returns the number of statements run since the process started
+// and how many of them failed.
+func GetStats() Stats {
+	read, written := blobBytesRead.Load(), blobBytesWritten.Load()
+	return Stats{
+		Queries:              queryCount.Load(),
+		Errors:               errorCount.Load(),
+		BlobBytesRead:        read,
+		BlobBytesWritten:     written,
+		BlobReadBytesPerSec:  throughput(read, blobReadNanos.Load()),
+		BlobWriteBytesPerSec: throughput(written, blobWriteNanos.Load()),
+	}
+}
+
+// countQuery counts a statement that finished with *err. Use it deferred.
+func countQuery(err *error) {
+	queryCount.Add(1)
+	if *err != nil {
+		errorCount.Add(1)
+	}
+}
+
+func recordBlobRead(n int64, d time.Duration) {
+	blobBytesRead.Add(n)
+	blobReadNanos.Add(int64(d))
+}
+
+func recordBlobWrite(n int64, d time.Duration) {
+	blobBytesWritten.Add(n)
+	blobWriteNanos.Add(int64(d))
+}
+
+// throughput returns bytes per second given the time taken in nanoseconds.
+func throughput(bytes, nanos int64) float64 {
+	if nanos <= 0 {
+		return 0
+	}
+	return float64(bytes) / (float64(nanos) / float64(time.Second))
+}
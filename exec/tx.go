@@ -0,0 +1,189 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+)
+
+// ErrTxDone is returned by the methods of a Tx that has been committed or
+// rolled back.
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+// Tx is a transaction holding a connection from the global pool. A Tx is not
+// safe for concurrent use.
+//
+// A Tx begun on a connection that is already inside a transaction is a
+// savepoint of it: Commit releases the savepoint and Rollback undoes only
+// the changes made since, leaving the outer transaction open.
+type Tx struct {
+	conn      *sqlite.Conn
+	put       func()
+	done      bool
+	savepoint string
+}
+
+// savepoints numbers the savepoints of nested transactions.
+var savepoints atomic.Uint64
+
+// Begin takes a connection from the global pool and begins a transaction in
+// mode on it. Commit or Rollback must be called to return the connection;
+// deferring Rollback is safe after Commit.
+func Begin(ctx context.Context, mode TxMode) (*Tx, error) {
+	if _, err := ParseTxMode(string(mode)); err != nil {
+		return nil, err
+	}
+	p, err := pool.GetPool()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create database pool: %w", err)
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain database connection: %w", err)
+	}
+	tx, err := beginConn(conn, mode, func() { p.Put(conn) })
+	if err != nil {
+		p.Put(conn)
+		return nil, err
+	}
+	return tx, nil
+}
+
+// beginConn begins a transaction on conn, or a savepoint if conn is already
+// inside one, calling put once it finishes.
+func beginConn(conn *sqlite.Conn, mode TxMode, put func()) (*Tx, error) {
+	if !conn.AutocommitEnabled() {
+		name := fmt.Sprintf("exec_tx_%d", savepoints.Add(1))
+		if err := executeRawStatement(conn, "SAVEPOINT "+name+";"); err != nil {
+			return nil, fmt.Errorf("failed to begin savepoint: %w", err)
+		}
+		return &Tx{conn: conn, put: put, savepoint: name}, nil
+	}
+	if err := executeRawStatement(conn, "BEGIN "+string(mode)+" TRANSACTION;"); err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	return &Tx{conn: conn, put: put}, nil
+}
+
+// Begin begins a nested transaction on the transaction's connection, as a
+// savepoint of tx.
+func (tx *Tx) Begin() (*Tx, error) {
+	if tx.done {
+		return nil, ErrTxDone
+	}
+	return beginConn(tx.conn, TxDeferred, func() {})
+}
+
+// WithTx runs fn in a nested transaction of tx, releasing it if fn returns
+// nil and rolling it back otherwise.
+func (tx *Tx) WithTx(fn func(tx *Tx) error) error {
+	nested, err := tx.Begin()
+	if err != nil {
+		return err
+	}
+	return runTx(nested, fn)
+}
+
+// WithTx runs fn in a transaction begun in mode, committing it if fn returns
+// nil and rolling it back otherwise.
+func WithTx(ctx context.Context, mode TxMode, fn func(tx *Tx) error) error {
+	tx, err := Begin(ctx, mode)
+	if err != nil {
+		return err
+	}
+	return runTx(tx, fn)
+}
+
+// runTx runs fn in tx, committing it if fn returns nil.
+func runTx(tx *Tx, fn func(tx *Tx) error) error {
+	defer tx.Rollback()
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Conn returns the transaction's connection, for use with the functions of
+// this module that take one.
+func (tx *Tx) Conn() *sqlite.Conn {
+	return tx.conn
+}
+
+// Exec is Exec inside the transaction.
+func (tx *Tx) Exec(query string, params map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	return tx.ExecMulti([]string{query}, []map[string]interface{}{params}, resultFunc)
+}
+
+// ExecMulti is ExecMulti inside the transaction.
+func (tx *Tx) ExecMulti(queries []string, params []map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	if len(queries) != len(params) {
+		return fmt.Errorf("the number of queries (%d) does not match the number of params (%d)", len(queries), len(params))
+	}
+	return executeStatements(tx.conn, queries, params, resultFunc)
+}
+
+// Query is Query inside the transaction.
+func (tx *Tx) Query(query string, params map[string]interface{}, rowFunc func(columns []string, values []interface{})) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	return QueryConn(tx.conn, query, params, rowFunc)
+}
+
+// Commit commits the transaction and returns the connection to the pool.
+// If the commit fails, the transaction is rolled back.
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	if tx.savepoint != "" {
+		if err := executeRawStatement(tx.conn, "RELEASE SAVEPOINT "+tx.savepoint+";"); err != nil {
+			return fmt.Errorf("failed to release savepoint: %w", errors.Join(err, tx.Rollback()))
+		}
+		tx.finish()
+		return nil
+	}
+	if err := executeRawStatement(tx.conn, "COMMIT;"); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", errors.Join(err, tx.Rollback()))
+	}
+	tx.finish()
+	return nil
+}
+
+// Rollback rolls the transaction back and returns the connection to the
+// pool. It does nothing if the transaction has already finished.
+func (tx *Tx) Rollback() error {
+	if tx.done {
+		return nil
+	}
+	defer tx.finish()
+	if tx.conn.AutocommitEnabled() {
+		// SQLite already rolled back, as it does for some errors.
+		return nil
+	}
+	if tx.savepoint != "" {
+		if err := executeRawStatement(tx.conn, "ROLLBACK TO SAVEPOINT "+tx.savepoint+";"); err != nil {
+			return fmt.Errorf("failed to rollback savepoint: %w", err)
+		}
+		if err := executeRawStatement(tx.conn, "RELEASE SAVEPOINT "+tx.savepoint+";"); err != nil {
+			return fmt.Errorf("failed to release savepoint: %w", err)
+		}
+		return nil
+	}
+	if err := executeRawStatement(tx.conn, "ROLLBACK;"); err != nil {
+		return fmt.Errorf("failed to rollback transaction: %w", err)
+	}
+	return nil
+}
+
+func (tx *Tx) finish() {
+	tx.done = true
+	tx.put()
+}
@@ -0,0 +1,87 @@
+package exec_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTx(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE kv (k TEXT PRIMARY KEY, v INTEGER);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	tx, err := exec.Begin(ctx, exec.TxImmediate)
+	require.NoError(t, err)
+	require.NoError(t, tx.ExecMulti([]string{
+		"INSERT INTO kv VALUES ('a', 1);",
+		"INSERT INTO kv VALUES ('b', 2);",
+	}, make([]map[string]interface{}, 2), nil))
+	require.NoError(t, tx.Commit())
+	assert.ErrorIs(t, tx.Commit(), exec.ErrTxDone)
+	assert.ErrorIs(t, tx.Exec("SELECT 1;", nil, nil), exec.ErrTxDone)
+	assert.NoError(t, tx.Rollback())
+
+	// A failing fn rolls back and releases the only connection.
+	errBoom := errors.New("boom")
+	err = exec.WithTx(ctx, exec.TxDeferred, func(tx *exec.Tx) error {
+		require.NoError(t, tx.Exec("UPDATE kv SET v = v * 10;", nil, nil))
+		return errBoom
+	})
+	assert.ErrorIs(t, err, errBoom)
+
+	var sum int64
+	require.NoError(t, exec.Query(ctx, "SELECT sum(v) FROM kv;", nil, func(_ []string, values []interface{}) {
+		sum = values[0].(int64)
+	}))
+	assert.Equal(t, int64(3), sum)
+
+	_, err = exec.Begin(ctx, "SOMETIMES")
+	assert.Error(t, err)
+}
+
+func TestTx_Nested(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE kv (k TEXT PRIMARY KEY, v INTEGER);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	errBoom := errors.New("boom")
+	err := exec.WithTx(ctx, exec.TxImmediate, func(tx *exec.Tx) error {
+		require.NoError(t, tx.Exec("INSERT INTO kv VALUES ('a', 1);", nil, nil))
+
+		// A failing nested transaction only undoes its own changes.
+		err := tx.WithTx(func(nested *exec.Tx) error {
+			require.NoError(t, nested.Exec("INSERT INTO kv VALUES ('b', 2);", nil, nil))
+			return errBoom
+		})
+		assert.ErrorIs(t, err, errBoom)
+
+		// ExecMultiTxConn on a connection inside a transaction uses a savepoint
+		// instead of failing to BEGIN.
+		require.NoError(t, exec.ExecMultiTxConn(tx.Conn(), exec.TxDeferred, []string{
+			"INSERT INTO kv VALUES ('c', 3);",
+		}, make([]map[string]interface{}, 1), nil))
+		assert.Error(t, exec.ExecMultiTxConn(tx.Conn(), exec.TxDeferred, []string{
+			"INSERT INTO kv VALUES ('d', 4);",
+			"INSERT INTO kv VALUES ('a', 5);",
+		}, make([]map[string]interface{}, 2), nil))
+		return nil
+	})
+	require.NoError(t, err)
+
+	var keys []interface{}
+	require.NoError(t, exec.Query(ctx, "SELECT k FROM kv ORDER BY k;", nil, func(_ []string, values []interface{}) {
+		keys = append(keys, values[0])
+	}))
+	assert.Equal(t, []interface{}{"a", "c"}, keys)
+}
@@ -0,0 +1,87 @@
+package exec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+)
+
+// ValidationError is returned by Validate when statements are invalid.
+type ValidationError struct {
+	// Statements holds one error per invalid statement, in order.
+	Statements []*StatementError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Statements))
+	for i, s := range e.Statements {
+		messages[i] = s.Error()
+	}
+	return fmt.Sprintf("%d invalid statement(s): %s", len(e.Statements), strings.Join(messages, "; "))
+}
+
+func (e *ValidationError) Unwrap() []error {
+	errs := make([]error, len(e.Statements))
+	for i, s := range e.Statements {
+		errs[i] = s
+	}
+	return errs
+}
+
+// Validate prepares each of queries on a connection from the global pool
+// without executing it, which checks its syntax and that the tables,
+// columns and functions it uses exist. It also rejects queries holding more
+// than one statement and, since it checks queries for Exec, Query and the
+// other functions binding parameters by name, anonymous ? parameters; only E
+// and Q bind those, by position. Each query is checked against the current
+// schema on its own, so a query that uses a table created by an earlier one
+// is reported as invalid.
+//
+// If any query is invalid, Validate returns a *ValidationError.
+func Validate(ctx context.Context, queries []string) error {
+	p, err := pool.GetPool()
+	if err != nil {
+		return fmt.Errorf("failed to create database pool: %w", err)
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return fmt.Errorf("failed to obtain database connection: %w", err)
+	}
+	defer p.Put(conn)
+
+	var invalid []*StatementError
+	for i, query := range queries {
+		if err := validateStatement(conn, query); err != nil {
+			invalid = append(invalid, &StatementError{Index: i, Err: err})
+		}
+	}
+	if len(invalid) > 0 {
+		return &ValidationError{Statements: invalid}
+	}
+	return nil
+}
+
+func validateStatement(conn *sqlite.Conn, query string) error {
+	trimmedQuery := trimQuery(query)
+	if trimmedQuery == "" {
+		return nil
+	}
+	stmt, trailingBytes, err := conn.PrepareTransient(trimmedQuery)
+	if err != nil {
+		return fmt.Errorf("SQL preparation error for query '%s': %w", trimmedQuery, err)
+	}
+	defer stmt.Finalize()
+	if strings.TrimSpace(strings.TrimLeft(trimmedQuery[len(trimmedQuery)-trailingBytes:], "; \t\r\n")) != "" {
+		return errors.New("query holds more than one statement")
+	}
+	for i := 1; i <= stmt.BindParamCount(); i++ {
+		if stmt.BindParamName(i) == "" {
+			return fmt.Errorf("parameter %d is an anonymous ?: use a named parameter such as :name", i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package exec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE orders (id INTEGER PRIMARY KEY, region TEXT, total REAL);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	require.NoError(t, exec.Validate(ctx, []string{
+		"SELECT region, sum(total) FROM orders WHERE id > :min GROUP BY region;",
+		"INSERT INTO orders (region, total) VALUES ($region, @total)",
+		"",
+	}))
+
+	err := exec.Validate(ctx, []string{
+		"SELECT * FROM orders;",
+		"SELEC * FROM orders;",
+		"SELECT missing FROM orders;",
+		"SELECT * FROM nowhere;",
+		"SELECT * FROM orders WHERE id = ?;",
+		"SELECT 1; SELECT 2;",
+	})
+	var validationErr *exec.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	var indexes []int
+	for _, s := range validationErr.Statements {
+		indexes = append(indexes, s.Index)
+	}
+	assert.Equal(t, []int{1, 2, 3, 4, 5}, indexes)
+	assert.Contains(t, validationErr.Statements[1].Error(), "no such column: missing")
+
+	// Nothing was executed.
+	var count int64
+	require.NoError(t, exec.Query(ctx, "SELECT count(*) FROM orders;", nil, func(_ []string, values []interface{}) {
+		count = values[0].(int64)
+	}))
+	assert.Zero(t, count)
+}
@@ -0,0 +1,48 @@
+package exec
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+)
+
+// VersionColumn is the column UpdateVersioned checks and increments.
+const VersionColumn = "version"
+
+// UpdateVersioned sets the columns in setCols on the row of table whose
+// idCol equals id, provided its version column still equals version, and
+// increments the version. It returns sqliteutils.ErrStaleVersion when no
+// row matches, because the row was changed or deleted since version was
+// read.
+func UpdateVersioned(ctx context.Context, table, idCol string, id interface{}, version int64, setCols map[string]interface{}) error {
+	columns := make([]string, 0, len(setCols))
+	for column := range setCols {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	q := sqliteutils.QuoteIdentifier
+	v := q(VersionColumn)
+	params := map[string]interface{}{":id": id, ":version": version}
+	sets := make([]string, 0, len(columns)+1)
+	for i, column := range columns {
+		name := fmt.Sprintf(":set%d", i)
+		sets = append(sets, q(column)+" = "+name)
+		params[name] = setCols[column]
+	}
+	sets = append(sets, v+" = "+v+" + 1")
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = :id AND %s = :version;",
+		q(table), strings.Join(sets, ", "), q(idCol), v)
+	n, err := changesOf(ctx, query, params)
+	if err != nil {
+		return fmt.Errorf("failed to update %s: %w", table, err)
+	}
+	if n == 0 {
+		return sqliteutils.ErrStaleVersion
+	}
+	return nil
+}
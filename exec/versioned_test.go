@@ -0,0 +1,42 @@
+package exec_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateVersioned(t *testing.T) {
+	ctx := context.Background()
+	const migration = `
+		CREATE TABLE docs (
+			id TEXT PRIMARY KEY,
+			title TEXT,
+			body TEXT,
+			version INTEGER NOT NULL DEFAULT 1
+		);
+		INSERT INTO docs (id, title) VALUES ('a', 'draft');
+	`
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	set := map[string]interface{}{"title": "final", "body": nil}
+	require.NoError(t, exec.UpdateVersioned(ctx, "docs", "id", "a", 1, set))
+
+	// A writer still holding version 1 loses.
+	assert.ErrorIs(t, exec.UpdateVersioned(ctx, "docs", "id", "a", 1, set), sqliteutils.ErrStaleVersion)
+	assert.ErrorIs(t, exec.UpdateVersioned(ctx, "docs", "id", "missing", 1, set), sqliteutils.ErrStaleVersion)
+
+	var row map[string]interface{}
+	require.NoError(t, exec.Exec(ctx, "SELECT title, body, version FROM docs WHERE id = 'a';", nil,
+		func(_ int, r map[string]interface{}) { row = r }))
+	assert.Equal(t, map[string]interface{}{"title": "final", "body": nil, "version": int64(2)}, row)
+}
@@ -0,0 +1,154 @@
+// Package export streams the results of queries on the global pool into
+// file formats, writing each row as it is read so that memory use does not
+// grow with the size of the result set.
+package export
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+)
+
+// BlobEncoding is how blob values are written as text.
+type BlobEncoding int
+
+const (
+	// BlobHexLiteral writes blobs as SQL hex literals such as x'01ff'.
+	BlobHexLiteral BlobEncoding = iota
+	// BlobHex writes blobs as bare hex digits such as 01ff.
+	BlobHex
+	// BlobBase64 writes blobs in standard base64.
+	BlobBase64
+)
+
+// ParseBlobEncoding parses "hexliteral", "hex" or "base64".
+func ParseBlobEncoding(s string) (BlobEncoding, error) {
+	switch s {
+	case "hexliteral":
+		return BlobHexLiteral, nil
+	case "hex":
+		return BlobHex, nil
+	case "base64":
+		return BlobBase64, nil
+	}
+	return 0, fmt.Errorf("invalid blob encoding %q: must be hexliteral, hex or base64", s)
+}
+
+func (e BlobEncoding) encode(b []byte) string {
+	switch e {
+	case BlobHex:
+		return hex.EncodeToString(b)
+	case BlobBase64:
+		return base64.StdEncoding.EncodeToString(b)
+	default:
+		return "x'" + hex.EncodeToString(b) + "'"
+	}
+}
+
+// CSVOptions configures CSV.
+type CSVOptions struct {
+	// Delimiter separates fields. Defaults to a comma.
+	Delimiter rune
+	// NoHeader leaves out the header row of column names.
+	NoHeader bool
+	// Null is written for NULL values. Defaults to an empty field.
+	Null string
+	// Blob is how blobs are encoded. Defaults to BlobHexLiteral.
+	Blob BlobEncoding
+}
+
+// CSV runs query with params and writes its rows to w as CSV, preceded by a
+// header row of column names unless opts.NoHeader is set. Integers, floats
+// and text are written as is. opts may be nil.
+//
+// CSV returns the number of rows written. The header is taken from the
+// prepared statement, so a query without rows still writes it.
+func CSV(ctx context.Context, query string, params map[string]interface{}, w io.Writer, opts *CSVOptions) (int64, error) {
+	if opts == nil {
+		opts = &CSVOptions{}
+	}
+	cw := csv.NewWriter(w)
+	if opts.Delimiter != 0 {
+		cw.Comma = opts.Delimiter
+	}
+
+	var rows int64
+	var writeErr error
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		if !opts.NoHeader {
+			columns, err := columnNames(conn, query)
+			if err != nil {
+				return err
+			}
+			if err := cw.Write(columns); err != nil {
+				return err
+			}
+		}
+		var record []string
+		return exec.QueryConn(conn, query, params, func(columns []string, values []interface{}) {
+			if writeErr != nil {
+				return
+			}
+			if record == nil {
+				record = make([]string, len(columns))
+			}
+			for i, v := range values {
+				record[i] = formatCSV(v, opts)
+			}
+			if writeErr = cw.Write(record); writeErr == nil {
+				rows++
+			}
+		})
+	})
+	if err == nil {
+		err = writeErr
+	}
+	cw.Flush()
+	if err == nil {
+		err = cw.Error()
+	}
+	return rows, err
+}
+
+// columnNames prepares query on conn, without running it, and returns the
+// names of its result columns.
+func columnNames(conn *sqlite.Conn, query string) ([]string, error) {
+	stmt, _, err := conn.PrepareTransient(query)
+	if err != nil {
+		return nil, fmt.Errorf("SQL preparation error for query '%s': %w", query, err)
+	}
+	if stmt == nil {
+		return nil, nil
+	}
+	defer stmt.Finalize()
+	columns := make([]string, stmt.ColumnCount())
+	for i := range columns {
+		columns[i] = stmt.ColumnName(i)
+	}
+	return columns, nil
+}
+
+func formatCSV(v interface{}, opts *CSVOptions) string {
+	switch v := v.(type) {
+	case nil:
+		return opts.Null
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return v
+	case []byte:
+		return opts.Blob.encode(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
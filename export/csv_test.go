@@ -0,0 +1,58 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/export"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const migration = `
+	CREATE TABLE files (id INTEGER PRIMARY KEY, name TEXT, size REAL, data BLOB);
+	INSERT INTO files VALUES (1, 'a, b', 1.5, x'01ff'), (2, NULL, 2, NULL);
+`
+
+func TestCSV(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	var buf bytes.Buffer
+	rows, err := export.CSV(ctx, "SELECT * FROM files WHERE id >= :min ORDER BY id;",
+		map[string]interface{}{":min": 1}, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), rows)
+	assert.Equal(t, "id,name,size,data\n1,\"a, b\",1.5,x'01ff'\n2,,2,\n", buf.String())
+
+	buf.Reset()
+	_, err = export.CSV(ctx, "SELECT * FROM files ORDER BY id;", nil, &buf, &export.CSVOptions{
+		Delimiter: '\t',
+		NoHeader:  true,
+		Null:      `\N`,
+		Blob:      export.BlobBase64,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "1\ta, b\t1.5\tAf8=\n2\t\\N\t2\t\\N\n", buf.String())
+
+	buf.Reset()
+	rows, err = export.CSV(ctx, "SELECT * FROM files WHERE id > 2;", nil, &buf, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), rows)
+	assert.Equal(t, "id,name,size,data\n", buf.String())
+
+	_, err = export.CSV(ctx, "SELECT * FROM missing;", nil, &buf, nil)
+	assert.Error(t, err)
+
+	encoding, err := export.ParseBlobEncoding("hex")
+	require.NoError(t, err)
+	assert.Equal(t, export.BlobHex, encoding)
+	_, err = export.ParseBlobEncoding("raw")
+	assert.Error(t, err)
+}
@@ -0,0 +1,207 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+)
+
+// DumpOptions configures Dump.
+type DumpOptions struct {
+	// Table limits the dump to one table and its indexes and triggers.
+	Table string
+	// SchemaOnly writes only the CREATE statements, without rows or the
+	// transaction around them. The shadow tables of virtual tables are left
+	// out, since creating the virtual table creates them.
+	SchemaOnly bool
+}
+
+// dumpObject is a row of sqlite_schema.
+type dumpObject struct {
+	typ, name, sql string
+}
+
+// Dump writes the database of the global pool to w as a SQL script that
+// rebuilds it, like the .dump command of the sqlite3 shell: CREATE TABLE
+// statements each followed by an INSERT per row, then indexes, triggers and
+// views, all in one transaction with foreign keys off. AUTOINCREMENT
+// counters are restored, and virtual tables are written into sqlite_schema
+// directly so that their shadow tables, dumped as ordinary tables, are not
+// created twice. opts may be nil.
+//
+// Dump reads everything on one connection inside a read transaction, so the
+// script is a consistent snapshot even while other connections write.
+func Dump(ctx context.Context, w io.Writer, opts *DumpOptions) error {
+	if opts == nil {
+		opts = &DumpOptions{}
+	}
+	tx, err := exec.Begin(ctx, exec.TxDeferred)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	bw := bufio.NewWriter(w)
+	d := &dumper{tx: tx, w: bw}
+	if err := d.dump(opts); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+type dumper struct {
+	tx *exec.Tx
+	w  *bufio.Writer
+}
+
+// dump writes tables first, followed by their rows unless opts.SchemaOnly is
+// set, and then indexes, triggers and views, so the output can be replayed in
+// order.
+func (d *dumper) dump(opts *DumpOptions) error {
+	query := `SELECT type, name, sql FROM sqlite_schema
+		WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%' AND ($table = '' OR tbl_name = $table)
+			AND NOT ($schemaOnly AND tbl_name IN (SELECT name FROM pragma_table_list WHERE schema = 'main' AND type = 'shadow'))
+		ORDER BY CASE type WHEN 'table' THEN 0 WHEN 'index' THEN 1 WHEN 'trigger' THEN 2 ELSE 3 END, rowid;`
+	var objects []dumpObject
+	err := d.tx.Query(query, map[string]interface{}{"$table": opts.Table, "$schemaOnly": opts.SchemaOnly}, func(_ []string, values []interface{}) {
+		objects = append(objects, dumpObject{typ: values[0].(string), name: values[1].(string), sql: values[2].(string)})
+	})
+	if err != nil {
+		return err
+	}
+	if opts.Table != "" && len(objects) == 0 {
+		return fmt.Errorf("no such table: %s", opts.Table)
+	}
+
+	data := !opts.SchemaOnly
+	if data {
+		d.w.WriteString("PRAGMA foreign_keys=OFF;\nBEGIN TRANSACTION;\n")
+	}
+	writableSchema := false
+	for _, obj := range objects {
+		if obj.typ == "table" && strings.HasPrefix(strings.ToUpper(obj.sql), "CREATE VIRTUAL TABLE") {
+			if !data {
+				fmt.Fprintf(d.w, "%s;\n", obj.sql)
+				continue
+			}
+			if !writableSchema {
+				d.w.WriteString("PRAGMA writable_schema=ON;\n")
+				writableSchema = true
+			}
+			fmt.Fprintf(d.w, "INSERT INTO sqlite_schema(type,name,tbl_name,rootpage,sql) VALUES('table',%s,%s,0,%s);\n",
+				sqlLiteral(obj.name), sqlLiteral(obj.name), sqlLiteral(obj.sql))
+			continue
+		}
+		fmt.Fprintf(d.w, "%s;\n", obj.sql)
+		if data && obj.typ == "table" {
+			if err := d.rows(obj.name); err != nil {
+				return err
+			}
+		}
+	}
+	if !data {
+		return nil
+	}
+	if err := d.sequences(opts.Table); err != nil {
+		return err
+	}
+	if writableSchema {
+		d.w.WriteString("PRAGMA writable_schema=RESET;\n")
+	}
+	_, err = d.w.WriteString("COMMIT;\n")
+	return err
+}
+
+// rows writes an INSERT statement for every row of table. Generated columns
+// cannot be inserted, so tables with any name their other columns instead.
+func (d *dumper) rows(table string) error {
+	quoted := sqliteutils.QuoteIdentifier(table)
+	var columns []string
+	generated := false
+	err := d.tx.Query("SELECT name, hidden FROM pragma_table_xinfo($table) ORDER BY cid;", map[string]interface{}{"$table": table},
+		func(_ []string, values []interface{}) {
+			if hidden, _ := values[1].(int64); hidden != 0 {
+				generated = true
+				return
+			}
+			columns = append(columns, sqliteutils.QuoteIdentifier(values[0].(string)))
+		})
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	insert := "INSERT INTO " + quoted
+	if generated {
+		insert += "(" + strings.Join(columns, ",") + ")"
+	}
+
+	literals := make([]string, len(columns))
+	err = d.tx.Query("SELECT "+strings.Join(columns, ",")+" FROM "+quoted+";", nil, func(_ []string, values []interface{}) {
+		for i, v := range values {
+			literals[i] = sqlLiteral(v)
+		}
+		fmt.Fprintf(d.w, "%s VALUES(%s);\n", insert, strings.Join(literals, ","))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to dump rows of %s: %w", table, err)
+	}
+	return nil
+}
+
+// sequences restores AUTOINCREMENT counters from sqlite_sequence.
+func (d *dumper) sequences(table string) error {
+	var exists bool
+	err := d.tx.Query("SELECT 1 FROM sqlite_schema WHERE name = 'sqlite_sequence';", nil, func(_ []string, _ []interface{}) {
+		exists = true
+	})
+	if err != nil || !exists {
+		return err
+	}
+	return d.tx.Query("SELECT name, seq FROM sqlite_sequence WHERE $table = '' OR name = $table ORDER BY name;",
+		map[string]interface{}{"$table": table}, func(_ []string, values []interface{}) {
+			fmt.Fprintf(d.w, "DELETE FROM sqlite_sequence WHERE name = %s;\n", sqlLiteral(values[0]))
+			fmt.Fprintf(d.w, "INSERT INTO sqlite_sequence VALUES(%s,%s);\n", sqlLiteral(values[0]), sqlLiteral(values[1]))
+		})
+}
+
+// sqlLiteral renders a column value as a SQL literal that reads back as the
+// same value and storage class.
+func sqlLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return "1e999"
+		case math.IsInf(v, -1):
+			return "-1e999"
+		case math.IsNaN(v):
+			return "NULL"
+		}
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".e") {
+			s += ".0"
+		}
+		return s
+	case []byte:
+		return "X'" + strings.ToUpper(hex.EncodeToString(v)) + "'"
+	default:
+		s := fmt.Sprint(v)
+		if strings.IndexByte(s, 0) >= 0 || !utf8.ValidString(s) {
+			// SQL text ends at a NUL byte, so such text is written as bytes.
+			return "CAST(X'" + strings.ToUpper(hex.EncodeToString([]byte(s))) + "' AS TEXT)"
+		}
+		return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+	}
+}
@@ -0,0 +1,116 @@
+package export_test
+
+import (
+	"bytes"
+	"context"
+	"math"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/export"
+	"github.com/dropsite-ai/sqliteutils/importer"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const dumpMigration = `
+	CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT, score REAL, avatar BLOB,
+		upper_name TEXT GENERATED ALWAYS AS (upper(name)));
+	CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id), body TEXT);
+	CREATE INDEX posts_user_id ON posts(user_id);
+	CREATE TRIGGER posts_touch AFTER INSERT ON posts BEGIN UPDATE users SET score = score + 1 WHERE id = new.user_id; END;
+	CREATE VIEW user_posts AS SELECT u.name, p.body FROM users u JOIN posts p ON p.user_id = u.id;
+	CREATE VIRTUAL TABLE notes USING fts5(body);
+	INSERT INTO users (name, score, avatar) VALUES ('O''Brien', 1, x'00ff'), ('multi
+line', 2.5, NULL);
+	INSERT INTO posts VALUES (1, 1, 'hi'), (2, 1, 'again');
+	INSERT INTO notes VALUES ('full text');
+	DELETE FROM users WHERE id = 2;
+`
+
+// dumpTables returns every row of the tables, views and full-text index
+// the dump covers.
+func dumpTables(ctx context.Context, t *testing.T) map[string][][]interface{} {
+	tables := map[string][][]interface{}{}
+	for name, query := range map[string]string{
+		"users":    "SELECT * FROM users ORDER BY id;",
+		"posts":    "SELECT * FROM posts ORDER BY id;",
+		"view":     "SELECT * FROM user_posts;",
+		"notes":    "SELECT body FROM notes WHERE notes MATCH 'text';",
+		"sequence": "SELECT * FROM sqlite_sequence;",
+	} {
+		require.NoError(t, exec.Query(ctx, query, nil, func(_ []string, values []interface{}) {
+			tables[name] = append(tables[name], values)
+		}))
+	}
+	return tables
+}
+
+func TestDump(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, dumpMigration, 1))
+	require.NoError(t, exec.E(ctx, "INSERT INTO users (name, score) VALUES (?, ?), (?, ?);",
+		"nul\x00byte", math.Inf(1), "third", 1e300))
+	want := dumpTables(ctx, t)
+
+	var buf bytes.Buffer
+	require.NoError(t, export.Dump(ctx, &buf, nil))
+	var schema bytes.Buffer
+	require.NoError(t, export.Dump(ctx, &schema, &export.DumpOptions{Table: "posts", SchemaOnly: true}))
+	assert.Error(t, export.Dump(ctx, &schema, &export.DumpOptions{Table: "missing"}))
+	require.NoError(t, pool.ClosePool())
+
+	assert.Equal(t, `CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id), body TEXT);
+CREATE INDEX posts_user_id ON posts(user_id);
+CREATE TRIGGER posts_touch AFTER INSERT ON posts BEGIN UPDATE users SET score = score + 1 WHERE id = new.user_id; END;
+`, schema.String())
+
+	// Replaying the dump into an empty database restores every row, the
+	// AUTOINCREMENT counter and the full-text index, without firing triggers.
+	require.NoError(t, test.Pool(ctx, t, "", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	statements, rest := sqliteutils.SplitStatements(buf.String())
+	require.Empty(t, rest)
+	queries := make([]string, len(statements))
+	for i, s := range statements {
+		queries[i] = s.SQL
+	}
+	require.NoError(t, exec.ExecMulti(ctx, queries, make([]map[string]interface{}, len(queries)), nil))
+	assert.Equal(t, want, dumpTables(ctx, t))
+
+	var integrity string
+	require.NoError(t, exec.Q(ctx, "PRAGMA integrity_check;", func(_ []string, values []interface{}) {
+		integrity = values[0].(string)
+	}))
+	assert.Equal(t, "ok", integrity)
+}
+
+func TestDump_SchemaOnly(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, dumpMigration, 1))
+	var schema bytes.Buffer
+	require.NoError(t, export.Dump(ctx, &schema, &export.DumpOptions{SchemaOnly: true}))
+	require.NoError(t, pool.ClosePool())
+
+	// The FTS5 shadow tables are created by the virtual table, so replaying
+	// them as well would fail.
+	assert.Contains(t, schema.String(), "CREATE VIRTUAL TABLE notes USING fts5(body);")
+	assert.NotContains(t, schema.String(), "notes_data")
+
+	require.NoError(t, test.Pool(ctx, t, "", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, importer.RunSQLFile(ctx, &schema))
+	require.NoError(t, exec.E(ctx, "INSERT INTO notes VALUES ('full text');"))
+	var matches int64
+	require.NoError(t, exec.Q(ctx, "SELECT count(*) FROM notes WHERE notes MATCH 'text';", func(_ []string, values []interface{}) {
+		matches = values[0].(int64)
+	}))
+	assert.Equal(t, int64(1), matches)
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"math"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+)
+
+// WriteJSONL runs query with params and writes each row to w as a JSON
+// object on its own line, with keys in select-list order. NULL is written
+// as null and blobs as base64 strings; floats that are NaN or infinite,
+// which JSON cannot represent, are written as null too.
+//
+// WriteJSONL returns the number of rows written.
+func WriteJSONL(ctx context.Context, query string, params map[string]interface{}, w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	enc := newEncoder()
+	var rows int64
+	var keys [][]byte
+	var writeErr error
+	err := exec.Query(ctx, query, params, func(columns []string, values []interface{}) {
+		if writeErr != nil {
+			return
+		}
+		if keys == nil {
+			keys = make([][]byte, len(columns))
+			for i, column := range columns {
+				key, err := enc.marshal(column)
+				if err != nil {
+					writeErr = err
+					return
+				}
+				keys[i] = append([]byte(nil), key...)
+			}
+		}
+		if writeErr = writeObject(bw, enc, keys, values); writeErr == nil {
+			rows++
+		}
+	})
+	if err == nil {
+		err = writeErr
+	}
+	if flushErr := bw.Flush(); err == nil {
+		err = flushErr
+	}
+	return rows, err
+}
+
+// encoder marshals JSON without escaping HTML characters, reusing one
+// buffer.
+type encoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+func newEncoder() *encoder {
+	e := &encoder{}
+	e.enc = json.NewEncoder(&e.buf)
+	e.enc.SetEscapeHTML(false)
+	return e
+}
+
+// marshal returns the encoding of v, valid until the next call.
+func (e *encoder) marshal(v interface{}) ([]byte, error) {
+	e.buf.Reset()
+	if err := e.enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(e.buf.Bytes(), []byte{'\n'}), nil
+}
+
+// writeObject writes one JSON object line from encoded keys and values.
+func writeObject(w *bufio.Writer, enc *encoder, keys [][]byte, values []interface{}) error {
+	w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			w.WriteByte(',')
+		}
+		w.Write(keys[i])
+		w.WriteByte(':')
+		if f, ok := v.(float64); ok && (math.IsNaN(f) || math.IsInf(f, 0)) {
+			v = nil
+		}
+		b, err := enc.marshal(v)
+		if err != nil {
+			return err
+		}
+		w.Write(b)
+	}
+	w.WriteByte('}')
+	return w.WriteByte('\n')
+}
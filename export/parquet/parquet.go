@@ -0,0 +1,382 @@
+// Package parquet exports the results of queries on the global pool as
+// Parquet files. It is a module of its own so that only programs writing
+// Parquet depend on parquet-go.
+package parquet
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/compress"
+	"github.com/parquet-go/parquet-go/encoding"
+	"zombiezen.com/go/sqlite"
+)
+
+// Type is the type of a Parquet column.
+type Type int
+
+const (
+	// Int64 is a 64-bit integer column.
+	Int64 Type = iota + 1
+	// Double is a 64-bit float column.
+	Double
+	// Boolean is a boolean column, written from integers as nonzero.
+	Boolean
+	// String is a UTF-8 text column.
+	String
+	// Bytes is a binary column.
+	Bytes
+)
+
+var typeNames = map[Type]string{
+	Int64:   "Int64",
+	Double:  "Double",
+	Boolean: "Boolean",
+	String:  "String",
+	Bytes:   "Bytes",
+}
+
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+// Options configures Export.
+type Options struct {
+	// Schema sets the type of columns by name. Other columns read from a
+	// table column are typed after its declared type: INTEGER affinity
+	// makes an int64 column, TEXT affinity a string column and REAL
+	// affinity a double column. The remaining columns, such as
+	// expressions, are typed after the values of the first SampleRows
+	// rows: numbers make a double column, BLOB values a binary column, and
+	// text or NULL values a string column.
+	Schema map[string]Type
+	// SampleRows is how many rows are read to infer column types. Defaults
+	// to 100.
+	SampleRows int
+	// RowGroupSize bounds the rows per row group, which are buffered in
+	// memory. Defaults to the parquet-go default.
+	RowGroupSize int64
+}
+
+// Export runs query with params and writes its rows to w as a
+// Snappy-compressed Parquet file with one optional column per result
+// column, in select-list order, so NULL values are kept. opts may be nil.
+//
+// Export returns the number of rows written. Only the sampled rows are held
+// in memory before the first row group is written. A query without rows
+// writes a file with its columns and no rows.
+func Export(ctx context.Context, query string, params map[string]interface{}, w io.Writer, opts *Options) (int64, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	sampleRows := opts.SampleRows
+	if sampleRows <= 0 {
+		sampleRows = 100
+	}
+
+	// pool.Take interrupts the query when its context is done, so canceling
+	// queryCtx stops the query at the first write error instead of stepping
+	// through the remaining rows.
+	queryCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	pw := &writer{w: w, opts: opts}
+	err := pool.WithConn(queryCtx, func(conn *sqlite.Conn) error {
+		columns, err := exec.ColumnsConn(conn, query)
+		if err != nil {
+			return err
+		}
+		if err := pw.describe(columns); err != nil {
+			return err
+		}
+		var sample [][]interface{}
+		var writeErr error
+		err = exec.QueryConn(conn, query, params, func(_ []string, values []interface{}) {
+			if writeErr != nil {
+				return
+			}
+			if pw.writer == nil && len(sample) < sampleRows {
+				sample = append(sample, values)
+				return
+			}
+			if pw.writer == nil {
+				writeErr = pw.start(sample)
+			}
+			if writeErr == nil {
+				writeErr = pw.write(values)
+			}
+			if writeErr != nil {
+				cancel()
+			}
+		})
+		if writeErr != nil {
+			// The query, if it failed, was interrupted by the write error.
+			err = writeErr
+		}
+		if err == nil && pw.writer == nil {
+			err = pw.start(sample)
+		}
+		return err
+	})
+	if pw.writer != nil {
+		if closeErr := pw.writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return pw.rows, err
+}
+
+// writer converts result rows to Parquet rows.
+type writer struct {
+	w    io.Writer
+	opts *Options
+	// columns and types hold the name and type of each result column. A
+	// type is zero until it is inferred from the sampled rows.
+	columns []string
+	types   []Type
+	writer  *pq.Writer
+	row     pq.Row
+	rows    int64
+}
+
+// describe types the result columns set in the schema or declared in their
+// tables.
+func (pw *writer) describe(columns []exec.ColumnInfo) error {
+	pw.columns = make([]string, len(columns))
+	pw.types = make([]Type, len(columns))
+	seen := make(map[string]bool, len(columns))
+	for i, c := range columns {
+		if seen[c.Name] {
+			return fmt.Errorf("duplicate column %q", c.Name)
+		}
+		seen[c.Name] = true
+		pw.columns[i] = c.Name
+		typ, ok := pw.opts.Schema[c.Name]
+		if !ok {
+			typ = declaredType(c.DeclType)
+		}
+		pw.types[i] = typ
+	}
+	return nil
+}
+
+// start infers the types left from sample, creates the writer and writes
+// the sampled rows.
+func (pw *writer) start(sample [][]interface{}) error {
+	group := make(columnGroup, len(pw.columns))
+	for i, column := range pw.columns {
+		if pw.types[i] == 0 {
+			pw.types[i] = inferType(sample, i)
+		}
+		node, err := newNode(pw.types[i])
+		if err != nil {
+			return fmt.Errorf("column %q: %w", column, err)
+		}
+		group[i] = columnField{Node: pq.Optional(node), name: column}
+	}
+
+	options := []pq.WriterOption{pq.NewSchema("query", group), pq.Compression(&pq.Snappy)}
+	if pw.opts.RowGroupSize > 0 {
+		options = append(options, pq.MaxRowsPerRowGroup(pw.opts.RowGroupSize))
+	}
+	pw.writer = pq.NewWriter(pw.w, options...)
+	pw.row = make(pq.Row, len(pw.columns))
+
+	for _, values := range sample {
+		if err := pw.write(values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (pw *writer) write(values []interface{}) error {
+	for i, v := range values {
+		value, err := newValue(pw.types[i], v)
+		if err != nil {
+			return fmt.Errorf("column %q: %w", pw.columns[i], err)
+		}
+		definition := 1
+		if v == nil {
+			definition = 0
+		}
+		pw.row[i] = value.Level(0, definition, i)
+	}
+	if _, err := pw.writer.WriteRows([]pq.Row{pw.row}); err != nil {
+		return err
+	}
+	pw.rows++
+	return nil
+}
+
+// columnGroup is a group whose fields keep the order of the result columns,
+// where pq.Group orders them by name.
+type columnGroup []columnField
+
+type columnField struct {
+	pq.Node
+	name string
+}
+
+func (f columnField) Name() string { return f.name }
+
+// Value is only used to write Go values; rows are written as pq.Row.
+func (f columnField) Value(base reflect.Value) reflect.Value { return reflect.Value{} }
+
+func (g columnGroup) ID() int { return 0 }
+
+func (g columnGroup) String() string {
+	var s strings.Builder
+	pq.PrintSchema(&s, "", g)
+	return s.String()
+}
+
+func (g columnGroup) Type() pq.Type { return pq.Group{}.Type() }
+
+func (g columnGroup) Optional() bool { return false }
+
+func (g columnGroup) Repeated() bool { return false }
+
+func (g columnGroup) Required() bool { return true }
+
+func (g columnGroup) Leaf() bool { return false }
+
+func (g columnGroup) Fields() []pq.Field {
+	fields := make([]pq.Field, len(g))
+	for i := range g {
+		fields[i] = g[i]
+	}
+	return fields
+}
+
+func (g columnGroup) Encoding() encoding.Encoding { return nil }
+
+func (g columnGroup) Compression() compress.Codec { return nil }
+
+func (g columnGroup) GoType() reflect.Type {
+	fields := make([]reflect.StructField, len(g))
+	for i, f := range g {
+		fields[i] = reflect.StructField{Name: fmt.Sprintf("F%d", i), Type: f.GoType()}
+	}
+	return reflect.StructOf(fields)
+}
+
+// declaredType returns the type of a column declared with declType, by
+// SQLite's affinity rules, or zero for columns whose values can be of any
+// type, such as those declared BLOB, NUMERIC or not at all.
+func declaredType(declType string) Type {
+	declType = strings.ToUpper(declType)
+	switch {
+	case declType == "":
+		return 0
+	case strings.Contains(declType, "INT"):
+		return Int64
+	case strings.Contains(declType, "CHAR"), strings.Contains(declType, "CLOB"), strings.Contains(declType, "TEXT"):
+		return String
+	case strings.Contains(declType, "REAL"), strings.Contains(declType, "FLOA"), strings.Contains(declType, "DOUB"):
+		return Double
+	}
+	return 0
+}
+
+// inferType returns the type of column i of the sampled rows. Numbers make
+// a double column, so that a float after the sample still fits.
+func inferType(sample [][]interface{}, i int) Type {
+	var numbers, texts, blobs bool
+	for _, values := range sample {
+		switch values[i].(type) {
+		case int64, float64:
+			numbers = true
+		case string:
+			texts = true
+		case []byte:
+			blobs = true
+		}
+	}
+	switch {
+	case blobs && !numbers:
+		return Bytes
+	case texts || blobs:
+		return String
+	case numbers:
+		return Double
+	}
+	return String
+}
+
+func newNode(typ Type) (pq.Node, error) {
+	switch typ {
+	case Int64:
+		return pq.Leaf(pq.Int64Type), nil
+	case Double:
+		return pq.Leaf(pq.DoubleType), nil
+	case Boolean:
+		return pq.Leaf(pq.BooleanType), nil
+	case String:
+		return pq.String(), nil
+	case Bytes:
+		return pq.Leaf(pq.ByteArrayType), nil
+	}
+	return nil, fmt.Errorf("invalid Parquet type %s", typ)
+}
+
+// newValue converts a column value to typ.
+func newValue(typ Type, v interface{}) (pq.Value, error) {
+	if v == nil {
+		return pq.NullValue(), nil
+	}
+	switch typ {
+	case Int64:
+		switch v := v.(type) {
+		case int64:
+			return pq.Int64Value(v), nil
+		case float64:
+			if v == math.Trunc(v) && v >= math.MinInt64 && v < math.MaxInt64 {
+				return pq.Int64Value(int64(v)), nil
+			}
+		}
+	case Double:
+		switch v := v.(type) {
+		case int64:
+			return pq.DoubleValue(float64(v)), nil
+		case float64:
+			return pq.DoubleValue(v), nil
+		}
+	case Boolean:
+		switch v := v.(type) {
+		case int64:
+			return pq.BooleanValue(v != 0), nil
+		case float64:
+			return pq.BooleanValue(v != 0), nil
+		}
+	case String:
+		switch v := v.(type) {
+		case string:
+			return pq.ByteArrayValue([]byte(v)), nil
+		case []byte:
+			return pq.ByteArrayValue(v), nil
+		case int64:
+			return pq.ByteArrayValue(strconv.AppendInt(nil, v, 10)), nil
+		case float64:
+			return pq.ByteArrayValue(strconv.AppendFloat(nil, v, 'g', -1, 64)), nil
+		}
+	case Bytes:
+		switch v := v.(type) {
+		case []byte:
+			return pq.ByteArrayValue(v), nil
+		case string:
+			return pq.ByteArrayValue([]byte(v)), nil
+		}
+	}
+	return pq.Value{}, fmt.Errorf("cannot write %T value %#v as Parquet type %s", v, v, typ)
+}
@@ -0,0 +1,123 @@
+package parquet_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/export/parquet"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	pq "github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const migration = `
+	CREATE TABLE files (id INTEGER PRIMARY KEY, name TEXT, size REAL, data BLOB);
+	INSERT INTO files VALUES (1, 'a, b', 1.5, x'01ff'), (2, NULL, 2, NULL);
+`
+
+type file struct {
+	ID    *int64   `parquet:"id,optional"`
+	Name  *string  `parquet:"name,optional"`
+	Size  *float64 `parquet:"size,optional"`
+	Data  []byte   `parquet:"data,optional"`
+	Flag  *bool    `parquet:"flag,optional"`
+	Ratio *float64 `parquet:"ratio,optional"`
+}
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	// ratio is an integer in the sampled row and a float after it.
+	var buf bytes.Buffer
+	rows, err := parquet.Export(ctx, "SELECT id, name, size, data, id = 1 AS flag, CASE id WHEN 1 THEN 1 ELSE 2.5 END AS ratio FROM files ORDER BY id;", nil, &buf,
+		&parquet.Options{
+			Schema:     map[string]parquet.Type{"flag": parquet.Boolean},
+			SampleRows: 1,
+		})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), rows)
+
+	files, err := pq.Read[file](bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	assert.Equal(t, int64(1), *files[0].ID)
+	assert.Equal(t, "a, b", *files[0].Name)
+	assert.Equal(t, 1.5, *files[0].Size)
+	assert.Equal(t, []byte{0x01, 0xff}, files[0].Data)
+	assert.True(t, *files[0].Flag)
+	assert.Equal(t, 1.0, *files[0].Ratio)
+	assert.Equal(t, int64(2), *files[1].ID)
+	assert.Nil(t, files[1].Name)
+	assert.Equal(t, 2.0, *files[1].Size)
+	assert.Nil(t, files[1].Data)
+	assert.False(t, *files[1].Flag)
+	assert.Equal(t, 2.5, *files[1].Ratio)
+
+	// Values that do not convert to the column type fail the export.
+	buf.Reset()
+	_, err = parquet.Export(ctx, "SELECT 'x' AS id;", nil, &buf, &parquet.Options{
+		Schema: map[string]parquet.Type{"id": parquet.Int64},
+	})
+	assert.ErrorContains(t, err, `column "id": cannot write string value "x" as Parquet type Int64`)
+	_, err = parquet.Export(ctx, "SELECT 1 AS a, 2 AS a;", nil, &buf, nil)
+	assert.Error(t, err)
+}
+
+func TestExport_ColumnsAndEmptyResult(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	// A query without rows still writes its columns, in select-list order
+	// and typed by their declarations.
+	var buf bytes.Buffer
+	rows, err := parquet.Export(ctx, "SELECT size, id, name, data FROM files WHERE id > 2;", nil, &buf, nil)
+	require.NoError(t, err)
+	assert.Zero(t, rows)
+
+	f, err := pq.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Zero(t, f.NumRows())
+	var names []string
+	var kinds []pq.Kind
+	for _, field := range f.Schema().Fields() {
+		names = append(names, field.Name())
+		kinds = append(kinds, field.Type().Kind())
+		assert.True(t, field.Optional())
+	}
+	assert.Equal(t, []string{"size", "id", "name", "data"}, names)
+	assert.Equal(t, []pq.Kind{pq.Double, pq.Int64, pq.ByteArray, pq.ByteArray}, kinds)
+}
+
+func TestExport_StopsAtWriteError(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	var stepped int64
+	remove := exec.OnStatement(func(info exec.StatementInfo) { stepped = info.Rows })
+	defer remove()
+
+	// The third of many rows does not convert.
+	var buf bytes.Buffer
+	rows, err := parquet.Export(ctx, `WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 100000)
+		SELECT CASE i WHEN 3 THEN 'x' ELSE i END AS id FROM n;`, nil, &buf, &parquet.Options{
+		Schema:     map[string]parquet.Type{"id": parquet.Int64},
+		SampleRows: 1,
+	})
+	assert.ErrorContains(t, err, "cannot write string")
+	assert.Equal(t, int64(2), rows)
+	assert.Equal(t, int64(3), stepped)
+}
@@ -0,0 +1,251 @@
+// Package fts sets up SQLite FTS5 full-text indexes over ordinary tables and
+// searches them.
+//
+// Create builds an external-content FTS5 table for some columns of a content
+// table and installs triggers that keep the index in sync with the content
+// table's inserts, updates and deletes. Search runs a MATCH query ranked by
+// bm25 and can return highlighted columns and snippets.
+package fts
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Options configures the FTS5 table created by Create.
+type Options struct {
+	// Columns are the content table columns to index. Required.
+	Columns []string
+	// Tokenizer is the FTS5 tokenize option, e.g. "porter unicode61".
+	// Defaults to FTS5's unicode61 tokenizer.
+	Tokenizer string
+	// ContentRowID is the content table's integer key column. Defaults to rowid.
+	ContentRowID string
+}
+
+// SearchOptions configures Search.
+type SearchOptions struct {
+	// Limit bounds the number of results. Defaults to 20.
+	Limit  int
+	Offset int
+	// Weights are per-column bm25 weights in index column order. Columns
+	// without a weight count as 1.
+	Weights []float64
+	// Highlight returns every indexed column with matched terms wrapped in
+	// HighlightStart and HighlightEnd, instead of the plain column text.
+	Highlight bool
+	// Snippet returns a short fragment of the best matching column in
+	// Result.Snippet, also wrapped in HighlightStart and HighlightEnd.
+	Snippet bool
+	// SnippetTokens is the maximum number of tokens in a snippet. Defaults to 16.
+	SnippetTokens int
+	// HighlightStart and HighlightEnd default to "<b>" and "</b>".
+	HighlightStart string
+	HighlightEnd   string
+	// Ellipsis marks text left out of a snippet. Defaults to "...".
+	Ellipsis string
+}
+
+// Result is one row matched by Search.
+type Result struct {
+	// RowID is the rowid of the matching content table row.
+	RowID int64
+	// Rank is the bm25 score; lower is a better match.
+	Rank float64
+	// Columns holds the indexed columns, highlighted if requested.
+	Columns map[string]string
+	Snippet string
+}
+
+// Create creates the FTS5 table name indexing opts.Columns of content,
+// installs the sync triggers and indexes the rows content already holds.
+func Create(ctx context.Context, name, content string, opts Options) error {
+	if len(opts.Columns) == 0 {
+		return fmt.Errorf("fts table %s needs at least one column", name)
+	}
+	rowID := opts.ContentRowID
+	if rowID == "" {
+		rowID = "rowid"
+	}
+
+	q := sqliteutils.QuoteIdentifier
+	columns := make([]string, len(opts.Columns))
+	oldValues := make([]string, len(opts.Columns))
+	newValues := make([]string, len(opts.Columns))
+	for i, column := range opts.Columns {
+		columns[i] = q(column)
+		oldValues[i] = "OLD." + q(column)
+		newValues[i] = "NEW." + q(column)
+	}
+	cols := strings.Join(columns, ", ")
+	args := append(append([]string{}, columns...),
+		"content="+quoteLiteral(content), "content_rowid="+quoteLiteral(rowID))
+	if opts.Tokenizer != "" {
+		args = append(args, "tokenize="+quoteLiteral(opts.Tokenizer))
+	}
+
+	insert := fmt.Sprintf("INSERT INTO %s (rowid, %s) VALUES (NEW.%s, %s);", q(name), cols, q(rowID), strings.Join(newValues, ", "))
+	remove := fmt.Sprintf("INSERT INTO %s (%s, rowid, %s) VALUES ('delete', OLD.%s, %s);", q(name), q(name), cols, q(rowID), strings.Join(oldValues, ", "))
+	script := fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts5(%s);\n", q(name), strings.Join(args, ", ")) +
+		fmt.Sprintf("CREATE TRIGGER %s AFTER INSERT ON %s BEGIN %s END;\n", q(name+"_ai"), q(content), insert) +
+		fmt.Sprintf("CREATE TRIGGER %s AFTER DELETE ON %s BEGIN %s END;\n", q(name+"_ad"), q(content), remove) +
+		fmt.Sprintf("CREATE TRIGGER %s AFTER UPDATE ON %s BEGIN %s %s END;\n", q(name+"_au"), q(content), remove, insert) +
+		fmt.Sprintf("INSERT INTO %s (%s) VALUES ('rebuild');\n", q(name), q(name))
+
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) (err error) {
+		endFn, err := sqlitex.ImmediateTransaction(conn)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer endFn(&err)
+		if err := sqlitex.ExecuteScript(conn, script, nil); err != nil {
+			return fmt.Errorf("failed to create fts table %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// Drop removes the FTS5 table name and its sync triggers. The content table
+// is left untouched.
+func Drop(ctx context.Context, name string) error {
+	q := sqliteutils.QuoteIdentifier
+	script := fmt.Sprintf("DROP TRIGGER IF EXISTS %s;\nDROP TRIGGER IF EXISTS %s;\nDROP TRIGGER IF EXISTS %s;\nDROP TABLE IF EXISTS %s;\n",
+		q(name+"_ai"), q(name+"_ad"), q(name+"_au"), q(name))
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		if err := sqlitex.ExecuteScript(conn, script, nil); err != nil {
+			return fmt.Errorf("failed to drop fts table %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// Rebuild reindexes every row of the content table, for example after rows
+// were changed while the triggers were missing.
+func Rebuild(ctx context.Context, name string) error {
+	return command(ctx, name, "rebuild")
+}
+
+// Optimize merges the index b-trees of name to speed up queries.
+func Optimize(ctx context.Context, name string) error {
+	return command(ctx, name, "optimize")
+}
+
+func command(ctx context.Context, name, cmd string) error {
+	q := sqliteutils.QuoteIdentifier
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES ('%s');", q(name), q(name), cmd)
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		if err := sqlitex.ExecuteTransient(conn, query, nil); err != nil {
+			return fmt.Errorf("failed to %s fts table %s: %w", cmd, name, err)
+		}
+		return nil
+	})
+}
+
+// Search returns the rows of the FTS5 table matching query, an FTS5 query
+// string, best matches first.
+func Search(ctx context.Context, table, query string, opts SearchOptions) ([]Result, error) {
+	if opts.Limit <= 0 {
+		opts.Limit = 20
+	}
+	if opts.SnippetTokens <= 0 {
+		opts.SnippetTokens = 16
+	}
+	if opts.HighlightStart == "" && opts.HighlightEnd == "" {
+		opts.HighlightStart, opts.HighlightEnd = "<b>", "</b>"
+	}
+	if opts.Ellipsis == "" {
+		opts.Ellipsis = "..."
+	}
+
+	var results []Result
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		columns, err := indexColumns(conn, table)
+		if err != nil {
+			return err
+		}
+
+		q := sqliteutils.QuoteIdentifier
+		rank := "bm25(" + q(table)
+		for i := range columns {
+			weight := 1.0
+			if i < len(opts.Weights) {
+				weight = opts.Weights[i]
+			}
+			rank += fmt.Sprintf(", %g", weight)
+		}
+		rank += ")"
+
+		selects := []string{"rowid", rank}
+		for i, column := range columns {
+			if opts.Highlight {
+				selects = append(selects, fmt.Sprintf("highlight(%s, %d, $start, $end)", q(table), i))
+			} else {
+				selects = append(selects, q(column))
+			}
+		}
+		if opts.Snippet {
+			selects = append(selects, fmt.Sprintf("snippet(%s, -1, $start, $end, $ellipsis, $tokens)", q(table)))
+		}
+		sql := fmt.Sprintf("SELECT %s FROM %s WHERE %s MATCH $query ORDER BY 2 LIMIT $limit OFFSET $offset;",
+			strings.Join(selects, ", "), q(table), q(table))
+
+		named := map[string]interface{}{"$query": query, "$limit": opts.Limit, "$offset": opts.Offset}
+		if opts.Highlight || opts.Snippet {
+			named["$start"], named["$end"] = opts.HighlightStart, opts.HighlightEnd
+		}
+		if opts.Snippet {
+			named["$ellipsis"], named["$tokens"] = opts.Ellipsis, opts.SnippetTokens
+		}
+		return sqlitex.Execute(conn, sql, &sqlitex.ExecOptions{
+			Named: named,
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				r := Result{
+					RowID:   stmt.ColumnInt64(0),
+					Rank:    stmt.ColumnFloat(1),
+					Columns: make(map[string]string, len(columns)),
+				}
+				for i, column := range columns {
+					r.Columns[column] = stmt.ColumnText(2 + i)
+				}
+				if opts.Snippet {
+					r.Snippet = stmt.ColumnText(2 + len(columns))
+				}
+				results = append(results, r)
+				return nil
+			},
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", table, err)
+	}
+	return results, nil
+}
+
+// indexColumns returns the indexed columns of the FTS5 table, in order.
+func indexColumns(conn *sqlite.Conn, table string) ([]string, error) {
+	var columns []string
+	err := sqlitex.ExecuteTransient(conn, "SELECT name FROM pragma_table_info(?);", &sqlitex.ExecOptions{
+		Args: []interface{}{table},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			columns = append(columns, stmt.ColumnText(0))
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("no such table: %s", table)
+	}
+	return columns, nil
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,86 @@
+package fts_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/fts"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const migration = `
+	CREATE TABLE articles (
+		id INTEGER PRIMARY KEY,
+		title TEXT NOT NULL,
+		body TEXT NOT NULL
+	);
+	INSERT INTO articles (id, title, body) VALUES
+		(1, 'SQLite tips', 'Use WAL mode for concurrent readers.'),
+		(2, 'Go concurrency', 'Channels and goroutines, with a note on SQLite pools.');
+`
+
+func rowIDs(results []fts.Result) []int64 {
+	ids := []int64{}
+	for _, r := range results {
+		ids = append(ids, r.RowID)
+	}
+	return ids
+}
+
+func TestSearch(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	require.NoError(t, fts.Create(ctx, "articles_fts", "articles", fts.Options{
+		Columns:      []string{"title", "body"},
+		Tokenizer:    "porter unicode61",
+		ContentRowID: "id",
+	}))
+
+	// Existing rows are indexed, and a title match outranks a body match.
+	results, err := fts.Search(ctx, "articles_fts", "sqlite", fts.SearchOptions{Weights: []float64{10, 1}})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, rowIDs(results))
+	assert.Equal(t, "SQLite tips", results[0].Columns["title"])
+	assert.Less(t, results[0].Rank, results[1].Rank)
+
+	// Triggers keep the index in sync.
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO articles (id, title, body) VALUES (3, 'Indexes', 'Covering indexes speed up queries.');", nil, nil))
+	require.NoError(t, exec.Exec(ctx, "UPDATE articles SET body = 'Use WAL mode.' WHERE id = 2;", nil, nil))
+	require.NoError(t, exec.Exec(ctx, "DELETE FROM articles WHERE id = 1;", nil, nil))
+
+	results, err = fts.Search(ctx, "articles_fts", "sqlite", fts.SearchOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// Porter stemming matches "query" against "queries".
+	results, err = fts.Search(ctx, "articles_fts", "query", fts.SearchOptions{Highlight: true, Snippet: true, SnippetTokens: 3})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, int64(3), results[0].RowID)
+	assert.Equal(t, "Covering indexes speed up <b>queries</b>.", results[0].Columns["body"])
+	assert.Equal(t, "...speed up <b>queries</b>.", results[0].Snippet)
+
+	results, err = fts.Search(ctx, "articles_fts", "wal OR index*", fts.SearchOptions{Limit: 1, Offset: 1})
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+
+	_, err = fts.Search(ctx, "articles_fts", "\"unterminated", fts.SearchOptions{})
+	assert.Error(t, err)
+
+	require.NoError(t, fts.Optimize(ctx, "articles_fts"))
+	require.NoError(t, fts.Rebuild(ctx, "articles_fts"))
+	require.NoError(t, fts.Drop(ctx, "articles_fts"))
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO articles (id, title, body) VALUES (4, 'After', 'drop');", nil, nil))
+	_, err = fts.Search(ctx, "articles_fts", "drop", fts.SearchOptions{})
+	assert.Error(t, err)
+
+	assert.Error(t, fts.Create(ctx, "empty_fts", "articles", fts.Options{}))
+}
@@ -0,0 +1,213 @@
+// Package geo indexes geographic points and bounding boxes with SQLite's
+// R*Tree module and answers rectangle and nearest-neighbor queries without
+// scanning whole tables.
+//
+// A spatial index is an rtree virtual table keyed by an integer id, normally
+// the rowid of the row in an ordinary table that holds the rest of the data.
+// Coordinates are degrees of longitude (X) and latitude (Y). The R*Tree stores
+// 32-bit floats, rounding boxes outward by up to about a meter.
+package geo
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// EarthRadius is the mean radius of the Earth in meters used for distances.
+const EarthRadius = 6371008.8
+
+// Box is a bounding box in degrees.
+type Box struct {
+	MinLat, MinLon float64
+	MaxLat, MaxLon float64
+}
+
+// PointBox returns the zero-area box of a single point.
+func PointBox(lat, lon float64) Box {
+	return Box{MinLat: lat, MinLon: lon, MaxLat: lat, MaxLon: lon}
+}
+
+// Match is an index entry found by Nearest.
+type Match struct {
+	ID int64
+	// Distance is the great-circle distance in meters from the query point
+	// to the center of the entry's box.
+	Distance float64
+}
+
+// CreateIndex creates the spatial index table name if it does not exist.
+func CreateIndex(ctx context.Context, name string) error {
+	query := fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS %s USING rtree(id, min_lon, max_lon, min_lat, max_lat);",
+		sqliteutils.QuoteIdentifier(name))
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		if err := sqlitex.ExecuteTransient(conn, query, nil); err != nil {
+			return fmt.Errorf("failed to create spatial index %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// Insert adds box to index under id, replacing any box already stored for id.
+func Insert(ctx context.Context, index string, id int64, box Box) error {
+	if box.MinLat > box.MaxLat || box.MinLon > box.MaxLon {
+		return fmt.Errorf("invalid box for id %d: minimum exceeds maximum", id)
+	}
+	query := fmt.Sprintf("INSERT OR REPLACE INTO %s (id, min_lon, max_lon, min_lat, max_lat) VALUES (?, ?, ?, ?, ?);",
+		sqliteutils.QuoteIdentifier(index))
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		err := sqlitex.ExecuteTransient(conn, query, &sqlitex.ExecOptions{
+			Args: []interface{}{id, box.MinLon, box.MaxLon, box.MinLat, box.MaxLat},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to insert into spatial index %s: %w", index, err)
+		}
+		return nil
+	})
+}
+
+// InsertPoint adds the point at lat, lon to index under id.
+func InsertPoint(ctx context.Context, index string, id int64, lat, lon float64) error {
+	return Insert(ctx, index, id, PointBox(lat, lon))
+}
+
+// Delete removes id from index.
+func Delete(ctx context.Context, index string, id int64) error {
+	query := fmt.Sprintf("DELETE FROM %s WHERE id = ?;", sqliteutils.QuoteIdentifier(index))
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		if err := sqlitex.ExecuteTransient(conn, query, &sqlitex.ExecOptions{Args: []interface{}{id}}); err != nil {
+			return fmt.Errorf("failed to delete from spatial index %s: %w", index, err)
+		}
+		return nil
+	})
+}
+
+// Within returns the ids of the entries of index whose boxes lie entirely
+// inside box, in ascending order.
+func Within(ctx context.Context, index string, box Box) ([]int64, error) {
+	query := fmt.Sprintf(`SELECT id FROM %s
+		WHERE min_lon >= ? AND max_lon <= ? AND min_lat >= ? AND max_lat <= ? ORDER BY id;`,
+		sqliteutils.QuoteIdentifier(index))
+	ids := []int64{}
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		return sqlitex.Execute(conn, query, &sqlitex.ExecOptions{
+			Args: []interface{}{box.MinLon, box.MaxLon, box.MinLat, box.MaxLat},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				ids = append(ids, stmt.ColumnInt64(0))
+				return nil
+			},
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query spatial index %s: %w", index, err)
+	}
+	return ids, nil
+}
+
+// Nearest returns up to n entries of index closest to lat, lon, nearest
+// first. It searches boxes of growing radius, so only entries near the point
+// are read.
+func Nearest(ctx context.Context, index string, lat, lon float64, n int) ([]Match, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	query := fmt.Sprintf(`SELECT id, min_lon, max_lon, min_lat, max_lat FROM %s
+		WHERE max_lon >= ? AND min_lon <= ? AND max_lat >= ? AND min_lat <= ?;`,
+		sqliteutils.QuoteIdentifier(index))
+
+	var matches []Match
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		// Start at 1km and double until n entries lie within the radius or
+		// the search box covers the whole globe.
+		for radius := 1000.0; ; radius *= 2 {
+			box := radiusBox(lat, lon, radius)
+			matches = matches[:0]
+			err := sqlitex.Execute(conn, query, &sqlitex.ExecOptions{
+				Args: []interface{}{box.MinLon, box.MaxLon, box.MinLat, box.MaxLat},
+				ResultFunc: func(stmt *sqlite.Stmt) error {
+					centerLon := (stmt.ColumnFloat(1) + stmt.ColumnFloat(2)) / 2
+					centerLat := (stmt.ColumnFloat(3) + stmt.ColumnFloat(4)) / 2
+					matches = append(matches, Match{
+						ID:       stmt.ColumnInt64(0),
+						Distance: Haversine(lat, lon, centerLat, centerLon),
+					})
+					return nil
+				},
+			})
+			if err != nil {
+				return err
+			}
+			sort.Slice(matches, func(i, j int) bool {
+				if matches[i].Distance != matches[j].Distance {
+					return matches[i].Distance < matches[j].Distance
+				}
+				return matches[i].ID < matches[j].ID
+			})
+			// Entries beyond the radius may be farther than unseen entries
+			// just outside the box, so only those within it are final.
+			within := sort.Search(len(matches), func(i int) bool { return matches[i].Distance > radius })
+			if within >= n || radius >= math.Pi*EarthRadius {
+				return nil
+			}
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query spatial index %s: %w", index, err)
+	}
+	if len(matches) > n {
+		matches = matches[:n]
+	}
+	return matches, nil
+}
+
+// radiusBox returns a box containing every point within radius meters of lat, lon.
+func radiusBox(lat, lon, radius float64) Box {
+	dLat := radius / EarthRadius * 180 / math.Pi
+	box := Box{MinLat: lat - dLat, MaxLat: lat + dLat, MinLon: -180, MaxLon: 180}
+	if box.MinLat <= -90 || box.MaxLat >= 90 {
+		// The circle reaches a pole, so it spans every longitude.
+		box.MinLat, box.MaxLat = math.Max(box.MinLat, -90), math.Min(box.MaxLat, 90)
+		return box
+	}
+	dLon := math.Asin(math.Sin(radius/EarthRadius)/math.Cos(lat*math.Pi/180)) * 180 / math.Pi
+	if lon-dLon >= -180 && lon+dLon <= 180 {
+		// Otherwise the circle crosses the antimeridian and keeps the full range.
+		box.MinLon, box.MaxLon = lon-dLon, lon+dLon
+	}
+	return box
+}
+
+// Haversine returns the great-circle distance in meters between two points
+// given in degrees.
+func Haversine(lat1, lon1, lat2, lon2 float64) float64 {
+	const rad = math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * EarthRadius * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// PrepareConn registers the haversine(lat1, lon1, lat2, lon2) SQL function,
+// returning meters, on conn. Pass it to pool.WithPrepareConn.
+func PrepareConn(conn *sqlite.Conn) error {
+	return conn.CreateFunction("haversine", &sqlite.FunctionImpl{
+		NArgs:         4,
+		Deterministic: true,
+		AllowIndirect: true,
+		Scalar: func(ctx sqlite.Context, args []sqlite.Value) (sqlite.Value, error) {
+			for _, arg := range args {
+				if arg.Type() == sqlite.TypeNull {
+					return sqlite.Value{}, nil
+				}
+			}
+			return sqlite.FloatValue(Haversine(args[0].Float(), args[1].Float(), args[2].Float(), args[3].Float())), nil
+		},
+	})
+}
@@ -0,0 +1,68 @@
+package geo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/geo"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var cities = []struct {
+	id       int64
+	lat, lon float64
+}{
+	{1, 48.8566, 2.3522},    // Paris
+	{2, 51.5074, -0.1278},   // London
+	{3, 52.5200, 13.4050},   // Berlin
+	{4, 40.7128, -74.0060},  // New York
+	{5, -33.8688, 151.2093}, // Sydney
+}
+
+func TestGeo(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool("file::memory:?mode=memory&cache=shared", 1, pool.WithPrepareConn(geo.PrepareConn)))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	require.NoError(t, geo.CreateIndex(ctx, "cities_geo"))
+	for _, c := range cities {
+		require.NoError(t, geo.InsertPoint(ctx, "cities_geo", c.id, c.lat, c.lon))
+	}
+
+	// Western Europe.
+	ids, err := geo.Within(ctx, "cities_geo", geo.Box{MinLat: 45, MinLon: -5, MaxLat: 55, MaxLon: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2}, ids)
+
+	// Nearest to Brussels.
+	matches, err := geo.Nearest(ctx, "cities_geo", 50.8503, 4.3517, 3)
+	require.NoError(t, err)
+	require.Len(t, matches, 3)
+	assert.Equal(t, []int64{1, 2, 3}, []int64{matches[0].ID, matches[1].ID, matches[2].ID})
+	assert.InDelta(t, 264000, matches[0].Distance, 2000)
+
+	// Across the globe, n larger than the index.
+	matches, err = geo.Nearest(ctx, "cities_geo", -36.8485, 174.7633, 10)
+	require.NoError(t, err)
+	require.Len(t, matches, 5)
+	assert.Equal(t, int64(5), matches[0].ID)
+
+	require.NoError(t, geo.Delete(ctx, "cities_geo", 1))
+	ids, err = geo.Within(ctx, "cities_geo", geo.Box{MinLat: 45, MinLon: -5, MaxLat: 55, MaxLon: 10})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, ids)
+
+	assert.Error(t, geo.Insert(ctx, "cities_geo", 9, geo.Box{MinLat: 10, MaxLat: 0}))
+
+	var distance float64
+	require.NoError(t, exec.Exec(ctx, "SELECT haversine(48.8566, 2.3522, 51.5074, -0.1278) AS d;", nil, func(_ int, row map[string]interface{}) {
+		distance = row["d"].(float64)
+	}))
+	assert.InDelta(t, 343500, distance, 1000)
+	assert.InDelta(t, 0, geo.Haversine(10, 20, 10, 20), 1e-9)
+}
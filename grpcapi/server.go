@@ -0,0 +1,448 @@
+// Package grpcapi serves the database behind the global pool over gRPC, so
+// clients in other languages and sidecars can run statements, transactions
+// and blob transfers with the same semantics as the exec package.
+//
+// The service is defined in sqliteutils.proto. Register a Server on a
+// grpc.Server with RegisterDatabaseServer:
+//
+//	s := grpc.NewServer()
+//	grpcapi.RegisterDatabaseServer(s, grpcapi.NewServer(grpcapi.Options{Mode: grpcapi.ReadWrite}))
+//
+// Authentication is left to gRPC interceptors and transport credentials.
+//
+// It is a module of its own so that only programs serving gRPC depend on
+// grpc-go.
+package grpcapi
+
+//go:generate go run github.com/bufbuild/buf/cmd/buf@v1.34.0 generate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"zombiezen.com/go/sqlite"
+)
+
+// Mode controls whether the server accepts requests that write.
+type Mode int
+
+const (
+	// ReadOnly runs Execute read-only and rejects ExecuteTx and BlobUpload.
+	ReadOnly Mode = iota
+	// ReadWrite enables every method. StreamQuery remains read-only.
+	ReadWrite
+)
+
+// DefaultBatchSize is the number of rows per StreamQuery response when
+// Options.BatchSize is zero.
+const DefaultBatchSize = 100
+
+// Options configures a Server.
+type Options struct {
+	Mode Mode
+	// BatchSize is the number of rows per StreamQuery response. Defaults to
+	// DefaultBatchSize.
+	BatchSize int
+}
+
+// Server implements DatabaseServer on the global pool.
+type Server struct {
+	UnimplementedDatabaseServer
+	opts Options
+}
+
+// NewServer returns a Server with the given options.
+func NewServer(opts Options) *Server {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	return &Server{opts: opts}
+}
+
+// Execute runs stmt on a connection of the pool, read-only in ReadOnly mode.
+func (s *Server) Execute(ctx context.Context, stmt *Statement) (*Result, error) {
+	params, err := statementParams(stmt)
+	if err != nil {
+		return nil, err
+	}
+	var result *Result
+	if s.opts.Mode == ReadOnly {
+		err = exec.ReadOnly(ctx, func(tx *exec.Tx) (err error) {
+			result, err = runStatement(tx.Conn(), stmt.GetSql(), params, false)
+			return err
+		})
+		return result, statusError(ctx, err)
+	}
+
+	p, err := pool.GetPool()
+	if err != nil {
+		return nil, statusError(ctx, sqliteutils.FailedToGetPoolError(err))
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return nil, statusError(ctx, sqliteutils.FailedToTakeConnectionFromPoolError(err))
+	}
+	defer p.Put(conn)
+	result, err = runStatement(conn, stmt.GetSql(), params, true)
+	return result, statusError(ctx, err)
+}
+
+// ExecuteTx runs the statements of req in one transaction begun in req's
+// mode. It fails with PermissionDenied in ReadOnly mode.
+func (s *Server) ExecuteTx(ctx context.Context, req *ExecuteTxRequest) (*ExecuteTxResponse, error) {
+	if s.opts.Mode != ReadWrite {
+		return nil, status.Error(codes.PermissionDenied, "transactions are disabled in read-only mode")
+	}
+	var mode exec.TxMode
+	switch req.GetMode() {
+	case TxMode_TX_MODE_UNSPECIFIED, TxMode_TX_MODE_DEFERRED:
+		mode = exec.TxDeferred
+	case TxMode_TX_MODE_IMMEDIATE:
+		mode = exec.TxImmediate
+	case TxMode_TX_MODE_EXCLUSIVE:
+		mode = exec.TxExclusive
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "invalid transaction mode %d", req.GetMode())
+	}
+	params := make([]map[string]interface{}, len(req.GetStatements()))
+	for i, stmt := range req.GetStatements() {
+		var err error
+		if params[i], err = statementParams(stmt); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := exec.Begin(ctx, mode)
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	defer tx.Rollback()
+	resp := &ExecuteTxResponse{}
+	for i, stmt := range req.GetStatements() {
+		result, err := runStatement(tx.Conn(), stmt.GetSql(), params[i], true)
+		if err != nil {
+			return nil, statusError(ctx, fmt.Errorf("statement %d: %w", i, err))
+		}
+		resp.Results = append(resp.Results, result)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, statusError(ctx, err)
+	}
+	return resp, nil
+}
+
+// StreamQuery runs stmt read-only and sends its rows in batches of
+// Options.BatchSize. A query without rows sends one response with no
+// columns.
+func (s *Server) StreamQuery(stmt *Statement, stream Database_StreamQueryServer) error {
+	ctx := stream.Context()
+	params, err := statementParams(stmt)
+	if err != nil {
+		return err
+	}
+	resp := &QueryResponse{}
+	sent := false
+	var sendErr error
+	err = exec.ReadOnly(ctx, func(tx *exec.Tx) error {
+		return tx.Query(stmt.GetSql(), params, func(columns []string, values []interface{}) {
+			if sendErr != nil {
+				return
+			}
+			if !sent && len(resp.Rows) == 0 {
+				resp.Columns = columns
+			}
+			row, err := rowOf(values)
+			if err != nil {
+				sendErr = err
+				return
+			}
+			resp.Rows = append(resp.Rows, row)
+			if len(resp.Rows) == s.opts.BatchSize {
+				sendErr = stream.Send(resp)
+				sent = true
+				resp = &QueryResponse{}
+			}
+		})
+	})
+	if err == nil {
+		err = sendErr
+	}
+	if err != nil {
+		return statusError(ctx, err)
+	}
+	if !sent || len(resp.Rows) > 0 {
+		return stream.Send(resp)
+	}
+	return nil
+}
+
+// BlobUpload writes the streamed data into a blob with
+// exec.WriteBlobFromReader, creating its row with exec.CreateBlob when the
+// first message has no row_id. The blob must already be large enough for
+// the data. It fails with PermissionDenied in ReadOnly mode.
+func (s *Server) BlobUpload(stream Database_BlobUploadServer) error {
+	if s.opts.Mode != ReadWrite {
+		return status.Error(codes.PermissionDenied, "blob uploads are disabled in read-only mode")
+	}
+	ctx := stream.Context()
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return status.Error(codes.InvalidArgument, "empty upload")
+	}
+	if err != nil {
+		return err
+	}
+	if first.GetTable() == "" || first.GetColumn() == "" {
+		return status.Error(codes.InvalidArgument, "table and column are required")
+	}
+
+	rowID := first.GetRowId()
+	if rowID == 0 {
+		columns := make(map[string]interface{}, len(first.GetColumns()))
+		for name, value := range first.GetColumns() {
+			columns[name] = valueOf(value)
+		}
+		if rowID, err = exec.CreateBlob(ctx, first.GetTable(), first.GetColumn(), first.GetSize(), columns); err != nil {
+			return statusError(ctx, err)
+		}
+	}
+	r := &uploadReader{stream: stream, data: first.GetData()}
+	written, err := exec.WriteBlobFromReader(ctx, first.GetTable(), first.GetColumn(), rowID, first.GetOffset(), r)
+	if r.err != nil {
+		return r.err
+	}
+	if err != nil {
+		return statusError(ctx, err)
+	}
+	return stream.SendAndClose(&BlobUploadResponse{RowId: rowID, Written: written})
+}
+
+// BlobDownload streams a blob with exec.StreamReadBlob, one chunk per copy
+// buffer.
+func (s *Server) BlobDownload(req *BlobDownloadRequest, stream Database_BlobDownloadServer) error {
+	ctx := stream.Context()
+	if req.GetTable() == "" || req.GetColumn() == "" {
+		return status.Error(codes.InvalidArgument, "table and column are required")
+	}
+	length := int64(-1)
+	if req.Length != nil {
+		length = req.GetLength()
+	}
+	w := &downloadWriter{stream: stream}
+	_, err := exec.StreamReadBlob(ctx, req.GetTable(), req.GetColumn(), req.GetRowId(), req.GetOffset(), length, w)
+	if w.err != nil {
+		return w.err
+	}
+	return statusError(ctx, err)
+}
+
+// uploadReader reads the data of upload messages.
+type uploadReader struct {
+	stream Database_BlobUploadServer
+	data   []byte
+	// err is a receive error, kept apart from blob errors.
+	err error
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	for len(r.data) == 0 {
+		msg, err := r.stream.Recv()
+		if err == io.EOF {
+			return 0, io.EOF
+		}
+		if err != nil {
+			r.err = err
+			return 0, err
+		}
+		r.data = msg.GetData()
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// downloadWriter sends each write as a chunk.
+type downloadWriter struct {
+	stream Database_BlobDownloadServer
+	// err is a send error, kept apart from blob errors.
+	err error
+}
+
+func (w *downloadWriter) Write(p []byte) (int, error) {
+	// Send marshals the message before returning, so p may be reused.
+	if err := w.stream.Send(&BlobChunk{Data: p}); err != nil {
+		w.err = err
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// runStatement runs query on conn and collects its rows. With changes, it
+// also reports the rows the statement changed and the last insert rowid.
+func runStatement(conn *sqlite.Conn, query string, params map[string]interface{}, changes bool) (*Result, error) {
+	if strings.TrimSpace(query) == "" {
+		return nil, status.Error(codes.InvalidArgument, "sql is required")
+	}
+	var before int64
+	if changes {
+		var err error
+		if before, err = totalChanges(conn); err != nil {
+			return nil, err
+		}
+	}
+	result := &Result{}
+	var rowErr error
+	err := exec.QueryConn(conn, query, params, func(columns []string, values []interface{}) {
+		if rowErr != nil {
+			return
+		}
+		if result.Columns == nil {
+			result.Columns = columns
+		}
+		var row *Row
+		if row, rowErr = rowOf(values); rowErr == nil {
+			result.Rows = append(result.Rows, row)
+		}
+	})
+	if err == nil {
+		err = rowErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if changes {
+		// Changes() would report a previous statement's count for statements
+		// that do not write, so diff total_changes() instead.
+		after, err := totalChanges(conn)
+		if err != nil {
+			return nil, err
+		}
+		result.Changes = after - before
+		result.LastInsertId = conn.LastInsertRowID()
+	}
+	return result, nil
+}
+
+// totalChanges returns the number of rows changed by the connection since it was opened.
+func totalChanges(conn *sqlite.Conn) (int64, error) {
+	var n int64
+	err := exec.QueryConn(conn, "SELECT total_changes();", nil, func(_ []string, values []interface{}) {
+		n, _ = values[0].(int64)
+	})
+	return n, err
+}
+
+// statementParams converts the parameters of stmt. A name without a $, :
+// or @ prefix binds to all three forms, as in the httpapi package.
+func statementParams(stmt *Statement) (map[string]interface{}, error) {
+	params := make(map[string]interface{}, len(stmt.GetParams()))
+	for name, value := range stmt.GetParams() {
+		if name == "" {
+			return nil, status.Error(codes.InvalidArgument, "empty parameter name")
+		}
+		if strings.ContainsRune("$:@", rune(name[0])) {
+			params[name] = valueOf(value)
+			continue
+		}
+		for _, prefix := range []string{"$", ":", "@"} {
+			params[prefix+name] = valueOf(value)
+		}
+	}
+	return params, nil
+}
+
+// valueOf converts a Value to the Go value exec binds.
+func valueOf(v *Value) interface{} {
+	switch kind := v.GetKind().(type) {
+	case *Value_Integer:
+		return kind.Integer
+	case *Value_Real:
+		return kind.Real
+	case *Value_Text:
+		return kind.Text
+	case *Value_Blob:
+		if kind.Blob == nil {
+			return []byte{}
+		}
+		return kind.Blob
+	}
+	return nil
+}
+
+// rowOf converts the column values exec returns to a Row. Values converted
+// by exec.WithConversions are sent as what SQLite stores for them: times as
+// RFC 3339 text in UTC, as exec binds them, and bools as integers. Other
+// converted values cannot be sent.
+func rowOf(values []interface{}) (*Row, error) {
+	row := &Row{Values: make([]*Value, len(values))}
+	for i, v := range values {
+		switch v := v.(type) {
+		case nil:
+			row.Values[i] = &Value{Kind: &Value_Null{Null: true}}
+		case int64:
+			row.Values[i] = &Value{Kind: &Value_Integer{Integer: v}}
+		case float64:
+			row.Values[i] = &Value{Kind: &Value_Real{Real: v}}
+		case string:
+			row.Values[i] = &Value{Kind: &Value_Text{Text: v}}
+		case []byte:
+			row.Values[i] = &Value{Kind: &Value_Blob{Blob: v}}
+		case time.Time:
+			row.Values[i] = &Value{Kind: &Value_Text{Text: v.UTC().Format(time.RFC3339Nano)}}
+		case bool:
+			var n int64
+			if v {
+				n = 1
+			}
+			row.Values[i] = &Value{Kind: &Value_Integer{Integer: n}}
+		default:
+			return nil, fmt.Errorf("column %d: cannot send %T value", i, v)
+		}
+	}
+	return row, nil
+}
+
+// statusError converts err to a gRPC status with a code after its cause.
+func statusError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if ctx.Err() != nil {
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	code := codes.Unknown
+	switch {
+	case errors.Is(err, context.Canceled):
+		code = codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = codes.DeadlineExceeded
+	case errors.Is(err, sqliteutils.ErrPoolNotInitialized):
+		code = codes.Unavailable
+	case errors.Is(err, sqliteutils.ErrReadOnly):
+		code = codes.PermissionDenied
+	default:
+		switch sqlite.ErrCode(err).ToPrimary() {
+		case sqlite.ResultConstraint:
+			code = codes.FailedPrecondition
+		case sqlite.ResultBusy, sqlite.ResultLocked:
+			code = codes.Unavailable
+		case sqlite.ResultError, sqlite.ResultRange, sqlite.ResultMismatch:
+			code = codes.InvalidArgument
+		case sqlite.ResultInterrupt:
+			code = codes.Canceled
+		}
+	}
+	return status.Error(code, err.Error())
+}
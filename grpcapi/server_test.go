@@ -0,0 +1,223 @@
+package grpcapi_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/grpcapi"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const migration = `
+	CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL UNIQUE
+	);
+	INSERT INTO users (name) VALUES ('Alice'), ('Bob');
+	CREATE TABLE files (id INTEGER PRIMARY KEY, name TEXT, data BLOB);
+`
+
+// client serves opts on an in-memory listener and returns a client for it.
+func client(t *testing.T, opts grpcapi.Options) grpcapi.DatabaseClient {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	grpcapi.RegisterDatabaseServer(s, grpcapi.NewServer(opts))
+	go s.Serve(lis)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return grpcapi.NewDatabaseClient(conn)
+}
+
+func text(s string) *grpcapi.Value {
+	return &grpcapi.Value{Kind: &grpcapi.Value_Text{Text: s}}
+}
+
+func integer(i int64) *grpcapi.Value {
+	return &grpcapi.Value{Kind: &grpcapi.Value_Integer{Integer: i}}
+}
+
+func TestServer(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	db := client(t, grpcapi.Options{Mode: grpcapi.ReadWrite, BatchSize: 2})
+
+	result, err := db.Execute(ctx, &grpcapi.Statement{
+		Sql:    "INSERT INTO users (name) VALUES (:name);",
+		Params: map[string]*grpcapi.Value{"name": text("Carol")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), result.GetChanges())
+	assert.Equal(t, int64(3), result.GetLastInsertId())
+
+	result, err = db.Execute(ctx, &grpcapi.Statement{Sql: "SELECT id, name, NULL AS note FROM users WHERE id = $id;", Params: map[string]*grpcapi.Value{"$id": integer(2)}})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "name", "note"}, result.GetColumns())
+	require.Len(t, result.GetRows(), 1)
+	values := result.GetRows()[0].GetValues()
+	assert.Equal(t, int64(2), values[0].GetInteger())
+	assert.Equal(t, "Bob", values[1].GetText())
+	assert.True(t, values[2].GetNull())
+	assert.Equal(t, int64(0), result.GetChanges())
+
+	_, err = db.Execute(ctx, &grpcapi.Statement{Sql: "INSERT INTO users (name) VALUES ('Alice');"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = db.Execute(ctx, &grpcapi.Statement{Sql: "SELECT * FROM missing;"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// A failing statement rolls back the whole transaction.
+	_, err = db.ExecuteTx(ctx, &grpcapi.ExecuteTxRequest{
+		Mode: grpcapi.TxMode_TX_MODE_IMMEDIATE,
+		Statements: []*grpcapi.Statement{
+			{Sql: "INSERT INTO users (name) VALUES ('Dave');"},
+			{Sql: "INSERT INTO users (name) VALUES ('Bob');"},
+		},
+	})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	_, err = db.ExecuteTx(ctx, &grpcapi.ExecuteTxRequest{
+		Mode:       grpcapi.TxMode(9),
+		Statements: []*grpcapi.Statement{{Sql: "SELECT 1;"}},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	// An unset mode begins a deferred transaction.
+	tx, err := db.ExecuteTx(ctx, &grpcapi.ExecuteTxRequest{
+		Statements: []*grpcapi.Statement{
+			{Sql: "INSERT INTO users (name) VALUES ('Dave');"},
+			{Sql: "SELECT count(*) FROM users;"},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, tx.GetResults(), 2)
+	assert.Equal(t, int64(1), tx.GetResults()[0].GetChanges())
+	assert.Equal(t, int64(4), tx.GetResults()[1].GetRows()[0].GetValues()[0].GetInteger())
+
+	stream, err := db.StreamQuery(ctx, &grpcapi.Statement{Sql: "SELECT name FROM users ORDER BY id;"})
+	require.NoError(t, err)
+	var batches [][]string
+	var columns []string
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		columns = append(columns, resp.GetColumns()...)
+		var names []string
+		for _, row := range resp.GetRows() {
+			names = append(names, row.GetValues()[0].GetText())
+		}
+		batches = append(batches, names)
+	}
+	assert.Equal(t, []string{"name"}, columns)
+	assert.Equal(t, [][]string{{"Alice", "Bob"}, {"Carol", "Dave"}}, batches)
+
+	stream, err = db.StreamQuery(ctx, &grpcapi.Statement{Sql: "DELETE FROM users;"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	data := bytes.Repeat([]byte("0123456789"), 10000)
+	upload, err := db.BlobUpload(ctx)
+	require.NoError(t, err)
+	require.NoError(t, upload.Send(&grpcapi.BlobUploadRequest{
+		Table: "files", Column: "data", Size: int64(len(data)),
+		Columns: map[string]*grpcapi.Value{"name": text("digits.txt")},
+		Data:    data[:1000],
+	}))
+	require.NoError(t, upload.Send(&grpcapi.BlobUploadRequest{Data: data[1000:]}))
+	uploaded, err := upload.CloseAndRecv()
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), uploaded.GetWritten())
+
+	length := int64(15)
+	download, err := db.BlobDownload(ctx, &grpcapi.BlobDownloadRequest{Table: "files", Column: "data", RowId: uploaded.GetRowId(), Offset: 5, Length: &length})
+	require.NoError(t, err)
+	var got []byte
+	for {
+		chunk, err := download.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		got = append(got, chunk.GetData()...)
+	}
+	assert.Equal(t, data[5:20], got)
+}
+
+func TestServer_ReadOnly(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	db := client(t, grpcapi.Options{})
+
+	result, err := db.Execute(ctx, &grpcapi.Statement{Sql: "SELECT count(*) FROM users;"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.GetRows()[0].GetValues()[0].GetInteger())
+
+	_, err = db.Execute(ctx, &grpcapi.Statement{Sql: "DELETE FROM users;"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = db.ExecuteTx(ctx, &grpcapi.ExecuteTxRequest{Statements: []*grpcapi.Statement{{Sql: "SELECT 1;"}}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	upload, err := db.BlobUpload(ctx)
+	require.NoError(t, err)
+	_, err = upload.CloseAndRecv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestServer_ConvertedValues(t *testing.T) {
+	ctx := context.Background()
+	conversions := exec.DefaultConversions().Name("point", func(value interface{}) (interface{}, error) {
+		return struct{ X, Y int64 }{}, nil
+	})
+	require.NoError(t, test.Pool(ctx, t, `
+		CREATE TABLE posts (id INTEGER PRIMARY KEY, published DATETIME, archived BOOLEAN);
+		INSERT INTO posts VALUES (1, '2024-05-01 10:30:00', 1);
+	`, 1, exec.WithConversions(conversions)))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	db := client(t, grpcapi.Options{})
+
+	// Times are sent as the text exec binds them as, bools as integers.
+	const query = "SELECT published, archived FROM posts;"
+	result, err := db.Execute(ctx, &grpcapi.Statement{Sql: query})
+	require.NoError(t, err)
+	want := []*grpcapi.Value{text("2024-05-01T10:30:00Z"), integer(1)}
+	assert.Equal(t, want, result.GetRows()[0].GetValues())
+
+	stream, err := db.StreamQuery(ctx, &grpcapi.Statement{Sql: query})
+	require.NoError(t, err)
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, want, resp.GetRows()[0].GetValues())
+
+	// Values of other converted types are an error rather than NULL.
+	_, err = db.Execute(ctx, &grpcapi.Statement{Sql: "SELECT 1 AS point;"})
+	assert.ErrorContains(t, err, "cannot send")
+	stream, err = db.StreamQuery(ctx, &grpcapi.Statement{Sql: "SELECT 1 AS point;"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.ErrorContains(t, err, "cannot send")
+}
@@ -0,0 +1,295 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: sqliteutils.proto
+
+package grpcapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Database_Execute_FullMethodName      = "/sqliteutils.v1.Database/Execute"
+	Database_ExecuteTx_FullMethodName    = "/sqliteutils.v1.Database/ExecuteTx"
+	Database_StreamQuery_FullMethodName  = "/sqliteutils.v1.Database/StreamQuery"
+	Database_BlobUpload_FullMethodName   = "/sqliteutils.v1.Database/BlobUpload"
+	Database_BlobDownload_FullMethodName = "/sqliteutils.v1.Database/BlobDownload"
+)
+
+// DatabaseClient is the client API for Database service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Database runs statements and streams blobs on the database behind the
+// global pool of a sqliteutils process.
+type DatabaseClient interface {
+	// Execute runs one statement and returns all of its rows, the number of
+	// rows it changed and the last insert rowid.
+	Execute(ctx context.Context, in *Statement, opts ...grpc.CallOption) (*Result, error)
+	// ExecuteTx runs statements in order in one transaction. If any fails,
+	// none of them take effect.
+	ExecuteTx(ctx context.Context, in *ExecuteTxRequest, opts ...grpc.CallOption) (*ExecuteTxResponse, error)
+	// StreamQuery runs a read-only statement and streams its rows in batches.
+	StreamQuery(ctx context.Context, in *Statement, opts ...grpc.CallOption) (grpc.ServerStreamingClient[QueryResponse], error)
+	// BlobUpload writes the data of the streamed messages into a blob, after
+	// creating its row when the first message has no row_id.
+	BlobUpload(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[BlobUploadRequest, BlobUploadResponse], error)
+	// BlobDownload streams the contents of a blob.
+	BlobDownload(ctx context.Context, in *BlobDownloadRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BlobChunk], error)
+}
+
+type databaseClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewDatabaseClient(cc grpc.ClientConnInterface) DatabaseClient {
+	return &databaseClient{cc}
+}
+
+func (c *databaseClient) Execute(ctx context.Context, in *Statement, opts ...grpc.CallOption) (*Result, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Result)
+	err := c.cc.Invoke(ctx, Database_Execute_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) ExecuteTx(ctx context.Context, in *ExecuteTxRequest, opts ...grpc.CallOption) (*ExecuteTxResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ExecuteTxResponse)
+	err := c.cc.Invoke(ctx, Database_ExecuteTx_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *databaseClient) StreamQuery(ctx context.Context, in *Statement, opts ...grpc.CallOption) (grpc.ServerStreamingClient[QueryResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Database_ServiceDesc.Streams[0], Database_StreamQuery_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[Statement, QueryResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Database_StreamQueryClient = grpc.ServerStreamingClient[QueryResponse]
+
+func (c *databaseClient) BlobUpload(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[BlobUploadRequest, BlobUploadResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Database_ServiceDesc.Streams[1], Database_BlobUpload_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[BlobUploadRequest, BlobUploadResponse]{ClientStream: stream}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Database_BlobUploadClient = grpc.ClientStreamingClient[BlobUploadRequest, BlobUploadResponse]
+
+func (c *databaseClient) BlobDownload(ctx context.Context, in *BlobDownloadRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BlobChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Database_ServiceDesc.Streams[2], Database_BlobDownload_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[BlobDownloadRequest, BlobChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Database_BlobDownloadClient = grpc.ServerStreamingClient[BlobChunk]
+
+// DatabaseServer is the server API for Database service.
+// All implementations must embed UnimplementedDatabaseServer
+// for forward compatibility.
+//
+// Database runs statements and streams blobs on the database behind the
+// global pool of a sqliteutils process.
+type DatabaseServer interface {
+	// Execute runs one statement and returns all of its rows, the number of
+	// rows it changed and the last insert rowid.
+	Execute(context.Context, *Statement) (*Result, error)
+	// ExecuteTx runs statements in order in one transaction. If any fails,
+	// none of them take effect.
+	ExecuteTx(context.Context, *ExecuteTxRequest) (*ExecuteTxResponse, error)
+	// StreamQuery runs a read-only statement and streams its rows in batches.
+	StreamQuery(*Statement, grpc.ServerStreamingServer[QueryResponse]) error
+	// BlobUpload writes the data of the streamed messages into a blob, after
+	// creating its row when the first message has no row_id.
+	BlobUpload(grpc.ClientStreamingServer[BlobUploadRequest, BlobUploadResponse]) error
+	// BlobDownload streams the contents of a blob.
+	BlobDownload(*BlobDownloadRequest, grpc.ServerStreamingServer[BlobChunk]) error
+	mustEmbedUnimplementedDatabaseServer()
+}
+
+// UnimplementedDatabaseServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedDatabaseServer struct{}
+
+func (UnimplementedDatabaseServer) Execute(context.Context, *Statement) (*Result, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Execute not implemented")
+}
+func (UnimplementedDatabaseServer) ExecuteTx(context.Context, *ExecuteTxRequest) (*ExecuteTxResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ExecuteTx not implemented")
+}
+func (UnimplementedDatabaseServer) StreamQuery(*Statement, grpc.ServerStreamingServer[QueryResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamQuery not implemented")
+}
+func (UnimplementedDatabaseServer) BlobUpload(grpc.ClientStreamingServer[BlobUploadRequest, BlobUploadResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method BlobUpload not implemented")
+}
+func (UnimplementedDatabaseServer) BlobDownload(*BlobDownloadRequest, grpc.ServerStreamingServer[BlobChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method BlobDownload not implemented")
+}
+func (UnimplementedDatabaseServer) mustEmbedUnimplementedDatabaseServer() {}
+func (UnimplementedDatabaseServer) testEmbeddedByValue()                  {}
+
+// UnsafeDatabaseServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to DatabaseServer will
+// result in compilation errors.
+type UnsafeDatabaseServer interface {
+	mustEmbedUnimplementedDatabaseServer()
+}
+
+func RegisterDatabaseServer(s grpc.ServiceRegistrar, srv DatabaseServer) {
+	// If the following call pancis, it indicates UnimplementedDatabaseServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Database_ServiceDesc, srv)
+}
+
+func _Database_Execute_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Statement)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).Execute(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Database_Execute_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).Execute(ctx, req.(*Statement))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_ExecuteTx_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExecuteTxRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(DatabaseServer).ExecuteTx(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Database_ExecuteTx_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(DatabaseServer).ExecuteTx(ctx, req.(*ExecuteTxRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Database_StreamQuery_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Statement)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DatabaseServer).StreamQuery(m, &grpc.GenericServerStream[Statement, QueryResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Database_StreamQueryServer = grpc.ServerStreamingServer[QueryResponse]
+
+func _Database_BlobUpload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(DatabaseServer).BlobUpload(&grpc.GenericServerStream[BlobUploadRequest, BlobUploadResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Database_BlobUploadServer = grpc.ClientStreamingServer[BlobUploadRequest, BlobUploadResponse]
+
+func _Database_BlobDownload_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BlobDownloadRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(DatabaseServer).BlobDownload(m, &grpc.GenericServerStream[BlobDownloadRequest, BlobChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Database_BlobDownloadServer = grpc.ServerStreamingServer[BlobChunk]
+
+// Database_ServiceDesc is the grpc.ServiceDesc for Database service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Database_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "sqliteutils.v1.Database",
+	HandlerType: (*DatabaseServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Execute",
+			Handler:    _Database_Execute_Handler,
+		},
+		{
+			MethodName: "ExecuteTx",
+			Handler:    _Database_ExecuteTx_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamQuery",
+			Handler:       _Database_StreamQuery_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "BlobUpload",
+			Handler:       _Database_BlobUpload_Handler,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "BlobDownload",
+			Handler:       _Database_BlobDownload_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "sqliteutils.proto",
+}
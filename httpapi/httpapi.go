@@ -0,0 +1,384 @@
+// Package httpapi exposes the database behind the global pool over HTTP.
+//
+// The handler serves two endpoints that accept a JSON body of the form
+// {"sql": "...", "params": {"$name": "value"}}:
+//
+//	POST /query  runs a read-only statement and streams its rows
+//	POST /exec   runs any statement (ReadWrite mode only) and also reports
+//	             the number of changed rows and the last insert rowid
+//
+// Responses are streamed as a single JSON object:
+//
+//	{"columns": ["id", "name"], "types": ["INTEGER", "TEXT"], "rows": [[1, "Alice"], ...], "changes": 1, "last_insert_id": 7}
+//
+// "types" holds the declared type of each column, or "" for expressions,
+// as described by exec.Columns.
+//
+// An error before the first row is returned with status 400 as
+// {"error": "..."}; an error after rows have been sent is reported in an
+// "error" field at the end of the object.
+//
+// With Options.QueryLog set, GET /debug/queries also returns the statements
+// kept by sqliteutils.EnableQueryLog as {"queries": [...]}.
+package httpapi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Mode controls whether the handler accepts statements that write.
+type Mode int
+
+const (
+	// ReadOnly rejects /exec and runs every statement as exec.ReadOnly does,
+	// so writes, ATTACH and setting pragmas fail.
+	ReadOnly Mode = iota
+	// ReadWrite enables /exec. /query remains read-only.
+	ReadWrite
+)
+
+// DefaultMaxBodyBytes bounds the size of a request body when Options.MaxBodyBytes is zero.
+const DefaultMaxBodyBytes = 1 << 20
+
+// flushEvery is how many rows are written between flushes of a streamed response.
+const flushEvery = 100
+
+// Options configures a Handler.
+type Options struct {
+	Mode Mode
+	// Auth authenticates each request; a non-nil error rejects it with status
+	// 401. When nil every request is allowed.
+	Auth func(r *http.Request) error
+	// Timeout bounds each request's statement. Zero means only the request's
+	// own context applies.
+	Timeout time.Duration
+	// MaxBodyBytes bounds the request body. Defaults to DefaultMaxBodyBytes.
+	MaxBodyBytes int64
+	// Pool is the pool statements run on. Defaults to the global pool.
+	Pool *sqlitex.Pool
+	// QueryLog serves GET /debug/queries. The log exposes statements and
+	// parameters of every caller, so enable it only behind Auth.
+	QueryLog bool
+}
+
+// Request is the body of a /query or /exec request.
+type Request struct {
+	SQL    string          `json:"sql"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// Handler serves /query and /exec.
+type Handler struct {
+	opts Options
+	mux  *http.ServeMux
+}
+
+// NewHandler returns a Handler with the given options. Mount it under a
+// prefix with http.StripPrefix.
+func NewHandler(opts Options) *Handler {
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	h := &Handler{opts: opts, mux: http.NewServeMux()}
+	h.mux.HandleFunc("/query", func(w http.ResponseWriter, r *http.Request) { h.serve(w, r, true) })
+	h.mux.HandleFunc("/exec", func(w http.ResponseWriter, r *http.Request) { h.serve(w, r, false) })
+	if opts.QueryLog {
+		h.mux.HandleFunc("/debug/queries", serveQueryLog)
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.opts.Auth != nil {
+		if err := h.opts.Auth(r); err != nil {
+			writeError(w, http.StatusUnauthorized, err)
+			return
+		}
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// BearerToken returns an Auth function requiring "Authorization: Bearer token".
+func BearerToken(token string) func(r *http.Request) error {
+	return func(r *http.Request) error {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			return errors.New("invalid or missing bearer token")
+		}
+		return nil
+	}
+}
+
+func (h *Handler) serve(w http.ResponseWriter, r *http.Request, readOnly bool) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+		return
+	}
+	if !readOnly && h.opts.Mode != ReadWrite {
+		writeError(w, http.StatusForbidden, errors.New("exec is disabled in read-only mode"))
+		return
+	}
+	readOnly = readOnly || h.opts.Mode == ReadOnly
+
+	var req Request
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.opts.MaxBodyBytes)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if strings.TrimSpace(req.SQL) == "" {
+		writeError(w, http.StatusBadRequest, errors.New("sql is required"))
+		return
+	}
+	params, err := DecodeParams(req.Params)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	ctx := r.Context()
+	if h.opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.opts.Timeout)
+		defer cancel()
+	}
+
+	p := h.opts.Pool
+	if p == nil {
+		if p, err = pool.GetPool(); err != nil {
+			writeError(w, http.StatusServiceUnavailable, sqliteutils.FailedToGetPoolError(err))
+			return
+		}
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, sqliteutils.FailedToTakeConnectionFromPoolError(err))
+		return
+	}
+	defer p.Put(conn)
+
+	s := &stream{w: w, bw: bufio.NewWriter(w)}
+	if !readOnly {
+		if s.changesBefore, err = totalChanges(conn); err != nil {
+			writeError(w, http.StatusInternalServerError, err)
+			return
+		}
+	}
+	run := func(conn *sqlite.Conn) error {
+		// Clear the interrupt before returning so that undoing read-only
+		// mode cannot be canceled.
+		conn.SetInterrupt(ctx.Done())
+		defer conn.SetInterrupt(nil)
+		// A statement that fails to prepare is reported by QueryConn below.
+		if columns, err := exec.ColumnsConn(conn, req.SQL); err == nil {
+			for _, c := range columns {
+				s.names = append(s.names, c.Name)
+				s.types = append(s.types, c.DeclType)
+			}
+		}
+		if err := exec.QueryConn(conn, req.SQL, params, s.row); err != nil {
+			return err
+		}
+		return s.err
+	}
+	if readOnly {
+		err = exec.ReadOnlyConn(conn, func(tx *exec.Tx) error { return run(tx.Conn()) })
+	} else {
+		err = run(conn)
+	}
+	if err != nil && !s.started {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	s.finish(conn, readOnly, err)
+}
+
+// stream writes a response object row by row.
+type stream struct {
+	w       http.ResponseWriter
+	bw      *bufio.Writer
+	started bool
+	rows    int
+	err     error
+
+	// changesBefore is total_changes() before an /exec statement ran.
+	changesBefore int64
+	// names and types are the result columns and their declared types, as
+	// prepared before the statement ran.
+	names []string
+	types []string
+}
+
+func (s *stream) start(columns []string) {
+	s.started = true
+	s.w.Header().Set("Content-Type", "application/json")
+	s.w.WriteHeader(http.StatusOK)
+	if columns == nil {
+		columns = s.names
+	}
+	if columns == nil {
+		columns = []string{}
+	}
+	types := s.types
+	if len(types) != len(columns) {
+		types = make([]string, len(columns))
+	}
+	s.bw.WriteString(`{"columns":`)
+	s.writeJSON(columns)
+	s.bw.WriteString(`,"types":`)
+	s.writeJSON(types)
+	s.bw.WriteString(`,"rows":[`)
+}
+
+func (s *stream) row(columns []string, values []interface{}) {
+	if s.err != nil {
+		return
+	}
+	if !s.started {
+		s.start(columns)
+	}
+	if s.rows > 0 {
+		s.bw.WriteByte(',')
+	}
+	s.writeJSON(values)
+	s.rows++
+	if s.rows%flushEvery == 0 {
+		s.flush()
+	}
+}
+
+// finish closes the rows array and appends the write summary and any error.
+func (s *stream) finish(conn *sqlite.Conn, readOnly bool, err error) {
+	if !s.started {
+		s.start(nil)
+	}
+	s.bw.WriteByte(']')
+	if !readOnly {
+		// Changes() would report a previous statement's count for statements
+		// that do not write, so diff total_changes() instead.
+		changes, changesErr := totalChanges(conn)
+		if changesErr == nil {
+			fmt.Fprintf(s.bw, `,"changes":%d,"last_insert_id":%d`, changes-s.changesBefore, conn.LastInsertRowID())
+		} else if err == nil {
+			err = changesErr
+		}
+	}
+	if err != nil {
+		s.bw.WriteString(`,"error":`)
+		s.writeJSON(err.Error())
+	}
+	s.bw.WriteString("}\n")
+	s.flush()
+}
+
+func (s *stream) writeJSON(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		if s.err == nil {
+			s.err = err
+		}
+		data = []byte("null")
+	}
+	s.bw.Write(data)
+}
+
+func (s *stream) flush() {
+	if err := s.bw.Flush(); err != nil && s.err == nil {
+		s.err = err
+	}
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// DecodeParams decodes a JSON object of statement parameters. Whole numbers
+// bind as integers and arrays and objects as their JSON text. A name without
+// a $, : or @ prefix binds to all three forms.
+func DecodeParams(data json.RawMessage) (map[string]interface{}, error) {
+	params := map[string]interface{}{}
+	if len(data) == 0 || string(data) == "null" {
+		return params, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	dec.UseNumber()
+	var values map[string]interface{}
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+	for name, value := range values {
+		switch v := value.(type) {
+		case json.Number:
+			if i, err := v.Int64(); err == nil {
+				value = i
+			} else if f, err := v.Float64(); err == nil {
+				value = f
+			} else {
+				value = v.String()
+			}
+		case map[string]interface{}, []interface{}:
+			var buf bytes.Buffer
+			enc := json.NewEncoder(&buf)
+			enc.SetEscapeHTML(false)
+			if err := enc.Encode(v); err != nil {
+				return nil, fmt.Errorf("invalid param %s: %w", name, err)
+			}
+			value = strings.TrimSuffix(buf.String(), "\n")
+		}
+		if name != "" && strings.ContainsRune("$:@", rune(name[0])) {
+			params[name] = value
+			continue
+		}
+		for _, prefix := range []string{"$", ":", "@"} {
+			params[prefix+name] = value
+		}
+	}
+	return params, nil
+}
+
+// totalChanges returns the number of rows changed by the connection since it was opened.
+func totalChanges(conn *sqlite.Conn) (int64, error) {
+	var n int64
+	err := sqlitex.ExecuteTransient(conn, "SELECT total_changes();", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			n = stmt.ColumnInt64(0)
+			return nil
+		},
+	})
+	return n, err
+}
+
+// serveQueryLog writes the recent statements of the query log.
+func serveQueryLog(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+		return
+	}
+	queries := sqliteutils.RecentQueries()
+	if queries == nil {
+		queries = []sqliteutils.QueryLogEntry{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"queries": queries})
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,173 @@
+package httpapi_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/httpapi"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const migration = `
+	CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL
+	);
+	INSERT INTO users (name) VALUES ('Alice'), ('Bob');
+`
+
+type response struct {
+	Columns      []string        `json:"columns"`
+	Types        []string        `json:"types"`
+	Rows         [][]interface{} `json:"rows"`
+	Changes      *int64          `json:"changes"`
+	LastInsertID *int64          `json:"last_insert_id"`
+	Error        string          `json:"error"`
+}
+
+func post(t *testing.T, h http.Handler, path, body, token string) (int, response) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var resp response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp), rec.Body.String())
+	return rec.Code, resp
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	t.Run("Query", func(t *testing.T) {
+		h := httpapi.NewHandler(httpapi.Options{})
+		code, resp := post(t, h, "/query", `{"sql": "SELECT id, name FROM users WHERE id >= $min ORDER BY id", "params": {"min": 1}}`, "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, []string{"id", "name"}, resp.Columns)
+		assert.Equal(t, []string{"INTEGER", "TEXT"}, resp.Types)
+		assert.Equal(t, [][]interface{}{{float64(1), "Alice"}, {float64(2), "Bob"}}, resp.Rows)
+		assert.Nil(t, resp.Changes)
+
+		code, resp = post(t, h, "/query", `{"sql": "SELECT *, 1 AS one FROM users WHERE id < 0"}`, "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Empty(t, resp.Rows)
+		assert.Equal(t, []string{"id", "name", "one"}, resp.Columns)
+		assert.Equal(t, []string{"INTEGER", "TEXT", ""}, resp.Types)
+	})
+
+	t.Run("ReadOnly", func(t *testing.T) {
+		h := httpapi.NewHandler(httpapi.Options{})
+		code, resp := post(t, h, "/exec", `{"sql": "DELETE FROM users"}`, "")
+		assert.Equal(t, http.StatusForbidden, code)
+		assert.NotEmpty(t, resp.Error)
+
+		code, resp = post(t, httpapi.NewHandler(httpapi.Options{Mode: httpapi.ReadWrite}), "/query", `{"sql": "DELETE FROM users"}`, "")
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, resp.Error, "read-only")
+	})
+
+	t.Run("Exec", func(t *testing.T) {
+		h := httpapi.NewHandler(httpapi.Options{Mode: httpapi.ReadWrite})
+		code, resp := post(t, h, "/exec", `{"sql": "INSERT INTO users (name) VALUES (:name)", "params": {"name": "Carol"}}`, "")
+		assert.Equal(t, http.StatusOK, code)
+		require.NotNil(t, resp.Changes)
+		assert.Equal(t, int64(1), *resp.Changes)
+		assert.Equal(t, int64(3), *resp.LastInsertID)
+
+		code, resp = post(t, h, "/exec", `{"sql": "UPDATE users SET name = upper(name) RETURNING name"}`, "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Len(t, resp.Rows, 3)
+		assert.Equal(t, int64(3), *resp.Changes)
+
+		code, resp = post(t, h, "/exec", `{"sql": "SELECT 1"}`, "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, int64(0), *resp.Changes)
+	})
+
+	t.Run("Auth", func(t *testing.T) {
+		h := httpapi.NewHandler(httpapi.Options{Auth: httpapi.BearerToken("s3cret")})
+		code, _ := post(t, h, "/query", `{"sql": "SELECT 1"}`, "")
+		assert.Equal(t, http.StatusUnauthorized, code)
+		code, _ = post(t, h, "/query", `{"sql": "SELECT 1"}`, "wrong")
+		assert.Equal(t, http.StatusUnauthorized, code)
+		code, _ = post(t, h, "/query", `{"sql": "SELECT 1"}`, "s3cret")
+		assert.Equal(t, http.StatusOK, code)
+	})
+
+	t.Run("QueryLog", func(t *testing.T) {
+		sqliteutils.EnableQueryLog(10)
+		defer sqliteutils.EnableQueryLog(0)
+
+		h := httpapi.NewHandler(httpapi.Options{QueryLog: true})
+		code, _ := post(t, h, "/query", `{"sql": "SELECT name FROM users WHERE id = $id", "params": {"id": 2}}`, "")
+		assert.Equal(t, http.StatusOK, code)
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/queries", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		var log struct {
+			Queries []sqliteutils.QueryLogEntry `json:"queries"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &log))
+		require.Len(t, log.Queries, 1)
+		assert.Equal(t, "SELECT name FROM users WHERE id = $id", log.Queries[0].SQL)
+		assert.Equal(t, "2", log.Queries[0].Params["$id"])
+
+		rec = httptest.NewRecorder()
+		httpapi.NewHandler(httpapi.Options{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/queries", nil))
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+	})
+
+	t.Run("BadRequests", func(t *testing.T) {
+		h := httpapi.NewHandler(httpapi.Options{})
+		code, _ := post(t, h, "/query", `{"sql": ""}`, "")
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _ = post(t, h, "/query", `not json`, "")
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, resp := post(t, h, "/query", `{"sql": "SELECT * FROM missing"}`, "")
+		assert.Equal(t, http.StatusBadRequest, code)
+		assert.Contains(t, resp.Error, "no such table")
+	})
+}
+
+func TestHandler_ReadOnlyLeavesConnection(t *testing.T) {
+	ctx := context.Background()
+	// One connection, so every request runs on the same one.
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	h := httpapi.NewHandler(httpapi.Options{})
+	for _, sql := range []string{
+		"PRAGMA foreign_keys = OFF",
+		"ATTACH DATABASE ':memory:' AS other",
+		"INSERT INTO users (name) VALUES ('Mallory')",
+	} {
+		code, resp := post(t, h, "/query", `{"sql": "`+sql+`"}`, "")
+		assert.Equal(t, http.StatusBadRequest, code, sql)
+		assert.Contains(t, resp.Error, "read-only", sql)
+	}
+
+	code, resp := post(t, h, "/query", `{"sql": "SELECT foreign_keys, (SELECT group_concat(name) FROM pragma_database_list) FROM pragma_foreign_keys"}`, "")
+	require.Equal(t, http.StatusOK, code, resp.Error)
+	assert.Equal(t, [][]interface{}{{float64(1), "main"}}, resp.Rows)
+
+	// Writes still work on the connection once read-only requests are done.
+	code, resp = post(t, httpapi.NewHandler(httpapi.Options{Mode: httpapi.ReadWrite}), "/exec", `{"sql": "INSERT INTO users (name) VALUES ('Carol')"}`, "")
+	assert.Equal(t, http.StatusOK, code, resp.Error)
+}
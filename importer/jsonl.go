@@ -0,0 +1,208 @@
+// Package importer loads data files into tables of the global pool in
+// batched transactions.
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+)
+
+// JSONLOptions configures ReadJSONL.
+type JSONLOptions struct {
+	// BatchSize is how many rows are inserted per transaction. Defaults to
+	// 1000.
+	BatchSize int
+	// Columns renames JSON keys to column names. Keys mapped to "-" are
+	// skipped.
+	Columns map[string]string
+	// CreateColumns adds a column for every key the table lacks, and creates
+	// the table if it does not exist, typed after the key's first value.
+	// Otherwise such keys are an error.
+	CreateColumns bool
+}
+
+// ReadJSONL inserts a row into table for each JSON object in r, one per
+// line, mapping keys to columns of the same name. Numbers bind as integers
+// when they are whole and floats otherwise, booleans as 1 or 0, and nested
+// objects and arrays as JSON text. Keys missing from an object leave their
+// columns to their defaults. Blank lines are skipped. opts may be nil.
+//
+// ReadJSONL returns the number of rows inserted. When it fails, the rows of
+// the batch being inserted are rolled back and earlier batches are kept.
+func ReadJSONL(ctx context.Context, table string, r io.Reader, opts *JSONLOptions) (int64, error) {
+	if opts == nil {
+		opts = &JSONLOptions{}
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	columns, err := tableColumns(ctx, table)
+	if err != nil {
+		return 0, err
+	}
+
+	var inserted int64
+	var queries []string
+	var params []map[string]interface{}
+	flush := func() error {
+		if len(queries) == 0 {
+			return nil
+		}
+		if err := exec.ExecMultiTxMode(ctx, exec.TxImmediate, queries, params, nil); err != nil {
+			return err
+		}
+		inserted += int64(len(queries))
+		queries, params = queries[:0], params[:0]
+		return nil
+	}
+
+	br := bufio.NewReader(r)
+	for line := 1; ; line++ {
+		text, readErr := br.ReadBytes('\n')
+		if readErr != nil && !errors.Is(readErr, io.EOF) {
+			return inserted, readErr
+		}
+		if len(bytes.TrimSpace(text)) > 0 {
+			row, err := decodeObject(text)
+			if err != nil {
+				return inserted, fmt.Errorf("line %d: %w", line, err)
+			}
+			query, rowParams, err := insertRow(ctx, table, columns, row, opts)
+			if err != nil {
+				return inserted, fmt.Errorf("line %d: %w", line, err)
+			}
+			queries = append(queries, query)
+			params = append(params, rowParams)
+			if len(queries) >= batchSize {
+				if err := flush(); err != nil {
+					return inserted, err
+				}
+			}
+		}
+		if readErr != nil {
+			break
+		}
+	}
+	return inserted, flush()
+}
+
+// tableColumns returns the set of table's column names, empty if the table
+// does not exist.
+func tableColumns(ctx context.Context, table string) (map[string]bool, error) {
+	columns := map[string]bool{}
+	err := exec.Query(ctx, "SELECT name FROM pragma_table_info(:table);", map[string]interface{}{":table": table},
+		func(_ []string, values []interface{}) {
+			name, _ := values[0].(string)
+			columns[name] = true
+		})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	return columns, nil
+}
+
+// decodeObject decodes one JSON object into bindable values.
+func decodeObject(text []byte) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(text))
+	dec.UseNumber()
+	var row map[string]interface{}
+	if err := dec.Decode(&row); err != nil {
+		return nil, err
+	}
+	if row == nil {
+		return nil, errors.New("line is not a JSON object")
+	}
+	for key, v := range row {
+		switch v := v.(type) {
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				row[key] = n
+			} else if f, err := v.Float64(); err == nil {
+				row[key] = f
+			} else {
+				return nil, fmt.Errorf("key %q: %w", key, err)
+			}
+		case map[string]interface{}, []interface{}:
+			b, err := json.Marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			row[key] = string(b)
+		}
+	}
+	return row, nil
+}
+
+// insertRow returns the INSERT statement and parameters for row, adding
+// missing columns to table first if opts allow it.
+func insertRow(ctx context.Context, table string, columns map[string]bool, row map[string]interface{}, opts *JSONLOptions) (string, map[string]interface{}, error) {
+	keys := make([]string, 0, len(row))
+	for key := range row {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var names, placeholders []string
+	params := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		column := key
+		if mapped, ok := opts.Columns[key]; ok {
+			column = mapped
+		}
+		if column == "-" {
+			continue
+		}
+		if !columns[column] {
+			if !opts.CreateColumns {
+				return "", nil, fmt.Errorf("%s has no column %q", table, column)
+			}
+			if err := addColumn(ctx, table, column, row[key], len(columns) == 0); err != nil {
+				return "", nil, err
+			}
+			columns[column] = true
+		}
+		name := fmt.Sprintf(":p%d", len(names))
+		names = append(names, sqliteutils.QuoteIdentifier(column))
+		placeholders = append(placeholders, name)
+		params[name] = row[key]
+	}
+	if len(names) == 0 {
+		return "INSERT INTO " + sqliteutils.QuoteIdentifier(table) + " DEFAULT VALUES;", nil, nil
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", sqliteutils.QuoteIdentifier(table),
+		strings.Join(names, ", "), strings.Join(placeholders, ", "))
+	return query, params, nil
+}
+
+// addColumn adds column to table, or creates table with it, typed after
+// value.
+func addColumn(ctx context.Context, table, column string, value interface{}, create bool) error {
+	def := sqliteutils.QuoteIdentifier(column)
+	switch value.(type) {
+	case int64, bool:
+		def += " INTEGER"
+	case float64:
+		def += " REAL"
+	case string:
+		def += " TEXT"
+	}
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", sqliteutils.QuoteIdentifier(table), def)
+	if create {
+		query = fmt.Sprintf("CREATE TABLE %s (%s);", sqliteutils.QuoteIdentifier(table), def)
+	}
+	if err := exec.Exec(ctx, query, nil, nil); err != nil {
+		return fmt.Errorf("failed to add column %q to %s: %w", column, table, err)
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package importer_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/export"
+	"github.com/dropsite-ai/sqliteutils/importer"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadJSONL(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE events (id INTEGER PRIMARY KEY, kind TEXT);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	input := `{"id": 1, "kind": "click", "score": 1.5, "ok": true, "tags": ["a", "b"], "internal": "x"}
+
+{"id": 2, "type": "view"}
+`
+	n, err := importer.ReadJSONL(ctx, "events", strings.NewReader(input), &importer.JSONLOptions{
+		BatchSize:     1,
+		Columns:       map[string]string{"type": "kind", "internal": "-"},
+		CreateColumns: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+
+	var out bytes.Buffer
+	_, err = export.WriteJSONL(ctx, "SELECT id, kind, score, ok, tags FROM events ORDER BY id;", nil, &out)
+	require.NoError(t, err)
+	assert.Equal(t, `{"id":1,"kind":"click","score":1.5,"ok":1,"tags":"[\"a\",\"b\"]"}
+{"id":2,"kind":"view","score":null,"ok":null,"tags":null}
+`, out.String())
+
+	var types []interface{}
+	require.NoError(t, exec.Query(ctx, "SELECT type FROM pragma_table_info('events') ORDER BY cid;", nil, func(_ []string, values []interface{}) {
+		types = append(types, values[0])
+	}))
+	assert.Equal(t, []interface{}{"INTEGER", "TEXT", "INTEGER", "REAL", "TEXT"}, types)
+
+	// Unknown keys are an error unless columns may be created; rows of the
+	// failing batch are rolled back.
+	n, err = importer.ReadJSONL(ctx, "events", strings.NewReader(`{"id": 3}`+"\n"+`{"id": 4, "extra": 1}`), nil)
+	assert.Error(t, err)
+	assert.Equal(t, int64(0), n)
+	_, err = importer.ReadJSONL(ctx, "events", strings.NewReader(`{"id": 5}`+"\n"+`[1]`), nil)
+	assert.ErrorContains(t, err, "line 2")
+
+	// A missing table is created from the first object.
+	n, err = importer.ReadJSONL(ctx, "logs", strings.NewReader(`{"msg": "<hi>", "n": 2}`), &importer.JSONLOptions{CreateColumns: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	out.Reset()
+	_, err = export.WriteJSONL(ctx, "SELECT msg, n FROM logs;", nil, &out)
+	require.NoError(t, err)
+	assert.Equal(t, "{\"msg\":\"<hi>\",\"n\":2}\n", out.String())
+}
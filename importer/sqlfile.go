@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"zombiezen.com/go/sqlite"
+)
+
+// ScriptError is returned by RunSQLFile for a failing statement.
+type ScriptError struct {
+	// Line is the 1-based line of the script on which the statement starts.
+	Line int
+	// SQL is the statement text.
+	SQL string
+	Err error
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ScriptError) Unwrap() error {
+	return e.Err
+}
+
+// unwrappedKeywords begin statements that control transactions themselves or
+// cannot run inside one.
+var unwrappedKeywords = map[string]bool{
+	"BEGIN":     true,
+	"COMMIT":    true,
+	"END":       true,
+	"ROLLBACK":  true,
+	"SAVEPOINT": true,
+	"RELEASE":   true,
+	"VACUUM":    true,
+	"ATTACH":    true,
+	"DETACH":    true,
+}
+
+// RunSQLFile executes the SQL script read from r, such as a dump written by
+// export.Dump, on one connection of the global pool. The script is split
+// with sqliteutils.SplitStatements, so semicolons inside string literals,
+// quoted identifiers, comments and trigger bodies do not end a statement.
+//
+// The statements run in one IMMEDIATE transaction, so a failing script
+// changes nothing, unless the script holds statements that manage
+// transactions themselves, such as BEGIN and COMMIT, or that cannot run
+// inside one, such as VACUUM; then it runs as written and a transaction it
+// leaves open is rolled back. The foreign_keys setting of the connection is
+// restored afterwards, as dumps turn it off.
+//
+// A failing statement is reported as a *ScriptError with its line. The whole
+// script is read into memory before it runs.
+func RunSQLFile(ctx context.Context, r io.Reader) (err error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read script: %w", err)
+	}
+	script := string(data)
+	statements, rest := sqliteutils.SplitStatements(script)
+	if rest != "" {
+		line := strings.Count(script[:len(script)-len(rest)], "\n") + 1
+		statements = append(statements, sqliteutils.Statement{SQL: rest, Line: line})
+	}
+
+	pin, err := exec.Pin(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err = errors.Join(err, pin.Release())
+	}()
+
+	var foreignKeys int64
+	err = pin.Query("PRAGMA foreign_keys;", nil, func(_ []string, values []interface{}) {
+		foreignKeys, _ = values[0].(int64)
+	})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// Roll back first: foreign_keys cannot change inside a transaction.
+		restoreErr := pin.Do(func(conn *sqlite.Conn) error {
+			if !conn.AutocommitEnabled() {
+				if err := exec.ExecConn(conn, "ROLLBACK;", nil, nil); err != nil {
+					return err
+				}
+			}
+			return exec.ExecConn(conn, fmt.Sprintf("PRAGMA foreign_keys = %d;", foreignKeys), nil, nil)
+		})
+		err = errors.Join(err, restoreErr)
+	}()
+
+	for _, stmt := range statements {
+		if unwrappedKeywords[firstKeyword(stmt.SQL)] {
+			return runStatements(pin.Exec, statements)
+		}
+	}
+	tx, err := pin.Begin(exec.TxImmediate)
+	if err != nil {
+		return err
+	}
+	if err := runStatements(tx.Exec, statements); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
+
+// runStatements executes statements in order with execute, stopping at the
+// first that fails.
+func runStatements(execute func(string, map[string]interface{}, func(int, map[string]interface{})) error, statements []sqliteutils.Statement) error {
+	for _, stmt := range statements {
+		if err := execute(stmt.SQL, nil, nil); err != nil {
+			var stmtErr *exec.StatementError
+			if errors.As(err, &stmtErr) {
+				err = stmtErr.Err
+			}
+			return &ScriptError{Line: stmt.Line, SQL: stmt.SQL, Err: err}
+		}
+	}
+	return nil
+}
+
+// firstKeyword returns the first word of sql in upper case.
+func firstKeyword(sql string) string {
+	word := strings.TrimSpace(sql)
+	if i := strings.IndexFunc(word, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	}); i >= 0 {
+		word = word[:i]
+	}
+	return strings.ToUpper(word)
+}
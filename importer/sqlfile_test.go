@@ -0,0 +1,69 @@
+package importer_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/export"
+	"github.com/dropsite-ai/sqliteutils/importer"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func count(ctx context.Context, t *testing.T, query string) int64 {
+	var n int64
+	require.NoError(t, exec.Q(ctx, query, func(_ []string, values []interface{}) {
+		n = values[0].(int64)
+	}))
+	return n
+}
+
+func TestRunSQLFile(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	script := `-- notes; with semicolons
+CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT);
+CREATE TABLE log (msg TEXT);
+CREATE TRIGGER notes_log AFTER INSERT ON notes BEGIN
+	INSERT INTO log VALUES ('added; ' || new.body);
+END;
+INSERT INTO notes (body) VALUES ('a;b'), ('it''s; fine');
+/* trailing; comment */`
+	require.NoError(t, importer.RunSQLFile(ctx, strings.NewReader(script)))
+	assert.Equal(t, int64(2), count(ctx, t, "SELECT count(*) FROM notes;"))
+	assert.Equal(t, int64(2), count(ctx, t, "SELECT count(*) FROM log WHERE msg LIKE 'added; %';"))
+
+	// A failing statement rolls back the whole script and reports its line.
+	err := importer.RunSQLFile(ctx, strings.NewReader("INSERT INTO notes (body) VALUES ('c');\n\nINSERT INTO missing VALUES (1);"))
+	var scriptErr *importer.ScriptError
+	require.ErrorAs(t, err, &scriptErr)
+	assert.Equal(t, 3, scriptErr.Line)
+	assert.Equal(t, "INSERT INTO missing VALUES (1);", scriptErr.SQL)
+	assert.Equal(t, int64(2), count(ctx, t, "SELECT count(*) FROM notes;"))
+
+	// A dump manages its own transaction and turns foreign keys off; both
+	// are undone on failure.
+	var dump bytes.Buffer
+	require.NoError(t, export.Dump(ctx, &dump, nil))
+	require.NoError(t, exec.E(ctx, "DROP TABLE notes;"))
+	require.NoError(t, exec.E(ctx, "DROP TABLE log;"))
+	require.NoError(t, importer.RunSQLFile(ctx, &dump))
+	assert.Equal(t, int64(2), count(ctx, t, "SELECT count(*) FROM notes;"))
+	assert.Equal(t, int64(2), count(ctx, t, "SELECT count(*) FROM log;"))
+	assert.Equal(t, int64(1), count(ctx, t, "PRAGMA foreign_keys;"))
+
+	err = importer.RunSQLFile(ctx, strings.NewReader("PRAGMA foreign_keys=OFF;\nBEGIN;\nDELETE FROM notes;\nSELECT * FROM missing;"))
+	require.ErrorAs(t, err, &scriptErr)
+	assert.Equal(t, 4, scriptErr.Line)
+	assert.Equal(t, int64(2), count(ctx, t, "SELECT count(*) FROM notes;"))
+	assert.Equal(t, int64(1), count(ctx, t, "PRAGMA foreign_keys;"))
+}
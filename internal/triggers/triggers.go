@@ -0,0 +1,82 @@
+// Package triggers holds the helpers shared by the packages that record row
+// changes with triggers, such as audit and cdc.
+package triggers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// JSONObject builds a json_object() call over the columns of the OLD or NEW
+// row. Blobs are stored as hex text, since JSON has no binary type.
+func JSONObject(ref string, columns []string) string {
+	args := make([]string, 0, 2*len(columns))
+	for _, column := range columns {
+		col := ref + "." + sqliteutils.QuoteIdentifier(column)
+		args = append(args, QuoteLiteral(column),
+			fmt.Sprintf("CASE typeof(%s) WHEN 'blob' THEN hex(%s) ELSE %s END", col, col, col))
+	}
+	return "json_object(" + strings.Join(args, ", ") + ")"
+}
+
+// QuoteLiteral quotes s as an SQL string literal.
+func QuoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// TableColumns returns the columns of table, leaving out the excluded ones,
+// compared without regard to case.
+func TableColumns(conn *sqlite.Conn, table string, exclude []string) ([]string, error) {
+	excluded := make(map[string]bool, len(exclude))
+	for _, name := range exclude {
+		excluded[strings.ToLower(name)] = true
+	}
+	var columns []string
+	found := false
+	err := sqlitex.ExecuteTransient(conn, "SELECT name FROM pragma_table_info(?);", &sqlitex.ExecOptions{
+		Args: []interface{}{table},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			found = true
+			if name := stmt.ColumnText(0); !excluded[strings.ToLower(name)] {
+				columns = append(columns, name)
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	if !found {
+		return nil, fmt.Errorf("no such table: %s", table)
+	}
+	return columns, nil
+}
+
+// DecodeValues decodes the JSONObject stored in column col of stmt, giving
+// integers as int64 and other numbers as float64. NULL decodes to nil.
+func DecodeValues(stmt *sqlite.Stmt, col int) (map[string]interface{}, error) {
+	if stmt.ColumnType(col) == sqlite.TypeNull {
+		return nil, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(stmt.ColumnText(col)))
+	dec.UseNumber()
+	var values map[string]interface{}
+	if err := dec.Decode(&values); err != nil {
+		return nil, fmt.Errorf("failed to decode row values: %w", err)
+	}
+	for k, v := range values {
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				values[k] = i
+			} else if f, err := n.Float64(); err == nil {
+				values[k] = f
+			}
+		}
+	}
+	return values, nil
+}
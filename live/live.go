@@ -0,0 +1,384 @@
+// Package live streams the row changes of a cdc.Capture to browsers over
+// Server-Sent Events or WebSocket, for live-updating UIs.
+//
+// Clients subscribe to tables with the tables query parameter, e.g.
+// GET /changes?tables=orders,users, and receive each change as a JSON
+// message:
+//
+//	{"type": "change", "id": 42, "table": "orders", "row_id": 7, "op": "update",
+//	 "old": {...}, "new": {...}, "time": "2024-05-01T12:00:00Z"}
+//
+// The id of a change is its resume token. A client that reconnects with it,
+// in the Last-Event-ID header of an SSE request or the since query
+// parameter, first receives the changes it missed from _cdc_changes. If
+// those changes have been pruned, it receives a "reset" message instead,
+// whose id is the latest change, and should reload its data.
+package live
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/cdc"
+	"golang.org/x/net/websocket"
+)
+
+// Message types.
+const (
+	TypeChange = "change"
+	TypeReset  = "reset"
+)
+
+// Message is a change, or a reset after a resume token that can no longer
+// be replayed, as sent to clients.
+type Message struct {
+	Type  string                 `json:"type"`
+	ID    int64                  `json:"id"`
+	Table string                 `json:"table,omitempty"`
+	RowID int64                  `json:"row_id,omitempty"`
+	Op    cdc.Op                 `json:"op,omitempty"`
+	Old   map[string]interface{} `json:"old,omitempty"`
+	New   map[string]interface{} `json:"new,omitempty"`
+	Time  *time.Time             `json:"time,omitempty"`
+}
+
+// Options configures a Handler.
+type Options struct {
+	// Tables limits the tables clients may subscribe to. When empty, any
+	// table the database records changes for is allowed.
+	Tables []string
+	// Auth authenticates each request; a non-nil error rejects it with status
+	// 401. When nil every request is allowed.
+	Auth func(r *http.Request) error
+	// CheckOrigin accepts or rejects the Origin of a WebSocket handshake.
+	// When nil, only same-host origins and requests without one are allowed.
+	CheckOrigin func(r *http.Request) bool
+	// Buffer is how many changes are queued per subscriber. A subscriber
+	// that falls further behind catches up from _cdc_changes instead.
+	// Defaults to 256.
+	Buffer int
+	// KeepAlive is how often an idle stream sends an SSE comment or a
+	// WebSocket ping. Defaults to 15s.
+	KeepAlive time.Duration
+}
+
+// Handler streams the changes delivered by a cdc.Capture.
+type Handler struct {
+	opts    Options
+	allowed map[string]bool
+
+	mu   sync.Mutex
+	subs map[*subscriber]struct{}
+}
+
+// subscriber is one client's queue of changes.
+type subscriber struct {
+	tables map[string]bool
+	events chan cdc.Event
+	// lagged is set when a change did not fit in events.
+	lagged atomic.Bool
+}
+
+// NewHandler returns a Handler streaming the changes of capture. It
+// registers a callback with the capture, so create one Handler per Capture.
+// Changes replayed for resuming clients are read from _cdc_changes, so a
+// capture with Prune set only lets clients resume while they are connected.
+func NewHandler(capture *cdc.Capture, opts Options) *Handler {
+	if opts.Buffer <= 0 {
+		opts.Buffer = 256
+	}
+	if opts.KeepAlive <= 0 {
+		opts.KeepAlive = 15 * time.Second
+	}
+	h := &Handler{opts: opts, subs: map[*subscriber]struct{}{}}
+	if len(opts.Tables) > 0 {
+		h.allowed = map[string]bool{}
+		for _, table := range opts.Tables {
+			h.allowed[table] = true
+		}
+	}
+	capture.OnChange(h.publish)
+	return h
+}
+
+// publish queues e for every subscriber of its table without blocking the
+// capture.
+func (h *Handler) publish(e cdc.Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subs {
+		if len(sub.tables) > 0 && !sub.tables[e.Table] {
+			continue
+		}
+		select {
+		case sub.events <- e:
+		default:
+			sub.lagged.Store(true)
+		}
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.opts.Auth != nil {
+		if err := h.opts.Auth(r); err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+	}
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "use GET", http.StatusMethodNotAllowed)
+		return
+	}
+	tables, err := h.tables(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	since, err := resumeToken(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		websocket.Server{
+			Handshake: h.handshake,
+			Handler: func(ws *websocket.Conn) {
+				h.serveWebSocket(ws, tables, since)
+			},
+		}.ServeHTTP(w, r)
+		return
+	}
+	h.serveSSE(w, r, tables, since)
+}
+
+// tables returns the tables requested by r, checked against Options.Tables.
+func (h *Handler) tables(r *http.Request) ([]string, error) {
+	var tables []string
+	for _, param := range r.URL.Query()["tables"] {
+		for _, table := range strings.Split(param, ",") {
+			if table = strings.TrimSpace(table); table != "" {
+				tables = append(tables, table)
+			}
+		}
+	}
+	if h.allowed == nil {
+		return tables, nil
+	}
+	if len(tables) == 0 {
+		return h.opts.Tables, nil
+	}
+	for _, table := range tables {
+		if !h.allowed[table] {
+			return nil, fmt.Errorf("table %q is not streamed", table)
+		}
+	}
+	return tables, nil
+}
+
+// resumeToken returns the change id a client resumes after, or -1 for a new
+// subscription.
+func resumeToken(r *http.Request) (int64, error) {
+	token := r.Header.Get("Last-Event-ID")
+	if token == "" {
+		token = r.URL.Query().Get("since")
+	}
+	if token == "" {
+		return -1, nil
+	}
+	id, err := strconv.ParseInt(token, 10, 64)
+	if err != nil || id < 0 {
+		return 0, fmt.Errorf("invalid resume token %q", token)
+	}
+	return id, nil
+}
+
+func (h *Handler) handshake(config *websocket.Config, r *http.Request) error {
+	if h.opts.CheckOrigin != nil {
+		if !h.opts.CheckOrigin(r) {
+			return errors.New("origin not allowed")
+		}
+		return nil
+	}
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := websocket.Origin(config, r)
+	if err != nil || u == nil || !strings.EqualFold(u.Host, r.Host) {
+		return errors.New("origin not allowed")
+	}
+	return nil
+}
+
+func (h *Handler) serveSSE(w http.ResponseWriter, r *http.Request, tables []string, since int64) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+	ready := func() {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+	}
+	send := func(msg *Message) error {
+		data, err := json.Marshal(msg)
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", msg.ID, msg.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	keepAlive := func() error {
+		if _, err := w.Write([]byte(": keep-alive\n\n")); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	if err := h.stream(r.Context(), tables, since, ready, send, keepAlive); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+	}
+}
+
+func (h *Handler) serveWebSocket(ws *websocket.Conn, tables []string, since int64) {
+	ctx, cancel := context.WithCancel(ws.Request().Context())
+	defer cancel()
+	// Read until the client goes away, discarding its messages.
+	go func() {
+		defer cancel()
+		var discard []byte
+		for websocket.Message.Receive(ws, &discard) == nil {
+		}
+	}()
+
+	send := func(msg *Message) error {
+		return websocket.JSON.Send(ws, msg)
+	}
+	keepAlive := func() error {
+		ws.PayloadType = websocket.PingFrame
+		defer func() { ws.PayloadType = websocket.TextFrame }()
+		_, err := ws.Write(nil)
+		return err
+	}
+	if err := h.stream(ctx, tables, since, func() {}, send, keepAlive); err != nil {
+		websocket.JSON.Send(ws, map[string]string{"error": err.Error()})
+	}
+}
+
+// stream subscribes to tables, calls ready once changes committed from then
+// on are sure to be sent, and sends changes after since, or after the latest
+// change when since is negative, until ctx is done or a send fails. It only
+// returns an error when it fails before calling ready.
+func (h *Handler) stream(ctx context.Context, tables []string, since int64, ready func(), send func(*Message) error, keepAlive func() error) error {
+	sub := &subscriber{events: make(chan cdc.Event, h.opts.Buffer)}
+	if len(tables) > 0 {
+		sub.tables = map[string]bool{}
+		for _, table := range tables {
+			sub.tables[table] = true
+		}
+	}
+	h.mu.Lock()
+	h.subs[sub] = struct{}{}
+	h.mu.Unlock()
+	defer func() {
+		h.mu.Lock()
+		delete(h.subs, sub)
+		h.mu.Unlock()
+	}()
+
+	// Subscribe before reading the range, so no change falls between them.
+	oldest, latest, err := cdc.Range(ctx)
+	if err != nil {
+		return err
+	}
+	ready()
+	lastID := since
+	catchUp := since >= 0
+	if since < 0 || since > latest {
+		lastID, catchUp = latest, false
+	} else if since+1 < oldest {
+		if send(&Message{Type: TypeReset, ID: latest}) != nil {
+			return nil
+		}
+		lastID, catchUp = latest, false
+	}
+
+	ticker := time.NewTicker(h.opts.KeepAlive)
+	defer ticker.Stop()
+	for {
+		if catchUp || sub.lagged.Swap(false) {
+			catchUp = false
+			if lastID, err = h.replay(ctx, tables, lastID, send); err != nil {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case e := <-sub.events:
+			// Changes already replayed are skipped.
+			if e.ID <= lastID {
+				continue
+			}
+			if send(message(e)) != nil {
+				return nil
+			}
+			lastID = e.ID
+		case <-ticker.C:
+			if keepAlive() != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// replay sends the recorded changes to tables after lastID and returns the
+// id of the last one sent.
+func (h *Handler) replay(ctx context.Context, tables []string, lastID int64, send func(*Message) error) (int64, error) {
+	const batchSize = 1000
+	for {
+		events, err := cdc.Changes(ctx, lastID, tables, batchSize)
+		if err != nil {
+			return lastID, err
+		}
+		for _, e := range events {
+			if err := send(message(e)); err != nil {
+				return lastID, err
+			}
+			lastID = e.ID
+		}
+		if len(events) < batchSize {
+			return lastID, nil
+		}
+	}
+}
+
+func message(e cdc.Event) *Message {
+	t := e.Time.UTC()
+	return &Message{
+		Type:  TypeChange,
+		ID:    e.ID,
+		Table: e.Table,
+		RowID: e.RowID,
+		Op:    e.Op,
+		Old:   e.Old,
+		New:   e.New,
+		Time:  &t,
+	}
+}
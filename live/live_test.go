@@ -0,0 +1,136 @@
+package live_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/cdc"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/live"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/websocket"
+)
+
+// sse reads the messages of an SSE response.
+type sse struct {
+	t       *testing.T
+	scanner *bufio.Scanner
+}
+
+func connectSSE(t *testing.T, url, lastEventID string) *sse {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	return &sse{t: t, scanner: bufio.NewScanner(resp.Body)}
+}
+
+// next returns the next message, checking that its SSE id and event match.
+func (s *sse) next() live.Message {
+	s.t.Helper()
+	var id, event string
+	var msg live.Message
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(s.t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &msg))
+		case line == "" && id != "":
+			assert.Equal(s.t, msg.Type, event)
+			assert.Equal(s.t, strconv.FormatInt(msg.ID, 10), id)
+			return msg
+		}
+	}
+	s.t.Fatalf("stream ended: %v", s.scanner.Err())
+	return msg
+}
+
+func TestHandler(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "live.db"), 4))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.E(ctx, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);"))
+	require.NoError(t, exec.E(ctx, "CREATE TABLE orders (id INTEGER PRIMARY KEY, total REAL);"))
+
+	capture, err := cdc.Start(ctx, cdc.Options{Tables: []string{"users", "orders"}, Values: true, Interval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, capture.Close())
+	}()
+	h := live.NewHandler(capture, live.Options{Tables: []string{"users", "orders"}, Buffer: 1})
+	server := httptest.NewServer(h)
+	defer func() {
+		// Streams only end when their clients go away.
+		server.CloseClientConnections()
+		server.Close()
+	}()
+
+	resp, err := http.Get(server.URL + "?tables=secrets")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// A new subscription only receives changes to its tables committed
+	// after it connected, including those past its buffer.
+	stream := connectSSE(t, server.URL+"?tables=users", "")
+	require.NoError(t, exec.E(ctx, "INSERT INTO orders (total) VALUES (9.5);"))
+	require.NoError(t, exec.E(ctx, "INSERT INTO users (name) VALUES ('Alice'), ('Bob'), ('Carol');"))
+	require.NoError(t, exec.E(ctx, "UPDATE users SET name = 'Alicia' WHERE id = 1;"))
+
+	first := stream.next()
+	assert.Equal(t, live.TypeChange, first.Type)
+	assert.Equal(t, "users", first.Table)
+	assert.Equal(t, cdc.Insert, first.Op)
+	assert.Equal(t, map[string]interface{}{"id": float64(1), "name": "Alice"}, first.New)
+	assert.Equal(t, "Bob", stream.next().New["name"])
+	assert.Equal(t, "Carol", stream.next().New["name"])
+	last := stream.next()
+	assert.Equal(t, cdc.Update, last.Op)
+	assert.Equal(t, "Alice", last.Old["name"])
+
+	// Resuming replays the changes after the token.
+	resumed := connectSSE(t, server.URL+"?tables=users", "3")
+	assert.Equal(t, "Carol", resumed.next().New["name"])
+	assert.Equal(t, last.ID, resumed.next().ID)
+
+	ws, err := websocket.Dial(strings.Replace(server.URL, "http", "ws", 1)+"?tables=orders&since=0", "", server.URL)
+	require.NoError(t, err)
+	defer ws.Close()
+	var msg live.Message
+	require.NoError(t, websocket.JSON.Receive(ws, &msg))
+	assert.Equal(t, "orders", msg.Table)
+	assert.Equal(t, 9.5, msg.New["total"])
+	require.NoError(t, exec.E(ctx, "DELETE FROM orders;"))
+	require.NoError(t, websocket.JSON.Receive(ws, &msg))
+	assert.Equal(t, cdc.Delete, msg.Op)
+
+	// A token older than the retained changes gets a reset.
+	require.NoError(t, exec.E(ctx, "DELETE FROM _cdc_changes WHERE id <= ?;", last.ID))
+	reset := connectSSE(t, server.URL, "1")
+	msg = reset.next()
+	assert.Equal(t, live.TypeReset, msg.Type)
+	assert.Equal(t, last.ID+1, msg.ID)
+}
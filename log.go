@@ -0,0 +1,23 @@
+package sqliteutils
+
+import (
+	"log/slog"
+	"sync/atomic"
+)
+
+var logger atomic.Pointer[slog.Logger]
+
+// SetLogger sets the logger the exec, pool and backup packages report to,
+// for problems that cannot be returned to a caller, such as failed rollbacks
+// and close errors. A nil logger restores the default, slog.Default().
+func SetLogger(l *slog.Logger) {
+	logger.Store(l)
+}
+
+// Logger returns the logger set by SetLogger, or slog.Default() if none is set.
+func Logger() *slog.Logger {
+	if l := logger.Load(); l != nil {
+		return l
+	}
+	return slog.Default()
+}
@@ -0,0 +1,30 @@
+package sqliteutils_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetLogger(t *testing.T) {
+	var buf bytes.Buffer
+	sqliteutils.SetLogger(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	defer sqliteutils.SetLogger(nil)
+
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE t (v);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	assert.Contains(t, buf.String(), "initialized pool")
+
+	sqliteutils.SetLogger(nil)
+	assert.Same(t, slog.Default(), sqliteutils.Logger())
+}
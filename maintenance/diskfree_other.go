@@ -0,0 +1,8 @@
+//go:build !(linux || darwin || freebsd)
+
+package maintenance
+
+// diskFree reports that free space cannot be measured on this platform.
+func diskFree(dir string) (int64, bool, error) {
+	return 0, false, nil
+}
@@ -0,0 +1,287 @@
+// Package maintenance runs space-reclaiming maintenance on the global pool's
+// database without surprising the application serving from it.
+package maintenance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// ErrNotIncremental is returned by incremental vacuums of a database that is
+// not in auto_vacuum=INCREMENTAL mode.
+var ErrNotIncremental = errors.New("incremental vacuum requires auto_vacuum=INCREMENTAL")
+
+// DefaultSlicePages is how many pages an incremental vacuum frees at a time
+// unless VacuumOptions.SlicePages is set.
+const DefaultSlicePages = 1024
+
+// Estimate describes the space a vacuum needs and frees.
+type Estimate struct {
+	// Path is the database file, or empty for an in-memory database.
+	Path     string `json:"path"`
+	PageSize int64  `json:"page_size"`
+	// Pages is the size of the database in pages and FreePages how many of
+	// them are unused, which is what a vacuum can reclaim.
+	Pages     int64 `json:"pages"`
+	FreePages int64 `json:"free_pages"`
+	// RequiredBytes is the temporary disk space a full VACUUM needs: a
+	// compacted copy of the database, and the same again in the WAL or
+	// rollback journal while it is copied back.
+	RequiredBytes int64 `json:"required_bytes"`
+	// AvailableBytes is the free space next to the database, or -1 when it
+	// cannot be measured.
+	AvailableBytes int64 `json:"available_bytes"`
+	// Incremental reports whether the database uses auto_vacuum=INCREMENTAL.
+	Incremental bool `json:"incremental"`
+}
+
+// ReclaimableBytes is the space a vacuum returns to the file system.
+func (e Estimate) ReclaimableBytes() int64 {
+	return e.FreePages * e.PageSize
+}
+
+// Progress is reported while Vacuum runs.
+type Progress struct {
+	// Pages is how many pages have been freed so far of TotalPages.
+	Pages      int64         `json:"pages"`
+	TotalPages int64         `json:"total_pages"`
+	Elapsed    time.Duration `json:"elapsed"`
+	Done       bool          `json:"done"`
+}
+
+// VacuumOptions configures Vacuum.
+type VacuumOptions struct {
+	// Incremental frees pages with PRAGMA incremental_vacuum in slices of
+	// SlicePages instead of rebuilding the database with VACUUM. Each slice
+	// is a short write transaction on a connection that is returned to the
+	// pool between slices, so the application keeps writing. It requires
+	// auto_vacuum=INCREMENTAL, and frees pages without defragmenting.
+	Incremental bool
+	// SlicePages is the number of pages freed per slice. Defaults to
+	// DefaultSlicePages.
+	SlicePages int
+	// Pause is waited between slices, leaving the database to the
+	// application.
+	Pause time.Duration
+	// Headroom is the free space to leave on the disk beyond what a full
+	// VACUUM needs.
+	Headroom int64
+	// Progress, when set, is called after each slice. SQLite does not
+	// report the progress of a full VACUUM, so for one it is called every
+	// ProgressInterval with the elapsed time only, and once when done.
+	Progress func(Progress)
+	// ProgressInterval defaults to 1s.
+	ProgressInterval time.Duration
+}
+
+// Result describes a completed Vacuum.
+type Result struct {
+	FreedPages int64         `json:"freed_pages"`
+	SizeBefore int64         `json:"size_before"`
+	SizeAfter  int64         `json:"size_after"`
+	Duration   time.Duration `json:"duration"`
+}
+
+// EstimateVacuum measures the global pool's database and the disk it is on.
+func EstimateVacuum(ctx context.Context) (Estimate, error) {
+	var estimate Estimate
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		var err error
+		estimate, err = estimateConn(conn)
+		return err
+	})
+	return estimate, err
+}
+
+func estimateConn(conn *sqlite.Conn) (Estimate, error) {
+	var estimate Estimate
+	var autoVacuum int64
+	for query, dst := range map[string]*int64{
+		"PRAGMA page_size;":      &estimate.PageSize,
+		"PRAGMA page_count;":     &estimate.Pages,
+		"PRAGMA freelist_count;": &estimate.FreePages,
+		"PRAGMA auto_vacuum;":    &autoVacuum,
+	} {
+		v, err := sqlitex.ResultInt64(conn.Prep(query))
+		if err != nil {
+			return estimate, fmt.Errorf("failed to run %s: %w", query, err)
+		}
+		*dst = v
+	}
+	estimate.Incremental = autoVacuum == 2
+	path, err := sqlitex.ResultText(conn.Prep("SELECT file FROM pragma_database_list WHERE name = 'main';"))
+	if err != nil {
+		return estimate, fmt.Errorf("failed to read database file: %w", err)
+	}
+	estimate.Path = path
+	estimate.RequiredBytes = 2 * (estimate.Pages - estimate.FreePages) * estimate.PageSize
+
+	estimate.AvailableBytes = -1
+	if path != "" {
+		free, ok, err := diskFree(filepath.Dir(path))
+		if err != nil {
+			return estimate, fmt.Errorf("failed to measure free disk space: %w", err)
+		}
+		if ok {
+			estimate.AvailableBytes = free
+		}
+	}
+	return estimate, nil
+}
+
+// Vacuum reclaims the unused pages of the global pool's database. A full
+// VACUUM rebuilds the database, which blocks writers until it finishes, so
+// it refuses to start with an error matching sqliteutils.ErrInsufficientSpace
+// when the disk cannot hold the temporary copy plus Headroom. Canceling ctx
+// interrupts a full VACUUM, which is then rolled back, or stops an
+// incremental one after the current slice.
+func Vacuum(ctx context.Context, opts VacuumOptions) (Result, error) {
+	if opts.SlicePages <= 0 {
+		opts.SlicePages = DefaultSlicePages
+	}
+	if opts.ProgressInterval <= 0 {
+		opts.ProgressInterval = time.Second
+	}
+	estimate, err := EstimateVacuum(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	start := time.Now()
+	result := Result{SizeBefore: estimate.Pages * estimate.PageSize}
+	if opts.Incremental {
+		err = incrementalVacuum(ctx, estimate, opts, &result)
+	} else {
+		err = fullVacuum(ctx, estimate, opts, &result)
+	}
+	result.Duration = time.Since(start)
+	if err != nil {
+		return result, err
+	}
+
+	after, err := EstimateVacuum(ctx)
+	if err != nil {
+		return result, err
+	}
+	result.SizeAfter = after.Pages * after.PageSize
+	sqliteutils.Logger().Info("vacuumed database", "incremental", opts.Incremental, "freed_pages", result.FreedPages, "duration", result.Duration)
+	return result, nil
+}
+
+func fullVacuum(ctx context.Context, estimate Estimate, opts VacuumOptions, result *Result) error {
+	if estimate.AvailableBytes >= 0 && estimate.AvailableBytes < estimate.RequiredBytes+opts.Headroom {
+		return fmt.Errorf("%w: vacuum needs %d bytes plus %d headroom, %d available",
+			sqliteutils.ErrInsufficientSpace, estimate.RequiredBytes, opts.Headroom, estimate.AvailableBytes)
+	}
+
+	start := time.Now()
+	report := func(done bool) {
+		if opts.Progress != nil {
+			p := Progress{TotalPages: estimate.FreePages, Elapsed: time.Since(start), Done: done}
+			if done {
+				p.Pages = estimate.FreePages
+			}
+			opts.Progress(p)
+		}
+	}
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(opts.ProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				report(false)
+			}
+		}
+	}()
+
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		conn.SetInterrupt(ctx.Done())
+		defer conn.SetInterrupt(nil)
+		return sqlitex.ExecuteTransient(conn, "VACUUM;", nil)
+	})
+	close(stop)
+	<-stopped
+	if err != nil {
+		return fmt.Errorf("failed to vacuum: %w", err)
+	}
+	result.FreedPages = estimate.FreePages
+	report(true)
+	return nil
+}
+
+func incrementalVacuum(ctx context.Context, estimate Estimate, opts VacuumOptions, result *Result) error {
+	if !estimate.Incremental {
+		return ErrNotIncremental
+	}
+	start := time.Now()
+	for {
+		freed, remaining, err := IncrementalVacuum(ctx, opts.SlicePages)
+		if err != nil {
+			return err
+		}
+		result.FreedPages += freed
+		if opts.Progress != nil {
+			// Deletes while the vacuum runs can free more pages than estimated.
+			opts.Progress(Progress{
+				Pages:      result.FreedPages,
+				TotalPages: max(estimate.FreePages, result.FreedPages),
+				Elapsed:    time.Since(start),
+				Done:       remaining == 0,
+			})
+		}
+		if remaining == 0 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(opts.Pause):
+		}
+	}
+}
+
+// IncrementalVacuum frees up to pages unused pages of the global pool's
+// database, or all of them when pages is not positive, and returns how many
+// were freed and how many remain. It is a short write transaction, cheap
+// enough to call whenever the application is idle. The database must be in
+// auto_vacuum=INCREMENTAL mode, see pool.WithAutoVacuum.
+func IncrementalVacuum(ctx context.Context, pages int) (freed, remaining int64, err error) {
+	err = pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		mode, err := sqlitex.ResultInt64(conn.Prep("PRAGMA auto_vacuum;"))
+		if err != nil {
+			return err
+		}
+		if mode != 2 {
+			return ErrNotIncremental
+		}
+		before, err := sqlitex.ResultInt64(conn.Prep("PRAGMA freelist_count;"))
+		if err != nil || before == 0 {
+			return err
+		}
+		// incremental_vacuum frees pages as its rows are stepped through.
+		if err := sqlitex.ExecuteTransient(conn, fmt.Sprintf("PRAGMA incremental_vacuum(%d);", max(pages, 0)), nil); err != nil {
+			return err
+		}
+		remaining, err = sqlitex.ResultInt64(conn.Prep("PRAGMA freelist_count;"))
+		freed = before - remaining
+		return err
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to vacuum: %w", err)
+	}
+	return freed, remaining, nil
+}
@@ -0,0 +1,112 @@
+package maintenance_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/maintenance"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fillAndDelete inserts rows spanning many pages and deletes them again,
+// leaving free pages behind.
+func fillAndDelete(t *testing.T, ctx context.Context) {
+	t.Helper()
+	require.NoError(t, exec.E(ctx, `INSERT INTO blobs (data)
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 500)
+		SELECT randomblob(2000) FROM n;`))
+	require.NoError(t, exec.E(ctx, "DELETE FROM blobs;"))
+}
+
+func TestVacuum(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "vacuum.db"), 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.E(ctx, "CREATE TABLE blobs (id INTEGER PRIMARY KEY, data BLOB);"))
+	fillAndDelete(t, ctx)
+
+	estimate, err := maintenance.EstimateVacuum(ctx)
+	require.NoError(t, err)
+	assert.Greater(t, estimate.FreePages, int64(200))
+	assert.False(t, estimate.Incremental)
+	assert.Positive(t, estimate.RequiredBytes)
+
+	_, err = maintenance.Vacuum(ctx, maintenance.VacuumOptions{Incremental: true})
+	assert.ErrorIs(t, err, maintenance.ErrNotIncremental)
+	_, _, err = maintenance.IncrementalVacuum(ctx, 10)
+	assert.ErrorIs(t, err, maintenance.ErrNotIncremental)
+
+	if estimate.AvailableBytes >= 0 {
+		_, err = maintenance.Vacuum(ctx, maintenance.VacuumOptions{Headroom: estimate.AvailableBytes})
+		assert.ErrorIs(t, err, sqliteutils.ErrInsufficientSpace)
+	}
+
+	var progress []maintenance.Progress
+	result, err := maintenance.Vacuum(ctx, maintenance.VacuumOptions{Progress: func(p maintenance.Progress) {
+		progress = append(progress, p)
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, estimate.FreePages, result.FreedPages)
+	assert.Less(t, result.SizeAfter, result.SizeBefore)
+	require.NotEmpty(t, progress)
+	assert.True(t, progress[len(progress)-1].Done)
+
+	estimate, err = maintenance.EstimateVacuum(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, estimate.FreePages)
+}
+
+func TestVacuum_Incremental(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "incremental.db"), 2, pool.WithAutoVacuum("INCREMENTAL")))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.E(ctx, "CREATE TABLE blobs (id INTEGER PRIMARY KEY, data BLOB);"))
+	fillAndDelete(t, ctx)
+
+	estimate, err := maintenance.EstimateVacuum(ctx)
+	require.NoError(t, err)
+	require.True(t, estimate.Incremental)
+
+	var progress []maintenance.Progress
+	result, err := maintenance.Vacuum(ctx, maintenance.VacuumOptions{Incremental: true, SlicePages: 100, Progress: func(p maintenance.Progress) {
+		progress = append(progress, p)
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, estimate.FreePages, result.FreedPages)
+	assert.Less(t, result.SizeAfter, result.SizeBefore)
+	assert.Greater(t, len(progress), 2, "pages are freed in slices")
+	last := progress[len(progress)-1]
+	assert.True(t, last.Done)
+	assert.Equal(t, last.TotalPages, last.Pages)
+}
+
+func TestIncrementalVacuum(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "idle.db"), 2, pool.WithAutoVacuum("incremental")))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.E(ctx, "CREATE TABLE blobs (id INTEGER PRIMARY KEY, data BLOB);"))
+	fillAndDelete(t, ctx)
+
+	estimate, err := maintenance.EstimateVacuum(ctx)
+	require.NoError(t, err)
+	freed, remaining, err := maintenance.IncrementalVacuum(ctx, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), freed)
+	assert.Equal(t, estimate.FreePages-10, remaining)
+
+	freed, remaining, err = maintenance.IncrementalVacuum(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, estimate.FreePages-10, freed)
+	assert.Zero(t, remaining)
+}
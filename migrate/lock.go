@@ -0,0 +1,156 @@
+package migrate
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// LockOptions controls how Up, Down and To coordinate when several processes
+// migrate the same database file.
+type LockOptions struct {
+	// Skip makes a process that finds the lock held return
+	// sqliteutils.ErrMigrationLocked instead of waiting for it.
+	Skip bool
+	// TTL is how long a lock lease lasts before other processes may take it
+	// over, protecting against crashed holders. The lease is extended after
+	// every migration. Defaults to one minute.
+	TTL time.Duration
+	// PollInterval is how often a waiting process retries. Defaults to 100ms.
+	PollInterval time.Duration
+}
+
+const createLockTable = `CREATE TABLE IF NOT EXISTS schema_migrations_lock (
+	id INTEGER PRIMARY KEY CHECK (id = 1),
+	owner TEXT NOT NULL,
+	expires_at INTEGER NOT NULL
+);`
+
+var (
+	lockOpts     LockOptions
+	lockOptsLock sync.Mutex
+)
+
+// SetLockOptions configures migration locking for subsequent calls to Up, Down and To.
+func SetLockOptions(opts LockOptions) {
+	lockOptsLock.Lock()
+	defer lockOptsLock.Unlock()
+	lockOpts = opts
+}
+
+func getLockOptions() LockOptions {
+	lockOptsLock.Lock()
+	defer lockOptsLock.Unlock()
+	opts := lockOpts
+	if opts.TTL <= 0 {
+		opts.TTL = time.Minute
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = 100 * time.Millisecond
+	}
+	return opts
+}
+
+// lock is a lease on the single row of schema_migrations_lock.
+type lock struct {
+	owner string
+	ttl   time.Duration
+}
+
+// withLock runs fn on a pooled connection while holding the migration lock.
+func withLock(ctx context.Context, fn func(conn *sqlite.Conn, l *lock) error) error {
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		return withLockConn(ctx, conn, fn)
+	})
+}
+
+// withLockConn runs fn on conn while holding the migration lock.
+func withLockConn(ctx context.Context, conn *sqlite.Conn, fn func(conn *sqlite.Conn, l *lock) error) (err error) {
+	opts := getLockOptions()
+	l := &lock{owner: newOwnerID(), ttl: opts.TTL}
+	for {
+		acquired, err := l.tryAcquire(conn)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			break
+		}
+		if opts.Skip {
+			return sqliteutils.ErrMigrationLocked
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(opts.PollInterval):
+		}
+	}
+	defer func() {
+		if releaseErr := l.release(conn); releaseErr != nil && err == nil {
+			err = releaseErr
+		}
+	}()
+	return fn(conn, l)
+}
+
+// tryAcquire takes the lock if it is free, expired or already ours.
+func (l *lock) tryAcquire(conn *sqlite.Conn) (acquired bool, err error) {
+	if err := sqlitex.ExecuteTransient(conn, createLockTable, nil); err != nil {
+		return false, fmt.Errorf("failed to create schema_migrations_lock table: %w", err)
+	}
+
+	endFn, err := sqlitex.ImmediateTransaction(conn)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin lock transaction: %w", err)
+	}
+	defer endFn(&err)
+
+	now := time.Now()
+	err = sqlitex.Execute(conn, `INSERT INTO schema_migrations_lock (id, owner, expires_at) VALUES (1, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at
+		WHERE schema_migrations_lock.owner = excluded.owner OR schema_migrations_lock.expires_at < ?;`,
+		&sqlitex.ExecOptions{Args: []interface{}{l.owner, now.Add(l.ttl).UnixMilli(), now.UnixMilli()}})
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	return conn.Changes() > 0, nil
+}
+
+// refresh extends the lease; it must run inside a transaction.
+func (l *lock) refresh(conn *sqlite.Conn) error {
+	err := sqlitex.Execute(conn, "UPDATE schema_migrations_lock SET expires_at = ? WHERE id = 1 AND owner = ?;",
+		&sqlitex.ExecOptions{Args: []interface{}{time.Now().Add(l.ttl).UnixMilli(), l.owner}})
+	if err != nil {
+		return fmt.Errorf("failed to refresh migration lock: %w", err)
+	}
+	if conn.Changes() == 0 {
+		return sqliteutils.ErrMigrationLockLost
+	}
+	return nil
+}
+
+func (l *lock) release(conn *sqlite.Conn) error {
+	err := sqlitex.Execute(conn, "DELETE FROM schema_migrations_lock WHERE id = 1 AND owner = ?;",
+		&sqlitex.ExecOptions{Args: []interface{}{l.owner}})
+	if err != nil {
+		return fmt.Errorf("failed to release migration lock: %w", err)
+	}
+	return nil
+}
+
+// newOwnerID identifies one lock holder across processes and hosts.
+func newOwnerID() string {
+	host, _ := os.Hostname()
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return fmt.Sprintf("%s:%d:%s", host, os.Getpid(), hex.EncodeToString(buf))
+}
@@ -0,0 +1,337 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Migration is a single schema change. Exactly one of SQL or Func must be set;
+// a migration is reversible when one of DownSQL or DownFunc is also set.
+type Migration struct {
+	// Version orders migrations and is recorded in schema_migrations once applied.
+	Version int64
+	Name    string
+	// SQL is a script of one or more statements.
+	SQL string
+	// Func runs arbitrary Go code on the migration's connection.
+	Func func(ctx context.Context, conn *sqlite.Conn) error
+	// DownSQL is a script that undoes SQL or Func.
+	DownSQL string
+	// DownFunc undoes SQL or Func in Go.
+	DownFunc func(ctx context.Context, conn *sqlite.Conn) error
+}
+
+// Reversible reports whether the migration can be rolled back.
+func (m Migration) Reversible() bool {
+	return m.DownSQL != "" || m.DownFunc != nil
+}
+
+// AppliedMigration is a row of the schema_migrations table.
+type AppliedMigration struct {
+	Version   int64
+	Name      string
+	AppliedAt string
+}
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name TEXT NOT NULL,
+	applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);`
+
+var (
+	registry     []Migration
+	registryLock sync.Mutex
+)
+
+// Register adds a migration to the global registry.
+// It should be called during application startup, before Up.
+func Register(m Migration) error {
+	if (m.SQL == "") == (m.Func == nil) {
+		return fmt.Errorf("migration %d must set exactly one of SQL or Func", m.Version)
+	}
+	if m.DownSQL != "" && m.DownFunc != nil {
+		return fmt.Errorf("migration %d must set at most one of DownSQL or DownFunc", m.Version)
+	}
+
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	for _, existing := range registry {
+		if existing.Version == m.Version {
+			return fmt.Errorf("migration %d is already registered", m.Version)
+		}
+	}
+	registry = append(registry, m)
+	sort.Slice(registry, func(i, j int) bool { return registry[i].Version < registry[j].Version })
+	return nil
+}
+
+// RegisterSQL registers a migration that runs a SQL script.
+func RegisterSQL(version int64, name, sql string) error {
+	return Register(Migration{Version: version, Name: name, SQL: sql})
+}
+
+// RegisterFunc registers a migration implemented in Go.
+func RegisterFunc(version int64, name string, fn func(ctx context.Context, conn *sqlite.Conn) error) error {
+	return Register(Migration{Version: version, Name: name, Func: fn})
+}
+
+// RegisterReversibleSQL registers a migration with SQL scripts for both directions.
+func RegisterReversibleSQL(version int64, name, up, down string) error {
+	return Register(Migration{Version: version, Name: name, SQL: up, DownSQL: down})
+}
+
+// Reset clears the registry.
+// This is primarily intended for testing purposes.
+func Reset() {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry = nil
+}
+
+// Migrations returns the registered migrations ordered by version.
+func Migrations() []Migration {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	return append([]Migration(nil), registry...)
+}
+
+// Up applies every registered migration that has not been applied yet, in
+// version order, each inside its own transaction. It returns the versions it applied.
+func Up(ctx context.Context) ([]int64, error) {
+	var applied []int64
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) (err error) {
+		applied, err = UpConn(ctx, conn)
+		return err
+	})
+	return applied, err
+}
+
+// UpConn is Up on a connection the caller already holds, such as one to a
+// database other than the global pool's.
+func UpConn(ctx context.Context, conn *sqlite.Conn) ([]int64, error) {
+	var applied []int64
+	err := withLockConn(ctx, conn, func(conn *sqlite.Conn, l *lock) error {
+		pending, err := pendingMigrations(conn)
+		if err != nil {
+			return err
+		}
+		applied, err = applyAll(ctx, conn, l, pending)
+		return err
+	})
+	return applied, err
+}
+
+// Down rolls back the most recently applied migrations, newest first, until
+// steps migrations have been reverted or none are left. It returns the
+// versions it reverted.
+func Down(ctx context.Context, steps int) ([]int64, error) {
+	if steps <= 0 {
+		return nil, nil
+	}
+	var reverted []int64
+	err := withLock(ctx, func(conn *sqlite.Conn, l *lock) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		if steps < len(applied) {
+			applied = applied[len(applied)-steps:]
+		}
+		reverted, err = revertAll(ctx, conn, l, applied)
+		return err
+	})
+	return reverted, err
+}
+
+// To migrates the database up or down so that exactly the registered
+// migrations with a version less than or equal to version are applied.
+// It returns the versions it applied or reverted.
+func To(ctx context.Context, version int64) ([]int64, error) {
+	var changed []int64
+	err := withLock(ctx, func(conn *sqlite.Conn, l *lock) error {
+		applied, err := appliedMigrations(conn)
+		if err != nil {
+			return err
+		}
+		var newer []AppliedMigration
+		for _, a := range applied {
+			if a.Version > version {
+				newer = append(newer, a)
+			}
+		}
+		if changed, err = revertAll(ctx, conn, l, newer); err != nil {
+			return err
+		}
+
+		pending, err := pendingMigrations(conn)
+		if err != nil {
+			return err
+		}
+		var older []Migration
+		for _, m := range pending {
+			if m.Version <= version {
+				older = append(older, m)
+			}
+		}
+		up, err := applyAll(ctx, conn, l, older)
+		changed = append(changed, up...)
+		return err
+	})
+	return changed, err
+}
+
+// Applied returns the rows of schema_migrations ordered by version.
+func Applied(ctx context.Context) ([]AppliedMigration, error) {
+	var applied []AppliedMigration
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) (err error) {
+		applied, err = appliedMigrations(conn)
+		return err
+	})
+	return applied, err
+}
+
+// pendingMigrations returns registered migrations missing from schema_migrations.
+func pendingMigrations(conn *sqlite.Conn) ([]Migration, error) {
+	applied, err := appliedMigrations(conn)
+	if err != nil {
+		return nil, err
+	}
+	done := make(map[int64]bool, len(applied))
+	for _, a := range applied {
+		done[a.Version] = true
+	}
+
+	var pending []Migration
+	for _, m := range Migrations() {
+		if !done[m.Version] {
+			pending = append(pending, m)
+		}
+	}
+	return pending, nil
+}
+
+func appliedMigrations(conn *sqlite.Conn) ([]AppliedMigration, error) {
+	if err := sqlitex.ExecuteTransient(conn, createMigrationsTable, nil); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	var applied []AppliedMigration
+	err := sqlitex.Execute(conn, "SELECT version, name, applied_at FROM schema_migrations ORDER BY version;", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			applied = append(applied, AppliedMigration{
+				Version:   stmt.ColumnInt64(0),
+				Name:      stmt.ColumnText(1),
+				AppliedAt: stmt.ColumnText(2),
+			})
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	return applied, nil
+}
+
+// applyAll applies migrations in order and returns the versions that succeeded.
+func applyAll(ctx context.Context, conn *sqlite.Conn, l *lock, migrations []Migration) ([]int64, error) {
+	var applied []int64
+	for _, m := range migrations {
+		if err := apply(ctx, conn, l, m); err != nil {
+			return applied, err
+		}
+		applied = append(applied, m.Version)
+	}
+	return applied, nil
+}
+
+// revertAll reverts applied migrations newest first and returns the versions
+// that succeeded. Every migration must be registered and reversible.
+func revertAll(ctx context.Context, conn *sqlite.Conn, l *lock, applied []AppliedMigration) ([]int64, error) {
+	registered := make(map[int64]Migration)
+	for _, m := range Migrations() {
+		registered[m.Version] = m
+	}
+
+	var reverted []int64
+	for i := len(applied) - 1; i >= 0; i-- {
+		m, ok := registered[applied[i].Version]
+		if !ok || !m.Reversible() {
+			return reverted, fmt.Errorf("%w: %d (%s)", sqliteutils.ErrIrreversibleMigration, applied[i].Version, applied[i].Name)
+		}
+		if err := revert(ctx, conn, l, m); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, m.Version)
+	}
+	return reverted, nil
+}
+
+// apply runs a single migration and records it in one IMMEDIATE transaction,
+// extending the migration lock lease in the same transaction.
+func apply(ctx context.Context, conn *sqlite.Conn, l *lock, m Migration) (err error) {
+	endFn, err := sqlitex.ImmediateTransaction(conn)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for migration %d: %w", m.Version, err)
+	}
+	defer endFn(&err)
+
+	if err := l.refresh(conn); err != nil {
+		return err
+	}
+
+	if m.Func != nil {
+		err = m.Func(ctx, conn)
+	} else {
+		err = sqlitex.ExecuteScript(conn, m.SQL, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to apply migration %d (%s): %w", m.Version, m.Name, err)
+	}
+
+	err = sqlitex.Execute(conn, "INSERT INTO schema_migrations (version, name) VALUES (?, ?);", &sqlitex.ExecOptions{
+		Args: []interface{}{m.Version, m.Name},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.Version, err)
+	}
+	return nil
+}
+
+// revert undoes a single migration and removes its record in one IMMEDIATE
+// transaction, extending the migration lock lease in the same transaction.
+func revert(ctx context.Context, conn *sqlite.Conn, l *lock, m Migration) (err error) {
+	endFn, err := sqlitex.ImmediateTransaction(conn)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for migration %d: %w", m.Version, err)
+	}
+	defer endFn(&err)
+
+	if err := l.refresh(conn); err != nil {
+		return err
+	}
+
+	if m.DownFunc != nil {
+		err = m.DownFunc(ctx, conn)
+	} else {
+		err = sqlitex.ExecuteScript(conn, m.DownSQL, nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revert migration %d (%s): %w", m.Version, m.Name, err)
+	}
+
+	err = sqlitex.Execute(conn, "DELETE FROM schema_migrations WHERE version = ?;", &sqlitex.ExecOptions{
+		Args: []interface{}{m.Version},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove migration record %d: %w", m.Version, err)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package migrate_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/migrate"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func countUsers(t *testing.T, ctx context.Context) int64 {
+	t.Helper()
+	var count int64
+	err := exec.Exec(ctx, "SELECT COUNT(1) AS count FROM users;", nil, func(i int, row map[string]interface{}) {
+		count = row["count"].(int64)
+	})
+	require.NoError(t, err)
+	return count
+}
+
+func TestUp(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "", 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	migrate.Reset()
+	defer migrate.Reset()
+
+	require.NoError(t, migrate.RegisterSQL(2, "add email", `ALTER TABLE users ADD COLUMN email TEXT;`))
+	require.NoError(t, migrate.RegisterSQL(1, "create users", `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);`))
+	require.NoError(t, migrate.RegisterFunc(3, "seed admin", func(ctx context.Context, conn *sqlite.Conn) error {
+		return sqlitex.Execute(conn, "INSERT INTO users (name, email) VALUES ('admin', 'admin@example.com');", nil)
+	}))
+
+	assert.Error(t, migrate.RegisterSQL(1, "duplicate", "SELECT 1;"))
+	assert.Error(t, migrate.Register(migrate.Migration{Version: 9}))
+
+	applied, err := migrate.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, applied)
+	assert.Equal(t, int64(1), countUsers(t, ctx))
+
+	// Running again is a no-op.
+	applied, err = migrate.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	rows, err := migrate.Applied(ctx)
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, "seed admin", rows[2].Name)
+
+	t.Run("FailedMigrationRollsBack", func(t *testing.T) {
+		require.NoError(t, migrate.RegisterFunc(4, "broken", func(ctx context.Context, conn *sqlite.Conn) error {
+			if err := sqlitex.Execute(conn, "INSERT INTO users (name) VALUES ('ghost');", nil); err != nil {
+				return err
+			}
+			return errors.New("boom")
+		}))
+
+		applied, err := migrate.Up(ctx)
+		assert.Error(t, err)
+		assert.Empty(t, applied)
+		assert.Equal(t, int64(1), countUsers(t, ctx))
+
+		rows, err := migrate.Applied(ctx)
+		require.NoError(t, err)
+		assert.Len(t, rows, 3)
+	})
+}
+
+func TestDownAndTo(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "", 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	migrate.Reset()
+	defer migrate.Reset()
+
+	require.NoError(t, migrate.RegisterReversibleSQL(1, "create users",
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);`,
+		`DROP TABLE users;`))
+	require.NoError(t, migrate.RegisterReversibleSQL(2, "create orders",
+		`CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER);`,
+		`DROP TABLE orders;`))
+	require.NoError(t, migrate.Register(migrate.Migration{
+		Version: 3,
+		Name:    "seed",
+		SQL:     `INSERT INTO users (name) VALUES ('admin');`,
+		DownFunc: func(ctx context.Context, conn *sqlite.Conn) error {
+			return sqlitex.Execute(conn, "DELETE FROM users WHERE name = 'admin';", nil)
+		},
+	}))
+	require.NoError(t, migrate.RegisterSQL(4, "irreversible", `CREATE TABLE audit (id INTEGER PRIMARY KEY);`))
+
+	versions := func() []int64 {
+		rows, err := migrate.Applied(ctx)
+		require.NoError(t, err)
+		var v []int64
+		for _, r := range rows {
+			v = append(v, r.Version)
+		}
+		return v
+	}
+
+	changed, err := migrate.To(ctx, 3)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3}, changed)
+	assert.Equal(t, int64(1), countUsers(t, ctx))
+
+	changed, err = migrate.Down(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3}, changed)
+	assert.Equal(t, int64(0), countUsers(t, ctx))
+	assert.Equal(t, []int64{1, 2}, versions())
+
+	changed, err = migrate.To(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2}, changed)
+	assert.Equal(t, []int64{1}, versions())
+
+	_, err = migrate.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1, 2, 3, 4}, versions())
+
+	changed, err = migrate.Down(ctx, 2)
+	assert.ErrorIs(t, err, sqliteutils.ErrIrreversibleMigration)
+	assert.Empty(t, changed)
+	assert.Equal(t, []int64{1, 2, 3, 4}, versions())
+
+	changed, err = migrate.Down(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, changed)
+}
+
+func TestPlanAndDryRun(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "", 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	migrate.Reset()
+	defer migrate.Reset()
+
+	require.NoError(t, migrate.RegisterSQL(1, "create users", `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);`))
+	_, err := migrate.Up(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, migrate.RegisterSQL(3, "seed orders", `
+		-- orders is created by migration 2
+		INSERT INTO orders (user_id) SELECT id FROM users;
+	`))
+	require.NoError(t, migrate.RegisterSQL(2, "create orders", `
+		/* depends on users */
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users(id));
+		CREATE INDEX orders_user_id ON orders (user_id);
+	`))
+
+	plan, err := migrate.Plan(ctx)
+	require.NoError(t, err)
+	require.Len(t, plan, 2)
+	assert.Equal(t, int64(2), plan[0].Version)
+	assert.Equal(t, int64(3), plan[1].Version)
+
+	plan, err = migrate.DryRun(ctx)
+	require.NoError(t, err)
+	assert.Len(t, plan, 2)
+
+	// The dry run must not have touched the schema.
+	err = exec.Exec(ctx, "SELECT * FROM orders;", nil, nil)
+	assert.Error(t, err)
+
+	require.NoError(t, migrate.RegisterSQL(4, "typo", `ALTER TABLE orders ADD COLUMN total INTEGER; SELEC 1;`))
+	_, err = migrate.DryRun(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "migration 4 (typo): statement 2")
+}
+
+func TestLocking(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "migrate.db"), 4))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	migrate.Reset()
+	defer migrate.Reset()
+	defer migrate.SetLockOptions(migrate.LockOptions{})
+
+	var runs int64
+	require.NoError(t, migrate.RegisterFunc(1, "create users", func(ctx context.Context, conn *sqlite.Conn) error {
+		atomic.AddInt64(&runs, 1)
+		return sqlitex.ExecuteScript(conn, `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);`, nil)
+	}))
+
+	t.Run("ConcurrentUp", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := migrate.Up(ctx)
+				assert.NoError(t, err)
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int64(1), atomic.LoadInt64(&runs), "the migration should run exactly once")
+	})
+
+	require.NoError(t, migrate.RegisterSQL(2, "add email", `ALTER TABLE users ADD COLUMN email TEXT;`))
+
+	// Simulate another process holding a live lease.
+	holdLock := func(expiresAt time.Time) {
+		err := exec.Exec(ctx, "INSERT OR REPLACE INTO schema_migrations_lock (id, owner, expires_at) VALUES (1, 'other', $expires);",
+			map[string]interface{}{"$expires": expiresAt.UnixMilli()}, nil)
+		require.NoError(t, err)
+	}
+
+	t.Run("Skip", func(t *testing.T) {
+		holdLock(time.Now().Add(time.Hour))
+		migrate.SetLockOptions(migrate.LockOptions{Skip: true})
+		_, err := migrate.Up(ctx)
+		assert.ErrorIs(t, err, sqliteutils.ErrMigrationLocked)
+	})
+
+	t.Run("WaitTimesOut", func(t *testing.T) {
+		migrate.SetLockOptions(migrate.LockOptions{PollInterval: 10 * time.Millisecond})
+		waitCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err := migrate.Up(waitCtx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+
+	t.Run("ExpiredLeaseIsTakenOver", func(t *testing.T) {
+		holdLock(time.Now().Add(-time.Second))
+		applied, err := migrate.Up(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, []int64{2}, applied)
+	})
+}
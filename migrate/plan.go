@@ -0,0 +1,103 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Plan returns the migrations Up would apply, in the order it would apply them.
+func Plan(ctx context.Context) ([]Migration, error) {
+	var pending []Migration
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) (err error) {
+		pending, err = pendingMigrations(conn)
+		return err
+	})
+	return pending, err
+}
+
+// DryRun is like Plan but additionally prepares every statement of each
+// pending SQL migration to catch syntax errors and references to missing
+// tables or columns. Statements are not executed, except that schema changes
+// (CREATE, ALTER, DROP) are applied inside a transaction that is always rolled
+// back, so later statements are checked against the schema they will see.
+// Go function migrations cannot be inspected and are only listed.
+func DryRun(ctx context.Context) ([]Migration, error) {
+	var pending []Migration
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) (err error) {
+		if pending, err = pendingMigrations(conn); err != nil {
+			return err
+		}
+
+		if err := sqlitex.Execute(conn, "BEGIN;", nil); err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer func() {
+			if rollbackErr := sqlitex.Execute(conn, "ROLLBACK;", nil); rollbackErr != nil && err == nil {
+				err = fmt.Errorf("failed to rollback transaction: %w", rollbackErr)
+			}
+		}()
+
+		for _, m := range pending {
+			if m.SQL == "" {
+				continue
+			}
+			if err := prepareScript(conn, m.SQL); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", m.Version, m.Name, err)
+			}
+		}
+		return nil
+	})
+	return pending, err
+}
+
+// prepareScript prepares each statement of script in turn, executing only the
+// ones that change the schema.
+func prepareScript(conn *sqlite.Conn, script string) error {
+	for i := 1; ; i++ {
+		script = strings.TrimSpace(script)
+		if script == "" {
+			return nil
+		}
+		stmt, trailingBytes, err := conn.PrepareTransient(script)
+		if err != nil {
+			return fmt.Errorf("statement %d: %w", i, err)
+		}
+		statement := script[:len(script)-trailingBytes]
+		script = script[len(script)-trailingBytes:]
+		if isSchemaChange(statement) {
+			_, err = stmt.Step()
+		}
+		stmt.Finalize()
+		if err != nil {
+			return fmt.Errorf("statement %d: %w", i, err)
+		}
+	}
+}
+
+// isSchemaChange reports whether a statement is DDL.
+func isSchemaChange(statement string) bool {
+	for {
+		statement = strings.TrimSpace(statement)
+		if strings.HasPrefix(statement, "--") {
+			_, statement, _ = strings.Cut(statement, "\n")
+		} else if strings.HasPrefix(statement, "/*") {
+			_, statement, _ = strings.Cut(statement, "*/")
+		} else {
+			break
+		}
+	}
+	fields := strings.Fields(statement)
+	if len(fields) == 0 {
+		return false
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "CREATE", "ALTER", "DROP":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,227 @@
+// Package notify is a publish/subscribe layer for processes sharing one
+// database file, in the spirit of PostgreSQL's NOTIFY and LISTEN.
+//
+// Publish inserts a message into the _notify_messages table, so it is
+// delivered only if its transaction commits. A Listener polls PRAGMA
+// data_version, which changes whenever another connection commits, and reads
+// new messages for its channels. Messages are kept for DefaultRetention and
+// pruned by later calls to Publish.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// DefaultRetention is how long messages are kept before Publish prunes them.
+// Listeners that fall further behind miss the pruned messages.
+const DefaultRetention = time.Hour
+
+// Message is a published notification.
+type Message struct {
+	ID      int64
+	Channel string
+	Payload string
+	Time    time.Time
+}
+
+// ListenOptions configures a Listener.
+type ListenOptions struct {
+	// Interval is how often PRAGMA data_version is polled. Defaults to 100ms.
+	Interval time.Duration
+	// Buffer is the capacity of the Messages channel. Defaults to 64.
+	Buffer int
+}
+
+const createMessagesTable = `CREATE TABLE IF NOT EXISTS _notify_messages (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	channel TEXT NOT NULL,
+	payload TEXT NOT NULL,
+	created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS _notify_messages_created_at ON _notify_messages (created_at);`
+
+// Publish sends payload to the listeners of channel.
+func Publish(ctx context.Context, channel, payload string) error {
+	p, err := pool.GetPool()
+	if err != nil {
+		return sqliteutils.FailedToGetPoolError(err)
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		return sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+	return PublishConn(conn, channel, payload)
+}
+
+// PublishConn is Publish on a connection the caller already holds. Inside a
+// transaction, the message is only delivered if the transaction commits.
+func PublishConn(conn *sqlite.Conn, channel, payload string) (err error) {
+	defer sqlitex.Save(conn)(&err)
+	if err := sqlitex.ExecuteScript(conn, createMessagesTable, nil); err != nil {
+		return fmt.Errorf("failed to create _notify_messages table: %w", err)
+	}
+	now := time.Now()
+	err = sqlitex.Execute(conn, "INSERT INTO _notify_messages (channel, payload, created_at) VALUES (?, ?, ?);", &sqlitex.ExecOptions{
+		Args: []interface{}{channel, payload, now.UnixMilli()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", channel, err)
+	}
+	err = sqlitex.Execute(conn, "DELETE FROM _notify_messages WHERE created_at < ?;", &sqlitex.ExecOptions{
+		Args: []interface{}{now.Add(-DefaultRetention).UnixMilli()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to prune _notify_messages: %w", err)
+	}
+	return nil
+}
+
+// Listener receives messages published to its channels after it started.
+type Listener struct {
+	messages chan Message
+	cancel   context.CancelFunc
+	done     chan struct{}
+	err      error
+}
+
+// Listen starts a Listener for channels. The Listener holds one pooled
+// connection and stops when ctx is done or Close is called.
+func Listen(ctx context.Context, channels []string, opts ListenOptions) (*Listener, error) {
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("listen requires at least one channel")
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = 100 * time.Millisecond
+	}
+	if opts.Buffer <= 0 {
+		opts.Buffer = 64
+	}
+
+	p, err := pool.GetPool()
+	if err != nil {
+		return nil, sqliteutils.FailedToGetPoolError(err)
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		return nil, sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	var lastID int64
+	err = sqlitex.ExecuteScript(conn, createMessagesTable, nil)
+	if err == nil {
+		err = sqlitex.ExecuteTransient(conn, "SELECT COALESCE(MAX(id), 0) FROM _notify_messages;", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				lastID = stmt.ColumnInt64(0)
+				return nil
+			},
+		})
+	}
+	if err != nil {
+		p.Put(conn)
+		return nil, fmt.Errorf("failed to read _notify_messages: %w", err)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	l := &Listener{messages: make(chan Message, opts.Buffer), cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(l.done)
+		defer close(l.messages)
+		defer p.Put(conn)
+		conn.SetInterrupt(runCtx.Done())
+		defer conn.SetInterrupt(nil)
+		l.err = l.run(runCtx, conn, channels, opts.Interval, lastID)
+	}()
+	return l, nil
+}
+
+// Messages returns the channel messages are delivered on, in publish order.
+// It is closed when the Listener stops.
+func (l *Listener) Messages() <-chan Message {
+	return l.messages
+}
+
+// Close stops the Listener and returns the error that stopped it, if any.
+func (l *Listener) Close() error {
+	l.cancel()
+	<-l.done
+	return l.err
+}
+
+func (l *Listener) run(ctx context.Context, conn *sqlite.Conn, channels []string, interval time.Duration, lastID int64) error {
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(channels)), ", ")
+	query := "SELECT id, channel, payload, created_at FROM _notify_messages WHERE id > ? AND channel IN (" + placeholders + ") ORDER BY id;"
+	args := make([]interface{}, 1, len(channels)+1)
+	for _, channel := range channels {
+		args = append(args, channel)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	version := int64(-1)
+	for {
+		v, err := dataVersion(conn)
+		if err != nil {
+			return ignoreCanceled(ctx, err)
+		}
+		if v != version {
+			version = v
+			var batch []Message
+			args[0] = lastID
+			err := sqlitex.Execute(conn, query, &sqlitex.ExecOptions{
+				Args: args,
+				ResultFunc: func(stmt *sqlite.Stmt) error {
+					batch = append(batch, Message{
+						ID:      stmt.ColumnInt64(0),
+						Channel: stmt.ColumnText(1),
+						Payload: stmt.ColumnText(2),
+						Time:    time.UnixMilli(stmt.ColumnInt64(3)),
+					})
+					return nil
+				},
+			})
+			if err != nil {
+				return ignoreCanceled(ctx, fmt.Errorf("failed to read _notify_messages: %w", err))
+			}
+			for _, m := range batch {
+				select {
+				case l.messages <- m:
+					lastID = m.ID
+				case <-ctx.Done():
+					return nil
+				}
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func dataVersion(conn *sqlite.Conn) (int64, error) {
+	var version int64
+	err := sqlitex.ExecuteTransient(conn, "PRAGMA data_version;", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			version = stmt.ColumnInt64(0)
+			return nil
+		},
+	})
+	return version, err
+}
+
+// ignoreCanceled drops errors caused by Close interrupting the connection.
+func ignoreCanceled(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
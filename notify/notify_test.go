@@ -0,0 +1,92 @@
+package notify_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/notify"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func receive(t *testing.T, l *notify.Listener) notify.Message {
+	t.Helper()
+	select {
+	case m := <-l.Messages():
+		return m
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for message")
+		return notify.Message{}
+	}
+}
+
+func TestPublishListen(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "notify.db"), 3))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	// Messages published before Listen are not delivered.
+	require.NoError(t, notify.Publish(ctx, "orders", "old"))
+
+	l, err := notify.Listen(ctx, []string{"orders", "users"}, notify.ListenOptions{Interval: 10 * time.Millisecond})
+	require.NoError(t, err)
+
+	require.NoError(t, notify.Publish(ctx, "orders", "order 1"))
+	require.NoError(t, notify.Publish(ctx, "other", "ignored"))
+	require.NoError(t, notify.Publish(ctx, "users", "user 1"))
+
+	// A message published in a rolled back transaction is never delivered.
+	p, err := pool.GetPool()
+	require.NoError(t, err)
+	conn, err := p.Take(ctx)
+	require.NoError(t, err)
+	func() {
+		err := assert.AnError
+		defer sqlitex.Save(conn)(&err)
+		require.NoError(t, notify.PublishConn(conn, "orders", "rolled back"))
+	}()
+	p.Put(conn)
+	require.NoError(t, notify.Publish(ctx, "orders", "order 2"))
+
+	var got []string
+	for i := 0; i < 3; i++ {
+		m := receive(t, l)
+		got = append(got, m.Channel+": "+m.Payload)
+	}
+	assert.Equal(t, []string{"orders: order 1", "users: user 1", "orders: order 2"}, got)
+
+	require.NoError(t, l.Close())
+	_, open := <-l.Messages()
+	assert.False(t, open)
+
+	_, err = notify.Listen(ctx, nil, notify.ListenOptions{})
+	assert.Error(t, err)
+}
+
+func TestListenContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "notify.db"), 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	l, err := notify.Listen(ctx, []string{"jobs"}, notify.ListenOptions{})
+	require.NoError(t, err)
+	cancel()
+	_, open := <-l.Messages()
+	assert.False(t, open)
+	require.NoError(t, l.Close())
+
+	// The connection went back to the pool.
+	p, err := pool.GetPool()
+	require.NoError(t, err)
+	conn, err := p.Take(context.Background())
+	require.NoError(t, err)
+	p.Put(conn)
+}
@@ -0,0 +1,113 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// CheckpointResult describes a completed CheckpointTruncate.
+type CheckpointResult struct {
+	// Checkpointed is the number of WAL frames, one per page written,
+	// copied into the database file.
+	Checkpointed int64 `json:"checkpointed"`
+	// Remaining is the number of frames left in the WAL, 0 once it has been
+	// truncated.
+	Remaining int64 `json:"remaining"`
+}
+
+// CheckpointBusyError is returned by CheckpointTruncate when readers or a
+// writer hold the WAL. It matches sqliteutils.ErrCheckpointBusy with
+// errors.Is.
+type CheckpointBusyError struct {
+	// Checkpointed is the number of WAL frames copied into the database file
+	// and Frames the number in the WAL. When they are equal, everything was
+	// copied but readers still using the WAL kept it from being truncated.
+	Checkpointed int64
+	Frames       int64
+}
+
+func (e *CheckpointBusyError) Error() string {
+	if e.Checkpointed == e.Frames {
+		return fmt.Sprintf("checkpoint busy: readers hold the wal (%d frames checkpointed)", e.Checkpointed)
+	}
+	return fmt.Sprintf("checkpoint busy: %d of %d wal frames checkpointed", e.Checkpointed, e.Frames)
+}
+
+func (e *CheckpointBusyError) Unwrap() error {
+	return sqliteutils.ErrCheckpointBusy
+}
+
+var (
+	checkpoints     atomic.Int64
+	checkpointsBusy atomic.Int64
+)
+
+// CheckpointTruncate copies the WAL of the global pool's database into the
+// database file and truncates it to zero bytes. It does not wait for readers
+// or writers: if they hold the WAL it returns a *CheckpointBusyError, and the
+// caller may retry later, e.g. once long-running reads have finished.
+func CheckpointTruncate(ctx context.Context) (CheckpointResult, error) {
+	var result CheckpointResult
+	err := WithConn(ctx, func(conn *sqlite.Conn) error {
+		conn.SetInterrupt(ctx.Done())
+		defer conn.SetInterrupt(nil)
+		// Give up on locks at once instead of queueing behind readers, then
+		// restore the busy handler the pool gave the connection.
+		conn.SetBusyTimeout(0)
+		defer restoreBusyHandler(conn)
+
+		// A successful TRUNCATE checkpoint reports no frames, so count them
+		// with a PASSIVE one first.
+		_, _, ckpt, err := walCheckpoint(conn, "PASSIVE")
+		if err != nil {
+			return err
+		}
+		busy, log, truncCkpt, err := walCheckpoint(conn, "TRUNCATE")
+		if err != nil {
+			return err
+		}
+		if busy {
+			checkpointsBusy.Add(1)
+			return &CheckpointBusyError{Checkpointed: truncCkpt, Frames: log}
+		}
+		result = CheckpointResult{Checkpointed: ckpt}
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+	checkpoints.Add(1)
+	checkpointLock.Lock()
+	walGeneration, walGenerationSince = "", time.Now()
+	checkpointLock.Unlock()
+	sqliteutils.Logger().Debug("checkpointed wal", "frames", result.Checkpointed)
+	return result, nil
+}
+
+// walCheckpoint runs PRAGMA wal_checkpoint in mode, returning whether it was
+// blocked, the frames in the WAL and the frames checkpointed.
+func walCheckpoint(conn *sqlite.Conn, mode string) (busy bool, log, ckpt int64, err error) {
+	err = sqlitex.ExecuteTransient(conn, "PRAGMA wal_checkpoint("+mode+");", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			busy, log, ckpt = stmt.ColumnInt64(0) != 0, stmt.ColumnInt64(1), stmt.ColumnInt64(2)
+			return nil
+		},
+	})
+	return busy, log, ckpt, sqliteutils.FailedToCheckpointError(err)
+}
+
+// restoreBusyHandler resets conn to the busy handling newPool sets up.
+func restoreBusyHandler(conn *sqlite.Conn) {
+	parsed, _ := sqliteutils.ParseURI(GetPoolUri())
+	if parsed.BusyTimeout > 0 {
+		conn.SetBusyTimeout(parsed.BusyTimeout)
+	} else {
+		conn.SetBlockOnBusy()
+	}
+}
@@ -0,0 +1,78 @@
+package pool_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestCheckpointTruncate(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "checkpoint.db")
+	if err := pool.InitPool(path, 2, pool.WithPragmas(pool.Pragmas{WALAutoCheckpoint: -1})); err != nil {
+		t.Fatalf("failed to initialize pool: %v", err)
+	}
+	defer func() {
+		if err := pool.ClosePool(); err != nil {
+			t.Errorf("failed to close pool: %v", err)
+		}
+	}()
+	p, err := pool.GetPool()
+	if err != nil {
+		t.Fatalf("failed to get pool: %v", err)
+	}
+	writer, err := p.Take(ctx)
+	if err != nil {
+		t.Fatalf("failed to take connection: %v", err)
+	}
+	err = sqlitex.ExecuteScript(writer, "CREATE TABLE t (x); INSERT INTO t VALUES (1), (2);", nil)
+	p.Put(writer)
+	if err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	// An open read transaction keeps the WAL from being truncated.
+	reader, err := p.Take(ctx)
+	if err != nil {
+		t.Fatalf("failed to take connection: %v", err)
+	}
+	if err := sqlitex.ExecuteTransient(reader, "BEGIN;", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := sqlitex.ExecuteTransient(reader, "SELECT count(*) FROM t;", nil); err != nil {
+		t.Fatal(err)
+	}
+	_, err = pool.CheckpointTruncate(ctx)
+	var busy *pool.CheckpointBusyError
+	if !errors.As(err, &busy) || !errors.Is(err, sqliteutils.ErrCheckpointBusy) {
+		t.Fatalf("expected a CheckpointBusyError, got %v", err)
+	}
+	if busy.Frames == 0 || busy.Checkpointed != busy.Frames {
+		t.Errorf("expected every frame to be checkpointed, got %+v", busy)
+	}
+	if err := sqlitex.ExecuteTransient(reader, "ROLLBACK;", nil); err != nil {
+		t.Fatal(err)
+	}
+	p.Put(reader)
+
+	result, err := pool.CheckpointTruncate(ctx)
+	if err != nil {
+		t.Fatalf("failed to checkpoint: %v", err)
+	}
+	if result.Checkpointed == 0 || result.Remaining != 0 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if info, err := os.Stat(path + "-wal"); err != nil || info.Size() != 0 {
+		t.Errorf("expected an empty WAL, got %v, %v", info, err)
+	}
+	stats := pool.GetStats()
+	if stats.Checkpoints != 1 || stats.CheckpointsBusy != 1 {
+		t.Errorf("unexpected checkpoint stats %+v", stats)
+	}
+}
@@ -0,0 +1,218 @@
+package pool
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/backup"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// FollowerOptions configures a Follower.
+type FollowerOptions struct {
+	// Size is the number of read connections. Defaults to 4.
+	Size int
+	// Interval is how often the archive is checked for new segments.
+	// Defaults to 1s.
+	Interval time.Duration
+	// OnError is called with the errors of background refreshes.
+	OnError func(error)
+}
+
+// FollowerStats describes a Follower.
+type FollowerStats struct {
+	Path string `json:"path"`
+	// LastSync is when the follower was last caught up with the archive.
+	LastSync time.Time `json:"last_sync"`
+	// LastApplied is when the newest change the follower serves was shipped.
+	LastApplied time.Time `json:"last_applied"`
+	// LagMillis is the time since LastSync, which grows while the archive
+	// is unreachable or refreshes fail.
+	LagMillis float64 `json:"lag_ms"`
+	Refreshes int64   `json:"refreshes"`
+}
+
+// Follower is a read-only pool on a local copy of a database archived by a
+// backup.WALShipper, typically on another machine sharing the archive
+// directory. It applies newly shipped segments in the background and
+// reopens its connections on the refreshed file.
+type Follower struct {
+	replica *backup.Replica
+	path    string
+	opts    FollowerOptions
+
+	// mu is held for reading by Do and for writing while the file is
+	// refreshed.
+	mu   sync.RWMutex
+	pool *sqlitex.Pool
+	// syncLock serializes refreshes.
+	syncLock    sync.Mutex
+	lastSync    atomic.Int64
+	lastApplied atomic.Int64
+	refreshes   atomic.Int64
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+var (
+	followers     = map[*Follower]struct{}{}
+	followersLock sync.Mutex
+)
+
+// OpenFollower materializes the archive in the directory archive at path,
+// which only the follower may write, and opens a read-only pool on it that
+// follows the archive until Close.
+func OpenFollower(ctx context.Context, archive, path string, opts FollowerOptions) (*Follower, error) {
+	if opts.Size <= 0 {
+		opts.Size = 4
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = time.Second
+	}
+	f := &Follower{replica: backup.NewReplica(archive, path), path: path, opts: opts}
+	if err := f.replica.Sync(ctx); err != nil {
+		return nil, err
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	f.synced()
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	f.cancel, f.done = cancel, make(chan struct{})
+	go f.run(runCtx)
+
+	followersLock.Lock()
+	followers[f] = struct{}{}
+	followersLock.Unlock()
+	return f, nil
+}
+
+// open opens the pool on the replica file. The file is immutable while the
+// pool is open, so SQLite neither locks it nor looks for a WAL.
+func (f *Follower) open() error {
+	uri := sqliteutils.URI{Path: f.path, Mode: "ro", Immutable: true}.String()
+	p, err := newPool(uri, f.opts.Size, options{})
+	if err != nil {
+		return sqliteutils.FailedToInitPoolError(err, uri)
+	}
+	f.pool = p
+	return nil
+}
+
+// Do calls fn with a connection to the follower's current snapshot. The
+// file is not refreshed while fn runs, so fn must not call Do again.
+func (f *Follower) Do(ctx context.Context, fn func(conn *sqlite.Conn) error) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	conn, err := Take(ctx, f.pool)
+	if err != nil {
+		return sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer f.pool.Put(conn)
+	return fn(conn)
+}
+
+// Refresh applies the segments shipped since the last refresh, waiting for
+// running Do calls to finish before reopening the pool.
+func (f *Follower) Refresh(ctx context.Context) error {
+	f.syncLock.Lock()
+	defer f.syncLock.Unlock()
+
+	pending, err := f.replica.Pending()
+	if err != nil {
+		return err
+	}
+	if !pending {
+		f.synced()
+		return nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := Close(f.pool); err != nil {
+		return sqliteutils.FailedToClosePoolError(err)
+	}
+	syncErr := f.replica.Sync(ctx)
+	// Reopen even when the sync failed, so readers keep the last snapshot.
+	if err := f.open(); err != nil {
+		return err
+	}
+	if syncErr != nil {
+		return syncErr
+	}
+	f.refreshes.Add(1)
+	f.synced()
+	return nil
+}
+
+// synced records that the follower is caught up with the archive.
+func (f *Follower) synced() {
+	f.lastSync.Store(time.Now().UnixNano())
+	f.lastApplied.Store(f.replica.LastApplied().UnixNano())
+}
+
+func (f *Follower) run(ctx context.Context) {
+	defer close(f.done)
+	ticker := time.NewTicker(f.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := f.Refresh(ctx); err != nil && ctx.Err() == nil {
+				sqliteutils.Logger().Warn("failed to refresh follower", "path", f.path, "error", err)
+				if f.opts.OnError != nil {
+					f.opts.OnError(err)
+				}
+			}
+		}
+	}
+}
+
+// Stats returns the current FollowerStats of f.
+func (f *Follower) Stats() FollowerStats {
+	lastSync := time.Unix(0, f.lastSync.Load())
+	return FollowerStats{
+		Path:        f.path,
+		LastSync:    lastSync,
+		LastApplied: time.Unix(0, f.lastApplied.Load()),
+		LagMillis:   float64(time.Since(lastSync)) / float64(time.Millisecond),
+		Refreshes:   f.refreshes.Load(),
+	}
+}
+
+// Close stops following the archive and closes the pool.
+func (f *Follower) Close() error {
+	followersLock.Lock()
+	delete(followers, f)
+	followersLock.Unlock()
+
+	f.cancel()
+	<-f.done
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := Close(f.pool); err != nil {
+		return sqliteutils.FailedToClosePoolError(err)
+	}
+	return nil
+}
+
+// followerStats returns the FollowerStats of every open Follower.
+func followerStats() []FollowerStats {
+	followersLock.Lock()
+	defer followersLock.Unlock()
+	var stats []FollowerStats
+	for f := range followers {
+		stats = append(stats, f.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Path < stats[j].Path })
+	return stats
+}
@@ -0,0 +1,73 @@
+package pool_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/backup"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestFollower(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "source.db")
+	archive := filepath.Join(dir, "archive")
+
+	require.NoError(t, pool.InitPool(dbPath, 2, pool.WithPrepareConn(backup.DisableAutoCheckpoint)))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.E(ctx, "CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT);"))
+	require.NoError(t, exec.E(ctx, "INSERT INTO items (name) VALUES ('a'), ('b');"))
+
+	shipper, err := backup.StartWALShipping(ctx, dbPath, archive, backup.WALShipperOptions{})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, shipper.Close())
+	}()
+
+	// URI characters in the path name the file rather than parameters.
+	follower, err := pool.OpenFollower(ctx, archive, filepath.Join(dir, "follower ?#%41.db"), pool.FollowerOptions{Size: 2, Interval: time.Hour})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, follower.Close())
+	}()
+	count := func() (n int64) {
+		err := follower.Do(ctx, func(conn *sqlite.Conn) error {
+			var err error
+			n, err = sqlitex.ResultInt64(conn.Prep("SELECT COUNT(1) FROM items;"))
+			return err
+		})
+		require.NoError(t, err)
+		return n
+	}
+	assert.Equal(t, int64(2), count())
+
+	// Writes are refused.
+	err = follower.Do(ctx, func(conn *sqlite.Conn) error {
+		return sqlitex.Execute(conn, "INSERT INTO items (name) VALUES ('c');", nil)
+	})
+	assert.Error(t, err)
+
+	require.NoError(t, exec.E(ctx, "INSERT INTO items (name) VALUES ('c');"))
+	require.NoError(t, shipper.Sync(ctx))
+	assert.Equal(t, int64(2), count())
+	require.NoError(t, follower.Refresh(ctx))
+	assert.Equal(t, int64(3), count())
+
+	stats := follower.Stats()
+	assert.Equal(t, int64(1), stats.Refreshes)
+	assert.False(t, stats.LastApplied.IsZero())
+	assert.Less(t, stats.LagMillis, float64(time.Minute/time.Millisecond))
+	followers := pool.GetStats().Followers
+	require.Len(t, followers, 1)
+	assert.Equal(t, stats.Path, followers[0].Path)
+}
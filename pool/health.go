@@ -0,0 +1,164 @@
+package pool
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// HealthReport describes the state of the global pool's database.
+type HealthReport struct {
+	// Reachable reports whether a connection could be taken and queried.
+	Reachable bool `json:"reachable"`
+	// ReadLatency is the time taken to read the schema.
+	ReadLatency time.Duration `json:"read_latency"`
+	// WriteLatency is the time taken to write and delete a row in a temp
+	// table, which does not lock the database.
+	WriteLatency time.Duration `json:"write_latency"`
+	// WALSize is the size of the write-ahead log in bytes, or 0 if the
+	// database does not have one.
+	WALSize int64 `json:"wal_size"`
+	// LastCheckpointAge is the time since a checkpoint was last seen to reset
+	// the WAL. Resets are observed by calls to Health, so it is measured from
+	// pool initialization until the first one is seen.
+	LastCheckpointAge time.Duration `json:"last_checkpoint_age"`
+	// FreePages is the number of unused pages in the database file.
+	FreePages int64 `json:"free_pages"`
+	// Error describes why the database is unreachable.
+	Error string `json:"error,omitempty"`
+}
+
+var (
+	checkpointLock sync.Mutex
+	// walGeneration identifies the WAL header last seen by Health, and
+	// walGenerationSince is when it was first seen.
+	walGeneration      string
+	walGenerationSince time.Time
+	poolInitAt         time.Time
+)
+
+// Health checks the database behind the global pool. The report is returned
+// with Reachable false and the error recorded in it if the check fails.
+func Health(ctx context.Context) (HealthReport, error) {
+	var report HealthReport
+	err := health(ctx, &report)
+	if err != nil {
+		report.Reachable = false
+		report.Error = err.Error()
+	}
+	return report, err
+}
+
+func health(ctx context.Context, report *HealthReport) error {
+	p, err := GetPool()
+	if err != nil {
+		return sqliteutils.FailedToGetPoolError(err)
+	}
+	conn, err := Take(ctx, p)
+	if err != nil {
+		return sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+
+	start := time.Now()
+	if err := sqlitex.ExecuteTransient(conn, "SELECT count(*) FROM sqlite_schema;", nil); err != nil {
+		return fmt.Errorf("failed to read database: %w", err)
+	}
+	report.ReadLatency = time.Since(start)
+	report.Reachable = true
+
+	start = time.Now()
+	err = sqlitex.ExecuteScript(conn, `CREATE TEMP TABLE IF NOT EXISTS _health_check (t INTEGER);
+		INSERT INTO _health_check (t) VALUES (unixepoch());
+		DELETE FROM _health_check;`, nil)
+	if err != nil {
+		return fmt.Errorf("failed to write temp table: %w", err)
+	}
+	report.WriteLatency = time.Since(start)
+
+	err = sqlitex.ExecuteTransient(conn, "PRAGMA freelist_count;", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			report.FreePages = stmt.ColumnInt64(0)
+			return nil
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read freelist_count: %w", err)
+	}
+
+	var path, journalMode string
+	err = sqlitex.ExecuteTransient(conn, "SELECT file FROM pragma_database_list WHERE name = 'main';", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			path = stmt.ColumnText(0)
+			return nil
+		},
+	})
+	if err == nil {
+		err = sqlitex.ExecuteTransient(conn, "PRAGMA journal_mode;", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				journalMode = stmt.ColumnText(0)
+				return nil
+			},
+		})
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read database file: %w", err)
+	}
+	if path == "" || journalMode != "wal" {
+		return nil
+	}
+	generation, size, err := readWALHeader(path + "-wal")
+	if err != nil {
+		return err
+	}
+	report.WALSize = size
+	report.LastCheckpointAge = time.Since(observeWALGeneration(generation))
+	return nil
+}
+
+// readWALHeader returns the checkpoint sequence number and salts of the WAL
+// at path, which change whenever a checkpoint resets it, and its size. A
+// missing or empty WAL has been truncated by a checkpoint.
+func readWALHeader(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to open WAL: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to stat WAL: %w", err)
+	}
+	var header [24]byte
+	if _, err := io.ReadFull(f, header[:]); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return "", info.Size(), nil
+		}
+		return "", 0, fmt.Errorf("failed to read WAL header: %w", err)
+	}
+	return fmt.Sprintf("%d-%x", binary.BigEndian.Uint32(header[12:16]), header[16:24]), info.Size(), nil
+}
+
+// observeWALGeneration records generation and returns when it was first seen.
+func observeWALGeneration(generation string) time.Time {
+	checkpointLock.Lock()
+	defer checkpointLock.Unlock()
+	if walGenerationSince.IsZero() {
+		walGeneration, walGenerationSince = generation, poolInitAt
+	} else if generation != walGeneration {
+		walGeneration, walGenerationSince = generation, time.Now()
+	}
+	return walGenerationSince
+}
@@ -0,0 +1,76 @@
+package pool_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestHealth(t *testing.T) {
+	ctx := context.Background()
+	if _, err := pool.Health(ctx); err == nil {
+		t.Fatal("expected an error without a pool")
+	}
+
+	uri := "file:" + filepath.Join(t.TempDir(), "health.db")
+	if err := pool.InitPool(uri, 2); err != nil {
+		t.Fatalf("failed to initialize pool: %v", err)
+	}
+	defer func() {
+		if err := pool.ClosePool(); err != nil {
+			t.Errorf("failed to close pool: %v", err)
+		}
+	}()
+
+	exec := func(script string) {
+		p, err := pool.GetPool()
+		if err != nil {
+			t.Fatalf("failed to get pool: %v", err)
+		}
+		conn, err := p.Take(ctx)
+		if err != nil {
+			t.Fatalf("failed to take connection: %v", err)
+		}
+		defer p.Put(conn)
+		if err := sqlitex.ExecuteScript(conn, script, nil); err != nil {
+			t.Fatalf("failed to execute %q: %v", script, err)
+		}
+	}
+	exec("CREATE TABLE t (v); INSERT INTO t VALUES (randomblob(10000)); DELETE FROM t;")
+	time.Sleep(20 * time.Millisecond)
+
+	report, err := pool.Health(ctx)
+	if err != nil {
+		t.Fatalf("health check failed: %v", err)
+	}
+	if !report.Reachable || report.Error != "" {
+		t.Errorf("expected a reachable database, got %+v", report)
+	}
+	if report.ReadLatency <= 0 || report.WriteLatency <= 0 {
+		t.Errorf("expected latencies to be measured, got %+v", report)
+	}
+	if report.WALSize == 0 {
+		t.Error("expected a non-empty WAL")
+	}
+	if report.LastCheckpointAge < 20*time.Millisecond {
+		t.Errorf("expected the checkpoint age to be measured from pool initialization, got %v", report.LastCheckpointAge)
+	}
+	before := report.LastCheckpointAge
+
+	// A truncating checkpoint and a later write start a new WAL.
+	exec("PRAGMA wal_checkpoint(TRUNCATE); INSERT INTO t VALUES (1);")
+	report, err = pool.Health(ctx)
+	if err != nil {
+		t.Fatalf("health check failed: %v", err)
+	}
+	if report.LastCheckpointAge >= before {
+		t.Errorf("expected the checkpoint age to reset, got %v after %v", report.LastCheckpointAge, before)
+	}
+	if report.FreePages == 0 {
+		t.Error("expected the deleted blob to leave free pages")
+	}
+}
@@ -0,0 +1,122 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Finding is one problem reported by IntegrityCheck or ForeignKeyCheck.
+type Finding struct {
+	// Table is the table the problem was found in, when known.
+	Table string `json:"table,omitempty"`
+	// RowID is the rowid of the offending row, when known.
+	RowID int64 `json:"rowid,omitempty"`
+	// Description is SQLite's message, or a description of the foreign key
+	// violation.
+	Description string `json:"description"`
+}
+
+// integrityPatterns extract the table or index and the rowid named by the
+// messages of PRAGMA integrity_check.
+var integrityPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^row (?P<rowid>\d+) missing from index (?P<index>\S+)$`),
+	regexp.MustCompile(`^(?:wrong # of entries|non-unique entry) in index (?P<index>\S+)$`),
+	regexp.MustCompile(`^\w+ value in (?P<table>[^.\s]+)\.\S+$`),
+	regexp.MustCompile(`^CHECK constraint failed in (?P<table>\S+)$`),
+	regexp.MustCompile(`^[Rr]owid (?P<rowid>-?\d+) out of order`),
+}
+
+// IntegrityCheck runs PRAGMA integrity_check, or the faster quick_check that
+// skips verifying indexes against their tables, on the global pool's
+// database and returns its findings. No findings means the database is
+// intact. The table and rowid are filled in for the messages that name them.
+func IntegrityCheck(ctx context.Context, quick bool) ([]Finding, error) {
+	var findings []Finding
+	err := WithConn(ctx, func(conn *sqlite.Conn) error {
+		conn.SetInterrupt(ctx.Done())
+		defer conn.SetInterrupt(nil)
+
+		indexTables := map[string]string{}
+		err := sqlitex.ExecuteTransient(conn, "SELECT name, tbl_name FROM sqlite_schema WHERE type = 'index';", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				indexTables[stmt.ColumnText(0)] = stmt.ColumnText(1)
+				return nil
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read indexes: %w", err)
+		}
+
+		pragma := "PRAGMA integrity_check;"
+		if quick {
+			pragma = "PRAGMA quick_check;"
+		}
+		return sqlitex.ExecuteTransient(conn, pragma, &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				if msg := stmt.ColumnText(0); msg != "ok" {
+					findings = append(findings, integrityFinding(msg, indexTables))
+				}
+				return nil
+			},
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check integrity: %w", err)
+	}
+	return findings, nil
+}
+
+// integrityFinding parses one message of PRAGMA integrity_check.
+func integrityFinding(msg string, indexTables map[string]string) Finding {
+	finding := Finding{Description: msg}
+	for _, pattern := range integrityPatterns {
+		match := pattern.FindStringSubmatch(msg)
+		if match == nil {
+			continue
+		}
+		for i, name := range pattern.SubexpNames() {
+			switch name {
+			case "rowid":
+				finding.RowID, _ = strconv.ParseInt(match[i], 10, 64)
+			case "table":
+				finding.Table = match[i]
+			case "index":
+				finding.Table = indexTables[match[i]]
+			}
+		}
+		break
+	}
+	return finding
+}
+
+// ForeignKeyCheck runs PRAGMA foreign_key_check on the global pool's
+// database and returns a finding for every row whose foreign key refers to
+// a missing parent row. Such rows exist when foreign keys were off while
+// they were written, e.g. in databases created by other tools.
+func ForeignKeyCheck(ctx context.Context) ([]Finding, error) {
+	var findings []Finding
+	err := WithConn(ctx, func(conn *sqlite.Conn) error {
+		conn.SetInterrupt(ctx.Done())
+		defer conn.SetInterrupt(nil)
+		return sqlitex.ExecuteTransient(conn, "PRAGMA foreign_key_check;", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				// The rowid is NULL for WITHOUT ROWID tables.
+				findings = append(findings, Finding{
+					Table:       stmt.ColumnText(0),
+					RowID:       stmt.ColumnInt64(1),
+					Description: fmt.Sprintf("foreign key %d refers to a missing row in %s", stmt.ColumnInt64(3), stmt.ColumnText(2)),
+				})
+				return nil
+			},
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check foreign keys: %w", err)
+	}
+	return findings, nil
+}
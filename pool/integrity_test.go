@@ -0,0 +1,88 @@
+package pool_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestIntegrityCheck(t *testing.T) {
+	ctx := context.Background()
+	if err := pool.InitPool("file:"+filepath.Join(t.TempDir(), "integrity.db"), 1); err != nil {
+		t.Fatalf("failed to initialize pool: %v", err)
+	}
+	defer func() {
+		if err := pool.ClosePool(); err != nil {
+			t.Errorf("failed to close pool: %v", err)
+		}
+	}()
+	p, err := pool.GetPool()
+	if err != nil {
+		t.Fatalf("failed to get pool: %v", err)
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		t.Fatalf("failed to take connection: %v", err)
+	}
+	err = sqlitex.ExecuteScript(conn, `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id));
+		INSERT INTO users (name) VALUES ('alice'), (NULL);
+		INSERT INTO orders (user_id) VALUES (1);`, nil)
+	if err != nil {
+		t.Fatalf("failed to create tables: %v", err)
+	}
+	p.Put(conn)
+
+	for _, quick := range []bool{false, true} {
+		findings, err := pool.IntegrityCheck(ctx, quick)
+		if err != nil {
+			t.Fatalf("failed to check integrity: %v", err)
+		}
+		if len(findings) != 0 {
+			t.Errorf("expected no findings, got %+v", findings)
+		}
+	}
+	if findings, err := pool.ForeignKeyCheck(ctx); err != nil || len(findings) != 0 {
+		t.Fatalf("expected no foreign key findings, got %+v, %v", findings, err)
+	}
+
+	// Write an orphaned order with foreign keys off, and declare a NOT NULL
+	// constraint the existing rows break behind SQLite's back.
+	conn, err = p.Take(ctx)
+	if err != nil {
+		t.Fatalf("failed to take connection: %v", err)
+	}
+	// foreign_keys cannot change inside the savepoint of ExecuteScript.
+	for _, query := range []string{
+		"PRAGMA foreign_keys = OFF;",
+		"INSERT INTO orders (id, user_id) VALUES (7, 42);",
+		"PRAGMA foreign_keys = ON;",
+		"PRAGMA writable_schema = ON;",
+		"UPDATE sqlite_schema SET sql = 'CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)' WHERE name = 'users';",
+		"PRAGMA writable_schema = RESET;",
+	} {
+		if err := sqlitex.ExecuteTransient(conn, query, nil); err != nil {
+			t.Fatalf("failed to run %s: %v", query, err)
+		}
+	}
+	p.Put(conn)
+
+	findings, err := pool.IntegrityCheck(ctx, false)
+	if err != nil {
+		t.Fatalf("failed to check integrity: %v", err)
+	}
+	if len(findings) != 1 || findings[0].Table != "users" || findings[0].Description != "NULL value in users.name" {
+		t.Errorf("unexpected findings %+v", findings)
+	}
+
+	findings, err = pool.ForeignKeyCheck(ctx)
+	if err != nil {
+		t.Fatalf("failed to check foreign keys: %v", err)
+	}
+	if len(findings) != 1 || findings[0].Table != "orders" || findings[0].RowID != 7 {
+		t.Errorf("unexpected foreign key findings %+v", findings)
+	}
+}
@@ -0,0 +1,121 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// AnalyzeEvery is how many automatic PRAGMA optimize runs pass between full
+// ANALYZE runs.
+const AnalyzeEvery = 24
+
+// idleTakeTimeout is how long a scheduled optimization waits for a free
+// connection before skipping its turn.
+const idleTakeTimeout = 10 * time.Millisecond
+
+var (
+	autoOptimizeLock sync.Mutex
+	autoOptimizeStop func()
+)
+
+// Optimize runs PRAGMA optimize on a connection from the global pool, which
+// updates the query planner's statistics for tables whose contents changed
+// enough to matter.
+func Optimize(ctx context.Context) error {
+	return WithConn(ctx, func(conn *sqlite.Conn) error {
+		if err := sqlitex.ExecuteTransient(conn, "PRAGMA optimize;", nil); err != nil {
+			return fmt.Errorf("failed to optimize: %w", err)
+		}
+		return nil
+	})
+}
+
+// Analyze runs a full ANALYZE on a connection from the global pool,
+// gathering statistics for every table and index.
+func Analyze(ctx context.Context) error {
+	return WithConn(ctx, func(conn *sqlite.Conn) error {
+		if err := sqlitex.ExecuteTransient(conn, "ANALYZE;", nil); err != nil {
+			return fmt.Errorf("failed to analyze: %w", err)
+		}
+		return nil
+	})
+}
+
+// EnableAutoOptimize runs PRAGMA optimize on the global pool every interval,
+// and a full ANALYZE instead every AnalyzeEvery runs, until the returned
+// stop function is called. A run is skipped when no connection is idle or
+// the pool is not initialized, and failures are logged. Enabling it again
+// replaces the previous schedule. interval must be positive.
+func EnableAutoOptimize(interval time.Duration) (stop func(), err error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid auto optimize interval %v", interval)
+	}
+
+	autoOptimizeLock.Lock()
+	defer autoOptimizeLock.Unlock()
+	if autoOptimizeStop != nil {
+		autoOptimizeStop()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for runs := 1; ; {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if autoOptimize(ctx, runs%AnalyzeEvery == 0) {
+				runs++
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			cancel()
+			<-done
+		})
+	}
+	autoOptimizeStop = stop
+	return stop, nil
+}
+
+// autoOptimize runs one scheduled optimization and reports whether it ran.
+func autoOptimize(ctx context.Context, analyze bool) bool {
+	p, err := GetPool()
+	if err != nil {
+		return false
+	}
+	takeCtx, cancel := context.WithTimeout(ctx, idleTakeTimeout)
+	defer cancel()
+	conn, err := Take(takeCtx, p)
+	if err != nil {
+		return false
+	}
+	defer p.Put(conn)
+	// Only the wait for the connection is bounded, not the optimization.
+	conn.SetInterrupt(ctx.Done())
+
+	query := "PRAGMA optimize;"
+	if analyze {
+		query = "ANALYZE;"
+	}
+	if err := sqlitex.ExecuteTransient(conn, query, nil); err != nil {
+		sqliteutils.Logger().Warn("failed to optimize database", "analyze", analyze, "error", err)
+	} else {
+		sqliteutils.Logger().Debug("optimized database", "analyze", analyze)
+	}
+	return true
+}
@@ -0,0 +1,112 @@
+package pool_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestOptimize(t *testing.T) {
+	ctx := context.Background()
+	if err := pool.Optimize(ctx); err == nil {
+		t.Fatal("expected an error without a pool")
+	}
+
+	uri := "file:" + filepath.Join(t.TempDir(), "optimize.db")
+	if err := pool.InitPool(uri, 2); err != nil {
+		t.Fatalf("failed to initialize pool: %v", err)
+	}
+	defer func() {
+		if err := pool.ClosePool(); err != nil {
+			t.Errorf("failed to close pool: %v", err)
+		}
+	}()
+
+	// statRows counts the rows of sqlite_stat1, which ANALYZE fills.
+	statRows := func() int {
+		p, err := pool.GetPool()
+		if err != nil {
+			t.Fatalf("failed to get pool: %v", err)
+		}
+		conn, err := p.Take(ctx)
+		if err != nil {
+			t.Fatalf("failed to take connection: %v", err)
+		}
+		defer p.Put(conn)
+		n := 0
+		err = sqlitex.ExecuteTransient(conn, "SELECT count(*) FROM sqlite_schema s, sqlite_stat1 WHERE s.name = 'sqlite_stat1';", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				n = stmt.ColumnInt(0)
+				return nil
+			},
+		})
+		if err != nil {
+			return 0
+		}
+		return n
+	}
+
+	p, err := pool.GetPool()
+	if err != nil {
+		t.Fatalf("failed to get pool: %v", err)
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		t.Fatalf("failed to take connection: %v", err)
+	}
+	err = sqlitex.ExecuteScript(conn, `
+		CREATE TABLE items (id INTEGER PRIMARY KEY, kind TEXT);
+		CREATE INDEX items_kind ON items (kind);
+		WITH RECURSIVE seq(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM seq WHERE i < 100)
+		INSERT INTO items (kind) SELECT 'k' || (i % 5) FROM seq;
+	`, nil)
+	p.Put(conn)
+	if err != nil {
+		t.Fatalf("failed to create items: %v", err)
+	}
+
+	if err := pool.Optimize(ctx); err != nil {
+		t.Fatalf("Optimize failed: %v", err)
+	}
+
+	if statRows() == 0 {
+		t.Fatal("expected Optimize to gather statistics")
+	}
+
+	// Clear the statistics and wait for the scheduler to gather them again.
+	conn, err = p.Take(ctx)
+	if err != nil {
+		t.Fatalf("failed to take connection: %v", err)
+	}
+	err = sqlitex.ExecuteTransient(conn, "DELETE FROM sqlite_stat1;", nil)
+	p.Put(conn)
+	if err != nil {
+		t.Fatalf("failed to clear statistics: %v", err)
+	}
+	if _, err := pool.EnableAutoOptimize(0); err == nil {
+		t.Fatal("expected an error for a zero interval")
+	}
+	stop, err := pool.EnableAutoOptimize(time.Millisecond)
+	if err != nil {
+		t.Fatalf("EnableAutoOptimize failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for statRows() == 0 {
+		if time.Now().After(deadline) {
+			stop()
+			t.Fatal("scheduled optimization did not run")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	stop()
+	stop()
+
+	if err := pool.Analyze(ctx); err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+}
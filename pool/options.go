@@ -0,0 +1,41 @@
+package pool
+
+import "zombiezen.com/go/sqlite"
+
+// Option configures the global pool at InitPool time.
+type Option func(*options)
+
+type options struct {
+	prepareConns  []func(conn *sqlite.Conn) error
+	unicodeNoCase bool
+	pragmas       Pragmas
+	values        map[interface{}]interface{}
+}
+
+func newOptions(opts []Option) options {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithPrepareConn registers an additional setup function that is run on every
+// pooled connection before it is first handed out.
+// Functions run in registration order, after foreign keys have been enabled
+// and the built-in functions registered, so they can use or replace them.
+func WithPrepareConn(fn func(conn *sqlite.Conn) error) Option {
+	return func(o *options) {
+		o.prepareConns = append(o.prepareConns, fn)
+	}
+}
+
+// WithUnicodeNoCase replaces the built-in NOCASE collation, which only folds
+// ASCII letters, with NOCASE_UNICODE on every pooled connection, so columns
+// declared COLLATE NOCASE compare and sort non-ASCII text correctly. Run
+// REINDEX NOCASE once after enabling it on an existing database.
+func WithUnicodeNoCase() Option {
+	return func(o *options) {
+		o.unicodeNoCase = true
+	}
+}
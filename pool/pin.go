@@ -0,0 +1,173 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// ErrReleased is returned by the methods of a Pinned that has been released.
+var ErrReleased = errors.New("pinned connection has been released")
+
+// Pinned holds one connection of the global pool until it is released, for
+// state SQLite keeps per connection: temporary tables, last_insert_rowid,
+// sessions and PRAGMA settings. A Pinned is not safe for concurrent use.
+type Pinned struct {
+	p    *sqlitex.Pool
+	conn *sqlite.Conn
+	// pragmas and databases hold the settings of connPragmas and the
+	// attached databases the connection had when it was pinned.
+	pragmas   map[string]string
+	databases map[string]bool
+}
+
+// connPragmas are the per-connection PRAGMAs Release restores.
+var connPragmas = []string{
+	"automatic_index", "busy_timeout", "cache_size", "cache_spill", "cell_size_check",
+	"defer_foreign_keys", "foreign_keys", "ignore_check_constraints", "mmap_size", "query_only",
+	"recursive_triggers", "reverse_unordered_selects", "synchronous", "temp_store", "trusted_schema",
+}
+
+// Pin takes a connection from the global pool and keeps it until Release is
+// called. Statements run on it are interrupted once ctx is done.
+func Pin(ctx context.Context) (*Pinned, error) {
+	p, err := GetPool()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := Take(ctx, p)
+	if err != nil {
+		return nil, sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	pin := &Pinned{p: p, conn: conn, pragmas: map[string]string{}}
+	if err := pin.snapshot(); err != nil {
+		p.Put(conn)
+		return nil, err
+	}
+	return pin, nil
+}
+
+// snapshot records the settings Release restores.
+func (pin *Pinned) snapshot() error {
+	for _, pragma := range connPragmas {
+		err := sqlitex.ExecuteTransient(pin.conn, "PRAGMA "+pragma+";", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				pin.pragmas[pragma] = stmt.ColumnText(0)
+				return nil
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read PRAGMA %s: %w", pragma, err)
+		}
+	}
+	var err error
+	pin.databases, err = databases(pin.conn)
+	return err
+}
+
+// databases returns the names of the databases attached to conn, other
+// than main and temp.
+func databases(conn *sqlite.Conn) (map[string]bool, error) {
+	names := map[string]bool{}
+	err := sqlitex.ExecuteTransient(conn, "SELECT name FROM pragma_database_list WHERE name NOT IN ('main', 'temp');", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			names[stmt.ColumnText(0)] = true
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attached databases: %w", err)
+	}
+	return names, nil
+}
+
+// Conn returns the pinned connection, or nil once it has been released.
+func (pin *Pinned) Conn() *sqlite.Conn {
+	return pin.conn
+}
+
+// Do runs fn with the pinned connection.
+func (pin *Pinned) Do(fn func(conn *sqlite.Conn) error) error {
+	if pin.conn == nil {
+		return ErrReleased
+	}
+	return fn(pin.conn)
+}
+
+// Release returns the connection to the pool in the state it was pinned in:
+// a transaction left open is rolled back, temporary tables, views and
+// triggers are dropped, databases attached since Pin are detached and the
+// PRAGMAs it changed are restored. Sessions must be deleted by the caller.
+// Release does nothing if already called.
+func (pin *Pinned) Release() error {
+	if pin.conn == nil {
+		return nil
+	}
+	conn := pin.conn
+	pin.conn = nil
+	defer pin.p.Put(conn)
+	if !conn.AutocommitEnabled() {
+		if err := sqlitex.ExecuteTransient(conn, "ROLLBACK;", nil); err != nil {
+			return fmt.Errorf("failed to rollback transaction left open on pinned connection: %w", err)
+		}
+	}
+	if err := dropTemp(conn); err != nil {
+		return err
+	}
+	attached, err := databases(conn)
+	if err != nil {
+		return err
+	}
+	for name := range attached {
+		if !pin.databases[name] {
+			if err := sqlitex.ExecuteTransient(conn, "DETACH DATABASE "+sqliteutils.QuoteIdentifier(name)+";", nil); err != nil {
+				return fmt.Errorf("failed to detach %s from pinned connection: %w", name, err)
+			}
+		}
+	}
+	for _, pragma := range connPragmas {
+		var value string
+		err := sqlitex.ExecuteTransient(conn, "PRAGMA "+pragma+";", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				value = stmt.ColumnText(0)
+				return nil
+			},
+		})
+		if err == nil && value != pin.pragmas[pragma] {
+			err = sqlitex.ExecuteTransient(conn, "PRAGMA "+pragma+" = "+pin.pragmas[pragma]+";", nil)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to restore PRAGMA %s on pinned connection: %w", pragma, err)
+		}
+	}
+	return nil
+}
+
+// dropTemp drops the temporary triggers, views and tables of conn.
+func dropTemp(conn *sqlite.Conn) error {
+	type object struct{ typ, name string }
+	var objects []object
+	err := sqlitex.ExecuteTransient(conn, `SELECT type, name FROM temp.sqlite_schema
+		WHERE type IN ('trigger', 'view', 'table') AND name NOT LIKE 'sqlite_%'
+		ORDER BY CASE type WHEN 'trigger' THEN 0 WHEN 'view' THEN 1 ELSE 2 END;`, &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			objects = append(objects, object{stmt.ColumnText(0), stmt.ColumnText(1)})
+			return nil
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list temporary objects: %w", err)
+	}
+	for _, o := range objects {
+		query := fmt.Sprintf("DROP %s IF EXISTS temp.%s;", strings.ToUpper(o.typ), sqliteutils.QuoteIdentifier(o.name))
+		if err := sqlitex.ExecuteTransient(conn, query, nil); err != nil {
+			return fmt.Errorf("failed to drop temporary %s %s: %w", o.typ, o.name, err)
+		}
+	}
+	return nil
+}
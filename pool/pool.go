@@ -2,23 +2,31 @@ package pool
 
 import (
 	"sync"
+	"time"
 
 	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/udf"
 	"zombiezen.com/go/sqlite"
 	"zombiezen.com/go/sqlite/sqlitex"
 )
 
 var (
 	poolUri  string
+	poolSize int
+	poolOpts options
 	pool     *sqlitex.Pool
 	poolLock sync.Mutex
 )
 
 // InitPool initializes the global pool with the given directory.
 // It should be called once during application startup.
-func InitPool(uri string, poolSize int) error {
+// Options are remembered and reapplied by ResetPool.
+func InitPool(uri string, poolSize int, opts ...Option) error {
 	poolLock.Lock()
 	defer poolLock.Unlock()
+	if pool == nil {
+		poolOpts = newOptions(opts)
+	}
 	return initPoolUnlocked(uri, poolSize)
 }
 
@@ -69,7 +77,7 @@ func SetPool(newPool *sqlitex.Pool) error {
 	defer poolLock.Unlock()
 
 	if pool != nil {
-		if err := pool.Close(); err != nil {
+		if err := Close(pool); err != nil {
 			return sqliteutils.FailedToClosePoolError(err)
 		}
 	}
@@ -82,24 +90,87 @@ func SetPool(newPool *sqlitex.Pool) error {
 
 // initPoolUnlocked initializes the pool without locking.
 // Assumes that the caller holds the poolLock.
-func initPoolUnlocked(uri string, poolSize int) error {
+func initPoolUnlocked(uri string, size int) error {
 	if pool != nil {
 		return nil // Pool already initialized
 	}
 
 	poolUri = uri
+	poolSize = size
 
 	var err error
-	pool, err = sqlitex.NewPool(uri, sqlitex.PoolOptions{
+	pool, err = newPool(uri, size, poolOpts)
+	if err != nil {
+		return sqliteutils.FailedToInitPoolError(err, poolUri)
+	}
+
+	checkpointLock.Lock()
+	poolInitAt, walGenerationSince = time.Now(), time.Time{}
+	checkpointLock.Unlock()
+
+	sqliteutils.Logger().Debug("initialized pool", "uri", uri, "size", size)
+	return nil
+}
+
+// Open opens a pool configured like the global pool, for callers that need
+// more than one database, such as one per tenant. The caller owns the pool
+// and must close it with Close.
+func Open(uri string, size int, opts ...Option) (*sqlitex.Pool, error) {
+	p, err := newPool(uri, size, newOptions(opts))
+	if err != nil {
+		return nil, sqliteutils.FailedToInitPoolError(err, uri)
+	}
+	return p, nil
+}
+
+// newPool opens a pool on uri with foreign keys enabled, the pragmas and udf
+// collations from opts, the regexp and reverse UDFs, and then the caller's
+// connection setup from opts.
+func newPool(uri string, size int, opts options) (*sqlitex.Pool, error) {
+	// URIs this module cannot parse are still passed to SQLite as is.
+	parsed, _ := sqliteutils.ParseURI(uri)
+	pragmas, err := opts.pragmas.statements()
+	if err != nil {
+		return nil, err
+	}
+
+	var values *poolValues
+	if len(opts.values) > 0 {
+		values = &poolValues{values: opts.values, conns: map[*sqlite.Conn]bool{}}
+	}
+
+	p, err := sqlitex.NewPool(uri, sqlitex.PoolOptions{
 		Flags:    sqlite.OpenReadWrite | sqlite.OpenCreate | sqlite.OpenWAL | sqlite.OpenURI,
-		PoolSize: poolSize,
+		PoolSize: size,
 		PrepareConn: func(conn *sqlite.Conn) error {
+			// Attach the values of WithConnValue
+			if values != nil {
+				values.add(conn)
+			}
+			if parsed.BusyTimeout > 0 {
+				conn.SetBusyTimeout(parsed.BusyTimeout)
+			}
 			// Enable foreign keys for this connection
-			if err = sqlitex.Execute(conn, "PRAGMA foreign_keys = ON;", nil); err != nil {
+			if err := sqlitex.Execute(conn, "PRAGMA foreign_keys = ON;", nil); err != nil {
 				return sqliteutils.FailedToEnableForeignKeysError(err)
 			}
+			// Apply the performance pragmas of presets and WithPragmas
+			if err := execPragmas(conn, pragmas); err != nil {
+				return err
+			}
+			if err := opts.pragmas.applyAutoVacuum(conn); err != nil {
+				return err
+			}
+			// Register the Unicode-aware collations
+			if err := udf.SetCollations(conn, opts.unicodeNoCase); err != nil {
+				return err
+			}
+			// Make the REGEXP operator usable
+			if err := conn.CreateFunction("regexp", udf.Regexp()); err != nil {
+				return err
+			}
 			// Create reverse UDF
-			return conn.CreateFunction("reverse", &sqlite.FunctionImpl{
+			if err := conn.CreateFunction("reverse", &sqlite.FunctionImpl{
 				NArgs:         1,
 				Deterministic: true,
 				AllowIndirect: true,
@@ -111,14 +182,24 @@ func initPoolUnlocked(uri string, poolSize int) error {
 					}
 					return sqlite.TextValue(string(runes)), nil
 				},
-			})
+			}); err != nil {
+				return err
+			}
+			// Run any caller-supplied connection setup, which can use or
+			// replace the built-in functions
+			for _, prepare := range opts.prepareConns {
+				if err := prepare(conn); err != nil {
+					return err
+				}
+			}
+			return nil
 		},
 	})
 	if err != nil {
-		return sqliteutils.FailedToInitPoolError(err, poolUri)
+		return nil, err
 	}
-
-	return nil
+	trackValues(p, values)
+	return p, nil
 }
 
 // closePoolUnlocked closes the pool without locking.
@@ -128,11 +209,13 @@ func closePoolUnlocked() error {
 		return sqliteutils.ErrPoolNotInitialized
 	}
 
-	err := pool.Close()
+	err := Close(pool)
 	if err != nil {
 		return sqliteutils.FailedToClosePoolError(err)
 	}
+	sqliteutils.Logger().Debug("closed pool", "uri", poolUri)
 	pool = nil
 	poolUri = ""
+	poolSize = 0
 	return nil
 }
@@ -5,6 +5,8 @@ import (
 	"testing"
 
 	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
 )
 
 func TestReverseUDF(t *testing.T) {
@@ -79,3 +81,53 @@ func TestReverseUDF(t *testing.T) {
 		}
 	}
 }
+
+func TestPrepareConnAfterBuiltins(t *testing.T) {
+	ctx := context.Background()
+	// The caller's setup can use the built-in functions and replace them.
+	var reversed string
+	prepare := func(conn *sqlite.Conn) error {
+		err := sqlitex.ExecuteTransient(conn, "SELECT reverse('ab');", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				reversed = stmt.ColumnText(0)
+				return nil
+			},
+		})
+		if err != nil {
+			return err
+		}
+		return conn.CreateFunction("reverse", &sqlite.FunctionImpl{
+			NArgs: 1,
+			Scalar: func(ctx sqlite.Context, args []sqlite.Value) (sqlite.Value, error) {
+				return sqlite.TextValue("replaced"), nil
+			},
+		})
+	}
+	if err := pool.InitPool("file::memory:?mode=memory&cache=shared", 1, pool.WithPrepareConn(prepare)); err != nil {
+		t.Fatalf("failed to initialize pool: %v", err)
+	}
+	defer func() {
+		if err := pool.ClosePool(); err != nil {
+			t.Errorf("failed to close pool: %v", err)
+		}
+	}()
+
+	var result string
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		return sqlitex.ExecuteTransient(conn, "SELECT reverse('ab');", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				result = stmt.ColumnText(0)
+				return nil
+			},
+		})
+	})
+	if err != nil {
+		t.Fatalf("failed to query: %v", err)
+	}
+	if reversed != "ba" {
+		t.Errorf("reverse in PrepareConn = %q; want %q", reversed, "ba")
+	}
+	if result != "replaced" {
+		t.Errorf("reverse = %q; want the caller's %q", result, "replaced")
+	}
+}
@@ -0,0 +1,227 @@
+package pool
+
+import (
+	"fmt"
+	"strings"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Pragmas are performance settings applied to every pooled connection.
+// Zero fields leave the SQLite default in place.
+type Pragmas struct {
+	// JournalMode is the journal_mode, e.g. WAL or DELETE. Pooled
+	// connections are opened in WAL mode already.
+	JournalMode string
+	// Synchronous is OFF, NORMAL, FULL or EXTRA.
+	Synchronous string
+	// CacheSizeKiB is the page cache size of each connection in KiB.
+	CacheSizeKiB int
+	// MmapSize is the number of bytes of the database file to memory-map.
+	MmapSize int64
+	// TempStore is DEFAULT, FILE or MEMORY.
+	TempStore string
+	// WALAutoCheckpoint is the WAL size in pages that triggers a checkpoint.
+	// A negative value turns automatic checkpoints off.
+	WALAutoCheckpoint int
+	// JournalSizeLimit is the size in bytes the WAL or rollback journal is
+	// truncated to after a checkpoint or transaction, so one large write
+	// does not leave a large file behind. A negative value removes the limit.
+	JournalSizeLimit int64
+	// AutoVacuum is NONE, FULL or INCREMENTAL. It is applied to a database
+	// without tables; others keep their mode until they are vacuumed.
+	AutoVacuum string
+}
+
+var (
+	// PresetLowLatency keeps commits cheap and small checkpoints frequent:
+	// WAL, synchronous NORMAL, a 32 MiB cache, 256 MiB of mmap, in-memory
+	// temporary tables and a checkpoint every 1000 pages. Committed
+	// transactions survive application crashes; the last ones may be lost
+	// on power failure, but the database stays consistent.
+	PresetLowLatency = WithPragmas(Pragmas{
+		JournalMode:       "WAL",
+		Synchronous:       "NORMAL",
+		CacheSizeKiB:      32 << 10,
+		MmapSize:          256 << 20,
+		TempStore:         "MEMORY",
+		WALAutoCheckpoint: 1000,
+	})
+
+	// PresetReadHeavy favors concurrent readers of a large database: WAL,
+	// synchronous NORMAL, a 64 MiB cache, 1 GiB of mmap, in-memory temporary
+	// tables and a checkpoint every 1000 pages, which keeps the WAL readers
+	// have to search short.
+	PresetReadHeavy = WithPragmas(Pragmas{
+		JournalMode:       "WAL",
+		Synchronous:       "NORMAL",
+		CacheSizeKiB:      64 << 10,
+		MmapSize:          1 << 30,
+		TempStore:         "MEMORY",
+		WALAutoCheckpoint: 1000,
+	})
+
+	// PresetBulkLoad favors write throughput for imports: WAL, synchronous
+	// OFF, a 256 MiB cache, in-memory temporary tables and a checkpoint
+	// every 10000 pages. An operating system crash or power failure during
+	// the load can corrupt the database, so only use it for data that can be
+	// loaded again, and switch to another preset with ResetPool afterwards.
+	PresetBulkLoad = WithPragmas(Pragmas{
+		JournalMode:       "WAL",
+		Synchronous:       "OFF",
+		CacheSizeKiB:      256 << 10,
+		TempStore:         "MEMORY",
+		WALAutoCheckpoint: 10000,
+	})
+)
+
+// WithPragmas applies p to every pooled connection before the functions
+// registered with WithPrepareConn run. Nonzero fields override those of
+// earlier WithPragmas options and presets.
+func WithPragmas(p Pragmas) Option {
+	return func(o *options) {
+		if p.JournalMode != "" {
+			o.pragmas.JournalMode = p.JournalMode
+		}
+		if p.Synchronous != "" {
+			o.pragmas.Synchronous = p.Synchronous
+		}
+		if p.CacheSizeKiB != 0 {
+			o.pragmas.CacheSizeKiB = p.CacheSizeKiB
+		}
+		if p.MmapSize != 0 {
+			o.pragmas.MmapSize = p.MmapSize
+		}
+		if p.TempStore != "" {
+			o.pragmas.TempStore = p.TempStore
+		}
+		if p.WALAutoCheckpoint != 0 {
+			o.pragmas.WALAutoCheckpoint = p.WALAutoCheckpoint
+		}
+		if p.JournalSizeLimit != 0 {
+			o.pragmas.JournalSizeLimit = p.JournalSizeLimit
+		}
+		if p.AutoVacuum != "" {
+			o.pragmas.AutoVacuum = p.AutoVacuum
+		}
+	}
+}
+
+// WithWALAutoCheckpoint sets the WAL size in pages that triggers an
+// automatic checkpoint. A negative value turns them off, for applications
+// that checkpoint with CheckpointTruncate or backup.WALShipper instead.
+func WithWALAutoCheckpoint(pages int) Option {
+	return WithPragmas(Pragmas{WALAutoCheckpoint: pages})
+}
+
+// WithJournalSizeLimit sets the size in bytes the WAL is truncated to after
+// a checkpoint. A negative value removes the limit.
+func WithJournalSizeLimit(bytes int64) Option {
+	return WithPragmas(Pragmas{JournalSizeLimit: bytes})
+}
+
+// WithAutoVacuum sets auto_vacuum to NONE, FULL or INCREMENTAL for a new
+// database. FULL shrinks the file at every commit that frees pages, and
+// INCREMENTAL leaves free pages for maintenance.IncrementalVacuum to
+// reclaim. An existing database keeps its mode until it is vacuumed.
+func WithAutoVacuum(mode string) Option {
+	return WithPragmas(Pragmas{AutoVacuum: mode})
+}
+
+// WithSynchronous sets synchronous to OFF, NORMAL, FULL or EXTRA.
+func WithSynchronous(mode string) Option {
+	return WithPragmas(Pragmas{Synchronous: mode})
+}
+
+// statements returns the PRAGMA statements for the nonzero fields of p, or
+// an error naming the first invalid one.
+func (p Pragmas) statements() ([]string, error) {
+	var statements []string
+	keyword := func(pragma, value string, allowed ...string) error {
+		if value == "" {
+			return nil
+		}
+		for _, a := range allowed {
+			if strings.EqualFold(value, a) {
+				statements = append(statements, fmt.Sprintf("PRAGMA %s = %s;", pragma, a))
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid %s %q: use one of %s", pragma, value, strings.Join(allowed, ", "))
+	}
+	if err := keyword("auto_vacuum", p.AutoVacuum, "NONE", "FULL", "INCREMENTAL"); err != nil {
+		return nil, err
+	}
+	if err := keyword("journal_mode", p.JournalMode, "DELETE", "TRUNCATE", "PERSIST", "MEMORY", "WAL", "OFF"); err != nil {
+		return nil, err
+	}
+	if err := keyword("synchronous", p.Synchronous, "OFF", "NORMAL", "FULL", "EXTRA"); err != nil {
+		return nil, err
+	}
+	if err := keyword("temp_store", p.TempStore, "DEFAULT", "FILE", "MEMORY"); err != nil {
+		return nil, err
+	}
+	if p.CacheSizeKiB < 0 {
+		return nil, fmt.Errorf("invalid cache size %d KiB", p.CacheSizeKiB)
+	}
+	if p.MmapSize < 0 {
+		return nil, fmt.Errorf("invalid mmap_size %d", p.MmapSize)
+	}
+	if p.CacheSizeKiB != 0 {
+		// A negative cache_size is a size in KiB rather than in pages.
+		statements = append(statements, fmt.Sprintf("PRAGMA cache_size = %d;", -p.CacheSizeKiB))
+	}
+	if p.MmapSize != 0 {
+		statements = append(statements, fmt.Sprintf("PRAGMA mmap_size = %d;", p.MmapSize))
+	}
+	if p.WALAutoCheckpoint != 0 {
+		statements = append(statements, fmt.Sprintf("PRAGMA wal_autocheckpoint = %d;", p.WALAutoCheckpoint))
+	}
+	if p.JournalSizeLimit != 0 {
+		statements = append(statements, fmt.Sprintf("PRAGMA journal_size_limit = %d;", p.JournalSizeLimit))
+	}
+	return statements, nil
+}
+
+// autoVacuumModes are the values PRAGMA auto_vacuum reports.
+var autoVacuumModes = map[string]int64{"NONE": 0, "FULL": 1, "INCREMENTAL": 2}
+
+// applyAutoVacuum converts a database without tables to p.AutoVacuum after
+// its PRAGMA has run. Pooled connections open the database in WAL mode,
+// which already creates it, so the mode only takes effect with a VACUUM,
+// which is instant while the database is empty.
+func (p Pragmas) applyAutoVacuum(conn *sqlite.Conn) error {
+	want, ok := autoVacuumModes[strings.ToUpper(p.AutoVacuum)]
+	if !ok {
+		return nil
+	}
+	mode, err := sqlitex.ResultInt64(conn.Prep("PRAGMA auto_vacuum;"))
+	if err != nil {
+		return fmt.Errorf("failed to read auto_vacuum: %w", err)
+	}
+	if mode == want {
+		return nil
+	}
+	tables, err := sqlitex.ResultInt64(conn.Prep("SELECT count(*) FROM sqlite_schema;"))
+	if err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+	if tables > 0 {
+		return nil
+	}
+	if err := sqlitex.ExecuteTransient(conn, "VACUUM;", nil); err != nil {
+		return fmt.Errorf("failed to set auto_vacuum: %w", err)
+	}
+	return nil
+}
+
+// execPragmas runs statements on conn.
+func execPragmas(conn *sqlite.Conn, statements []string) error {
+	for _, statement := range statements {
+		if err := sqlitex.ExecuteTransient(conn, statement, nil); err != nil {
+			return fmt.Errorf("failed to run %s: %w", statement, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package pool_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestPresets(t *testing.T) {
+	ctx := context.Background()
+	uri := "file:" + filepath.Join(t.TempDir(), "presets.db")
+	// Later options override the fields they set.
+	err := pool.InitPool(uri, 1, pool.PresetBulkLoad, pool.WithPragmas(pool.Pragmas{Synchronous: "normal"}))
+	if err != nil {
+		t.Fatalf("failed to initialize pool: %v", err)
+	}
+	defer func() {
+		if err := pool.ClosePool(); err != nil {
+			t.Errorf("failed to close pool: %v", err)
+		}
+	}()
+
+	p, err := pool.GetPool()
+	if err != nil {
+		t.Fatalf("failed to get pool: %v", err)
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		t.Fatalf("failed to take connection: %v", err)
+	}
+	defer p.Put(conn)
+
+	for pragma, want := range map[string]string{
+		"journal_mode":       "wal",
+		"synchronous":        "1",
+		"cache_size":         "-262144",
+		"temp_store":         "2",
+		"wal_autocheckpoint": "10000",
+	} {
+		var got string
+		err := sqlitex.ExecuteTransient(conn, "PRAGMA "+pragma+";", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				got = stmt.ColumnText(0)
+				return nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", pragma, err)
+		}
+		if got != want {
+			t.Errorf("expected %s %s, got %s", pragma, want, got)
+		}
+	}
+}
+
+func TestPresets_Invalid(t *testing.T) {
+	uri := "file:" + filepath.Join(t.TempDir(), "invalid.db")
+	for _, opt := range []pool.Option{
+		pool.WithSynchronous("sometimes"),
+		pool.WithPragmas(pool.Pragmas{TempStore: "disk"}),
+		pool.WithPragmas(pool.Pragmas{CacheSizeKiB: -1}),
+		pool.WithAutoVacuum("sometimes"),
+	} {
+		if p, err := pool.Open(uri, 1, opt); err == nil {
+			p.Close()
+			t.Error("expected an invalid setting to fail")
+		}
+	}
+	if err := pool.InitPool(uri, 1, pool.WithSynchronous("sometimes")); err == nil {
+		pool.ClosePool()
+		t.Fatal("expected InitPool to reject an invalid setting")
+	}
+}
+
+func TestPresets_WALOptions(t *testing.T) {
+	ctx := context.Background()
+	uri := "file:" + filepath.Join(t.TempDir(), "wal.db")
+	p, err := pool.Open(uri, 1, pool.PresetLowLatency, pool.WithWALAutoCheckpoint(-1), pool.WithJournalSizeLimit(64<<20), pool.WithSynchronous("full"), pool.WithAutoVacuum("full"))
+	if err != nil {
+		t.Fatalf("failed to open pool: %v", err)
+	}
+	defer p.Close()
+	conn, err := p.Take(ctx)
+	if err != nil {
+		t.Fatalf("failed to take connection: %v", err)
+	}
+	defer p.Put(conn)
+
+	for pragma, want := range map[string]int64{
+		"wal_autocheckpoint": 0,
+		"journal_size_limit": 64 << 20,
+		"synchronous":        2,
+		// The new database is converted even though it is in WAL mode.
+		"auto_vacuum": 1,
+	} {
+		got, err := sqlitex.ResultInt64(conn.Prep("PRAGMA " + pragma + ";"))
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", pragma, err)
+		}
+		if got != want {
+			t.Errorf("expected %s %d, got %d", pragma, want, got)
+		}
+	}
+}
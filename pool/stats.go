@@ -0,0 +1,56 @@
+package pool
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+)
+
+// Stats describes the global pool.
+type Stats struct {
+	URI         string `json:"uri"`
+	Size        int    `json:"size"`
+	Initialized bool   `json:"initialized"`
+	// Takes and WaitMillis count the connections taken through Take and the
+	// total time spent waiting for them.
+	Takes      int64   `json:"takes"`
+	WaitMillis float64 `json:"wait_ms"`
+	// Checkpoints and CheckpointsBusy count the calls to CheckpointTruncate
+	// that truncated the WAL and that readers or a writer blocked.
+	Checkpoints     int64 `json:"checkpoints"`
+	CheckpointsBusy int64 `json:"checkpoints_busy"`
+	// Followers describes the open Followers.
+	Followers []FollowerStats `json:"followers,omitempty"`
+}
+
+var (
+	takes     atomic.Int64
+	waitNanos atomic.Int64
+)
+
+func init() {
+	sqliteutils.RegisterStats("pool", func() interface{} { return GetStats() })
+}
+
+// GetStats returns the current Stats of the global pool.
+func GetStats() Stats {
+	poolLock.Lock()
+	defer poolLock.Unlock()
+	return Stats{
+		URI:             poolUri,
+		Size:            poolSize,
+		Initialized:     pool != nil,
+		Takes:           takes.Load(),
+		WaitMillis:      float64(waitNanos.Load()) / float64(time.Millisecond),
+		Checkpoints:     checkpoints.Load(),
+		CheckpointsBusy: checkpointsBusy.Load(),
+		Followers:       followerStats(),
+	}
+}
+
+// recordTake counts a connection taken after waiting wait.
+func recordTake(wait time.Duration) {
+	takes.Add(1)
+	waitNanos.Add(int64(wait))
+}
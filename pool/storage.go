@@ -0,0 +1,104 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// StorageStats describes how the global pool's database uses its file.
+type StorageStats struct {
+	PageSize      int64 `json:"page_size"`
+	PageCount     int64 `json:"page_count"`
+	FreelistCount int64 `json:"freelist_count"`
+	// FileSize is PageCount pages of PageSize bytes.
+	FileSize int64 `json:"file_size"`
+	// WALSize is the size of the write-ahead log in bytes, or 0 if the
+	// database does not have one.
+	WALSize int64 `json:"wal_size"`
+	// Tables and Indexes are sorted by size, largest first. Tables include
+	// the schema table and the internal sqlite_ tables.
+	Tables  []ObjectSize `json:"tables"`
+	Indexes []ObjectSize `json:"indexes"`
+}
+
+// ObjectSize is the storage used by one table or index.
+type ObjectSize struct {
+	Name string `json:"name"`
+	// Table is the table an index belongs to, or the name of a table.
+	Table string `json:"table"`
+	Pages int64  `json:"pages"`
+	// Bytes is the size of the pages. PayloadBytes of it hold data and
+	// UnusedBytes are free space within the pages.
+	Bytes        int64 `json:"bytes"`
+	PayloadBytes int64 `json:"payload_bytes"`
+	UnusedBytes  int64 `json:"unused_bytes"`
+	// Cells is the number of rows of a table or entries of an index.
+	Cells int64 `json:"cells"`
+}
+
+// Storage reports the size of the global pool's database, its WAL and each
+// of its tables and indexes, measured with the dbstat virtual table. It
+// reads every page of the database, so keep it off hot paths.
+func Storage(ctx context.Context) (StorageStats, error) {
+	var stats StorageStats
+	err := WithConn(ctx, func(conn *sqlite.Conn) error {
+		conn.SetInterrupt(ctx.Done())
+		defer conn.SetInterrupt(nil)
+
+		for query, dst := range map[string]*int64{
+			"PRAGMA page_size;":      &stats.PageSize,
+			"PRAGMA page_count;":     &stats.PageCount,
+			"PRAGMA freelist_count;": &stats.FreelistCount,
+		} {
+			v, err := sqlitex.ResultInt64(conn.Prep(query))
+			if err != nil {
+				return fmt.Errorf("failed to run %s: %w", query, err)
+			}
+			*dst = v
+		}
+		stats.FileSize = stats.PageCount * stats.PageSize
+
+		// Table rows are the cells of leaf pages, while index entries are
+		// also kept in interior pages.
+		err := sqlitex.ExecuteTransient(conn, `SELECT d.name, coalesce(s.tbl_name, d.name), coalesce(s.type, 'table'),
+				count(*), sum(d.pgsize), sum(d.payload), sum(d.unused),
+				sum(CASE WHEN d.pagetype = 'leaf' OR s.type = 'index' THEN d.ncell ELSE 0 END)
+			FROM dbstat AS d LEFT JOIN sqlite_schema AS s ON s.name = d.name
+			GROUP BY d.name
+			ORDER BY sum(d.pgsize) DESC, d.name;`, &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				size := ObjectSize{
+					Name:         stmt.ColumnText(0),
+					Table:        stmt.ColumnText(1),
+					Pages:        stmt.ColumnInt64(3),
+					Bytes:        stmt.ColumnInt64(4),
+					PayloadBytes: stmt.ColumnInt64(5),
+					UnusedBytes:  stmt.ColumnInt64(6),
+					Cells:        stmt.ColumnInt64(7),
+				}
+				if stmt.ColumnText(2) == "index" {
+					stats.Indexes = append(stats.Indexes, size)
+				} else {
+					stats.Tables = append(stats.Tables, size)
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read dbstat: %w", err)
+		}
+
+		path, err := sqlitex.ResultText(conn.Prep("SELECT file FROM pragma_database_list WHERE name = 'main';"))
+		if err != nil {
+			return fmt.Errorf("failed to read database file: %w", err)
+		}
+		if path != "" {
+			_, stats.WALSize, err = readWALHeader(path + "-wal")
+		}
+		return err
+	})
+	return stats, err
+}
@@ -0,0 +1,61 @@
+package pool_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestStorage(t *testing.T) {
+	ctx := context.Background()
+	if err := pool.InitPool("file:"+filepath.Join(t.TempDir(), "storage.db"), 1); err != nil {
+		t.Fatalf("failed to initialize pool: %v", err)
+	}
+	defer func() {
+		if err := pool.ClosePool(); err != nil {
+			t.Errorf("failed to close pool: %v", err)
+		}
+	}()
+	p, err := pool.GetPool()
+	if err != nil {
+		t.Fatalf("failed to get pool: %v", err)
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		t.Fatalf("failed to take connection: %v", err)
+	}
+	err = sqlitex.ExecuteScript(conn, `CREATE TABLE docs (id INTEGER PRIMARY KEY, body TEXT);
+		CREATE INDEX docs_body ON docs (body);
+		CREATE TABLE small (id INTEGER PRIMARY KEY);
+		INSERT INTO docs (body)
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 300)
+		SELECT hex(randomblob(200)) FROM n;`, nil)
+	p.Put(conn)
+	if err != nil {
+		t.Fatalf("failed to create tables: %v", err)
+	}
+
+	stats, err := pool.Storage(ctx)
+	if err != nil {
+		t.Fatalf("failed to read storage stats: %v", err)
+	}
+	if stats.PageSize == 0 || stats.PageCount == 0 || stats.FileSize != stats.PageSize*stats.PageCount {
+		t.Errorf("unexpected page stats %+v", stats)
+	}
+	if stats.WALSize == 0 {
+		t.Error("expected a WAL")
+	}
+	if len(stats.Tables) < 3 || stats.Tables[0].Name != "docs" {
+		t.Fatalf("expected docs to be the largest table, got %+v", stats.Tables)
+	}
+	docs := stats.Tables[0]
+	if docs.Cells != 300 || docs.Pages < 10 || docs.Bytes != docs.Pages*stats.PageSize || docs.PayloadBytes == 0 {
+		t.Errorf("unexpected docs size %+v", docs)
+	}
+	if len(stats.Indexes) != 1 || stats.Indexes[0].Name != "docs_body" || stats.Indexes[0].Table != "docs" || stats.Indexes[0].Cells != 300 {
+		t.Errorf("unexpected indexes %+v", stats.Indexes)
+	}
+}
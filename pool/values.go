@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"sync"
+
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// connValues holds the values of WithConnValue by connection, and
+// valueConns the connections of each pool that has any, so that closing the
+// pool forgets them.
+var (
+	connValues     sync.Map // *sqlite.Conn -> map[interface{}]interface{}
+	valueConnsLock sync.Mutex
+	valueConns     = map[*sqlitex.Pool]*poolValues{}
+)
+
+// poolValues tracks the connections of a pool its values were stored for.
+type poolValues struct {
+	values map[interface{}]interface{}
+	mu     sync.Mutex
+	conns  map[*sqlite.Conn]bool
+}
+
+func (v *poolValues) add(conn *sqlite.Conn) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.conns[conn] = true
+	connValues.Store(conn, v.values)
+}
+
+// WithConnValue attaches value under key to every connection of the pool,
+// for settings other packages keep per pool rather than per process, such as
+// the column conversions of exec. ConnValue reads it back. Keys follow the
+// rules of context.WithValue keys.
+func WithConnValue(key, value interface{}) Option {
+	return func(o *options) {
+		if o.values == nil {
+			o.values = map[interface{}]interface{}{}
+		}
+		o.values[key] = value
+	}
+}
+
+// ConnValue returns the value attached under key with WithConnValue to the
+// pool conn belongs to, or nil.
+func ConnValue(conn *sqlite.Conn, key interface{}) interface{} {
+	values, ok := connValues.Load(conn)
+	if !ok {
+		return nil
+	}
+	return values.(map[interface{}]interface{})[key]
+}
+
+// Close closes a pool opened with Open and forgets the values attached to
+// its connections. Pools from Open must be closed with Close rather than
+// their own Close method for those to be released.
+func Close(p *sqlitex.Pool) error {
+	err := p.Close()
+	valueConnsLock.Lock()
+	v := valueConns[p]
+	delete(valueConns, p)
+	valueConnsLock.Unlock()
+	if v != nil {
+		v.mu.Lock()
+		for conn := range v.conns {
+			connValues.Delete(conn)
+		}
+		v.mu.Unlock()
+	}
+	return err
+}
+
+// trackValues records the connections of p as they are prepared, if opts
+// attach values to them.
+func trackValues(p *sqlitex.Pool, v *poolValues) {
+	if v == nil {
+		return
+	}
+	valueConnsLock.Lock()
+	defer valueConnsLock.Unlock()
+	valueConns[p] = v
+}
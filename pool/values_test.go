@@ -0,0 +1,37 @@
+package pool_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testKey struct{}
+
+func TestConnValue(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	a, err := pool.Open(filepath.Join(dir, "a.db"), 1, pool.WithConnValue(testKey{}, "a"))
+	require.NoError(t, err)
+	b, err := pool.Open(filepath.Join(dir, "b.db"), 1)
+	require.NoError(t, err)
+	defer pool.Close(b)
+
+	conn, err := a.Take(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "a", pool.ConnValue(conn, testKey{}))
+	a.Put(conn)
+
+	other, err := b.Take(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, pool.ConnValue(other, testKey{}))
+	b.Put(other)
+
+	// Values are forgotten once the pool is closed.
+	require.NoError(t, pool.Close(a))
+	assert.Nil(t, pool.ConnValue(conn, testKey{}))
+}
@@ -0,0 +1,50 @@
+package pool
+
+import (
+	"context"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+type waitObserverKey struct{}
+
+// WithWaitObserver returns a context under which Take reports how long it
+// waited for a connection to fn. Instrumentation uses it to attribute pool
+// contention to the operation that suffered it.
+func WithWaitObserver(ctx context.Context, fn func(wait time.Duration)) context.Context {
+	return context.WithValue(ctx, waitObserverKey{}, fn)
+}
+
+// Take takes a connection from p like p.Take, reporting the time spent
+// waiting to the observer set by WithWaitObserver, if any, and counting it
+// in GetStats.
+func Take(ctx context.Context, p *sqlitex.Pool) (*sqlite.Conn, error) {
+	start := time.Now()
+	conn, err := p.Take(ctx)
+	wait := time.Since(start)
+	if err == nil {
+		recordTake(wait)
+	}
+	if fn, _ := ctx.Value(waitObserverKey{}).(func(time.Duration)); fn != nil {
+		fn(wait)
+	}
+	return conn, err
+}
+
+// WithConn runs fn with a connection taken from the global pool with Take,
+// returning it to the pool once fn returns.
+func WithConn(ctx context.Context, fn func(conn *sqlite.Conn) error) error {
+	p, err := GetPool()
+	if err != nil {
+		return sqliteutils.FailedToGetPoolError(err)
+	}
+	conn, err := Take(ctx, p)
+	if err != nil {
+		return sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+	return fn(conn)
+}
@@ -0,0 +1,105 @@
+package sqliteutils
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// MaxLoggedParamLength truncates parameter values in the query log.
+const MaxLoggedParamLength = 64
+
+// QueryLogEntry is a statement kept by the query log.
+type QueryLogEntry struct {
+	Time time.Time `json:"time"`
+	SQL  string    `json:"sql"`
+	// Params holds the parameters by name, formatted and truncated to
+	// MaxLoggedParamLength bytes.
+	Params   map[string]string `json:"params,omitempty"`
+	Duration time.Duration     `json:"duration"`
+	Error    string            `json:"error,omitempty"`
+}
+
+var (
+	queryLogEnabled atomic.Bool
+	queryLogLock    sync.Mutex
+	queryLog        []QueryLogEntry
+	queryLogNext    int
+	queryLogFull    bool
+)
+
+// EnableQueryLog keeps the last size statements run by the exec package in
+// memory for RecentQueries, to see what an application just did to its
+// database. A size of zero or less turns the log off and discards it.
+func EnableQueryLog(size int) {
+	queryLogLock.Lock()
+	defer queryLogLock.Unlock()
+	queryLog, queryLogNext, queryLogFull = nil, 0, false
+	if size > 0 {
+		queryLog = make([]QueryLogEntry, size)
+	}
+	queryLogEnabled.Store(size > 0)
+}
+
+// QueryLogEnabled reports whether EnableQueryLog turned the query log on.
+func QueryLogEnabled() bool {
+	return queryLogEnabled.Load()
+}
+
+// LogQuery adds a statement that started at start to the query log if it is
+// on. The exec package calls it for every statement.
+func LogQuery(start time.Time, sql string, params map[string]interface{}, err error) {
+	if !queryLogEnabled.Load() {
+		return
+	}
+	entry := QueryLogEntry{Time: start, SQL: sql, Duration: time.Since(start)}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if len(params) > 0 {
+		entry.Params = make(map[string]string, len(params))
+		for name, value := range params {
+			entry.Params[name] = formatParam(value)
+		}
+	}
+
+	queryLogLock.Lock()
+	defer queryLogLock.Unlock()
+	if len(queryLog) == 0 {
+		return
+	}
+	queryLog[queryLogNext] = entry
+	queryLogNext = (queryLogNext + 1) % len(queryLog)
+	queryLogFull = queryLogFull || queryLogNext == 0
+}
+
+// RecentQueries returns the statements in the query log in the order they
+// finished, oldest first.
+func RecentQueries() []QueryLogEntry {
+	queryLogLock.Lock()
+	defer queryLogLock.Unlock()
+	if !queryLogFull {
+		return append([]QueryLogEntry(nil), queryLog[:queryLogNext]...)
+	}
+	entries := append([]QueryLogEntry(nil), queryLog[queryLogNext:]...)
+	return append(entries, queryLog[:queryLogNext]...)
+}
+
+// formatParam formats a parameter value for the query log.
+func formatParam(value interface{}) string {
+	var s string
+	switch v := value.(type) {
+	case []byte:
+		return fmt.Sprintf("<%d bytes>", len(v))
+	case string:
+		s = fmt.Sprintf("%q", v)
+	default:
+		s = fmt.Sprint(v)
+	}
+	if len(s) > MaxLoggedParamLength {
+		s = strings.ToValidUTF8(s[:MaxLoggedParamLength], "") + "..."
+	}
+	return s
+}
@@ -0,0 +1,54 @@
+package sqliteutils_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecentQueries(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT, data BLOB);", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	require.NoError(t, exec.Exec(ctx, "SELECT 1;", nil, nil))
+	assert.Empty(t, sqliteutils.RecentQueries())
+
+	sqliteutils.EnableQueryLog(3)
+	defer sqliteutils.EnableQueryLog(0)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, exec.Exec(ctx, "INSERT INTO t (id, name, data) VALUES (:id, :name, :data);", map[string]interface{}{
+			":id":   i,
+			":name": strings.Repeat("x", 100),
+			":data": []byte{1, 2, 3},
+		}, nil))
+	}
+	assert.Error(t, exec.Query(ctx, "SELECT * FROM missing", nil, nil))
+
+	queries := sqliteutils.RecentQueries()
+	require.Len(t, queries, 3)
+	for i, q := range queries[:2] {
+		assert.Equal(t, "INSERT INTO t (id, name, data) VALUES (:id, :name, :data)", q.SQL)
+		assert.Equal(t, fmt.Sprint(i+2), q.Params[":id"])
+		assert.Equal(t, "<3 bytes>", q.Params[":data"])
+		assert.Len(t, q.Params[":name"], sqliteutils.MaxLoggedParamLength+3)
+		assert.Empty(t, q.Error)
+	}
+	assert.Equal(t, "SELECT * FROM missing", queries[2].SQL)
+	assert.Contains(t, queries[2].Error, "no such table")
+	assert.False(t, queries[2].Time.Before(queries[1].Time))
+
+	sqliteutils.EnableQueryLog(0)
+	assert.False(t, sqliteutils.QueryLogEnabled())
+	assert.Empty(t, sqliteutils.RecentQueries())
+}
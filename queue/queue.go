@@ -0,0 +1,354 @@
+// Package queue is a durable job queue stored in the database behind the
+// global pool.
+//
+// Jobs live in the _queue_jobs table and move through three states: queued,
+// leased and dead. Dequeue leases the highest priority job that is due, in an
+// immediate transaction so concurrent workers in any process never receive
+// the same job. A worker then calls Complete, which deletes the job, or Fail,
+// which schedules a retry with backoff or, once the job has used up its
+// attempts, moves it to the dead-letter state. A job whose lease expires
+// before either call, because its worker crashed or stalled, is handed out
+// again and counts as a failed attempt.
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Job states.
+const (
+	StateQueued = "queued"
+	StateLeased = "leased"
+	StateDead   = "dead"
+)
+
+// Job is a unit of work read from a queue.
+type Job struct {
+	ID       int64
+	Queue    string
+	Payload  []byte
+	Priority int
+	RunAt    time.Time
+	// Attempts counts the times the job has been leased, including the
+	// current lease.
+	Attempts    int
+	MaxAttempts int
+	LeaseUntil  time.Time
+	LastError   string
+	State       string
+	CreatedAt   time.Time
+}
+
+// Options configures a Queue.
+type Options struct {
+	// Lease is how long a dequeued job is reserved for its worker. Defaults
+	// to 30 seconds; use Extend for longer jobs.
+	Lease time.Duration
+	// MaxAttempts is the default number of attempts before a job is
+	// dead-lettered. Defaults to 5.
+	MaxAttempts int
+	// Backoff returns the delay before retrying a job that failed its
+	// attempt'th attempt. Defaults to ExponentialBackoff.
+	Backoff func(attempt int) time.Duration
+}
+
+// EnqueueOptions configures a single job.
+type EnqueueOptions struct {
+	// Priority orders due jobs; higher runs first.
+	Priority int
+	// RunAt delays the job until the given time. Defaults to now.
+	RunAt time.Time
+	// MaxAttempts overrides Options.MaxAttempts for this job.
+	MaxAttempts int
+}
+
+// ExponentialBackoff waits one second after the first failed attempt and
+// doubles the delay after every further failure, up to one hour.
+func ExponentialBackoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt > 13 {
+		return time.Hour
+	}
+	delay := time.Second << (attempt - 1)
+	if delay > time.Hour {
+		return time.Hour
+	}
+	return delay
+}
+
+const createJobsTable = `CREATE TABLE IF NOT EXISTS _queue_jobs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	queue TEXT NOT NULL,
+	payload BLOB,
+	priority INTEGER NOT NULL DEFAULT 0,
+	run_at INTEGER NOT NULL,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	max_attempts INTEGER NOT NULL,
+	lease_until INTEGER,
+	last_error TEXT,
+	state TEXT NOT NULL DEFAULT 'queued',
+	created_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS _queue_jobs_due ON _queue_jobs (queue, state, priority DESC, run_at, id);`
+
+const jobColumns = "id, queue, payload, priority, run_at, attempts, max_attempts, lease_until, last_error, state, created_at"
+
+// Queue is a named queue of jobs.
+type Queue struct {
+	name string
+	opts Options
+}
+
+// Open creates the jobs table if needed and returns the queue called name.
+func Open(ctx context.Context, name string, opts Options) (*Queue, error) {
+	if opts.Lease <= 0 {
+		opts.Lease = 30 * time.Second
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = 5
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = ExponentialBackoff
+	}
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		if err := sqlitex.ExecuteScript(conn, createJobsTable, nil); err != nil {
+			return fmt.Errorf("failed to create _queue_jobs table: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Queue{name: name, opts: opts}, nil
+}
+
+// Enqueue adds a job with payload and returns its id.
+func (q *Queue) Enqueue(ctx context.Context, payload []byte, opts EnqueueOptions) (int64, error) {
+	now := time.Now()
+	runAt := opts.RunAt
+	if runAt.IsZero() {
+		runAt = now
+	}
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = q.opts.MaxAttempts
+	}
+	var id int64
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		err := sqlitex.Execute(conn, `INSERT INTO _queue_jobs (queue, payload, priority, run_at, max_attempts, created_at)
+			VALUES (?, ?, ?, ?, ?, ?);`, &sqlitex.ExecOptions{
+			Args: []interface{}{q.name, payload, opts.Priority, runAt.UnixMilli(), maxAttempts, now.UnixMilli()},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to enqueue job: %w", err)
+		}
+		id = conn.LastInsertRowID()
+		return nil
+	})
+	return id, err
+}
+
+// Dequeue leases the next due job. It returns sqliteutils.ErrQueueEmpty when
+// no job is due.
+func (q *Queue) Dequeue(ctx context.Context) (*Job, error) {
+	var job *Job
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) (err error) {
+		// An immediate transaction takes the write lock before reading, so
+		// two workers cannot select the same job.
+		endFn, err := sqlitex.ImmediateTransaction(conn)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer endFn(&err)
+
+		now := time.Now().UnixMilli()
+		// Dead-letter jobs whose final lease expired.
+		err = sqlitex.Execute(conn, `UPDATE _queue_jobs SET state = 'dead', lease_until = NULL, last_error = 'lease expired'
+			WHERE queue = ? AND state = 'leased' AND lease_until <= ? AND attempts >= max_attempts;`, &sqlitex.ExecOptions{
+			Args: []interface{}{q.name, now},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to dead-letter expired jobs: %w", err)
+		}
+
+		err = sqlitex.Execute(conn, `UPDATE _queue_jobs SET state = 'leased', attempts = attempts + 1, lease_until = ?
+			WHERE id = (
+				SELECT id FROM _queue_jobs
+				WHERE queue = ? AND ((state = 'queued' AND run_at <= ?) OR (state = 'leased' AND lease_until <= ?))
+				ORDER BY priority DESC, run_at, id LIMIT 1
+			)
+			RETURNING `+jobColumns+`;`, &sqlitex.ExecOptions{
+			Args: []interface{}{now + q.opts.Lease.Milliseconds(), q.name, now, now},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				job = readJob(stmt)
+				return nil
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to dequeue job: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if job == nil {
+		return nil, sqliteutils.ErrQueueEmpty
+	}
+	return job, nil
+}
+
+// Complete deletes a finished job. It returns sqliteutils.ErrLeaseLost if the
+// job's lease expired and it was handed to another worker.
+func (q *Queue) Complete(ctx context.Context, job *Job) error {
+	return q.updateLeased(ctx, job, "DELETE FROM _queue_jobs", nil)
+}
+
+// Fail records cause as the job's last error and schedules a retry after the
+// backoff delay, or dead-letters the job when it has no attempts left. It
+// returns sqliteutils.ErrLeaseLost if the job's lease expired and it was
+// handed to another worker.
+func (q *Queue) Fail(ctx context.Context, job *Job, cause error) error {
+	message := ""
+	if cause != nil {
+		message = cause.Error()
+	}
+	if job.Attempts >= job.MaxAttempts {
+		return q.updateLeased(ctx, job,
+			"UPDATE _queue_jobs SET state = 'dead', lease_until = NULL, last_error = ?", []interface{}{message})
+	}
+	runAt := time.Now().Add(q.opts.Backoff(job.Attempts)).UnixMilli()
+	return q.updateLeased(ctx, job,
+		"UPDATE _queue_jobs SET state = 'queued', lease_until = NULL, run_at = ?, last_error = ?", []interface{}{runAt, message})
+}
+
+// Extend renews the lease on job for another lease period, for jobs that run
+// longer than Options.Lease.
+func (q *Queue) Extend(ctx context.Context, job *Job) error {
+	leaseUntil := time.Now().Add(q.opts.Lease)
+	if err := q.updateLeased(ctx, job, "UPDATE _queue_jobs SET lease_until = ?", []interface{}{leaseUntil.UnixMilli()}); err != nil {
+		return err
+	}
+	job.LeaseUntil = leaseUntil
+	return nil
+}
+
+// updateLeased runs statement, an UPDATE or DELETE without a WHERE clause, on
+// job if the caller still holds its lease.
+func (q *Queue) updateLeased(ctx context.Context, job *Job, statement string, args []interface{}) error {
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		// The attempt count fences out workers whose lease was taken over.
+		args = append(args, job.ID, job.Attempts, time.Now().UnixMilli())
+		err := sqlitex.Execute(conn, statement+" WHERE id = ? AND state = 'leased' AND attempts = ? AND lease_until > ?;",
+			&sqlitex.ExecOptions{Args: args})
+		if err != nil {
+			return fmt.Errorf("failed to update job %d: %w", job.ID, err)
+		}
+		if conn.Changes() == 0 {
+			return sqliteutils.ErrLeaseLost
+		}
+		return nil
+	})
+}
+
+// DeadLetters returns up to limit dead-lettered jobs, oldest first.
+func (q *Queue) DeadLetters(ctx context.Context, limit int) ([]Job, error) {
+	var jobs []Job
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		return sqlitex.Execute(conn, "SELECT "+jobColumns+" FROM _queue_jobs WHERE queue = ? AND state = 'dead' ORDER BY id LIMIT ?;",
+			&sqlitex.ExecOptions{
+				Args: []interface{}{q.name, limit},
+				ResultFunc: func(stmt *sqlite.Stmt) error {
+					jobs = append(jobs, *readJob(stmt))
+					return nil
+				},
+			})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letters: %w", err)
+	}
+	return jobs, nil
+}
+
+// Retry requeues the dead-lettered job id with a fresh set of attempts.
+func (q *Queue) Retry(ctx context.Context, id int64) error {
+	return pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		err := sqlitex.Execute(conn, `UPDATE _queue_jobs SET state = 'queued', attempts = 0, run_at = ?
+			WHERE id = ? AND queue = ? AND state = 'dead';`, &sqlitex.ExecOptions{
+			Args: []interface{}{time.Now().UnixMilli(), id, q.name},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to retry job %d: %w", id, err)
+		}
+		if conn.Changes() == 0 {
+			return fmt.Errorf("job %d: %w", id, sqliteutils.ErrRowNotFound)
+		}
+		return nil
+	})
+}
+
+// Run dequeues and processes jobs with fn until ctx is done, polling every
+// interval while the queue is empty. A job is completed when fn returns nil
+// and failed with its error otherwise. Run returns nil when ctx is canceled.
+func (q *Queue) Run(ctx context.Context, interval time.Duration, fn func(ctx context.Context, job *Job) error) error {
+	for {
+		job, err := q.Dequeue(ctx)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case errors.Is(err, sqliteutils.ErrQueueEmpty):
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(interval):
+			}
+			continue
+		case err != nil:
+			return err
+		}
+
+		if jobErr := fn(ctx, job); jobErr != nil {
+			err = q.Fail(ctx, job, jobErr)
+		} else {
+			err = q.Complete(ctx, job)
+		}
+		if err != nil && !errors.Is(err, sqliteutils.ErrLeaseLost) {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func readJob(stmt *sqlite.Stmt) *Job {
+	job := &Job{
+		ID:          stmt.ColumnInt64(0),
+		Queue:       stmt.ColumnText(1),
+		Priority:    stmt.ColumnInt(3),
+		RunAt:       time.UnixMilli(stmt.ColumnInt64(4)),
+		Attempts:    stmt.ColumnInt(5),
+		MaxAttempts: stmt.ColumnInt(6),
+		LastError:   stmt.ColumnText(8),
+		State:       stmt.ColumnText(9),
+		CreatedAt:   time.UnixMilli(stmt.ColumnInt64(10)),
+	}
+	if stmt.ColumnType(2) != sqlite.TypeNull {
+		job.Payload = make([]byte, stmt.ColumnLen(2))
+		stmt.ColumnBytes(2, job.Payload)
+	}
+	if stmt.ColumnType(7) != sqlite.TypeNull {
+		job.LeaseUntil = time.UnixMilli(stmt.ColumnInt64(7))
+	}
+	return job
+}
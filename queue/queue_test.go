@@ -0,0 +1,156 @@
+package queue_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/queue"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noBackoff(int) time.Duration { return 0 }
+
+func TestQueue(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "queue.db"), 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	q, err := queue.Open(ctx, "emails", queue.Options{Lease: 200 * time.Millisecond, MaxAttempts: 2, Backoff: noBackoff})
+	require.NoError(t, err)
+	other, err := queue.Open(ctx, "other", queue.Options{})
+	require.NoError(t, err)
+
+	_, err = q.Dequeue(ctx)
+	assert.ErrorIs(t, err, sqliteutils.ErrQueueEmpty)
+
+	low, err := q.Enqueue(ctx, []byte("low"), queue.EnqueueOptions{})
+	require.NoError(t, err)
+	high, err := q.Enqueue(ctx, []byte("high"), queue.EnqueueOptions{Priority: 10})
+	require.NoError(t, err)
+	_, err = q.Enqueue(ctx, []byte("later"), queue.EnqueueOptions{Priority: 20, RunAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	_, err = other.Enqueue(ctx, []byte("other"), queue.EnqueueOptions{Priority: 30})
+	require.NoError(t, err)
+
+	// Higher priority first; future and other-queue jobs are skipped.
+	job, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, high, job.ID)
+	assert.Equal(t, []byte("high"), job.Payload)
+	assert.Equal(t, 1, job.Attempts)
+	assert.Equal(t, queue.StateLeased, job.State)
+	require.NoError(t, q.Extend(ctx, job))
+	require.NoError(t, q.Complete(ctx, job))
+	assert.ErrorIs(t, q.Complete(ctx, job), sqliteutils.ErrLeaseLost)
+
+	// A failed job is retried, then dead-lettered once out of attempts.
+	job, err = q.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, low, job.ID)
+	require.NoError(t, q.Fail(ctx, job, errors.New("smtp down")))
+
+	job, err = q.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, low, job.ID)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Equal(t, "smtp down", job.LastError)
+	require.NoError(t, q.Fail(ctx, job, errors.New("still down")))
+
+	_, err = q.Dequeue(ctx)
+	assert.ErrorIs(t, err, sqliteutils.ErrQueueEmpty)
+	dead, err := q.DeadLetters(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, low, dead[0].ID)
+	assert.Equal(t, "still down", dead[0].LastError)
+
+	// Retrying a dead letter requeues it with fresh attempts.
+	require.NoError(t, q.Retry(ctx, low))
+	assert.ErrorIs(t, q.Retry(ctx, low), sqliteutils.ErrRowNotFound)
+	job, err = q.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, job.Attempts)
+
+	// An expired lease hands the job out again and fences the old worker.
+	time.Sleep(250 * time.Millisecond)
+	again, err := q.Dequeue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, job.ID, again.ID)
+	assert.Equal(t, 2, again.Attempts)
+	assert.ErrorIs(t, q.Complete(ctx, job), sqliteutils.ErrLeaseLost)
+
+	// When the final lease expires the job is dead-lettered.
+	time.Sleep(250 * time.Millisecond)
+	_, err = q.Dequeue(ctx)
+	assert.ErrorIs(t, err, sqliteutils.ErrQueueEmpty)
+	dead, err = q.DeadLetters(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, "lease expired", dead[0].LastError)
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "queue.db"), 4))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	q, err := queue.Open(ctx, "jobs", queue.Options{MaxAttempts: 3, Backoff: noBackoff})
+	require.NoError(t, err)
+	const total = 50
+	for i := 0; i < total; i++ {
+		_, err := q.Enqueue(ctx, []byte(fmt.Sprint(i)), queue.EnqueueOptions{})
+		require.NoError(t, err)
+	}
+
+	var mu sync.Mutex
+	processed := map[string]int{}
+	failedOnce := map[string]bool{}
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	for w := 0; w < 3; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, q.Run(runCtx, 5*time.Millisecond, func(ctx context.Context, job *queue.Job) error {
+				mu.Lock()
+				defer mu.Unlock()
+				payload := string(job.Payload)
+				// Every job fails its first attempt.
+				if !failedOnce[payload] {
+					failedOnce[payload] = true
+					return errors.New("transient")
+				}
+				processed[payload]++
+				if len(processed) == total {
+					cancel()
+				}
+				return nil
+			}))
+		}()
+	}
+
+	select {
+	case <-runCtx.Done():
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out processing jobs")
+	}
+	wg.Wait()
+
+	assert.Len(t, processed, total)
+	for payload, n := range processed {
+		assert.Equal(t, 1, n, payload)
+	}
+}
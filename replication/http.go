@@ -0,0 +1,141 @@
+package replication
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// fetchResponse is the JSON body served by NewHandler.
+type fetchResponse struct {
+	PrevTerm int64       `json:"prev_term"`
+	Batches  []httpBatch `json:"batches"`
+	Error    string      `json:"error,omitempty"`
+	// Code names the sentinel error in Error, so clients can match it.
+	Code string `json:"code,omitempty"`
+}
+
+type httpBatch struct {
+	Seq       int64  `json:"seq"`
+	Term      int64  `json:"term"`
+	Changeset []byte `json:"changeset"`
+	// Time is in Unix milliseconds.
+	Time int64 `json:"time"`
+}
+
+// sentinels are the errors that keep their identity over HTTP.
+var sentinels = map[string]error{
+	"gap":      ErrReplicationGap,
+	"diverged": ErrDiverged,
+}
+
+// NewHandler serves the log of n to HTTPTransport clients at
+// GET ?after=N&limit=M. Mount it behind authentication: the log holds every
+// replicated row.
+func NewHandler(n *Node) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			writeFetchError(w, http.StatusMethodNotAllowed, errors.New("use GET"))
+			return
+		}
+		after, err := strconv.ParseInt(r.URL.Query().Get("after"), 10, 64)
+		if err != nil || after < 0 {
+			writeFetchError(w, http.StatusBadRequest, fmt.Errorf("invalid after %q", r.URL.Query().Get("after")))
+			return
+		}
+		limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil || limit <= 0 {
+			writeFetchError(w, http.StatusBadRequest, fmt.Errorf("invalid limit %q", r.URL.Query().Get("limit")))
+			return
+		}
+		result, err := n.Fetch(r.Context(), after, limit)
+		if err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, ErrReplicationGap) || errors.Is(err, ErrDiverged) {
+				status = http.StatusConflict
+			}
+			writeFetchError(w, status, err)
+			return
+		}
+		resp := fetchResponse{PrevTerm: result.PrevTerm, Batches: make([]httpBatch, len(result.Batches))}
+		for i, batch := range result.Batches {
+			resp.Batches[i] = httpBatch{Seq: batch.Seq, Term: batch.Term, Changeset: batch.Changeset, Time: batch.Time.UnixMilli()}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func writeFetchError(w http.ResponseWriter, status int, err error) {
+	resp := fetchResponse{Error: err.Error()}
+	for code, sentinel := range sentinels {
+		if errors.Is(err, sentinel) {
+			resp.Code = code
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// HTTPTransport fetches the log of a node served by NewHandler.
+type HTTPTransport struct {
+	// URL is the address NewHandler is mounted at.
+	URL string
+	// Client sends the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+	// Header is added to every request, e.g. for authentication.
+	Header http.Header
+}
+
+// Fetch implements Transport.
+func (t *HTTPTransport) Fetch(ctx context.Context, after int64, limit int) (*FetchResult, error) {
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return nil, err
+	}
+	q := u.Query()
+	q.Set("after", strconv.FormatInt(after, 10))
+	q.Set("limit", strconv.Itoa(limit))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range t.Header {
+		req.Header[name] = values
+	}
+	client := t.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var resp fetchResponse
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to decode replication log: %s: %w", res.Status, err)
+	}
+	if res.StatusCode != http.StatusOK {
+		if sentinel, ok := sentinels[resp.Code]; ok {
+			return nil, sentinel
+		}
+		return nil, fmt.Errorf("failed to fetch replication log: %s: %s", res.Status, resp.Error)
+	}
+	result := &FetchResult{PrevTerm: resp.PrevTerm, Batches: make([]Batch, len(resp.Batches))}
+	for i, batch := range resp.Batches {
+		result.Batches[i] = Batch{Seq: batch.Seq, Term: batch.Term, Changeset: batch.Changeset, Time: time.UnixMilli(batch.Time)}
+	}
+	return result, nil
+}
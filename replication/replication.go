@@ -0,0 +1,476 @@
+// Package replication keeps replica databases in step with a primary by
+// shipping changesets.
+//
+// Every write on the primary goes through Node.Write or Node.Exec, which
+// record its changes with the session extension and append the changeset to
+// the _replication_log table in the same transaction. Replicas pull the log
+// through a Transport, apply each changeset and append it to their own log,
+// so every node holds the same numbered sequence of batches. That lets a
+// replica take over with Promote, and the other replicas follow it from
+// where they are.
+//
+// Batches carry the term of the primary that wrote them, which Promote
+// increases. A replica whose log disagrees with its source's, such as a
+// former primary with writes that never reached the new one, stops with
+// ErrDiverged instead of applying batches on top.
+//
+// Changesets only hold row changes to tables with a PRIMARY KEY. Apply
+// schema changes on every node, for example with the migrate package. For
+// byte-for-byte file replicas, see WAL shipping in the backup package.
+package replication
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Role is whether a Node accepts writes.
+type Role int
+
+const (
+	// Replica nodes apply batches pulled from a source.
+	Replica Role = iota
+	// Primary nodes accept writes and serve their log.
+	Primary
+)
+
+func (r Role) String() string {
+	if r == Primary {
+		return "primary"
+	}
+	return "replica"
+}
+
+// LogTable is the table holding the replicated batches on every node.
+const LogTable = "_replication_log"
+
+const createLogTable = `CREATE TABLE IF NOT EXISTS _replication_log (
+	seq INTEGER PRIMARY KEY,
+	term INTEGER NOT NULL,
+	changeset BLOB NOT NULL,
+	ts INTEGER NOT NULL
+);`
+
+// Batch is the changeset of one committed write.
+type Batch struct {
+	// Seq numbers batches from 1 without gaps.
+	Seq int64
+	// Term is the term of the primary that wrote the batch.
+	Term      int64
+	Changeset []byte
+	Time      time.Time
+}
+
+// FetchResult is a page of a node's log.
+type FetchResult struct {
+	// PrevTerm is the term of the batch numbered after in the source's log,
+	// or 0 when after is 0. Replicas compare it with their own log.
+	PrevTerm int64
+	Batches  []Batch
+}
+
+// Transport fetches the log of a source node.
+type Transport interface {
+	// Fetch returns up to limit batches numbered after after, in order. It
+	// returns ErrReplicationGap when the source no longer holds them.
+	Fetch(ctx context.Context, after int64, limit int) (*FetchResult, error)
+}
+
+// Options configures a Node.
+type Options struct {
+	// Pool is the database of the node. Defaults to the global pool.
+	Pool *sqlitex.Pool
+	// Tables limits the tables whose changes are recorded. Defaults to every
+	// table.
+	Tables []string
+	// BatchSize is how many batches a replica fetches at a time. Defaults to
+	// 100.
+	BatchSize int
+}
+
+// Node is a primary or replica database.
+type Node struct {
+	opts Options
+
+	// mu guards role and serializes applying batches with Promote.
+	mu   sync.Mutex
+	role Role
+}
+
+// Errors returned by Node and Transport methods.
+var (
+	ErrNotPrimary     = errors.New("node is not the primary")
+	ErrNotReplica     = errors.New("node is not a replica")
+	ErrReplicationGap = errors.New("source no longer holds the batches the replica needs")
+	ErrDiverged       = errors.New("replica log has diverged from its source")
+)
+
+// NewNode creates the log table in the database of opts.Pool and returns a
+// Node in role. A new replica should start from a copy of its source, such
+// as one made with backup.BackupDatabase, or from an empty database with the
+// same schema and an empty log.
+func NewNode(ctx context.Context, role Role, opts Options) (*Node, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 100
+	}
+	n := &Node{opts: opts, role: role}
+	err := n.withConn(ctx, func(conn *sqlite.Conn) error {
+		if err := sqlitex.ExecuteTransient(conn, createLogTable, nil); err != nil {
+			return fmt.Errorf("failed to create %s: %w", LogTable, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Role returns the role of the node.
+func (n *Node) Role() Role {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.role
+}
+
+// Write runs fn in an immediate transaction and appends the changeset of its
+// changes to the log in the same transaction. If fn returns an error,
+// nothing is changed. It returns ErrNotPrimary on a replica.
+func (n *Node) Write(ctx context.Context, fn func(conn *sqlite.Conn) error) error {
+	if n.Role() != Primary {
+		return ErrNotPrimary
+	}
+	return n.withConn(ctx, func(conn *sqlite.Conn) (err error) {
+		session, err := conn.CreateSession("")
+		if err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+		defer session.Delete()
+		tables := n.opts.Tables
+		if len(tables) == 0 {
+			tables = []string{""}
+		}
+		for _, table := range tables {
+			if err := session.Attach(table); err != nil {
+				return err
+			}
+		}
+
+		endFn, err := sqlitex.ImmediateTransaction(conn)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer endFn(&err)
+		if err := fn(conn); err != nil {
+			return err
+		}
+		var buf bytes.Buffer
+		if err := session.WriteChangeset(&buf); err != nil {
+			return fmt.Errorf("failed to write changeset: %w", err)
+		}
+		if buf.Len() == 0 {
+			return nil
+		}
+		seq, term, err := lastBatch(conn)
+		if err != nil {
+			return err
+		}
+		return appendBatch(conn, Batch{Seq: seq + 1, Term: max(term, 1), Changeset: buf.Bytes(), Time: time.Now()})
+	})
+}
+
+// Exec is exec.Exec through Write.
+func (n *Node) Exec(ctx context.Context, query string, params map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	return n.Write(ctx, func(conn *sqlite.Conn) error {
+		return exec.ExecConn(conn, query, params, resultFunc)
+	})
+}
+
+// ExecMulti is exec.ExecMultiTx through Write: the statements are replicated
+// as one batch.
+func (n *Node) ExecMulti(ctx context.Context, queries []string, params []map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	if len(queries) != len(params) {
+		return fmt.Errorf("the number of queries (%d) does not match the number of params (%d)", len(queries), len(params))
+	}
+	return n.Write(ctx, func(conn *sqlite.Conn) error {
+		for i, query := range queries {
+			err := exec.ExecConn(conn, query, params[i], func(_ int, row map[string]interface{}) {
+				if resultFunc != nil {
+					resultFunc(i, row)
+				}
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Fetch returns up to limit batches of the node's log numbered after after,
+// so a Node is the Transport of replicas in the same process. Replicas can
+// fetch from replicas, too.
+func (n *Node) Fetch(ctx context.Context, after int64, limit int) (*FetchResult, error) {
+	result := &FetchResult{}
+	err := n.withConn(ctx, func(conn *sqlite.Conn) error {
+		return withReadTx(conn, func() error {
+			if after > 0 {
+				found := false
+				err := sqlitex.Execute(conn, "SELECT term FROM _replication_log WHERE seq = ?;", &sqlitex.ExecOptions{
+					Args: []interface{}{after},
+					ResultFunc: func(stmt *sqlite.Stmt) error {
+						result.PrevTerm = stmt.ColumnInt64(0)
+						found = true
+						return nil
+					},
+				})
+				if err != nil {
+					return fmt.Errorf("failed to read %s: %w", LogTable, err)
+				}
+				if !found {
+					// Either the batch was compacted away or the replica is
+					// ahead of the source; both need attention.
+					seq, _, err := lastBatch(conn)
+					if err != nil {
+						return err
+					}
+					if after > seq {
+						return ErrDiverged
+					}
+					return ErrReplicationGap
+				}
+			}
+			err := sqlitex.Execute(conn, "SELECT seq, term, changeset, ts FROM _replication_log WHERE seq > ? ORDER BY seq LIMIT ?;", &sqlitex.ExecOptions{
+				Args: []interface{}{after, limit},
+				ResultFunc: func(stmt *sqlite.Stmt) error {
+					changeset := make([]byte, stmt.ColumnLen(2))
+					stmt.ColumnBytes(2, changeset)
+					result.Batches = append(result.Batches, Batch{
+						Seq:       stmt.ColumnInt64(0),
+						Term:      stmt.ColumnInt64(1),
+						Changeset: changeset,
+						Time:      time.UnixMilli(stmt.ColumnInt64(3)),
+					})
+					return nil
+				},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to read %s: %w", LogTable, err)
+			}
+			if len(result.Batches) > 0 && result.Batches[0].Seq != after+1 {
+				return ErrReplicationGap
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Seq returns the number of the last batch in the node's log and its term.
+func (n *Node) Seq(ctx context.Context) (seq, term int64, err error) {
+	err = n.withConn(ctx, func(conn *sqlite.Conn) error {
+		seq, term, err = lastBatch(conn)
+		return err
+	})
+	return seq, term, err
+}
+
+// Sync fetches and applies every batch source holds after the node's log and
+// returns how many were applied. Each batch is applied and appended to the
+// log in one transaction. It returns ErrNotReplica once the node is promoted.
+func (n *Node) Sync(ctx context.Context, source Transport) (int, error) {
+	applied := 0
+	for {
+		if n.Role() != Replica {
+			return applied, ErrNotReplica
+		}
+		seq, term, err := n.Seq(ctx)
+		if err != nil {
+			return applied, err
+		}
+		result, err := source.Fetch(ctx, seq, n.opts.BatchSize)
+		if err != nil {
+			return applied, err
+		}
+		if seq > 0 && result.PrevTerm != term {
+			return applied, ErrDiverged
+		}
+		for _, batch := range result.Batches {
+			if err := n.apply(ctx, batch); err != nil {
+				return applied, err
+			}
+			applied++
+		}
+		if len(result.Batches) < n.opts.BatchSize {
+			return applied, nil
+		}
+	}
+}
+
+// Run calls Sync every interval until ctx is done or the node is promoted.
+// A Sync error stops Run unless it is temporary, such as an unreachable
+// source; pass onError to log those and keep going.
+func (n *Node) Run(ctx context.Context, source Transport, interval time.Duration, onError func(error)) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		_, err := n.Sync(ctx, source)
+		switch {
+		case errors.Is(err, ErrNotReplica):
+			return nil
+		case errors.Is(err, ErrDiverged), errors.Is(err, ErrReplicationGap):
+			return err
+		case err != nil && ctx.Err() == nil:
+			if onError == nil {
+				return err
+			}
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// apply applies batch and appends it to the log.
+func (n *Node) apply(ctx context.Context, batch Batch) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.role != Replica {
+		return ErrNotReplica
+	}
+	return n.withConn(ctx, func(conn *sqlite.Conn) (err error) {
+		endFn, err := sqlitex.ImmediateTransaction(conn)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer endFn(&err)
+		seq, _, err := lastBatch(conn)
+		if err != nil {
+			return err
+		}
+		if batch.Seq != seq+1 {
+			return fmt.Errorf("%w: got batch %d after %d", ErrReplicationGap, batch.Seq, seq)
+		}
+		if len(batch.Changeset) > 0 {
+			// Replicas must not be written to, so any conflict is an error.
+			err := conn.ApplyChangeset(bytes.NewReader(batch.Changeset), nil, func(sqlite.ConflictType, *sqlite.ChangesetIterator) sqlite.ConflictAction {
+				return sqlite.ChangesetAbort
+			})
+			if err != nil {
+				return fmt.Errorf("failed to apply batch %d: %w", batch.Seq, err)
+			}
+		}
+		return appendBatch(conn, batch)
+	})
+}
+
+// Promote makes a replica the primary, for failover once the old primary is
+// gone. It appends an empty batch with a new term, so replicas of the old
+// primary that follow this node detect any batches it never received. Stop
+// the old primary first: nothing here prevents two primaries.
+func (n *Node) Promote(ctx context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.role == Primary {
+		return nil
+	}
+	err := n.withConn(ctx, func(conn *sqlite.Conn) (err error) {
+		endFn, err := sqlitex.ImmediateTransaction(conn)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer endFn(&err)
+		seq, term, err := lastBatch(conn)
+		if err != nil {
+			return err
+		}
+		// Terms start at 1, so the first promotion also differs from batches
+		// written before any.
+		return appendBatch(conn, Batch{Seq: seq + 1, Term: max(term, 1) + 1, Changeset: []byte{}, Time: time.Now()})
+	})
+	if err != nil {
+		return err
+	}
+	n.role = Primary
+	return nil
+}
+
+// Compact deletes the batches numbered up to through from the log. Replicas
+// that have not applied them yet fail with ErrReplicationGap and must be
+// reseeded. The last batch is always kept, so its term stays known.
+func (n *Node) Compact(ctx context.Context, through int64) error {
+	return n.withConn(ctx, func(conn *sqlite.Conn) error {
+		err := sqlitex.ExecuteTransient(conn, "DELETE FROM _replication_log WHERE seq <= ? AND seq < (SELECT MAX(seq) FROM _replication_log);", &sqlitex.ExecOptions{
+			Args: []interface{}{through},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to compact %s: %w", LogTable, err)
+		}
+		return nil
+	})
+}
+
+// lastBatch returns the number and term of the last batch in the log.
+func lastBatch(conn *sqlite.Conn) (seq, term int64, err error) {
+	err = sqlitex.Execute(conn, "SELECT seq, term FROM _replication_log ORDER BY seq DESC LIMIT 1;", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			seq, term = stmt.ColumnInt64(0), stmt.ColumnInt64(1)
+			return nil
+		},
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read %s: %w", LogTable, err)
+	}
+	return seq, term, nil
+}
+
+func appendBatch(conn *sqlite.Conn, batch Batch) error {
+	err := sqlitex.Execute(conn, "INSERT INTO _replication_log (seq, term, changeset, ts) VALUES (?, ?, ?, ?);", &sqlitex.ExecOptions{
+		Args: []interface{}{batch.Seq, batch.Term, batch.Changeset, batch.Time.UnixMilli()},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append batch %d: %w", batch.Seq, err)
+	}
+	return nil
+}
+
+// withReadTx runs fn in a deferred transaction, so the reads it makes see one
+// snapshot.
+func withReadTx(conn *sqlite.Conn, fn func() error) (err error) {
+	endFn := sqlitex.Transaction(conn)
+	defer endFn(&err)
+	return fn()
+}
+
+// withConn runs fn with a connection of the node's pool.
+func (n *Node) withConn(ctx context.Context, fn func(conn *sqlite.Conn) error) error {
+	p := n.opts.Pool
+	if p == nil {
+		var err error
+		if p, err = pool.GetPool(); err != nil {
+			return sqliteutils.FailedToGetPoolError(err)
+		}
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+	return fn(conn)
+}
@@ -0,0 +1,125 @@
+package replication_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/replication"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// open opens a database with a users table.
+func open(t *testing.T, name string) *sqlitex.Pool {
+	t.Helper()
+	p, err := pool.Open(filepath.Join(t.TempDir(), name+".db"), 2)
+	require.NoError(t, err)
+	t.Cleanup(func() { pool.Close(p) })
+	conn, err := p.Take(context.Background())
+	require.NoError(t, err)
+	defer p.Put(conn)
+	require.NoError(t, sqlitex.ExecuteTransient(conn, "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);", nil))
+	return p
+}
+
+func newNode(t *testing.T, role replication.Role, p *sqlitex.Pool) *replication.Node {
+	t.Helper()
+	n, err := replication.NewNode(context.Background(), role, replication.Options{Pool: p, BatchSize: 2})
+	require.NoError(t, err)
+	return n
+}
+
+// users returns the names in the users table of p, ordered by id.
+func users(t *testing.T, p *sqlitex.Pool) []string {
+	t.Helper()
+	conn, err := p.Take(context.Background())
+	require.NoError(t, err)
+	defer p.Put(conn)
+	var names []string
+	err = sqlitex.ExecuteTransient(conn, "SELECT name FROM users ORDER BY id;", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			names = append(names, stmt.ColumnText(0))
+			return nil
+		},
+	})
+	require.NoError(t, err)
+	return names
+}
+
+func TestReplication(t *testing.T) {
+	ctx := context.Background()
+	primaryDB, replicaDB, otherDB := open(t, "primary"), open(t, "replica"), open(t, "other")
+	primary := newNode(t, replication.Primary, primaryDB)
+	replica := newNode(t, replication.Replica, replicaDB)
+	other := newNode(t, replication.Replica, otherDB)
+
+	require.NoError(t, primary.Exec(ctx, "INSERT INTO users (name) VALUES (:name);", map[string]interface{}{":name": "Alice"}, nil))
+	require.NoError(t, primary.ExecMulti(ctx, []string{
+		"INSERT INTO users (name) VALUES ('Bob');",
+		"INSERT INTO users (name) VALUES ('Carol');",
+	}, []map[string]interface{}{nil, nil}, nil))
+	require.NoError(t, primary.Exec(ctx, "UPDATE users SET name = 'Alicia' WHERE id = 1;", nil, nil))
+	// Writes that change nothing are not logged.
+	require.NoError(t, primary.Exec(ctx, "DELETE FROM users WHERE id = 99;", nil, nil))
+	seq, term, err := primary.Seq(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), seq)
+	assert.Equal(t, int64(1), term)
+
+	assert.ErrorIs(t, replica.Exec(ctx, "DELETE FROM users;", nil, nil), replication.ErrNotPrimary)
+
+	// Replicas pull from a Node directly or over HTTP, in pages of BatchSize.
+	server := httptest.NewServer(replication.NewHandler(primary))
+	defer server.Close()
+	applied, err := replica.Sync(ctx, &replication.HTTPTransport{URL: server.URL})
+	require.NoError(t, err)
+	assert.Equal(t, 3, applied)
+	applied, err = other.Sync(ctx, primary)
+	require.NoError(t, err)
+	assert.Equal(t, 3, applied)
+	applied, err = replica.Sync(ctx, primary)
+	require.NoError(t, err)
+	assert.Equal(t, 0, applied)
+	assert.Equal(t, []string{"Alicia", "Bob", "Carol"}, users(t, replicaDB))
+	assert.Equal(t, []string{"Alicia", "Bob", "Carol"}, users(t, otherDB))
+
+	// The primary fails with a write no replica received.
+	require.NoError(t, primary.Exec(ctx, "DELETE FROM users WHERE id = 2;", nil, nil))
+
+	require.NoError(t, replica.Promote(ctx))
+	assert.Equal(t, replication.Primary, replica.Role())
+	_, err = replica.Sync(ctx, primary)
+	assert.ErrorIs(t, err, replication.ErrNotReplica)
+	require.NoError(t, replica.Exec(ctx, "INSERT INTO users (name) VALUES ('Dave');", nil, nil))
+	seq, term, err = replica.Seq(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), seq)
+	assert.Equal(t, int64(2), term)
+
+	// The other replica follows the new primary, while the old primary,
+	// restarted as a replica, has diverged from it.
+	applied, err = other.Sync(ctx, replica)
+	require.NoError(t, err)
+	assert.Equal(t, 2, applied)
+	assert.Equal(t, []string{"Alicia", "Bob", "Carol", "Dave"}, users(t, otherDB))
+	_, err = newNode(t, replication.Replica, primaryDB).Sync(ctx, replica)
+	assert.ErrorIs(t, err, replication.ErrDiverged)
+
+	// Compacted batches can no longer be fetched.
+	require.NoError(t, replica.Compact(ctx, 10))
+	_, err = replica.Fetch(ctx, 2, 10)
+	assert.ErrorIs(t, err, replication.ErrReplicationGap)
+	newServer := httptest.NewServer(replication.NewHandler(replica))
+	defer newServer.Close()
+	_, err = (&replication.HTTPTransport{URL: newServer.URL}).Fetch(ctx, 2, 10)
+	assert.ErrorIs(t, err, replication.ErrReplicationGap)
+	result, err := replica.Fetch(ctx, 5, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), result.PrevTerm)
+	assert.Empty(t, result.Batches)
+}
@@ -0,0 +1,407 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/udf"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// IndexAdvice is what SuggestIndexes found for one query.
+type IndexAdvice struct {
+	Query string
+	// Plan holds the detail lines of the query's EXPLAIN QUERY PLAN.
+	Plan []string
+	// Scans names the tables the query reads in full, or through an index
+	// SQLite has to build for the statement.
+	Scans []string
+	// Indexes holds CREATE INDEX statements that turn those scans into
+	// searches.
+	Indexes []string
+}
+
+// SuggestIndexes reports which of queries scan tables of the database behind
+// the global pool and which indexes would let them search instead.
+//
+// Each query is planned with EXPLAIN QUERY PLAN. For the tables a query scans,
+// candidate indexes on the columns the query mentions are created in a
+// private in-memory copy of the schema and its statistics, and the candidates
+// the planner then uses to search those tables are suggested. The database
+// itself is not changed. The SQLite expert extension is not available to this
+// driver, so the candidates are limited to single columns and one composite
+// index of every mentioned column in order of appearance.
+func SuggestIndexes(ctx context.Context, queries []string) ([]IndexAdvice, error) {
+	p, err := pool.GetPool()
+	if err != nil {
+		return nil, sqliteutils.FailedToGetPoolError(err)
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		return nil, sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+
+	current, err := Inspect(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect schema: %w", err)
+	}
+
+	advice := make([]IndexAdvice, len(queries))
+	var scratch *sqlite.Conn
+	defer func() {
+		if scratch != nil {
+			scratch.Close()
+		}
+	}()
+	for i, query := range queries {
+		a := &advice[i]
+		a.Query = query
+		plan, err := explain(conn, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to explain %q: %w", query, err)
+		}
+		tokens := words(query)
+		a.Plan = plan
+		for _, detail := range plan {
+			if table := scannedTable(current, tokens, detail); table != nil && !contains(a.Scans, table.Name) {
+				a.Scans = append(a.Scans, table.Name)
+			}
+		}
+		if len(a.Scans) == 0 {
+			continue
+		}
+
+		if scratch == nil {
+			if scratch, err = copySchema(conn); err != nil {
+				return nil, err
+			}
+			scratch.SetInterrupt(ctx.Done())
+		}
+		if a.Indexes, err = tryCandidates(scratch, current, tokens, query, a.Scans); err != nil {
+			return nil, err
+		}
+	}
+	return advice, nil
+}
+
+// explain returns the detail lines of the EXPLAIN QUERY PLAN of query.
+// Parameters are left unbound, since they do not change the plan.
+func explain(conn *sqlite.Conn, query string) (plan []string, err error) {
+	stmt, _, err := conn.PrepareTransient("EXPLAIN QUERY PLAN " + strings.TrimRight(strings.TrimSpace(query), ";"))
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if finalizeErr := stmt.Finalize(); err == nil {
+			err = finalizeErr
+		}
+	}()
+	for {
+		hasRow, err := stmt.Step()
+		if err != nil {
+			return nil, err
+		}
+		if !hasRow {
+			return plan, nil
+		}
+		plan = append(plan, stmt.GetText("detail"))
+	}
+}
+
+// scannedTable returns the table a plan step reads in full, or nil. Plan
+// steps name tables by their alias when the query gives one.
+func scannedTable(s *Schema, tokens []string, detail string) *Table {
+	var name string
+	switch {
+	case strings.Contains(detail, "VIRTUAL TABLE"):
+		// Virtual tables are indexed by their module.
+		return nil
+	case strings.HasPrefix(detail, "SCAN ") && !strings.Contains(detail, "COVERING INDEX"):
+		name = strings.TrimPrefix(detail, "SCAN ")
+	case strings.HasPrefix(detail, "SEARCH ") && strings.Contains(detail, "USING AUTOMATIC"):
+		name = strings.TrimPrefix(detail, "SEARCH ")
+	default:
+		return nil
+	}
+	if end := strings.IndexByte(name, ' '); end >= 0 {
+		name = name[:end]
+	}
+	name = strings.TrimPrefix(name, "main.")
+	if t := s.Table(resolveAlias(s, tokens, name)); t != nil {
+		return t
+	}
+	return nil
+}
+
+// resolveAlias returns the table that alias stands for in the query made of
+// tokens, or alias itself.
+func resolveAlias(s *Schema, tokens []string, alias string) string {
+	for i := 0; i+1 < len(tokens); i++ {
+		if s.Table(tokens[i]) == nil {
+			continue
+		}
+		next := i + 1
+		if strings.EqualFold(tokens[next], "AS") && next+1 < len(tokens) {
+			next++
+		}
+		if strings.EqualFold(tokens[next], alias) && !aliasKeywords[strings.ToUpper(tokens[next])] {
+			return tokens[i]
+		}
+	}
+	return alias
+}
+
+// aliasKeywords are the keywords that may follow a table name in place of an
+// alias.
+var aliasKeywords = map[string]bool{
+	"CROSS": true, "EXCEPT": true, "FULL": true, "GROUP": true, "HAVING": true,
+	"INDEXED": true, "INNER": true, "INTERSECT": true, "JOIN": true, "LEFT": true,
+	"LIMIT": true, "NATURAL": true, "NOT": true, "ON": true, "ORDER": true,
+	"OUTER": true, "RETURNING": true, "RIGHT": true, "SELECT": true, "SET": true,
+	"UNION": true, "USING": true, "VALUES": true, "WHERE": true, "WINDOW": true,
+}
+
+// tryCandidates creates candidate indexes for the scanned tables on scratch,
+// plans query again and returns the CREATE INDEX statements of the
+// candidates used to search. The candidates are dropped again.
+func tryCandidates(scratch *sqlite.Conn, s *Schema, tokens []string, query string, scans []string) (suggestions []string, err error) {
+	type candidate struct{ name, create string }
+	var candidates []candidate
+	seen := make(map[string]bool)
+	for _, name := range scans {
+		t := s.Table(name)
+		var columns []string
+		for _, token := range tokens {
+			c := t.Column(token)
+			if c == nil || contains(columns, c.Name) || isRowID(t, c) {
+				continue
+			}
+			columns = append(columns, c.Name)
+		}
+		sets := make([][]string, 0, len(columns)+1)
+		for _, c := range columns {
+			sets = append(sets, []string{c})
+		}
+		if len(columns) > 1 {
+			sets = append(sets, columns)
+		}
+		for _, set := range sets {
+			index := indexName(t.Name, set)
+			if seen[index] || t.Index(index) != nil {
+				continue
+			}
+			seen[index] = true
+			quoted := make([]string, len(set))
+			for i, c := range set {
+				quoted[i] = sqliteutils.QuoteIdentifier(c)
+			}
+			candidates = append(candidates, candidate{index, fmt.Sprintf("CREATE INDEX %s ON %s (%s);",
+				sqliteutils.QuoteIdentifier(index), sqliteutils.QuoteIdentifier(t.Name), strings.Join(quoted, ", "))})
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	defer func() {
+		for _, c := range candidates {
+			if dropErr := sqlitex.ExecuteTransient(scratch, "DROP INDEX IF EXISTS "+sqliteutils.QuoteIdentifier(c.name)+";", nil); dropErr != nil && err == nil {
+				err = fmt.Errorf("failed to drop candidate index %s: %w", c.name, dropErr)
+			}
+		}
+	}()
+	for _, c := range candidates {
+		if err := sqlitex.ExecuteTransient(scratch, c.create, nil); err != nil {
+			return nil, fmt.Errorf("failed to create candidate index %s: %w", c.name, err)
+		}
+	}
+
+	plan, err := explain(scratch, query)
+	if err != nil {
+		// The copy lacks objects the query needs, such as functions
+		// registered by the caller, so there is nothing to suggest.
+		return nil, nil
+	}
+	for _, detail := range plan {
+		if !strings.HasPrefix(detail, "SEARCH ") {
+			continue
+		}
+		for _, c := range candidates {
+			used := strings.Contains(detail, " INDEX "+c.name+" ") || strings.HasSuffix(detail, " INDEX "+c.name)
+			if used && !contains(suggestions, c.create) {
+				suggestions = append(suggestions, c.create)
+			}
+		}
+	}
+	return suggestions, nil
+}
+
+// isRowID reports whether c is the INTEGER PRIMARY KEY of t, which is
+// already the key of the table's b-tree.
+func isRowID(t *Table, c *Column) bool {
+	if c.PrimaryKey != 1 || !strings.EqualFold(c.Type, "INTEGER") {
+		return false
+	}
+	for _, other := range t.Columns {
+		if other.PrimaryKey > 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// indexName names an index on columns of table as <table>_<column>_...,
+// the convention GenerateDDL uses.
+func indexName(table string, columns []string) string {
+	return table + "_" + strings.Join(columns, "_")
+}
+
+// copySchema opens a private in-memory database with the tables, indexes
+// and views of conn and its planner statistics, but none of its rows.
+// Objects that cannot be recreated, such as virtual tables from modules the
+// copy lacks, are left out.
+func copySchema(conn *sqlite.Conn) (*sqlite.Conn, error) {
+	scratch, err := sqlite.OpenConn(":memory:")
+	if err != nil {
+		return nil, sqliteutils.FailedToOpenDatabaseError(err, ":memory:")
+	}
+	if err := udf.SetCollations(scratch, false); err != nil {
+		scratch.Close()
+		return nil, err
+	}
+	if err := scratch.CreateFunction("regexp", udf.Regexp()); err != nil {
+		scratch.Close()
+		return nil, err
+	}
+
+	var statements []string
+	hasStats := false
+	err = sqlitex.ExecuteTransient(conn, `SELECT name, sql FROM sqlite_master
+		WHERE type IN ('table', 'index', 'view') ORDER BY rowid;`, &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			name := stmt.ColumnText(0)
+			if name == "sqlite_stat1" {
+				hasStats = true
+			}
+			if !stmt.ColumnIsNull(1) && !strings.HasPrefix(name, "sqlite_") {
+				statements = append(statements, stmt.ColumnText(1))
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		scratch.Close()
+		return nil, fmt.Errorf("failed to read schema: %w", err)
+	}
+	for _, statement := range statements {
+		// Shadow tables of virtual tables already exist, and modules or
+		// collations may be missing; the rest of the schema still helps.
+		_ = sqlitex.ExecuteTransient(scratch, statement, nil)
+	}
+
+	if hasStats {
+		var rows [][3]interface{}
+		err = sqlitex.ExecuteTransient(conn, "SELECT tbl, idx, stat FROM sqlite_stat1;", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				row := [3]interface{}{stmt.ColumnText(0), nil, stmt.ColumnText(2)}
+				if !stmt.ColumnIsNull(1) {
+					row[1] = stmt.ColumnText(1)
+				}
+				rows = append(rows, row)
+				return nil
+			},
+		})
+		if err == nil {
+			err = sqlitex.ExecuteTransient(scratch, "ANALYZE sqlite_master;", nil)
+		}
+		for _, row := range rows {
+			if err != nil {
+				break
+			}
+			err = sqlitex.ExecuteTransient(scratch, "INSERT INTO sqlite_stat1 (tbl, idx, stat) VALUES (?, ?, ?);", &sqlitex.ExecOptions{
+				Args: row[:],
+			})
+		}
+		if err == nil {
+			// Load the copied statistics into the planner.
+			err = sqlitex.ExecuteTransient(scratch, "ANALYZE sqlite_master;", nil)
+		}
+		if err != nil {
+			scratch.Close()
+			return nil, fmt.Errorf("failed to copy statistics: %w", err)
+		}
+	}
+	return scratch, nil
+}
+
+// words returns the identifiers and keywords of query in order, without
+// quotes, skipping string literals and comments.
+func words(query string) []string {
+	var out []string
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 4
+			}
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			var b strings.Builder
+			j := i + 1
+			for ; j < len(query); j++ {
+				if query[j] == closing {
+					if closing != ']' && j+1 < len(query) && query[j+1] == closing {
+						b.WriteByte(closing)
+						j++
+						continue
+					}
+					break
+				}
+				b.WriteByte(query[j])
+			}
+			if c != '\'' {
+				out = append(out, b.String())
+			}
+			i = j + 1
+		case isWordChar(c):
+			j := i
+			for j < len(query) && isWordChar(query[j]) {
+				j++
+			}
+			out = append(out, query[i:j])
+			i = j
+		default:
+			i++
+		}
+	}
+	return out
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,49 @@
+package schema_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/schema"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSuggestIndexes(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, `
+		CREATE TABLE customers (id INTEGER PRIMARY KEY, email TEXT, country TEXT);
+		CREATE TABLE orders (id INTEGER PRIMARY KEY, customer_id INTEGER, status TEXT, total REAL);
+		CREATE INDEX orders_status ON orders (status);
+	`, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	advice, err := schema.SuggestIndexes(ctx, []string{
+		"SELECT * FROM customers WHERE email = :email;",
+		"SELECT count(*) FROM orders WHERE status = 'open'",
+		"SELECT c.email, o.total FROM customers AS c JOIN orders o ON o.customer_id = c.id WHERE c.country = 'NZ'",
+		"SELECT * FROM customers WHERE id = 1",
+	})
+	require.NoError(t, err)
+	require.Len(t, advice, 4)
+
+	assert.Equal(t, []string{"customers"}, advice[0].Scans)
+	assert.Equal(t, []string{`CREATE INDEX "customers_email" ON "customers" ("email");`}, advice[0].Indexes)
+	assert.NotEmpty(t, advice[0].Plan)
+
+	assert.Empty(t, advice[1].Scans)
+	assert.Empty(t, advice[1].Indexes)
+
+	// Aliases are resolved to the tables they stand for.
+	assert.Equal(t, []string{"orders"}, advice[2].Scans)
+	assert.Equal(t, []string{`CREATE INDEX "orders_customer_id" ON "orders" ("customer_id");`}, advice[2].Indexes)
+
+	assert.Empty(t, advice[3].Scans)
+
+	_, err = schema.SuggestIndexes(ctx, []string{"SELECT * FROM missing"})
+	assert.Error(t, err)
+}
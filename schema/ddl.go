@@ -0,0 +1,273 @@
+package schema
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/dropsite-ai/sqliteutils"
+)
+
+// GenerateDDL returns CREATE TABLE and CREATE INDEX statements for table based
+// on the fields of model, a struct or pointer to struct. The result is a
+// script suitable for migrate.RegisterSQL or Parse.
+//
+// Columns are configured with a `db` struct tag whose first element is the
+// column name, followed by comma-separated options:
+//
+//	type=TEXT        column type; inferred from the Go type when omitted
+//	pk               part of the primary key
+//	autoincrement    INTEGER PRIMARY KEY AUTOINCREMENT (single-column keys only)
+//	notnull          NOT NULL
+//	unique           UNIQUE column constraint
+//	default=VALUE    DEFAULT VALUE, copied verbatim
+//	index            single-column index named <table>_<column>
+//	index=NAME       index NAME; fields sharing a name form a composite index
+//	uniqueindex=NAME like index=NAME, but UNIQUE
+//	fk=TABLE(COLUMN) REFERENCES TABLE(COLUMN)
+//	ondelete=ACTION  ON DELETE ACTION for fk, e.g. cascade
+//
+// A tag of "-" skips the field, and untagged exported fields use the
+// snake_case form of their name. Anonymous struct fields are flattened.
+func GenerateDDL(table string, model interface{}) (string, error) {
+	t := reflect.TypeOf(model)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return "", fmt.Errorf("model for table %s must be a struct, got %T", table, model)
+	}
+
+	var fields []ddlField
+	if err := collectFields(t, &fields); err != nil {
+		return "", fmt.Errorf("table %s: %w", table, err)
+	}
+	if len(fields) == 0 {
+		return "", fmt.Errorf("table %s has no columns", table)
+	}
+
+	var pk []string
+	for _, f := range fields {
+		if f.pk {
+			pk = append(pk, f.name)
+		}
+	}
+	for _, f := range fields {
+		if f.autoincrement && (len(pk) != 1 || !f.pk || !strings.EqualFold(f.typ, "INTEGER")) {
+			return "", fmt.Errorf("table %s: autoincrement requires %s to be the only INTEGER primary key", table, f.name)
+		}
+	}
+
+	var defs []string
+	for _, f := range fields {
+		def := sqliteutils.QuoteIdentifier(f.name) + " " + f.typ
+		if f.pk && len(pk) == 1 {
+			def += " PRIMARY KEY"
+			if f.autoincrement {
+				def += " AUTOINCREMENT"
+			}
+		}
+		if f.notNull {
+			def += " NOT NULL"
+		}
+		if f.unique {
+			def += " UNIQUE"
+		}
+		if f.def != "" {
+			def += " DEFAULT " + f.def
+		}
+		if f.fk != "" {
+			def += " REFERENCES " + f.fk
+			if f.onDelete != "" {
+				def += " ON DELETE " + strings.ToUpper(f.onDelete)
+			}
+		}
+		defs = append(defs, def)
+	}
+	if len(pk) > 1 {
+		quoted := make([]string, len(pk))
+		for i, name := range pk {
+			quoted[i] = sqliteutils.QuoteIdentifier(name)
+		}
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(quoted, ", ")+")")
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CREATE TABLE %s (\n\t%s\n);\n", sqliteutils.QuoteIdentifier(table), strings.Join(defs, ",\n\t"))
+	for _, idx := range collectIndexes(table, fields) {
+		quoted := make([]string, len(idx.Columns))
+		for i, name := range idx.Columns {
+			quoted[i] = sqliteutils.QuoteIdentifier(name)
+		}
+		create := "CREATE INDEX"
+		if idx.Unique {
+			create = "CREATE UNIQUE INDEX"
+		}
+		fmt.Fprintf(&b, "%s %s ON %s (%s);\n", create, sqliteutils.QuoteIdentifier(idx.Name),
+			sqliteutils.QuoteIdentifier(table), strings.Join(quoted, ", "))
+	}
+	return b.String(), nil
+}
+
+// ddlField is a struct field parsed from its db tag.
+type ddlField struct {
+	name          string
+	typ           string
+	pk            bool
+	autoincrement bool
+	notNull       bool
+	unique        bool
+	def           string
+	fk            string
+	onDelete      string
+	indexes       []string
+	uniqueIndexes []string
+}
+
+func collectFields(t reflect.Type, fields *[]ddlField) error {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag, tagged := sf.Tag.Lookup("db")
+		if tag == "-" {
+			continue
+		}
+		if sf.Anonymous && !tagged {
+			ft := sf.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				if err := collectFields(ft, fields); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		parts := strings.Split(tag, ",")
+		f := ddlField{name: strings.TrimSpace(parts[0])}
+		if f.name == "" {
+			f.name = snakeCase(sf.Name)
+		}
+		for _, opt := range parts[1:] {
+			key, value, _ := strings.Cut(strings.TrimSpace(opt), "=")
+			switch strings.ToLower(key) {
+			case "type":
+				f.typ = value
+			case "pk":
+				f.pk = true
+			case "autoincrement":
+				f.autoincrement = true
+			case "notnull":
+				f.notNull = true
+			case "unique":
+				f.unique = true
+			case "default":
+				f.def = value
+			case "index":
+				f.indexes = append(f.indexes, value)
+			case "uniqueindex":
+				if value == "" {
+					return fmt.Errorf("field %s: uniqueindex requires a name", sf.Name)
+				}
+				f.uniqueIndexes = append(f.uniqueIndexes, value)
+			case "fk":
+				f.fk = value
+			case "ondelete":
+				f.onDelete = value
+			case "":
+			default:
+				return fmt.Errorf("field %s: unknown db tag option %q", sf.Name, key)
+			}
+		}
+		if f.typ == "" {
+			typ, ok := columnType(sf.Type)
+			if !ok {
+				return fmt.Errorf("field %s: cannot infer column type for %s, set type=", sf.Name, sf.Type)
+			}
+			f.typ = typ
+		}
+		*fields = append(*fields, f)
+	}
+	return nil
+}
+
+// collectIndexes groups the index options of fields into indexes ordered by name.
+func collectIndexes(table string, fields []ddlField) []Index {
+	byName := make(map[string]*Index)
+	var names []string
+	add := func(name, column string, unique bool) {
+		idx, ok := byName[name]
+		if !ok {
+			idx = &Index{Name: name, Table: table, Unique: unique}
+			byName[name] = idx
+			names = append(names, name)
+		}
+		idx.Columns = append(idx.Columns, column)
+	}
+	for _, f := range fields {
+		for _, name := range f.indexes {
+			if name == "" {
+				name = table + "_" + f.name
+			}
+			add(name, f.name, false)
+		}
+		for _, name := range f.uniqueIndexes {
+			add(name, f.name, true)
+		}
+	}
+	sort.Strings(names)
+	indexes := make([]Index, len(names))
+	for i, name := range names {
+		indexes[i] = *byName[name]
+	}
+	return indexes
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// columnType maps a Go type to the SQLite column type used for it.
+func columnType(t reflect.Type) (string, bool) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return "DATETIME", true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "INTEGER", true
+	case reflect.Float32, reflect.Float64:
+		return "REAL", true
+	case reflect.String:
+		return "TEXT", true
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "BLOB", true
+		}
+	}
+	return "", false
+}
+
+// snakeCase converts a Go identifier such as UserID to user_id.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
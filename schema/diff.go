@@ -0,0 +1,226 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// DiffKind classifies a Difference.
+type DiffKind string
+
+const (
+	MissingTable  DiffKind = "missing_table"
+	ExtraTable    DiffKind = "extra_table"
+	MissingColumn DiffKind = "missing_column"
+	ExtraColumn   DiffKind = "extra_column"
+	ChangedColumn DiffKind = "changed_column"
+	MissingIndex  DiffKind = "missing_index"
+	ExtraIndex    DiffKind = "extra_index"
+	ChangedIndex  DiffKind = "changed_index"
+)
+
+// Difference is one way the current schema departs from the desired one.
+type Difference struct {
+	Kind   DiffKind
+	Table  string
+	Column string
+	Index  string
+	// Current and Desired describe the differing definitions, when both exist.
+	Current string
+	Desired string
+	// Fix holds the statements that resolve the difference, or is empty when
+	// it cannot be fixed without rebuilding the table or dropping data.
+	Fix []string
+}
+
+func (d Difference) String() string {
+	switch {
+	case d.Column != "":
+		return fmt.Sprintf("%s: %s.%s", d.Kind, d.Table, d.Column)
+	case d.Index != "":
+		return fmt.Sprintf("%s: %s on %s", d.Kind, d.Index, d.Table)
+	default:
+		return fmt.Sprintf("%s: %s", d.Kind, d.Table)
+	}
+}
+
+// Diff compares the schema of the database behind currentPool with the schema
+// produced by running desiredDDL against an empty database.
+func Diff(ctx context.Context, currentPool *sqlitex.Pool, desiredDDL string) ([]Difference, error) {
+	desired, err := Parse(desiredDDL)
+	if err != nil {
+		return nil, err
+	}
+	return diffPool(ctx, currentPool, desired)
+}
+
+// diffPool compares the schema of the database behind p with desired.
+func diffPool(ctx context.Context, p *sqlitex.Pool, desired *Schema) ([]Difference, error) {
+	conn, err := p.Take(ctx)
+	if err != nil {
+		return nil, sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+
+	current, err := Inspect(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect current schema: %w", err)
+	}
+	return Compare(current, desired), nil
+}
+
+// Parse builds a Schema by executing ddl against a private in-memory database.
+func Parse(ddl string) (*Schema, error) {
+	conn, err := sqlite.OpenConn(":memory:")
+	if err != nil {
+		return nil, sqliteutils.FailedToOpenDatabaseError(err, ":memory:")
+	}
+	defer conn.Close()
+
+	if err := sqlitex.ExecuteScript(conn, ddl, nil); err != nil {
+		return nil, sqliteutils.FailedToExecScriptError(err, ddl)
+	}
+	return Inspect(conn)
+}
+
+// Compare lists the differences between current and desired.
+func Compare(current, desired *Schema) []Difference {
+	var diffs []Difference
+	for _, want := range desired.Tables {
+		have := current.Table(want.Name)
+		if have == nil {
+			diffs = append(diffs, Difference{Kind: MissingTable, Table: want.Name, Desired: want.SQL, Fix: []string{want.SQL + ";"}})
+			for _, idx := range want.Indexes {
+				diffs = append(diffs, Difference{Kind: MissingIndex, Table: want.Name, Index: idx.Name, Desired: idx.SQL, Fix: []string{idx.SQL + ";"}})
+			}
+			continue
+		}
+		diffs = append(diffs, compareColumns(have, &want)...)
+		diffs = append(diffs, compareIndexes(have, &want)...)
+	}
+	for _, have := range current.Tables {
+		if desired.Table(have.Name) == nil {
+			diffs = append(diffs, Difference{Kind: ExtraTable, Table: have.Name, Current: have.SQL})
+		}
+	}
+	return diffs
+}
+
+// FixStatements returns the Fix statements of diffs, skipping differences
+// that cannot be fixed automatically.
+func FixStatements(diffs []Difference) []string {
+	var statements []string
+	for _, d := range diffs {
+		statements = append(statements, d.Fix...)
+	}
+	return statements
+}
+
+func compareColumns(have, want *Table) []Difference {
+	var diffs []Difference
+	for _, wc := range want.Columns {
+		hc := have.Column(wc.Name)
+		if hc == nil {
+			diffs = append(diffs, Difference{
+				Kind:    MissingColumn,
+				Table:   want.Name,
+				Column:  wc.Name,
+				Desired: columnDefinition(wc),
+				Fix:     addColumnStatement(want.Name, wc),
+			})
+			continue
+		}
+		if !sameColumn(*hc, wc) {
+			diffs = append(diffs, Difference{
+				Kind:    ChangedColumn,
+				Table:   want.Name,
+				Column:  wc.Name,
+				Current: columnDefinition(*hc),
+				Desired: columnDefinition(wc),
+			})
+		}
+	}
+	for _, hc := range have.Columns {
+		if want.Column(hc.Name) == nil {
+			diffs = append(diffs, Difference{Kind: ExtraColumn, Table: have.Name, Column: hc.Name, Current: columnDefinition(hc)})
+		}
+	}
+	return diffs
+}
+
+func compareIndexes(have, want *Table) []Difference {
+	var diffs []Difference
+	for _, wi := range want.Indexes {
+		hi := have.Index(wi.Name)
+		if hi == nil {
+			diffs = append(diffs, Difference{Kind: MissingIndex, Table: want.Name, Index: wi.Name, Desired: wi.SQL, Fix: []string{wi.SQL + ";"}})
+			continue
+		}
+		if hi.Unique != wi.Unique || !strings.EqualFold(strings.Join(hi.Columns, ","), strings.Join(wi.Columns, ",")) {
+			diffs = append(diffs, Difference{
+				Kind:    ChangedIndex,
+				Table:   want.Name,
+				Index:   wi.Name,
+				Current: hi.SQL,
+				Desired: wi.SQL,
+				Fix:     []string{"DROP INDEX " + sqliteutils.QuoteIdentifier(hi.Name) + ";", wi.SQL + ";"},
+			})
+		}
+	}
+	for _, hi := range have.Indexes {
+		if want.Index(hi.Name) == nil {
+			diffs = append(diffs, Difference{
+				Kind:    ExtraIndex,
+				Table:   have.Name,
+				Index:   hi.Name,
+				Current: hi.SQL,
+				Fix:     []string{"DROP INDEX " + sqliteutils.QuoteIdentifier(hi.Name) + ";"},
+			})
+		}
+	}
+	return diffs
+}
+
+func sameColumn(a, b Column) bool {
+	return strings.EqualFold(a.Type, b.Type) &&
+		a.NotNull == b.NotNull &&
+		a.HasDefault == b.HasDefault &&
+		a.Default == b.Default &&
+		a.PrimaryKey == b.PrimaryKey
+}
+
+// columnDefinition renders a column the way it would appear in CREATE TABLE.
+func columnDefinition(c Column) string {
+	def := sqliteutils.QuoteIdentifier(c.Name)
+	if c.Type != "" {
+		def += " " + c.Type
+	}
+	if c.PrimaryKey > 0 {
+		def += " PRIMARY KEY"
+	}
+	if c.NotNull {
+		def += " NOT NULL"
+	}
+	if c.HasDefault {
+		def += " DEFAULT " + c.Default
+	}
+	return def
+}
+
+// addColumnStatement returns an ALTER TABLE statement adding c, or nil when
+// SQLite cannot add such a column in place.
+func addColumnStatement(table string, c Column) []string {
+	if c.PrimaryKey > 0 || (c.NotNull && !c.HasDefault) {
+		return nil
+	}
+	if c.HasDefault && (strings.HasPrefix(c.Default, "(") || strings.HasPrefix(strings.ToUpper(c.Default), "CURRENT_")) {
+		// ADD COLUMN only accepts constant defaults.
+		return nil
+	}
+	return []string{"ALTER TABLE " + sqliteutils.QuoteIdentifier(table) + " ADD COLUMN " + columnDefinition(c) + ";"}
+}
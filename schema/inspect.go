@@ -0,0 +1,175 @@
+package schema
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Schema is the set of user tables in a database.
+type Schema struct {
+	Tables []Table
+}
+
+// Table describes a table, its columns and its explicitly created indexes.
+type Table struct {
+	Name    string
+	SQL     string
+	Columns []Column
+	Indexes []Index
+}
+
+// Column describes a table column as reported by PRAGMA table_info.
+type Column struct {
+	Name    string
+	Type    string
+	NotNull bool
+	// Default is the default value expression as written in the DDL.
+	Default    string
+	HasDefault bool
+	// PrimaryKey is the 1-based position of the column in the primary key, or 0.
+	PrimaryKey int
+}
+
+// Index describes an index created with CREATE INDEX.
+type Index struct {
+	Name    string
+	Table   string
+	Unique  bool
+	Columns []string
+	SQL     string
+}
+
+// Table returns the table with the given name, or nil.
+func (s *Schema) Table(name string) *Table {
+	for i := range s.Tables {
+		if strings.EqualFold(s.Tables[i].Name, name) {
+			return &s.Tables[i]
+		}
+	}
+	return nil
+}
+
+// Column returns the column with the given name, or nil.
+func (t *Table) Column(name string) *Column {
+	for i := range t.Columns {
+		if strings.EqualFold(t.Columns[i].Name, name) {
+			return &t.Columns[i]
+		}
+	}
+	return nil
+}
+
+// Index returns the index with the given name, or nil.
+func (t *Table) Index(name string) *Index {
+	for i := range t.Indexes {
+		if strings.EqualFold(t.Indexes[i].Name, name) {
+			return &t.Indexes[i]
+		}
+	}
+	return nil
+}
+
+// Inspect reads the schema of the main database of conn. Internal sqlite_
+// tables, the migrate package's bookkeeping tables and HistoryTable are
+// skipped.
+func Inspect(conn *sqlite.Conn) (*Schema, error) {
+	s := &Schema{}
+	err := sqlitex.Execute(conn, `SELECT name, sql FROM sqlite_master
+		WHERE type = 'table' AND name NOT LIKE 'sqlite\_%' ESCAPE '\'
+		AND name NOT IN ('schema_migrations', 'schema_migrations_lock', ?)
+		ORDER BY name;`, &sqlitex.ExecOptions{
+		Args: []interface{}{HistoryTable},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			s.Tables = append(s.Tables, Table{Name: stmt.ColumnText(0), SQL: stmt.ColumnText(1)})
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range s.Tables {
+		t := &s.Tables[i]
+		if t.Columns, err = inspectColumns(conn, t.Name); err != nil {
+			return nil, err
+		}
+		if t.Indexes, err = inspectIndexes(conn, t.Name); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+func inspectColumns(conn *sqlite.Conn, table string) ([]Column, error) {
+	var columns []Column
+	err := sqlitex.Execute(conn, "PRAGMA table_info("+sqliteutils.QuoteIdentifier(table)+");", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			columns = append(columns, Column{
+				Name:       stmt.GetText("name"),
+				Type:       stmt.GetText("type"),
+				NotNull:    stmt.GetInt64("notnull") != 0,
+				Default:    stmt.GetText("dflt_value"),
+				HasDefault: !stmt.IsNull("dflt_value"),
+				PrimaryKey: int(stmt.GetInt64("pk")),
+			})
+			return nil
+		},
+	})
+	return columns, err
+}
+
+func inspectIndexes(conn *sqlite.Conn, table string) ([]Index, error) {
+	var indexes []Index
+	err := sqlitex.Execute(conn, "PRAGMA index_list("+sqliteutils.QuoteIdentifier(table)+");", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			if stmt.GetText("origin") != "c" {
+				// Skip indexes backing PRIMARY KEY and UNIQUE constraints.
+				return nil
+			}
+			indexes = append(indexes, Index{
+				Name:   stmt.GetText("name"),
+				Table:  table,
+				Unique: stmt.GetInt64("unique") != 0,
+			})
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].Name < indexes[j].Name })
+
+	for i := range indexes {
+		idx := &indexes[i]
+		err := sqlitex.Execute(conn, "PRAGMA index_info("+sqliteutils.QuoteIdentifier(idx.Name)+");", &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				// Expression indexes report a NULL column name.
+				name := stmt.GetText("name")
+				if stmt.IsNull("name") {
+					name = "<expr>"
+				}
+				idx.Columns = append(idx.Columns, name)
+				return nil
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		err = sqlitex.Execute(conn, "SELECT sql FROM sqlite_master WHERE type = 'index' AND name = ?;", &sqlitex.ExecOptions{
+			Args: []interface{}{idx.Name},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				idx.SQL = stmt.ColumnText(0)
+				return nil
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return indexes, nil
+}
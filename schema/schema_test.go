@@ -0,0 +1,176 @@
+package schema_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/schema"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const current = `
+	CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		status TEXT DEFAULT 'active',
+		legacy TEXT
+	);
+	CREATE INDEX users_name ON users (name);
+	CREATE INDEX users_legacy ON users (legacy);
+	CREATE TABLE sessions (id INTEGER PRIMARY KEY);
+`
+
+const desired = `
+	CREATE TABLE users (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		name TEXT NOT NULL,
+		status TEXT DEFAULT 'pending',
+		email TEXT,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+	CREATE UNIQUE INDEX users_name ON users (name);
+	CREATE TABLE orders (
+		id INTEGER PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id)
+	);
+	CREATE INDEX orders_user_id ON orders (user_id);
+`
+
+func TestDiff(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, current, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	p, err := pool.GetPool()
+	require.NoError(t, err)
+
+	diffs, err := schema.Diff(ctx, p, desired)
+	require.NoError(t, err)
+
+	var got []string
+	for _, d := range diffs {
+		got = append(got, d.String())
+	}
+	assert.Equal(t, []string{
+		"missing_table: orders",
+		"missing_index: orders_user_id on orders",
+		"changed_column: users.status",
+		"missing_column: users.email",
+		"missing_column: users.created_at",
+		"extra_column: users.legacy",
+		"changed_index: users_name on users",
+		"extra_index: users_legacy on users",
+		"extra_table: sessions",
+	}, got)
+
+	assert.Equal(t, `"status" TEXT DEFAULT 'active'`, diffs[2].Current)
+	assert.Equal(t, `"status" TEXT DEFAULT 'pending'`, diffs[2].Desired)
+	assert.Empty(t, diffs[2].Fix)
+	assert.Equal(t, []string{`ALTER TABLE "users" ADD COLUMN "email" TEXT;`}, diffs[3].Fix)
+	assert.Empty(t, diffs[4].Fix, "CURRENT_TIMESTAMP defaults cannot be added in place")
+	assert.Equal(t, []string{`DROP INDEX "users_name";`, `CREATE UNIQUE INDEX users_name ON users (name);`}, diffs[6].Fix)
+
+	// Applying the generated fixes resolves every fixable difference.
+	for _, statement := range schema.FixStatements(diffs) {
+		require.NoError(t, exec.Exec(ctx, statement, nil, nil), statement)
+	}
+	diffs, err = schema.Diff(ctx, p, desired)
+	require.NoError(t, err)
+	for _, d := range diffs {
+		assert.Empty(t, d.Fix, d.String())
+	}
+}
+
+func TestValidate(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, current, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	// Extra tables, columns and indexes in the database are allowed.
+	require.NoError(t, schema.ValidateDDL(ctx, `
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			name TEXT NOT NULL,
+			status TEXT DEFAULT 'active'
+		);
+		CREATE INDEX users_name ON users (name);
+	`))
+
+	err := schema.ValidateDDL(ctx, desired)
+	require.ErrorIs(t, err, sqliteutils.ErrSchemaMismatch)
+	assert.Contains(t, err.Error(), "missing_table: orders")
+	assert.Contains(t, err.Error(), `changed_column: users.status (have "status" TEXT DEFAULT 'active', want "status" TEXT DEFAULT 'pending')`)
+	assert.NotContains(t, err.Error(), "sessions")
+	assert.NotContains(t, err.Error(), "legacy")
+}
+
+type timestamps struct {
+	CreatedAt time.Time `db:"created_at,notnull,default=CURRENT_TIMESTAMP"`
+}
+
+type user struct {
+	ID     int64  `db:"id,pk,autoincrement"`
+	Email  string `db:"email,notnull,unique"`
+	Name   string `db:",index"`
+	Status string `db:"status,default='active'"`
+	timestamps
+	Scratch string `db:"-"`
+}
+
+type membership struct {
+	UserID  int64   `db:"user_id,pk,fk=users(id),ondelete=cascade,uniqueindex=memberships_user_org"`
+	OrgID   int64   `db:"org_id,pk,uniqueindex=memberships_user_org"`
+	Role    *string `db:"role,type=VARCHAR(32)"`
+	Avatar  []byte
+	private string
+}
+
+func TestGenerateDDL(t *testing.T) {
+	usersDDL, err := schema.GenerateDDL("users", user{})
+	require.NoError(t, err)
+	assert.Equal(t, `CREATE TABLE "users" (
+	"id" INTEGER PRIMARY KEY AUTOINCREMENT,
+	"email" TEXT NOT NULL UNIQUE,
+	"name" TEXT,
+	"status" TEXT DEFAULT 'active',
+	"created_at" DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX "users_name" ON "users" ("name");
+`, usersDDL)
+
+	membershipsDDL, err := schema.GenerateDDL("memberships", &membership{})
+	require.NoError(t, err)
+
+	s, err := schema.Parse(usersDDL + membershipsDDL)
+	require.NoError(t, err)
+	memberships := s.Table("memberships")
+	require.NotNil(t, memberships)
+	require.Len(t, memberships.Columns, 4)
+	assert.Equal(t, 1, memberships.Column("user_id").PrimaryKey)
+	assert.Equal(t, 2, memberships.Column("org_id").PrimaryKey)
+	assert.Equal(t, "VARCHAR(32)", memberships.Column("role").Type)
+	assert.Equal(t, "BLOB", memberships.Column("avatar").Type)
+	assert.Contains(t, memberships.SQL, `REFERENCES users(id) ON DELETE CASCADE`)
+	idx := memberships.Index("memberships_user_org")
+	require.NotNil(t, idx)
+	assert.True(t, idx.Unique)
+	assert.Equal(t, []string{"user_id", "org_id"}, idx.Columns)
+
+	_, err = schema.GenerateDDL("bad", struct {
+		Tags []string
+	}{})
+	assert.Error(t, err)
+	_, err = schema.GenerateDDL("bad", struct {
+		ID string `db:"id,pk,autoincrement"`
+	}{})
+	assert.Error(t, err)
+}
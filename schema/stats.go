@@ -0,0 +1,231 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// HistoryTable records the row counts of TableStats runs with History set.
+const HistoryTable = "_table_stats"
+
+// DefaultCountLimit is the number of rows TableStats counts per table
+// unless TableStatsOptions.CountLimit is set.
+const DefaultCountLimit = 1_000_000
+
+// TableStat is the size of one table at one time.
+type TableStat struct {
+	Table string `json:"table"`
+	// Rows is the number of rows. It is a lower bound or an estimate when
+	// Exact is false.
+	Rows  int64 `json:"rows"`
+	Exact bool  `json:"exact"`
+	// MaxRowID is the largest rowid, which grows with every insert, or 0
+	// for WITHOUT ROWID tables.
+	MaxRowID int64 `json:"max_rowid"`
+	// ChangedAt, when History is recorded, is the first recording that saw
+	// the current Rows and MaxRowID after a different one: the table last
+	// grew or shrank between the recording before it and ChangedAt. Updates
+	// in place are not seen. It is zero when no change has been recorded.
+	ChangedAt time.Time `json:"changed_at,omitempty"`
+	// RecordedAt is when the stat was taken.
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// TableStatsOptions configures TableStats.
+type TableStatsOptions struct {
+	// CountLimit bounds the rows counted per table, so large tables do not
+	// stall the call. Larger tables report the row count ANALYZE stored in
+	// sqlite_stat1 when it is higher, and CountLimit otherwise. Defaults to
+	// DefaultCountLimit.
+	CountLimit int64
+	// History appends the stats to HistoryTable, creating it if needed, so
+	// growth can be graphed with TableHistory.
+	History bool
+}
+
+// TableStats returns the row counts of the tables of the database behind
+// the global pool, leaving out internal sqlite_ tables, virtual tables and
+// HistoryTable. A nil opts uses the defaults without recording history.
+func TableStats(ctx context.Context, opts *TableStatsOptions) ([]TableStat, error) {
+	if opts == nil {
+		opts = &TableStatsOptions{}
+	}
+	limit := opts.CountLimit
+	if limit <= 0 {
+		limit = DefaultCountLimit
+	}
+	var stats []TableStat
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		conn.SetInterrupt(ctx.Done())
+		defer conn.SetInterrupt(nil)
+
+		estimates, err := stat1Rows(conn)
+		if err != nil {
+			return err
+		}
+		now := time.Now()
+		err = sqlitex.Execute(conn, "SELECT name, wr FROM pragma_table_list WHERE schema = 'main' AND type = 'table' AND name NOT LIKE 'sqlite\\_%' ESCAPE '\\' AND name != ? ORDER BY name;", &sqlitex.ExecOptions{
+			Args: []interface{}{HistoryTable},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				stats = append(stats, TableStat{Table: stmt.ColumnText(0), MaxRowID: -stmt.ColumnInt64(1), RecordedAt: now})
+				return nil
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to list tables: %w", err)
+		}
+		for i := range stats {
+			if err := countRows(conn, &stats[i], limit, estimates[stats[i].Table]); err != nil {
+				return err
+			}
+		}
+		if opts.History {
+			return recordHistory(conn, stats)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// stat1Rows returns the row count ANALYZE recorded for each table: the
+// first number of its sqlite_stat1 rows.
+func stat1Rows(conn *sqlite.Conn) (map[string]int64, error) {
+	rows := map[string]int64{}
+	exists, err := sqlitex.ResultBool(conn.Prep("SELECT count(*) > 0 FROM sqlite_schema WHERE name = 'sqlite_stat1';"))
+	if err != nil || !exists {
+		return rows, err
+	}
+	err = sqlitex.ExecuteTransient(conn, "SELECT tbl, stat FROM sqlite_stat1;", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			first, _, _ := strings.Cut(stmt.ColumnText(1), " ")
+			if n, err := strconv.ParseInt(first, 10, 64); err == nil && n > rows[stmt.ColumnText(0)] {
+				rows[stmt.ColumnText(0)] = n
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sqlite_stat1: %w", err)
+	}
+	return rows, nil
+}
+
+// countRows fills in the row count and largest rowid of stat, whose
+// MaxRowID is -1 on entry for WITHOUT ROWID tables.
+func countRows(conn *sqlite.Conn, stat *TableStat, limit, estimate int64) error {
+	table := sqliteutils.QuoteIdentifier(stat.Table)
+	n, err := sqlitex.ResultInt64(conn.Prep(fmt.Sprintf("SELECT count(*) FROM (SELECT 1 FROM %s LIMIT %d);", table, limit+1)))
+	if err != nil {
+		return fmt.Errorf("failed to count rows of %s: %w", stat.Table, err)
+	}
+	stat.Rows, stat.Exact = n, n <= limit
+	if !stat.Exact {
+		stat.Rows = max(limit, estimate)
+	}
+	if stat.MaxRowID < 0 {
+		stat.MaxRowID = 0
+		return nil
+	}
+	err = sqlitex.ExecuteTransient(conn, "SELECT max(_rowid_) FROM "+table+";", &sqlitex.ExecOptions{
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			stat.MaxRowID = stmt.ColumnInt64(0)
+			return nil
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read the largest rowid of %s: %w", stat.Table, err)
+	}
+	return nil
+}
+
+// createHistory creates HistoryTable if it does not exist.
+func createHistory(conn *sqlite.Conn) error {
+	err := sqlitex.ExecuteTransient(conn, `CREATE TABLE IF NOT EXISTS `+HistoryTable+` (
+		table_name TEXT NOT NULL,
+		recorded_at INTEGER NOT NULL,
+		rows INTEGER NOT NULL,
+		exact INTEGER NOT NULL,
+		max_rowid INTEGER NOT NULL,
+		PRIMARY KEY (table_name, recorded_at)
+	);`, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", HistoryTable, err)
+	}
+	return nil
+}
+
+// recordHistory appends stats to HistoryTable and sets their ChangedAt.
+func recordHistory(conn *sqlite.Conn, stats []TableStat) (err error) {
+	if err := createHistory(conn); err != nil {
+		return err
+	}
+	defer sqlitex.Save(conn)(&err)
+	for i := range stats {
+		s := &stats[i]
+		err := sqlitex.Execute(conn, "INSERT OR REPLACE INTO "+HistoryTable+" (table_name, recorded_at, rows, exact, max_rowid) VALUES (?, ?, ?, ?, ?);", &sqlitex.ExecOptions{
+			Args: []interface{}{s.Table, s.RecordedAt.UnixMilli(), s.Rows, s.Exact, s.MaxRowID},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to record stats of %s: %w", s.Table, err)
+		}
+		err = sqlitex.Execute(conn, `SELECT min(recorded_at) FROM `+HistoryTable+`
+			WHERE table_name = ?1 AND recorded_at > (
+				SELECT max(recorded_at) FROM `+HistoryTable+`
+				WHERE table_name = ?1 AND (rows != ?2 OR max_rowid != ?3)
+			);`, &sqlitex.ExecOptions{
+			Args: []interface{}{s.Table, s.Rows, s.MaxRowID},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				if stmt.ColumnType(0) != sqlite.TypeNull {
+					s.ChangedAt = time.UnixMilli(stmt.ColumnInt64(0))
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read history of %s: %w", s.Table, err)
+		}
+	}
+	return nil
+}
+
+// TableHistory returns the stats of table recorded in HistoryTable since
+// since, oldest first, for graphing its growth. ChangedAt is not set. It
+// returns no stats if no history was recorded yet, without writing, so it
+// works on read-only pools.
+func TableHistory(ctx context.Context, table string, since time.Time) ([]TableStat, error) {
+	var history []TableStat
+	err := pool.WithConn(ctx, func(conn *sqlite.Conn) error {
+		exists, err := sqlitex.ResultBool(conn.Prep("SELECT count(*) > 0 FROM sqlite_schema WHERE name = '" + HistoryTable + "';"))
+		if err != nil || !exists {
+			return err
+		}
+		return sqlitex.Execute(conn, "SELECT recorded_at, rows, exact, max_rowid FROM "+HistoryTable+" WHERE table_name = ? AND recorded_at >= ? ORDER BY recorded_at;", &sqlitex.ExecOptions{
+			Args: []interface{}{table, since.UnixMilli()},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				history = append(history, TableStat{
+					Table:      table,
+					RecordedAt: time.UnixMilli(stmt.ColumnInt64(0)),
+					Rows:       stmt.ColumnInt64(1),
+					Exact:      stmt.ColumnBool(2),
+					MaxRowID:   stmt.ColumnInt64(3),
+				})
+				return nil
+			},
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read history of %s: %w", table, err)
+	}
+	return history, nil
+}
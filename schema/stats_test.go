@@ -0,0 +1,118 @@
+package schema_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/schema"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+func TestTableStats(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, `
+		CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT);
+		CREATE TABLE tags (name TEXT PRIMARY KEY) WITHOUT ROWID;
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 50)
+		INSERT INTO events (name) SELECT 'e' || i FROM n;
+		INSERT INTO tags VALUES ('a'), ('b');
+	`, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	stats, err := schema.TableStats(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, "events", stats[0].Table)
+	assert.Equal(t, int64(50), stats[0].Rows)
+	assert.True(t, stats[0].Exact)
+	assert.Equal(t, int64(50), stats[0].MaxRowID)
+	assert.Equal(t, "tags", stats[1].Table)
+	assert.Equal(t, int64(2), stats[1].Rows)
+	assert.Equal(t, int64(0), stats[1].MaxRowID)
+
+	stats, err = schema.TableStats(ctx, &schema.TableStatsOptions{CountLimit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), stats[0].Rows)
+	assert.False(t, stats[0].Exact)
+
+	exec := func(query string) {
+		p, err := pool.GetPool()
+		require.NoError(t, err)
+		conn, err := p.Take(ctx)
+		require.NoError(t, err)
+		defer p.Put(conn)
+		require.NoError(t, sqlitex.ExecuteTransient(conn, query, nil))
+	}
+	exec("ANALYZE;")
+	stats, err = schema.TableStats(ctx, &schema.TableStatsOptions{CountLimit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, int64(50), stats[0].Rows)
+	assert.False(t, stats[0].Exact)
+}
+
+func TestTableStats_History(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, `
+		CREATE TABLE events (id INTEGER PRIMARY KEY, name TEXT);
+		INSERT INTO events (name) VALUES ('a');
+	`, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	insert := func() {
+		p, err := pool.GetPool()
+		require.NoError(t, err)
+		conn, err := p.Take(ctx)
+		require.NoError(t, err)
+		defer p.Put(conn)
+		require.NoError(t, sqlitex.ExecuteTransient(conn, "INSERT INTO events (name) VALUES ('b');", nil))
+	}
+	record := func() schema.TableStat {
+		time.Sleep(2 * time.Millisecond)
+		stats, err := schema.TableStats(ctx, &schema.TableStatsOptions{History: true})
+		require.NoError(t, err)
+		require.Len(t, stats, 1)
+		return stats[0]
+	}
+
+	// Reading history before any was recorded does not create the table,
+	// so it works on read-only connections.
+	p, err := pool.GetPool()
+	require.NoError(t, err)
+	conn, err := p.Take(ctx)
+	require.NoError(t, err)
+	require.NoError(t, sqlitex.ExecuteTransient(conn, "PRAGMA query_only = ON;", nil))
+	p.Put(conn)
+	history, err := schema.TableHistory(ctx, "events", time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, history)
+	conn, err = p.Take(ctx)
+	require.NoError(t, err)
+	require.NoError(t, sqlitex.ExecuteTransient(conn, "PRAGMA query_only = OFF;", nil))
+	p.Put(conn)
+
+	first := record()
+	assert.True(t, first.ChangedAt.IsZero())
+	insert()
+	grown := record()
+	assert.Equal(t, grown.RecordedAt.UnixMilli(), grown.ChangedAt.UnixMilli())
+	same := record()
+	assert.Equal(t, grown.RecordedAt.UnixMilli(), same.ChangedAt.UnixMilli())
+
+	history, err = schema.TableHistory(ctx, "events", first.RecordedAt)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, []int64{1, 2, 2}, []int64{history[0].Rows, history[1].Rows, history[2].Rows})
+
+	history, err = schema.TableHistory(ctx, "events", grown.RecordedAt)
+	require.NoError(t, err)
+	assert.Len(t, history, 2)
+}
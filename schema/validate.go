@@ -0,0 +1,51 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+)
+
+// Validate checks that the database behind the global pool has every table,
+// column and index in expected, with matching definitions. Tables, columns and
+// indexes that exist only in the database are allowed. On mismatch it returns
+// an error wrapping sqliteutils.ErrSchemaMismatch that lists every problem.
+func Validate(ctx context.Context, expected *Schema) error {
+	p, err := pool.GetPool()
+	if err != nil {
+		return sqliteutils.FailedToGetPoolError(err)
+	}
+	diffs, err := diffPool(ctx, p, expected)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for _, d := range diffs {
+		switch d.Kind {
+		case ExtraTable, ExtraColumn, ExtraIndex:
+			continue
+		}
+		problem := d.String()
+		if d.Current != "" {
+			problem += fmt.Sprintf(" (have %s, want %s)", d.Current, d.Desired)
+		}
+		problems = append(problems, problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", sqliteutils.ErrSchemaMismatch, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ValidateDDL is Validate with the expected schema given as DDL.
+func ValidateDDL(ctx context.Context, ddl string) error {
+	expected, err := Parse(ddl)
+	if err != nil {
+		return err
+	}
+	return Validate(ctx, expected)
+}
@@ -0,0 +1,177 @@
+package scope
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrBypass is returned for statements that would read or write a scoped
+// table without going through the guard.
+var ErrBypass = errors.New("statement bypasses the scope guard")
+
+// token is a word, quoted identifier, literal or punctuation character of a
+// statement, with comments and whitespace removed.
+type token struct {
+	text   string
+	start  int
+	ident  bool // bare word or quoted identifier
+	quoted bool
+}
+
+// value returns the identifier a token names, without quotes.
+func (t token) value() string {
+	if !t.quoted {
+		return t.text
+	}
+	inner := t.text[1 : len(t.text)-1]
+	switch t.text[0] {
+	case '"':
+		return strings.ReplaceAll(inner, `""`, `"`)
+	case '`':
+		return strings.ReplaceAll(inner, "``", "`")
+	}
+	return inner
+}
+
+// keyword reports whether t is the bare word kw, ignoring case.
+func (t token) keyword(kw string) bool {
+	return t.ident && !t.quoted && strings.EqualFold(t.text, kw)
+}
+
+// tokenize splits query into tokens.
+func tokenize(query string) []token {
+	var tokens []token
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+		case c == '-' && i+1 < len(query) && query[i+1] == '-':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				i = len(query)
+			} else {
+				i += end + 4
+			}
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			j := i + 1
+			for j < len(query) {
+				if query[j] == closing {
+					// A doubled quote is an escaped quote, except in brackets.
+					if closing != ']' && j+1 < len(query) && query[j+1] == closing {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j < len(query) {
+				j++
+			}
+			tokens = append(tokens, token{text: query[i:j], start: i, ident: c != '\'', quoted: true})
+			i = j
+		case isWordChar(c):
+			j := i
+			for j < len(query) && isWordChar(query[j]) {
+				j++
+			}
+			tokens = append(tokens, token{text: query[i:j], start: i, ident: c < '0' || c > '9'})
+			i = j
+		default:
+			tokens = append(tokens, token{text: query[i : i+1], start: i})
+			i++
+		}
+	}
+	return tokens
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// Rewrite returns query with the target of each INSERT, UPDATE and DELETE
+// on a registered table qualified as main.<table>, so the write reaches the
+// table, where the guard's triggers check it, rather than the read-only view
+// shadowing it. It returns an error wrapping ErrBypass if query names a
+// registered table as main.<table> itself or replaces rows of one, since
+// REPLACE deletes conflicting rows without firing the guard's triggers.
+//
+// Exec and Query rewrite their statements; callers of WithConn must rewrite
+// theirs.
+func (g *Guard) Rewrite(query string) (string, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	tokens := tokenize(query)
+
+	var targets []int
+	for i, t := range tokens {
+		if t.ident && strings.EqualFold(t.value(), "main") && i+2 < len(tokens) && tokens[i+1].text == "." && g.registered(tokens[i+2]) {
+			return "", fmt.Errorf("%w: %s is read through its scoped view", ErrBypass, tokens[i+2].value())
+		}
+		target := -1
+		switch {
+		case t.keyword("INTO"), t.keyword("FROM") && i > 0 && tokens[i-1].keyword("DELETE"):
+			target = i + 1
+		case t.keyword("UPDATE"):
+			target = i + 1
+			if target+1 < len(tokens) && tokens[target].keyword("OR") {
+				target += 2
+			}
+		}
+		if target < 0 || target >= len(tokens) || !g.registered(tokens[target]) {
+			continue
+		}
+		if target+1 < len(tokens) && tokens[target+1].text == "." {
+			// Already qualified with a schema other than main.
+			continue
+		}
+		if replaces(tokens[:target]) {
+			return "", fmt.Errorf("%w: REPLACE on scoped table %s", ErrBypass, tokens[target].value())
+		}
+		targets = append(targets, tokens[target].start)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, start := range targets {
+		b.WriteString(query[last:start])
+		b.WriteString("main.")
+		last = start
+	}
+	b.WriteString(query[last:])
+	return b.String(), nil
+}
+
+// registered reports whether t names a registered table.
+// Assumes that the caller holds g.mu.
+func (g *Guard) registered(t token) bool {
+	if !t.ident {
+		return false
+	}
+	for table := range g.tables {
+		if strings.EqualFold(table, t.value()) {
+			return true
+		}
+	}
+	return false
+}
+
+// replaces reports whether the tokens before a write target end in
+// REPLACE INTO, INSERT OR REPLACE INTO or UPDATE OR REPLACE.
+func replaces(before []token) bool {
+	n := len(before)
+	if n > 0 && before[n-1].keyword("INTO") {
+		n--
+	}
+	return n > 0 && before[n-1].keyword("REPLACE")
+}
@@ -0,0 +1,239 @@
+// Package scope confines statements on multi-tenant tables to the rows of
+// one tenant or owner, as defense in depth against queries that forget to
+// filter by it.
+//
+// A Guard knows which tables carry a scope column. For the duration of a
+// guarded call it shadows each of them with a temporary view of the same
+// name that only shows the rows whose scope column equals the scope value
+// taken from the context, so unqualified references in any statement,
+// including joins and subqueries, are filtered without rewriting SQL text.
+// Writes are rewritten to target main.<table>, where temporary triggers make
+// updates and deletes skip rows outside the scope and reject inserts and
+// updates that would put a row outside it. Statements that bypass the view,
+// such as reads of main.<table>, are rejected.
+//
+// Inserts must set the scope column. REPLACE is not supported on scoped
+// tables, since the rows it deletes do not fire triggers.
+package scope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// ErrNoScope is returned by guarded calls whose context has no scope value.
+var ErrNoScope = errors.New("no scope value in context")
+
+type scopeKey struct{}
+
+// WithValue returns a context whose guarded calls are confined to rows
+// whose scope column equals value, an integer or a string.
+func WithValue(ctx context.Context, value interface{}) context.Context {
+	return context.WithValue(ctx, scopeKey{}, value)
+}
+
+// values holds the scope value of each connection during a guarded call.
+var values sync.Map // *sqlite.Conn -> sqlite.Value
+
+// PrepareConn registers the scope_value() SQL function used by the guard's
+// views and triggers on conn. Pass it to pool.WithPrepareConn.
+func PrepareConn(conn *sqlite.Conn) error {
+	return conn.CreateFunction("scope_value", &sqlite.FunctionImpl{
+		NArgs:         0,
+		AllowIndirect: true,
+		Scalar: func(ctx sqlite.Context, args []sqlite.Value) (sqlite.Value, error) {
+			if v, ok := values.Load(conn); ok {
+				return v.(sqlite.Value), nil
+			}
+			return sqlite.Value{}, nil
+		},
+	})
+}
+
+// Guard confines statements to the scope of their context.
+type Guard struct {
+	mu     sync.Mutex
+	tables map[string]string
+}
+
+// New returns a Guard with no tables registered.
+func New() *Guard {
+	return &Guard{tables: make(map[string]string)}
+}
+
+// Register confines table to the rows whose column equals the scope value.
+func (g *Guard) Register(table, column string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.tables[table] = column
+}
+
+// WithConn runs fn with a connection from the global pool on which the
+// registered tables only show the rows in the scope of ctx. Statements run
+// on conn are not rewritten: pass them through Rewrite first.
+func (g *Guard) WithConn(ctx context.Context, fn func(conn *sqlite.Conn) error) (err error) {
+	value, err := scopeValue(ctx)
+	if err != nil {
+		return err
+	}
+	p, err := pool.GetPool()
+	if err != nil {
+		return sqliteutils.FailedToGetPoolError(err)
+	}
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+
+	g.mu.Lock()
+	tables := make(map[string]string, len(g.tables))
+	for table, column := range g.tables {
+		tables[table] = column
+	}
+	g.mu.Unlock()
+
+	names := make([]string, 0, len(tables))
+	for table := range tables {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	// Remove the shadowing objects even if installing them failed halfway,
+	// so other users of the connection see the real tables.
+	defer func() {
+		if dropErr := drop(conn, names); dropErr != nil {
+			err = errors.Join(err, dropErr)
+		}
+	}()
+	for _, table := range names {
+		if err := install(conn, table, tables[table]); err != nil {
+			return err
+		}
+	}
+
+	values.Store(conn, value)
+	defer values.Delete(conn)
+	err = conn.SetAuthorizer(sqlite.AuthorizeFunc(func(action sqlite.Action) sqlite.AuthResult {
+		switch action.Type() {
+		case sqlite.OpCreateTempView, sqlite.OpCreateTempTrigger, sqlite.OpDropTempView, sqlite.OpDropTempTrigger:
+			// The guard's objects must stay in place for the call.
+			return sqlite.AuthResultDeny
+		case sqlite.OpCreateView, sqlite.OpCreateTrigger, sqlite.OpAttach:
+			// Views, triggers and attached copies would read the tables unscoped.
+			return sqlite.AuthResultDeny
+		}
+		return sqlite.AuthResultOK
+	}))
+	if err != nil {
+		return fmt.Errorf("failed to set authorizer: %w", err)
+	}
+	defer conn.SetAuthorizer(nil)
+	return fn(conn)
+}
+
+// Exec is exec.Exec confined to the scope of ctx.
+func (g *Guard) Exec(ctx context.Context, query string, params map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	query, err := g.Rewrite(query)
+	if err != nil {
+		return err
+	}
+	return g.WithConn(ctx, func(conn *sqlite.Conn) error {
+		return exec.ExecConn(conn, query, params, resultFunc)
+	})
+}
+
+// Query is exec.Query confined to the scope of ctx.
+func (g *Guard) Query(ctx context.Context, query string, params map[string]interface{}, rowFunc func(columns []string, values []interface{})) error {
+	query, err := g.Rewrite(query)
+	if err != nil {
+		return err
+	}
+	return g.WithConn(ctx, func(conn *sqlite.Conn) error {
+		return exec.QueryConn(conn, query, params, rowFunc)
+	})
+}
+
+func scopeValue(ctx context.Context) (sqlite.Value, error) {
+	switch v := ctx.Value(scopeKey{}).(type) {
+	case nil:
+		return sqlite.Value{}, ErrNoScope
+	case string:
+		return sqlite.TextValue(v), nil
+	case int:
+		return sqlite.IntegerValue(int64(v)), nil
+	case int64:
+		return sqlite.IntegerValue(v), nil
+	default:
+		return sqlite.Value{}, fmt.Errorf("unsupported scope value type %T: use an integer or string", v)
+	}
+}
+
+var triggerOps = []string{"insert", "update", "delete"}
+
+func triggerName(table, op string) string {
+	return "_scope_" + table + "_" + op
+}
+
+// install creates the temporary view shadowing table on conn and the
+// temporary triggers checking writes to it.
+func install(conn *sqlite.Conn, table, scopeColumn string) error {
+	found := false
+	err := sqlitex.Execute(conn, "SELECT 1 FROM pragma_table_info(?, 'main') WHERE name = ?;", &sqlitex.ExecOptions{
+		Args: []interface{}{table, scopeColumn},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			found = true
+			return nil
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read columns of %s: %w", table, err)
+	}
+	if !found {
+		return fmt.Errorf("scoped table %s has no column %s", table, scopeColumn)
+	}
+
+	q := sqliteutils.QuoteIdentifier
+	script := fmt.Sprintf(`CREATE TEMP VIEW %[1]s AS SELECT * FROM main.%[1]s WHERE %[2]s = scope_value();
+CREATE TEMP TRIGGER %[3]s BEFORE INSERT ON main.%[1]s BEGIN
+	SELECT RAISE(ABORT, 'row is outside the current scope') WHERE NEW.%[2]s IS NOT scope_value();
+END;
+CREATE TEMP TRIGGER %[4]s BEFORE UPDATE ON main.%[1]s BEGIN
+	SELECT RAISE(IGNORE) WHERE OLD.%[2]s IS NOT scope_value();
+	SELECT RAISE(ABORT, 'row is outside the current scope') WHERE NEW.%[2]s IS NOT scope_value();
+END;
+CREATE TEMP TRIGGER %[5]s BEFORE DELETE ON main.%[1]s BEGIN
+	SELECT RAISE(IGNORE) WHERE OLD.%[2]s IS NOT scope_value();
+END;`,
+		q(table), q(scopeColumn),
+		q(triggerName(table, "insert")), q(triggerName(table, "update")), q(triggerName(table, "delete")))
+	if err := sqlitex.ExecuteScript(conn, script, nil); err != nil {
+		return fmt.Errorf("failed to scope %s: %w", table, err)
+	}
+	return nil
+}
+
+// drop removes the temporary objects the guard created for tables from conn.
+func drop(conn *sqlite.Conn, tables []string) error {
+	var b strings.Builder
+	for _, table := range tables {
+		fmt.Fprintf(&b, "DROP VIEW IF EXISTS temp.%s;\n", sqliteutils.QuoteIdentifier(table))
+		for _, op := range triggerOps {
+			fmt.Fprintf(&b, "DROP TRIGGER IF EXISTS temp.%s;\n", sqliteutils.QuoteIdentifier(triggerName(table, op)))
+		}
+	}
+	if err := sqlitex.ExecuteScript(conn, b.String(), nil); err != nil {
+		return fmt.Errorf("failed to drop scope views: %w", err)
+	}
+	return nil
+}
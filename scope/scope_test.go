@@ -0,0 +1,93 @@
+package scope_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/scope"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuard(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool("file::memory:?mode=memory&cache=shared", 1, pool.WithPrepareConn(scope.PrepareConn)))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.ExecMulti(ctx, []string{
+		`CREATE TABLE projects (id INTEGER PRIMARY KEY, tenant_id TEXT NOT NULL, name TEXT, status TEXT NOT NULL DEFAULT 'open');`,
+		`CREATE TABLE tasks (id INTEGER PRIMARY KEY, tenant_id TEXT NOT NULL, project_id INTEGER REFERENCES projects (id), title TEXT);`,
+		`INSERT INTO projects (id, tenant_id, name) VALUES (1, 'acme', 'rocket'), (2, 'globex', 'dome');`,
+		`INSERT INTO tasks (tenant_id, project_id, title) VALUES ('acme', 1, 'fuel'), ('globex', 2, 'paint');`,
+	}, make([]map[string]interface{}, 4), nil))
+
+	g := scope.New()
+	g.Register("projects", "tenant_id")
+	g.Register("tasks", "tenant_id")
+	acme := scope.WithValue(ctx, "acme")
+
+	rows := func(ctx context.Context, query string) [][]interface{} {
+		var out [][]interface{}
+		require.NoError(t, g.Query(ctx, query, nil, func(_ []string, values []interface{}) { out = append(out, values) }))
+		return out
+	}
+
+	// Reads, joins and subqueries only see the scope's rows.
+	assert.Equal(t, [][]interface{}{{"rocket", "fuel"}}, rows(acme,
+		"SELECT p.name, t.title FROM projects p JOIN tasks t ON t.project_id = p.id ORDER BY p.id;"))
+	assert.Equal(t, [][]interface{}{{int64(1)}}, rows(acme, "SELECT count(*) FROM tasks WHERE project_id IN (SELECT id FROM projects);"))
+
+	// Inserts must stay in scope.
+	require.NoError(t, g.Exec(acme, "INSERT INTO projects (id, tenant_id, name) VALUES (3, 'acme', 'sled');", nil, nil))
+	assert.Error(t, g.Exec(acme, "INSERT INTO projects (id, tenant_id, name) VALUES (4, 'globex', 'spy');", nil, nil))
+	assert.Error(t, g.Exec(acme, "INSERT INTO projects (id, name) VALUES (4, 'spy');", nil, nil))
+
+	// Updates and deletes without a predicate only touch the scope's rows.
+	require.NoError(t, g.Exec(acme, "UPDATE projects SET status = 'done';", nil, nil))
+	assert.Error(t, g.Exec(acme, "UPDATE projects SET tenant_id = 'globex' WHERE id = 1;", nil, nil))
+	require.NoError(t, g.Exec(acme, "DELETE FROM tasks;", nil, nil))
+
+	// Bypassing the view is rejected.
+	assert.ErrorIs(t, g.Exec(acme, "SELECT * FROM main.projects;", nil, nil), scope.ErrBypass)
+	assert.ErrorIs(t, g.Exec(acme, `REPLACE INTO "projects" (id, tenant_id) VALUES (2, 'acme');`, nil, nil), scope.ErrBypass)
+	assert.Error(t, g.Exec(acme, "DROP VIEW temp.projects;", nil, nil))
+	assert.ErrorIs(t, g.Exec(ctx, "SELECT 1;", nil, nil), scope.ErrNoScope)
+
+	// Outside the guard, the real tables are unchanged for other scopes.
+	var all []map[string]interface{}
+	require.NoError(t, exec.Exec(ctx, "SELECT id, tenant_id, status FROM projects ORDER BY id;", nil,
+		func(_ int, row map[string]interface{}) { all = append(all, row) }))
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(1), "tenant_id": "acme", "status": "done"},
+		{"id": int64(2), "tenant_id": "globex", "status": "open"},
+		{"id": int64(3), "tenant_id": "acme", "status": "done"},
+	}, all)
+	var tasks int64
+	require.NoError(t, exec.Query(ctx, "SELECT count(*) FROM tasks;", nil, func(_ []string, values []interface{}) { tasks = values[0].(int64) }))
+	assert.Equal(t, int64(1), tasks)
+}
+
+func TestGuard_Rewrite(t *testing.T) {
+	g := scope.New()
+	g.Register("projects", "tenant_id")
+
+	for query, want := range map[string]string{
+		"SELECT * FROM projects;":                                               "SELECT * FROM projects;",
+		"INSERT INTO projects (name) SELECT name FROM projects":                 "INSERT INTO main.projects (name) SELECT name FROM projects",
+		"UPDATE OR IGNORE [projects] SET name = 'x'":                            "UPDATE OR IGNORE main.[projects] SET name = 'x'",
+		"DELETE /* all */ FROM Projects WHERE name = 'UPDATE projects'":         "DELETE /* all */ FROM main.Projects WHERE name = 'UPDATE projects'",
+		"INSERT INTO projects (id) VALUES (1) ON CONFLICT DO UPDATE SET id = 2": "INSERT INTO main.projects (id) VALUES (1) ON CONFLICT DO UPDATE SET id = 2",
+		"DELETE FROM tasks": "DELETE FROM tasks",
+	} {
+		got, err := g.Rewrite(query)
+		require.NoError(t, err, query)
+		assert.Equal(t, want, got)
+	}
+	_, err := g.Rewrite(`SELECT * FROM "main"."projects"`)
+	assert.ErrorIs(t, err, scope.ErrBypass)
+	_, err = g.Rewrite("UPDATE OR REPLACE projects SET id = 1")
+	assert.ErrorIs(t, err, scope.ErrBypass)
+}
@@ -0,0 +1,338 @@
+// Package shard partitions rows across a fixed number of SQLite files by
+// the hash of a key.
+//
+// A Router owns one pool per shard. Statements for a single key are routed
+// to the shard that key hashes to; QueryAll fans a query out to every shard
+// concurrently and merges the rows, optionally re-sorting and limiting them.
+// The shard of a key depends on the number of shards, so changing it
+// requires moving rows between files.
+package shard
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/migrate"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Options configures a Router.
+type Options struct {
+	// Dir holds the shard files, named shard-000.db, shard-001.db and so on.
+	Dir string
+	// Shards is the number of shards. It must not change once rows are stored.
+	Shards int
+	// PoolSize is the number of connections per shard. Defaults to 4.
+	PoolSize int
+	// BusyTimeout is how long connections wait for locks. Defaults to 5s.
+	BusyTimeout time.Duration
+	// PoolOptions configure every shard pool, as for pool.InitPool.
+	PoolOptions []pool.Option
+}
+
+// OrderBy sorts the merged rows of QueryAll by a result column.
+type OrderBy struct {
+	Column string
+	Desc   bool
+}
+
+// FanOutOptions configures QueryAll.
+type FanOutOptions struct {
+	// OrderBy sorts the merged rows, comparing values as SQLite does. Without
+	// it, rows are in shard order.
+	OrderBy []OrderBy
+	// Limit bounds the number of merged rows if positive. Each shard's query
+	// should apply the same ORDER BY and LIMIT so it returns no more rows
+	// than needed.
+	Limit int
+}
+
+// Result holds the merged rows of QueryAll.
+type Result struct {
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// Router routes statements to shard pools. It is safe for concurrent use.
+type Router struct {
+	pools []*sqlitex.Pool
+}
+
+// Open opens the shards in opts.Dir, creating the directory and files as
+// needed.
+func Open(opts Options) (*Router, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("shard directory is required")
+	}
+	if opts.Shards <= 0 {
+		return nil, fmt.Errorf("invalid number of shards %d", opts.Shards)
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 4
+	}
+	if opts.BusyTimeout <= 0 {
+		opts.BusyTimeout = 5 * time.Second
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create shard directory: %w", err)
+	}
+
+	r := &Router{pools: make([]*sqlitex.Pool, opts.Shards)}
+	for i := range r.pools {
+		uri := sqliteutils.URI{Path: filepath.Join(opts.Dir, fmt.Sprintf("shard-%03d.db", i)), BusyTimeout: opts.BusyTimeout}
+		p, err := pool.Open(uri.String(), opts.PoolSize, opts.PoolOptions...)
+		if err != nil {
+			r.Close()
+			return nil, fmt.Errorf("failed to open shard %d: %w", i, err)
+		}
+		r.pools[i] = p
+	}
+	return r, nil
+}
+
+// Shards returns the number of shards.
+func (r *Router) Shards() int {
+	return len(r.pools)
+}
+
+// ShardFor returns the shard key is stored in, from its 64-bit FNV-1a hash.
+func (r *Router) ShardFor(key string) int {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int(h.Sum64() % uint64(len(r.pools)))
+}
+
+// WithConn runs fn with a connection to the shard of key.
+func (r *Router) WithConn(ctx context.Context, key string, fn func(conn *sqlite.Conn) error) error {
+	return r.withShard(ctx, r.ShardFor(key), fn)
+}
+
+// Exec is exec.Exec on the shard of key.
+func (r *Router) Exec(ctx context.Context, key, query string, params map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	return r.WithConn(ctx, key, func(conn *sqlite.Conn) error {
+		return exec.ExecConn(conn, query, params, resultFunc)
+	})
+}
+
+// Query is exec.Query on the shard of key.
+func (r *Router) Query(ctx context.Context, key, query string, params map[string]interface{}, rowFunc func(columns []string, values []interface{})) error {
+	return r.WithConn(ctx, key, func(conn *sqlite.Conn) error {
+		return exec.QueryConn(conn, query, params, rowFunc)
+	})
+}
+
+// ExecAll runs query on every shard concurrently, for schema changes and
+// maintenance. It returns the errors of the shards that failed.
+func (r *Router) ExecAll(ctx context.Context, query string, params map[string]interface{}) error {
+	return r.fanOut(ctx, func(shard int, conn *sqlite.Conn) error {
+		return exec.ExecConn(conn, query, params, nil)
+	})
+}
+
+// Migrate applies the migrations registered with the migrate package to
+// every shard.
+func (r *Router) Migrate(ctx context.Context) error {
+	return r.fanOut(ctx, func(shard int, conn *sqlite.Conn) error {
+		_, err := migrate.UpConn(ctx, conn)
+		return err
+	})
+}
+
+// QueryAll runs query on every shard concurrently and merges the rows.
+// Every shard must return the same columns.
+func (r *Router) QueryAll(ctx context.Context, query string, params map[string]interface{}, opts FanOutOptions) (*Result, error) {
+	results := make([]Result, len(r.pools))
+	err := r.fanOut(ctx, func(shard int, conn *sqlite.Conn) error {
+		return exec.QueryConn(conn, query, params, func(columns []string, values []interface{}) {
+			results[shard].Columns = columns
+			results[shard].Rows = append(results[shard].Rows, values)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	merged := &Result{}
+	for _, result := range results {
+		if merged.Columns == nil {
+			merged.Columns = result.Columns
+		}
+		merged.Rows = append(merged.Rows, result.Rows...)
+	}
+	if len(opts.OrderBy) > 0 {
+		if err := sortRows(merged, opts.OrderBy); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Limit > 0 && len(merged.Rows) > opts.Limit {
+		merged.Rows = merged.Rows[:opts.Limit]
+	}
+	return merged, nil
+}
+
+// Close closes every shard pool.
+func (r *Router) Close() error {
+	var errs []error
+	for _, p := range r.pools {
+		if p == nil {
+			continue
+		}
+		if err := pool.Close(p); err != nil {
+			errs = append(errs, sqliteutils.FailedToClosePoolError(err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (r *Router) withShard(ctx context.Context, shard int, fn func(conn *sqlite.Conn) error) error {
+	p := r.pools[shard]
+	conn, err := pool.Take(ctx, p)
+	if err != nil {
+		return sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+	return fn(conn)
+}
+
+// fanOut runs fn on a connection to every shard concurrently.
+func (r *Router) fanOut(ctx context.Context, fn func(shard int, conn *sqlite.Conn) error) error {
+	errs := make([]error, len(r.pools))
+	var wg sync.WaitGroup
+	for i := range r.pools {
+		wg.Add(1)
+		go func(shard int) {
+			defer wg.Done()
+			err := r.withShard(ctx, shard, func(conn *sqlite.Conn) error {
+				return fn(shard, conn)
+			})
+			if err != nil {
+				errs[shard] = fmt.Errorf("shard %d: %w", shard, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// sortRows sorts result.Rows stably by orderBy.
+func sortRows(result *Result, orderBy []OrderBy) error {
+	indexes := make([]int, len(orderBy))
+	for i, o := range orderBy {
+		indexes[i] = -1
+		for j, column := range result.Columns {
+			if column == o.Column {
+				indexes[i] = j
+				break
+			}
+		}
+		if indexes[i] < 0 && len(result.Rows) > 0 {
+			return fmt.Errorf("cannot order by %s: not a result column", o.Column)
+		}
+	}
+	var err error
+	sort.SliceStable(result.Rows, func(a, b int) bool {
+		for i, o := range orderBy {
+			c, cerr := compareValues(result.Rows[a][indexes[i]], result.Rows[b][indexes[i]])
+			if cerr != nil {
+				if err == nil {
+					err = fmt.Errorf("cannot order by %s: %w", o.Column, cerr)
+				}
+				return false
+			}
+			if c == 0 {
+				continue
+			}
+			if o.Desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+	return err
+}
+
+// compareValues orders values as SQLite does: NULL, then numbers, then text,
+// then blobs. Values converted by exec.WithConversions order among the
+// values they are converted from: bools with numbers, and times after
+// numbers and before text. Other converted values cannot be ordered.
+func compareValues(a, b interface{}) (int, error) {
+	ra, err := typeRank(a)
+	if err != nil {
+		return 0, err
+	}
+	rb, err := typeRank(b)
+	if err != nil {
+		return 0, err
+	}
+	if ra != rb {
+		return ra - rb, nil
+	}
+	switch a := a.(type) {
+	case int64, float64, bool:
+		if ia, ok := a.(int64); ok {
+			if ib, ok := b.(int64); ok {
+				return compareOrdered(ia, ib), nil
+			}
+		}
+		return compareOrdered(toFloat(a), toFloat(b)), nil
+	case time.Time:
+		return a.Compare(b.(time.Time)), nil
+	case string:
+		return compareOrdered(a, b.(string)), nil
+	case []byte:
+		return bytes.Compare(a, b.([]byte)), nil
+	}
+	return 0, nil
+}
+
+func typeRank(v interface{}) (int, error) {
+	switch v.(type) {
+	case nil:
+		return 0, nil
+	case int64, float64, bool:
+		return 1, nil
+	case time.Time:
+		return 2, nil
+	case string:
+		return 3, nil
+	case []byte:
+		return 4, nil
+	}
+	return 0, fmt.Errorf("cannot compare %T values", v)
+}
+
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int64:
+		return float64(v)
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	}
+	return v.(float64)
+}
+
+func compareOrdered[T int64 | float64 | string](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,110 @@
+package shard_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/shard"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+	r, err := shard.Open(shard.Options{Dir: t.TempDir(), Shards: 4})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, r.Close())
+	}()
+
+	require.NoError(t, r.ExecAll(ctx, "CREATE TABLE events (user TEXT, seq INTEGER, score REAL);", nil))
+
+	used := map[int]bool{}
+	for u := 0; u < 20; u++ {
+		user := fmt.Sprintf("user-%d", u)
+		used[r.ShardFor(user)] = true
+		for seq := 0; seq < 3; seq++ {
+			require.NoError(t, r.Exec(ctx, user, "INSERT INTO events VALUES ($user, $seq, $score);",
+				map[string]interface{}{"$user": user, "$seq": seq, "$score": float64(u) + float64(seq)/10}, nil))
+		}
+	}
+	assert.Len(t, used, 4, "keys should spread over every shard")
+	assert.Equal(t, r.ShardFor("user-7"), r.ShardFor("user-7"))
+
+	// A key's rows are all on its shard.
+	var n int64
+	require.NoError(t, r.Query(ctx, "user-7", "SELECT count(*) FROM events WHERE user = 'user-7';", nil,
+		func(_ []string, values []interface{}) { n = values[0].(int64) }))
+	assert.Equal(t, int64(3), n)
+
+	all, err := r.QueryAll(ctx, "SELECT user, score FROM events;", nil, shard.FanOutOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user", "score"}, all.Columns)
+	assert.Len(t, all.Rows, 60)
+
+	top, err := r.QueryAll(ctx, "SELECT user, score FROM events ORDER BY score DESC LIMIT 3;", nil, shard.FanOutOptions{
+		OrderBy: []shard.OrderBy{{Column: "score", Desc: true}},
+		Limit:   3,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"user-19", 19.2}, {"user-19", 19.1}, {"user-19", 19.0}}, top.Rows)
+
+	_, err = r.QueryAll(ctx, "SELECT user FROM events;", nil, shard.FanOutOptions{OrderBy: []shard.OrderBy{{Column: "nope"}}})
+	assert.Error(t, err)
+	assert.ErrorContains(t, r.ExecAll(ctx, "SELECT * FROM missing;", nil), "shard 0")
+
+	_, err = shard.Open(shard.Options{Dir: t.TempDir()})
+	assert.Error(t, err)
+}
+
+func TestRouter_OrderConvertedValues(t *testing.T) {
+	ctx := context.Background()
+	r, err := shard.Open(shard.Options{
+		Dir:         t.TempDir(),
+		Shards:      3,
+		PoolOptions: []pool.Option{exec.WithConversions(exec.DefaultConversions().Name("stamp", func(v interface{}) (interface{}, error) {
+			if _, ok := v.([]byte); ok {
+				return v, nil
+			}
+			return exec.ConvertTime(v)
+		}))},
+	})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, r.Close())
+	}()
+
+	require.NoError(t, r.ExecAll(ctx, "CREATE TABLE events (user TEXT, created DATETIME, archived BOOLEAN, data BLOB);", nil))
+	start := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	for u := 0; u < 12; u++ {
+		user := fmt.Sprintf("user-%d", u)
+		require.NoError(t, r.Exec(ctx, user, "INSERT INTO events VALUES ($user, $created, $archived, $data);",
+			map[string]interface{}{"$user": user, "$created": start.Add(time.Duration(u) * time.Hour), "$archived": u%2 == 0, "$data": []byte{byte(u)}}, nil))
+	}
+
+	latest, err := r.QueryAll(ctx, "SELECT user, created FROM events;", nil, shard.FanOutOptions{
+		OrderBy: []shard.OrderBy{{Column: "created", Desc: true}},
+		Limit:   2,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{"user-11", start.Add(11 * time.Hour)}, {"user-10", start.Add(10 * time.Hour)}}, latest.Rows)
+
+	byFlag, err := r.QueryAll(ctx, "SELECT archived, user FROM events;", nil, shard.FanOutOptions{
+		OrderBy: []shard.OrderBy{{Column: "archived"}, {Column: "user"}},
+		Limit:   1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{false, "user-1"}}, byFlag.Rows)
+
+	// Blobs order after converted values instead of panicking on them.
+	mixed, err := r.QueryAll(ctx, "SELECT CASE WHEN user = 'user-3' THEN data ELSE created END AS stamp FROM events;", nil, shard.FanOutOptions{
+		OrderBy: []shard.OrderBy{{Column: "stamp", Desc: true}},
+		Limit:   1,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{[]byte{3}}}, mixed.Rows)
+}
@@ -0,0 +1,104 @@
+package sqliteutils
+
+import (
+	"strings"
+	"unicode"
+)
+
+// QuoteIdentifier quotes a table, column or index name for safe inclusion in SQL.
+func QuoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// Statement is a single statement of a SQL script.
+type Statement struct {
+	// SQL is the statement text, including its terminating semicolon.
+	SQL string
+	// Line is the 1-based line of the script on which the statement starts.
+	Line int
+}
+
+// SplitStatements splits script into complete statements, honoring string
+// literals, quoted identifiers, comments and CREATE TRIGGER bodies. Any
+// trailing text that does not yet end in a semicolon is returned as rest,
+// which is empty when only whitespace and comments remain.
+func SplitStatements(script string) (statements []Statement, rest string) {
+	start, line, startLine := -1, 1, 1
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\n':
+			line++
+			continue
+		case c == '-' && i+1 < len(script) && script[i+1] == '-':
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			i--
+			continue
+		case c == '/' && i+1 < len(script) && script[i+1] == '*':
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				line += strings.Count(script[i:i+2+end], "\n")
+				i += end + 3
+			}
+			continue
+		case unicode.IsSpace(rune(c)):
+			continue
+		}
+
+		if start < 0 {
+			start, startLine = i, line
+		}
+		switch c {
+		case '\'', '"', '`', '[':
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := strings.IndexByte(script[i+1:], closing)
+			if end < 0 {
+				i = len(script)
+			} else {
+				line += strings.Count(script[i:i+1+end], "\n")
+				i += end + 1
+			}
+		case ';':
+			sql := script[start : i+1]
+			if isTriggerStatement(sql) && !endsWithEnd(sql) {
+				continue
+			}
+			statements = append(statements, Statement{SQL: sql, Line: startLine})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		rest = script[start:]
+	}
+	return statements, rest
+}
+
+// isTriggerStatement reports whether sql begins with CREATE [TEMP] TRIGGER.
+func isTriggerStatement(sql string) bool {
+	words := strings.Fields(strings.ToUpper(sql))
+	if len(words) < 2 || words[0] != "CREATE" {
+		return false
+	}
+	if words[1] == "TEMP" || words[1] == "TEMPORARY" {
+		words = words[1:]
+	}
+	return len(words) > 1 && words[1] == "TRIGGER"
+}
+
+// endsWithEnd reports whether the last word before the final semicolon is END.
+func endsWithEnd(sql string) bool {
+	body := strings.TrimRightFunc(strings.TrimSuffix(sql, ";"), unicode.IsSpace)
+	return len(body) >= 3 && strings.EqualFold(body[len(body)-3:], "END") &&
+		(len(body) == 3 || !isIdentChar(body[len(body)-4]))
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
@@ -0,0 +1,32 @@
+package sqliteutils_test
+
+import (
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `-- users
+CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);
+INSERT INTO users (name) VALUES ('a;b'), ("c;d");
+/* multi
+   line; comment */
+CREATE TRIGGER users_ai AFTER INSERT ON users BEGIN
+	UPDATE users SET name = upper(name) WHERE id = new.id;
+END;
+SELECT [weird;name] FROM users
+`
+	statements, rest := sqliteutils.SplitStatements(script)
+	assert.Equal(t, []sqliteutils.Statement{
+		{SQL: "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT);", Line: 2},
+		{SQL: `INSERT INTO users (name) VALUES ('a;b'), ("c;d");`, Line: 3},
+		{SQL: "CREATE TRIGGER users_ai AFTER INSERT ON users BEGIN\n\tUPDATE users SET name = upper(name) WHERE id = new.id;\nEND;", Line: 6},
+	}, statements)
+	assert.Equal(t, "SELECT [weird;name] FROM users\n", rest)
+
+	statements, rest = sqliteutils.SplitStatements("SELECT 1; -- done\n")
+	assert.Len(t, statements, 1)
+	assert.Empty(t, rest)
+}
@@ -0,0 +1,54 @@
+package sqliteutils
+
+import (
+	"expvar"
+	"sort"
+	"sync"
+)
+
+var (
+	statsLock      sync.Mutex
+	statsProviders = map[string]func() interface{}{}
+	publishOnce    sync.Once
+)
+
+// RegisterStats registers fn to report the stats published under name by
+// Stats and PublishExpvars. The pool, exec and backup packages register
+// themselves when imported. Registering a name again replaces its provider.
+func RegisterStats(name string, fn func() interface{}) {
+	statsLock.Lock()
+	defer statsLock.Unlock()
+	statsProviders[name] = fn
+}
+
+// Stats returns the current value of every registered stats provider by name.
+func Stats() map[string]interface{} {
+	statsLock.Lock()
+	names := make([]string, 0, len(statsProviders))
+	for name := range statsProviders {
+		names = append(names, name)
+	}
+	providers := make([]func() interface{}, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		providers[i] = statsProviders[name]
+	}
+	statsLock.Unlock()
+
+	stats := make(map[string]interface{}, len(names))
+	for i, name := range names {
+		stats[name] = providers[i]()
+	}
+	return stats
+}
+
+// PublishExpvars publishes Stats as the "sqliteutils" expvar, so it appears
+// in /debug/vars. Values are computed on each read. It is safe to call more
+// than once.
+func PublishExpvars() {
+	publishOnce.Do(func() {
+		expvar.Publish("sqliteutils", expvar.Func(func() interface{} {
+			return Stats()
+		}))
+	})
+}
@@ -0,0 +1,49 @@
+package sqliteutils_test
+
+import (
+	"context"
+	"encoding/json"
+	"expvar"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublishExpvars(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE t (id INTEGER PRIMARY KEY);", 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	before := exec.GetStats()
+	require.NoError(t, exec.Exec(ctx, "INSERT INTO t (id) VALUES (1);", nil, nil))
+	assert.Error(t, exec.Exec(ctx, "SELECT * FROM missing;", nil, nil))
+
+	sqliteutils.PublishExpvars()
+	sqliteutils.PublishExpvars()
+	v := expvar.Get("sqliteutils")
+	require.NotNil(t, v)
+
+	var vars struct {
+		Pool   pool.Stats `json:"pool"`
+		Exec   exec.Stats `json:"exec"`
+		Backup struct {
+			Count int64 `json:"count"`
+		} `json:"backup"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(v.String()), &vars))
+	assert.True(t, vars.Pool.Initialized)
+	assert.Equal(t, 2, vars.Pool.Size)
+	assert.Positive(t, vars.Pool.Takes)
+	assert.Equal(t, before.Queries+2, vars.Exec.Queries)
+	assert.Equal(t, before.Errors+1, vars.Exec.Errors)
+
+	sqliteutils.RegisterStats("custom", func() interface{} { return 42 })
+	assert.Equal(t, 42, sqliteutils.Stats()["custom"])
+}
@@ -0,0 +1,360 @@
+// Package tenant manages one SQLite database file per tenant, the usual way
+// to isolate the data of SaaS customers with SQLite.
+//
+// A Manager opens a pool for a tenant on first use and keeps a bounded
+// number of pools open, closing the least recently used idle one when the
+// limit is exceeded. Migrations registered with the migrate package can be
+// applied to every tenant, and each tenant can be backed up or exported on
+// its own.
+package tenant
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/backup"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/migrate"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Options configures a Manager.
+type Options struct {
+	// Dir holds the tenant databases, one file named <tenant>.db each.
+	Dir string
+	// PoolSize is the number of connections per tenant. Defaults to 4.
+	PoolSize int
+	// MaxOpen bounds the number of tenant pools kept open. Pools in use are
+	// never closed, so more may be open briefly. Defaults to 32.
+	MaxOpen int
+	// BusyTimeout is how long connections wait for locks. Defaults to 5s.
+	BusyTimeout time.Duration
+	// Migrate applies the migrations registered with the migrate package to
+	// each tenant database when its pool is opened.
+	Migrate bool
+	// PoolOptions configure every tenant pool, as for pool.InitPool.
+	PoolOptions []pool.Option
+}
+
+// ErrClosed is returned by a Manager that has been closed.
+var ErrClosed = errors.New("tenant manager is closed")
+
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-][A-Za-z0-9_.-]*$`)
+
+const dbExt = ".db"
+
+// Manager routes operations to per-tenant pools. It is safe for concurrent use.
+type Manager struct {
+	opts Options
+
+	mu     sync.Mutex
+	open   map[string]*entry
+	lru    *list.List
+	closed bool
+}
+
+type entry struct {
+	id   string
+	elem *list.Element
+	// ready is closed once pool and err are set.
+	ready chan struct{}
+	pool  *sqlitex.Pool
+	err   error
+	refs  int
+	// evicted entries are closed when their last user releases them.
+	evicted bool
+}
+
+// NewManager returns a Manager for the tenant databases in opts.Dir,
+// creating the directory if needed.
+func NewManager(opts Options) (*Manager, error) {
+	if opts.Dir == "" {
+		return nil, fmt.Errorf("tenant directory is required")
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = 4
+	}
+	if opts.MaxOpen <= 0 {
+		opts.MaxOpen = 32
+	}
+	if opts.BusyTimeout <= 0 {
+		opts.BusyTimeout = 5 * time.Second
+	}
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create tenant directory: %w", err)
+	}
+	return &Manager{opts: opts, open: make(map[string]*entry), lru: list.New()}, nil
+}
+
+// Path returns the database file of tenantID.
+func (m *Manager) Path(tenantID string) (string, error) {
+	if !validID.MatchString(tenantID) {
+		return "", fmt.Errorf("invalid tenant ID %q: use letters, digits, '_', '-' and '.'", tenantID)
+	}
+	return filepath.Join(m.opts.Dir, tenantID+dbExt), nil
+}
+
+// Tenants returns the IDs of the tenants with a database file, sorted.
+func (m *Manager) Tenants() ([]string, error) {
+	entries, err := os.ReadDir(m.opts.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant directory: %w", err)
+	}
+	var ids []string
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), dbExt)
+		if ok && !e.IsDir() && validID.MatchString(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// WithConn runs fn with a connection to tenantID's database, opening its
+// pool if needed.
+func (m *Manager) WithConn(ctx context.Context, tenantID string, fn func(conn *sqlite.Conn) error) error {
+	e, err := m.acquire(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	defer m.release(e)
+
+	conn, err := pool.Take(ctx, e.pool)
+	if err != nil {
+		return sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer e.pool.Put(conn)
+	return fn(conn)
+}
+
+// Exec is exec.Exec on tenantID's database.
+func (m *Manager) Exec(ctx context.Context, tenantID, query string, params map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	return m.WithConn(ctx, tenantID, func(conn *sqlite.Conn) error {
+		return exec.ExecConn(conn, query, params, resultFunc)
+	})
+}
+
+// Query is exec.Query on tenantID's database.
+func (m *Manager) Query(ctx context.Context, tenantID, query string, params map[string]interface{}, rowFunc func(columns []string, values []interface{})) error {
+	return m.WithConn(ctx, tenantID, func(conn *sqlite.Conn) error {
+		return exec.QueryConn(conn, query, params, rowFunc)
+	})
+}
+
+// Migrate applies the pending registered migrations to tenantID's database
+// and returns the versions it applied.
+func (m *Manager) Migrate(ctx context.Context, tenantID string) ([]int64, error) {
+	var applied []int64
+	err := m.WithConn(ctx, tenantID, func(conn *sqlite.Conn) (err error) {
+		applied, err = migrate.UpConn(ctx, conn)
+		return err
+	})
+	return applied, err
+}
+
+// MigrateAll applies the pending registered migrations to every tenant with
+// a database file and returns the versions applied by tenant. It stops at
+// the first tenant that fails.
+func (m *Manager) MigrateAll(ctx context.Context) (map[string][]int64, error) {
+	ids, err := m.Tenants()
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[string][]int64, len(ids))
+	for _, id := range ids {
+		versions, err := m.Migrate(ctx, id)
+		if err != nil {
+			return applied, fmt.Errorf("failed to migrate tenant %s: %w", id, err)
+		}
+		applied[id] = versions
+	}
+	return applied, nil
+}
+
+// Backup copies tenantID's database to destPath with backup.BackupDatabase,
+// writing a manifest next to it.
+func (m *Manager) Backup(ctx context.Context, tenantID, destPath string) error {
+	path, err := m.Path(tenantID)
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("failed to back up tenant %s: %w", tenantID, err)
+	}
+	return backup.BackupDatabase(path, destPath)
+}
+
+// Export writes a compacted, standalone copy of tenantID's database to
+// destPath with VACUUM INTO, for handing a tenant its data. destPath must
+// not exist.
+func (m *Manager) Export(ctx context.Context, tenantID, destPath string) error {
+	return m.WithConn(ctx, tenantID, func(conn *sqlite.Conn) error {
+		err := sqlitex.ExecuteTransient(conn, "VACUUM INTO ?;", &sqlitex.ExecOptions{Args: []interface{}{destPath}})
+		if err != nil {
+			return fmt.Errorf("failed to export tenant %s: %w", tenantID, err)
+		}
+		return nil
+	})
+}
+
+// Close closes every open tenant pool. Pools in use are closed when released.
+func (m *Manager) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	var idle []*entry
+	for _, e := range m.open {
+		m.remove(e)
+		if e.refs == 0 {
+			idle = append(idle, e)
+		}
+	}
+	m.mu.Unlock()
+
+	var errs []error
+	for _, e := range idle {
+		errs = append(errs, closeEntry(e))
+	}
+	return errors.Join(errs...)
+}
+
+// acquire returns the open pool of tenantID, opening it if needed. The
+// caller must release it.
+func (m *Manager) acquire(ctx context.Context, tenantID string) (*entry, error) {
+	path, err := m.Path(tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		return nil, ErrClosed
+	}
+	e, ok := m.open[tenantID]
+	if ok {
+		e.refs++
+		m.lru.MoveToFront(e.elem)
+		m.mu.Unlock()
+		select {
+		case <-e.ready:
+		case <-ctx.Done():
+			m.release(e)
+			return nil, ctx.Err()
+		}
+		if e.err != nil {
+			m.release(e)
+			return nil, e.err
+		}
+		return e, nil
+	}
+	e = &entry{id: tenantID, ready: make(chan struct{}), refs: 1}
+	e.elem = m.lru.PushFront(e)
+	m.open[tenantID] = e
+	m.mu.Unlock()
+
+	e.pool, e.err = m.openPool(ctx, tenantID, path)
+	close(e.ready)
+
+	m.mu.Lock()
+	var evicted []*entry
+	if e.err != nil {
+		// Forget the failure so the next call retries.
+		m.remove(e)
+	} else {
+		evicted = m.evict()
+	}
+	m.mu.Unlock()
+	for _, old := range evicted {
+		if err := closeEntry(old); err != nil {
+			sqliteutils.Logger().Warn("failed to close tenant pool", "tenant", old.id, "error", err)
+		}
+	}
+	if e.err != nil {
+		m.release(e)
+		return nil, e.err
+	}
+	return e, nil
+}
+
+func (m *Manager) openPool(ctx context.Context, tenantID, path string) (*sqlitex.Pool, error) {
+	uri := sqliteutils.URI{Path: path, BusyTimeout: m.opts.BusyTimeout}
+	p, err := pool.Open(uri.String(), m.opts.PoolSize, m.opts.PoolOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tenant %s: %w", tenantID, err)
+	}
+	if !m.opts.Migrate {
+		return p, nil
+	}
+	conn, err := p.Take(ctx)
+	if err == nil {
+		_, err = migrate.UpConn(ctx, conn)
+		p.Put(conn)
+	}
+	if err != nil {
+		pool.Close(p)
+		return nil, fmt.Errorf("failed to migrate tenant %s: %w", tenantID, err)
+	}
+	return p, nil
+}
+
+// release gives up a reference taken by acquire, closing the pool if it was
+// evicted while in use.
+func (m *Manager) release(e *entry) {
+	m.mu.Lock()
+	e.refs--
+	closeNow := e.evicted && e.refs == 0 && e.pool != nil
+	m.mu.Unlock()
+	if closeNow {
+		if err := closeEntry(e); err != nil {
+			sqliteutils.Logger().Warn("failed to close tenant pool", "tenant", e.id, "error", err)
+		}
+	}
+}
+
+// evict removes the least recently used idle entries beyond MaxOpen and
+// returns them for closing. The caller holds m.mu.
+func (m *Manager) evict() []*entry {
+	var evicted []*entry
+	for elem := m.lru.Back(); elem != nil && m.lru.Len() > m.opts.MaxOpen; {
+		e := elem.Value.(*entry)
+		elem = elem.Prev()
+		if e.refs == 0 {
+			m.remove(e)
+			evicted = append(evicted, e)
+		}
+	}
+	return evicted
+}
+
+// remove forgets e, marking it to be closed by its last user. The caller
+// holds m.mu.
+func (m *Manager) remove(e *entry) {
+	if m.open[e.id] == e {
+		delete(m.open, e.id)
+		m.lru.Remove(e.elem)
+	}
+	e.evicted = true
+}
+
+func closeEntry(e *entry) error {
+	if e.pool == nil {
+		return nil
+	}
+	if err := pool.Close(e.pool); err != nil {
+		return sqliteutils.FailedToClosePoolError(err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package tenant_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/migrate"
+	"github.com/dropsite-ai/sqliteutils/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	migrate.Reset()
+	defer migrate.Reset()
+	require.NoError(t, migrate.RegisterSQL(1, "create notes", `CREATE TABLE notes (id INTEGER PRIMARY KEY, body TEXT NOT NULL);`))
+
+	dir := t.TempDir()
+	m, err := tenant.NewManager(tenant.Options{Dir: filepath.Join(dir, "tenants"), MaxOpen: 1, Migrate: true})
+	require.NoError(t, err)
+	defer func() {
+		assert.NoError(t, m.Close())
+	}()
+
+	count := func(id string) int64 {
+		var n int64
+		require.NoError(t, m.Query(ctx, id, "SELECT count(*) FROM notes;", nil, func(_ []string, values []interface{}) {
+			n = values[0].(int64)
+		}))
+		return n
+	}
+
+	// Tenants are isolated and reopened transparently after eviction.
+	var wg sync.WaitGroup
+	for _, id := range []string{"acme", "globex", "acme", "initech"} {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			assert.NoError(t, m.Exec(ctx, id, "INSERT INTO notes (body) VALUES ($body);", map[string]interface{}{"$body": id}, nil))
+		}(id)
+	}
+	wg.Wait()
+	assert.Equal(t, int64(2), count("acme"))
+	assert.Equal(t, int64(1), count("globex"))
+	assert.Equal(t, int64(1), count("initech"))
+
+	ids, err := m.Tenants()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"acme", "globex", "initech"}, ids)
+
+	// New migrations reach every tenant, whether its pool is reopened or
+	// MigrateAll finds it open.
+	require.NoError(t, migrate.RegisterSQL(2, "add pinned", `ALTER TABLE notes ADD COLUMN pinned INTEGER NOT NULL DEFAULT 0;`))
+	applied, err := m.MigrateAll(ctx)
+	require.NoError(t, err)
+	assert.Len(t, applied, 3)
+	for _, id := range ids {
+		require.NoError(t, m.Exec(ctx, id, "UPDATE notes SET pinned = 1;", nil, nil))
+	}
+
+	require.NoError(t, m.Backup(ctx, "acme", filepath.Join(dir, "acme-backup.db")))
+	assert.FileExists(t, filepath.Join(dir, "acme-backup.db"))
+	require.NoError(t, m.Export(ctx, "globex", filepath.Join(dir, "globex-export.db")))
+	assert.FileExists(t, filepath.Join(dir, "globex-export.db"))
+	assert.Error(t, m.Backup(ctx, "missing", filepath.Join(dir, "missing.db")))
+
+	for _, id := range []string{"", "../etc", "a/b", ".hidden"} {
+		assert.Error(t, m.Exec(ctx, id, "SELECT 1;", nil, nil), id)
+	}
+	_, err = os.Stat(filepath.Join(dir, "etc.db"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestManagerClosed(t *testing.T) {
+	m, err := tenant.NewManager(tenant.Options{Dir: t.TempDir()})
+	require.NoError(t, err)
+	require.NoError(t, m.Close())
+	assert.ErrorIs(t, m.Exec(context.Background(), "acme", "SELECT 1;", nil, nil), tenant.ErrClosed)
+}
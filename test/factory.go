@@ -0,0 +1,263 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// Defaults maps column names to the values a Factory gives them. A value is
+// either used as is or, if it is a Generator or a function of the same
+// signature, called for every row.
+type Defaults map[string]interface{}
+
+// Override maps column names to values that replace a Factory's defaults.
+type Override map[string]interface{}
+
+// Generator returns a column value for the seq'th row built by a Factory,
+// starting at 1. fake produces deterministic fake data.
+type Generator func(seq int, fake *Faker) interface{}
+
+// Sequence returns a Generator formatting the row number with format, as in
+// Sequence("user%d@example.com").
+func Sequence(format string) Generator {
+	return func(seq int, _ *Faker) interface{} {
+		return fmt.Sprintf(format, seq)
+	}
+}
+
+// Factory builds and inserts rows of one table.
+type Factory struct {
+	table    string
+	defaults Defaults
+
+	mu   sync.Mutex
+	seq  int
+	fake *Faker
+}
+
+// NewFactory returns a Factory for table. Its fake data is seeded with 1, so
+// a test creating the same rows in the same order always gets the same data.
+func NewFactory(table string, defaults Defaults) *Factory {
+	return &Factory{table: table, defaults: defaults, fake: NewFaker(1)}
+}
+
+// Seed reseeds the Factory's fake data and returns the Factory.
+func (f *Factory) Seed(seed int64) *Factory {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fake = NewFaker(seed)
+	return f
+}
+
+// Build returns a row of defaults with overrides applied, without inserting it.
+func (f *Factory) Build(overrides ...Override) map[string]interface{} {
+	row, _ := f.build(overrides)
+	return row
+}
+
+func (f *Factory) build(overrides []Override) (map[string]interface{}, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	row := make(map[string]interface{}, len(f.defaults))
+	for column, value := range f.defaults {
+		if overridden(overrides, column) {
+			continue
+		}
+		switch gen := value.(type) {
+		case Generator:
+			value = gen(f.seq, f.fake)
+		case func(int, *Faker) interface{}:
+			value = gen(f.seq, f.fake)
+		}
+		row[column] = value
+	}
+	for _, override := range overrides {
+		for column, value := range override {
+			row[column] = value
+		}
+	}
+	return row, f.seq
+}
+
+// overridden reports whether column is overridden, so its default need not
+// be generated.
+func overridden(overrides []Override, column string) bool {
+	for _, override := range overrides {
+		if _, ok := override[column]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Create builds a row, inserts it into the global pool's database and
+// returns it as stored, including generated keys and column defaults.
+// NOT NULL columns without a default that the Factory does not set are
+// filled with placeholder values of their declared type. Create fails the
+// test on error.
+func (f *Factory) Create(ctx context.Context, t *testing.T, overrides ...Override) map[string]interface{} {
+	t.Helper()
+
+	row, seq := f.build(overrides)
+	p, err := pool.GetPool()
+	if err != nil {
+		t.Fatalf("failed to create %s row: %v", f.table, sqliteutils.FailedToGetPoolError(err))
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		t.Fatalf("failed to create %s row: %v", f.table, sqliteutils.FailedToTakeConnectionFromPoolError(err))
+	}
+	defer p.Put(conn)
+
+	stored, err := f.insert(conn, row, seq)
+	if err != nil {
+		t.Fatalf("failed to create %s row: %v", f.table, err)
+	}
+	return stored
+}
+
+// CreateN creates n rows with the same overrides.
+func (f *Factory) CreateN(ctx context.Context, t *testing.T, n int, overrides ...Override) []map[string]interface{} {
+	t.Helper()
+	rows := make([]map[string]interface{}, n)
+	for i := range rows {
+		rows[i] = f.Create(ctx, t, overrides...)
+	}
+	return rows
+}
+
+func (f *Factory) insert(conn *sqlite.Conn, row map[string]interface{}, seq int) (map[string]interface{}, error) {
+	err := sqlitex.Execute(conn, "SELECT name, type, \"notnull\", dflt_value IS NOT NULL, pk FROM pragma_table_info(?);", &sqlitex.ExecOptions{
+		Args: []interface{}{f.table},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			name, declType := stmt.ColumnText(0), stmt.ColumnText(1)
+			if _, ok := row[name]; ok || stmt.ColumnInt(2) == 0 || stmt.ColumnBool(3) {
+				return nil
+			}
+			if stmt.ColumnInt(4) > 0 && strings.EqualFold(declType, "INTEGER") {
+				return nil // rowid alias
+			}
+			row[name] = placeholder(name, declType, seq)
+			return nil
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read columns of %s: %w", f.table, err)
+	}
+	if err := insertRow(conn, f.table, row); err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]interface{})
+	err = sqlitex.Execute(conn, fmt.Sprintf("SELECT * FROM %s WHERE rowid = ?;", sqliteutils.QuoteIdentifier(f.table)), &sqlitex.ExecOptions{
+		Args: []interface{}{conn.LastInsertRowID()},
+		ResultFunc: func(stmt *sqlite.Stmt) error {
+			for i := 0; i < stmt.ColumnCount(); i++ {
+				stored[stmt.ColumnName(i)] = columnValue(stmt, i)
+			}
+			return nil
+		},
+	})
+	if err != nil || len(stored) == 0 {
+		// WITHOUT ROWID tables cannot be read back by rowid.
+		return row, nil
+	}
+	return stored, nil
+}
+
+// placeholder returns a value for column of the declared type, following
+// SQLite's type affinity rules.
+func placeholder(column, declType string, seq int) interface{} {
+	upper := strings.ToUpper(declType)
+	switch {
+	case strings.Contains(upper, "INT"):
+		return int64(seq)
+	case strings.Contains(upper, "CHAR"), strings.Contains(upper, "CLOB"), strings.Contains(upper, "TEXT"):
+		return fmt.Sprintf("%s-%d", column, seq)
+	case upper == "", strings.Contains(upper, "BLOB"):
+		return []byte(fmt.Sprintf("%s-%d", column, seq))
+	case strings.Contains(upper, "REAL"), strings.Contains(upper, "FLOA"), strings.Contains(upper, "DOUB"):
+		return float64(seq)
+	default:
+		return int64(seq)
+	}
+}
+
+func columnValue(stmt *sqlite.Stmt, i int) interface{} {
+	switch stmt.ColumnType(i) {
+	case sqlite.TypeInteger:
+		return stmt.ColumnInt64(i)
+	case sqlite.TypeFloat:
+		return stmt.ColumnFloat(i)
+	case sqlite.TypeBlob:
+		buf := make([]byte, stmt.ColumnLen(i))
+		stmt.ColumnBytes(i, buf)
+		return buf
+	case sqlite.TypeNull:
+		return nil
+	default:
+		return stmt.ColumnText(i)
+	}
+}
+
+// Faker produces deterministic fake data from a seed.
+type Faker struct {
+	rand *rand.Rand
+}
+
+// NewFaker returns a Faker seeded with seed.
+func NewFaker(seed int64) *Faker {
+	return &Faker{rand: rand.New(rand.NewSource(seed))}
+}
+
+var (
+	firstNames = []string{"Ada", "Alan", "Barbara", "Claude", "Donald", "Edsger", "Frances", "Grace", "Ken", "Leslie", "Margaret", "Niklaus", "Radia", "Sophie", "Tim", "Yukihiro"}
+	lastNames  = []string{"Allen", "Berners-Lee", "Dijkstra", "Hamilton", "Hopper", "Knuth", "Lamport", "Liskov", "Lovelace", "Matsumoto", "Perlman", "Ritchie", "Shannon", "Thompson", "Turing", "Wirth"}
+	words      = []string{"alpha", "bravo", "cedar", "delta", "ember", "fjord", "grove", "harbor", "iris", "juniper", "kestrel", "lumen", "meadow", "nectar", "orbit", "prism", "quartz", "river", "summit", "tundra"}
+)
+
+// FirstName returns a first name.
+func (f *Faker) FirstName() string { return firstNames[f.rand.Intn(len(firstNames))] }
+
+// LastName returns a last name.
+func (f *Faker) LastName() string { return lastNames[f.rand.Intn(len(lastNames))] }
+
+// Name returns a first and last name.
+func (f *Faker) Name() string { return f.FirstName() + " " + f.LastName() }
+
+// Email returns an address at example.com.
+func (f *Faker) Email() string {
+	return fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(f.FirstName()), strings.ToLower(f.Word()), f.rand.Intn(1000))
+}
+
+// Word returns a lowercase word.
+func (f *Faker) Word() string { return words[f.rand.Intn(len(words))] }
+
+// Sentence returns n words, capitalized and ending in a period.
+func (f *Faker) Sentence(n int) string {
+	ws := make([]string, n)
+	for i := range ws {
+		ws[i] = f.Word()
+	}
+	s := strings.Join(ws, " ")
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
+
+// Int returns an integer in [min, max].
+func (f *Faker) Int(min, max int) int { return min + f.rand.Intn(max-min+1) }
+
+// Bool returns true or false.
+func (f *Faker) Bool() bool { return f.rand.Intn(2) == 1 }
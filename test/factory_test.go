@@ -0,0 +1,62 @@
+package test_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFactory(t *testing.T) {
+	ctx := context.Background()
+	const migration = `
+		CREATE TABLE users (
+			id INTEGER PRIMARY KEY,
+			email TEXT NOT NULL UNIQUE,
+			name TEXT NOT NULL,
+			age INTEGER NOT NULL,
+			score REAL NOT NULL,
+			role TEXT NOT NULL DEFAULT 'member',
+			bio TEXT
+		);
+		CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER NOT NULL REFERENCES users (id), title TEXT NOT NULL);
+	`
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	users := test.NewFactory("users", test.Defaults{
+		"email": test.Sequence("user%d@example.com"),
+		"name":  func(_ int, fake *test.Faker) interface{} { return fake.Name() },
+	})
+	posts := test.NewFactory("posts", test.Defaults{
+		"title": test.Generator(func(_ int, fake *test.Faker) interface{} { return fake.Sentence(3) }),
+	})
+
+	alice := users.Create(ctx, t, test.Override{"email": "x@y", "bio": "hi"})
+	assert.Equal(t, int64(1), alice["id"])
+	assert.Equal(t, "x@y", alice["email"])
+	assert.Equal(t, "hi", alice["bio"])
+	assert.Equal(t, "member", alice["role"])
+	assert.Equal(t, int64(1), alice["age"])
+	assert.Equal(t, 1.0, alice["score"])
+	assert.NotEmpty(t, alice["name"])
+
+	rest := users.CreateN(ctx, t, 2)
+	require.Len(t, rest, 2)
+	assert.Equal(t, "user2@example.com", rest[0]["email"])
+	assert.Equal(t, "user3@example.com", rest[1]["email"])
+
+	post := posts.Create(ctx, t, test.Override{"user_id": alice["id"]})
+	assert.Equal(t, alice["id"], post["user_id"])
+
+	// Fake data is deterministic for a seed.
+	a := test.NewFactory("users", test.Defaults{"name": test.Generator(func(_ int, fake *test.Faker) interface{} { return fake.Name() })}).Seed(7)
+	b := test.NewFactory("users", test.Defaults{"name": test.Generator(func(_ int, fake *test.Faker) interface{} { return fake.Name() })}).Seed(7)
+	assert.Equal(t, a.Build(), b.Build())
+	assert.Equal(t, map[string]interface{}{"name": "x"}, a.Build(test.Override{"name": "x"}))
+}
@@ -0,0 +1,175 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"gopkg.in/yaml.v3"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// LoadFixtures inserts the rows of every .yaml, .yml and .json file in fsys
+// into the global pool's database. Each file maps table names to lists of
+// rows, and each row maps column names to values; nested objects and lists
+// are stored as JSON text:
+//
+//	users:
+//	  - {id: 1, name: alice}
+//	posts:
+//	  - {id: 1, user_id: 1, title: hello, tags: [a, b]}
+//
+// Tables are filled in foreign key dependency order, parents first, inside a
+// single transaction, so a failing fixture leaves the database unchanged.
+func LoadFixtures(ctx context.Context, t *testing.T, fsys fs.FS) error {
+	t.Helper()
+
+	fixtures, err := readFixtures(fsys)
+	if err != nil {
+		return err
+	}
+
+	p, err := pool.GetPool()
+	if err != nil {
+		return sqliteutils.FailedToGetPoolError(err)
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		return sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+
+	return insertFixtures(conn, fixtures)
+}
+
+// readFixtures merges the rows of every fixture file in fsys by table.
+func readFixtures(fsys fs.FS) (map[string][]map[string]interface{}, error) {
+	fixtures := make(map[string][]map[string]interface{})
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch strings.ToLower(path.Ext(name)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return fmt.Errorf("failed to read fixture %s: %w", name, err)
+		}
+		// JSON is valid YAML, so one decoder reads both.
+		var file map[string][]map[string]interface{}
+		if err := yaml.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("failed to parse fixture %s: %w", name, err)
+		}
+		for table, rows := range file {
+			fixtures[table] = append(fixtures[table], rows...)
+		}
+		return nil
+	})
+	return fixtures, err
+}
+
+func insertFixtures(conn *sqlite.Conn, fixtures map[string][]map[string]interface{}) (err error) {
+	defer sqlitex.Save(conn)(&err)
+
+	// Foreign keys between tables that form a cycle are checked at commit.
+	if err := sqlitex.ExecuteTransient(conn, "PRAGMA defer_foreign_keys = ON;", nil); err != nil {
+		return fmt.Errorf("failed to defer foreign keys: %w", err)
+	}
+	tables, err := dependencyOrder(conn, fixtures)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		for i, row := range fixtures[table] {
+			if err := insertRow(conn, table, row); err != nil {
+				return fmt.Errorf("failed to insert fixture row %d into %s: %w", i, table, err)
+			}
+		}
+	}
+	return nil
+}
+
+// dependencyOrder sorts the fixture tables so that tables referenced by
+// foreign keys come before the tables referencing them.
+func dependencyOrder(conn *sqlite.Conn, fixtures map[string][]map[string]interface{}) ([]string, error) {
+	names := make([]string, 0, len(fixtures))
+	for table := range fixtures {
+		names = append(names, table)
+	}
+	sort.Strings(names)
+
+	parents := make(map[string][]string, len(names))
+	for _, table := range names {
+		err := sqlitex.Execute(conn, `SELECT DISTINCT "table" FROM pragma_foreign_key_list(?);`, &sqlitex.ExecOptions{
+			Args: []interface{}{table},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				parents[table] = append(parents[table], stmt.ColumnText(0))
+				return nil
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read foreign keys of %s: %w", table, err)
+		}
+	}
+
+	var order []string
+	visited := make(map[string]bool, len(names))
+	var visit func(table string)
+	visit = func(table string) {
+		if visited[table] {
+			return
+		}
+		visited[table] = true
+		for _, parent := range parents[table] {
+			if _, ok := fixtures[parent]; ok {
+				visit(parent)
+			}
+		}
+		order = append(order, table)
+	}
+	for _, table := range names {
+		visit(table)
+	}
+	return order, nil
+}
+
+func insertRow(conn *sqlite.Conn, table string, row map[string]interface{}) error {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	quoted := make([]string, len(columns))
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		quoted[i] = sqliteutils.QuoteIdentifier(column)
+		switch v := row[column].(type) {
+		case map[string]interface{}, []interface{}:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("failed to encode column %s: %w", column, err)
+			}
+			args[i] = string(data)
+		default:
+			args[i] = v
+		}
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", sqliteutils.QuoteIdentifier(table),
+		strings.Join(quoted, ", "), strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", "))
+	if len(columns) == 0 {
+		query = fmt.Sprintf("INSERT INTO %s DEFAULT VALUES;", sqliteutils.QuoteIdentifier(table))
+	}
+	return sqlitex.Execute(conn, query, &sqlitex.ExecOptions{Args: args})
+}
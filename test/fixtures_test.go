@@ -0,0 +1,62 @@
+package test_test
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadFixtures(t *testing.T) {
+	ctx := context.Background()
+	const migration = `
+		CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, active BOOLEAN);
+		CREATE TABLE posts (
+			id INTEGER PRIMARY KEY,
+			user_id INTEGER NOT NULL REFERENCES users (id),
+			title TEXT,
+			meta TEXT
+		);
+	`
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	// posts sorts before users but references it, so users is loaded first.
+	fixtures := fstest.MapFS{
+		"fixtures/a_posts.yaml": {Data: []byte(`
+posts:
+  - {id: 10, user_id: 1, title: hello, meta: {tags: [a, b]}}
+  - {id: 11, user_id: 2, title: ~}
+`)},
+		"fixtures/b_users.json": {Data: []byte(`{"users": [{"id": 1, "name": "alice", "active": true}, {"id": 2, "name": "bob"}]}`)},
+		"fixtures/README.md":    {Data: []byte("ignored")},
+	}
+	require.NoError(t, test.LoadFixtures(ctx, t, fixtures))
+
+	var rows []map[string]interface{}
+	require.NoError(t, exec.Exec(ctx, "SELECT p.id, u.name, u.active, p.title, p.meta FROM posts p JOIN users u ON u.id = p.user_id ORDER BY p.id;", nil,
+		func(_ int, row map[string]interface{}) { rows = append(rows, row) }))
+	assert.Equal(t, []map[string]interface{}{
+		{"id": int64(10), "name": "alice", "active": int64(1), "title": "hello", "meta": `{"tags":["a","b"]}`},
+		{"id": int64(11), "name": "bob", "active": nil, "title": nil, "meta": nil},
+	}, rows)
+
+	// A failing row rolls back every fixture.
+	bad := fstest.MapFS{"bad.yaml": {Data: []byte(`
+users:
+  - {id: 3, name: carol}
+  - {id: 4}
+`)}}
+	require.Error(t, test.LoadFixtures(ctx, t, bad))
+	var count int64
+	require.NoError(t, exec.Exec(ctx, "SELECT count(*) AS n FROM users;", nil,
+		func(_ int, row map[string]interface{}) { count = row["n"].(int64) }))
+	assert.Equal(t, int64(2), count)
+}
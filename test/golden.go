@@ -0,0 +1,104 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"zombiezen.com/go/sqlite"
+	"zombiezen.com/go/sqlite/sqlitex"
+)
+
+// update is named after the package so that it cannot clash with an
+// -update flag of the tests importing it.
+var update = flag.Bool("sqliteutils.update", false, "update golden files of test.AssertGolden")
+
+// AssertGolden dumps tables from the global pool's database as JSON and
+// compares it with testdata/<test name>.golden.json. Run the test with
+// -sqliteutils.update to write the file instead. Rows are sorted by all
+// their columns, so the dump does not depend on insertion order.
+func AssertGolden(ctx context.Context, t *testing.T, tables ...string) {
+	t.Helper()
+
+	got, err := dumpTables(ctx, tables)
+	if err != nil {
+		t.Fatalf("failed to dump tables: %v", err)
+	}
+	path := filepath.Join("testdata", strings.ReplaceAll(t.Name(), "/", "_")+".golden.json")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("failed to create golden file directory: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("failed to write golden file: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read golden file (run with -sqliteutils.update to create it): %v", err)
+	}
+	assert.Equal(t, string(want), string(got), "tables differ from %s (run with -sqliteutils.update to accept)", path)
+}
+
+// dumpTables renders the rows of tables as indented JSON, keyed by table.
+func dumpTables(ctx context.Context, tables []string) ([]byte, error) {
+	p, err := pool.GetPool()
+	if err != nil {
+		return nil, sqliteutils.FailedToGetPoolError(err)
+	}
+	conn, err := p.Take(ctx)
+	if err != nil {
+		return nil, sqliteutils.FailedToTakeConnectionFromPoolError(err)
+	}
+	defer p.Put(conn)
+
+	dump := make(map[string][]map[string]interface{}, len(tables))
+	for _, table := range tables {
+		rows := []map[string]interface{}{}
+		var order []string
+		err := sqlitex.Execute(conn, "SELECT count(*) FROM pragma_table_xinfo(?) WHERE hidden = 0;", &sqlitex.ExecOptions{
+			Args: []interface{}{table},
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				for i := 1; i <= stmt.ColumnInt(0); i++ {
+					order = append(order, fmt.Sprint(i))
+				}
+				return nil
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read columns of %s: %w", table, err)
+		}
+		if len(order) == 0 {
+			return nil, fmt.Errorf("table %s does not exist", table)
+		}
+		query := fmt.Sprintf("SELECT * FROM %s ORDER BY %s;", sqliteutils.QuoteIdentifier(table), strings.Join(order, ", "))
+		err = sqlitex.Execute(conn, query, &sqlitex.ExecOptions{
+			ResultFunc: func(stmt *sqlite.Stmt) error {
+				row := make(map[string]interface{}, stmt.ColumnCount())
+				for i := 0; i < stmt.ColumnCount(); i++ {
+					row[stmt.ColumnName(i)] = columnValue(stmt, i)
+				}
+				rows = append(rows, row)
+				return nil
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to dump %s: %w", table, err)
+		}
+		dump[table] = rows
+	}
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
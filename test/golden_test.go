@@ -0,0 +1,37 @@
+package test_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAssertGolden(t *testing.T) {
+	ctx := context.Background()
+	const migration = `
+		CREATE TABLE accounts (id INTEGER PRIMARY KEY, owner TEXT, balance REAL);
+		CREATE TABLE transfers (src INTEGER, dst INTEGER, amount REAL, memo BLOB);
+	`
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	require.NoError(t, exec.ExecMultiTx(ctx, []string{
+		"INSERT INTO accounts (id, owner, balance) VALUES (2, 'bob', 5), (1, 'alice', 10);",
+		"UPDATE accounts SET balance = balance - 2.5 WHERE id = 1;",
+		"UPDATE accounts SET balance = balance + 2.5 WHERE id = 2;",
+		"INSERT INTO transfers VALUES (1, 2, 2.5, x'6869'), (1, 2, 0, NULL);",
+	}, make([]map[string]interface{}, 4), nil))
+
+	test.AssertGolden(ctx, t, "accounts", "transfers")
+	_, err := os.Stat(filepath.Join("testdata", "TestAssertGolden.golden.json"))
+	assert.NoError(t, err)
+}
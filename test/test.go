@@ -11,14 +11,15 @@ import (
 
 // Pool initializes an in-memory SQLite pool using dbpool.InitPool,
 // This function should be called at the beginning of each sqlite test.
-func Pool(ctx context.Context, t *testing.T, migration string, poolSize int) error {
+// opts are passed to InitPool.
+func Pool(ctx context.Context, t *testing.T, migration string, poolSize int, opts ...pool.Option) error {
 	t.Helper()
 
 	// Define the in-memory DSN for testing
-	uri := "file::memory:?mode=memory&cache=shared"
+	uri := sqliteutils.MemoryURI("").String()
 
 	// Initialize the pool using dbpool.InitPool with the in-memory URI
-	err := pool.InitPool(uri, poolSize)
+	err := pool.InitPool(uri, poolSize, opts...)
 	if err != nil {
 		return sqliteutils.FailedToInitPoolError(err, uri)
 	}
@@ -0,0 +1,26 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+)
+
+// InTx runs fn inside a transaction on the global pool and always rolls it
+// back, so the test leaves no rows behind and tests can share one migrated
+// database. The transaction is rolled back even if fn fails the test.
+func InTx(ctx context.Context, t *testing.T, fn func(tx *exec.Tx)) {
+	t.Helper()
+
+	tx, err := exec.Begin(ctx, exec.TxDeferred)
+	if err != nil {
+		t.Fatalf("failed to begin test transaction: %v", err)
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil {
+			t.Errorf("failed to roll back test transaction: %v", err)
+		}
+	}()
+	fn(tx)
+}
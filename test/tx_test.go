@@ -0,0 +1,47 @@
+package test_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInTx(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, "CREATE TABLE items (name TEXT);", 2))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	count := func(query func(string, map[string]interface{}, func([]string, []interface{})) error) int64 {
+		var n int64
+		require.NoError(t, query("SELECT count(*) FROM items;", nil, func(_ []string, values []interface{}) {
+			n = values[0].(int64)
+		}))
+		return n
+	}
+	outside := func(query string, params map[string]interface{}, rowFunc func([]string, []interface{})) error {
+		return exec.Query(ctx, query, params, rowFunc)
+	}
+
+	for _, name := range []string{"first", "second"} {
+		t.Run(name, func(t *testing.T) {
+			test.InTx(ctx, t, func(tx *exec.Tx) {
+				require.NoError(t, tx.Exec("INSERT INTO items (name) VALUES ($name);", map[string]interface{}{"$name": name}, nil))
+				assert.Equal(t, int64(1), count(tx.Query))
+			})
+		})
+	}
+	assert.Equal(t, int64(0), count(outside))
+
+	// WithTx commits when fn succeeds.
+	require.NoError(t, exec.WithTx(ctx, exec.TxImmediate, func(tx *exec.Tx) error {
+		return tx.Exec("INSERT INTO items (name) VALUES ('kept');", nil, nil)
+	}))
+	assert.Equal(t, int64(1), count(outside))
+}
@@ -0,0 +1,223 @@
+// Package tracing instruments this module's database operations with
+// OpenTelemetry spans. It is a module of its own so that only programs
+// tracing their database operations depend on OpenTelemetry.
+//
+// A Tracer mirrors the exec, blob and backup functions. Each call starts a
+// span as a child of the span in the caller's context, records the statement
+// text (truncated), the number of result rows and the time spent waiting for
+// a pooled connection, and marks the span as failed when the operation
+// returns an error.
+package tracing
+
+import (
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/dropsite-ai/sqliteutils/backup"
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+)
+
+// InstrumentationName identifies this package as the tracer's instrumentation scope.
+const InstrumentationName = "github.com/dropsite-ai/sqliteutils/tracing"
+
+// Span attribute keys.
+const (
+	AttrSystem     = attribute.Key("db.system")
+	AttrStatement  = attribute.Key("db.statement")
+	AttrStatements = attribute.Key("db.sqlite.statements")
+	AttrRows       = attribute.Key("db.sqlite.rows")
+	AttrPoolWait   = attribute.Key("db.sqlite.pool_wait_ms")
+	AttrTable      = attribute.Key("db.sql.table")
+	AttrBytes      = attribute.Key("db.sqlite.bytes")
+	AttrPath       = attribute.Key("db.sqlite.path")
+)
+
+// Options configures a Tracer.
+type Options struct {
+	// TracerProvider creates the tracer. Defaults to otel.GetTracerProvider().
+	TracerProvider trace.TracerProvider
+	// MaxStatementLength truncates db.statement. Defaults to 1000 bytes.
+	MaxStatementLength int
+}
+
+// Tracer runs database operations inside spans.
+type Tracer struct {
+	tracer             trace.Tracer
+	maxStatementLength int
+}
+
+// New returns a Tracer.
+func New(opts Options) *Tracer {
+	if opts.TracerProvider == nil {
+		opts.TracerProvider = otel.GetTracerProvider()
+	}
+	if opts.MaxStatementLength <= 0 {
+		opts.MaxStatementLength = 1000
+	}
+	return &Tracer{
+		tracer:             opts.TracerProvider.Tracer(InstrumentationName),
+		maxStatementLength: opts.MaxStatementLength,
+	}
+}
+
+// op is an operation in progress.
+type op struct {
+	span trace.Span
+
+	mu   sync.Mutex
+	wait time.Duration
+	rows int64
+}
+
+// start begins a span named name and returns a context that reports pool
+// waits to it.
+func (t *Tracer) start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, *op) {
+	attrs = append([]attribute.KeyValue{AttrSystem.String("sqlite")}, attrs...)
+	ctx, span := t.tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	o := &op{span: span}
+	ctx = pool.WithWaitObserver(ctx, func(wait time.Duration) {
+		o.mu.Lock()
+		o.wait += wait
+		o.mu.Unlock()
+	})
+	return ctx, o
+}
+
+func (o *op) row() {
+	o.mu.Lock()
+	o.rows++
+	o.mu.Unlock()
+}
+
+// end records the collected attributes and err and ends the span.
+func (o *op) end(err error, countRows bool) error {
+	o.mu.Lock()
+	if countRows {
+		o.span.SetAttributes(AttrRows.Int64(o.rows))
+	}
+	o.span.SetAttributes(AttrPoolWait.Float64(float64(o.wait) / float64(time.Millisecond)))
+	o.mu.Unlock()
+	if err != nil {
+		o.span.RecordError(err)
+		o.span.SetStatus(codes.Error, err.Error())
+	}
+	o.span.End()
+	return err
+}
+
+// statement truncates the statements for db.statement.
+func (t *Tracer) statement(queries ...string) attribute.KeyValue {
+	trimmed := make([]string, 0, len(queries))
+	for _, q := range queries {
+		if q = strings.TrimSpace(q); q != "" {
+			trimmed = append(trimmed, strings.TrimSuffix(q, ";"))
+		}
+	}
+	s := strings.Join(trimmed, "; ")
+	if len(s) > t.maxStatementLength {
+		s = s[:t.maxStatementLength] + "..."
+	}
+	return AttrStatement.String(s)
+}
+
+// Exec is exec.Exec inside a span.
+func (t *Tracer) Exec(ctx context.Context, query string, params map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	return t.ExecMulti(ctx, []string{query}, []map[string]interface{}{params}, resultFunc)
+}
+
+// ExecMulti is exec.ExecMulti inside a span.
+func (t *Tracer) ExecMulti(ctx context.Context, queries []string, params []map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	ctx, o := t.start(ctx, "sqliteutils.ExecMulti", t.statement(queries...), AttrStatements.Int(len(queries)))
+	return o.end(exec.ExecMulti(ctx, queries, params, o.counting(resultFunc)), true)
+}
+
+// ExecMultiTx is exec.ExecMultiTx inside a span.
+func (t *Tracer) ExecMultiTx(ctx context.Context, queries []string, params []map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	return t.ExecMultiTxMode(ctx, exec.TxDeferred, queries, params, resultFunc)
+}
+
+// ExecMultiTxMode is exec.ExecMultiTxMode inside a span.
+func (t *Tracer) ExecMultiTxMode(ctx context.Context, mode exec.TxMode, queries []string, params []map[string]interface{}, resultFunc func(int, map[string]interface{})) error {
+	ctx, o := t.start(ctx, "sqliteutils.ExecMultiTx", t.statement(queries...), AttrStatements.Int(len(queries)),
+		attribute.String("db.sqlite.tx_mode", string(mode)))
+	return o.end(exec.ExecMultiTxMode(ctx, mode, queries, params, o.counting(resultFunc)), true)
+}
+
+// Query is exec.Query inside a span.
+func (t *Tracer) Query(ctx context.Context, query string, params map[string]interface{}, rowFunc func(columns []string, values []interface{})) error {
+	ctx, o := t.start(ctx, "sqliteutils.Query", t.statement(query))
+	err := exec.Query(ctx, query, params, func(columns []string, values []interface{}) {
+		o.row()
+		if rowFunc != nil {
+			rowFunc(columns, values)
+		}
+	})
+	return o.end(err, true)
+}
+
+// counting wraps resultFunc to count rows.
+func (o *op) counting(resultFunc func(int, map[string]interface{})) func(int, map[string]interface{}) {
+	return func(index int, row map[string]interface{}) {
+		o.row()
+		if resultFunc != nil {
+			resultFunc(index, row)
+		}
+	}
+}
+
+// CreateBlob is exec.CreateBlob inside a span.
+func (t *Tracer) CreateBlob(ctx context.Context, table, column string, size int64, extraCols map[string]interface{}) (int64, error) {
+	ctx, o := t.start(ctx, "sqliteutils.CreateBlob", AttrTable.String(table), AttrBytes.Int64(size))
+	rowID, err := exec.CreateBlob(ctx, table, column, size, extraCols)
+	return rowID, o.end(err, false)
+}
+
+// WriteBlobChunk is exec.WriteBlobChunk inside a span.
+func (t *Tracer) WriteBlobChunk(ctx context.Context, table, column string, rowID, offset int64, data []byte) error {
+	ctx, o := t.start(ctx, "sqliteutils.WriteBlobChunk", AttrTable.String(table), AttrBytes.Int(len(data)))
+	return o.end(exec.WriteBlobChunk(ctx, table, column, rowID, offset, data), false)
+}
+
+// StreamReadBlob is exec.StreamReadBlob inside a span.
+func (t *Tracer) StreamReadBlob(ctx context.Context, table, column string, rowID, offset, length int64, w io.Writer) (int64, error) {
+	ctx, o := t.start(ctx, "sqliteutils.StreamReadBlob", AttrTable.String(table))
+	n, err := exec.StreamReadBlob(ctx, table, column, rowID, offset, length, w)
+	o.span.SetAttributes(AttrBytes.Int64(n))
+	return n, o.end(err, false)
+}
+
+// WriteBlobFromReader is exec.WriteBlobFromReader inside a span.
+func (t *Tracer) WriteBlobFromReader(ctx context.Context, table, column string, rowID, offset int64, r io.Reader) (int64, error) {
+	ctx, o := t.start(ctx, "sqliteutils.WriteBlobFromReader", AttrTable.String(table))
+	n, err := exec.WriteBlobFromReader(ctx, table, column, rowID, offset, r)
+	o.span.SetAttributes(AttrBytes.Int64(n))
+	return n, o.end(err, false)
+}
+
+// BackupDatabase is backup.BackupDatabase inside a span.
+func (t *Tracer) BackupDatabase(ctx context.Context, sourceDBPath, destDBPath string) error {
+	_, o := t.start(ctx, "sqliteutils.BackupDatabase", AttrPath.String(sourceDBPath))
+	return o.end(backup.BackupDatabase(sourceDBPath, destDBPath), false)
+}
+
+// Restore is backup.Restore inside a span.
+func (t *Tracer) Restore(ctx context.Context, backupPath, destPath string, opts *backup.RestoreOptions) error {
+	ctx, o := t.start(ctx, "sqliteutils.Restore", AttrPath.String(backupPath))
+	return o.end(backup.Restore(ctx, backupPath, destPath, opts), false)
+}
+
+// Verify is backup.Verify inside a span.
+func (t *Tracer) Verify(ctx context.Context, backupPath string, opts *backup.VerifyOptions) (*backup.VerifyReport, error) {
+	ctx, o := t.start(ctx, "sqliteutils.Verify", AttrPath.String(backupPath))
+	report, err := backup.Verify(ctx, backupPath, opts)
+	return report, o.end(err, false)
+}
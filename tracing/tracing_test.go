@@ -0,0 +1,112 @@
+package tracing_test
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/test"
+	"github.com/dropsite-ai/sqliteutils/tracing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+const migration = `
+	CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL);
+	CREATE TABLE files (id INTEGER PRIMARY KEY, data BLOB);
+	INSERT INTO users (name) VALUES ('Alice'), ('Bob');
+`
+
+func attrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	m := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes() {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestTracer(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, test.Pool(ctx, t, migration, 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tracer := tracing.New(tracing.Options{TracerProvider: provider, MaxStatementLength: 20})
+
+	// Spans are children of the caller's span.
+	parentCtx, parent := provider.Tracer("test").Start(ctx, "request")
+	rows := 0
+	require.NoError(t, tracer.Exec(parentCtx, "SELECT id, name FROM users ORDER BY id;", nil, func(int, map[string]interface{}) { rows++ }))
+	parent.End()
+	assert.Equal(t, 2, rows)
+
+	require.NoError(t, tracer.Query(ctx, "SELECT name FROM users", nil, nil))
+	assert.Error(t, tracer.ExecMultiTx(ctx, []string{"INSERT INTO users (name) VALUES ('Carol');", "INSERT INTO missing VALUES (1);"},
+		[]map[string]interface{}{nil, nil}, nil))
+
+	rowID, err := tracer.CreateBlob(ctx, "files", "data", 5, nil)
+	require.NoError(t, err)
+	require.NoError(t, tracer.WriteBlobChunk(ctx, "files", "data", rowID, 0, []byte("hello")))
+	var buf bytes.Buffer
+	_, err = tracer.StreamReadBlob(ctx, "files", "data", rowID, 0, -1, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", buf.String())
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 7)
+
+	exec := spans[0]
+	assert.Equal(t, "sqliteutils.ExecMulti", exec.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), exec.Parent().SpanID())
+	a := attrs(exec)
+	assert.Equal(t, "sqlite", a[tracing.AttrSystem].AsString())
+	assert.Equal(t, "SELECT id, name FROM...", a[tracing.AttrStatement].AsString())
+	assert.Equal(t, int64(2), a[tracing.AttrRows].AsInt64())
+	assert.Contains(t, a, tracing.AttrPoolWait)
+	assert.Equal(t, codes.Unset, exec.Status().Code)
+
+	assert.Equal(t, "sqliteutils.Query", spans[2].Name())
+	assert.Equal(t, int64(2), attrs(spans[2])[tracing.AttrRows].AsInt64())
+
+	failed := spans[3]
+	assert.Equal(t, "sqliteutils.ExecMultiTx", failed.Name())
+	assert.Equal(t, codes.Error, failed.Status().Code)
+	assert.True(t, strings.Contains(failed.Status().Description, "missing"), failed.Status().Description)
+	assert.Equal(t, "DEFERRED", attrs(failed)["db.sqlite.tx_mode"].AsString())
+
+	assert.Equal(t, "sqliteutils.StreamReadBlob", spans[6].Name())
+	assert.Equal(t, int64(5), attrs(spans[6])[tracing.AttrBytes].AsInt64())
+}
+
+func TestPoolWait(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool(filepath.Join(t.TempDir(), "wait.db"), 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	recorder := tracetest.NewSpanRecorder()
+	tracer := tracing.New(tracing.Options{TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))})
+
+	p, err := pool.GetPool()
+	require.NoError(t, err)
+	conn, err := p.Take(ctx)
+	require.NoError(t, err)
+	time.AfterFunc(50*time.Millisecond, func() { p.Put(conn) })
+
+	require.NoError(t, tracer.Exec(ctx, "SELECT 1;", nil, nil))
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.GreaterOrEqual(t, attrs(spans[0])[tracing.AttrPoolWait].AsFloat64(), 40.0)
+}
@@ -0,0 +1,162 @@
+package udf
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"zombiezen.com/go/sqlite"
+)
+
+// RegisterAggregates registers statistical aggregate functions, which can
+// also be used as window functions. They ignore NULL inputs and return NULL
+// when there are none.
+//
+//	median(x)         the middle value, or the mean of the two middle values
+//	percentile(x, p)  the pth percentile for p between 0 and 1, linearly
+//	                  interpolated between the closest values
+//	stddev(x)         the sample standard deviation; NULL for a single value
+//	mode(x)           the most frequent value, of any type; ties go to the
+//	                  value that reached the count first
+func RegisterAggregates() {
+	register := func(name string, nargs int, value func(a *sample) (sqlite.Value, error)) {
+		Register(name, &sqlite.FunctionImpl{
+			NArgs:         nargs,
+			Deterministic: true,
+			AllowIndirect: true,
+			MakeAggregate: func(ctx sqlite.Context) (sqlite.AggregateFunction, error) {
+				return &sample{value: value}, nil
+			},
+		})
+	}
+	register("median", 1, func(a *sample) (sqlite.Value, error) {
+		return a.percentile(0.5), nil
+	})
+	register("percentile", 2, func(a *sample) (sqlite.Value, error) {
+		if a.p < 0 || a.p > 1 {
+			return sqlite.Value{}, errors.New("percentile must be between 0 and 1")
+		}
+		return a.percentile(a.p), nil
+	})
+	register("stddev", 1, func(a *sample) (sqlite.Value, error) {
+		if len(a.values) < 2 {
+			return sqlite.Value{}, nil
+		}
+		var sum float64
+		for _, v := range a.values {
+			sum += v.Float()
+		}
+		mean := sum / float64(len(a.values))
+		var squares float64
+		for _, v := range a.values {
+			squares += (v.Float() - mean) * (v.Float() - mean)
+		}
+		return sqlite.FloatValue(math.Sqrt(squares / float64(len(a.values)-1))), nil
+	})
+	register("mode", 1, func(a *sample) (sqlite.Value, error) {
+		counts := make(map[valueKey]int)
+		var best sqlite.Value
+		bestCount := 0
+		for _, v := range a.values {
+			k := keyOf(v)
+			counts[k]++
+			if counts[k] > bestCount {
+				best, bestCount = v, counts[k]
+			}
+		}
+		return best, nil
+	})
+}
+
+// sample is the state of a statistical aggregate: the non-NULL values in
+// the current group or window, in the order they were added.
+type sample struct {
+	values []sqlite.Value
+	p      float64
+	value  func(a *sample) (sqlite.Value, error)
+}
+
+func (a *sample) Step(ctx sqlite.Context, rowArgs []sqlite.Value) error {
+	if len(rowArgs) > 1 {
+		a.p = rowArgs[1].Float()
+	}
+	if v, ok := copyValue(rowArgs[0]); ok {
+		a.values = append(a.values, v)
+	}
+	return nil
+}
+
+func (a *sample) WindowInverse(ctx sqlite.Context, rowArgs []sqlite.Value) error {
+	if rowArgs[0].Type() == sqlite.TypeNull {
+		return nil
+	}
+	// Rows leave a window in the order they entered it.
+	k := keyOf(rowArgs[0])
+	for i, v := range a.values {
+		if keyOf(v) == k {
+			a.values = append(a.values[:i], a.values[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
+
+func (a *sample) WindowValue(ctx sqlite.Context) (sqlite.Value, error) {
+	if len(a.values) == 0 {
+		return sqlite.Value{}, nil
+	}
+	return a.value(a)
+}
+
+func (a *sample) Finalize(ctx sqlite.Context) {}
+
+// percentile returns the pth percentile of the values as numbers.
+func (a *sample) percentile(p float64) sqlite.Value {
+	sorted := make([]float64, len(a.values))
+	for i, v := range a.values {
+		sorted[i] = v.Float()
+	}
+	sort.Float64s(sorted)
+	rank := p * float64(len(sorted)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return sqlite.FloatValue(sorted[lo] + (sorted[hi]-sorted[lo])*(rank-float64(lo)))
+}
+
+// valueKey identifies a value by type and content.
+type valueKey struct {
+	typ  sqlite.ColumnType
+	i    int64
+	f    float64
+	text string
+}
+
+func keyOf(v sqlite.Value) valueKey {
+	switch v.Type() {
+	case sqlite.TypeInteger:
+		return valueKey{typ: sqlite.TypeInteger, i: v.Int64()}
+	case sqlite.TypeFloat:
+		return valueKey{typ: sqlite.TypeFloat, f: v.Float()}
+	case sqlite.TypeBlob:
+		return valueKey{typ: sqlite.TypeBlob, text: string(v.Blob())}
+	default:
+		return valueKey{typ: v.Type(), text: v.Text()}
+	}
+}
+
+// copyValue copies an argument so it outlives the call, reporting false for
+// NULL.
+func copyValue(v sqlite.Value) (sqlite.Value, bool) {
+	switch v.Type() {
+	case sqlite.TypeInteger:
+		return sqlite.IntegerValue(v.Int64()), true
+	case sqlite.TypeFloat:
+		return sqlite.FloatValue(v.Float()), true
+	case sqlite.TypeText:
+		return sqlite.TextValue(v.Text()), true
+	case sqlite.TypeBlob:
+		return sqlite.BlobValue(append([]byte(nil), v.Blob()...)), true
+	default:
+		return sqlite.Value{}, false
+	}
+}
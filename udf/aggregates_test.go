@@ -0,0 +1,44 @@
+package udf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/udf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregates(t *testing.T) {
+	ctx := context.Background()
+	udf.RegisterAggregates()
+	require.NoError(t, pool.InitPool("file::memory:?mode=memory", 1, pool.WithPrepareConn(udf.PrepareConn)))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.ExecMulti(ctx, []string{
+		"CREATE TABLE samples (id INTEGER PRIMARY KEY, x REAL, tag TEXT);",
+		"INSERT INTO samples (x, tag) VALUES (2, 'a'), (4, 'b'), (4, 'b'), (4, 'c'), (5, 'c'), (5, 'c'), (7, NULL), (9, NULL), (NULL, NULL);",
+	}, make([]map[string]interface{}, 2), nil))
+
+	var row []interface{}
+	require.NoError(t, exec.Query(ctx,
+		"SELECT median(x), percentile(x, 0.25), percentile(x, 1), stddev(x), mode(x), mode(tag) FROM samples;", nil,
+		func(_ []string, values []interface{}) { row = values }))
+	assert.Equal(t, []interface{}{4.5, 4.0, 9.0, float64(2.138089935299395), 4.0, "c"}, row)
+
+	// Empty groups are NULL.
+	row = nil
+	require.NoError(t, exec.Query(ctx, "SELECT median(x), stddev(x), mode(x) FROM samples WHERE id > 100;", nil,
+		func(_ []string, values []interface{}) { row = values }))
+	assert.Equal(t, []interface{}{nil, nil, nil}, row)
+
+	// As window functions over a sliding frame.
+	var medians []interface{}
+	require.NoError(t, exec.Query(ctx,
+		"SELECT median(x) OVER (ORDER BY id ROWS BETWEEN 1 PRECEDING AND CURRENT ROW) FROM samples WHERE id <= 5 ORDER BY id;", nil,
+		func(_ []string, values []interface{}) { medians = append(medians, values[0]) }))
+	assert.Equal(t, []interface{}{2.0, 3.0, 4.0, 4.0, 4.5}, medians)
+}
@@ -0,0 +1,126 @@
+package udf
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+	"zombiezen.com/go/sqlite"
+)
+
+// Collation names.
+const (
+	// NoCaseUnicode compares text by the Unicode collation algorithm,
+	// ignoring case, so that "émile" sorts between "Emil" and "Emma" and
+	// equals "ÉMILE".
+	NoCaseUnicode = "NOCASE_UNICODE"
+	// NaturalSort is NoCaseUnicode with runs of digits compared by numeric
+	// value, so that "file2" sorts before "file10".
+	NaturalSort = "NATURALSORT"
+)
+
+// SetCollations creates the NOCASE_UNICODE and NATURALSORT collations on
+// conn. With overrideNoCase, the built-in NOCASE collation is replaced by
+// NOCASE_UNICODE as well, so that columns and expressions already declared
+// COLLATE NOCASE become Unicode-aware; indexes on such columns must then be
+// rebuilt with REINDEX NOCASE. Pooled connections have the collations
+// already.
+func SetCollations(conn *sqlite.Conn, overrideNoCase bool) error {
+	// Collators are not safe for concurrent use, and a connection is only
+	// used by one goroutine at a time.
+	noCase := newNoCaseCollator()
+	if err := conn.SetCollation(NoCaseUnicode, noCase); err != nil {
+		return err
+	}
+	if err := conn.SetCollation(NaturalSort, newNaturalCollator()); err != nil {
+		return err
+	}
+	if overrideNoCase {
+		return conn.SetCollation("NOCASE", newNoCaseCollator())
+	}
+	return nil
+}
+
+func newNoCaseCollator() sqlite.CollatingFunc {
+	c := collate.New(language.Und, collate.IgnoreCase)
+	return c.CompareString
+}
+
+func newNaturalCollator() sqlite.CollatingFunc {
+	c := collate.New(language.Und, collate.IgnoreCase)
+	return func(a, b string) int {
+		for a != "" && b != "" {
+			da, db := isDigitAt(a), isDigitAt(b)
+			var sa, sb string
+			sa, a = splitRun(a, da)
+			sb, b = splitRun(b, db)
+			var cmp int
+			if da && db {
+				cmp = compareNumbers(sa, sb)
+			} else {
+				cmp = c.CompareString(sa, sb)
+			}
+			if cmp != 0 {
+				return cmp
+			}
+		}
+		switch {
+		case a == "" && b == "":
+			return 0
+		case a == "":
+			return -1
+		default:
+			return 1
+		}
+	}
+}
+
+func isDigitAt(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r >= '0' && r <= '9'
+}
+
+// splitRun splits s after its leading run of ASCII digits, or of other
+// characters when digits is false.
+func splitRun(s string, digits bool) (run, rest string) {
+	for i, r := range s {
+		if (r >= '0' && r <= '9') != digits {
+			return s[:i], s[i:]
+		}
+	}
+	return s, ""
+}
+
+// compareNumbers compares two runs of digits by numeric value, then by
+// length so that "01" sorts after "1".
+func compareNumbers(a, b string) int {
+	ta, tb := trimZeros(a), trimZeros(b)
+	switch {
+	case len(ta) != len(tb):
+		return sign(len(ta) - len(tb))
+	case ta != tb:
+		if ta < tb {
+			return -1
+		}
+		return 1
+	default:
+		return sign(len(a) - len(b))
+	}
+}
+
+func trimZeros(s string) string {
+	for len(s) > 1 && s[0] == '0' {
+		s = s[1:]
+	}
+	return s
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+package udf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollations(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool("file::memory:?mode=memory", 1, pool.WithUnicodeNoCase()))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+	require.NoError(t, exec.ExecMulti(ctx, []string{
+		"CREATE TABLE names (name TEXT, legacy TEXT COLLATE NOCASE);",
+		"INSERT INTO names (name, legacy) VALUES ('Emma', 'Émile'), ('émile', 'x'), ('Emil', 'y'), ('file10', 'z'), ('file2', 'w'), ('File1', 'v');",
+	}, make([]map[string]interface{}, 2), nil))
+
+	column := func(query string) []interface{} {
+		var out []interface{}
+		require.NoError(t, exec.Query(ctx, query, nil, func(_ []string, values []interface{}) { out = append(out, values[0]) }))
+		return out
+	}
+	assert.Equal(t, []interface{}{"Emil", "émile", "Emma", "File1", "file10", "file2"},
+		column("SELECT name FROM names ORDER BY name COLLATE NOCASE_UNICODE;"))
+	assert.Equal(t, []interface{}{"Emil", "émile", "Emma", "File1", "file2", "file10"},
+		column("SELECT name FROM names ORDER BY name COLLATE NATURALSORT;"))
+	assert.Equal(t, []interface{}{int64(1)}, column("SELECT 'ÉMILE' = 'émile' COLLATE NOCASE_UNICODE;"))
+
+	// WithUnicodeNoCase makes columns declared COLLATE NOCASE Unicode-aware.
+	assert.Equal(t, []interface{}{"Émile"}, column("SELECT legacy FROM names WHERE legacy = 'émile';"))
+}
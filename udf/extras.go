@@ -0,0 +1,191 @@
+package udf
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"zombiezen.com/go/sqlite"
+)
+
+// RegisterExtras registers a bundle of date and string functions missing
+// from SQLite. Each returns NULL when any argument is NULL.
+//
+//	date_trunc(unit, ts)     ts truncated to the start of its second, minute,
+//	                         hour, day, week (starting Monday), month, quarter
+//	                         or year, in the same form as ts: Unix seconds for
+//	                         a number, 'YYYY-MM-DD HH:MM:SS' for text
+//	format_bytes(n)          n bytes in binary units, such as '1.5 KiB'
+//	slugify(text)            text lowercased with runs of anything but
+//	                         letters and digits replaced by a hyphen
+//	levenshtein(a, b)        the edit distance between a and b, in characters
+//	split_part(text, sep, n) the nth field of text split on sep, counting from
+//	                         1, or from the end when n is negative; '' when
+//	                         there is no such field
+func RegisterExtras() {
+	register := func(name string, nargs int, fn func(args []sqlite.Value) (sqlite.Value, error)) {
+		Register(name, &sqlite.FunctionImpl{
+			NArgs:         nargs,
+			Deterministic: true,
+			AllowIndirect: true,
+			Scalar: func(ctx sqlite.Context, args []sqlite.Value) (sqlite.Value, error) {
+				for _, arg := range args {
+					if arg.Type() == sqlite.TypeNull {
+						return sqlite.Value{}, nil
+					}
+				}
+				return fn(args)
+			},
+		})
+	}
+	register("date_trunc", 2, func(args []sqlite.Value) (sqlite.Value, error) {
+		return dateTrunc(args[0].Text(), args[1])
+	})
+	register("format_bytes", 1, func(args []sqlite.Value) (sqlite.Value, error) {
+		return sqlite.TextValue(FormatBytes(args[0].Int64())), nil
+	})
+	register("slugify", 1, func(args []sqlite.Value) (sqlite.Value, error) {
+		return sqlite.TextValue(Slugify(args[0].Text())), nil
+	})
+	register("levenshtein", 2, func(args []sqlite.Value) (sqlite.Value, error) {
+		return sqlite.IntegerValue(int64(Levenshtein(args[0].Text(), args[1].Text()))), nil
+	})
+	register("split_part", 3, func(args []sqlite.Value) (sqlite.Value, error) {
+		return sqlite.TextValue(SplitPart(args[0].Text(), args[1].Text(), args[2].Int())), nil
+	})
+}
+
+// sqliteTimeLayout is the layout of SQLite's datetime().
+const sqliteTimeLayout = "2006-01-02 15:04:05"
+
+// timeLayouts are the text forms date_trunc accepts, as produced by SQLite's
+// date and time functions and by RFC 3339.
+var timeLayouts = []string{sqliteTimeLayout, "2006-01-02 15:04:05.999", "2006-01-02T15:04:05", "2006-01-02T15:04:05.999", time.RFC3339Nano, "2006-01-02 15:04", "2006-01-02"}
+
+func dateTrunc(unit string, ts sqlite.Value) (sqlite.Value, error) {
+	if ts.Type() == sqlite.TypeInteger || ts.Type() == sqlite.TypeFloat {
+		t, err := TruncateTime(unit, time.Unix(ts.Int64(), 0).UTC())
+		if err != nil {
+			return sqlite.Value{}, err
+		}
+		return sqlite.IntegerValue(t.Unix()), nil
+	}
+	for _, layout := range timeLayouts {
+		if parsed, err := time.Parse(layout, ts.Text()); err == nil {
+			t, err := TruncateTime(unit, parsed)
+			if err != nil {
+				return sqlite.Value{}, err
+			}
+			return sqlite.TextValue(t.Format(sqliteTimeLayout)), nil
+		}
+	}
+	return sqlite.Value{}, fmt.Errorf("date_trunc: cannot parse time %q", ts.Text())
+}
+
+// TruncateTime returns t truncated to the start of unit: second, minute,
+// hour, day, week (starting Monday), month, quarter or year.
+func TruncateTime(unit string, t time.Time) (time.Time, error) {
+	y, m, d := t.Date()
+	switch strings.ToLower(unit) {
+	case "second":
+		return t.Truncate(time.Second), nil
+	case "minute":
+		return time.Date(y, m, d, t.Hour(), t.Minute(), 0, 0, t.Location()), nil
+	case "hour":
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location()), nil
+	case "day":
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location()), nil
+	case "week":
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location()), nil
+	case "month":
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location()), nil
+	case "quarter":
+		return time.Date(y, m-(m-1)%3, 1, 0, 0, 0, 0, t.Location()), nil
+	case "year":
+		return time.Date(y, 1, 1, 0, 0, 0, 0, t.Location()), nil
+	default:
+		return time.Time{}, fmt.Errorf("date_trunc: unknown unit %q", unit)
+	}
+}
+
+// FormatBytes formats n bytes in binary units with one decimal, such as
+// "512 B", "1.5 KiB" or "2.0 GiB".
+func FormatBytes(n int64) string {
+	const unit = 1024
+	abs := n
+	if abs < 0 {
+		abs = -abs
+	}
+	if abs < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for q := abs / unit; q >= unit && exp < 5; q /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Slugify lowercases s and replaces each run of characters other than
+// letters and digits with a single hyphen, trimming hyphens at either end.
+func Slugify(s string) string {
+	var b strings.Builder
+	pending := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pending && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pending = false
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		pending = true
+	}
+	return b.String()
+}
+
+// Levenshtein returns the number of single-character insertions, deletions
+// and substitutions needed to turn a into b.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// SplitPart splits s on sep and returns field n, counting from 1, or from
+// the end when n is negative. It returns "" when there is no such field.
+func SplitPart(s, sep string, n int) string {
+	if sep == "" {
+		if n == 1 || n == -1 {
+			return s
+		}
+		return ""
+	}
+	fields := strings.Split(s, sep)
+	switch {
+	case n > 0 && n <= len(fields):
+		return fields[n-1]
+	case n < 0 && -n <= len(fields):
+		return fields[len(fields)+n]
+	default:
+		return ""
+	}
+}
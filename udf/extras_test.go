@@ -0,0 +1,41 @@
+package udf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/udf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtras(t *testing.T) {
+	ctx := context.Background()
+	udf.RegisterExtras()
+	require.NoError(t, pool.InitPool("file::memory:?mode=memory", 1, pool.WithPrepareConn(udf.PrepareConn)))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	for query, want := range map[string]interface{}{
+		"SELECT date_trunc('month', '2024-05-17 13:45:10')": "2024-05-01 00:00:00",
+		"SELECT date_trunc('week', '2024-05-19T08:00:00Z')": "2024-05-13 00:00:00",
+		"SELECT date_trunc('quarter', '2024-05-17')":        "2024-04-01 00:00:00",
+		"SELECT date_trunc('hour', 1715953510)":             int64(1715950800),
+		"SELECT format_bytes(512)":                          "512 B",
+		"SELECT format_bytes(1536)":                         "1.5 KiB",
+		"SELECT format_bytes(3 * 1024 * 1024 * 1024)":       "3.0 GiB",
+		"SELECT slugify('  Hello, Wörld! 2024 ')":           "hello-wörld-2024",
+		"SELECT levenshtein('kitten', 'sitting')":           int64(3),
+		"SELECT split_part('a,b,c', ',', 2)":                "b",
+		"SELECT split_part('a,b,c', ',', -1)":               "c",
+		"SELECT split_part('a,b,c', ',', 4)":                "",
+		"SELECT split_part(NULL, ',', 1)":                   nil,
+	} {
+		var got interface{}
+		require.NoError(t, exec.Query(ctx, query, nil, func(_ []string, values []interface{}) { got = values[0] }), query)
+		assert.Equal(t, want, got, query)
+	}
+}
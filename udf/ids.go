@@ -0,0 +1,109 @@
+package udf
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"zombiezen.com/go/sqlite"
+)
+
+// RegisterIDs registers the uuid4(), uuid7() and ulid() SQL functions,
+// which return the same text as NewUUID4, NewUUID7 and NewULID, so that
+// DEFAULT expressions and inserts can generate identifiers:
+//
+//	CREATE TABLE events (id TEXT PRIMARY KEY DEFAULT (uuid7()), ...)
+func RegisterIDs() {
+	for name, fn := range map[string]func() string{
+		"uuid4": NewUUID4,
+		"uuid7": NewUUID7,
+		"ulid":  NewULID,
+	} {
+		fn := fn
+		Register(name, &sqlite.FunctionImpl{
+			NArgs:         0,
+			AllowIndirect: true,
+			Scalar: func(ctx sqlite.Context, args []sqlite.Value) (sqlite.Value, error) {
+				return sqlite.TextValue(fn()), nil
+			},
+		})
+	}
+}
+
+// NewUUID4 returns a random (version 4) UUID in its canonical lowercase form.
+func NewUUID4() string {
+	var u [16]byte
+	randomBytes(u[:])
+	return formatUUID(u, 4)
+}
+
+// NewUUID7 returns a time-ordered (version 7) UUID in its canonical lowercase
+// form: the Unix time in milliseconds followed by random bits, so identifiers
+// sort roughly by creation time.
+func NewUUID7() string {
+	var u [16]byte
+	putMillis(u[:6], time.Now())
+	randomBytes(u[6:])
+	return formatUUID(u, 7)
+}
+
+// NewULID returns a ULID: 26 characters of Crockford base32 encoding the Unix
+// time in milliseconds followed by 80 random bits, which sort by creation
+// time.
+func NewULID() string {
+	var u [16]byte
+	putMillis(u[:6], time.Now())
+	randomBytes(u[6:])
+
+	const alphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+	// 128 bits are encoded as 26 five-bit characters, the first holding
+	// only the top three bits.
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		var carry byte
+		// Divide the 128-bit big-endian number in u by 32 in place.
+		for j := 0; j < 16; j++ {
+			acc := uint16(carry)<<8 | uint16(u[j])
+			u[j] = byte(acc / 32)
+			carry = byte(acc % 32)
+		}
+		out[i] = alphabet[carry]
+	}
+	return string(out[:])
+}
+
+// putMillis stores the Unix time of t in milliseconds as a 48-bit big-endian
+// integer in b.
+func putMillis(b []byte, t time.Time) {
+	ms := uint64(t.UnixMilli())
+	for i := 5; i >= 0; i-- {
+		b[i] = byte(ms)
+		ms >>= 8
+	}
+}
+
+// randomBytes fills b from crypto/rand, which does not fail on supported
+// platforms.
+func randomBytes(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+}
+
+// formatUUID sets the version and RFC 9562 variant bits of u and formats it
+// as 8-4-4-4-12 hex digits.
+func formatUUID(u [16]byte, version byte) string {
+	u[6] = u[6]&0x0f | version<<4
+	u[8] = u[8]&0x3f | 0x80
+	var out [36]byte
+	hex.Encode(out[0:8], u[0:4])
+	out[8] = '-'
+	hex.Encode(out[9:13], u[4:6])
+	out[13] = '-'
+	hex.Encode(out[14:18], u[6:8])
+	out[18] = '-'
+	hex.Encode(out[19:23], u[8:10])
+	out[23] = '-'
+	hex.Encode(out[24:], u[10:])
+	return string(out[:])
+}
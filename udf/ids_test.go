@@ -0,0 +1,55 @@
+package udf_test
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/dropsite-ai/sqliteutils/udf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	uuid4Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	uuid7Pattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-7[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	ulidPattern  = regexp.MustCompile(`^[0-7][0-9A-HJKMNP-TV-Z]{25}$`)
+)
+
+func TestIDs(t *testing.T) {
+	assert.Regexp(t, uuid4Pattern, udf.NewUUID4())
+	assert.Regexp(t, uuid7Pattern, udf.NewUUID7())
+	assert.Regexp(t, ulidPattern, udf.NewULID())
+
+	// Time-ordered identifiers sort by creation time.
+	first, firstULID := udf.NewUUID7(), udf.NewULID()
+	time.Sleep(2 * time.Millisecond)
+	assert.Less(t, first, udf.NewUUID7())
+	assert.Less(t, firstULID, udf.NewULID())
+
+	ctx := context.Background()
+	udf.RegisterIDs()
+	assert.Subset(t, udf.Registered(), []string{"ulid", "uuid4", "uuid7"})
+	require.NoError(t, pool.InitPool("file::memory:?mode=memory", 1, pool.WithPrepareConn(udf.PrepareConn)))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	require.NoError(t, exec.ExecMulti(ctx, []string{
+		"CREATE TABLE events (id TEXT PRIMARY KEY DEFAULT (uuid7()), ref TEXT, name TEXT);",
+		"INSERT INTO events (ref, name) VALUES (ulid(), 'a'), (uuid4(), 'b');",
+	}, make([]map[string]interface{}, 2), nil))
+
+	var rows []map[string]interface{}
+	require.NoError(t, exec.Exec(ctx, "SELECT id, ref FROM events ORDER BY name;", nil, func(_ int, row map[string]interface{}) {
+		rows = append(rows, row)
+	}))
+	require.Len(t, rows, 2)
+	assert.Regexp(t, uuid7Pattern, rows[0]["id"])
+	assert.NotEqual(t, rows[0]["id"], rows[1]["id"])
+	assert.Regexp(t, ulidPattern, rows[0]["ref"])
+	assert.Regexp(t, uuid4Pattern, rows[1]["ref"])
+}
@@ -0,0 +1,77 @@
+package udf
+
+import (
+	"container/list"
+	"regexp"
+	"sync"
+
+	"zombiezen.com/go/sqlite"
+)
+
+// RegexpCacheSize is the number of compiled patterns Regexp keeps.
+const RegexpCacheSize = 128
+
+// Regexp implements regexp(pattern, text), which SQLite calls for
+// text REGEXP pattern, with Go regexp syntax. It returns NULL when either
+// argument is NULL. Compiled patterns are shared by all connections in a
+// least recently used cache. Pooled connections have it already.
+func Regexp() *sqlite.FunctionImpl {
+	return &sqlite.FunctionImpl{
+		NArgs:         2,
+		Deterministic: true,
+		AllowIndirect: true,
+		Scalar: func(ctx sqlite.Context, args []sqlite.Value) (sqlite.Value, error) {
+			if args[0].Type() == sqlite.TypeNull || args[1].Type() == sqlite.TypeNull {
+				return sqlite.Value{}, nil
+			}
+			re, err := patterns.get(args[0].Text())
+			if err != nil {
+				return sqlite.Value{}, err
+			}
+			if re.MatchString(args[1].Text()) {
+				return sqlite.IntegerValue(1), nil
+			}
+			return sqlite.IntegerValue(0), nil
+		},
+	}
+}
+
+var patterns = &patternCache{entries: make(map[string]*list.Element), lru: list.New()}
+
+// patternCache is a least recently used cache of compiled patterns.
+type patternCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type patternEntry struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+func (c *patternCache) get(pattern string) (*regexp.Regexp, error) {
+	c.mu.Lock()
+	if elem, ok := c.entries[pattern]; ok {
+		c.lru.MoveToFront(elem)
+		c.mu.Unlock()
+		return elem.Value.(*patternEntry).re, nil
+	}
+	c.mu.Unlock()
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[pattern]; !ok {
+		c.entries[pattern] = c.lru.PushFront(&patternEntry{pattern: pattern, re: re})
+		for c.lru.Len() > RegexpCacheSize {
+			oldest := c.lru.Remove(c.lru.Back()).(*patternEntry)
+			delete(c.entries, oldest.pattern)
+		}
+	}
+	return re, nil
+}
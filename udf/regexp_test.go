@@ -0,0 +1,37 @@
+package udf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/dropsite-ai/sqliteutils/exec"
+	"github.com/dropsite-ai/sqliteutils/pool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexp(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, pool.InitPool("file::memory:?mode=memory", 1))
+	defer func() {
+		assert.NoError(t, pool.ClosePool())
+	}()
+
+	require.NoError(t, exec.ExecMulti(ctx, []string{
+		"CREATE TABLE users (email TEXT);",
+		"INSERT INTO users (email) VALUES ('ada@example.com'), ('bob@test.org'), (NULL);",
+	}, make([]map[string]interface{}, 2), nil))
+
+	var emails []interface{}
+	require.NoError(t, exec.Query(ctx, "SELECT email FROM users WHERE email REGEXP :pattern;",
+		map[string]interface{}{":pattern": `^[a-z]+@example\.com$`}, func(_ []string, values []interface{}) {
+			emails = append(emails, values[0])
+		}))
+	assert.Equal(t, []interface{}{"ada@example.com"}, emails)
+
+	var matches []interface{}
+	require.NoError(t, exec.Query(ctx, "SELECT email REGEXP '\\.org$' FROM users;", nil, func(_ []string, values []interface{}) {
+		matches = append(matches, values[0])
+	}))
+	assert.Equal(t, []interface{}{int64(0), int64(1), nil}, matches)
+}
@@ -0,0 +1,54 @@
+// Package udf collects application-defined SQL functions in a registry and
+// creates them on every pooled connection.
+//
+// Functions are added with Register, or in bundles such as RegisterIDs, and
+// installed by passing PrepareConn to pool.WithPrepareConn. Register
+// functions before the pool opens its first connection; connections that
+// are already open keep the functions they were created with.
+package udf
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"zombiezen.com/go/sqlite"
+)
+
+var (
+	registry     = make(map[string]*sqlite.FunctionImpl)
+	registryLock sync.Mutex
+)
+
+// Register adds the SQL function name to the registry, replacing any
+// function registered under the same name.
+func Register(name string, impl *sqlite.FunctionImpl) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	registry[name] = impl
+}
+
+// Registered returns the names of the registered functions in sorted order.
+func Registered() []string {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// PrepareConn creates every registered function on conn. Pass it to
+// pool.WithPrepareConn.
+func PrepareConn(conn *sqlite.Conn) error {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	for name, impl := range registry {
+		if err := conn.CreateFunction(name, impl); err != nil {
+			return fmt.Errorf("failed to create function %s: %w", name, err)
+		}
+	}
+	return nil
+}
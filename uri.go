@@ -0,0 +1,177 @@
+package sqliteutils
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// URI describes an SQLite database URI, as accepted by pool.InitPool. See
+// https://sqlite.org/uri.html.
+type URI struct {
+	// Path is the database file, or ":memory:" for an in-memory database.
+	Path string
+	// Mode is "ro", "rw", "rwc" or "memory". Empty leaves it to the open flags.
+	Mode string
+	// Cache is "shared" or "private". Connections to an in-memory database
+	// see the same data only with a shared cache.
+	Cache string
+	// Immutable declares that the file cannot change, so SQLite skips locking
+	// and change detection.
+	Immutable bool
+	// NoLock disables file locking.
+	NoLock bool
+	// NoPSOW disables the powersafe overwrite assumption.
+	NoPSOW bool
+	// VFS names the VFS to open the database with.
+	VFS string
+	// BusyTimeout is how long a connection retries when the database is
+	// locked. It is not an SQLite parameter: it is encoded as _busy_timeout
+	// in milliseconds and applied by the pool package.
+	BusyTimeout time.Duration
+}
+
+// MemoryURI returns the URI of the shared-cache in-memory database name,
+// which every connection of a pool opened with it sees. Databases with
+// different names are distinct.
+func MemoryURI(name string) URI {
+	if name == "" {
+		name = ":memory:"
+	}
+	return URI{Path: name, Mode: "memory", Cache: "shared"}
+}
+
+// String returns u as a file: URI.
+func (u URI) String() string {
+	var params []string
+	add := func(key, value string) {
+		params = append(params, key+"="+url.QueryEscape(value))
+	}
+	if u.VFS != "" {
+		add("vfs", u.VFS)
+	}
+	if u.Mode != "" {
+		add("mode", u.Mode)
+	}
+	if u.Cache != "" {
+		add("cache", u.Cache)
+	}
+	if u.Immutable {
+		add("immutable", "1")
+	}
+	if u.NoLock {
+		add("nolock", "1")
+	}
+	if u.NoPSOW {
+		add("psow", "0")
+	}
+	if u.BusyTimeout > 0 {
+		add("_busy_timeout", strconv.FormatInt(u.BusyTimeout.Milliseconds(), 10))
+	}
+	s := "file:" + uriPathEscaper.Replace(u.Path)
+	if len(params) > 0 {
+		s += "?" + strings.Join(params, "&")
+	}
+	return s
+}
+
+var uriPathEscaper = strings.NewReplacer("%", "%25", "?", "%3f", "#", "%23")
+
+// Validate reports parameters of u that SQLite would reject.
+func (u URI) Validate() error {
+	switch u.Mode {
+	case "", "ro", "rw", "rwc", "memory":
+	default:
+		return fmt.Errorf("invalid URI mode %q: use ro, rw, rwc or memory", u.Mode)
+	}
+	switch u.Cache {
+	case "", "shared", "private":
+	default:
+		return fmt.Errorf("invalid URI cache %q: use shared or private", u.Cache)
+	}
+	if u.BusyTimeout < 0 {
+		return fmt.Errorf("invalid URI busy timeout %v", u.BusyTimeout)
+	}
+	return nil
+}
+
+// ParseURI parses an SQLite file: URI or a plain file path. Unknown
+// parameters are an error.
+func ParseURI(s string) (URI, error) {
+	if !strings.HasPrefix(s, "file:") {
+		return URI{Path: s}, nil
+	}
+	rest := strings.TrimPrefix(s, "file:")
+	if i := strings.IndexByte(rest, '#'); i >= 0 {
+		rest = rest[:i]
+	}
+	rawPath, rawQuery, _ := strings.Cut(rest, "?")
+	if strings.HasPrefix(rawPath, "//") {
+		authority, path, _ := strings.Cut(rawPath[2:], "/")
+		if authority != "" && authority != "localhost" {
+			return URI{}, fmt.Errorf("invalid URI %q: authority must be empty or localhost", s)
+		}
+		rawPath = "/" + path
+	}
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		return URI{}, fmt.Errorf("invalid URI path %q: %w", rawPath, err)
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return URI{}, fmt.Errorf("invalid URI query %q: %w", rawQuery, err)
+	}
+
+	u := URI{Path: path}
+	var unknown []string
+	for key, values := range query {
+		value := values[len(values)-1]
+		switch key {
+		case "vfs":
+			u.VFS = value
+		case "mode":
+			u.Mode = value
+		case "cache":
+			u.Cache = value
+		case "immutable":
+			u.Immutable, err = parseURIBool(key, value)
+		case "nolock":
+			u.NoLock, err = parseURIBool(key, value)
+		case "psow":
+			var psow bool
+			psow, err = parseURIBool(key, value)
+			u.NoPSOW = !psow
+		case "_busy_timeout":
+			var ms int64
+			ms, err = strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				err = fmt.Errorf("invalid URI _busy_timeout %q: want milliseconds", value)
+			}
+			u.BusyTimeout = time.Duration(ms) * time.Millisecond
+		default:
+			unknown = append(unknown, key)
+		}
+		if err != nil {
+			return URI{}, err
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return URI{}, fmt.Errorf("unknown URI parameters: %s", strings.Join(unknown, ", "))
+	}
+	return u, u.Validate()
+}
+
+// parseURIBool parses a boolean parameter the way SQLite does.
+func parseURIBool(key, value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "1", "yes", "true", "on":
+		return true, nil
+	case "0", "no", "false", "off":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid URI %s %q: want a boolean", key, value)
+}
@@ -0,0 +1,46 @@
+package sqliteutils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dropsite-ai/sqliteutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURI(t *testing.T) {
+	assert.Equal(t, "file::memory:?mode=memory&cache=shared", sqliteutils.MemoryURI("").String())
+	assert.Equal(t, "file:data/app%3f.db", sqliteutils.URI{Path: "data/app?.db"}.String())
+
+	u := sqliteutils.URI{
+		Path:        "/var/lib/app.db",
+		Mode:        "ro",
+		Cache:       "private",
+		Immutable:   true,
+		BusyTimeout: 5 * time.Second,
+	}
+	s := u.String()
+	assert.Equal(t, "file:/var/lib/app.db?mode=ro&cache=private&immutable=1&_busy_timeout=5000", s)
+
+	parsed, err := sqliteutils.ParseURI(s)
+	require.NoError(t, err)
+	assert.Equal(t, u, parsed)
+
+	parsed, err = sqliteutils.ParseURI("file://localhost/tmp/a%20b.db?nolock=yes&psow=0&vfs=unix-dotfile#frag")
+	require.NoError(t, err)
+	assert.Equal(t, sqliteutils.URI{Path: "/tmp/a b.db", NoLock: true, NoPSOW: true, VFS: "unix-dotfile"}, parsed)
+
+	parsed, err = sqliteutils.ParseURI("plain.db")
+	require.NoError(t, err)
+	assert.Equal(t, sqliteutils.URI{Path: "plain.db"}, parsed)
+
+	_, err = sqliteutils.ParseURI("file:a.db?mode=memory&cahce=shared&foo=1")
+	assert.EqualError(t, err, "unknown URI parameters: cahce, foo")
+	_, err = sqliteutils.ParseURI("file:a.db?mode=readonly")
+	assert.Error(t, err)
+	_, err = sqliteutils.ParseURI("file:a.db?immutable=maybe")
+	assert.Error(t, err)
+	_, err = sqliteutils.ParseURI("file://example.com/a.db")
+	assert.Error(t, err)
+}